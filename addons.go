@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// clusterAddon identifies the pods of a cluster-critical addon by one of its labels
+type clusterAddon struct {
+	Name   string
+	Labels map[string]string
+	// Instâncias por node (DaemonSet) em vez de réplicas
+	PerNode bool
+}
+
+var clusterAddons = []clusterAddon{
+	{Name: "CoreDNS", Labels: map[string]string{"k8s-app": "kube-dns"}},
+	{Name: "kube-proxy", Labels: map[string]string{"k8s-app": "kube-proxy"}, PerNode: true},
+	{Name: "metrics-server", Labels: map[string]string{"k8s-app": "metrics-server", "app.kubernetes.io/name": "metrics-server"}},
+	{Name: "Calico", Labels: map[string]string{"k8s-app": "calico-node"}, PerNode: true},
+	{Name: "Cilium", Labels: map[string]string{"k8s-app": "cilium"}, PerNode: true},
+	{Name: "AWS VPC CNI", Labels: map[string]string{"k8s-app": "aws-node"}, PerNode: true},
+	{Name: "Flannel", Labels: map[string]string{"app": "flannel"}, PerNode: true},
+	{Name: "Weave Net", Labels: map[string]string{"name": "weave-net"}, PerNode: true},
+	{Name: "Antrea", Labels: map[string]string{"component": "antrea-agent"}, PerNode: true},
+}
+
+// Guias de dimensionamento dos addons
+const (
+	// CoreDNS: memória (MB) = (pods + services) / 1000 + 54
+	coreDNSPodsServicesPerMB = 1000
+	coreDNSBaseMemoryMB      = 54
+	// CoreDNS: réplicas do cluster-proportional-autoscaler (linear)
+	coreDNSNodesPerReplica = 16
+	coreDNSCoresPerReplica = 256
+	coreDNSMinReplicas     = 2
+	// metrics-server: 1m de CPU e 2Mi de memória por node, com mínimo de 100m e 200Mi
+	metricsServerCPUPerNode    = 1
+	metricsServerMemoryPerNode = 2 * 1024 * 1024
+	metricsServerMinCPU        = 100
+	metricsServerMinMemory     = 200 * 1024 * 1024
+	// Margem sobre o pico observado ao recomendar requests de addons
+	addonHeadroomPct = 20
+	// Uso de memória, em percentual do limite, a partir do qual o addon corre risco de OOMKill
+	addonMemoryLimitPct = 90
+)
+
+// AddonSizing compares the resources of a cluster addon with its usage and the known sizing guidance
+type AddonSizing struct {
+	Name      string
+	Namespace string
+	Workload  string
+	PerNode   bool
+	Pods      int
+	// Por pod (maior valor entre os pods)
+	RequestCPU    int64
+	RequestMemory int64
+	LimitCPU      int64
+	LimitMemory   int64
+	MaxCPU        int64
+	MaxMemory     int64
+	// Recomendação pela guia do addon (0 quando não há guia)
+	GuidanceCPU      int64
+	GuidanceMemory   int64
+	GuidanceReplicas int
+	PriorityClass    string
+	Issues           []PerformanceRecommendation
+}
+
+// addonFor returns the addon the pod belongs to, if any
+func addonFor(pod *corev1.Pod) (clusterAddon, bool) {
+	for _, addon := range clusterAddons {
+		for key, value := range addon.Labels {
+			if pod.Labels[key] == value {
+				return addon, true
+			}
+		}
+	}
+	return clusterAddon{}, false
+}
+
+// withHeadroom adds addonHeadroomPct to the observed peak
+func withHeadroom(value int64) int64 {
+	return value + value*addonHeadroomPct/100
+}
+
+// analyzeAddons sizes the cluster-critical addons: CoreDNS (memory by pods and services, replicas by
+// nodes and cores), metrics-server (per node) and the per-node agents (kube-proxy and CNI) by usage
+func analyzeAddons(clientset *kubernetes.Clientset, nodes []corev1.Node, pods []corev1.Pod, metrics *MetricsData, deploymentIndex map[string]*DeploymentMetrics) ([]*AddonSizing, error) {
+	services, err := clientset.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar services: %v", err)
+	}
+	var cores int64
+	for _, node := range nodes {
+		cores += node.Status.Allocatable.Cpu().MilliValue()
+	}
+	cores /= 1000
+
+	addons := make(map[string]*AddonSizing)
+	var order []string
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		addon, found := addonFor(pod)
+		if !found {
+			continue
+		}
+		sizing, exists := addons[addon.Name]
+		if !exists {
+			sizing = &AddonSizing{
+				Name:          addon.Name,
+				Namespace:     pod.Namespace,
+				Workload:      workloadForPod(pod, deploymentIndex),
+				PerNode:       addon.PerNode,
+				PriorityClass: pod.Spec.PriorityClassName,
+			}
+			addons[addon.Name] = sizing
+			order = append(order, addon.Name)
+		}
+		sizing.Pods++
+
+		var requestCPU, requestMemory, limitCPU, limitMemory int64
+		for j := range pod.Spec.Containers {
+			totals := containerTotals(&pod.Spec.Containers[j])
+			requestCPU += totals[quotaRequestsCPU]
+			requestMemory += totals[quotaRequestsMemory]
+			limitCPU += totals[quotaLimitsCPU]
+			limitMemory += totals[quotaLimitsMemory]
+		}
+		sizing.RequestCPU = max(sizing.RequestCPU, requestCPU)
+		sizing.RequestMemory = max(sizing.RequestMemory, requestMemory)
+		sizing.LimitCPU = max(sizing.LimitCPU, limitCPU)
+		sizing.LimitMemory = max(sizing.LimitMemory, limitMemory)
+		if pm, exists := metrics.PodMetrics[pod.Name]; exists && pm.Namespace == pod.Namespace {
+			sizing.MaxCPU = max(sizing.MaxCPU, pm.MaxCPU)
+			sizing.MaxMemory = max(sizing.MaxMemory, pm.MaxMemory)
+		}
+	}
+
+	result := make([]*AddonSizing, 0, len(order))
+	for _, name := range order {
+		sizing := addons[name]
+		switch sizing.Name {
+		case "CoreDNS":
+			memoryMB := int64(len(pods)+len(services.Items))/coreDNSPodsServicesPerMB + coreDNSBaseMemoryMB
+			sizing.GuidanceMemory = memoryMB * 1024 * 1024
+			sizing.GuidanceReplicas = max(coreDNSMinReplicas,
+				int(math.Ceil(float64(len(nodes))/coreDNSNodesPerReplica)),
+				int(math.Ceil(float64(cores)/coreDNSCoresPerReplica)))
+		case "metrics-server":
+			sizing.GuidanceCPU = max(metricsServerMinCPU, int64(len(nodes))*metricsServerCPUPerNode)
+			sizing.GuidanceMemory = max(metricsServerMinMemory, int64(len(nodes))*metricsServerMemoryPerNode)
+		}
+		sizing.Issues = addonIssues(sizing)
+		result = append(result, sizing)
+	}
+	return result, nil
+}
+
+// addonIssues compares the resources of the addon with its peak usage and sizing guidance
+func addonIssues(a *AddonSizing) []PerformanceRecommendation {
+	var issues []PerformanceRecommendation
+	add := func(issue, recommendation, priority string) {
+		issues = append(issues, PerformanceRecommendation{
+			ResourceName:   a.Name,
+			Namespace:      a.Namespace,
+			Issue:          issue,
+			Recommendation: recommendation,
+			Priority:       priority,
+		})
+	}
+
+	targetCPU := max(withHeadroom(a.MaxCPU), a.GuidanceCPU)
+	targetMemory := max(withHeadroom(a.MaxMemory), a.GuidanceMemory)
+
+	if a.RequestCPU == 0 || a.RequestMemory == 0 {
+		add("Addon crítico sem requests de CPU ou memória: pode ser privado de recursos ou despejado sob pressão",
+			fmt.Sprintf("Definir requests de pelo menos CPU %s e Memory %s", formatCPU(max(targetCPU, 10)), formatMemory(max(targetMemory, 1024*1024))), "Alta")
+	} else {
+		if a.RequestCPU < targetCPU {
+			add(fmt.Sprintf("Request de CPU (%s) abaixo do pico observado ou da guia do addon (%s)", formatCPU(a.RequestCPU), formatCPU(targetCPU)),
+				fmt.Sprintf("Aumentar o request de CPU para %s", formatCPU(targetCPU)), "Média")
+		}
+		if a.RequestMemory < targetMemory {
+			add(fmt.Sprintf("Request de memória (%s) abaixo do pico observado ou da guia do addon (%s)", formatMemory(a.RequestMemory), formatMemory(targetMemory)),
+				fmt.Sprintf("Aumentar o request de memória para %s", formatMemory(targetMemory)), "Alta")
+		}
+	}
+	if a.LimitMemory > 0 && (percent(a.MaxMemory, a.LimitMemory) >= addonMemoryLimitPct || a.GuidanceMemory > a.LimitMemory) {
+		add(fmt.Sprintf("Limite de memória (%s) próximo do uso (%s) ou abaixo da guia do addon", formatMemory(a.LimitMemory), formatMemory(a.MaxMemory)),
+			fmt.Sprintf("Aumentar o limite de memória para pelo menos %s para evitar OOMKill", formatMemory(withHeadroom(targetMemory))), "Alta")
+	}
+	if a.GuidanceReplicas > 0 && a.Pods < a.GuidanceReplicas {
+		add(fmt.Sprintf("%d réplicas para o tamanho do cluster (guia: %d)", a.Pods, a.GuidanceReplicas),
+			fmt.Sprintf("Escalar para %d réplicas ou usar o cluster-proportional-autoscaler (%d nodes ou %d cores por réplica)",
+				a.GuidanceReplicas, coreDNSNodesPerReplica, coreDNSCoresPerReplica), "Média")
+	}
+	if !strings.HasPrefix(a.PriorityClass, "system-") {
+		class := "system-cluster-critical"
+		if a.PerNode {
+			class = "system-node-critical"
+		}
+		add("Addon crítico sem PriorityClass de sistema: pode ser preemptado por workloads comuns",
+			fmt.Sprintf("Usar priorityClassName: %s", class), "Média")
+	}
+	return issues
+}
+
+// addonFindings turns the high priority addon issues into findings
+func addonFindings(addons []*AddonSizing) []Finding {
+	var findings []Finding
+	for _, a := range addons {
+		var body string
+		for _, issue := range a.Issues {
+			if issue.Priority == "Alta" {
+				body += fmt.Sprintf("- %s. Recomendação: %s\n", issue.Issue, issue.Recommendation)
+			}
+		}
+		if body == "" {
+			continue
+		}
+		findings = append(findings, Finding{
+			Kind:      "addon-subdimensionado",
+			Severity:  SeverityHigh,
+			Title:     fmt.Sprintf("Addon %s subdimensionado", a.Name),
+			Namespace: a.Namespace,
+			Workload:  a.Workload,
+			Body:      fmt.Sprintf("O addon %s (%s/%s) é crítico para o cluster e está com recursos insuficientes:\n%s", a.Name, a.Namespace, a.Workload, body),
+		})
+	}
+	return findings
+}
+
+func writeAddons(w io.Writer, addons []*AddonSizing) {
+	fmt.Fprintf(w, "\n=== Addons do Cluster ===\n")
+	fmt.Fprintf(w, "-------------------------\n")
+
+	if len(addons) == 0 {
+		fmt.Fprintf(w, "Nenhum addon conhecido identificado\n")
+		return
+	}
+	for _, a := range addons {
+		unit := "réplicas"
+		if a.PerNode {
+			unit = "pods (um por node)"
+		}
+		fmt.Fprintf(w, "\nAddon: %s (%s, Namespace: %s) - %d %s\n", a.Name, a.Workload, a.Namespace, a.Pods, unit)
+		fmt.Fprintf(w, "Por pod: requests CPU %s, Memory %s; limits CPU %s, Memory %s; pico CPU %s, Memory %s\n",
+			formatCPU(a.RequestCPU), formatMemory(a.RequestMemory), formatCPU(a.LimitCPU), formatMemory(a.LimitMemory), formatCPU(a.MaxCPU), formatMemory(a.MaxMemory))
+		if a.GuidanceCPU > 0 || a.GuidanceMemory > 0 || a.GuidanceReplicas > 0 {
+			fmt.Fprintf(w, "Guia:")
+			if a.GuidanceCPU > 0 {
+				fmt.Fprintf(w, " CPU %s", formatCPU(a.GuidanceCPU))
+			}
+			if a.GuidanceMemory > 0 {
+				fmt.Fprintf(w, " Memory %s", formatMemory(a.GuidanceMemory))
+			}
+			if a.GuidanceReplicas > 0 {
+				fmt.Fprintf(w, " %d réplicas", a.GuidanceReplicas)
+			}
+			fmt.Fprintf(w, "\n")
+		}
+		for i, issue := range a.Issues {
+			fmt.Fprintf(w, "%d. Problema: %s\n", i+1, issue.Issue)
+			fmt.Fprintf(w, "   Recomendação: %s\n", issue.Recommendation)
+			fmt.Fprintf(w, "   Prioridade: %s\n", issue.Priority)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Label dos namespaces em que o webhook injeta os recursos recomendados nos pods criados sem eles
+const injectDefaultsLabel = "performance-analyzer.io/inject-defaults"
+
+// Intervalo de releitura do histórico e dos namespaces habilitados
+const admissionRefreshInterval = time.Minute
+
+// Tamanho máximo aceito de uma AdmissionReview
+const maxAdmissionReviewBytes = 4 * 1024 * 1024
+
+// AdmissionServer is the mutating admission webhook that fills the missing requests and limits of the
+// containers of new pods with the recommendations of the latest run in the history. Each container gets
+// the values of its resource patch, so the webhook and the patches agree; containers excluded or pinned in
+// the configuration are left unchanged
+type AdmissionServer struct {
+	clientset   *kubernetes.Clientset
+	historyFile string
+	overrides   ContainerOverrides
+
+	mu sync.RWMutex
+	// Recomendação de cada container do deployment ("namespace/nome") na execução mais recente que a gerou
+	recommendations map[string]map[string]ContainerRecommendation
+	namespaces      map[string]bool
+}
+
+func newAdmissionServer(clientset *kubernetes.Clientset, historyFile string, overrides ContainerOverrides) *AdmissionServer {
+	return &AdmissionServer{clientset: clientset, historyFile: historyFile, overrides: overrides}
+}
+
+// refresh reloads the recommendations from the history and the namespaces that opted in
+func (s *AdmissionServer) refresh() error {
+	history, err := loadHistory(s.historyFile)
+	if err != nil {
+		return err
+	}
+	recommendations := admissionRecommendations(history)
+
+	list, err := s.clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{
+		LabelSelector: injectDefaultsLabel + "=true",
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao listar namespaces habilitados para o webhook: %v", err)
+	}
+	namespaces := make(map[string]bool, len(list.Items))
+	for _, ns := range list.Items {
+		namespaces[ns.Name] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recommendations = recommendations
+	s.namespaces = namespaces
+	return nil
+}
+
+// admissionRecommendations returns the latest patch recommendation of each container in the history,
+// by deployment
+func admissionRecommendations(history []HistoryRecord) map[string]map[string]ContainerRecommendation {
+	recommendations := make(map[string]map[string]ContainerRecommendation)
+	for _, record := range history {
+		for key, usage := range record.Deployments {
+			for _, r := range usage.Recommendations {
+				if r.RequestCPU == 0 && r.RequestMemory == 0 && r.LimitCPU == 0 && r.LimitMemory == 0 {
+					continue
+				}
+				if recommendations[key] == nil {
+					recommendations[key] = make(map[string]ContainerRecommendation)
+				}
+				recommendations[key][r.Container] = r
+			}
+		}
+	}
+	return recommendations
+}
+
+// podDeployment returns the deployment of a pod being created from its ReplicaSet owner
+// ("<deploy>-<hash>"); the pod name is usually still empty at admission
+func podDeployment(pod *corev1.Pod) string {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "ReplicaSet" {
+			if i := strings.LastIndex(owner.Name, "-"); i > 0 {
+				return owner.Name[:i]
+			}
+		}
+	}
+	return ""
+}
+
+// defaultResources fills the requests and limits missing from the container with the values of its
+// resource patch, keeping requests within the limits. It reports whether something was added
+func defaultResources(resources *corev1.ResourceRequirements, recommendation ContainerRecommendation) bool {
+	changed := false
+	set := func(list *corev1.ResourceList, name corev1.ResourceName, quantity resource.Quantity) {
+		if _, exists := (*list)[name]; exists || quantity.IsZero() {
+			return
+		}
+		if *list == nil {
+			*list = corev1.ResourceList{}
+		}
+		(*list)[name] = quantity
+		changed = true
+	}
+	cpu := func(millis int64) resource.Quantity { return *resource.NewMilliQuantity(millis, resource.DecimalSI) }
+	memory := func(bytes int64) resource.Quantity { return *resource.NewQuantity(bytes, resource.BinarySI) }
+
+	requestCPU, requestMemory := recommendation.RequestCPU, recommendation.RequestMemory
+	// Request acima de um limite já definido é rejeitado pela validação do pod
+	if limit, exists := resources.Limits[corev1.ResourceCPU]; exists {
+		requestCPU = min(requestCPU, limit.MilliValue())
+	}
+	if limit, exists := resources.Limits[corev1.ResourceMemory]; exists {
+		requestMemory = min(requestMemory, limit.Value())
+	}
+	set(&resources.Requests, corev1.ResourceCPU, cpu(requestCPU))
+	set(&resources.Requests, corev1.ResourceMemory, memory(requestMemory))
+
+	// Sem limite no patch (recurso sem leituras) nenhum limite é criado a partir do request
+	limitCPU, limitMemory := recommendation.LimitCPU, recommendation.LimitMemory
+	if request, exists := resources.Requests[corev1.ResourceCPU]; exists && limitCPU > 0 {
+		limitCPU = max(limitCPU, request.MilliValue())
+	}
+	if request, exists := resources.Requests[corev1.ResourceMemory]; exists && limitMemory > 0 {
+		limitMemory = max(limitMemory, request.Value())
+	}
+	set(&resources.Limits, corev1.ResourceCPU, cpu(limitCPU))
+	set(&resources.Limits, corev1.ResourceMemory, memory(limitMemory))
+	return changed
+}
+
+// mutate returns the JSON patch with the resources of the containers that had something missing, or
+// warnings explaining why the pod was left unchanged
+func (s *AdmissionServer) mutate(request *admissionv1.AdmissionRequest) ([]map[string]interface{}, []string, error) {
+	if request.Kind.Kind != "Pod" || request.Operation != admissionv1.Create {
+		return nil, nil, nil
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal(request.Object.Raw, &pod); err != nil {
+		return nil, nil, fmt.Errorf("erro ao decodificar o pod: %v", err)
+	}
+
+	s.mu.RLock()
+	enabled := s.namespaces[request.Namespace]
+	deployment := podDeployment(&pod)
+	containers, exists := s.recommendations[request.Namespace+"/"+deployment]
+	s.mu.RUnlock()
+	if !enabled {
+		return nil, nil, nil
+	}
+	if !exists {
+		return nil, []string{fmt.Sprintf("k8s-performance-analyzer: sem recomendação para o workload do pod em %s; recursos não injetados", request.Namespace)}, nil
+	}
+
+	var patch []map[string]interface{}
+	var warnings []string
+	for i, container := range pod.Spec.Containers {
+		if s.overrides.skip(request.Namespace, container.Name) {
+			continue
+		}
+		recommendation, exists := containers[container.Name]
+		if !exists {
+			warnings = append(warnings, fmt.Sprintf("k8s-performance-analyzer: sem recomendação para o container %s; recursos não injetados nele", container.Name))
+			continue
+		}
+		resources := container.Resources.DeepCopy()
+		if !defaultResources(resources, recommendation) {
+			continue
+		}
+		patch = append(patch, map[string]interface{}{
+			"op":    "add",
+			"path":  fmt.Sprintf("/spec/containers/%d/resources", i),
+			"value": resources,
+		})
+	}
+	return patch, warnings, nil
+}
+
+func (s *AdmissionServer) handleMutate(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAdmissionReviewBytes))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("erro ao ler a requisição: %v", err), http.StatusBadRequest)
+		return
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, "AdmissionReview inválida", http.StatusBadRequest)
+		return
+	}
+
+	// O pod é sempre admitido: uma falha ao calcular os recursos não bloqueia a criação
+	response := &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+	patch, warnings, err := s.mutate(review.Request)
+	if err != nil {
+		response.Warnings = []string{fmt.Sprintf("k8s-performance-analyzer: %v", err)}
+	} else {
+		response.Warnings = warnings
+	}
+	if len(patch) > 0 {
+		data, err := json.Marshal(patch)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("erro ao gerar o patch: %v", err), http.StatusInternalServerError)
+			return
+		}
+		patchType := admissionv1.PatchTypeJSONPatch
+		response.Patch = data
+		response.PatchType = &patchType
+		fmt.Printf("   - %s: recursos injetados em %d containers de um pod de %s\n",
+			time.Now().Format("15:04:05"), len(patch), review.Request.Namespace)
+	}
+
+	review.Response = response
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		fmt.Printf("⚠️  Aviso: erro ao responder à AdmissionReview: %v\n", err)
+	}
+}
+
+// run loads the recommendations and serves the webhook over TLS until the process ends, refreshing the
+// recommendations and the enabled namespaces periodically
+func (s *AdmissionServer) run(addr, certFile, keyFile string) error {
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	fmt.Printf("   - Recomendações carregadas: %d deployments; namespaces habilitados (%s=true): %d\n",
+		len(s.recommendations), injectDefaultsLabel, len(s.namespaces))
+	s.mu.RUnlock()
+
+	go func() {
+		for range time.Tick(admissionRefreshInterval) {
+			if err := s.refresh(); err != nil {
+				fmt.Printf("⚠️  Aviso: %v\n", err)
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutate", s.handleMutate)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { fmt.Fprintln(w, "ok") })
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	if err := server.ListenAndServeTLS(certFile, keyFile); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("erro no servidor do webhook: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// admissionTestRun builds the patches of a critical deployment with a sidecar in a namespace that
+// requires memory requests equal to limits, and records them in a history file
+func admissionTestRun(t *testing.T) ([]ResourcePatch, []HistoryRecord) {
+	t.Helper()
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "shop"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "proxy"}}},
+			},
+		},
+	}
+	metrics := &MetricsData{PodMetrics: map[string]*PodMetrics{
+		"checkout-1": {Namespace: "shop", Containers: map[string]*ContainerMetrics{
+			"app":   {MaxCPU: 400, MaxMemory: 300 * 1024 * 1024},
+			"proxy": {MaxCPU: 50, MaxMemory: 40 * 1024 * 1024},
+		}},
+		"checkout-2": {Namespace: "shop", Containers: map[string]*ContainerMetrics{
+			"app":   {MaxCPU: 200, MaxMemory: 200 * 1024 * 1024},
+			"proxy": {MaxCPU: 30, MaxMemory: 35 * 1024 * 1024},
+		}},
+	}}
+	dm := &DeploymentMetrics{Name: "checkout", Namespace: "shop", Pods: []string{"checkout-1", "checkout-2"},
+		MaxCPU: 450, MaxMemory: 340 * 1024 * 1024, AvgCPU: 340, AvgMemory: 287 * 1024 * 1024, Tier: tierCritical}
+	deploymentMetrics := map[string]*DeploymentMetrics{"shop/checkout": dm}
+	deployments := map[string]*appsv1.Deployment{"shop/checkout": deployment}
+
+	patches := buildResourcePatches(deploymentMetrics, metrics, deployments, NamespaceThresholds{}, nil)
+	RequestLimitPolicies{{Source: "LimitRange shop/limits", Memory: true}}.adaptPatches(patches, deployments)
+	if len(patches) != 1 || len(patches[0].Containers) != 2 {
+		t.Fatalf("esperado um patch com dois containers, obtido %+v", patches)
+	}
+
+	historyFile := filepath.Join(t.TempDir(), "history.jsonl")
+	patchIndex := indexPatches(patches)
+	record := HistoryRecord{Timestamp: time.Now(), Deployments: map[string]DeploymentUsage{
+		"shop/checkout": {
+			AvgCPU: dm.AvgCPU, AvgMemory: dm.AvgMemory, MaxCPU: dm.MaxCPU, MaxMemory: dm.MaxMemory,
+			Containers:      deploymentContainerUsage(dm, metrics),
+			Recommendations: patchIndex["shop/checkout"].Containers,
+		},
+	}}
+	if err := appendHistory(historyFile, record); err != nil {
+		t.Fatal(err)
+	}
+	history, err := loadHistory(historyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return patches, history
+}
+
+func admissionTestRequest(t *testing.T, containers []corev1.Container) *admissionv1.AdmissionRequest {
+	t.Helper()
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "shop",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "checkout-5d8f9c"}},
+		},
+		Spec: corev1.PodSpec{Containers: containers},
+	}
+	raw, err := json.Marshal(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+		Operation: admissionv1.Create,
+		Namespace: "shop",
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
+
+// injectedResources returns the resources set by the JSON patch for each container index
+func injectedResources(t *testing.T, patch []map[string]interface{}) map[string]*corev1.ResourceRequirements {
+	t.Helper()
+	injected := make(map[string]*corev1.ResourceRequirements)
+	for _, op := range patch {
+		resources, ok := op["value"].(*corev1.ResourceRequirements)
+		if !ok {
+			t.Fatalf("valor inesperado no patch: %#v", op["value"])
+		}
+		injected[op["path"].(string)] = resources
+	}
+	return injected
+}
+
+func TestAdmissionInjectsPatchValues(t *testing.T) {
+	patches, history := admissionTestRun(t)
+	server := &AdmissionServer{recommendations: admissionRecommendations(history), namespaces: map[string]bool{"shop": true}}
+
+	patch, warnings, err := server.mutate(admissionTestRequest(t, []corev1.Container{{Name: "app"}, {Name: "proxy"}}))
+	if err != nil || len(warnings) > 0 {
+		t.Fatalf("erro %v, avisos %v", err, warnings)
+	}
+	injected := injectedResources(t, patch)
+	for _, want := range patches[0].Containers {
+		path := map[string]string{"app": "/spec/containers/0/resources", "proxy": "/spec/containers/1/resources"}[want.Container]
+		got, exists := injected[path]
+		if !exists {
+			t.Fatalf("%s: recursos não injetados", want.Container)
+		}
+		if cpu := got.Requests.Cpu().MilliValue(); cpu != want.RequestCPU {
+			t.Errorf("%s: request de CPU %d, patch %d", want.Container, cpu, want.RequestCPU)
+		}
+		if memory := got.Requests.Memory().Value(); memory != want.RequestMemory {
+			t.Errorf("%s: request de memória %d, patch %d", want.Container, memory, want.RequestMemory)
+		}
+		if cpu := got.Limits.Cpu().MilliValue(); cpu != want.LimitCPU {
+			t.Errorf("%s: limite de CPU %d, patch %d", want.Container, cpu, want.LimitCPU)
+		}
+		if memory := got.Limits.Memory().Value(); memory != want.LimitMemory {
+			t.Errorf("%s: limite de memória %d, patch %d", want.Container, memory, want.LimitMemory)
+		}
+		// O tier critical e a política de memória alteram os valores em relação ao uso bruto
+		usage := history[0].Deployments["shop/checkout"].Containers[want.Container]
+		if want.LimitCPU == usage.MaxCPU || want.RequestMemory != want.LimitMemory {
+			t.Errorf("%s: patch %+v sem a folga do tier ou a política de memória (uso %+v)", want.Container, want, usage)
+		}
+	}
+}
+
+func TestAdmissionKeepsExistingResources(t *testing.T) {
+	patches, history := admissionTestRun(t)
+	server := &AdmissionServer{recommendations: admissionRecommendations(history), namespaces: map[string]bool{"shop": true}}
+	want := patches[0].Containers[0]
+	if want.Container != "app" {
+		t.Fatalf("container inesperado %s", want.Container)
+	}
+
+	// Limite de CPU abaixo do request recomendado: o request injetado fica no limite existente
+	limit := *resource.NewMilliQuantity(want.RequestCPU/2, resource.DecimalSI)
+	app := corev1.Container{Name: "app", Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: limit}}}
+	patch, _, err := server.mutate(admissionTestRequest(t, []corev1.Container{app}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := injectedResources(t, patch)["/spec/containers/0/resources"]
+	if got == nil {
+		t.Fatal("recursos não injetados")
+	}
+	if cpu := got.Limits.Cpu().MilliValue(); cpu != limit.MilliValue() {
+		t.Errorf("limite de CPU alterado para %d", cpu)
+	}
+	if cpu := got.Requests.Cpu().MilliValue(); cpu != limit.MilliValue() {
+		t.Errorf("request de CPU %d acima do limite %d", cpu, limit.MilliValue())
+	}
+	if memory := got.Limits.Memory().Value(); memory != want.LimitMemory {
+		t.Errorf("limite de memória %d, patch %d", memory, want.LimitMemory)
+	}
+}
+
+func TestAdmissionRecommendationsLatestRun(t *testing.T) {
+	older := HistoryRecord{Deployments: map[string]DeploymentUsage{"shop/checkout": {Recommendations: []ContainerRecommendation{
+		{Container: "app", RequestCPU: 100, LimitCPU: 200},
+		{Container: "proxy", RequestCPU: 10, LimitCPU: 20},
+	}}}}
+	newer := HistoryRecord{Deployments: map[string]DeploymentUsage{"shop/checkout": {Recommendations: []ContainerRecommendation{
+		{Container: "app", RequestCPU: 300, LimitCPU: 400},
+	}}}}
+	// Registros sem recomendações (anteriores aos patches no histórico) não geram valores
+	legacy := HistoryRecord{Deployments: map[string]DeploymentUsage{"shop/legacy": {
+		Containers: map[string]ContainerUsage{"app": {AvgCPU: 100, MaxCPU: 200}},
+	}}}
+
+	recommendations := admissionRecommendations([]HistoryRecord{older, newer, legacy})
+	if got := recommendations["shop/checkout"]["app"].RequestCPU; got != 300 {
+		t.Errorf("app: request de CPU %d, esperado o da execução mais recente", got)
+	}
+	if got := recommendations["shop/checkout"]["proxy"].RequestCPU; got != 10 {
+		t.Errorf("proxy: request de CPU %d, esperado o da execução anterior", got)
+	}
+	if _, exists := recommendations["shop/legacy"]; exists {
+		t.Error("registro sem recomendações gerou valores")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Provedores de alerta suportados
+const (
+	alertProviderPagerDuty = "pagerduty"
+	alertProviderOpsgenie  = "opsgenie"
+)
+
+// AlertingConfig configures the events sent for imminent risks. The key comes from the
+// PAGERDUTY_ROUTING_KEY or OPSGENIE_API_KEY environment variable
+type AlertingConfig struct {
+	Provider string `json:"provider,omitempty"`
+	// URL da API (padrão: https://events.pagerduty.com ou https://api.opsgenie.com)
+	URL string `json:"url,omitempty"`
+	// Limites de memória, em percentual do allocatable, a partir dos quais um node gera alerta
+	OvercommitPct int `json:"overcommit_pct,omitempty"`
+}
+
+// overcommitPct returns the configured overcommit threshold or the default
+func (c AlertingConfig) overcommitPct() int {
+	if c.OvercommitPct > 0 {
+		return c.OvercommitPct
+	}
+	return defaultOvercommitRiskPct
+}
+
+// sendAlerts triggers one event per risk in the configured provider, returning how many were sent.
+// The fingerprint of the risk is used as deduplication key, so repeated runs update the same alert
+func sendAlerts(config AlertingConfig, cluster string, risks []Finding) (int, error) {
+	source := cluster
+	if source == "" {
+		source = trackerLabel
+	}
+	sent := 0
+	for _, risk := range risks {
+		fingerprint := trackerFingerprint(cluster, risk.key())
+		details := map[string]string{
+			"cluster":   cluster,
+			"namespace": risk.Namespace,
+			"workload":  risk.Workload,
+		}
+
+		var err error
+		switch config.Provider {
+		case alertProviderPagerDuty:
+			base := "https://events.pagerduty.com"
+			if config.URL != "" {
+				base = strings.TrimRight(config.URL, "/")
+			}
+			details["descricao"] = risk.Body
+			event := map[string]interface{}{
+				"routing_key":  os.Getenv("PAGERDUTY_ROUTING_KEY"),
+				"event_action": "trigger",
+				"dedup_key":    fingerprint,
+				"payload": map[string]interface{}{
+					"summary":        truncate(fmt.Sprintf("[%s] %s", cluster, risk.Title), 1024),
+					"source":         source,
+					"severity":       "critical",
+					"component":      risk.Workload,
+					"group":          risk.Namespace,
+					"class":          risk.Kind,
+					"custom_details": details,
+				},
+			}
+			err = trackerRequest(http.MethodPost, base+"/v2/enqueue", nil, event, nil)
+		case alertProviderOpsgenie:
+			base := "https://api.opsgenie.com"
+			if config.URL != "" {
+				base = strings.TrimRight(config.URL, "/")
+			}
+			header := http.Header{}
+			header.Set("Authorization", "GenieKey "+os.Getenv("OPSGENIE_API_KEY"))
+			alert := map[string]interface{}{
+				"message":     truncate(fmt.Sprintf("[%s] %s", cluster, risk.Title), 130),
+				"alias":       fingerprint,
+				"description": risk.Body,
+				"priority":    "P1",
+				"source":      trackerLabel,
+				"tags":        []string{trackerLabel, risk.Kind},
+				"details":     details,
+			}
+			err = trackerRequest(http.MethodPost, base+"/v2/alerts", header, alert, nil)
+		default:
+			return sent, fmt.Errorf("provedor de alertas inválido: %s (use pagerduty ou opsgenie)", config.Provider)
+		}
+		if err != nil {
+			return sent, fmt.Errorf("erro ao enviar alerta para %s: %v", config.Provider, err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// truncate limits s to n characters, as required by APIs with short message fields
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Arquivo, no diretório dos relatórios, com a chave dos aliases desta instalação
+const anonymizeKeyFile = ".anonymize-key"
+
+// Tamanho da chave dos aliases, em bytes
+const anonymizeKeySize = 32
+
+// Anonymizer replaces namespace, workload and pod names with stable aliases, so the same name
+// always maps to the same alias within and across reports. The aliases are an HMAC of the name with a
+// key of the installation: without the key, common names cannot be recovered by hashing a dictionary
+type Anonymizer struct {
+	key     []byte
+	aliases map[string]string
+	// Valores com separadores (URLs, paths), trocados inteiros antes dos nomes
+	literals map[string]string
+	replacer *strings.Replacer
+}
+
+func newAnonymizer(key []byte) *Anonymizer {
+	return &Anonymizer{key: key, aliases: make(map[string]string), literals: make(map[string]string)}
+}
+
+func (a *Anonymizer) alias(kind, value string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(value))
+	return kind + "-" + hex.EncodeToString(mac.Sum(nil))[:10]
+}
+
+// loadAnonymizeKey reads the key stored next to the reports, creating a random one on first use. The
+// same key keeps the aliases stable across runs; it must not be shared with the reports
+func loadAnonymizeKey(reportDir string) ([]byte, error) {
+	return loadSecret(filepath.Join(reportDir, anonymizeKeyFile), anonymizeKeySize, "chave de anonimização")
+}
+
+// loadSecret reads a hex-encoded secret of at least size bytes from path, creating a random one (readable
+// only by the owner) when the file does not exist; name describes the secret in the errors
+func loadSecret(path string, size int, name string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		secret, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(secret) < size {
+			return nil, fmt.Errorf("%s inválida em %s", name, path)
+		}
+		return secret, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("erro ao ler %s: %v", name, err)
+	}
+	secret := make([]byte, size)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("erro ao gerar %s: %v", name, err)
+	}
+	// O_EXCL: com duas execuções simultâneas, a segunda lê o segredo criado pela primeira
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return loadSecret(path, size, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao gravar %s: %v", name, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(hex.EncodeToString(secret) + "\n"); err != nil {
+		return nil, fmt.Errorf("erro ao gravar %s: %v", name, err)
+	}
+	return secret, nil
+}
+
+// register adds a name to be anonymized; kind ("ns", "deploy", "pod"...) prefixes the alias.
+// The first kind registered for a name wins. A nil Anonymizer ignores the call
+func (a *Anonymizer) register(kind, name string) {
+	if a == nil || name == "" {
+		return
+	}
+	if _, exists := a.aliases[name]; exists {
+		return
+	}
+	a.aliases[name] = a.alias(kind, name)
+}
+
+// registerLiteral adds a value that is not a single name, such as a repository URL or a path, to be
+// replaced as a whole. Splitting it into names would also anonymize common words ("apps", "prod")
+// everywhere in the report. A nil Anonymizer ignores the call
+func (a *Anonymizer) registerLiteral(kind, value string) {
+	if a == nil || value == "" {
+		return
+	}
+	if _, exists := a.literals[value]; exists {
+		return
+	}
+	a.literals[value] = a.alias(kind, value)
+	a.replacer = nil
+}
+
+// registerCluster collects in one place, before any output is written, every name the reports may emit:
+// the namespaces, the workloads (Deployments, StatefulSets, DaemonSets, ReplicaSets and other owners of
+// pods), the pods and their containers, the PVCs and volumeClaimTemplates, the HPAs, VPAs and KEDA
+// ScaledObjects, the Helm releases, the GitOps applications with their sources, the images and registries,
+// and the values of the labels in labels (groups of -split-by and -cost-label). Objects that cannot be
+// listed are reported; their analyses fail the same way and do not emit the names
+func (a *Anonymizer) registerCluster(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, pods []corev1.Pod, replicaSets []appsv1.ReplicaSet,
+	deployments map[string]*appsv1.Deployment, deploymentMetrics map[string]*DeploymentMetrics, kedaScalers []*KEDAScaler, labels []string) []error {
+	if a == nil {
+		return nil
+	}
+	var errs []error
+	list := func(kind string, err error) bool {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("anonimização: erro ao listar %s: %v", kind, err))
+		}
+		return err == nil
+	}
+
+	// Namespaces primeiro, inclusive os sem workloads (higiene), depois os workloads, que dão nome aos pods
+	namespaces, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	if list("namespaces", err) {
+		for _, ns := range namespaces.Items {
+			a.register("ns", ns.Name)
+		}
+	}
+	for _, pod := range pods {
+		a.register("ns", pod.Namespace)
+	}
+	for _, d := range deployments {
+		a.register("ns", d.Namespace)
+		a.register("deploy", d.Name)
+	}
+	for _, pod := range pods {
+		for _, owner := range pod.OwnerReferences {
+			if owner.Kind == "ReplicaSet" {
+				a.register("deploy", workloadFromPodName(pod.Name))
+			}
+		}
+	}
+	statefulSets, err := clientset.AppsV1().StatefulSets("").List(context.TODO(), metav1.ListOptions{})
+	if list("StatefulSets", err) {
+		for _, sts := range statefulSets.Items {
+			a.register("sts", sts.Name)
+			for _, template := range sts.Spec.VolumeClaimTemplates {
+				a.register("tpl", template.Name)
+			}
+		}
+	}
+	daemonSets, err := clientset.AppsV1().DaemonSets("").List(context.TODO(), metav1.ListOptions{})
+	if list("DaemonSets", err) {
+		for _, ds := range daemonSets.Items {
+			a.register("ds", ds.Name)
+		}
+	}
+	for _, rs := range replicaSets {
+		a.register("rs", rs.Name)
+	}
+	for _, pod := range pods {
+		for _, owner := range pod.OwnerReferences {
+			a.register("wl", owner.Name)
+		}
+		a.register("pod", pod.Name)
+	}
+
+	// Containers dos pods e dos templates, inclusive init containers
+	registerContainers := func(spec *corev1.PodSpec) {
+		for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+			for _, c := range containers {
+				a.register("ctr", c.Name)
+				a.register("registry", imageRegistry(c.Image))
+				a.registerLiteral("img", c.Image)
+			}
+		}
+	}
+	for i := range pods {
+		registerContainers(&pods[i].Spec)
+	}
+	for _, d := range deployments {
+		registerContainers(&d.Spec.Template.Spec)
+	}
+
+	pvcs, err := clientset.CoreV1().PersistentVolumeClaims("").List(context.TODO(), metav1.ListOptions{})
+	if list("PVCs", err) {
+		for _, pvc := range pvcs.Items {
+			a.register("pvc", pvc.Name)
+		}
+	}
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("").List(context.TODO(), metav1.ListOptions{})
+	if list("HPAs", err) {
+		for _, hpa := range hpas.Items {
+			a.register("hpa", hpa.Name)
+		}
+	}
+	vpas, err := listVPAs(dynamicClient)
+	if list("VPAs", err) {
+		for _, vpa := range vpas {
+			a.register("vpa", vpa.Name)
+		}
+	}
+	for _, scaler := range kedaScalers {
+		a.register("so", scaler.Name)
+		a.register("hpa", scaler.HPA)
+	}
+
+	for _, d := range deployments {
+		if release, _, found := helmRelease(d); found {
+			a.register("release", release)
+		}
+	}
+	for _, dm := range deploymentMetrics {
+		if dm.Source != nil {
+			_, app, _ := strings.Cut(dm.Source.Name, "/")
+			a.register("app", app)
+			a.registerLiteral("repo", dm.Source.Repo)
+			a.registerLiteral("path", dm.Source.Path)
+		}
+	}
+
+	for _, label := range labels {
+		if label == "" {
+			continue
+		}
+		for _, pod := range pods {
+			a.register("group", pod.Labels[label])
+		}
+		for _, d := range deployments {
+			a.register("group", d.Labels[label])
+			a.register("group", d.Spec.Template.Labels[label])
+		}
+	}
+	return errs
+}
+
+// isNameChar reports whether c can be part of a Kubernetes object name
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_'
+}
+
+// isWordName reports whether the name is a plain word ("api", "web"), which may also appear in prose
+func isWordName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] < 'a' || name[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Palavras que, antes de um nome, indicam que ele é um identificador (ex: "Namespace payments",
+// "deployment api -n web")
+var identifierKeywords = map[string]bool{
+	"namespace": true, "namespaces": true, "deployment": true, "deployments": true, "statefulset": true,
+	"statefulsets": true, "daemonset": true, "daemonsets": true, "replicaset": true, "replicasets": true,
+	"pod": true, "pods": true, "container": true, "containers": true, "pvc": true, "pvcs": true, "hpa": true,
+	"hpas": true, "vpa": true, "vpas": true, "scaledobject": true, "scaledobjects": true, "workload": true,
+	"workloads": true, "release": true, "releases": true, "application": true, "applications": true,
+	"app": true, "addon": true, "addons": true, "cluster": true, "contexto": true, "em": true, "-n": true, "-c": true,
+}
+
+// identifierPosition reports whether the token text[i:j] is in the position of an identifier: next to a
+// delimiter ("ns/nome", "Namespace: nome", "(nome)", "\"nome\"", start or end of a line), or after a
+// label ("Origem: nome") or a keyword ("deployment nome"). A word between spaces in a sentence is prose
+func identifierPosition(text string, i, j int) bool {
+	if i == 0 || text[i-1] != ' ' {
+		return true
+	}
+	if j == len(text) || !strings.ContainsRune(" .,;!?", rune(text[j])) {
+		return true
+	}
+	start := strings.LastIndexAny(text[:i-1], " \t\n(") + 1
+	previous := text[start : i-1]
+	if strings.HasSuffix(previous, ":") || previous == "-" || previous == "" {
+		return true
+	}
+	if n := strings.TrimSuffix(previous, "."); n != previous && strings.Trim(n, "0123456789") == "" {
+		// Item numerado ("1. nome")
+		return true
+	}
+	return identifierKeywords[strings.ToLower(previous)]
+}
+
+// Anonymize replaces the registered names in the text. Names with separators or digits
+// ("checkout-api", "pod-7d9f") are identifiers wherever they appear; plain words ("api", "web") are
+// replaced only in identifier positions, so the same word in prose is kept. Pods no longer listed (from
+// events) are recognized by the registered workload in their name. A nil Anonymizer returns the text
+// unchanged
+func (a *Anonymizer) Anonymize(text string) string {
+	if a == nil {
+		return text
+	}
+	if len(a.literals) > 0 {
+		if a.replacer == nil {
+			// Valores mais longos primeiro, para que um path não seja trocado dentro de outro
+			values := make([]string, 0, len(a.literals))
+			for value := range a.literals {
+				values = append(values, value)
+			}
+			sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+			var pairs []string
+			for _, value := range values {
+				pairs = append(pairs, value, a.literals[value])
+			}
+			a.replacer = strings.NewReplacer(pairs...)
+		}
+		text = a.replacer.Replace(text)
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(text); {
+		if !isNameChar(text[i]) {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(text) && isNameChar(text[j]) {
+			j++
+		}
+		token := text[i:j]
+		// Pontuação final (ex: "node-1.") não faz parte do nome
+		trimmed := strings.TrimRight(token, ".")
+		alias, exists := a.aliases[trimmed]
+		if !exists {
+			alias, exists = a.podAlias(trimmed)
+		}
+		if exists && (!isWordName(trimmed) || identifierPosition(text, i, i+len(trimmed))) {
+			b.WriteString(alias)
+			b.WriteString(token[len(trimmed):])
+		} else {
+			b.WriteString(token)
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// podAlias returns the alias of a pod that was not registered, such as an evicted or preempted pod known
+// only from the events, when its name is "<workload>-<hash>-<sufixo>" of a registered deployment
+func (a *Anonymizer) podAlias(name string) (string, bool) {
+	workload := workloadFromPodName(name)
+	if workload == name || !strings.HasPrefix(a.aliases[workload], "deploy-") {
+		return "", false
+	}
+	a.register("pod", name)
+	return a.aliases[name], true
+}
+
+// anonymizingWriter keeps the text until Flush and anonymizes it as a whole, so names registered after a
+// section was written are still replaced in it
+type anonymizingWriter struct {
+	w          io.Writer
+	anonymizer *Anonymizer
+	pending    []byte
+}
+
+func newAnonymizingWriter(w io.Writer, anonymizer *Anonymizer) *anonymizingWriter {
+	return &anonymizingWriter{w: w, anonymizer: anonymizer}
+}
+
+func (aw *anonymizingWriter) Write(p []byte) (int, error) {
+	aw.pending = append(aw.pending, p...)
+	return len(p), nil
+}
+
+// Flush anonymizes and writes the text kept so far
+func (aw *anonymizingWriter) Flush() error {
+	if len(aw.pending) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(aw.w, aw.anonymizer.Anonymize(string(aw.pending)))
+	aw.pending = aw.pending[:0]
+	return err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizeIdentifierPositions(t *testing.T) {
+	a := newAnonymizer([]byte("chave"))
+	a.register("ns", "web")
+	a.register("deploy", "api")
+	a.register("deploy", "checkout-api")
+	web, api, checkout := a.aliases["web"], a.aliases["api"], a.aliases["checkout-api"]
+
+	tests := []struct {
+		text, want string
+	}{
+		{"web/api: 2 réplicas", web + "/" + api + ": 2 réplicas"},
+		{"Namespace web", "Namespace " + web},
+		{"Deployment: api", "Deployment: " + api},
+		{"- api\n", "- " + api + "\n"},
+		{"1. api (web)", "1. " + api + " (" + web + ")"},
+		{"kubectl -n web rollout restart deployment api", "kubectl -n " + web + " rollout restart deployment " + api},
+		// Palavras comuns em frases são mantidas; nomes com separadores são trocados em qualquer posição
+		{"o tráfego web da api cresceu.", "o tráfego web da api cresceu."},
+		{"a api checkout-api cresceu", "a api " + checkout + " cresceu"},
+	}
+	for _, tt := range tests {
+		if got := a.Anonymize(tt.text); got != tt.want {
+			t.Errorf("Anonymize(%q) = %q, esperado %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestAnonymizeDeletedPods(t *testing.T) {
+	a := newAnonymizer([]byte("chave"))
+	a.register("deploy", "checkout")
+	a.register("ns", "shop")
+
+	// Pods despejados só aparecem nos eventos: o workload registrado no nome identifica o pod
+	got := a.Anonymize("shop/checkout-5d8f9c-x7k2p despejado")
+	if strings.Contains(got, "checkout") || !strings.Contains(got, "pod-") {
+		t.Errorf("pod removido não anonimizado: %q", got)
+	}
+	if again := a.Anonymize("shop/checkout-5d8f9c-x7k2p"); !strings.HasSuffix(got, " despejado") || !strings.HasPrefix(got, again) {
+		t.Errorf("alias do pod removido não é estável: %q, %q", got, again)
+	}
+	if got := a.Anonymize("node-pool-a"); got != "node-pool-a" {
+		t.Errorf("nome sem workload registrado alterado: %q", got)
+	}
+}
+
+func TestAnonymizingWriterLateRegistration(t *testing.T) {
+	a := newAnonymizer([]byte("chave"))
+	var out bytes.Buffer
+	w := newAnonymizingWriter(&out, a)
+	fmt.Fprintln(w, "Namespace: payments")
+	// Nome registrado depois da seção escrita, mas antes da escrita do relatório
+	a.register("ns", "payments")
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "payments") {
+		t.Errorf("nome registrado depois da escrita não anonimizado: %q", out.String())
+	}
+}
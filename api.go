@@ -0,0 +1,321 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// Endereço padrão do serve quando -health-addr não é informado: apenas a própria máquina
+const defaultServeAddr = "127.0.0.1:8080"
+
+// Arquivo, no diretório dos relatórios, com a chave de acesso da API quando ANALYZER_API_TOKEN não é definida
+const apiTokenFile = ".api-token"
+
+// Tamanho da chave de acesso gerada, em bytes
+const apiTokenSize = 32
+
+// Estados de uma análise disparada pela API
+const (
+	analysisRunning   = "running"
+	analysisSucceeded = "succeeded"
+	analysisFailed    = "failed"
+)
+
+// AnalysisRun is an analysis triggered through the API, run as a child process of the analyzer
+type AnalysisRun struct {
+	ID       int        `json:"id"`
+	Status   string     `json:"status"`
+	Started  time.Time  `json:"started_at"`
+	Finished *time.Time `json:"finished_at,omitempty"`
+	ExitCode *int       `json:"exit_code,omitempty"`
+	// Relatório JSON gerado pela análise
+	Report string `json:"report,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// APISample is one reading of a container streamed by the samples endpoint
+type APISample struct {
+	Time      time.Time `json:"timestamp"`
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod"`
+	Container string    `json:"container"`
+	CPU       int64     `json:"cpu_millis"`
+	Memory    int64     `json:"memory_bytes"`
+}
+
+// AnalysisAPI is the HTTP/JSON API of the serve command, for portals and internal tools: it triggers
+// analyses, returns the recommendations of the latest report and streams the current usage. Each
+// analysis runs the analyzer itself with the options given to serve, one at a time
+type AnalysisAPI struct {
+	metricsClient *MetricsAPI
+	reportDir     string
+	// Contexto já sanitizado, usado no nome dos relatórios
+	context  string
+	args     []string
+	interval time.Duration
+	// Chave exigida no cabeçalho Authorization: Bearer de todas as rotas
+	token string
+
+	mu   sync.Mutex
+	runs []*AnalysisRun
+}
+
+func newAnalysisAPI(metricsClient *MetricsAPI, reportDir, sanitizedContext string, args []string, interval time.Duration, token string) *AnalysisAPI {
+	return &AnalysisAPI{metricsClient: metricsClient, reportDir: reportDir, context: sanitizedContext,
+		args: analysisArgs(args), interval: interval, token: token}
+}
+
+// loadAPIToken returns the access key of the API: ANALYZER_API_TOKEN when defined, otherwise the key
+// stored next to the reports, created on first use. The second return is where the key came from
+func loadAPIToken(reportDir string) (string, string, error) {
+	if token := os.Getenv("ANALYZER_API_TOKEN"); token != "" {
+		return token, "ANALYZER_API_TOKEN", nil
+	}
+	path := filepath.Join(reportDir, apiTokenFile)
+	token, err := loadSecret(path, apiTokenSize, "chave de acesso da API")
+	if err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(token), path, nil
+}
+
+// isLoopbackAddr reports whether the listen address only accepts local connections
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authorized rejects the requests without the access key; the comparison takes constant time
+func (a *AnalysisAPI) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="k8s-performance-analyzer"`)
+			writeAPIError(w, http.StatusUnauthorized, "chave de acesso ausente ou inválida (Authorization: Bearer <chave>)")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// analysisArgs removes the options that only apply to serve from the arguments of the child process
+func analysisArgs(args []string) []string {
+	var filtered []string
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimLeft(args[i], "-")
+		if name == "health-addr" {
+			i++
+			continue
+		}
+		if strings.HasPrefix(name, "health-addr=") {
+			continue
+		}
+		filtered = append(filtered, args[i])
+	}
+	return filtered
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		fmt.Printf("⚠️  Aviso: erro ao responder à API: %v\n", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
+
+// latestReport returns the newest JSON report of the context; the timestamp in the name sorts by time
+func (a *AnalysisAPI) latestReport() (string, error) {
+	matches, err := filepath.Glob(filepath.Join(a.reportDir, fmt.Sprintf("report-%s-*.json", a.context)))
+	if err != nil || len(matches) == 0 {
+		return "", err
+	}
+	sort.Strings(matches)
+	return matches[len(matches)-1], nil
+}
+
+// trigger starts an analysis unless one is already running
+func (a *AnalysisAPI) trigger() (*AnalysisRun, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if n := len(a.runs); n > 0 && a.runs[n-1].Status == analysisRunning {
+		return a.runs[n-1], false
+	}
+	run := &AnalysisRun{ID: len(a.runs) + 1, Status: analysisRunning, Started: time.Now()}
+	a.runs = append(a.runs, run)
+	go a.execute(run)
+	return run, true
+}
+
+// execute runs the analyzer as a child process and records the outcome
+func (a *AnalysisAPI) execute(run *AnalysisRun) {
+	previous, _ := a.latestReport()
+	executable, err := os.Executable()
+	exitCode := -1
+	if err == nil {
+		cmd := exec.Command(executable, a.args...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		err = cmd.Run()
+		if cmd.ProcessState != nil {
+			exitCode = cmd.ProcessState.ExitCode()
+		}
+	}
+	report, _ := a.latestReport()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	finished := time.Now()
+	run.Finished = &finished
+	// O código de saída das políticas (3) também gera todas as saídas
+	if err != nil && exitCode != policyFailureExitCode {
+		run.Status, run.Error = analysisFailed, err.Error()
+	} else {
+		run.Status = analysisSucceeded
+	}
+	if exitCode >= 0 {
+		run.ExitCode = &exitCode
+	}
+	if report != previous {
+		run.Report = report
+	}
+	fmt.Printf("   - %s: análise %d concluída (%s)\n", finished.Format("15:04:05"), run.ID, run.Status)
+}
+
+// handleAnalyses triggers an analysis (POST) or lists the analyses triggered since serve started (GET)
+func (a *AnalysisAPI) handleAnalyses(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		run, started := a.trigger()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if !started {
+			writeAPIJSON(w, http.StatusConflict, run)
+			return
+		}
+		writeAPIJSON(w, http.StatusAccepted, run)
+	case http.MethodGet:
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		runs := a.runs
+		if runs == nil {
+			runs = []*AnalysisRun{}
+		}
+		writeAPIJSON(w, http.StatusOK, runs)
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, "use GET ou POST")
+	}
+}
+
+// handleRecommendations returns the recommendations of the latest report, optionally filtered by
+// namespace and deployment
+func (a *AnalysisAPI) handleRecommendations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	path, err := a.latestReport()
+	if err != nil || path == "" {
+		writeAPIError(w, http.StatusNotFound, "nenhum relatório JSON do contexto; dispare uma análise com POST /api/v1/analyses")
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, fmt.Sprintf("erro ao ler %s: %v", path, err))
+		return
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, fmt.Sprintf("erro ao analisar %s: %v", path, err))
+		return
+	}
+
+	namespace, deployment := r.URL.Query().Get("namespace"), r.URL.Query().Get("deployment")
+	recommendations := []ResourcePatch{}
+	for _, p := range report.Recommendations {
+		if (namespace == "" || p.Namespace == namespace) && (deployment == "" || p.Deployment == deployment) {
+			recommendations = append(recommendations, p)
+		}
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]interface{}{
+		"report":          filepath.Base(path),
+		"generated_at":    report.GeneratedAt,
+		"recommendations": recommendations,
+	})
+}
+
+// handleSamples streams the usage of the containers (NDJSON, one reading per line) every interval
+// until the client disconnects, optionally filtered by namespace
+func (a *AnalysisAPI) handleSamples(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, "streaming não suportado pela conexão")
+		return
+	}
+	namespace := r.URL.Query().Get("namespace")
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		err := streamPodMetrics(a.metricsClient, func(pm *metricsv1beta1.PodMetrics) {
+			if namespace != "" && pm.Namespace != namespace {
+				return
+			}
+			for _, container := range pm.Containers {
+				encoder.Encode(APISample{
+					Time:      pm.Timestamp.Time,
+					Namespace: pm.Namespace,
+					Pod:       pm.Name,
+					Container: container.Name,
+					CPU:       container.Usage.Cpu().MilliValue(),
+					Memory:    container.Usage.Memory().Value(),
+				})
+			}
+		})
+		if err != nil {
+			encoder.Encode(map[string]string{"error": err.Error()})
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// register adds the routes of the API to the mux, all of them behind the access key
+func (a *AnalysisAPI) register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/analyses", a.authorized(a.handleAnalyses))
+	mux.HandleFunc("/api/v1/recommendations", a.authorized(a.handleRecommendations))
+	mux.HandleFunc("/api/v1/samples", a.authorized(a.handleSamples))
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Chamadas feitas uma única vez na análise, independentes do tamanho do cluster: listagens de
+// deployments, HPAs, eventos, quotas, PVCs, PriorityClasses, CRDs opcionais (KEDA, VPA, Kyverno,
+// Gatekeeper, Argo CD, Flux), versão e métricas do API server e consultas de verificação
+const estimateFixedCalls = 42
+
+// ClusterCensus is the size of the cluster read with the cheapest calls: one list with limit 1 per
+// resource, whose remainingItemCount gives the total
+type ClusterCensus struct {
+	Nodes      int
+	Namespaces int
+	Pods       int
+	HPAs       int
+}
+
+// takeClusterCensus counts the nodes, namespaces, pods and HPAs with four calls
+func takeClusterCensus(clientset *kubernetes.Clientset) (ClusterCensus, error) {
+	var census ClusterCensus
+	opts := metav1.ListOptions{Limit: 1}
+	count := func(items int, meta metav1.ListMeta) int {
+		if meta.RemainingItemCount != nil {
+			return items + int(*meta.RemainingItemCount)
+		}
+		return items
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), opts)
+	if err != nil {
+		return census, fmt.Errorf("erro ao contar nodes: %v", err)
+	}
+	census.Nodes = count(len(nodes.Items), nodes.ListMeta)
+	namespaces, err := clientset.CoreV1().Namespaces().List(context.TODO(), opts)
+	if err != nil {
+		return census, fmt.Errorf("erro ao contar namespaces: %v", err)
+	}
+	census.Namespaces = count(len(namespaces.Items), namespaces.ListMeta)
+	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), opts)
+	if err != nil {
+		return census, fmt.Errorf("erro ao contar pods: %v", err)
+	}
+	census.Pods = count(len(pods.Items), pods.ListMeta)
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("").List(context.TODO(), opts)
+	if err != nil {
+		return census, fmt.Errorf("erro ao contar HPAs: %v", err)
+	}
+	census.HPAs = count(len(hpas.Items), hpas.ListMeta)
+	return census, nil
+}
+
+// EstimatePhase is the number of API calls of a phase of the run
+type EstimatePhase struct {
+	Name   string
+	Calls  int
+	Detail string
+}
+
+// APIEstimate is the planned number of calls of a run against the API server, for approval before
+// running against fragile or rate-limited control planes
+type APIEstimate struct {
+	Census     ClusterCensus
+	Source     string
+	Period     time.Duration
+	Iterations int
+	// Chamadas de cada leitura da coleta
+	PerIteration int
+	Phases       []EstimatePhase
+	Total        int
+	QPS          float32
+	Burst        int
+}
+
+// pages returns how many pages of podListPageSize items a list of n items takes
+func pages(n int) int {
+	return max(1, (n+podListPageSize-1)/podListPageSize)
+}
+
+// estimateAPIRequests plans the calls of each phase from the size of the cluster. The counts are an
+// upper bound: readings skipped by -window make no calls, and the per-namespace calls only
+// happen for namespaces with pods
+func estimateAPIRequests(census ClusterCensus, source string, period time.Duration, deepMetrics bool, workers int, fromSamples bool, qps float32, burst int) *APIEstimate {
+	e := &APIEstimate{Census: census, Source: source, Period: period, QPS: qps, Burst: burst}
+	if e.QPS <= 0 {
+		e.QPS = rest.DefaultQPS
+	}
+	if e.Burst <= 0 {
+		e.Burst = rest.DefaultBurst
+	}
+
+	e.Phases = append(e.Phases, EstimatePhase{Name: "início", Calls: pages(census.Pods),
+		Detail: fmt.Sprintf("contagem de reinícios: %d páginas de %d pods", pages(census.Pods), podListPageSize)})
+
+	if !fromSamples {
+		e.Iterations = int(period / collectionInterval)
+		var detail string
+		switch source {
+		case sourcePrometheus:
+			detail = "Prometheus (fora do API server)"
+		case sourceKubelet:
+			e.PerIteration = census.Nodes
+			detail = fmt.Sprintf("summary do kubelet: 1 por node (%d)", census.Nodes)
+		default:
+			e.PerIteration = 2
+			detail = "métricas dos pods e dos nodes"
+		}
+		// Condições dos nodes a cada leitura
+		e.PerIteration++
+		detail += ", condições dos nodes"
+		if deepMetrics && source != sourceKubelet {
+			e.PerIteration += census.Nodes
+			detail += fmt.Sprintf(", métricas detalhadas: 1 por node (%d)", census.Nodes)
+		}
+		// Listagem dos nodes, negociação da versão de metrics.k8s.io (/api e /apis) e verificação da fonte antes da primeira leitura
+		e.Phases = append(e.Phases, EstimatePhase{Name: "coleta", Calls: 4 + e.Iterations*e.PerIteration,
+			Detail: fmt.Sprintf("%d leituras x %d chamadas (%s)", e.Iterations, e.PerIteration, detail)})
+	}
+
+	podCalls := pages(census.Pods)
+	podDetail := fmt.Sprintf("%d páginas", podCalls)
+	if workers > 1 {
+		podCalls = 1 + census.Namespaces
+		podDetail = fmt.Sprintf("1 por namespace com %d workers", workers)
+	}
+	e.Phases = append(e.Phases,
+		EstimatePhase{Name: "pods", Calls: podCalls, Detail: podDetail},
+		EstimatePhase{Name: "replicasets", Calls: census.Namespaces, Detail: "1 por namespace com pods"},
+		EstimatePhase{Name: "kubelet", Calls: census.Nodes, Detail: "configuração de despejo: 1 por node"},
+		EstimatePhase{Name: "hpas", Calls: census.HPAs, Detail: "StatefulSet alvo: até 1 por HPA"},
+		EstimatePhase{Name: "análise", Calls: estimateFixedCalls, Detail: "listagens feitas uma vez (deployments, eventos, quotas, CRDs)"},
+	)
+	for _, phase := range e.Phases {
+		e.Total += phase.Calls
+	}
+	return e
+}
+
+func writeAPIEstimate(w io.Writer, e *APIEstimate, censusCalls int) {
+	fmt.Fprintf(w, "\n=== Estimativa de Chamadas ao API Server ===\n")
+	fmt.Fprintf(w, "--------------------------------------------\n")
+	fmt.Fprintf(w, "Cluster: %d nodes, %d namespaces, %d pods, %d HPAs\n", e.Census.Nodes, e.Census.Namespaces, e.Census.Pods, e.Census.HPAs)
+	if e.Iterations > 0 {
+		fmt.Fprintf(w, "Coleta: %v, %d leituras a cada %v, fonte %s\n", e.Period, e.Iterations, collectionInterval, e.Source)
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "FASE\tCHAMADAS\tDETALHE\n")
+	for _, phase := range e.Phases {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", phase.Name, phase.Calls, phase.Detail)
+	}
+	fmt.Fprintf(tw, "total\t%d\t\n", e.Total)
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nRate limiter do cliente: %.0f chamadas/s (burst %d)\n", e.QPS, e.Burst)
+	if e.Iterations > 0 {
+		fmt.Fprintf(w, "Taxa durante a coleta: %.2f chamadas/s (%d a cada %v)\n", float64(e.PerIteration)/collectionInterval.Seconds(), e.PerIteration, collectionInterval)
+	}
+	analysis := e.Total
+	for _, phase := range e.Phases {
+		if phase.Name == "coleta" {
+			analysis -= phase.Calls
+		}
+	}
+	fmt.Fprintf(w, "Início e análise: %d chamadas, ao menos %v limitadas pelo rate limiter\n", analysis,
+		time.Duration(float64(max(0, analysis-e.Burst))/float64(e.QPS)*float64(time.Second)).Round(time.Second))
+	fmt.Fprintf(w, "Chamadas feitas pela estimativa: %d\n", censusCalls)
+	fmt.Fprintf(w, "\nObservação: os totais são um limite superior; leituras fora das janelas de coleta não fazem chamadas e integrações (Prometheus, webhooks, Jira) não passam pelo API server\n")
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ApplyResult records which patches were applied, skipped or failed
+type ApplyResult struct {
+	Applied []ResourcePatch
+	Skipped []ResourcePatch
+	Failed  []ResourcePatch
+}
+
+// ApplyJournal persists each change while the apply runs, so an apply interrupted by Ctrl-C or a crash
+// still leaves the previous values and the audit record of the deployments it already patched
+type ApplyJournal struct {
+	RollbackPath string
+	Bundle       *RollbackBundle
+	Audit        *AuditLog
+}
+
+// before adds the previous values of a deployment to the rollback bundle on disk, ahead of the patch
+func (j *ApplyJournal) before(entry RollbackEntry) error {
+	j.Bundle.Entries = append(j.Bundle.Entries, entry)
+	if err := writeRollbackBundle(j.RollbackPath, j.Bundle); err != nil {
+		j.Bundle.Entries = j.Bundle.Entries[:len(j.Bundle.Entries)-1]
+		return err
+	}
+	if len(j.Bundle.Entries) == 1 {
+		fmt.Printf("   - Pacote de rollback: %s (desfazer com: rollback %s)\n", j.RollbackPath, j.RollbackPath)
+	}
+	return nil
+}
+
+// applied records the patch in the audit log
+func (j *ApplyJournal) applied(p ResourcePatch) {
+	j.Audit.record(AuditEntry{Action: auditActionApply, Namespace: p.Namespace, Deployment: p.Deployment, Changes: p.Containers})
+}
+
+// failed removes the entry of a patch the API server rejected
+func (j *ApplyJournal) failed() {
+	j.Bundle.Entries = j.Bundle.Entries[:len(j.Bundle.Entries)-1]
+	if len(j.Bundle.Entries) == 0 {
+		os.Remove(j.RollbackPath)
+		return
+	}
+	if err := writeRollbackBundle(j.RollbackPath, j.Bundle); err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+}
+
+// writePatchSummary shows the current and the proposed resources of each container of the patch
+func writePatchSummary(w io.Writer, p ResourcePatch) {
+	fmt.Fprintf(w, "\nDeployment: %s (Namespace: %s)\n", p.Deployment, p.Namespace)
+	if p.Source != "" {
+		fmt.Fprintf(w, "  ⚠️  Gerenciado por %s: o patch será revertido na próxima sincronização\n", p.Source)
+	}
+	writePatchDiffs(w, p, "  ")
+	if p.Confidence != nil {
+		fmt.Fprintf(w, "  Confiança: %s\n", p.Confidence)
+	}
+	for _, note := range p.Notes {
+		fmt.Fprintf(w, "  ⚠️  %s\n", note)
+	}
+}
+
+// applyPatch submits the strategic merge patch of a deployment. With dryRun the API server runs
+// admission (LimitRanges, policies, webhooks) without persisting the change
+func applyPatch(clientset *kubernetes.Clientset, p ResourcePatch, dryRun bool) error {
+	data, err := p.strategicMergePatch()
+	if err != nil {
+		return fmt.Errorf("erro ao gerar patch de %s/%s: %v", p.Namespace, p.Deployment, err)
+	}
+	options := metav1.PatchOptions{}
+	if dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	_, err = clientset.AppsV1().Deployments(p.Namespace).Patch(context.TODO(), p.Deployment,
+		types.StrategicMergePatchType, data, options)
+	if err != nil {
+		return fmt.Errorf("erro ao aplicar patch em %s/%s: %v", p.Namespace, p.Deployment, err)
+	}
+	return nil
+}
+
+// applyRecorded captures the current resources, writes them to the rollback bundle and only then
+// applies the patch, recording it in the audit log right after
+func applyRecorded(clientset *kubernetes.Clientset, p ResourcePatch, result *ApplyResult, journal *ApplyJournal) error {
+	entry, err := captureRollback(clientset, p)
+	if err != nil {
+		return err
+	}
+	if err := journal.before(entry); err != nil {
+		return err
+	}
+	if err := applyPatch(clientset, p, false); err != nil {
+		journal.failed()
+		return err
+	}
+	journal.applied(p)
+	result.Applied = append(result.Applied, p)
+	return nil
+}
+
+// applyInteractive walks through each patch asking for confirmation before applying it. Each patch is
+// validated with a server-side dry run first, so only patches the API server accepts are offered
+func applyInteractive(clientset *kubernetes.Clientset, patches []ResourcePatch, in io.Reader, journal *ApplyJournal) *ApplyResult {
+	result := &ApplyResult{}
+	reader := bufio.NewReader(in)
+
+	for i, p := range patches {
+		writePatchSummary(os.Stdout, p)
+		if err := applyPatch(clientset, p, true); err != nil {
+			fmt.Printf("❌ Rejeitado na validação do servidor: %v\n", err)
+			result.Failed = append(result.Failed, p)
+			continue
+		}
+		fmt.Printf("Aplicar esta alteração (%d/%d)? [s]im/[n]ão/[q] sair: ", i+1, len(patches))
+
+		answer, err := reader.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if err != nil && answer == "" {
+			// Entrada encerrada: os itens restantes ficam pendentes
+			answer = "q"
+		}
+
+		switch answer {
+		case "s", "sim", "y", "yes":
+			if err := applyRecorded(clientset, p, result, journal); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				result.Failed = append(result.Failed, p)
+				continue
+			}
+			fmt.Printf("✅ Aplicado\n")
+		case "q", "sair":
+			result.Skipped = append(result.Skipped, patches[i:]...)
+			return result
+		default:
+			result.Skipped = append(result.Skipped, p)
+		}
+	}
+	return result
+}
+
+// applyAll submits every patch without prompting. The patches are always validated with a
+// server-side dry run first; unless dryRun is set, the accepted ones are then applied and recorded in
+// the journal
+func applyAll(clientset *kubernetes.Clientset, patches []ResourcePatch, dryRun bool, journal *ApplyJournal) *ApplyResult {
+	result := &ApplyResult{}
+	for _, p := range patches {
+		if err := applyPatch(clientset, p, true); err != nil {
+			fmt.Printf("❌ Rejeitado na validação do servidor: %v\n", err)
+			result.Failed = append(result.Failed, p)
+			continue
+		}
+		if dryRun {
+			fmt.Printf("✅ %s/%s aceito pelo servidor (dry-run)\n", p.Namespace, p.Deployment)
+			result.Applied = append(result.Applied, p)
+			continue
+		}
+		if err := applyRecorded(clientset, p, result, journal); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			result.Failed = append(result.Failed, p)
+			continue
+		}
+		fmt.Printf("✅ %s/%s aplicado\n", p.Namespace, p.Deployment)
+	}
+	return result
+}
+
+// writeSkippedPatches saves the patches that were not applied, to be reviewed later
+func writeSkippedPatches(path string, skipped []ResourcePatch) error {
+	data, err := json.MarshalIndent(skipped, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar patches ignorados: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("erro ao escrever patches ignorados: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// Tamanho máximo dos dados de cada ConfigMap de auditoria, abaixo do limite de 1MiB de um objeto no
+// etcd; acima dele as novas entradas vão para o próximo ConfigMap da sequência
+const auditConfigMapMaxBytes = 900 * 1024
+
+// Ações registradas no log de auditoria
+const (
+	auditActionApply    = "apply"
+	auditActionRollback = "rollback"
+)
+
+// AuditEntry is one change made to the cluster, as recorded in the audit log
+type AuditEntry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Action     string    `json:"action"`
+	Cluster    string    `json:"cluster"`
+	User       string    `json:"user"`
+	Namespace  string    `json:"namespace"`
+	Deployment string    `json:"deployment"`
+	// Valores anteriores e novos de cada container (apply)
+	Changes []ContainerRecommendation `json:"changes,omitempty"`
+	// Recursos restaurados (rollback)
+	Restored      []ContainerResources `json:"restored,omitempty"`
+	Justification string               `json:"justification"`
+}
+
+// auditUser returns the identity the API server sees for the current credentials, falling back to
+// the local user when SelfSubjectReview is not available
+func auditUser(clientset *kubernetes.Clientset) string {
+	review, err := clientset.AuthenticationV1().SelfSubjectReviews().Create(context.TODO(),
+		&authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err == nil && review.Status.UserInfo.Username != "" {
+		return review.Status.UserInfo.Username
+	}
+	if user := os.Getenv("USER"); user != "" {
+		return "local:" + user
+	}
+	return "desconhecido"
+}
+
+// auditFilePath returns the path of the audit log for the given (sanitized) context
+func auditFilePath(reportDir, sanitizedContext string) string {
+	return filepath.Join(reportDir, fmt.Sprintf("audit-%s.jsonl", sanitizedContext))
+}
+
+// appendAuditLog appends the entries to the JSONL audit file; existing lines are never rewritten
+func appendAuditLog(path string, entries []AuditEntry) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir log de auditoria: %v", err)
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("erro ao escrever log de auditoria: %v", err)
+		}
+	}
+	return nil
+}
+
+// appendAuditConfigMap adds the entries to a ConfigMap ("namespace/nome"), one key per entry. Entries
+// are never removed: when the ConfigMap would exceed auditConfigMapMaxBytes, they go to the next one of
+// the sequence ("nome", "nome-1", "nome-2"...), created as needed. The update is retried on conflicts,
+// so concurrent applies do not overwrite each other's entries
+func appendAuditConfigMap(clientset *kubernetes.Clientset, ref string, entries []AuditEntry) error {
+	namespace, name, found := strings.Cut(ref, "/")
+	if !found || namespace == "" || name == "" {
+		return fmt.Errorf("ConfigMap de auditoria inválido: %s (use namespace/nome)", ref)
+	}
+
+	values := make(map[string]string)
+	size := 0
+	for i, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("erro ao serializar entrada de auditoria: %v", err)
+		}
+		key := fmt.Sprintf("%s-%s-%s-%d", entry.Timestamp.UTC().Format("20060102T150405.000000000Z"), entry.Namespace, entry.Deployment, i)
+		values[key] = string(data)
+		size += len(key) + len(data)
+	}
+
+	configMaps := clientset.CoreV1().ConfigMaps(namespace)
+	written, rotated := name, false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Percorrer a sequência até o primeiro ConfigMap com espaço (ou inexistente)
+		for i := 0; ; i++ {
+			written = auditConfigMapName(name, i)
+			cm, err := configMaps.Get(context.TODO(), written, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: written, Namespace: namespace}, Data: values}
+				_, err = configMaps.Create(context.TODO(), cm, metav1.CreateOptions{})
+				if errors.IsAlreadyExists(err) {
+					// Criado por outro apply entre a leitura e a criação: tentar de novo
+					return errors.NewConflict(corev1.Resource("configmaps"), written, err)
+				}
+				rotated = err == nil && i > 0
+				return err
+			}
+			if err != nil {
+				return err
+			}
+			// Um ConfigMap vazio sempre recebe as entradas, mesmo acima do limite
+			if len(cm.Data) > 0 && auditConfigMapSize(cm.Data)+size > auditConfigMapMaxBytes {
+				continue
+			}
+			if cm.Data == nil {
+				cm.Data = make(map[string]string)
+			}
+			for key, value := range values {
+				cm.Data[key] = value
+			}
+			_, err = configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+			return err
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao gravar ConfigMap de auditoria: %v", err)
+	}
+	if rotated {
+		fmt.Printf("   - ConfigMap de auditoria cheio (limite de %d KiB): novas entradas em %s/%s\n", auditConfigMapMaxBytes/1024, namespace, written)
+	}
+	return nil
+}
+
+// auditConfigMapName returns the name of the i-th ConfigMap of the audit sequence: the configured name
+// and then "<nome>-1", "<nome>-2"...
+func auditConfigMapName(name string, i int) string {
+	if i == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s-%d", name, i)
+}
+
+// auditConfigMapSize returns the size of the keys and values of the ConfigMap data
+func auditConfigMapSize(data map[string]string) int {
+	size := 0
+	for key, value := range data {
+		size += len(key) + len(value)
+	}
+	return size
+}
+
+// AuditLog records each change in the audit file and, when configured, in the in-cluster ConfigMap
+// as soon as it is made, so an interrupted apply or rollback still leaves its record
+type AuditLog struct {
+	clientset     *kubernetes.Clientset
+	Path          string
+	ConfigMap     string
+	Cluster       string
+	User          string
+	Justification string
+	location      *time.Location
+	// Entradas gravadas no arquivo
+	Recorded int
+}
+
+func newAuditLog(clientset *kubernetes.Clientset, path, configMapRef, cluster, justification string, location *time.Location) *AuditLog {
+	return &AuditLog{clientset: clientset, Path: path, ConfigMap: configMapRef, Cluster: cluster,
+		User: auditUser(clientset), Justification: justification, location: location}
+}
+
+// record completes the entry with the time, cluster, user and justification and writes it
+func (a *AuditLog) record(entry AuditEntry) {
+	entry.Timestamp = time.Now().In(a.location)
+	entry.Cluster = a.Cluster
+	entry.User = a.User
+	entry.Justification = a.Justification
+	if err := appendAuditLog(a.Path, []AuditEntry{entry}); err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	} else {
+		a.Recorded++
+	}
+	if a.ConfigMap != "" {
+		if err := appendAuditConfigMap(a.clientset, a.ConfigMap, []AuditEntry{entry}); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+	}
+}
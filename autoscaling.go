@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+var vpaResource = schema.GroupVersionResource{
+	Group:    "autoscaling.k8s.io",
+	Version:  "v1",
+	Resource: "verticalpodautoscalers",
+}
+
+// VPAInfo holds the fields of a VerticalPodAutoscaler relevant to the analysis
+type VPAInfo struct {
+	Name                string
+	Namespace           string
+	TargetKind          string
+	TargetName          string
+	UpdateMode          string
+	ControlledResources []string
+}
+
+// listVPAs lists the VPAs in the cluster. A missing VPA CRD is not an error
+func listVPAs(dynamicClient dynamic.Interface) ([]VPAInfo, error) {
+	list, err := dynamicClient.Resource(vpaResource).Namespace("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	vpas := make([]VPAInfo, 0, len(list.Items))
+	for _, item := range list.Items {
+		vpa := VPAInfo{
+			Name:      item.GetName(),
+			Namespace: item.GetNamespace(),
+		}
+		vpa.TargetKind, _, _ = unstructured.NestedString(item.Object, "spec", "targetRef", "kind")
+		vpa.TargetName, _, _ = unstructured.NestedString(item.Object, "spec", "targetRef", "name")
+
+		// O modo padrão do VPA é "Auto" quando updatePolicy não é definido
+		vpa.UpdateMode, _, _ = unstructured.NestedString(item.Object, "spec", "updatePolicy", "updateMode")
+		if vpa.UpdateMode == "" {
+			vpa.UpdateMode = "Auto"
+		}
+
+		vpa.ControlledResources = vpaControlledResources(item.Object)
+		vpas = append(vpas, vpa)
+	}
+
+	return vpas, nil
+}
+
+// vpaControlledResources returns the resources managed by the VPA across its container policies
+func vpaControlledResources(obj map[string]interface{}) []string {
+	policies, found, _ := unstructured.NestedSlice(obj, "spec", "resourcePolicy", "containerPolicies")
+	if !found || len(policies) == 0 {
+		return []string{"cpu", "memory"}
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range policies {
+		policy, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if mode, _, _ := unstructured.NestedString(policy, "mode"); mode == "Off" {
+			continue
+		}
+		resources, found, _ := unstructured.NestedStringSlice(policy, "controlledResources")
+		if !found {
+			resources = []string{"cpu", "memory"}
+		}
+		for _, r := range resources {
+			seen[r] = true
+		}
+	}
+
+	controlled := make([]string, 0, len(seen))
+	for _, r := range []string{"cpu", "memory"} {
+		if seen[r] {
+			controlled = append(controlled, r)
+		}
+	}
+	return controlled
+}
+
+// hpaResourceMetrics returns the resource names (cpu/memory) the HPA scales on
+func hpaResourceMetrics(hpa *autoscalingv2.HorizontalPodAutoscaler) []string {
+	seen := make(map[corev1.ResourceName]bool)
+	for _, m := range hpa.Spec.Metrics {
+		switch m.Type {
+		case autoscalingv2.ResourceMetricSourceType:
+			if m.Resource != nil {
+				seen[m.Resource.Name] = true
+			}
+		case autoscalingv2.ContainerResourceMetricSourceType:
+			if m.ContainerResource != nil {
+				seen[m.ContainerResource.Name] = true
+			}
+		}
+	}
+
+	resources := make([]string, 0, len(seen))
+	for _, r := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if seen[r] {
+			resources = append(resources, string(r))
+		}
+	}
+	return resources
+}
+
+func detectHPAVPAConflicts(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface) ([]PerformanceRecommendation, error) {
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar HPAs: %v", err)
+	}
+
+	vpas, err := listVPAs(dynamicClient)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar VPAs: %v", err)
+	}
+
+	// Indexar VPAs em modo automático pelo workload alvo
+	vpaByTarget := make(map[string]VPAInfo)
+	for _, vpa := range vpas {
+		if vpa.UpdateMode == "Off" || vpa.UpdateMode == "Initial" {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s/%s", vpa.Namespace, vpa.TargetKind, vpa.TargetName)
+		vpaByTarget[key] = vpa
+	}
+
+	var conflicts []PerformanceRecommendation
+	for i := range hpas.Items {
+		hpa := &hpas.Items[i]
+		target := hpa.Spec.ScaleTargetRef
+		key := fmt.Sprintf("%s/%s/%s", hpa.Namespace, target.Kind, target.Name)
+
+		vpa, exists := vpaByTarget[key]
+		if !exists {
+			continue
+		}
+
+		var overlapping []string
+		for _, r := range hpaResourceMetrics(hpa) {
+			for _, c := range vpa.ControlledResources {
+				if r == c {
+					overlapping = append(overlapping, r)
+				}
+			}
+		}
+		if len(overlapping) == 0 {
+			continue
+		}
+
+		conflicts = append(conflicts, PerformanceRecommendation{
+			ResourceName: fmt.Sprintf("%s/%s", target.Kind, target.Name),
+			Namespace:    hpa.Namespace,
+			Issue: fmt.Sprintf("HPA %s e VPA %s (modo %s) atuam sobre %s - risco de oscilação",
+				hpa.Name, vpa.Name, vpa.UpdateMode, strings.Join(overlapping, ", ")),
+			Recommendation: "Configurar o HPA para escalar por métricas customizadas/externas, " +
+				"ou colocar o VPA em modo \"Off\"/\"Initial\", ou remover os recursos em conflito de controlledResources do VPA",
+			Priority: "Alta",
+		})
+	}
+
+	return conflicts, nil
+}
+
+func writeHPAVPAConflicts(w io.Writer, conflicts []PerformanceRecommendation) {
+	fmt.Fprintf(w, "\n=== Conflitos HPA x VPA ===\n")
+	fmt.Fprintf(w, "---------------------------\n")
+
+	if len(conflicts) == 0 {
+		fmt.Fprintf(w, "Nenhum conflito encontrado\n")
+		return
+	}
+
+	for i, c := range conflicts {
+		fmt.Fprintf(w, "\n%d. %s (Namespace: %s)\n", i+1, c.ResourceName, c.Namespace)
+		fmt.Fprintf(w, "   Problema: %s\n", c.Issue)
+		fmt.Fprintf(w, "   Recomendação: %s\n", c.Recommendation)
+		fmt.Fprintf(w, "   Prioridade: %s\n", c.Priority)
+	}
+}
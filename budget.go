@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Número de workloads listados como maiores contribuintes de um namespace acima do orçamento
+const budgetTopWorkloads = 5
+
+// BudgetAlert is a namespace whose projected monthly cost exceeds its budget
+type BudgetAlert struct {
+	Namespace    string
+	Budget       float64
+	Projected    float64
+	TopWorkloads []CostAllocation
+}
+
+// checkBudgets compares the monthly cost of each namespace with the budgets from the configuration
+func checkBudgets(budgets map[string]float64, report *CostReport) []BudgetAlert {
+	projected := make(map[string]float64)
+	for _, a := range report.ByNamespace {
+		projected[a.Name] = a.Total()
+	}
+
+	var alerts []BudgetAlert
+	for namespace, budget := range budgets {
+		if projected[namespace] <= budget {
+			continue
+		}
+		alert := BudgetAlert{Namespace: namespace, Budget: budget, Projected: projected[namespace]}
+		// ByWorkload já está ordenado pelo custo total
+		for _, a := range report.ByWorkload {
+			if a.Namespace != namespace {
+				continue
+			}
+			alert.TopWorkloads = append(alert.TopWorkloads, a)
+			if len(alert.TopWorkloads) == budgetTopWorkloads {
+				break
+			}
+		}
+		alerts = append(alerts, alert)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Projected-alerts[i].Budget > alerts[j].Projected-alerts[j].Budget
+	})
+	return alerts
+}
+
+func writeBudgetAlerts(w io.Writer, budgets map[string]float64, alerts []BudgetAlert) {
+	fmt.Fprintf(w, "\n=== Alertas de Orçamento ===\n")
+	fmt.Fprintf(w, "----------------------------\n")
+
+	if len(alerts) == 0 {
+		fmt.Fprintf(w, "Todos os %d namespaces com orçamento estão dentro do limite\n", len(budgets))
+		return
+	}
+
+	for _, alert := range alerts {
+		fmt.Fprintf(w, "\nNamespace: %s\n", alert.Namespace)
+		fmt.Fprintf(w, "Problema: Custo mensal projetado %.2f excede o orçamento %.2f (%.0f%% do orçamento)\n",
+			alert.Projected, alert.Budget, alert.Projected*100/alert.Budget)
+		fmt.Fprintf(w, "Maiores contribuintes:\n")
+		for _, a := range alert.TopWorkloads {
+			fmt.Fprintf(w, "- %s: %.2f (CPU %.2f, Memory %.2f, %d pods)\n", a.Name, a.Total(), a.CPUCost, a.MemoryCost, a.Pods)
+		}
+		fmt.Fprintf(w, "Recomendação: Aplicar as recomendações de requests dos maiores contribuintes ou revisar o orçamento do namespace\n")
+		fmt.Fprintf(w, "Prioridade: Alta\n")
+	}
+}
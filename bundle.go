@@ -0,0 +1,100 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// BundleSamples is the raw data collected during the run, stored in the bundle as samples.json
+type BundleSamples struct {
+	Source         string                  `json:"source"`
+	ClusterSamples []UsageSample           `json:"cluster_samples"`
+	Pods           map[string]*PodMetrics  `json:"pods"`
+	Nodes          map[string]*NodeMetrics `json:"nodes"`
+	// Uso dos workloads por dia da semana e hora
+	Hourly map[string]*HourlyUsage `json:"hourly,omitempty"`
+}
+
+// writeBundle zips the given files (directories are added recursively, under their own name) and
+// the raw samples into a single archive
+func writeBundle(path string, files []string, metrics *MetricsData, anonymizer *Anonymizer) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("erro ao criar pacote: %v", err)
+	}
+	defer out.Close()
+
+	archive := zip.NewWriter(out)
+	for _, file := range files {
+		root := filepath.Dir(file)
+		err := filepath.WalkDir(file, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			relative, err := filepath.Rel(root, name)
+			if err != nil {
+				return err
+			}
+			return addBundleFile(archive, filepath.ToSlash(relative), name)
+		})
+		if err != nil {
+			return fmt.Errorf("erro ao adicionar %s ao pacote: %v", file, err)
+		}
+	}
+
+	samples := BundleSamples{
+		Source:         metrics.Source,
+		ClusterSamples: metrics.ClusterSamples,
+		Pods:           metrics.PodMetrics,
+		Nodes:          metrics.NodeMetrics,
+		Hourly:         metrics.Hourly,
+	}
+	data, err := json.MarshalIndent(samples, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar amostras: %v", err)
+	}
+	w, err := archive.Create("samples.json")
+	if err != nil {
+		return fmt.Errorf("erro ao adicionar amostras ao pacote: %v", err)
+	}
+	if _, err := io.WriteString(w, anonymizer.Anonymize(string(data))); err != nil {
+		return fmt.Errorf("erro ao adicionar amostras ao pacote: %v", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("erro ao finalizar pacote: %v", err)
+	}
+	return nil
+}
+
+// addBundleFile copies a file into the archive under name
+func addBundleFile(archive *zip.Writer, name, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	w, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
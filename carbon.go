@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Valores padrão da estimativa de carbono, usados quando não configurados: média global da rede
+// elétrica e coeficientes médios por vCPU e por GiB de memória de provedores de nuvem
+const (
+	defaultGridIntensity  = 475.0
+	defaultPUE            = 1.135
+	idleWattsPerVCPU      = 0.74
+	maxWattsPerVCPU       = 3.5
+	memoryWattsPerGiB     = 0.392
+	instanceTypeLabel     = "node.kubernetes.io/instance-type"
+	betaInstanceTypeLabel = "beta.kubernetes.io/instance-type"
+)
+
+// NodeEmissions is the estimated power and monthly emissions of a node
+type NodeEmissions struct {
+	Name         string
+	InstanceType string
+	// Perfil configurado para o instance type (caso contrário, coeficientes padrão)
+	Profiled       bool
+	Utilization    float64
+	Watts          float64
+	MonthlyKgCO2e  float64
+	AllocatedKgCO2 float64
+}
+
+// NamespaceEmissions is the share of the node emissions attributed to a namespace
+type NamespaceEmissions struct {
+	Namespace     string
+	MonthlyKgCO2e float64
+	// Redução estimada com os requests recomendados
+	ReductionKgCO2e float64
+}
+
+// CarbonReport estimates the emissions of the cluster and the reduction from right-sizing
+type CarbonReport struct {
+	GridIntensity  float64
+	PUE            float64
+	TotalKgCO2e    float64
+	IdleKgCO2e     float64
+	Nodes          []NodeEmissions
+	Namespaces     []NamespaceEmissions
+	DrainableKgCO2 float64
+}
+
+func nodeInstanceType(node *corev1.Node) string {
+	if instanceType := node.Labels[instanceTypeLabel]; instanceType != "" {
+		return instanceType
+	}
+	return node.Labels[betaInstanceTypeLabel]
+}
+
+// nodeWatts estimates the power draw of a node at the given CPU utilization (0-1)
+func nodeWatts(node *corev1.Node, utilization float64, profiles map[string]PowerProfile) (float64, bool) {
+	if profile, exists := profiles[nodeInstanceType(node)]; exists {
+		return profile.IdleWatts + (profile.MaxWatts-profile.IdleWatts)*utilization, true
+	}
+	vcpus := float64(node.Status.Capacity.Cpu().MilliValue()) / 1000
+	gib := float64(node.Status.Capacity.Memory().Value()) / 1024 / 1024 / 1024
+	return vcpus*(idleWattsPerVCPU+(maxWattsPerVCPU-idleWattsPerVCPU)*utilization) + gib*memoryWattsPerGiB, false
+}
+
+// estimateEmissions converts the node power draw into monthly emissions and splits them among the
+// namespaces by CPU requests. The simulation, when available, gives the reduction from draining nodes
+func estimateEmissions(config CarbonConfig, nodes []corev1.Node, pods []corev1.Pod, metrics *MetricsData, deploymentIndex map[string]*DeploymentMetrics, patchIndex map[string]*ResourcePatch, simulation *SimulationResult) *CarbonReport {
+	report := &CarbonReport{GridIntensity: config.GridIntensity, PUE: config.PUE}
+	if report.GridIntensity == 0 {
+		report.GridIntensity = defaultGridIntensity
+	}
+	if report.PUE == 0 {
+		report.PUE = defaultPUE
+	}
+
+	podsByNode := make(map[string][]*corev1.Pod)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+
+	namespaces := make(map[string]*NamespaceEmissions)
+	nodeEmissions := make(map[string]float64)
+	for i := range nodes {
+		node := &nodes[i]
+		allocatableCPU := node.Status.Allocatable.Cpu().MilliValue()
+		if allocatableCPU == 0 {
+			continue
+		}
+
+		// Utilização de pico observada, ou a fração requisitada quando não há métricas do node
+		var usedCPU int64
+		if nm, exists := metrics.NodeMetrics[node.Name]; exists {
+			usedCPU = nm.MaxCPU
+		} else {
+			for _, pod := range podsByNode[node.Name] {
+				cpu, _ := podRequests(pod)
+				usedCPU += cpu
+			}
+		}
+		utilization := min(float64(usedCPU)/float64(allocatableCPU), 1)
+
+		watts, profiled := nodeWatts(node, utilization, config.Profiles)
+		kwh := watts * report.PUE * hoursPerMonth / 1000
+		ne := NodeEmissions{
+			Name:          node.Name,
+			InstanceType:  nodeInstanceType(node),
+			Profiled:      profiled,
+			Utilization:   utilization,
+			Watts:         watts,
+			MonthlyKgCO2e: kwh * report.GridIntensity / 1000,
+		}
+		nodeEmissions[node.Name] = ne.MonthlyKgCO2e
+		report.TotalKgCO2e += ne.MonthlyKgCO2e
+
+		for _, pod := range podsByNode[node.Name] {
+			cpu, _ := podRequests(pod)
+			if cpu == 0 {
+				continue
+			}
+			share := ne.MonthlyKgCO2e * float64(cpu) / float64(allocatableCPU)
+			ne.AllocatedKgCO2 += share
+
+			nse, exists := namespaces[pod.Namespace]
+			if !exists {
+				nse = &NamespaceEmissions{Namespace: pod.Namespace}
+				namespaces[pod.Namespace] = nse
+			}
+			nse.MonthlyKgCO2e += share
+
+			proposedCPU, _ := proposedPodRequests(pod, podPatch(pod, deploymentIndex, patchIndex))
+			if proposedCPU < cpu {
+				nse.ReductionKgCO2e += share * float64(cpu-proposedCPU) / float64(cpu)
+			}
+		}
+		if idle := ne.MonthlyKgCO2e - ne.AllocatedKgCO2; idle > 0 {
+			report.IdleKgCO2e += idle
+		}
+		report.Nodes = append(report.Nodes, ne)
+	}
+
+	if simulation != nil {
+		for _, name := range simulation.DrainableNodes {
+			report.DrainableKgCO2 += nodeEmissions[name]
+		}
+	}
+
+	for _, nse := range namespaces {
+		report.Namespaces = append(report.Namespaces, *nse)
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].MonthlyKgCO2e > report.Namespaces[j].MonthlyKgCO2e
+	})
+	sort.Slice(report.Nodes, func(i, j int) bool {
+		return report.Nodes[i].MonthlyKgCO2e > report.Nodes[j].MonthlyKgCO2e
+	})
+
+	return report
+}
+
+func writeCarbonReport(w io.Writer, report *CarbonReport) {
+	fmt.Fprintf(w, "\n=== Pegada de Carbono Estimada ===\n")
+	fmt.Fprintf(w, "----------------------------------\n")
+
+	fmt.Fprintf(w, "Intensidade da rede: %.0f gCO2e/kWh | PUE: %.3f\n", report.GridIntensity, report.PUE)
+	fmt.Fprintf(w, "Emissões mensais estimadas: %.1f kgCO2e (capacidade ociosa: %.1f kgCO2e)\n",
+		report.TotalKgCO2e, report.IdleKgCO2e)
+
+	fmt.Fprintf(w, "\nPor node:\n")
+	for _, ne := range report.Nodes {
+		profile := "coeficientes padrão"
+		if ne.Profiled {
+			profile = "perfil configurado"
+		}
+		instanceType := ne.InstanceType
+		if instanceType == "" {
+			instanceType = "desconhecido"
+		}
+		fmt.Fprintf(w, "- %s (%s, %s): %.0fW a %.0f%% de CPU, %.1f kgCO2e/mês\n",
+			ne.Name, instanceType, profile, ne.Watts, ne.Utilization*100, ne.MonthlyKgCO2e)
+	}
+
+	var reduction float64
+	fmt.Fprintf(w, "\nPor namespace (kgCO2e/mês):\n")
+	for _, nse := range report.Namespaces {
+		fmt.Fprintf(w, "- %s: %.1f (redução com as recomendações: %.1f)\n", nse.Namespace, nse.MonthlyKgCO2e, nse.ReductionKgCO2e)
+		reduction += nse.ReductionKgCO2e
+	}
+
+	fmt.Fprintf(w, "\nRedução estimada aplicando as recomendações de requests: %.1f kgCO2e/mês (%.0f%%)\n",
+		reduction, costShare(reduction, report.TotalKgCO2e))
+	if report.DrainableKgCO2 > 0 {
+		fmt.Fprintf(w, "Redução ao desligar os nodes drenáveis da simulação: %.1f kgCO2e/mês\n", report.DrainableKgCO2)
+	}
+	fmt.Fprintf(w, "Observação: estimativa baseada na utilização de pico de CPU observada; use perfis por instance type "+
+		"no arquivo de configuração para maior precisão\n")
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Limites usados para classificar a confiança das recomendações
+const (
+	// Leituras do pod mais medido do deployment
+	confidenceHighSamples   = 30
+	confidenceMediumSamples = 10
+	// Tempo entre a primeira e a última leitura do pod mais medido
+	confidenceHighWindow   = 24 * time.Hour
+	confidenceMediumWindow = time.Hour
+	// Coeficiente de variação (desvio padrão / média) das leituras de um container
+	confidenceHighVariation   = 0.25
+	confidenceMediumVariation = 0.75
+)
+
+// ConfidenceLevel ranks how much a recommendation can be trusted
+type ConfidenceLevel int
+
+const (
+	ConfidenceLow ConfidenceLevel = iota
+	ConfidenceMedium
+	ConfidenceHigh
+)
+
+var confidenceNames = []string{"baixa", "média", "alta"}
+
+func (l ConfidenceLevel) String() string {
+	return confidenceNames[l]
+}
+
+// MarshalText writes the level by name in the JSON outputs
+func (l ConfidenceLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// RecommendationConfidence is how much the usage behind the recommendation of a deployment can be trusted
+type RecommendationConfidence struct {
+	Level   ConfidenceLevel `json:"level"`
+	Samples int             `json:"samples"`
+	Window  time.Duration   `json:"-"`
+	// Janela em segundos, para as saídas em JSON
+	WindowSeconds int64 `json:"window_seconds"`
+	// Maior coeficiente de variação entre os containers do deployment
+	Variation float64 `json:"variation"`
+	// Fatores que reduziram a confiança
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+func (c *RecommendationConfidence) String() string {
+	if c == nil {
+		return ""
+	}
+	s := fmt.Sprintf("%s (%d leituras em %v, variação de %.0f%%)", c.Level, c.Samples, c.Window.Round(time.Second), c.Variation*100)
+	if len(c.Reasons) > 0 {
+		s += ": " + strings.Join(c.Reasons, ", ")
+	}
+	return s
+}
+
+// usageVariation accumulates the readings of a series to compute its coefficient of variation
+type usageVariation struct {
+	count      float64
+	sum, sumSq float64
+}
+
+func (v *usageVariation) add(value int64) {
+	v.count++
+	v.sum += float64(value)
+	v.sumSq += float64(value) * float64(value)
+}
+
+func (v *usageVariation) merge(other usageVariation) {
+	v.count += other.count
+	v.sum += other.sum
+	v.sumSq += other.sumSq
+}
+
+// mean returns the average of the readings (0 without readings)
+func (v usageVariation) mean() int64 {
+	if v.count == 0 {
+		return 0
+	}
+	return int64(v.sum / v.count)
+}
+
+// coefficient returns the standard deviation relative to the mean (0 without readings or usage)
+func (v usageVariation) coefficient() float64 {
+	if v.count < 2 || v.sum == 0 {
+		return 0
+	}
+	mean := v.sum / v.count
+	variance := max(v.sumSq/v.count-mean*mean, 0)
+	return math.Sqrt(variance) / mean
+}
+
+// assessConfidence scores the recommendation of each deployment with metrics by the number of readings,
+// the length of the window they cover and how much the usage of its containers varied. Each criterion
+// is worth up to two points; five or more is high confidence, three or four medium. Containers with
+// overrides do not count
+func assessConfidence(deploymentMetrics map[string]*DeploymentMetrics, metrics *MetricsData, overrides ContainerOverrides) {
+	for _, dm := range deploymentMetrics {
+		if dm.MaxCPU == 0 && dm.MaxMemory == 0 {
+			continue
+		}
+		c := &RecommendationConfidence{}
+		cpu := make(map[string]*usageVariation)
+		memory := make(map[string]*usageVariation)
+		for _, podName := range dm.Pods {
+			pm, exists := metrics.PodMetrics[podName]
+			if !exists || pm.Namespace != dm.Namespace {
+				continue
+			}
+			c.Samples = max(c.Samples, pm.Samples)
+			c.Window = max(c.Window, pm.LastSeen.Sub(pm.FirstSeen))
+			for name, cm := range pm.Containers {
+				if overrides.skip(dm.Namespace, name) {
+					continue
+				}
+				if cpu[name] == nil {
+					cpu[name], memory[name] = &usageVariation{}, &usageVariation{}
+				}
+				cpu[name].merge(cm.cpuVariation)
+				memory[name].merge(cm.memoryVariation)
+			}
+		}
+		c.WindowSeconds = int64(c.Window.Seconds())
+		for name := range cpu {
+			c.Variation = max(c.Variation, cpu[name].coefficient(), memory[name].coefficient())
+		}
+
+		score := 0
+		switch {
+		case c.Samples >= confidenceHighSamples:
+			score += 2
+		case c.Samples >= confidenceMediumSamples:
+			score++
+			c.Reasons = append(c.Reasons, "poucas leituras")
+		default:
+			c.Reasons = append(c.Reasons, fmt.Sprintf("menos de %d leituras", confidenceMediumSamples))
+		}
+		switch {
+		case c.Window >= confidenceHighWindow:
+			score += 2
+		case c.Window >= confidenceMediumWindow:
+			score++
+			c.Reasons = append(c.Reasons, fmt.Sprintf("janela menor que %v", confidenceHighWindow))
+		default:
+			c.Reasons = append(c.Reasons, fmt.Sprintf("janela menor que %v", confidenceMediumWindow))
+		}
+		switch {
+		case c.Variation <= confidenceHighVariation:
+			score += 2
+		case c.Variation <= confidenceMediumVariation:
+			score++
+			c.Reasons = append(c.Reasons, "uso variável")
+		default:
+			c.Reasons = append(c.Reasons, "uso muito variável")
+		}
+
+		switch {
+		case score >= 5:
+			c.Level = ConfidenceHigh
+		case score >= 3:
+			c.Level = ConfidenceMedium
+		}
+		dm.Confidence = c
+	}
+}
+
+// countLowConfidence counts the deployments whose recommendation has low confidence
+func countLowConfidence(deploymentMetrics map[string]*DeploymentMetrics) int {
+	count := 0
+	for _, dm := range deploymentMetrics {
+		if dm.Confidence != nil && dm.Confidence.Level == ConfidenceLow {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Config is the optional configuration file passed with -config (YAML or JSON)
+type Config struct {
+	// Orçamento mensal por namespace, na mesma moeda dos preços do modelo de custos
+	Budgets map[string]float64 `json:"budgets,omitempty"`
+	// Namespaces de produção (aceita curingas, ex: "prod-*"), onde deployments com uma réplica são apontados
+	ProductionNamespaces []string `json:"production_namespaces,omitempty"`
+	// Parâmetros da estimativa de emissões de carbono
+	Carbon CarbonConfig `json:"carbon,omitempty"`
+	// Criação de tickets no Jira para os problemas encontrados
+	Jira JiraConfig `json:"jira,omitempty"`
+	// Criação de issues em um repositório do GitHub ou GitLab
+	Issues IssuesConfig `json:"issues,omitempty"`
+	// Alertas no PagerDuty ou Opsgenie para riscos iminentes
+	Alerting AlertingConfig `json:"alerting,omitempty"`
+	// Banco de séries temporais (InfluxDB ou TimescaleDB) que recebe cada leitura da coleta
+	Sink SinkConfig `json:"sink,omitempty"`
+	// Destinos notificados quando o relatório fica pronto, cada um com seu formato
+	Webhooks []WebhookConfig `json:"webhooks,omitempty"`
+	// Containers com recomendação fixa ou excluídos (ex: sidecars de service mesh gerenciados centralmente)
+	Containers ContainerOverrides `json:"containers,omitempty"`
+	// Expressões (sintaxe do CEL) avaliadas sobre os deployments, os problemas e o resumo da execução
+	Policies []PolicyRule `json:"policies,omitempty"`
+}
+
+// CarbonConfig holds the emission factor and the power profiles of the instance types
+type CarbonConfig struct {
+	// Intensidade de carbono da rede elétrica, em gCO2e/kWh
+	GridIntensity float64 `json:"grid_intensity,omitempty"`
+	// Eficiência do datacenter (Power Usage Effectiveness)
+	PUE float64 `json:"pue,omitempty"`
+	// Perfis de consumo indexados pelo instance type do node
+	Profiles map[string]PowerProfile `json:"profiles,omitempty"`
+}
+
+// PowerProfile is the power draw of an instance type at idle and at full CPU utilization
+type PowerProfile struct {
+	IdleWatts float64 `json:"idle_watts"`
+	MaxWatts  float64 `json:"max_watts"`
+}
+
+// loadConfig reads the configuration file. An empty path yields an empty configuration
+func loadConfig(path string) (*Config, error) {
+	config := &Config{}
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler arquivo de configuração: %v", err)
+	}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("erro ao analisar arquivo de configuração %s: %v", path, err)
+	}
+
+	for _, problem := range config.problems() {
+		if !problem.Warning {
+			return nil, problem
+		}
+	}
+	return config, nil
+}
+
+// ConfigProblem is an invalid or conflicting setting of the configuration file
+type ConfigProblem struct {
+	// Caminho da chave, com os índices das listas (ex: webhooks, 0, url)
+	Path    []string
+	Message string
+	// Opções conflitantes ou ignoradas, que não impedem a execução
+	Warning bool
+	// Posição no arquivo, preenchida pelo comando config validate
+	Line   int
+	Column int
+}
+
+// field formats the path of the key (ex: webhooks[0].url)
+func (p ConfigProblem) field() string {
+	var b strings.Builder
+	for _, segment := range p.Path {
+		if _, err := strconv.Atoi(segment); err == nil && b.Len() > 0 {
+			fmt.Fprintf(&b, "[%s]", segment)
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(segment)
+	}
+	return b.String()
+}
+
+func (p ConfigProblem) Error() string {
+	if len(p.Path) == 0 {
+		return p.Message
+	}
+	return p.field() + ": " + p.Message
+}
+
+// problems checks the values of the configuration and the options that conflict with each other or are
+// ignored. Conflicts are warnings; everything else prevents the run
+func (c *Config) problems() []ConfigProblem {
+	var problems []ConfigProblem
+	invalid := func(message string, path ...string) {
+		problems = append(problems, ConfigProblem{Path: path, Message: message})
+	}
+	conflict := func(message string, path ...string) {
+		problems = append(problems, ConfigProblem{Path: path, Message: message, Warning: true})
+	}
+
+	namespaces := make([]string, 0, len(c.Budgets))
+	for namespace := range c.Budgets {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		if budget := c.Budgets[namespace]; budget <= 0 {
+			invalid(fmt.Sprintf("orçamento deve ser positivo: %.2f", budget), "budgets", namespace)
+		}
+	}
+
+	for i, pattern := range c.ProductionNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			invalid(fmt.Sprintf("padrão inválido: %q", pattern), "production_namespaces", strconv.Itoa(i))
+		}
+	}
+
+	if c.Carbon.GridIntensity < 0 {
+		invalid("deve ser positivo", "carbon", "grid_intensity")
+	}
+	if c.Carbon.PUE != 0 && c.Carbon.PUE < 1 {
+		invalid("deve ser maior ou igual a 1", "carbon", "pue")
+	}
+	instanceTypes := make([]string, 0, len(c.Carbon.Profiles))
+	for instanceType := range c.Carbon.Profiles {
+		instanceTypes = append(instanceTypes, instanceType)
+	}
+	sort.Strings(instanceTypes)
+	for _, instanceType := range instanceTypes {
+		profile := c.Carbon.Profiles[instanceType]
+		if profile.IdleWatts < 0 {
+			invalid("deve ser positivo", "carbon", "profiles", instanceType, "idle_watts")
+		}
+		if profile.MaxWatts < profile.IdleWatts {
+			invalid("deve ser maior ou igual a idle_watts", "carbon", "profiles", instanceType, "max_watts")
+		}
+	}
+
+	if c.Jira.URL != "" && c.Jira.Project == "" {
+		invalid("project é obrigatório", "jira")
+	}
+	if c.Jira.MinSeverity != "" {
+		if _, err := parseSeverity(c.Jira.MinSeverity); err != nil {
+			invalid(err.Error(), "jira", "min_severity")
+		}
+	}
+	if c.Jira.URL == "" && (c.Jira.Project != "" || c.Jira.IssueType != "" || c.Jira.MinSeverity != "" || len(c.Jira.Labels) > 0 || len(c.Jira.OwnerLabels) > 0) {
+		conflict("sem url a seção é ignorada e nenhum ticket é aberto", "jira")
+	}
+
+	if c.Issues.Provider != "" {
+		if c.Issues.Provider != issueProviderGitHub && c.Issues.Provider != issueProviderGitLab {
+			invalid("deve ser github ou gitlab", "issues", "provider")
+		}
+		if c.Issues.Repository == "" {
+			invalid("repository é obrigatório", "issues")
+		}
+	} else if c.Issues.Repository != "" || c.Issues.URL != "" || c.Issues.MinSeverity != "" || len(c.Issues.Labels) > 0 {
+		conflict("sem provider a seção é ignorada e nenhuma issue é aberta", "issues")
+	}
+	if c.Issues.MinSeverity != "" {
+		if _, err := parseSeverity(c.Issues.MinSeverity); err != nil {
+			invalid(err.Error(), "issues", "min_severity")
+		}
+	}
+
+	if c.Alerting.Provider != "" && c.Alerting.Provider != alertProviderPagerDuty && c.Alerting.Provider != alertProviderOpsgenie {
+		invalid("deve ser pagerduty ou opsgenie", "alerting", "provider")
+	}
+	if c.Alerting.OvercommitPct < 0 {
+		invalid("deve ser positivo", "alerting", "overcommit_pct")
+	} else if c.Alerting.OvercommitPct > 0 && c.Alerting.OvercommitPct < 100 {
+		conflict("abaixo de 100% nodes sem sobrecomprometimento também são apontados como risco", "alerting", "overcommit_pct")
+	}
+	if c.Alerting.Provider == "" && c.Alerting.URL != "" {
+		conflict("sem provider nenhum alerta é enviado", "alerting", "url")
+	}
+
+	if err := c.Sink.validate(); err != nil {
+		invalid(err.Error(), "sink")
+	}
+	switch c.Sink.Type {
+	case "":
+		if c.Sink != (SinkConfig{}) {
+			conflict("sem type a seção é ignorada e as amostras não são gravadas", "sink")
+		}
+	case sinkInfluxDB:
+		if c.Sink.DSN != "" || c.Sink.Table != "" {
+			conflict("dsn e table valem apenas para timescaledb", "sink", "type")
+		}
+	case sinkTimescaleDB:
+		if c.Sink.URL != "" || c.Sink.Org != "" || c.Sink.Bucket != "" || c.Sink.Database != "" {
+			conflict("url, org, bucket e database valem apenas para influxdb", "sink", "type")
+		}
+	}
+
+	for i, webhook := range c.Webhooks {
+		index := strconv.Itoa(i)
+		if err := webhook.validate(); err != nil {
+			invalid(err.Error(), "webhooks", index)
+		}
+		if webhook.Format != "" && webhook.Template != "" {
+			conflict("template substitui o formato pronto", "webhooks", index, "format")
+		}
+	}
+
+	for i := range c.Containers {
+		if err := c.Containers[i].validate(); err != nil {
+			invalid(err.Error(), "containers", strconv.Itoa(i))
+		}
+	}
+
+	problems = append(problems, policyProblems(c.Policies)...)
+	return problems
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+	yaml3 "sigs.k8s.io/yaml/goyaml.v3"
+)
+
+// validateConfigFile checks the configuration file without connecting to the cluster: syntax, unknown
+// keys and values of the wrong type first, then the settings themselves. Each problem carries the line
+// and column of the key in the file
+func validateConfigFile(path string) ([]ConfigProblem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler arquivo de configuração: %v", err)
+	}
+
+	var root yaml3.Node
+	if err := yaml3.Unmarshal(data, &root); err != nil {
+		return []ConfigProblem{{Message: err.Error()}}, nil
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	document := root.Content[0]
+
+	var problems []ConfigProblem
+	checkConfigNode(document, reflect.TypeOf(Config{}), nil, &problems)
+	if len(problems) == 0 {
+		config := &Config{}
+		if err := yaml.UnmarshalStrict(data, config); err != nil {
+			return []ConfigProblem{{Message: err.Error()}}, nil
+		}
+		for _, problem := range config.problems() {
+			problem.Line, problem.Column = locateConfigKey(document, problem.Path)
+			problems = append(problems, problem)
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+	return problems, nil
+}
+
+// configFields maps the keys of a configuration struct to its fields
+func configFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = t.Field(i).Type
+		}
+	}
+	return fields
+}
+
+// checkConfigNode compares a node of the file with the type it is decoded into, reporting unknown keys
+// and values of the wrong kind at their position
+func checkConfigNode(node *yaml3.Node, t reflect.Type, path []string, problems *[]ConfigProblem) {
+	if node.Kind == yaml3.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml3.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	report := func(message string) {
+		*problems = append(*problems, ConfigProblem{Path: path, Message: message, Line: node.Line, Column: node.Column})
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml3.MappingNode {
+			report("esperado um objeto")
+			return
+		}
+		fields := configFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldType, exists := fields[key.Value]
+			if !exists {
+				valid := make([]string, 0, len(fields))
+				for name := range fields {
+					valid = append(valid, name)
+				}
+				sort.Strings(valid)
+				*problems = append(*problems, ConfigProblem{
+					Path:    append(append([]string{}, path...), key.Value),
+					Message: fmt.Sprintf("chave desconhecida (válidas: %s)", strings.Join(valid, ", ")),
+					Line:    key.Line,
+					Column:  key.Column,
+				})
+				continue
+			}
+			checkConfigNode(value, fieldType, append(append([]string{}, path...), key.Value), problems)
+		}
+	case reflect.Map:
+		if node.Kind != yaml3.MappingNode {
+			report("esperado um objeto")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkConfigNode(node.Content[i+1], t.Elem(), append(append([]string{}, path...), node.Content[i].Value), problems)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml3.SequenceNode {
+			report("esperada uma lista")
+			return
+		}
+		for i, item := range node.Content {
+			checkConfigNode(item, t.Elem(), append(append([]string{}, path...), strconv.Itoa(i)), problems)
+		}
+	case reflect.String:
+		if node.Kind != yaml3.ScalarNode || node.Tag != "!!str" {
+			report("esperado um texto (use aspas em valores numéricos)")
+		}
+	case reflect.Int, reflect.Int64:
+		if node.Kind != yaml3.ScalarNode || node.Tag != "!!int" {
+			report("esperado um número inteiro")
+		}
+	case reflect.Float64:
+		if node.Kind != yaml3.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") {
+			report("esperado um número")
+		}
+	case reflect.Bool:
+		if node.Kind != yaml3.ScalarNode || node.Tag != "!!bool" {
+			report("esperado true ou false")
+		}
+	}
+}
+
+// locateConfigKey returns the position of the key in the file, or of its closest parent present
+func locateConfigKey(node *yaml3.Node, path []string) (int, int) {
+	line, column := node.Line, node.Column
+	for _, segment := range path {
+		if node.Kind == yaml3.AliasNode {
+			node = node.Alias
+		}
+		var next *yaml3.Node
+		switch node.Kind {
+		case yaml3.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == segment {
+					line, column = node.Content[i].Line, node.Content[i].Column
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml3.SequenceNode:
+			if index, err := strconv.Atoi(segment); err == nil && index < len(node.Content) {
+				next = node.Content[index]
+				line, column = next.Line, next.Column
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line, column
+}
+
+// printConfigProblems prints the problems found by config validate and returns how many prevent the run
+func printConfigProblems(path string, problems []ConfigProblem) int {
+	errors := 0
+	for _, problem := range problems {
+		location := path
+		if problem.Line > 0 {
+			location = fmt.Sprintf("%s:%d:%d", path, problem.Line, problem.Column)
+		}
+		if problem.Warning {
+			fmt.Printf("⚠️  %s: %v\n", location, problem)
+			continue
+		}
+		errors++
+		fmt.Printf("❌ %s: %v\n", location, problem)
+	}
+	return errors
+}
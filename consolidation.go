@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Percentual de capacidade ociosa (após as recomendações) a partir do qual a consolidação é sugerida
+const consolidationWastePct = 40.0
+
+// DrainStep is a node to cordon and drain, with the workload currently running on it
+type DrainStep struct {
+	Node       string
+	Pods       int
+	RequestCPU int64
+	RequestMem int64
+}
+
+// ConsolidationPlan lists the nodes that can be removed once the recommendations are applied
+type ConsolidationPlan struct {
+	WastePct float64
+	// Todos os pods continuam agendáveis com os requests propostos
+	Feasible  bool
+	Steps     []DrainStep
+	Remaining []SimulatedNode
+}
+
+// planConsolidation derives the drain order from the scheduling simulation: nodes with the
+// fewest requests are drained first, so each step moves as little workload as possible
+func planConsolidation(pods []corev1.Pod, nodes []corev1.Node, simulation *SimulationResult) *ConsolidationPlan {
+	plan := &ConsolidationPlan{Feasible: len(simulation.UnschedulablePods) == 0}
+
+	allocatableCPU, allocatableMemory := clusterAllocatable(nodes)
+	cpuWaste := 100 - percent(simulation.ProposedCPURequest, allocatableCPU)
+	memoryWaste := 100 - percent(simulation.ProposedMemRequest, allocatableMemory)
+	plan.WastePct = min(cpuWaste, memoryWaste)
+	if plan.WastePct < consolidationWastePct || len(simulation.DrainableNodes) == 0 {
+		return plan
+	}
+
+	drainable := make(map[string]bool)
+	for _, name := range simulation.DrainableNodes {
+		drainable[name] = true
+	}
+
+	steps := make(map[string]*DrainStep)
+	for _, name := range simulation.DrainableNodes {
+		steps[name] = &DrainStep{Node: name}
+	}
+	for i := range pods {
+		pod := &pods[i]
+		step, exists := steps[pod.Spec.NodeName]
+		if !exists || isDaemonSetPod(pod) || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		cpu, memory := podRequests(pod)
+		step.Pods++
+		step.RequestCPU += cpu
+		step.RequestMem += memory
+	}
+
+	for _, step := range steps {
+		plan.Steps = append(plan.Steps, *step)
+	}
+	sort.Slice(plan.Steps, func(i, j int) bool {
+		if plan.Steps[i].RequestCPU != plan.Steps[j].RequestCPU {
+			return plan.Steps[i].RequestCPU < plan.Steps[j].RequestCPU
+		}
+		return plan.Steps[i].Node < plan.Steps[j].Node
+	})
+
+	for _, n := range simulation.Nodes {
+		if !drainable[n.Name] {
+			plan.Remaining = append(plan.Remaining, n)
+		}
+	}
+
+	return plan
+}
+
+func writeConsolidationPlan(w io.Writer, plan *ConsolidationPlan) {
+	fmt.Fprintf(w, "\n=== Plano de Consolidação de Nodes ===\n")
+	fmt.Fprintf(w, "--------------------------------------\n")
+	fmt.Fprintf(w, "Capacidade ociosa com os requests recomendados: %.0f%%\n", plan.WastePct)
+
+	if plan.WastePct < consolidationWastePct {
+		fmt.Fprintf(w, "Abaixo do limite de %.0f%%: consolidação não recomendada\n", consolidationWastePct)
+		return
+	}
+	if len(plan.Steps) == 0 {
+		fmt.Fprintf(w, "Nenhum node pode ser liberado respeitando taints, limites de pods e o tamanho dos pods\n")
+		return
+	}
+	if !plan.Feasible {
+		fmt.Fprintf(w, "⚠️  Nem todos os pods seriam agendados na simulação; resolva os pods não agendáveis antes de consolidar\n")
+	}
+
+	fmt.Fprintf(w, "\nPré-requisito: aplicar as recomendações de requests antes de drenar os nodes\n")
+	fmt.Fprintf(w, "\nOrdem sugerida:\n")
+	for i, step := range plan.Steps {
+		fmt.Fprintf(w, "%d. %s: %d pods a realocar (CPU %s, Memory %s em requests atuais)\n",
+			i+1, step.Node, step.Pods, formatCPU(step.RequestCPU), formatMemory(step.RequestMem))
+		fmt.Fprintf(w, "   kubectl cordon %s && kubectl drain %s --ignore-daemonsets --delete-emptydir-data\n", step.Node, step.Node)
+	}
+
+	fmt.Fprintf(w, "\nUtilização resultante dos nodes restantes (requests):\n")
+	for _, n := range plan.Remaining {
+		fmt.Fprintf(w, "- %s: CPU %.0f%% (%s/%s), Memory %.0f%% (%s/%s), %d pods (sem DaemonSets)\n",
+			n.Name, percent(n.UsedCPU, n.CPU), formatCPU(n.UsedCPU), formatCPU(n.CPU),
+			percent(n.UsedMemory, n.Memory), formatMemory(n.UsedMemory), formatMemory(n.Memory), n.Pods)
+	}
+	fmt.Fprintf(w, "\nRecomendação: Drenar um node por vez, verificando PodDisruptionBudgets e a saúde dos workloads entre os passos\n")
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Consultas de saturação do API server e do etcd (métricas expostas pelo próprio control plane)
+const (
+	promAPIServerRequestRateQuery = `sum(rate(apiserver_request_total[5m]))`
+	promAPIServerThrottledQuery   = `sum(rate(apiserver_request_total{code="429"}[5m]))`
+	promAPIServerRejectedQuery    = `sum(rate(apiserver_flowcontrol_rejected_requests_total[5m]))`
+	promAPIServerInflightQuery    = `max by (request_kind) (apiserver_current_inflight_requests)`
+	promAPIServerLatencyQuery     = `histogram_quantile(0.99, sum by (le, verb) (rate(apiserver_request_duration_seconds_bucket{verb!~"WATCH|CONNECT"}[5m])))`
+	promEtcdDBSizeQuery           = `max(etcd_mvcc_db_total_size_in_bytes or apiserver_storage_db_total_size_in_bytes or apiserver_storage_size_bytes)`
+	promEtcdQuotaQuery            = `max(etcd_server_quota_backend_bytes)`
+	promEtcdFsyncQuery            = `histogram_quantile(0.99, sum by (le) (rate(etcd_disk_wal_fsync_duration_seconds_bucket[5m])))`
+	promEtcdLeaderChangesQuery    = `max(increase(etcd_server_leader_changes_seen_total[1h]))`
+)
+
+// Limites de saturação do control plane
+const (
+	// Padrões de --max-requests-inflight e --max-mutating-requests-inflight
+	apiServerMaxReadOnlyInflight = 400
+	apiServerMaxMutatingInflight = 200
+	apiServerInflightWarnPct     = 80
+	// SLO de latência do Kubernetes para chamadas a um único objeto
+	apiServerLatencySLOSeconds = 1.0
+	// Quota padrão do etcd (--quota-backend-bytes) quando a métrica não está disponível
+	etcdDefaultQuotaBytes = 2 * 1024 * 1024 * 1024
+	etcdDBSizeWarnPct     = 80
+	// Fsync do WAL acima de 10ms indica disco lento para o etcd
+	etcdFsyncWarnSeconds = 0.01
+)
+
+// ControlPlaneReport summarizes the API server saturation and the etcd size, from the control-plane
+// metrics scraped by Prometheus. Metrics the cluster does not expose are left out
+type ControlPlaneReport struct {
+	RequestRate      float64
+	ThrottledRate    float64
+	RejectedRate     float64
+	InflightReadOnly float64
+	InflightMutating float64
+	// Latência p99 por verbo, em segundos
+	LatencyP99        map[string]float64
+	EtcdDBSize        float64
+	EtcdQuota         float64
+	EtcdFsyncP99      float64
+	EtcdLeaderChanges float64
+	// Métricas encontradas no Prometheus
+	Available map[string]bool
+	Issues    []PerformanceRecommendation
+}
+
+// promScalar runs a query expected to return a single value
+func promScalar(baseURL, query string) (float64, bool, error) {
+	result, err := queryPrometheus(baseURL, query)
+	if err != nil || len(result) == 0 {
+		return 0, false, err
+	}
+	// Histogramas sem amostras na janela retornam NaN
+	value, err := result[0].value()
+	if err != nil || math.IsNaN(value) {
+		return 0, false, nil
+	}
+	return value, true, nil
+}
+
+// analyzeControlPlane queries the API server and etcd metrics. It returns nil when Prometheus does
+// not scrape the control plane (managed clusters usually hide it)
+func analyzeControlPlane(baseURL string) (*ControlPlaneReport, error) {
+	report := &ControlPlaneReport{LatencyP99: make(map[string]float64), Available: make(map[string]bool)}
+
+	scalars := []struct {
+		name   string
+		query  string
+		target *float64
+	}{
+		{"requests", promAPIServerRequestRateQuery, &report.RequestRate},
+		{"throttled", promAPIServerThrottledQuery, &report.ThrottledRate},
+		{"rejected", promAPIServerRejectedQuery, &report.RejectedRate},
+		{"etcd_db", promEtcdDBSizeQuery, &report.EtcdDBSize},
+		{"etcd_quota", promEtcdQuotaQuery, &report.EtcdQuota},
+		{"etcd_fsync", promEtcdFsyncQuery, &report.EtcdFsyncP99},
+		{"etcd_leader", promEtcdLeaderChangesQuery, &report.EtcdLeaderChanges},
+	}
+	for _, s := range scalars {
+		value, found, err := promScalar(baseURL, s.query)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao consultar métricas do control plane: %v", err)
+		}
+		if found {
+			*s.target = value
+			report.Available[s.name] = true
+		}
+	}
+
+	inflight, err := queryPrometheus(baseURL, promAPIServerInflightQuery)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar métricas do control plane: %v", err)
+	}
+	for _, v := range inflight {
+		value, err := v.value()
+		if err != nil {
+			continue
+		}
+		switch v.Metric["request_kind"] {
+		case "readOnly":
+			report.InflightReadOnly = value
+		case "mutating":
+			report.InflightMutating = value
+		}
+		report.Available["inflight"] = true
+	}
+
+	latency, err := queryPrometheus(baseURL, promAPIServerLatencyQuery)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar métricas do control plane: %v", err)
+	}
+	for _, v := range latency {
+		value, err := v.value()
+		if err != nil || math.IsNaN(value) || v.Metric["verb"] == "" {
+			continue
+		}
+		report.LatencyP99[v.Metric["verb"]] = value
+		report.Available["latency"] = true
+	}
+
+	if len(report.Available) == 0 {
+		return nil, nil
+	}
+	report.Issues = controlPlaneIssues(report)
+	return report, nil
+}
+
+// controlPlaneIssues checks the control-plane metrics against the saturation thresholds
+func controlPlaneIssues(r *ControlPlaneReport) []PerformanceRecommendation {
+	var issues []PerformanceRecommendation
+	add := func(component, issue, recommendation, priority string) {
+		issues = append(issues, PerformanceRecommendation{
+			ResourceName:   component,
+			Issue:          issue,
+			Recommendation: recommendation,
+			Priority:       priority,
+		})
+	}
+
+	if r.ThrottledRate > 0 || r.RejectedRate > 0 {
+		add("kube-apiserver", fmt.Sprintf("API server rejeitando requisições: %.2f/s com HTTP 429, %.2f/s rejeitadas pelo API Priority and Fairness",
+			r.ThrottledRate, r.RejectedRate),
+			"Identificar os clientes mais ativos (apiserver_flowcontrol_dispatched_requests_total por flow_schema) e ajustar FlowSchemas/PriorityLevelConfigurations ou os controladores que fazem polling", "Alta")
+	}
+	if r.InflightReadOnly >= apiServerMaxReadOnlyInflight*apiServerInflightWarnPct/100 ||
+		r.InflightMutating >= apiServerMaxMutatingInflight*apiServerInflightWarnPct/100 {
+		add("kube-apiserver", fmt.Sprintf("Requisições simultâneas próximas do limite padrão: %.0f de leitura (limite %d), %.0f de escrita (limite %d)",
+			r.InflightReadOnly, apiServerMaxReadOnlyInflight, r.InflightMutating, apiServerMaxMutatingInflight),
+			"Escalar o API server ou revisar --max-requests-inflight e --max-mutating-requests-inflight", "Média")
+	}
+	var slow []string
+	for verb, seconds := range r.LatencyP99 {
+		if seconds > apiServerLatencySLOSeconds {
+			slow = append(slow, fmt.Sprintf("%s %.2fs", verb, seconds))
+		}
+	}
+	if len(slow) > 0 {
+		sort.Strings(slow)
+		add("kube-apiserver", fmt.Sprintf("Latência p99 acima do SLO de %.0fs: %v", apiServerLatencySLOSeconds, slow),
+			"Verificar a latência do etcd, webhooks de admissão lentos e LISTs sem paginação", "Alta")
+	}
+
+	if r.Available["etcd_db"] {
+		quota := r.EtcdQuota
+		if quota == 0 {
+			quota = etcdDefaultQuotaBytes
+		}
+		if percent(int64(r.EtcdDBSize), int64(quota)) >= etcdDBSizeWarnPct {
+			add("etcd", fmt.Sprintf("Banco do etcd com %s, %.0f%% da quota de %s: ao atingir a quota o cluster passa a recusar escritas (alarme NOSPACE)",
+				formatMemory(int64(r.EtcdDBSize)), percent(int64(r.EtcdDBSize), int64(quota)), formatMemory(int64(quota))),
+				"Compactar e desfragmentar o etcd, remover objetos obsoletos (eventos, ReplicaSets antigos) ou aumentar --quota-backend-bytes (máximo recomendado 8Gi)", "Alta")
+		}
+	}
+	if r.EtcdFsyncP99 > etcdFsyncWarnSeconds {
+		add("etcd", fmt.Sprintf("Fsync do WAL do etcd com p99 de %.0fms (acima de %.0fms)", r.EtcdFsyncP99*1000, etcdFsyncWarnSeconds*1000),
+			"Usar discos SSD dedicados para o etcd; disco lento aumenta a latência de todo o API server", "Média")
+	}
+	if r.EtcdLeaderChanges > 0 {
+		add("etcd", fmt.Sprintf("%.0f trocas de líder do etcd na última hora", r.EtcdLeaderChanges),
+			"Verificar a latência de rede e de disco entre os membros do etcd", "Média")
+	}
+	return issues
+}
+
+// controlPlaneFindings turns the high priority control-plane issues into findings
+func controlPlaneFindings(r *ControlPlaneReport) []Finding {
+	if r == nil {
+		return nil
+	}
+	var findings []Finding
+	for _, issue := range r.Issues {
+		if issue.Priority != "Alta" {
+			continue
+		}
+		findings = append(findings, Finding{
+			Kind:     "control-plane",
+			Severity: SeverityHigh,
+			Title:    fmt.Sprintf("%s saturado", issue.ResourceName),
+			Workload: issue.ResourceName,
+			Body:     fmt.Sprintf("%s.\n\nRecomendação: %s\n", issue.Issue, issue.Recommendation),
+		})
+	}
+	return findings
+}
+
+func writeControlPlane(w io.Writer, r *ControlPlaneReport) {
+	fmt.Fprintf(w, "\n=== Control Plane (API Server e etcd) ===\n")
+	fmt.Fprintf(w, "-----------------------------------------\n")
+
+	if r == nil {
+		fmt.Fprintf(w, "Métricas do control plane não encontradas no Prometheus (comum em clusters gerenciados)\n")
+		return
+	}
+	if r.Available["requests"] {
+		fmt.Fprintf(w, "Requisições ao API server: %.1f/s (429: %.2f/s, rejeitadas pelo APF: %.2f/s)\n", r.RequestRate, r.ThrottledRate, r.RejectedRate)
+	}
+	if r.Available["inflight"] {
+		fmt.Fprintf(w, "Requisições simultâneas: %.0f de leitura, %.0f de escrita\n", r.InflightReadOnly, r.InflightMutating)
+	}
+	if len(r.LatencyP99) > 0 {
+		verbs := make([]string, 0, len(r.LatencyP99))
+		for verb := range r.LatencyP99 {
+			verbs = append(verbs, verb)
+		}
+		sort.Strings(verbs)
+		fmt.Fprintf(w, "Latência p99 por verbo:")
+		for _, verb := range verbs {
+			fmt.Fprintf(w, " %s %.0fms", verb, r.LatencyP99[verb]*1000)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	if r.Available["etcd_db"] {
+		fmt.Fprintf(w, "Tamanho do banco do etcd: %s", formatMemory(int64(r.EtcdDBSize)))
+		if r.EtcdQuota > 0 {
+			fmt.Fprintf(w, " (quota: %s)", formatMemory(int64(r.EtcdQuota)))
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	if r.Available["etcd_fsync"] {
+		fmt.Fprintf(w, "Fsync do WAL do etcd (p99): %.1fms\n", r.EtcdFsyncP99*1000)
+	}
+	if r.Available["etcd_leader"] {
+		fmt.Fprintf(w, "Trocas de líder do etcd na última hora: %.0f\n", r.EtcdLeaderChanges)
+	}
+
+	if len(r.Issues) == 0 {
+		fmt.Fprintf(w, "\nNenhum sinal de saturação do control plane\n")
+		return
+	}
+	for i, issue := range r.Issues {
+		fmt.Fprintf(w, "\n%d. %s\n", i+1, issue.ResourceName)
+		fmt.Fprintf(w, "   Problema: %s\n", issue.Issue)
+		fmt.Fprintf(w, "   Recomendação: %s\n", issue.Recommendation)
+		fmt.Fprintf(w, "   Prioridade: %s\n", issue.Priority)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Horas médias de um mês, usadas para converter o custo por hora em custo mensal
+const hoursPerMonth = 730.0
+
+// Bases de rateio do custo dos nodes
+const (
+	costBasisRequests = "requests"
+	costBasisUsage    = "usage"
+)
+
+// CostModel holds the prices used to derive the cost of each node from its allocatable capacity
+type CostModel struct {
+	// Preço por core de CPU por hora
+	CPUHourly float64
+	// Preço por GiB de memória por hora
+	MemoryHourly float64
+	// "requests" ou "usage"
+	Basis string
+	// Label dos pods usada para agrupar o custo por time (opcional)
+	Label string
+}
+
+// CostAllocation is the monthly cost attributed to a namespace, label value or workload
+type CostAllocation struct {
+	Name string
+	// Namespace do workload (apenas no rateio por workload)
+	Namespace  string
+	Pods       int
+	CPUCost    float64
+	MemoryCost float64
+}
+
+// Total returns the monthly cost of the allocation
+func (c CostAllocation) Total() float64 {
+	return c.CPUCost + c.MemoryCost
+}
+
+// CostReport is the showback of node costs to namespaces and teams
+type CostReport struct {
+	Model       CostModel
+	TotalCost   float64
+	IdleCost    float64
+	ByNamespace []CostAllocation
+	ByLabel     []CostAllocation
+	ByWorkload  []CostAllocation
+}
+
+// nodeMonthlyCost returns the monthly CPU and memory cost of a node
+func (m CostModel) nodeMonthlyCost(node *corev1.Node) (float64, float64) {
+	cores := float64(node.Status.Allocatable.Cpu().MilliValue()) / 1000
+	gib := float64(node.Status.Allocatable.Memory().Value()) / 1024 / 1024 / 1024
+	return cores * m.CPUHourly * hoursPerMonth, gib * m.MemoryHourly * hoursPerMonth
+}
+
+// podUsage returns the peak usage observed for the pod, summed over its containers
+func podUsage(pod *corev1.Pod, metrics *MetricsData) (int64, int64) {
+	pm, exists := metrics.PodMetrics[pod.Name]
+	if !exists || pm.Namespace != pod.Namespace {
+		return 0, 0
+	}
+	var cpu, memory int64
+	for _, cm := range pm.Containers {
+		cpu += cm.MaxCPU
+		memory += cm.MaxMemory
+	}
+	return cpu, memory
+}
+
+// allocateCosts splits the cost of each node among its pods proportionally to their requests
+// (or observed usage); the capacity not consumed by any pod is reported as idle cost
+func allocateCosts(model CostModel, nodes []corev1.Node, pods []corev1.Pod, metrics *MetricsData, deploymentIndex map[string]*DeploymentMetrics) *CostReport {
+	report := &CostReport{Model: model}
+
+	podsByNode := make(map[string][]*corev1.Pod)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+
+	byNamespace := make(map[string]*CostAllocation)
+	byLabel := make(map[string]*CostAllocation)
+	byWorkload := make(map[string]*CostAllocation)
+	addCost := func(index map[string]*CostAllocation, key string, allocation CostAllocation, cpuCost, memoryCost float64) {
+		a, exists := index[key]
+		if !exists {
+			a = &allocation
+			index[key] = a
+		}
+		a.Pods++
+		a.CPUCost += cpuCost
+		a.MemoryCost += memoryCost
+	}
+
+	for i := range nodes {
+		node := &nodes[i]
+		allocatableCPU := node.Status.Allocatable.Cpu().MilliValue()
+		allocatableMemory := node.Status.Allocatable.Memory().Value()
+		if allocatableCPU == 0 || allocatableMemory == 0 {
+			continue
+		}
+		nodeCPUCost, nodeMemoryCost := model.nodeMonthlyCost(node)
+		report.TotalCost += nodeCPUCost + nodeMemoryCost
+
+		var allocated float64
+		for _, pod := range podsByNode[node.Name] {
+			var cpu, memory int64
+			if model.Basis == costBasisUsage {
+				cpu, memory = podUsage(pod, metrics)
+			} else {
+				cpu, memory = podRequests(pod)
+			}
+			cpuCost := nodeCPUCost * float64(cpu) / float64(allocatableCPU)
+			memoryCost := nodeMemoryCost * float64(memory) / float64(allocatableMemory)
+			allocated += cpuCost + memoryCost
+
+			addCost(byNamespace, pod.Namespace, CostAllocation{Name: pod.Namespace}, cpuCost, memoryCost)
+			workload := workloadForPod(pod, deploymentIndex)
+			addCost(byWorkload, pod.Namespace+"/"+workload, CostAllocation{Name: workload, Namespace: pod.Namespace}, cpuCost, memoryCost)
+			if model.Label != "" {
+				value := pod.Labels[model.Label]
+				if value == "" {
+					value = "(sem label)"
+				}
+				addCost(byLabel, value, CostAllocation{Name: value}, cpuCost, memoryCost)
+			}
+		}
+
+		// Requests acima do allocatable não geram custo ocioso negativo
+		if idle := nodeCPUCost + nodeMemoryCost - allocated; idle > 0 {
+			report.IdleCost += idle
+		}
+	}
+
+	report.ByNamespace = sortedAllocations(byNamespace)
+	report.ByLabel = sortedAllocations(byLabel)
+	report.ByWorkload = sortedAllocations(byWorkload)
+	return report
+}
+
+// costShare returns part as a percentage of the total cost
+func costShare(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return part * 100 / total
+}
+
+func sortedAllocations(index map[string]*CostAllocation) []CostAllocation {
+	allocations := make([]CostAllocation, 0, len(index))
+	for _, a := range index {
+		allocations = append(allocations, *a)
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		if allocations[i].Total() != allocations[j].Total() {
+			return allocations[i].Total() > allocations[j].Total()
+		}
+		return allocations[i].Name < allocations[j].Name
+	})
+	return allocations
+}
+
+func writeCostAllocation(w io.Writer, report *CostReport) {
+	fmt.Fprintf(w, "\n=== Rateio de Custos (Showback) ===\n")
+	fmt.Fprintf(w, "-----------------------------------\n")
+
+	basis := "requests"
+	if report.Model.Basis == costBasisUsage {
+		basis = "uso máximo observado"
+	}
+	fmt.Fprintf(w, "Base de rateio: %s | Preços: %.4f/core-hora, %.4f/GiB-hora\n",
+		basis, report.Model.CPUHourly, report.Model.MemoryHourly)
+	fmt.Fprintf(w, "Custo mensal estimado dos nodes: %.2f (ocioso/não alocado: %.2f, %.0f%%)\n",
+		report.TotalCost, report.IdleCost, costShare(report.IdleCost, report.TotalCost))
+
+	fmt.Fprintf(w, "\nPor namespace (custo mensal):\n")
+	for _, a := range report.ByNamespace {
+		fmt.Fprintf(w, "- %s: %.2f (CPU %.2f, Memory %.2f, %d pods)\n", a.Name, a.Total(), a.CPUCost, a.MemoryCost, a.Pods)
+	}
+
+	if report.Model.Label != "" {
+		fmt.Fprintf(w, "\nPor label %s (custo mensal):\n", report.Model.Label)
+		for _, a := range report.ByLabel {
+			fmt.Fprintf(w, "- %s: %.2f (CPU %.2f, Memory %.2f, %d pods)\n", a.Name, a.Total(), a.CPUCost, a.MemoryCost, a.Pods)
+		}
+	}
+}
+
+// writeCostCSV exports the cost allocation as CSV, one row per namespace and label value; with an
+// anonymizer the names are replaced by their aliases
+func writeCostCSV(path string, report *CostReport, anonymizer *Anonymizer) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo de custos: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"grupo", "nome", "pods", "custo_cpu_mensal", "custo_memoria_mensal", "custo_total_mensal", "percentual"})
+
+	formatCost := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+	writeRows := func(group string, allocations []CostAllocation) {
+		for _, a := range allocations {
+			writer.Write([]string{
+				group,
+				anonymizer.Anonymize(a.Name),
+				strconv.Itoa(a.Pods),
+				formatCost(a.CPUCost),
+				formatCost(a.MemoryCost),
+				formatCost(a.Total()),
+				strconv.FormatFloat(costShare(a.Total(), report.TotalCost), 'f', 1, 64),
+			})
+		}
+	}
+	writeRows("namespace", report.ByNamespace)
+	if report.Model.Label != "" {
+		writeRows("label:"+report.Model.Label, report.ByLabel)
+	}
+	writer.Write([]string{"ocioso", "", "", "", "", formatCost(report.IdleCost),
+		strconv.FormatFloat(costShare(report.IdleCost, report.TotalCost), 'f', 1, 64)})
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("erro ao escrever arquivo de custos: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// Label de criticidade padrão dos workloads
+const defaultTierLabel = "tier"
+
+// Níveis de criticidade aceitos no label
+const (
+	tierCritical   = "critical"
+	tierStandard   = "standard"
+	tierBestEffort = "best-effort"
+)
+
+// TierProfile is the recommendation profile and the severity weight of a criticality tier
+type TierProfile struct {
+	// Margem somada aos requests sugeridos, em percentual da média dos picos
+	RequestHeadroomPct int
+	// Folga mínima dos limites sugeridos; a folga do namespace prevalece quando maior
+	CPUHeadroomPct    int
+	MemoryHeadroomPct int
+	// Níveis somados à severidade dos problemas do workload (negativo reduz)
+	SeverityShift int
+}
+
+// Perfis dos níveis: serviços críticos recebem margens conservadoras e problemas mais graves; workloads
+// best-effort ficam com o uso observado e problemas menos graves
+var tierProfiles = map[string]TierProfile{
+	tierCritical:   {RequestHeadroomPct: 20, CPUHeadroomPct: 30, MemoryHeadroomPct: 30, SeverityShift: 1},
+	tierStandard:   {},
+	tierBestEffort: {SeverityShift: -1},
+}
+
+// workloadTier returns the tier of the deployment from its labels or the labels of its pod template.
+// Unknown values are reported and treated as standard
+func workloadTier(d *appsv1.Deployment, label string) (string, error) {
+	value, exists := d.Labels[label]
+	if !exists {
+		value, exists = d.Spec.Template.Labels[label]
+	}
+	if !exists {
+		return "", nil
+	}
+	tier := strings.ToLower(value)
+	if tier == "besteffort" || tier == "best_effort" {
+		tier = tierBestEffort
+	}
+	if _, known := tierProfiles[tier]; !known {
+		return "", fmt.Errorf("deployment %s/%s: valor inválido para o label %s: %q (use critical, standard ou best-effort)", d.Namespace, d.Name, label, value)
+	}
+	return tier, nil
+}
+
+// assignTiers reads the criticality label of the deployments, returning how many have it
+func assignTiers(deploymentMetrics map[string]*DeploymentMetrics, deployments map[string]*appsv1.Deployment, label string) (int, []error) {
+	tiered := 0
+	var errs []error
+	for key, dm := range deploymentMetrics {
+		d, exists := deployments[key]
+		if !exists {
+			continue
+		}
+		tier, err := workloadTier(d, label)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dm.Tier = tier
+		if tier != "" {
+			tiered++
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return tiered, errs
+}
+
+// tierProfile returns the profile of the tier; workloads without the label use the standard profile
+func tierProfile(tier string) TierProfile {
+	return tierProfiles[tier]
+}
+
+// withTier applies the minimum headroom of the tier to the thresholds of the namespace
+func (t Thresholds) withTier(tier string) Thresholds {
+	profile := tierProfile(tier)
+	t.CPUHeadroomPct = max(t.CPUHeadroomPct, profile.CPUHeadroomPct)
+	t.MemoryHeadroomPct = max(t.MemoryHeadroomPct, profile.MemoryHeadroomPct)
+	return t
+}
+
+// weighFindings shifts the severity of the findings of each deployment by the weight of its tier
+func weighFindings(findings []Finding, deploymentMetrics map[string]*DeploymentMetrics) {
+	for i := range findings {
+		f := &findings[i]
+		dm, exists := deploymentMetrics[f.Namespace+"/"+f.Workload]
+		if !exists || dm.Tier == "" {
+			continue
+		}
+		shifted := int(f.Severity) + tierProfile(dm.Tier).SeverityShift
+		f.Severity = Severity(min(max(shifted, int(SeverityLow)), int(SeverityCritical)))
+	}
+}
+
+func writeWorkloadTiers(w io.Writer, deploymentMetrics map[string]*DeploymentMetrics, label string) {
+	fmt.Fprintf(w, "\n=== Criticidade dos Workloads ===\n")
+	fmt.Fprintf(w, "---------------------------------\n")
+
+	counts := make(map[string]int)
+	var critical []string
+	for _, dm := range deploymentMetrics {
+		counts[dm.Tier]++
+		if dm.Tier == tierCritical {
+			critical = append(critical, dm.Namespace+"/"+dm.Name)
+		}
+	}
+	sort.Strings(critical)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NÍVEL\tDEPLOYMENTS\tMARGEM DOS REQUESTS\tFOLGA MÍNIMA CPU\tFOLGA MÍNIMA MEMÓRIA\tSEVERIDADE\n")
+	for _, tier := range []string{tierCritical, tierStandard, tierBestEffort} {
+		profile := tierProfile(tier)
+		deployments := counts[tier]
+		if tier == tierStandard {
+			deployments += counts[""]
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d%%\t%d%%\t%d%%\t%+d\n", tier, deployments,
+			profile.RequestHeadroomPct, profile.CPUHeadroomPct, profile.MemoryHeadroomPct, profile.SeverityShift)
+	}
+	tw.Flush()
+
+	if len(critical) > 0 {
+		fmt.Fprintf(w, "\nDeployments críticos: %s\n", strings.Join(critical, ", "))
+	}
+	fmt.Fprintf(w, "\nObservação: o nível vem do label %s do deployment ou do template dos pods; deployments sem o label usam o perfil standard.\n", label)
+	fmt.Fprintf(w, "A folga dos limites é a maior entre a do nível e a do namespace; a severidade dos problemas do deployment é ajustada pelo nível\n")
+}
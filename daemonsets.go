@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Critérios para considerar que o uso de um DaemonSet acompanha o tamanho ou a ocupação do node
+const (
+	daemonSetScalingMinNodes       = 3
+	daemonSetScalingMinCorrelation = 0.7
+	// Razão mínima entre o maior e o menor pico dos pods do DaemonSet
+	daemonSetScalingMinSpread = 1.5
+)
+
+// DaemonSetScalingFactor is the linear relation between the usage of the DaemonSet pods and a
+// property of their nodes: usage = Base + Increment × value
+type DaemonSetScalingFactor struct {
+	// "CPU" ou "Memory"
+	Resource string
+	// Propriedade do node: cores ou GiB allocatable, ou pods em execução
+	Driver      string
+	Unit        string
+	Correlation float64
+	// Em millicores (CPU) ou bytes (Memory)
+	Base      int64
+	Increment int64
+}
+
+// DaemonSetPool holds the usage of the DaemonSet pods on the nodes of a node group and the requests
+// suggested for them
+type DaemonSetPool struct {
+	Pool          string
+	Nodes         int
+	MaxCPU        int64
+	MaxMemory     int64
+	RequestCPU    int64
+	RequestMemory int64
+}
+
+// DaemonSetScaling is a DaemonSet whose usage grows with the size or the pod count of the node, for
+// which a single request either wastes the small nodes or starves the large ones
+type DaemonSetScaling struct {
+	Namespace     string
+	Name          string
+	Nodes         int
+	RequestCPU    int64
+	RequestMemory int64
+	MinCPU        int64
+	MaxCPU        int64
+	MinMemory     int64
+	MaxMemory     int64
+	Factors       []DaemonSetScalingFactor
+	// Somente quando os nodes estão em mais de um grupo
+	Pools     []DaemonSetPool
+	PoolLabel string
+}
+
+// linearFit returns the Pearson correlation and the least-squares base and slope of ys over xs
+func linearFit(xs, ys []float64) (float64, float64, float64) {
+	meanX, stdDevX := meanStdDev(xs)
+	meanY, stdDevY := meanStdDev(ys)
+	if stdDevX == 0 || stdDevY == 0 {
+		return 0, 0, 0
+	}
+	var covariance float64
+	for i := range xs {
+		covariance += (xs[i] - meanX) * (ys[i] - meanY)
+	}
+	covariance /= float64(len(xs))
+	slope := covariance / (stdDevX * stdDevX)
+	return covariance / (stdDevX * stdDevY), meanY - slope*meanX, slope
+}
+
+// daemonSetOwner returns the name of the DaemonSet owning the pod, or an empty string
+func daemonSetOwner(pod *corev1.Pod) string {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+// analyzeDaemonSetScaling correlates the peak usage of each DaemonSet pod with the allocatable resources
+// and the pod count of its node, reporting the DaemonSets with a strong positive correlation and a
+// meaningful spread between the smallest and the largest pod. The pools are the node groups of groupLabel
+func analyzeDaemonSetScaling(nodes []corev1.Node, pods []corev1.Pod, metrics *MetricsData, groupLabel string) []*DaemonSetScaling {
+	nodesByName := make(map[string]*corev1.Node, len(nodes))
+	for i := range nodes {
+		nodesByName[nodes[i].Name] = &nodes[i]
+	}
+	podsPerNode := make(map[string]int)
+	for i := range pods {
+		if pods[i].Spec.NodeName != "" && pods[i].Status.Phase == corev1.PodRunning {
+			podsPerNode[pods[i].Spec.NodeName]++
+		}
+	}
+
+	type sample struct {
+		node        *corev1.Node
+		cpu, memory int64
+	}
+	type daemonSetPods struct {
+		namespace, name           string
+		requestCPU, requestMemory int64
+		samples                   []sample
+	}
+	daemonSets := make(map[string]*daemonSetPods)
+	for i := range pods {
+		pod := &pods[i]
+		owner := daemonSetOwner(pod)
+		node, exists := nodesByName[pod.Spec.NodeName]
+		if owner == "" || !exists || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		pm, exists := metrics.PodMetrics[pod.Name]
+		if !exists || pm.Namespace != pod.Namespace || (pm.MaxCPU == 0 && pm.MaxMemory == 0) {
+			continue
+		}
+		key := pod.Namespace + "/" + owner
+		ds, exists := daemonSets[key]
+		if !exists {
+			ds = &daemonSetPods{namespace: pod.Namespace, name: owner}
+			ds.requestCPU, ds.requestMemory = podRequests(pod)
+			daemonSets[key] = ds
+		}
+		ds.samples = append(ds.samples, sample{node: node, cpu: pm.MaxCPU, memory: pm.MaxMemory})
+	}
+
+	var result []*DaemonSetScaling
+	for _, ds := range daemonSets {
+		if len(ds.samples) < daemonSetScalingMinNodes {
+			continue
+		}
+		s := &DaemonSetScaling{
+			Namespace:     ds.namespace,
+			Name:          ds.name,
+			Nodes:         len(ds.samples),
+			RequestCPU:    ds.requestCPU,
+			RequestMemory: ds.requestMemory,
+			MinCPU:        ds.samples[0].cpu,
+			MinMemory:     ds.samples[0].memory,
+		}
+		cores := make([]float64, len(ds.samples))
+		gib := make([]float64, len(ds.samples))
+		podCounts := make([]float64, len(ds.samples))
+		cpu := make([]float64, len(ds.samples))
+		memory := make([]float64, len(ds.samples))
+		for i, smp := range ds.samples {
+			cores[i] = float64(smp.node.Status.Allocatable.Cpu().MilliValue()) / 1000
+			gib[i] = float64(smp.node.Status.Allocatable.Memory().Value()) / (1024 * 1024 * 1024)
+			podCounts[i] = float64(podsPerNode[smp.node.Name])
+			cpu[i] = float64(smp.cpu)
+			memory[i] = float64(smp.memory)
+			s.MinCPU, s.MaxCPU = min(s.MinCPU, smp.cpu), max(s.MaxCPU, smp.cpu)
+			s.MinMemory, s.MaxMemory = min(s.MinMemory, smp.memory), max(s.MaxMemory, smp.memory)
+		}
+
+		// Para cada recurso, a propriedade do node que melhor explica o uso
+		best := func(resource string, usage []float64, capacity []float64, capacityUnit string, minUsage, maxUsage int64) {
+			if minUsage <= 0 || float64(maxUsage) < float64(minUsage)*daemonSetScalingMinSpread {
+				return
+			}
+			var factor DaemonSetScalingFactor
+			for _, driver := range []struct {
+				name, unit string
+				values     []float64
+			}{
+				{"allocatable do node", capacityUnit, capacity},
+				{"pods em execução no node", "pod", podCounts},
+			} {
+				correlation, base, slope := linearFit(driver.values, usage)
+				if correlation >= daemonSetScalingMinCorrelation && correlation > factor.Correlation {
+					factor = DaemonSetScalingFactor{Resource: resource, Driver: driver.name, Unit: driver.unit,
+						Correlation: correlation, Base: max(0, int64(math.Round(base))), Increment: int64(math.Round(slope))}
+				}
+			}
+			if factor.Correlation > 0 && factor.Increment > 0 {
+				s.Factors = append(s.Factors, factor)
+			}
+		}
+		best("CPU", cpu, cores, "core", s.MinCPU, s.MaxCPU)
+		best("Memory", memory, gib, "GiB", s.MinMemory, s.MaxMemory)
+		if len(s.Factors) == 0 {
+			continue
+		}
+
+		pools := make(map[string]*DaemonSetPool)
+		for _, smp := range ds.samples {
+			group := nodeGroup(smp.node, groupLabel)
+			p, exists := pools[group]
+			if !exists {
+				p = &DaemonSetPool{Pool: group}
+				pools[group] = p
+			}
+			p.Nodes++
+			p.MaxCPU = max(p.MaxCPU, smp.cpu)
+			p.MaxMemory = max(p.MaxMemory, smp.memory)
+		}
+		if len(pools) > 1 {
+			s.PoolLabel = groupLabel
+			for _, p := range pools {
+				p.RequestCPU = withHeadroomPct(p.MaxCPU, addonHeadroomPct)
+				p.RequestMemory = roundUpMiB(withHeadroomPct(p.MaxMemory, addonHeadroomPct))
+				s.Pools = append(s.Pools, *p)
+			}
+			sort.Slice(s.Pools, func(i, j int) bool {
+				return s.Pools[i].Pool < s.Pools[j].Pool
+			})
+		}
+		result = append(result, s)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// formatUsage formats a CPU or memory value of a scaling factor
+func (f DaemonSetScalingFactor) formatUsage(value int64) string {
+	if f.Resource == "CPU" {
+		return formatCPU(value)
+	}
+	return formatMemory(value)
+}
+
+func writeDaemonSetScaling(w io.Writer, daemonSets []*DaemonSetScaling) {
+	fmt.Fprintf(w, "\n=== Escala de DaemonSets por Node ===\n")
+	fmt.Fprintf(w, "-------------------------------------\n")
+
+	if len(daemonSets) == 0 {
+		fmt.Fprintf(w, "Nenhum DaemonSet com uso proporcional ao tamanho ou à ocupação dos nodes\n")
+		return
+	}
+
+	for _, s := range daemonSets {
+		fmt.Fprintf(w, "\nDaemonSet: %s (Namespace: %s) - %d nodes\n", s.Name, s.Namespace, s.Nodes)
+		fmt.Fprintf(w, "Requests atuais por pod: CPU %s, Memory %s; pico por pod: CPU %s a %s, Memory %s a %s\n",
+			formatCPU(s.RequestCPU), formatMemory(s.RequestMemory), formatCPU(s.MinCPU), formatCPU(s.MaxCPU),
+			formatMemory(s.MinMemory), formatMemory(s.MaxMemory))
+		for _, f := range s.Factors {
+			fmt.Fprintf(w, "- %s acompanha %s (correlação %.2f): %s + %s por %s\n",
+				f.Resource, f.Driver, f.Correlation, f.formatUsage(f.Base), f.formatUsage(f.Increment), f.Unit)
+		}
+		if len(s.Pools) > 0 {
+			fmt.Fprintf(w, "Recomendação: separar o DaemonSet por pool de nodes (um DaemonSet por grupo, com nodeSelector %s), com requests diferenciados:\n", s.PoolLabel)
+			for _, p := range s.Pools {
+				fmt.Fprintf(w, "  %s (%d nodes): pico CPU %s, Memory %s -> requests CPU %s, Memory %s\n",
+					p.Pool, p.Nodes, formatCPU(p.MaxCPU), formatMemory(p.MaxMemory), formatCPU(p.RequestCPU), formatMemory(p.RequestMemory))
+			}
+		} else {
+			fmt.Fprintf(w, "Recomendação: dimensionar os requests pela fórmula acima (padrão do addon-resizer: base + incremento), ")
+			fmt.Fprintf(w, "em vez de um valor único que desperdiça os nodes pequenos e restringe os grandes\n")
+		}
+	}
+	fmt.Fprintf(w, "\nObservação: requests por pool incluem %d%% de margem sobre o maior pico do pool. O addon-resizer (nanny) aplica base + incremento por node do cluster;\n", addonHeadroomPct)
+	fmt.Fprintf(w, "para acompanhar o tamanho de cada node, gere um DaemonSet por pool com os valores da fórmula\n")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Percentual de ocupação de pods a partir do qual o node é considerado próximo do esgotamento
+const podDensityWarningPct = 90.0
+
+// NodePodDensity holds the pod count of a node against its max-pods setting
+type NodePodDensity struct {
+	Name      string
+	Pods      int64
+	MaxPods   int64
+	PodPct    float64
+	CPUPct    float64
+	MemoryPct float64
+}
+
+func analyzePodDensity(nodes []corev1.Node, pods []corev1.Pod) []NodePodDensity {
+	podsPerNode := make(map[string]int64)
+	requestedCPU := make(map[string]int64)
+	requestedMemory := make(map[string]int64)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		cpu, memory := podRequests(pod)
+		podsPerNode[pod.Spec.NodeName]++
+		requestedCPU[pod.Spec.NodeName] += cpu
+		requestedMemory[pod.Spec.NodeName] += memory
+	}
+
+	densities := make([]NodePodDensity, 0, len(nodes))
+	for _, node := range nodes {
+		maxPods := node.Status.Allocatable.Pods().Value()
+		densities = append(densities, NodePodDensity{
+			Name:      node.Name,
+			Pods:      podsPerNode[node.Name],
+			MaxPods:   maxPods,
+			PodPct:    percent(podsPerNode[node.Name], maxPods),
+			CPUPct:    percent(requestedCPU[node.Name], node.Status.Allocatable.Cpu().MilliValue()),
+			MemoryPct: percent(requestedMemory[node.Name], node.Status.Allocatable.Memory().Value()),
+		})
+	}
+
+	sort.Slice(densities, func(i, j int) bool {
+		return densities[i].PodPct > densities[j].PodPct
+	})
+
+	return densities
+}
+
+// nodesNearPodExhaustion returns the nodes at or above the pod density warning threshold
+func nodesNearPodExhaustion(densities []NodePodDensity) []NodePodDensity {
+	var near []NodePodDensity
+	for _, d := range densities {
+		if d.MaxPods > 0 && d.PodPct >= podDensityWarningPct {
+			near = append(near, d)
+		}
+	}
+	return near
+}
+
+func writePodDensity(w io.Writer, densities []NodePodDensity) {
+	fmt.Fprintf(w, "\n=== Densidade de Pods por Node ===\n")
+	fmt.Fprintf(w, "----------------------------------\n")
+
+	for _, d := range densities {
+		fmt.Fprintf(w, "%s: %d/%d pods (%.1f%%), requests CPU %.1f%%, Memory %.1f%%\n",
+			d.Name, d.Pods, d.MaxPods, d.PodPct, d.CPUPct, d.MemoryPct)
+	}
+
+	near := nodesNearPodExhaustion(densities)
+	if len(near) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nProblemas Identificados:\n")
+	for i, d := range near {
+		fmt.Fprintf(w, "%d. Node %s com %d de %d pods (%.1f%%)", i+1, d.Name, d.Pods, d.MaxPods, d.PodPct)
+		if d.CPUPct < podDensityWarningPct && d.MemoryPct < podDensityWarningPct {
+			fmt.Fprintf(w, " - CPU e memória ainda disponíveis")
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	fmt.Fprintf(w, "   Recomendação: Aumentar o max-pods do kubelet (respeitando o limite de IPs do CNI), "+
+		"consolidar pods pequenos ou adicionar nodes\n")
+	fmt.Fprintf(w, "   Impacto: Alto - Novos pods não serão agendados mesmo com CPU e memória livres\n")
+	fmt.Fprintf(w, "   Prioridade: Alta\n")
+}
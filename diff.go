@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// resourcesYAML renders the resources stanza of a container in the same layout kubectl uses
+func resourcesYAML(requestCPU, requestMemory, limitCPU, limitMemory int64) []string {
+	lines := []string{"resources:"}
+	section := func(name string, cpu, memory int64) {
+		if cpu == 0 && memory == 0 {
+			return
+		}
+		lines = append(lines, "  "+name+":")
+		if cpu > 0 {
+			lines = append(lines, "    cpu: "+resource.NewMilliQuantity(cpu, resource.DecimalSI).String())
+		}
+		if memory > 0 {
+			lines = append(lines, "    memory: "+resource.NewQuantity(memory, resource.BinarySI).String())
+		}
+	}
+	section("limits", limitCPU, limitMemory)
+	section("requests", requestCPU, requestMemory)
+	if len(lines) == 1 {
+		return []string{"resources: {}"}
+	}
+	return lines
+}
+
+// unifiedDiff returns a single-hunk unified diff between two short texts, based on their longest
+// common subsequence of lines
+func unifiedDiff(fromName, toName string, from, to []string) []string {
+	// lcs[i][j] é o tamanho da maior subsequência comum de from[i:] e to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	diff := []string{
+		"--- " + fromName,
+		"+++ " + toName,
+		fmt.Sprintf("@@ -1,%d +1,%d @@", len(from), len(to)),
+	}
+	i, j := 0, 0
+	for i < len(from) || j < len(to) {
+		switch {
+		case i < len(from) && j < len(to) && from[i] == to[j]:
+			diff = append(diff, " "+from[i])
+			i++
+			j++
+		case i < len(from) && (j == len(to) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "-"+from[i])
+			i++
+		default:
+			diff = append(diff, "+"+to[j])
+			j++
+		}
+	}
+	return diff
+}
+
+// resourcesDiff returns the unified diff of the resources stanza of a container, or nil when the
+// patch does not change it
+func resourcesDiff(deployment string, c ContainerRecommendation) []string {
+	current := resourcesYAML(c.CurrentRequestCPU, c.CurrentRequestMemory, c.CurrentLimitCPU, c.CurrentLimitMemory)
+	proposed := resourcesYAML(c.projectedValue(quotaRequestsCPU), c.projectedValue(quotaRequestsMemory),
+		c.projectedValue(quotaLimitsCPU), c.projectedValue(quotaLimitsMemory))
+	if strings.Join(current, "\n") == strings.Join(proposed, "\n") {
+		return nil
+	}
+	return unifiedDiff(
+		fmt.Sprintf("atual/%s/%s", deployment, c.Container),
+		fmt.Sprintf("proposto/%s/%s", deployment, c.Container),
+		current, proposed)
+}
+
+// writePatchDiffs writes the diff of every container of the patch, each line prefixed by indent
+func writePatchDiffs(w io.Writer, p ResourcePatch, indent string) {
+	for _, c := range p.Containers {
+		for _, line := range resourcesDiff(p.Deployment, c) {
+			fmt.Fprintf(w, "%s%s\n", indent, line)
+		}
+	}
+}
+
+func writeProposedChanges(w io.Writer, patches []ResourcePatch) {
+	fmt.Fprintf(w, "\n=== Alterações Propostas (diff) ===\n")
+	fmt.Fprintf(w, "-----------------------------------\n")
+
+	if len(patches) == 0 {
+		fmt.Fprintf(w, "Nenhuma alteração de recursos proposta\n")
+		return
+	}
+	for _, p := range patches {
+		fmt.Fprintf(w, "\nDeployment: %s (Namespace: %s)\n", p.Deployment, p.Namespace)
+		if p.Source != "" {
+			fmt.Fprintf(w, "Origem: %s\n", p.Source)
+		}
+		writePatchDiffs(w, p, "")
+		for _, note := range p.Notes {
+			fmt.Fprintf(w, "Observação: %s\n", note)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EvictedPod is a pod evicted by the kubelet due to node pressure
+type EvictedPod struct {
+	Name      string
+	Namespace string
+	Workload  string
+	Node      string
+	Resource  string
+	Message   string
+	Time      time.Time
+	// Evicção ocorrida durante a janela de coleta
+	DuringWindow bool
+}
+
+// EvictionGroup aggregates evictions of a workload on a node
+type EvictionGroup struct {
+	Workload  string
+	Namespace string
+	Node      string
+	Resource  string
+	Count     int
+	During    int
+}
+
+// evictionResource extracts the starved resource from the kubelet eviction message
+// (ex: "The node was low on resource: memory. ...")
+func evictionResource(message string) string {
+	const marker = "low on resource: "
+	idx := strings.Index(message, marker)
+	if idx < 0 {
+		return "desconhecido"
+	}
+	resource := message[idx+len(marker):]
+	if end := strings.IndexAny(resource, ". "); end >= 0 {
+		resource = resource[:end]
+	}
+	return resource
+}
+
+// evictionTime returns when the pod was evicted, using the DisruptionTarget condition when present
+func evictionTime(pod *corev1.Pod) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.DisruptionTarget {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			return status.State.Terminated.FinishedAt.Time
+		}
+	}
+	if pod.Status.StartTime != nil {
+		return pod.Status.StartTime.Time
+	}
+	return time.Time{}
+}
+
+// findEvictedPods returns the pods evicted for node pressure, from pod status and Evicted events
+// (events cover pods that were already deleted)
+func findEvictedPods(clientset *kubernetes.Clientset, pods []corev1.Pod, deploymentIndex map[string]*DeploymentMetrics, collectionStart time.Time) []EvictedPod {
+	var evicted []EvictedPod
+	seen := make(map[string]bool)
+
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != corev1.PodFailed || pod.Status.Reason != "Evicted" {
+			continue
+		}
+		t := evictionTime(pod)
+		evicted = append(evicted, EvictedPod{
+			Name:         pod.Name,
+			Namespace:    pod.Namespace,
+			Workload:     workloadForPod(pod, deploymentIndex),
+			Node:         pod.Spec.NodeName,
+			Resource:     evictionResource(pod.Status.Message),
+			Message:      pod.Status.Message,
+			Time:         t,
+			DuringWindow: !t.IsZero() && !t.Before(collectionStart),
+		})
+		seen[pod.Namespace+"/"+pod.Name] = true
+	}
+
+	events, err := clientset.CoreV1().Events("").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "reason=Evicted,involvedObject.kind=Pod",
+	})
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: Erro ao listar eventos de evicção: %v\n", err)
+		return evicted
+	}
+
+	for _, event := range events.Items {
+		key := event.InvolvedObject.Namespace + "/" + event.InvolvedObject.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		t := event.LastTimestamp.Time
+		if t.IsZero() {
+			t = event.EventTime.Time
+		}
+		node := event.Source.Host
+		if node == "" {
+			node = event.ReportingInstance
+		}
+		evicted = append(evicted, EvictedPod{
+			Name:         event.InvolvedObject.Name,
+			Namespace:    event.InvolvedObject.Namespace,
+			Workload:     workloadFromPodName(event.InvolvedObject.Name),
+			Node:         node,
+			Resource:     evictionResource(event.Message),
+			Message:      event.Message,
+			Time:         t,
+			DuringWindow: !t.IsZero() && !t.Before(collectionStart),
+		})
+	}
+
+	return evicted
+}
+
+// workloadFromPodName guesses the deployment name of a deleted pod ("<deploy>-<hash>-<sufixo>")
+func workloadFromPodName(podName string) string {
+	parts := strings.Split(podName, "-")
+	if len(parts) >= 3 {
+		return strings.Join(parts[:len(parts)-2], "-")
+	}
+	return podName
+}
+
+func groupEvictions(evicted []EvictedPod) []EvictionGroup {
+	groups := make(map[string]*EvictionGroup)
+	for _, e := range evicted {
+		key := strings.Join([]string{e.Namespace, e.Workload, e.Node, e.Resource}, "/")
+		g, exists := groups[key]
+		if !exists {
+			g = &EvictionGroup{Workload: e.Workload, Namespace: e.Namespace, Node: e.Node, Resource: e.Resource}
+			groups[key] = g
+		}
+		g.Count++
+		if e.DuringWindow {
+			g.During++
+		}
+	}
+
+	result := make([]EvictionGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Workload < result[j].Workload
+	})
+	return result
+}
+
+// evictionRemediation returns the recommended fix for evictions caused by the given resource
+func evictionRemediation(resource string) string {
+	switch resource {
+	case "memory":
+		return "Aumentar o request de memória para o uso real (pods acima do request são os primeiros despejados) " +
+			"e revisar kube-reserved/system-reserved do node"
+	case "ephemeral-storage", "nodefs", "imagefs":
+		return "Definir requests/limits de ephemeral-storage, reduzir logs/arquivos temporários " +
+			"e revisar o garbage collection de imagens do node"
+	case "pids":
+		return "Definir limite de PIDs por pod (podPidsLimit) e investigar processos em excesso"
+	default:
+		return "Revisar os limiares de evicção do kubelet e os recursos reservados do node"
+	}
+}
+
+func writeEvictions(w io.Writer, evicted []EvictedPod) {
+	fmt.Fprintf(w, "\n=== Pods Despejados (Evicted) ===\n")
+	fmt.Fprintf(w, "---------------------------------\n")
+
+	if len(evicted) == 0 {
+		fmt.Fprintf(w, "Nenhum pod despejado por pressão de recursos no node\n")
+		return
+	}
+
+	groups := groupEvictions(evicted)
+	byNode := make(map[string]int)
+	for _, g := range groups {
+		byNode[g.Node] += g.Count
+	}
+
+	fmt.Fprintf(w, "Total de pods despejados: %d\n", len(evicted))
+	fmt.Fprintf(w, "\nPor workload e node:\n")
+	for _, g := range groups {
+		fmt.Fprintf(w, "- %s (Namespace: %s) no node %s: %d evicções por %s (%d durante a coleta)\n",
+			g.Workload, g.Namespace, g.Node, g.Count, g.Resource, g.During)
+	}
+
+	nodeNames := make([]string, 0, len(byNode))
+	for node := range byNode {
+		nodeNames = append(nodeNames, node)
+	}
+	sort.Strings(nodeNames)
+	fmt.Fprintf(w, "\nPor node:\n")
+	for _, node := range nodeNames {
+		fmt.Fprintf(w, "- %s: %d evicções\n", node, byNode[node])
+	}
+
+	seen := make(map[string]bool)
+	var resources []string
+	for _, g := range groups {
+		if !seen[g.Resource] {
+			seen[g.Resource] = true
+			resources = append(resources, g.Resource)
+		}
+	}
+	sort.Strings(resources)
+	fmt.Fprintf(w, "\nRecomendações:\n")
+	for _, resource := range resources {
+		fmt.Fprintf(w, "- %s: %s\n", resource, evictionRemediation(resource))
+	}
+}
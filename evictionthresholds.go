@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Sinal de despejo por memória do kubelet e o limite rígido padrão quando a configuração não pode ser lida
+const (
+	memoryAvailableSignal     = "memory.available"
+	defaultHardEvictionMemory = 100 * mebibyte
+)
+
+// KubeletConfigz is the part of the kubelet configuration (/configz) used to find the eviction thresholds
+type KubeletConfigz struct {
+	KubeletConfig struct {
+		EvictionHard map[string]string `json:"evictionHard"`
+		EvictionSoft map[string]string `json:"evictionSoft"`
+		PodPidsLimit *int64            `json:"podPidsLimit"`
+	} `json:"kubeletconfig"`
+}
+
+// EvictionThresholds are the memory eviction thresholds of a node and the capacity they leave for pods
+type EvictionThresholds struct {
+	Node              string
+	CapacityMemory    int64
+	AllocatableMemory int64
+	HardMemory        int64
+	SoftMemory        int64
+	// Configuração lida do kubelet; falso quando o padrão do kubelet foi assumido
+	FromConfig bool
+	// Memória que os pods podem usar sem acionar o despejo: o allocatable já desconta o limite rígido,
+	// mas o limite suave dispara despejos antes dele
+	UsableMemory int64
+	// Limite de processos por pod do kubelet (podPidsLimit), 0 quando ilimitado ou desconhecido
+	PodPidsLimit int64
+}
+
+// reserved returns the memory kept out of allocatable (kube-reserved, system-reserved and the hard threshold)
+func (e *EvictionThresholds) reserved() int64 {
+	return e.CapacityMemory - e.AllocatableMemory
+}
+
+// parseEvictionThreshold converts a threshold (quantity or percentage of the capacity) to bytes
+func parseEvictionThreshold(value string, capacity int64) (int64, error) {
+	if pct, isPct := strings.CutSuffix(value, "%"); isPct {
+		p, err := strconv.ParseFloat(pct, 64)
+		if err != nil {
+			return 0, fmt.Errorf("limite de despejo inválido: %q", value)
+		}
+		return int64(float64(capacity) * p / 100), nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, fmt.Errorf("limite de despejo inválido: %q", value)
+	}
+	return q.Value(), nil
+}
+
+// fetchKubeletConfigz reads the running configuration of the kubelet through the API server proxy
+func fetchKubeletConfigz(clientset *kubernetes.Clientset, nodeName string) (*KubeletConfigz, error) {
+	data, err := clientset.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", nodeName, "proxy", "configz").
+		DoRaw(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar a configuração do kubelet no node %s: %v", nodeName, err)
+	}
+	config := &KubeletConfigz{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar a configuração do kubelet no node %s: %v", nodeName, err)
+	}
+	return config, nil
+}
+
+// readEvictionThresholds reads the memory eviction thresholds of each node from the kubelet configuration,
+// assuming the kubelet default when it cannot be read. The nodes are queried by the given number of
+// workers; the failures are returned as warnings
+func readEvictionThresholds(clientset *kubernetes.Clientset, nodes []corev1.Node, workers int) (map[string]*EvictionThresholds, []error) {
+	index := make(map[string]*corev1.Node, len(nodes))
+	names := make([]string, 0, len(nodes))
+	for i := range nodes {
+		index[nodes[i].Name] = &nodes[i]
+		names = append(names, nodes[i].Name)
+	}
+
+	thresholds := make(map[string]*EvictionThresholds, len(nodes))
+	var mu sync.Mutex
+	errs := forEachNamespace(names, workers, func(name string) error {
+		node := index[name]
+		e := &EvictionThresholds{
+			Node:              name,
+			CapacityMemory:    node.Status.Capacity.Memory().Value(),
+			AllocatableMemory: node.Status.Allocatable.Memory().Value(),
+			HardMemory:        defaultHardEvictionMemory,
+		}
+		e.UsableMemory = e.AllocatableMemory
+		mu.Lock()
+		thresholds[name] = e
+		mu.Unlock()
+
+		config, err := fetchKubeletConfigz(clientset, name)
+		if err != nil {
+			return err
+		}
+		e.FromConfig = true
+		if limit := config.KubeletConfig.PodPidsLimit; limit != nil && *limit > 0 {
+			e.PodPidsLimit = *limit
+		}
+		if value, exists := config.KubeletConfig.EvictionHard[memoryAvailableSignal]; exists {
+			if e.HardMemory, err = parseEvictionThreshold(value, e.CapacityMemory); err != nil {
+				return err
+			}
+		}
+		if value, exists := config.KubeletConfig.EvictionSoft[memoryAvailableSignal]; exists {
+			if e.SoftMemory, err = parseEvictionThreshold(value, e.CapacityMemory); err != nil {
+				return err
+			}
+		}
+		if e.SoftMemory > e.HardMemory {
+			e.UsableMemory = max(e.AllocatableMemory-(e.SoftMemory-e.HardMemory), 0)
+		}
+		return nil
+	})
+
+	var warnings []error
+	for _, name := range names {
+		if err, failed := errs[name]; failed {
+			warnings = append(warnings, err)
+		}
+	}
+	return thresholds, warnings
+}
+
+// usableNodes returns copies of the nodes whose allocatable memory is the memory pods can use without
+// triggering eviction, for the capacity planning (simulation, consolidation and forecast)
+func usableNodes(nodes []corev1.Node, thresholds map[string]*EvictionThresholds) []corev1.Node {
+	usable := make([]corev1.Node, len(nodes))
+	for i := range nodes {
+		e, exists := thresholds[nodes[i].Name]
+		if !exists || e.UsableMemory == e.AllocatableMemory {
+			usable[i] = nodes[i]
+			continue
+		}
+		node := nodes[i].DeepCopy()
+		node.Status.Allocatable[corev1.ResourceMemory] = *resource.NewQuantity(e.UsableMemory, resource.BinarySI)
+		usable[i] = *node
+	}
+	return usable
+}
+
+func writeEvictionThresholds(w io.Writer, thresholds map[string]*EvictionThresholds) {
+	fmt.Fprintf(w, "\n=== Limites de Despejo do Kubelet ===\n")
+	fmt.Fprintf(w, "-------------------------------------\n")
+
+	names := make([]string, 0, len(thresholds))
+	var allocatable, usable int64
+	for name, e := range thresholds {
+		names = append(names, name)
+		allocatable += e.AllocatableMemory
+		usable += e.UsableMemory
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		e := thresholds[name]
+		fmt.Fprintf(w, "- %s: capacidade %s, allocatable %s (reservado: %s), despejo rígido %s",
+			name, formatMemory(e.CapacityMemory), formatMemory(e.AllocatableMemory), formatMemory(e.reserved()), formatMemory(e.HardMemory))
+		if e.SoftMemory > 0 {
+			fmt.Fprintf(w, ", despejo suave %s", formatMemory(e.SoftMemory))
+		}
+		fmt.Fprintf(w, ", utilizável %s", formatMemory(e.UsableMemory))
+		if !e.FromConfig {
+			fmt.Fprintf(w, " (configuração do kubelet indisponível: padrão assumido)")
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	fmt.Fprintf(w, "\nMemória utilizável pelos pods sem acionar despejos: %s de %s allocatable\n", formatMemory(usable), formatMemory(allocatable))
+	fmt.Fprintf(w, "Simulação de agendamento, consolidação e previsão de capacidade consideram a memória utilizável\n")
+}
+
+// countSoftEvictionNodes returns how many nodes evict pods before their allocatable memory is used
+func countSoftEvictionNodes(thresholds map[string]*EvictionThresholds) int {
+	count := 0
+	for _, e := range thresholds {
+		if e.UsableMemory < e.AllocatableMemory {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// Ações listadas por namespace no resumo executivo
+const executiveActions = 3
+
+// NamespaceSummary is the executive view of a namespace: a health score, the most severe findings as
+// actions and the monthly cost of the requests the recommendations free
+type NamespaceSummary struct {
+	Namespace   string
+	Deployments int
+	HealthScore int
+	Findings    map[Severity]int
+	Actions     []Finding
+	// Requests atuais e recomendados somando as réplicas dos deployments com patch
+	CurrentCPU     int64
+	ProposedCPU    int64
+	CurrentMemory  int64
+	ProposedMemory int64
+	// Custo mensal do namespace e dos requests liberados
+	Cost      *CostAllocation
+	WasteCost float64
+}
+
+// summarizeNamespaces builds the summary of each namespace with deployments. The health score is the
+// cluster score (healthScore) restricted to the findings, deployments and patches of the namespace
+func summarizeNamespaces(deploymentMetrics map[string]*DeploymentMetrics, deployments map[string]*appsv1.Deployment, findings []Finding,
+	patches []ResourcePatch, costReport *CostReport) []*NamespaceSummary {
+	summaries := make(map[string]*NamespaceSummary)
+	for _, dm := range deploymentMetrics {
+		s, exists := summaries[dm.Namespace]
+		if !exists {
+			s = &NamespaceSummary{Namespace: dm.Namespace, Findings: make(map[Severity]int)}
+			summaries[dm.Namespace] = s
+		}
+		s.Deployments++
+	}
+
+	byNamespace := make(map[string][]Finding)
+	for _, f := range findings {
+		s, exists := summaries[f.Namespace]
+		if !exists {
+			continue
+		}
+		s.Findings[f.Severity]++
+		byNamespace[f.Namespace] = append(byNamespace[f.Namespace], f)
+	}
+
+	for _, p := range patches {
+		s, exists := summaries[p.Namespace]
+		if !exists {
+			continue
+		}
+		replicas := int64(1)
+		if d, exists := deployments[p.Namespace+"/"+p.Deployment]; exists && d.Spec.Replicas != nil {
+			replicas = int64(*d.Spec.Replicas)
+		}
+		for _, c := range p.Containers {
+			s.CurrentCPU += c.CurrentRequestCPU * replicas
+			s.ProposedCPU += c.RequestCPU * replicas
+			s.CurrentMemory += c.CurrentRequestMemory * replicas
+			s.ProposedMemory += c.RequestMemory * replicas
+		}
+	}
+
+	costs := make(map[string]*CostAllocation)
+	for i := range costReport.ByNamespace {
+		costs[costReport.ByNamespace[i].Name] = &costReport.ByNamespace[i]
+	}
+
+	result := make([]*NamespaceSummary, 0, len(summaries))
+	for namespace, s := range summaries {
+		namespaceFindings := byNamespace[namespace]
+		sort.SliceStable(namespaceFindings, func(i, j int) bool {
+			return namespaceFindings[i].Severity > namespaceFindings[j].Severity
+		})
+		s.Actions = namespaceFindings[:min(executiveActions, len(namespaceFindings))]
+		s.HealthScore = healthScore(namespaceFindings, s.Deployments, &SimulationResult{
+			CurrentCPURequest: s.CurrentCPU, ProposedCPURequest: s.ProposedCPU,
+			CurrentMemRequest: s.CurrentMemory, ProposedMemRequest: s.ProposedMemory,
+		})
+		s.Cost = costs[namespace]
+		wasteCPU := max(0, s.CurrentCPU-s.ProposedCPU)
+		wasteMemory := max(0, s.CurrentMemory-s.ProposedMemory)
+		s.WasteCost = (float64(wasteCPU)/1000*costReport.Model.CPUHourly + float64(wasteMemory)/gibibyte*costReport.Model.MemoryHourly) * hoursPerMonth
+		result = append(result, s)
+	}
+	// Namespaces com a pior saúde primeiro
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].HealthScore != result[j].HealthScore {
+			return result[i].HealthScore < result[j].HealthScore
+		}
+		return result[i].Namespace < result[j].Namespace
+	})
+	return result
+}
+
+// findingAction returns the recommendation of the finding (the text after "Recomendação"), or its title
+func findingAction(f Finding) string {
+	if _, recommendation, found := strings.Cut(f.Body, "Recomendação"); found {
+		recommendation = strings.TrimLeft(recommendation, " :")
+		if i := strings.Index(recommendation, ":"); i >= 0 && i < 30 && strings.HasPrefix(recommendation, "(") {
+			recommendation = strings.TrimSpace(recommendation[i+1:])
+		}
+		return strings.TrimSpace(strings.SplitN(recommendation, "\n", 2)[0])
+	}
+	return f.Title
+}
+
+// healthColor is green from 80, yellow from 50 and red below
+func healthColor(score int) (float64, float64, float64) {
+	switch {
+	case score >= 80:
+		return 0.20, 0.65, 0.32
+	case score >= 50:
+		return 0.95, 0.70, 0.10
+	}
+	return 0.85, 0.20, 0.18
+}
+
+// writeExecutivePDF writes one page per namespace for engineering managers: health score, the top 3
+// actions and the monthly cost freed by the recommendations. Names are anonymized with the anonymizer
+func writeExecutivePDF(path, cluster string, summaries []*NamespaceSummary, generated time.Time, anonymizer *Anonymizer) error {
+	const margin = 50.0
+	width := pdfPageWidth - 2*margin
+	doc := &pdfDocument{}
+	for _, s := range summaries {
+		page := doc.newPage()
+		y := pdfPageHeight - margin
+
+		page.text(margin, y, 9, false, fmt.Sprintf("Cluster %s - resumo executivo de %s", anonymizer.Anonymize(cluster), generated.Format("2006-01-02 15:04")))
+		y -= 30
+		page.text(margin, y, 22, true, "Namespace "+anonymizer.Anonymize(s.Namespace))
+		y -= 14
+		page.line(margin, y, pdfPageWidth-margin, y)
+
+		// Pontuação de saúde com uma barra colorida
+		y -= 40
+		page.text(margin, y, 12, true, "Saúde")
+		r, g, b := healthColor(s.HealthScore)
+		page.text(margin+120, y, 28, true, fmt.Sprintf("%d/100", s.HealthScore))
+		y -= 18
+		page.rect(margin, y, width, 10, 0.90, 0.90, 0.90)
+		page.rect(margin, y, width*float64(s.HealthScore)/100, 10, r, g, b)
+		y -= 20
+		page.text(margin, y, 10, false, fmt.Sprintf("%d deployments; problemas: %d críticos, %d altos, %d médios, %d baixos",
+			s.Deployments, s.Findings[SeverityCritical], s.Findings[SeverityHigh], s.Findings[SeverityMedium], s.Findings[SeverityLow]))
+
+		// Custo
+		y -= 40
+		page.text(margin, y, 12, true, "Custo mensal")
+		y -= 20
+		if s.Cost != nil {
+			page.text(margin, y, 10, false, fmt.Sprintf("Custo atual estimado: %.2f (%d pods)", s.Cost.Total(), s.Cost.Pods))
+			y -= 16
+		}
+		page.text(margin, y, 10, false, fmt.Sprintf("Desperdício (requests acima do necessário): %.2f por mês", s.WasteCost))
+		y -= 16
+		page.text(margin, y, 10, false, fmt.Sprintf("Requests liberados com as recomendações: CPU %s, Memory %s",
+			formatCPU(max(0, s.CurrentCPU-s.ProposedCPU)), formatMemory(max(0, s.CurrentMemory-s.ProposedMemory))))
+
+		// Ações
+		y -= 40
+		page.text(margin, y, 12, true, "Principais ações")
+		y -= 6
+		if len(s.Actions) == 0 {
+			y -= 16
+			page.text(margin, y, 10, false, "Nenhum problema encontrado no namespace.")
+		}
+		for i, f := range s.Actions {
+			y -= 8
+			for _, line := range wrapText(fmt.Sprintf("%d. [%s] %s", i+1, f.Severity, anonymizer.Anonymize(f.Title)), width, 10) {
+				y -= 14
+				page.text(margin, y, 10, true, line)
+			}
+			for _, line := range wrapText(anonymizer.Anonymize(findingAction(f)), width-15, 10) {
+				y -= 14
+				page.text(margin+15, y, 10, false, line)
+			}
+		}
+
+		page.line(margin, margin, pdfPageWidth-margin, margin)
+		page.text(margin, margin-14, 8, false, "Gerado pelo k8s-performance-analyzer; os detalhes de cada ação estão no relatório de recomendações.")
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("erro ao criar resumo executivo: %v", err)
+	}
+	defer f.Close()
+	if err := doc.write(f); err != nil {
+		return fmt.Errorf("erro ao escrever resumo executivo: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Classes de encerramento, cada uma com uma correção diferente
+const (
+	exitClassOOM       = "memória"
+	exitClassKilled    = "sinal KILL"
+	exitClassSIGTERM   = "sinal TERM"
+	exitClassCrash     = "falha da aplicação"
+	exitClassCommand   = "comando inválido"
+	exitClassCompleted = "processo terminou"
+)
+
+// Correção sugerida para cada classe de encerramento
+var exitRemediations = map[string]string{
+	exitClassOOM:       "aumentar o limite de memória para acima do pico observado ou investigar vazamento de memória",
+	exitClassKilled:    "verificar a liveness probe (timeout e failureThreshold) e se o terminationGracePeriodSeconds é suficiente para o encerramento",
+	exitClassSIGTERM:   "tratar o SIGTERM na aplicação para encerrar com código 0 e revisar a liveness probe que provoca o reinício",
+	exitClassCrash:     "erro da própria aplicação: analisar kubectl logs --previous e a configuração (variáveis, secrets, dependências)",
+	exitClassCommand:   "corrigir o command/args da imagem (binário inexistente ou sem permissão de execução)",
+	exitClassCompleted: "o processo principal termina com sucesso: usar um Job ou CronJob em vez de um workload com restartPolicy Always",
+}
+
+// exitClass classifies the last termination of a container by its exit code and reason
+func exitClass(code int32, reason string) string {
+	switch {
+	case reason == "OOMKilled":
+		return exitClassOOM
+	case code == 0:
+		return exitClassCompleted
+	case code == 126 || code == 127 || reason == "ContainerCannotRun" || reason == "StartError":
+		return exitClassCommand
+	case code == 137:
+		return exitClassKilled
+	case code == 143:
+		return exitClassSIGTERM
+	}
+	return exitClassCrash
+}
+
+// ExitCodeGroup counts the containers of a workload that last terminated with the same code and reason
+type ExitCodeGroup struct {
+	Namespace  string
+	Workload   string
+	ExitCode   int32
+	Reason     string
+	Class      string
+	Containers int
+	Restarts   int32
+}
+
+// exitCodeBreakdown aggregates the last termination of the containers that restarted, by workload,
+// exit code and reason
+func exitCodeBreakdown(pods []corev1.Pod, deploymentIndex map[string]*DeploymentMetrics) []*ExitCodeGroup {
+	groups := make(map[string]*ExitCodeGroup)
+	for i := range pods {
+		pod := &pods[i]
+		for _, status := range pod.Status.ContainerStatuses {
+			terminated := status.LastTerminationState.Terminated
+			if status.RestartCount == 0 || terminated == nil {
+				continue
+			}
+			workload := workloadForPod(pod, deploymentIndex)
+			key := fmt.Sprintf("%s/%s/%d/%s", pod.Namespace, workload, terminated.ExitCode, terminated.Reason)
+			g, exists := groups[key]
+			if !exists {
+				g = &ExitCodeGroup{
+					Namespace: pod.Namespace,
+					Workload:  workload,
+					ExitCode:  terminated.ExitCode,
+					Reason:    terminated.Reason,
+					Class:     exitClass(terminated.ExitCode, terminated.Reason),
+				}
+				groups[key] = g
+			}
+			g.Containers++
+			g.Restarts += status.RestartCount
+		}
+	}
+
+	result := make([]*ExitCodeGroup, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Restarts != result[j].Restarts {
+			return result[i].Restarts > result[j].Restarts
+		}
+		if result[i].Namespace+"/"+result[i].Workload != result[j].Namespace+"/"+result[j].Workload {
+			return result[i].Namespace+"/"+result[i].Workload < result[j].Namespace+"/"+result[j].Workload
+		}
+		return result[i].ExitCode < result[j].ExitCode
+	})
+	return result
+}
+
+func writeExitCodeBreakdown(w io.Writer, groups []*ExitCodeGroup) {
+	fmt.Fprintf(w, "\n=== Códigos de Saída dos Containers Reiniciados ===\n")
+	fmt.Fprintf(w, "---------------------------------------------------\n")
+
+	if len(groups) == 0 {
+		fmt.Fprintf(w, "Nenhum container com reinícios e último encerramento registrado\n")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NAMESPACE\tWORKLOAD\tCÓDIGO\tMOTIVO\tCLASSE\tCONTAINERS\tREINÍCIOS\n")
+	classes := make(map[string]int32)
+	for _, g := range groups {
+		reason := g.Reason
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%d\t%d\n", g.Namespace, g.Workload, g.ExitCode, reason, g.Class, g.Containers, g.Restarts)
+		classes[g.Class] += g.Restarts
+	}
+	tw.Flush()
+
+	names := make([]string, 0, len(classes))
+	for class := range classes {
+		names = append(names, class)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if classes[names[i]] != classes[names[j]] {
+			return classes[names[i]] > classes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	fmt.Fprintf(w, "\nCorreção por classe:\n")
+	for _, class := range names {
+		fmt.Fprintf(w, "- %s (%d reinícios): %s\n", class, classes[class], exitRemediations[class])
+	}
+}
@@ -0,0 +1,905 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Linguagem de expressões das políticas. A sintaxe segue a do CEL (Common Expression Language), mas
+// a implementação é própria e não é o CEL: literais (inteiros, decimais, strings, true, false, null e
+// listas), acesso a campos (a.b, a["b"], lista[0]), aritmética (+ - * / %), comparações, in, && || !,
+// o operador ternário (a ? b : c), os métodos de string (startsWith, endsWith, contains, matches,
+// lowerAscii, upperAscii, size) e as funções size, has, int, double e string. Diferenças em relação ao
+// CEL:
+//   - inteiros e decimais se misturam sem conversão explícita (1 + 2.5 resulta em 3.5 e 1 == 1.0 é
+//     verdadeiro), enquanto o CEL rejeita a combinação de int e double
+//   - não há verificação de tipos na compilação: erros de tipo e variáveis desconhecidas só aparecem na
+//     avaliação
+//   - não há literais de mapa, uint, bytes, timestamp e duration, nem as macros all, exists, map e filter
+// Como no CEL, estouros de inteiros são erro, && || e o ternário avaliam apenas o lado necessário e
+// lowerAscii e upperAscii alteram apenas letras ASCII
+
+// Expression is a compiled policy expression
+type Expression struct {
+	Source string
+	root   exprNode
+}
+
+type exprNode interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+// compileExpression parses the expression, reporting the position of syntax errors
+func compileExpression(source string) (*Expression, error) {
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("posição %d: token inesperado %q", t.pos+1, t.text)
+	}
+	return &Expression{Source: source, root: root}, nil
+}
+
+// eval evaluates the expression with the variables
+func (e *Expression) eval(vars map[string]interface{}) (interface{}, error) {
+	return e.root.eval(vars)
+}
+
+// evalBool evaluates an expression that must result in a boolean
+func (e *Expression) evalBool(vars map[string]interface{}) (bool, error) {
+	value, err := e.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("a expressão deve resultar em bool, resultou em %s", exprTypeName(value))
+	}
+	return result, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenInt
+	tokenFloat
+	tokenString
+	tokenOperator
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// Operadores em ordem decrescente de tamanho, para que "<=" não seja lido como "<"
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", "[", "]", ".", ",", "?", ":"}
+
+func tokenizeExpression(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, exprToken{tokenIdent, string(runes[start:i]), start})
+		case unicode.IsDigit(r):
+			start, kind := i, tokenInt
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '-' || runes[i] == '+') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				if !unicode.IsDigit(runes[i]) {
+					kind = tokenFloat
+				}
+				i++
+			}
+			tokens = append(tokens, exprToken{kind, string(runes[start:i]), start})
+		case r == '"' || r == '\'':
+			start := i
+			var b strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						b.WriteRune('\n')
+					case 't':
+						b.WriteRune('\t')
+					default:
+						b.WriteRune(runes[i])
+					}
+					continue
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("posição %d: string sem aspas de fechamento", start+1)
+			}
+			i++
+			tokens = append(tokens, exprToken{tokenString, b.String(), start})
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(string(runes[i:min(i+2, len(runes))]), op) {
+					tokens = append(tokens, exprToken{tokenOperator, op, i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("posição %d: caractere inválido %q", i+1, r)
+			}
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+type exprParser struct {
+	tokens []exprToken
+	next   int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.next]
+}
+
+// accept consumes the next token when it is the operator or keyword
+func (p *exprParser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokenOperator || t.kind == tokenIdent) && t.text == text {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(text string) error {
+	if !p.accept(text) {
+		t := p.peek()
+		if t.kind == tokenEOF {
+			return fmt.Errorf("posição %d: esperado %q no fim da expressão", t.pos+1, text)
+		}
+		return fmt.Errorf("posição %d: esperado %q, encontrado %q", t.pos+1, text, t.text)
+	}
+	return nil
+}
+
+func (p *exprParser) ternary() (exprNode, error) {
+	cond, err := p.binary(0)
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+	then, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return &conditionalNode{cond, then, otherwise}, nil
+}
+
+// Precedência dos operadores binários, da menor para a maior
+var exprPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) binary(level int) (exprNode, error) {
+	if level == len(exprPrecedence) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range exprPrecedence[level] {
+			if p.accept(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op, left, right}
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	for _, op := range []string{"!", "-"} {
+		if !p.accept(op) {
+			continue
+		}
+		// Inteiro precedido de - é um literal negativo, de modo que o menor int (-9223372036854775808)
+		// pode ser escrito
+		if t := p.peek(); op == "-" && t.kind == tokenInt {
+			if value, err := strconv.ParseInt("-"+t.text, 10, 64); err == nil {
+				p.next++
+				return &literalNode{value}, nil
+			}
+		}
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op, operand}, nil
+	}
+	return p.postfix()
+}
+
+func (p *exprParser) postfix() (exprNode, error) {
+	node, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.peek()
+			if t.kind != tokenIdent {
+				return nil, fmt.Errorf("posição %d: esperado nome de campo após \".\"", t.pos+1)
+			}
+			p.next++
+			if p.accept("(") {
+				args, err := p.arguments()
+				if err != nil {
+					return nil, err
+				}
+				node = &callNode{name: t.text, target: node, args: args}
+			} else {
+				node = &fieldNode{node, &literalNode{t.text}}
+			}
+		case p.accept("["):
+			index, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &fieldNode{node, index}
+		default:
+			return node, nil
+		}
+	}
+}
+
+// arguments parses the arguments of a call, after the opening parenthesis
+func (p *exprParser) arguments() ([]exprNode, error) {
+	var args []exprNode
+	if p.accept(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(")") {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenInt:
+		p.next++
+		value, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("posição %d: número inválido %q", t.pos+1, t.text)
+		}
+		return &literalNode{value}, nil
+	case tokenFloat:
+		p.next++
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("posição %d: número inválido %q", t.pos+1, t.text)
+		}
+		return &literalNode{value}, nil
+	case tokenString:
+		p.next++
+		return &literalNode{t.text}, nil
+	case tokenIdent:
+		p.next++
+		switch t.text {
+		case "true", "false":
+			return &literalNode{t.text == "true"}, nil
+		case "null":
+			return &literalNode{nil}, nil
+		}
+		if p.accept("(") {
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			if t.text == "has" {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("posição %d: has recebe um único campo", t.pos+1)
+				}
+				field, ok := args[0].(*fieldNode)
+				if !ok {
+					return nil, fmt.Errorf("posição %d: has recebe um campo (ex: has(deployment.labels.team))", t.pos+1)
+				}
+				return &hasNode{field}, nil
+			}
+			return &callNode{name: t.text, args: args}, nil
+		}
+		return &variableNode{t.text}, nil
+	case tokenOperator:
+		switch {
+		case p.accept("("):
+			node, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		case p.accept("["):
+			var items []exprNode
+			if p.accept("]") {
+				return &listNode{items}, nil
+			}
+			for {
+				item, err := p.ternary()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+				if p.accept("]") {
+					return &listNode{items}, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case tokenEOF:
+		return nil, fmt.Errorf("posição %d: expressão incompleta", t.pos+1)
+	}
+	return nil, fmt.Errorf("posição %d: token inesperado %q", t.pos+1, t.text)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type variableNode struct {
+	name string
+}
+
+func (n *variableNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, exists := vars[n.name]
+	if !exists {
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("variável desconhecida %q (disponíveis: %s)", n.name, strings.Join(names, ", "))
+	}
+	return value, nil
+}
+
+type listNode struct {
+	items []exprNode
+}
+
+func (n *listNode) eval(vars map[string]interface{}) (interface{}, error) {
+	list := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+type fieldNode struct {
+	target exprNode
+	index  exprNode
+}
+
+// lookup returns the field or element and whether it exists
+func (n *fieldNode) lookup(vars map[string]interface{}) (interface{}, bool, error) {
+	target, err := n.target.eval(vars)
+	if err != nil {
+		return nil, false, err
+	}
+	index, err := n.index.eval(vars)
+	if err != nil {
+		return nil, false, err
+	}
+	switch t := target.(type) {
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("chave de mapa deve ser string, recebido %s", exprTypeName(index))
+		}
+		value, exists := t[key]
+		return value, exists, nil
+	case map[string]string:
+		key, ok := index.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("chave de mapa deve ser string, recebido %s", exprTypeName(index))
+		}
+		value, exists := t[key]
+		return value, exists, nil
+	case []interface{}:
+		i, ok := index.(int64)
+		if !ok {
+			return nil, false, fmt.Errorf("índice de lista deve ser int, recebido %s", exprTypeName(index))
+		}
+		if i < 0 || i >= int64(len(t)) {
+			return nil, false, fmt.Errorf("índice %d fora da lista de %d elementos", i, len(t))
+		}
+		return t[i], true, nil
+	}
+	return nil, false, fmt.Errorf("%s não tem campos", exprTypeName(target))
+}
+
+func (n *fieldNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, exists, err := n.lookup(vars)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		index, _ := n.index.eval(vars)
+		return nil, fmt.Errorf("campo inexistente %v (use has() para campos opcionais)", index)
+	}
+	return value, nil
+}
+
+type hasNode struct {
+	field *fieldNode
+}
+
+func (n *hasNode) eval(vars map[string]interface{}) (interface{}, error) {
+	_, exists, err := n.field.lookup(vars)
+	return exists, err
+}
+
+type conditionalNode struct {
+	cond, then, otherwise exprNode
+}
+
+func (n *conditionalNode) eval(vars map[string]interface{}) (interface{}, error) {
+	cond, err := evalBoolOperand(n.cond, vars, "?")
+	if err != nil {
+		return nil, err
+	}
+	if cond {
+		return n.then.eval(vars)
+	}
+	return n.otherwise.eval(vars)
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n *unaryNode) eval(vars map[string]interface{}) (interface{}, error) {
+	if n.op == "!" {
+		value, err := evalBoolOperand(n.operand, vars, "!")
+		return !value, err
+	}
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return nil, fmt.Errorf("estouro de inteiro: -(%d) fora do intervalo de int", v)
+		}
+		return -v, nil
+	case float64:
+		return -v, nil
+	}
+	return nil, fmt.Errorf("operador - não se aplica a %s", exprTypeName(value))
+}
+
+// evalBoolOperand evaluates an operand of a logical operator
+func evalBoolOperand(node exprNode, vars map[string]interface{}, op string) (bool, error) {
+	value, err := node.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("operador %s espera bool, recebido %s", op, exprTypeName(value))
+	}
+	return result, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *binaryNode) eval(vars map[string]interface{}) (interface{}, error) {
+	// && e || avaliam o lado direito apenas quando necessário, como no CEL
+	if n.op == "&&" || n.op == "||" {
+		left, err := evalBoolOperand(n.left, vars, n.op)
+		if err != nil {
+			return nil, err
+		}
+		if left == (n.op == "||") {
+			return left, nil
+		}
+		return evalBoolOperand(n.right, vars, n.op)
+	}
+
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return exprEqual(left, right), nil
+	case "!=":
+		return !exprEqual(left, right), nil
+	case "in":
+		return exprContains(right, left)
+	case "<", "<=", ">", ">=":
+		cmp, err := exprCompare(left, right)
+		if err != nil {
+			return nil, fmt.Errorf("operador %s: %v", n.op, err)
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		}
+		return cmp >= 0, nil
+	}
+	return exprArithmetic(n.op, left, right)
+}
+
+// exprNumber converts ints and doubles to float64
+func exprNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func exprEqual(left, right interface{}) bool {
+	if l, ok := exprNumber(left); ok {
+		r, ok := exprNumber(right)
+		return ok && l == r
+	}
+	switch l := left.(type) {
+	case string, bool, nil:
+		return l == right
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !exprEqual(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func exprCompare(left, right interface{}) (int, error) {
+	if l, ok := exprNumber(left); ok {
+		if r, ok := exprNumber(right); ok {
+			switch {
+			case l < r:
+				return -1, nil
+			case l > r:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	}
+	return 0, fmt.Errorf("não é possível comparar %s com %s", exprTypeName(left), exprTypeName(right))
+}
+
+// exprContains implements "in": an element of a list or a key of a map
+func exprContains(container, value interface{}) (bool, error) {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, item := range c {
+			if exprEqual(item, value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		key, ok := value.(string)
+		_, exists := c[key]
+		return ok && exists, nil
+	case map[string]string:
+		key, ok := value.(string)
+		_, exists := c[key]
+		return ok && exists, nil
+	}
+	return false, fmt.Errorf("operador in espera lista ou mapa, recebido %s", exprTypeName(container))
+}
+
+func exprArithmetic(op string, left, right interface{}) (interface{}, error) {
+	if op == "+" {
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		}
+		if l, ok := left.([]interface{}); ok {
+			if r, ok := right.([]interface{}); ok {
+				return append(append([]interface{}{}, l...), r...), nil
+			}
+		}
+	}
+	if l, ok := left.(int64); ok {
+		if r, ok := right.(int64); ok {
+			return exprIntArithmetic(op, l, r)
+		}
+	}
+	l, okLeft := exprNumber(left)
+	r, okRight := exprNumber(right)
+	if !okLeft || !okRight {
+		return nil, fmt.Errorf("operador %s não se aplica a %s e %s", op, exprTypeName(left), exprTypeName(right))
+	}
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		return l / r, nil
+	}
+	return math.Mod(l, r), nil
+}
+
+// exprIntArithmetic operates on ints, failing on overflow instead of wrapping around, as in CEL
+func exprIntArithmetic(op string, l, r int64) (interface{}, error) {
+	overflow := false
+	switch op {
+	case "+":
+		overflow = (r > 0 && l > math.MaxInt64-r) || (r < 0 && l < math.MinInt64-r)
+	case "-":
+		overflow = (r < 0 && l > math.MaxInt64+r) || (r > 0 && l < math.MinInt64+r)
+	case "*":
+		overflow = l != 0 && r != 0 && ((l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64) || (l*r)/r != l)
+	default:
+		if r == 0 {
+			return nil, fmt.Errorf("divisão por zero")
+		}
+		// O resto de MinInt64 por -1 é 0; apenas a divisão estoura
+		overflow = op == "/" && l == math.MinInt64 && r == -1
+	}
+	if overflow {
+		return nil, fmt.Errorf("estouro de inteiro: %d %s %d fora do intervalo de int", l, op, r)
+	}
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		return l / r, nil
+	}
+	return l % r, nil
+}
+
+type callNode struct {
+	name   string
+	target exprNode
+	args   []exprNode
+}
+
+func (n *callNode) eval(vars map[string]interface{}) (interface{}, error) {
+	var values []interface{}
+	if n.target != nil {
+		target, err := n.target.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, target)
+	}
+	for _, arg := range n.args {
+		value, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	result, err := exprCall(n.name, values)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %v", n.name, err)
+	}
+	return result, nil
+}
+
+// exprCall runs a function; for methods, the receiver is the first value
+func exprCall(name string, values []interface{}) (interface{}, error) {
+	arity := map[string]int{
+		"startsWith": 2, "endsWith": 2, "contains": 2, "matches": 2,
+		"lowerAscii": 1, "upperAscii": 1, "size": 1, "int": 1, "double": 1, "string": 1,
+	}
+	expected, exists := arity[name]
+	if !exists {
+		return nil, fmt.Errorf("função desconhecida")
+	}
+	if len(values) != expected {
+		return nil, fmt.Errorf("esperados %d argumentos, recebidos %d", expected, len(values))
+	}
+
+	switch name {
+	case "size":
+		switch v := values[0].(type) {
+		case string:
+			return int64(len([]rune(v))), nil
+		case []interface{}:
+			return int64(len(v)), nil
+		case map[string]interface{}:
+			return int64(len(v)), nil
+		case map[string]string:
+			return int64(len(v)), nil
+		}
+		return nil, fmt.Errorf("não se aplica a %s", exprTypeName(values[0]))
+	case "int":
+		switch v := values[0].(type) {
+		case int64:
+			return v, nil
+		case float64:
+			// 2^63 não é representável em int; valores fora do intervalo (e NaN) são erro, como no CEL
+			if math.IsNaN(v) || v >= math.MaxInt64 || v < math.MinInt64 {
+				return nil, fmt.Errorf("%v fora do intervalo de int", v)
+			}
+			return int64(v), nil
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		}
+		return nil, fmt.Errorf("não converte %s", exprTypeName(values[0]))
+	case "double":
+		if v, ok := exprNumber(values[0]); ok {
+			return v, nil
+		}
+		if v, ok := values[0].(string); ok {
+			return strconv.ParseFloat(v, 64)
+		}
+		return nil, fmt.Errorf("não converte %s", exprTypeName(values[0]))
+	case "string":
+		switch v := values[0].(type) {
+		case string:
+			return v, nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+		return nil, fmt.Errorf("não converte %s", exprTypeName(values[0]))
+	}
+
+	strs := make([]string, len(values))
+	for i, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("espera string, recebido %s", exprTypeName(value))
+		}
+		strs[i] = s
+	}
+	switch name {
+	case "startsWith":
+		return strings.HasPrefix(strs[0], strs[1]), nil
+	case "endsWith":
+		return strings.HasSuffix(strs[0], strs[1]), nil
+	case "contains":
+		return strings.Contains(strs[0], strs[1]), nil
+	case "matches":
+		re, err := regexp.Compile(strs[1])
+		if err != nil {
+			return nil, fmt.Errorf("expressão regular inválida: %v", err)
+		}
+		return re.MatchString(strs[0]), nil
+	case "lowerAscii":
+		return asciiCase(strs[0], 'A', 'Z', 'a'-'A'), nil
+	}
+	return asciiCase(strs[0], 'a', 'z', 'A'-'a'), nil
+}
+
+// asciiCase shifts the letters between from and to by delta, leaving the other characters (including
+// non-ASCII letters) unchanged
+func asciiCase(s string, from, to, delta rune) string {
+	return strings.Map(func(r rune) rune {
+		if r >= from && r <= to {
+			return r + delta
+		}
+		return r
+	}, s)
+}
+
+// exprTypeName returns the name of the type of the value, as in CEL
+func exprTypeName(value interface{}) string {
+	switch value.(type) {
+	case int64:
+		return "int"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case nil:
+		return "null"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}, map[string]string:
+		return "map"
+	}
+	return fmt.Sprintf("%T", value)
+}
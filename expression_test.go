@@ -0,0 +1,288 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func expressionTestVars() map[string]interface{} {
+	return map[string]interface{}{
+		"deployment": map[string]interface{}{
+			"name":     "checkout",
+			"replicas": int64(3),
+			"labels":   map[string]string{"team": "payments"},
+			"images":   []interface{}{"registry.local/checkout:1.2"},
+		},
+	}
+}
+
+// evalExpression compiles and evaluates the source; compilation errors are returned like evaluation errors
+func evalExpression(t *testing.T, source string) (interface{}, error) {
+	t.Helper()
+	expr, err := compileExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	return expr.eval(expressionTestVars())
+}
+
+func checkExpression(t *testing.T, source string, want interface{}, wantErr string) {
+	t.Helper()
+	got, err := evalExpression(t, source)
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("%s: erro esperado contendo %q, obtido %v (valor %v)", source, wantErr, err, got)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("%s: erro inesperado: %v", source, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%s = %#v, esperado %#v", source, got, want)
+	}
+}
+
+func TestExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		source string
+		want   interface{}
+	}{
+		{"1 + 2 * 3", int64(7)},
+		{"(1 + 2) * 3", int64(9)},
+		{"10 - 4 - 3", int64(3)},
+		{"100 / 10 / 5", int64(2)},
+		{"7 % 4 * 2", int64(6)},
+		{"-2 * 3", int64(-6)},
+		{"--2", int64(2)},
+		{"1 + 2 == 3", true},
+		{"2 < 3 == true", true},
+		{"true || false && false", true},
+		{"(true || false) && false", false},
+		{"!true || true", true},
+		{"!(true || true)", false},
+		{"true ? 1 : 2 + 3", int64(1)},
+		{"false ? 1 : 2 + 3", int64(5)},
+		{"false ? 1 : true ? 2 : 3", int64(2)},
+		// Inteiros e decimais se misturam sem conversão, ao contrário do CEL
+		{"1 + 2.5", 3.5},
+		{"1 == 1.0", true},
+		{"'a' + 'b' == 'ab'", true},
+		{"deployment.replicas * 2 > 5 && deployment.name.startsWith('check')", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			checkExpression(t, tt.source, tt.want, "")
+		})
+	}
+}
+
+func TestExpressionShortCircuit(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    interface{}
+		wantErr string
+	}{
+		// O lado direito não é avaliado: a variável desconhecida e a divisão por zero não geram erro
+		{"false && unknown", false, ""},
+		{"true || unknown", true, ""},
+		{"true || 1 / 0 == 1", true, ""},
+		{"false && deployment.missing == 1", false, ""},
+		{"has(deployment.labels.owner) && deployment.labels.owner == 'x'", false, ""},
+		{"false ? 1 / 0 : 2", int64(2), ""},
+		{"true ? 2 : 1 / 0", int64(2), ""},
+		// Quando o lado esquerdo não decide, o direito é avaliado
+		{"true && unknown", nil, "variável desconhecida \"unknown\""},
+		{"false || 1 / 0 == 1", nil, "divisão por zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			checkExpression(t, tt.source, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestExpressionHas(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    interface{}
+		wantErr string
+	}{
+		{"has(deployment.labels.team)", true, ""},
+		{"has(deployment.labels.owner)", false, ""},
+		{"has(deployment.replicas)", true, ""},
+		{"has(deployment['name'])", true, ""},
+		{"!has(deployment.annotations)", true, ""},
+		{"has(deployment.missing.team)", nil, "campo inexistente missing"},
+		{"has(deployment.replicas.value)", nil, "int não tem campos"},
+		{"has(deployment)", nil, "has recebe um campo"},
+		{"has(deployment.name, deployment.replicas)", nil, "has recebe um único campo"},
+		{"deployment.labels.owner == 'x'", nil, "use has() para campos opcionais"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			checkExpression(t, tt.source, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestExpressionIn(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    interface{}
+		wantErr string
+	}{
+		{"'a' in ['a', 'b']", true, ""},
+		{"'c' in ['a', 'b']", false, ""},
+		{"1 in [1.0, 2.0]", true, ""},
+		{"[1] in [[1], [2]]", true, ""},
+		{"'x' in []", false, ""},
+		{"'team' in deployment.labels", true, ""},
+		{"'owner' in deployment.labels", false, ""},
+		{"'replicas' in deployment", true, ""},
+		{"1 in deployment.labels", false, ""},
+		{"'registry.local/checkout:1.2' in deployment.images", true, ""},
+		{"1 + 1 in [2]", true, ""},
+		{"'a' in 'abc'", nil, "operador in espera lista ou mapa, recebido string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			checkExpression(t, tt.source, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestExpressionTypeErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr string
+	}{
+		{"1 + 'a'", "operador + não se aplica a int e string"},
+		{"'a' - 'b'", "operador - não se aplica a string e string"},
+		{"'a' < 1", "não é possível comparar string com int"},
+		{"null < 1", "não é possível comparar null com int"},
+		{"!1", "operador ! espera bool, recebido int"},
+		{"1 && true", "operador && espera bool, recebido int"},
+		{"true || 'a'", ""},
+		{"false || 'a'", "operador || espera bool, recebido string"},
+		{"1 ? 2 : 3", "operador ? espera bool, recebido int"},
+		{"-'a'", "operador - não se aplica a string"},
+		{"deployment.labels.team + 1", "operador + não se aplica a string e int"},
+		{"deployment.images['a']", "índice de lista deve ser int, recebido string"},
+		{"deployment.images[5]", "índice 5 fora da lista de 1 elementos"},
+		{"deployment[1]", "chave de mapa deve ser string, recebido int"},
+		{"size(1)", "size(): não se aplica a int"},
+		{"deployment.replicas.startsWith('a')", "startsWith(): espera string, recebido int"},
+		{"int('x')", "int(): "},
+		{"unknown(1)", "unknown(): função desconhecida"},
+		{"size('a', 'b')", "size(): esperados 1 argumentos, recebidos 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if tt.wantErr == "" {
+				checkExpression(t, tt.source, true, "")
+				return
+			}
+			checkExpression(t, tt.source, nil, tt.wantErr)
+		})
+	}
+}
+
+func TestExpressionSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr string
+	}{
+		{"1 +", "posição 4: expressão incompleta"},
+		{"(1 + 2", "posição 7: esperado \")\" no fim da expressão"},
+		{"1 2", "posição 3: token inesperado \"2\""},
+		{"'abc", "posição 1: string sem aspas de fechamento"},
+		{"1 # 2", "posição 3: caractere inválido '#'"},
+		{"true ? 1", "esperado \":\""},
+		{"99999999999999999999", "número inválido"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if _, err := compileExpression(tt.source); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("%s: erro esperado contendo %q, obtido %v", tt.source, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExpressionIntOverflow(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    interface{}
+		wantErr string
+	}{
+		{"9223372036854775807 + 1", nil, "estouro de inteiro"},
+		{"9223372036854775806 + 1", int64(math.MaxInt64), ""},
+		{"-9223372036854775807 - 2", nil, "estouro de inteiro"},
+		{"-9223372036854775807 - 1", int64(math.MinInt64), ""},
+		{"1 - -9223372036854775807", nil, "estouro de inteiro"},
+		{"4611686018427387904 * 2", nil, "estouro de inteiro"},
+		{"4611686018427387904 * -2", int64(math.MinInt64), ""},
+		{"-4611686018427387905 * 2", nil, "estouro de inteiro"},
+		{"(-9223372036854775807 - 1) * -1", nil, "estouro de inteiro"},
+		{"-1 * (-9223372036854775807 - 1)", nil, "estouro de inteiro"},
+		{"3037000500 * 3037000500", nil, "estouro de inteiro"},
+		{"3037000499 * 3037000499", int64(9223372030926249001), ""},
+		{"-(-9223372036854775807 - 1)", nil, "estouro de inteiro"},
+		{"(-9223372036854775807 - 1) / -1", nil, "estouro de inteiro"},
+		{"(-9223372036854775807 - 1) % -1", int64(0), ""},
+		{"1 / 0", nil, "divisão por zero"},
+		{"1 % 0", nil, "divisão por zero"},
+		{"int(9223372036854775807.0)", nil, "fora do intervalo de int"},
+		{"int(-1e19)", nil, "fora do intervalo de int"},
+		{"int(-9223372036854775808.0)", int64(math.MinInt64), ""},
+		// O menor int é escrito como literal negativo
+		{"-9223372036854775808", int64(math.MinInt64), ""},
+		{"-9223372036854775808 + 1", int64(math.MinInt64 + 1), ""},
+		{"--9223372036854775808", nil, "estouro de inteiro"},
+		{"-9223372036854775808 - 1", nil, "estouro de inteiro"},
+		{"- 9223372036854775808", int64(math.MinInt64), ""},
+		// Decimais seguem o IEEE 754, sem erro de estouro
+		{"9223372036854775807 + 1.0", 9223372036854775808.0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			checkExpression(t, tt.source, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestExpressionStringMethods(t *testing.T) {
+	tests := []struct {
+		source string
+		want   interface{}
+	}{
+		{"'ABC-xyz'.lowerAscii()", "abc-xyz"},
+		{"'ABC-xyz'.upperAscii()", "ABC-XYZ"},
+		// Apenas letras ASCII mudam de caixa
+		{"'ÉCOLE'.lowerAscii()", "École"},
+		{"'école'.upperAscii()", "éCOLE"},
+		{"'checkout'.startsWith('check')", true},
+		{"'checkout'.endsWith('out')", true},
+		{"'checkout'.contains('ecko')", true},
+		{"'checkout-v2'.matches('^[a-z]+-v[0-9]$')", true},
+		{"size('ação')", int64(4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			checkExpression(t, tt.source, tt.want, "")
+		})
+	}
+}
+
+func TestExpressionEvalBool(t *testing.T) {
+	expr, err := compileExpression("deployment.replicas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := expr.evalBool(expressionTestVars()); err == nil || !strings.Contains(err.Error(), "deve resultar em bool, resultou em int") {
+		t.Fatalf("erro esperado para resultado não booleano, obtido %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Ocupação a partir da qual um recurso estendido é considerado esgotado
+const extendedResourceFullPct = 90.0
+
+// Maior número de workloads listados por recurso
+const maxExtendedResourceConsumers = 5
+
+// isTrackedExtendedResource reports whether the resource is tracked here: hugepages and extended
+// resources (devices, vendor resources), but not CPU, memory, pods and ephemeral storage
+func isTrackedExtendedResource(name corev1.ResourceName) bool {
+	switch name {
+	case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourcePods, corev1.ResourceEphemeralStorage:
+		return false
+	}
+	return true
+}
+
+// formatExtendedQuantity formats the hugepages in bytes and the other resources as counts
+func formatExtendedQuantity(name corev1.ResourceName, value int64) string {
+	if strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix) {
+		return formatMemory(value)
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+// podExtendedRequests returns the effective requests of the pod for the tracked resources: the largest
+// of the app containers (with the sidecars) and each init container
+func podExtendedRequests(pod *corev1.Pod) map[corev1.ResourceName]int64 {
+	requests := make(map[corev1.ResourceName]int64)
+	add := func(c *corev1.Container) {
+		for name, q := range c.Resources.Requests {
+			if isTrackedExtendedResource(name) {
+				requests[name] += q.Value()
+			}
+		}
+	}
+	for i := range pod.Spec.Containers {
+		add(&pod.Spec.Containers[i])
+	}
+	for i := range pod.Spec.InitContainers {
+		if isSidecar(&pod.Spec.InitContainers[i]) {
+			add(&pod.Spec.InitContainers[i])
+		}
+	}
+	for i := range pod.Spec.InitContainers {
+		c := &pod.Spec.InitContainers[i]
+		if isSidecar(c) {
+			continue
+		}
+		for name, q := range c.Resources.Requests {
+			if isTrackedExtendedResource(name) {
+				requests[name] = max(requests[name], q.Value())
+			}
+		}
+	}
+	return requests
+}
+
+// ExtendedResourceNode is the allocation of an extended resource on a node
+type ExtendedResourceNode struct {
+	Name        string
+	Allocatable int64
+	Allocated   int64
+}
+
+// ExtendedResourceConsumer is a workload requesting an extended resource
+type ExtendedResourceConsumer struct {
+	Namespace string
+	Workload  string
+	Pods      int
+	Requested int64
+}
+
+// ExtendedResourceUsage is the allocation of an extended resource across the cluster
+type ExtendedResourceUsage struct {
+	Name        corev1.ResourceName
+	Allocatable int64
+	Allocated   int64
+	Nodes       []ExtendedResourceNode
+	Consumers   []ExtendedResourceConsumer
+	// Pods pendentes que pedem o recurso
+	Pending []string
+}
+
+func (u *ExtendedResourceUsage) fullNodes() int {
+	count := 0
+	for _, n := range u.Nodes {
+		if n.Allocatable > 0 && percent(n.Allocated, n.Allocatable) >= extendedResourceFullPct {
+			count++
+		}
+	}
+	return count
+}
+
+// analyzeExtendedResources compares the requests of hugepages and extended resources with the
+// allocatable of the nodes, by resource
+func analyzeExtendedResources(nodes []corev1.Node, pods []corev1.Pod, deploymentIndex map[string]*DeploymentMetrics) []*ExtendedResourceUsage {
+	usages := make(map[corev1.ResourceName]*ExtendedResourceUsage)
+	usage := func(name corev1.ResourceName) *ExtendedResourceUsage {
+		if _, exists := usages[name]; !exists {
+			usages[name] = &ExtendedResourceUsage{Name: name}
+		}
+		return usages[name]
+	}
+
+	nodeIndex := make(map[string]map[corev1.ResourceName]*ExtendedResourceNode)
+	for _, node := range nodes {
+		nodeIndex[node.Name] = make(map[corev1.ResourceName]*ExtendedResourceNode)
+		for name, q := range node.Status.Allocatable {
+			if !isTrackedExtendedResource(name) || q.IsZero() {
+				continue
+			}
+			u := usage(name)
+			u.Allocatable += q.Value()
+			u.Nodes = append(u.Nodes, ExtendedResourceNode{Name: node.Name, Allocatable: q.Value()})
+		}
+	}
+	for _, u := range usages {
+		for i := range u.Nodes {
+			nodeIndex[u.Nodes[i].Name][u.Name] = &u.Nodes[i]
+		}
+	}
+
+	consumers := make(map[corev1.ResourceName]map[string]*ExtendedResourceConsumer)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for name, value := range podExtendedRequests(pod) {
+			if value == 0 {
+				continue
+			}
+			u := usage(name)
+			if pod.Spec.NodeName == "" {
+				u.Pending = append(u.Pending, pod.Namespace+"/"+pod.Name)
+				continue
+			}
+			u.Allocated += value
+			if n, exists := nodeIndex[pod.Spec.NodeName][name]; exists {
+				n.Allocated += value
+			}
+
+			workload := workloadForPod(pod, deploymentIndex)
+			if consumers[name] == nil {
+				consumers[name] = make(map[string]*ExtendedResourceConsumer)
+			}
+			c, exists := consumers[name][pod.Namespace+"/"+workload]
+			if !exists {
+				c = &ExtendedResourceConsumer{Namespace: pod.Namespace, Workload: workload}
+				consumers[name][pod.Namespace+"/"+workload] = c
+			}
+			c.Pods++
+			c.Requested += value
+		}
+	}
+
+	result := make([]*ExtendedResourceUsage, 0, len(usages))
+	for name, u := range usages {
+		for _, c := range consumers[name] {
+			u.Consumers = append(u.Consumers, *c)
+		}
+		sort.Slice(u.Consumers, func(i, j int) bool {
+			return u.Consumers[i].Requested > u.Consumers[j].Requested
+		})
+		if len(u.Consumers) > maxExtendedResourceConsumers {
+			u.Consumers = u.Consumers[:maxExtendedResourceConsumers]
+		}
+		sort.Slice(u.Nodes, func(i, j int) bool {
+			return percent(u.Nodes[i].Allocated, u.Nodes[i].Allocatable) > percent(u.Nodes[j].Allocated, u.Nodes[j].Allocatable)
+		})
+		sort.Strings(u.Pending)
+		result = append(result, u)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// extendedResourceFindings reports the resources with pods waiting for them
+func extendedResourceFindings(usages []*ExtendedResourceUsage) []Finding {
+	var findings []Finding
+	for _, u := range usages {
+		if len(u.Pending) == 0 {
+			continue
+		}
+		recommendation := "adicionar nodes com o recurso ou liberar as alocações ociosas dos workloads que o usam"
+		if u.Allocatable == 0 {
+			recommendation = "nenhum node anuncia o recurso: verificar o device plugin e os nodes do pool que deveria oferecê-lo"
+		}
+		findings = append(findings, Finding{
+			Kind:     "recurso-estendido",
+			Severity: SeverityHigh,
+			Title:    fmt.Sprintf("Pods pendentes aguardando %s", u.Name),
+			Workload: string(u.Name),
+			Body: fmt.Sprintf("%d pods pendentes pedem %s; %s de %s allocatable estão alocados.\n\nRecomendação: %s.",
+				len(u.Pending), u.Name, formatExtendedQuantity(u.Name, u.Allocated), formatExtendedQuantity(u.Name, u.Allocatable), recommendation),
+		})
+	}
+	return findings
+}
+
+func writeExtendedResources(w io.Writer, usages []*ExtendedResourceUsage) {
+	fmt.Fprintf(w, "\n=== Recursos Estendidos ===\n")
+	fmt.Fprintf(w, "---------------------------\n")
+
+	if len(usages) == 0 {
+		fmt.Fprintf(w, "Nenhum recurso estendido (hugepages, GPUs, dispositivos) anunciado pelos nodes ou pedido pelos pods\n")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "RECURSO\tNODES\tALLOCATABLE\tALOCADO\tOCUPAÇÃO\tNODES ESGOTADOS\tPODS PENDENTES\n")
+	for _, u := range usages {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%.0f%%\t%d\t%d\n", u.Name, len(u.Nodes),
+			formatExtendedQuantity(u.Name, u.Allocatable), formatExtendedQuantity(u.Name, u.Allocated),
+			percent(u.Allocated, u.Allocatable), u.fullNodes(), len(u.Pending))
+	}
+	tw.Flush()
+
+	for _, u := range usages {
+		if u.Allocated == 0 && len(u.Pending) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\nRecurso: %s\n", u.Name)
+		for _, n := range u.Nodes {
+			if n.Allocated == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "- Node %s: %s de %s (%.0f%%)\n", n.Name,
+				formatExtendedQuantity(u.Name, n.Allocated), formatExtendedQuantity(u.Name, n.Allocatable), percent(n.Allocated, n.Allocatable))
+		}
+		if len(u.Consumers) > 0 {
+			fmt.Fprintf(w, "Workloads:\n")
+			for _, c := range u.Consumers {
+				fmt.Fprintf(w, "  - %s (Namespace: %s): %d pods, %s\n", c.Workload, c.Namespace, c.Pods, formatExtendedQuantity(u.Name, c.Requested))
+			}
+		}
+		if len(u.Pending) > 0 {
+			fmt.Fprintf(w, "Pods pendentes: %s\n", strings.Join(u.Pending, ", "))
+			if u.Allocatable == 0 {
+				fmt.Fprintf(w, "Recomendação: nenhum node anuncia o recurso; verificar o device plugin e os nodes do pool que deveria oferecê-lo\n")
+			} else {
+				fmt.Fprintf(w, "Recomendação: adicionar nodes com o recurso ou liberar as alocações ociosas dos workloads listados\n")
+			}
+		}
+	}
+	fmt.Fprintf(w, "\nObservação: o uso efetivo de dispositivos não é exposto pelo Metrics Server; a ocupação considera apenas as alocações\n")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// Severity ranks how urgent a finding is
+type Severity int
+
+const (
+	SeverityLow Severity = iota
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = []string{"baixa", "média", "alta", "crítica"}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// parseSeverity accepts the Portuguese names (with or without accents) and their English equivalents
+func parseSeverity(name string) (Severity, error) {
+	switch strings.ToLower(name) {
+	case "baixa", "low":
+		return SeverityLow, nil
+	case "média", "media", "medium":
+		return SeverityMedium, nil
+	case "alta", "high":
+		return SeverityHigh, nil
+	case "crítica", "critica", "critical":
+		return SeverityCritical, nil
+	}
+	return 0, fmt.Errorf("severidade inválida: %s (use baixa, média, alta ou crítica)", name)
+}
+
+// Finding is a single issue detected by the analysis, in a form that can be sent to external trackers
+type Finding struct {
+	// Tipo do problema (ex: sem-limites), usado para identificar o mesmo problema entre execuções
+	Kind      string
+	Severity  Severity
+	Title     string
+	Namespace string
+	// Deployment ou workload afetado; vazio para problemas do namespace. Para problemas de um
+	// node, Namespace fica vazio e Workload é o nome do node
+	Workload string
+	Body     string
+	// Responsável pelo workload, obtido das labels do deployment
+	Owner string
+}
+
+// key identifies the finding across runs
+func (f Finding) key() string {
+	return f.Kind + "/" + f.Namespace + "/" + f.Workload
+}
+
+// collectFindings turns the results of the analyses into findings, together with the findings already
+// detected by other checks (risks, HPAs without requests), sorted by severity (highest first)
+func collectFindings(deploymentMetrics map[string]*DeploymentMetrics, unsatisfiable []UnsatisfiableRecommendation,
+	evicted []EvictedPod, preemptions *PreemptionReport, priorityCoverage *PriorityCoverage, budgetAlerts []BudgetAlert,
+	detected ...[]Finding) []Finding {
+	var findings []Finding
+	for _, d := range detected {
+		findings = append(findings, d...)
+	}
+
+	for _, dm := range deploymentMetrics {
+		if dm.PodsWithoutLimits == 0 {
+			continue
+		}
+		body := fmt.Sprintf("%d de %d pods do deployment não têm limites de CPU e memória definidos, o que pode causar problemas de performance no cluster.",
+			dm.PodsWithoutLimits, dm.TotalPods)
+		if (dm.MaxCPU > 0 || dm.MaxMemory > 0) && dm.InsufficientData == "" {
+			body += fmt.Sprintf("\n\nRecomendação: limites de CPU %s e Memory %s (máximo observado); requests de CPU %s e Memory %s (média observada).",
+				formatCPU(dm.MaxCPU), formatMemory(dm.MaxMemory), formatCPU(dm.AvgCPU), formatMemory(dm.AvgMemory))
+		} else {
+			body += "\n\nRecomendação: definir limites de recursos (CPU e Memory) para evitar consumo excessivo."
+		}
+		findings = append(findings, Finding{
+			Kind:      "sem-limites",
+			Severity:  SeverityHigh,
+			Title:     fmt.Sprintf("Deployment %s/%s sem limites de recursos", dm.Namespace, dm.Name),
+			Namespace: dm.Namespace,
+			Workload:  dm.Name,
+			Body:      body,
+		})
+	}
+
+	for _, u := range unsatisfiable {
+		findings = append(findings, Finding{
+			Kind:      "nao-agendavel",
+			Severity:  SeverityMedium,
+			Title:     fmt.Sprintf("Requests sugeridos para %s/%s não cabem em nenhum node", u.Namespace, u.Deployment),
+			Namespace: u.Namespace,
+			Workload:  u.Deployment,
+			Body: fmt.Sprintf("Os requests sugeridos por pod (CPU %s, Memory %s) excedem o maior allocatable disponível (CPU %s, Memory %s).\n\nRecomendação: dividir a carga em mais réplicas ou adicionar nodes maiores.",
+				formatCPU(u.PodCPU), formatMemory(u.PodMemory), formatCPU(u.LargestCPU), formatMemory(u.LargestMemory)),
+		})
+	}
+
+	for _, g := range groupEvictions(evicted) {
+		findings = append(findings, Finding{
+			Kind:      "despejo",
+			Severity:  SeverityHigh,
+			Title:     fmt.Sprintf("Pods de %s/%s despejados por pressão de %s", g.Namespace, g.Workload, g.Resource),
+			Namespace: g.Namespace,
+			Workload:  g.Workload,
+			Body: fmt.Sprintf("%d pods despejados no node %s por falta de %s (%d durante a coleta).\n\nRecomendação: ajustar os requests para refletir o uso real, evitando que o node fique sobrecomprometido.",
+				g.Count, g.Node, g.Resource, g.During),
+		})
+	}
+
+	if preemptions != nil {
+		for _, p := range preemptions.Preempted {
+			severity := SeverityMedium
+			if p.Preemptions >= recurrentPreemptions {
+				severity = SeverityHigh
+			}
+			class := p.PriorityClass
+			if class == "" {
+				class = "(nenhuma)"
+			}
+			findings = append(findings, Finding{
+				Kind:      "preempcao",
+				Severity:  severity,
+				Title:     fmt.Sprintf("Workload %s/%s preemptado %d vezes", p.Namespace, p.Name, p.Preemptions),
+				Namespace: p.Namespace,
+				Workload:  p.Name,
+				Body: fmt.Sprintf("O workload foi removido %d vezes para dar lugar a pods de maior prioridade (PriorityClass atual: %s, valor %d).\n\nRecomendação: atribuir uma PriorityClass adequada à criticidade do workload.",
+					p.Preemptions, class, p.Priority),
+			})
+		}
+	}
+
+	if priorityCoverage != nil {
+		for _, c := range priorityCoverage.Critical {
+			findings = append(findings, Finding{
+				Kind:      "prioridade",
+				Severity:  SeverityMedium,
+				Title:     fmt.Sprintf("Workload de plataforma %s/%s com prioridade padrão", c.Namespace, c.Name),
+				Namespace: c.Namespace,
+				Workload:  c.Name,
+				Body:      fmt.Sprintf("Workload de plataforma (%s) sem PriorityClass.\n\nRecomendação: usar a PriorityClass %s.", c.Reason, c.SuggestedClass),
+			})
+		}
+	}
+
+	for _, a := range budgetAlerts {
+		findings = append(findings, Finding{
+			Kind:      "orcamento",
+			Severity:  SeverityHigh,
+			Title:     fmt.Sprintf("Namespace %s acima do orçamento mensal", a.Namespace),
+			Namespace: a.Namespace,
+			Body: fmt.Sprintf("Custo mensal projetado de %.2f para um orçamento de %.2f (%.0f%%).",
+				a.Projected, a.Budget, costShare(a.Projected, a.Budget)),
+		})
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity > findings[j].Severity
+		}
+		return findings[i].key() < findings[j].key()
+	})
+	return findings
+}
+
+// assignOwners fills the owner of each finding with the first of the labels present on its deployment
+func assignOwners(findings []Finding, deployments map[string]*appsv1.Deployment, labels []string) {
+	for i := range findings {
+		d, exists := deployments[findings[i].Namespace+"/"+findings[i].Workload]
+		if !exists {
+			continue
+		}
+		for _, label := range labels {
+			if owner := d.Labels[label]; owner != "" {
+				findings[i].Owner = owner
+				break
+			}
+		}
+	}
+}
+
+// filterFindings returns the findings at or above the given severity
+func filterFindings(findings []Finding, minimum Severity) []Finding {
+	var result []Finding
+	for _, f := range findings {
+		if f.Severity >= minimum {
+			result = append(result, f)
+		}
+	}
+	return result
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// Labels aplicadas pelo Flux aos objetos que ele gerencia
+const (
+	fluxHelmNameLabel           = "helm.toolkit.fluxcd.io/name"
+	fluxHelmNamespaceLabel      = "helm.toolkit.fluxcd.io/namespace"
+	fluxKustomizeNameLabel      = "kustomize.toolkit.fluxcd.io/name"
+	fluxKustomizeNamespaceLabel = "kustomize.toolkit.fluxcd.io/namespace"
+)
+
+// Ferramentas de origem dos workloads gerenciados pelo Flux
+const (
+	sourceToolFluxHelm      = "Flux HelmRelease"
+	sourceToolFluxKustomize = "Flux Kustomization"
+)
+
+// Versões dos CRDs do Flux, da mais recente para a mais antiga
+var (
+	fluxHelmReleaseResources = []schema.GroupVersionResource{
+		{Group: "helm.toolkit.fluxcd.io", Version: "v2", Resource: "helmreleases"},
+		{Group: "helm.toolkit.fluxcd.io", Version: "v2beta2", Resource: "helmreleases"},
+		{Group: "helm.toolkit.fluxcd.io", Version: "v2beta1", Resource: "helmreleases"},
+	}
+	fluxKustomizationResources = []schema.GroupVersionResource{
+		{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"},
+		{Group: "kustomize.toolkit.fluxcd.io", Version: "v1beta2", Resource: "kustomizations"},
+	}
+	fluxSourceResources = map[string][]schema.GroupVersionResource{
+		"GitRepository": {
+			{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "gitrepositories"},
+			{Group: "source.toolkit.fluxcd.io", Version: "v1beta2", Resource: "gitrepositories"},
+		},
+		"HelmRepository": {
+			{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "helmrepositories"},
+			{Group: "source.toolkit.fluxcd.io", Version: "v1beta2", Resource: "helmrepositories"},
+		},
+		"OCIRepository": {
+			{Group: "source.toolkit.fluxcd.io", Version: "v1beta2", Resource: "ocirepositories"},
+		},
+	}
+)
+
+// listFluxObjects lists the objects of the first served version of a Flux CRD. A missing CRD is not an error
+func listFluxObjects(dynamicClient dynamic.Interface, resources []schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	for _, resource := range resources {
+		list, err := dynamicClient.Resource(resource).Namespace("").List(context.TODO(), metav1.ListOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar %s: %v", resource.Resource, err)
+		}
+		return list.Items, nil
+	}
+	return nil, nil
+}
+
+// fluxSourceURLs indexes the URL of the Flux sources by "<kind>/<namespace>/<nome>"
+func fluxSourceURLs(dynamicClient dynamic.Interface) (map[string]string, error) {
+	urls := make(map[string]string)
+	for kind, resources := range fluxSourceResources {
+		items, err := listFluxObjects(dynamicClient, resources)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			url, _, _ := unstructured.NestedString(item.Object, "spec", "url")
+			urls[kind+"/"+item.GetNamespace()+"/"+item.GetName()] = url
+		}
+	}
+	return urls, nil
+}
+
+// fluxSourceRef resolves a sourceRef to the URL of the source (the namespace defaults to the object's own)
+func fluxSourceRef(ref map[string]interface{}, namespace string, urls map[string]string) string {
+	kind, _, _ := unstructured.NestedString(ref, "kind")
+	name, _, _ := unstructured.NestedString(ref, "name")
+	if ns, _, _ := unstructured.NestedString(ref, "namespace"); ns != "" {
+		namespace = ns
+	}
+	if url := urls[kind+"/"+namespace+"/"+name]; url != "" {
+		return url
+	}
+	return fmt.Sprintf("%s %s/%s", kind, namespace, name)
+}
+
+// listFluxSources indexes the HelmReleases and Kustomizations by "<tool>/<namespace>/<nome>"
+func listFluxSources(dynamicClient dynamic.Interface) (map[string]*WorkloadSource, error) {
+	urls, err := fluxSourceURLs(dynamicClient)
+	if err != nil {
+		return nil, err
+	}
+	sources := make(map[string]*WorkloadSource)
+
+	releases, err := listFluxObjects(dynamicClient, fluxHelmReleaseResources)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range releases {
+		source := &WorkloadSource{Tool: sourceToolFluxHelm, Name: item.GetNamespace() + "/" + item.GetName()}
+		chart, _, _ := unstructured.NestedMap(item.Object, "spec", "chart", "spec")
+		if name, _, _ := unstructured.NestedString(chart, "chart"); name != "" {
+			source.Path = "chart " + name
+		}
+		source.Revision, _, _ = unstructured.NestedString(chart, "version")
+		if ref, found, _ := unstructured.NestedMap(chart, "sourceRef"); found {
+			source.Repo = fluxSourceRef(ref, item.GetNamespace(), urls)
+		}
+		sources[sourceToolFluxHelm+"/"+source.Name] = source
+	}
+
+	kustomizations, err := listFluxObjects(dynamicClient, fluxKustomizationResources)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range kustomizations {
+		source := &WorkloadSource{Tool: sourceToolFluxKustomize, Name: item.GetNamespace() + "/" + item.GetName()}
+		source.Path, _, _ = unstructured.NestedString(item.Object, "spec", "path")
+		if ref, found, _ := unstructured.NestedMap(item.Object, "spec", "sourceRef"); found {
+			source.Repo = fluxSourceRef(ref, item.GetNamespace(), urls)
+		}
+		sources[sourceToolFluxKustomize+"/"+source.Name] = source
+	}
+	return sources, nil
+}
+
+// fluxSourceFor returns the HelmRelease or Kustomization that manages the deployment, if any. A
+// HelmRelease takes precedence, since its Kustomization only applies the HelmRelease itself
+func fluxSourceFor(d *appsv1.Deployment, sources map[string]*WorkloadSource) *WorkloadSource {
+	if name := d.Labels[fluxHelmNameLabel]; name != "" {
+		if source, exists := sources[sourceToolFluxHelm+"/"+d.Labels[fluxHelmNamespaceLabel]+"/"+name]; exists {
+			return source
+		}
+	}
+	if name := d.Labels[fluxKustomizeNameLabel]; name != "" {
+		if source, exists := sources[sourceToolFluxKustomize+"/"+d.Labels[fluxKustomizeNamespaceLabel]+"/"+name]; exists {
+			return source
+		}
+	}
+	return nil
+}
+
+// mapFluxSources sets the source of the deployments managed by Flux that have no source yet,
+// returning how many were mapped
+func mapFluxSources(deploymentMetrics map[string]*DeploymentMetrics, deployments map[string]*appsv1.Deployment, sources map[string]*WorkloadSource) int {
+	mapped := 0
+	for key, dm := range deploymentMetrics {
+		d, exists := deployments[key]
+		if !exists || dm.Source != nil {
+			continue
+		}
+		if source := fluxSourceFor(d, sources); source != nil {
+			dm.Source = source
+			mapped++
+		}
+	}
+	return mapped
+}
+
+// fluxPatchSnippet renders the resource patch in the format expected by the Flux object that manages
+// the deployment: spec.patches of a Kustomization or spec.postRenderers of a HelmRelease
+func fluxPatchSnippet(p ResourcePatch, source *WorkloadSource) (string, error) {
+	data, err := p.strategicMergePatch()
+	if err != nil {
+		return "", err
+	}
+	var spec map[string]interface{}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return "", err
+	}
+	spec["apiVersion"] = "apps/v1"
+	spec["kind"] = "Deployment"
+	spec["metadata"] = map[string]string{"name": p.Deployment}
+	patch, err := yaml.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+
+	patches := []interface{}{map[string]interface{}{
+		"target": map[string]string{"kind": "Deployment", "name": p.Deployment, "namespace": p.Namespace},
+		"patch":  string(patch),
+	}}
+	var snippet map[string]interface{}
+	if source.Tool == sourceToolFluxHelm {
+		snippet = map[string]interface{}{"spec": map[string]interface{}{
+			"postRenderers": []interface{}{map[string]interface{}{
+				"kustomize": map[string]interface{}{"patches": patches},
+			}},
+		}}
+	} else {
+		snippet = map[string]interface{}{"spec": map[string]interface{}{"patches": patches}}
+	}
+	out, err := yaml.Marshal(snippet)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// writeFluxPatches writes, for each patch of a deployment managed by Flux, the snippet to be added to
+// its HelmRelease or Kustomization
+func writeFluxPatches(w io.Writer, patches []ResourcePatch, deploymentMetrics map[string]*DeploymentMetrics) {
+	var managed []ResourcePatch
+	for _, p := range patches {
+		if dm, exists := deploymentMetrics[p.Namespace+"/"+p.Deployment]; exists && dm.Source != nil &&
+			(dm.Source.Tool == sourceToolFluxHelm || dm.Source.Tool == sourceToolFluxKustomize) {
+			managed = append(managed, p)
+		}
+	}
+	if len(managed) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n=== Patches para o Flux ===\n")
+	fmt.Fprintf(w, "---------------------------\n")
+	for _, p := range managed {
+		source := deploymentMetrics[p.Namespace+"/"+p.Deployment].Source
+		snippet, err := fluxPatchSnippet(p, source)
+		if err != nil {
+			fmt.Fprintf(w, "\nErro ao gerar patch de %s/%s: %v\n", p.Namespace, p.Deployment, err)
+			continue
+		}
+		fmt.Fprintf(w, "\nDeployment: %s (Namespace: %s)\n", p.Deployment, p.Namespace)
+		fmt.Fprintf(w, "Adicionar em %s:\n", source)
+		fmt.Fprintf(w, "%s", snippet)
+	}
+	fmt.Fprintf(w, "\nObservação: Se o objeto já tiver patches ou postRenderers, acrescente a entrada à lista existente.\n")
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CapacityForecast holds the headroom projection for one resource
+type CapacityForecast struct {
+	Resource      string
+	Allocatable   int64
+	CurrentUsage  int64
+	GrowthPerDay  float64
+	ThresholdDate time.Time
+	// Status: "ok" (sem crescimento), "previsto", "excedido" ou "dados insuficientes"
+	Status string
+}
+
+// clusterAllocatable returns the total allocatable CPU (millicores) and memory (bytes) of the nodes
+func clusterAllocatable(nodes []corev1.Node) (int64, int64) {
+	var cpu, memory int64
+	for _, node := range nodes {
+		cpu += node.Status.Allocatable.Cpu().MilliValue()
+		memory += node.Status.Allocatable.Memory().Value()
+	}
+	return cpu, memory
+}
+
+// averageSamples returns the average and maximum cluster usage over the samples
+func averageSamples(samples []UsageSample) (avgCPU, avgMemory, maxCPU, maxMemory int64) {
+	if len(samples) == 0 {
+		return 0, 0, 0, 0
+	}
+	var totalCPU, totalMemory int64
+	for _, s := range samples {
+		totalCPU += s.CPU
+		totalMemory += s.Memory
+		if s.CPU > maxCPU {
+			maxCPU = s.CPU
+		}
+		if s.Memory > maxMemory {
+			maxMemory = s.Memory
+		}
+	}
+	n := int64(len(samples))
+	return totalCPU / n, totalMemory / n, maxCPU, maxMemory
+}
+
+// linearRegression fits y = slope*x + intercept by least squares
+func linearRegression(xs, ys []float64) (slope, intercept float64, ok bool) {
+	n := float64(len(xs))
+	if len(xs) < 2 || len(xs) != len(ys) {
+		return 0, 0, false
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+		sumXY += xs[i] * ys[i]
+		sumXX += xs[i] * xs[i]
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, 0, false
+	}
+	slope = (n*sumXY - sumX*sumY) / denominator
+	intercept = (sumY - slope*sumX) / n
+	return slope, intercept, true
+}
+
+// forecastResource projects when usage will leave less than headroomPercent of the allocatable free
+func forecastResource(resource string, allocatable int64, points []UsageSample, value func(UsageSample) int64, headroomPercent int, now time.Time) CapacityForecast {
+	forecast := CapacityForecast{Resource: resource, Allocatable: allocatable, Status: "dados insuficientes"}
+	if len(points) == 0 || allocatable == 0 {
+		return forecast
+	}
+
+	// Eixo x em dias a partir do primeiro ponto
+	start := points[0].Time
+	xs := make([]float64, 0, len(points))
+	ys := make([]float64, 0, len(points))
+	for _, p := range points {
+		xs = append(xs, p.Time.Sub(start).Hours()/24)
+		ys = append(ys, float64(value(p)))
+	}
+	forecast.CurrentUsage = value(points[len(points)-1])
+
+	limit := float64(allocatable) * float64(100-headroomPercent) / 100
+	if float64(forecast.CurrentUsage) >= limit {
+		forecast.Status = "excedido"
+		forecast.ThresholdDate = now
+		return forecast
+	}
+
+	slope, _, ok := linearRegression(xs, ys)
+	if !ok {
+		return forecast
+	}
+	forecast.GrowthPerDay = slope
+	if slope <= 0 {
+		forecast.Status = "ok"
+		return forecast
+	}
+
+	days := (limit - float64(forecast.CurrentUsage)) / slope
+	if math.IsInf(days, 0) || days > 365*10 {
+		forecast.Status = "ok"
+		return forecast
+	}
+	forecast.Status = "previsto"
+	forecast.ThresholdDate = now.Add(time.Duration(days * 24 * float64(time.Hour)))
+	return forecast
+}
+
+// forecastCapacity combines the run history with the samples of the current window
+func forecastCapacity(history []HistoryRecord, samples []UsageSample, nodes []corev1.Node, headroomPercent int, now time.Time) []CapacityForecast {
+	allocatableCPU, allocatableMemory := clusterAllocatable(nodes)
+
+	// Sem histórico, a tendência vem apenas das amostras da janela atual;
+	// com histórico, a janela atual entra como um único ponto (sua média)
+	points := samples
+	if len(history) > 0 {
+		points = make([]UsageSample, 0, len(history)+1)
+		for _, record := range history {
+			points = append(points, UsageSample{Time: record.Timestamp, CPU: record.AvgCPU, Memory: record.AvgMemory})
+		}
+		if len(samples) > 0 {
+			avgCPU, avgMemory, _, _ := averageSamples(samples)
+			points = append(points, UsageSample{Time: samples[len(samples)-1].Time, CPU: avgCPU, Memory: avgMemory})
+		}
+	}
+
+	// Uma tendência de poucos minutos (ex.: pico de deploy) projetaria datas sem sentido;
+	// exige o mesmo período mínimo da projeção de crescimento
+	if len(points) < 2 || points[len(points)-1].Time.Sub(points[0].Time) < minGrowthSpan {
+		points = nil
+	}
+
+	return []CapacityForecast{
+		forecastResource("CPU", allocatableCPU, points, func(s UsageSample) int64 { return s.CPU }, headroomPercent, now),
+		forecastResource("Memory", allocatableMemory, points, func(s UsageSample) int64 { return s.Memory }, headroomPercent, now),
+	}
+}
+
+func writeCapacityForecast(w io.Writer, forecasts []CapacityForecast, headroomPercent int, historyRuns int) {
+	fmt.Fprintf(w, "\nPrevisão de Capacidade (folga mínima de %d%%, %d execuções no histórico):\n", headroomPercent, historyRuns)
+	for _, f := range forecasts {
+		current := fmt.Sprintf("%s de %s", formatCPU(f.CurrentUsage), formatCPU(f.Allocatable))
+		growth := fmt.Sprintf("%s/dia", formatCPU(int64(f.GrowthPerDay)))
+		if f.Resource == "Memory" {
+			current = fmt.Sprintf("%s de %s", formatMemory(f.CurrentUsage), formatMemory(f.Allocatable))
+			growth = fmt.Sprintf("%s/dia", formatMemory(int64(f.GrowthPerDay)))
+		}
+
+		switch f.Status {
+		case "excedido":
+			fmt.Fprintf(w, "  %s: uso atual %s - folga já abaixo do limite\n", f.Resource, current)
+		case "previsto":
+			fmt.Fprintf(w, "  %s: uso atual %s, crescimento de %s - folga abaixo do limite previsto para %s\n",
+				f.Resource, current, growth, f.ThresholdDate.Format("2006-01-02"))
+		case "ok":
+			fmt.Fprintf(w, "  %s: uso atual %s - sem tendência de crescimento\n", f.Resource, current)
+		default:
+			fmt.Fprintf(w, "  %s: dados insuficientes para previsão (mínimo de %v de amostras ou histórico)\n", f.Resource, minGrowthSpan)
+		}
+	}
+}
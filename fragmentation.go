@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Limiares de fragmentação: um recurso está esgotado abaixo de fragmentationExhaustedPct livre, e o
+// outro recurso fica ocioso (stranded) quando ainda tem ao menos fragmentationStrandedPct livre
+const (
+	fragmentationExhaustedPct = 10.0
+	fragmentationStrandedPct  = 30.0
+	// Proporção de memória/CPU que difere da proporção do node por este fator é considerada desbalanceada
+	skewedRatioFactor = 2.0
+)
+
+// StrandedNode is a node where one resource is exhausted by requests while the other is left idle
+type StrandedNode struct {
+	Name string
+	// Recurso esgotado: "cpu" ou "memory"
+	Exhausted      string
+	FreeCPU        int64
+	FreeMemory     int64
+	StrandedCPU    int64
+	StrandedMemory int64
+}
+
+// SkewedRequestWorkload is a workload whose memory/CPU request ratio drives the fragmentation
+type SkewedRequestWorkload struct {
+	Name      string
+	Namespace string
+	// Recurso consumido em excesso em relação ao formato dos nodes
+	Heavy string
+	// MiB de memória por core requisitado
+	MemoryPerCore float64
+	Pods          int
+	Nodes         map[string]bool
+}
+
+// FragmentationReport quantifies the stranded capacity of the cluster
+type FragmentationReport struct {
+	Nodes          []StrandedNode
+	StrandedCPU    int64
+	StrandedMemory int64
+	Workloads      []SkewedRequestWorkload
+}
+
+// memoryPerCore returns the MiB of memory per CPU core of the given amounts
+func memoryPerCore(cpu, memory int64) float64 {
+	if cpu == 0 {
+		return 0
+	}
+	return float64(memory) / 1024 / 1024 / (float64(cpu) / 1000)
+}
+
+func analyzeFragmentation(nodes []corev1.Node, pods []corev1.Pod, deploymentIndex map[string]*DeploymentMetrics) *FragmentationReport {
+	report := &FragmentationReport{}
+
+	requestedCPU := make(map[string]int64)
+	requestedMemory := make(map[string]int64)
+	podsByNode := make(map[string][]*corev1.Pod)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		cpu, memory := podRequests(pod)
+		requestedCPU[pod.Spec.NodeName] += cpu
+		requestedMemory[pod.Spec.NodeName] += memory
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+
+	workloads := make(map[string]*SkewedRequestWorkload)
+	for _, node := range nodes {
+		allocatableCPU := node.Status.Allocatable.Cpu().MilliValue()
+		allocatableMemory := node.Status.Allocatable.Memory().Value()
+		if allocatableCPU == 0 || allocatableMemory == 0 {
+			continue
+		}
+
+		freeCPU := allocatableCPU - requestedCPU[node.Name]
+		freeMemory := allocatableMemory - requestedMemory[node.Name]
+		freeCPUPct := percent(freeCPU, allocatableCPU)
+		freeMemoryPct := percent(freeMemory, allocatableMemory)
+
+		stranded := StrandedNode{Name: node.Name, FreeCPU: freeCPU, FreeMemory: freeMemory}
+		switch {
+		case freeCPUPct < fragmentationExhaustedPct && freeMemoryPct >= fragmentationStrandedPct:
+			stranded.Exhausted = "cpu"
+			stranded.StrandedMemory = freeMemory
+		case freeMemoryPct < fragmentationExhaustedPct && freeCPUPct >= fragmentationStrandedPct:
+			stranded.Exhausted = "memory"
+			stranded.StrandedCPU = freeCPU
+		default:
+			continue
+		}
+		report.Nodes = append(report.Nodes, stranded)
+		report.StrandedCPU += stranded.StrandedCPU
+		report.StrandedMemory += stranded.StrandedMemory
+
+		// Workloads cuja proporção de requests difere da proporção do node no sentido do recurso esgotado
+		nodeRatio := memoryPerCore(allocatableCPU, allocatableMemory)
+		for _, pod := range podsByNode[node.Name] {
+			cpu, memory := podRequests(pod)
+			if cpu == 0 || memory == 0 {
+				continue
+			}
+			ratio := memoryPerCore(cpu, memory)
+			heavy := ""
+			if stranded.Exhausted == "cpu" && ratio*skewedRatioFactor <= nodeRatio {
+				heavy = "cpu"
+			} else if stranded.Exhausted == "memory" && ratio >= nodeRatio*skewedRatioFactor {
+				heavy = "memory"
+			}
+			if heavy == "" {
+				continue
+			}
+
+			name := workloadForPod(pod, deploymentIndex)
+			key := pod.Namespace + "/" + name
+			sw, exists := workloads[key]
+			if !exists {
+				sw = &SkewedRequestWorkload{
+					Name:          name,
+					Namespace:     pod.Namespace,
+					Heavy:         heavy,
+					MemoryPerCore: ratio,
+					Nodes:         make(map[string]bool),
+				}
+				workloads[key] = sw
+			}
+			sw.Pods++
+			sw.Nodes[node.Name] = true
+		}
+	}
+
+	for _, sw := range workloads {
+		report.Workloads = append(report.Workloads, *sw)
+	}
+	sort.Slice(report.Workloads, func(i, j int) bool {
+		if report.Workloads[i].Pods != report.Workloads[j].Pods {
+			return report.Workloads[i].Pods > report.Workloads[j].Pods
+		}
+		return report.Workloads[i].Name < report.Workloads[j].Name
+	})
+	sort.Slice(report.Nodes, func(i, j int) bool {
+		return report.Nodes[i].Name < report.Nodes[j].Name
+	})
+
+	return report
+}
+
+func writeFragmentation(w io.Writer, report *FragmentationReport) {
+	fmt.Fprintf(w, "\n=== Fragmentação de Recursos ===\n")
+	fmt.Fprintf(w, "--------------------------------\n")
+
+	if len(report.Nodes) == 0 {
+		fmt.Fprintf(w, "Nenhuma capacidade ociosa por fragmentação (CPU e memória esgotam de forma equilibrada)\n")
+		return
+	}
+
+	fmt.Fprintf(w, "Capacidade ociosa total: CPU %s, Memory %s\n", formatCPU(report.StrandedCPU), formatMemory(report.StrandedMemory))
+	fmt.Fprintf(w, "\nNodes com capacidade ociosa:\n")
+	for _, node := range report.Nodes {
+		if node.Exhausted == "cpu" {
+			fmt.Fprintf(w, "- %s: CPU esgotada (livre %s), %s de memória ociosa\n",
+				node.Name, formatCPU(node.FreeCPU), formatMemory(node.StrandedMemory))
+		} else {
+			fmt.Fprintf(w, "- %s: memória esgotada (livre %s), %s de CPU ociosa\n",
+				node.Name, formatMemory(node.FreeMemory), formatCPU(node.StrandedCPU))
+		}
+	}
+
+	if len(report.Workloads) > 0 {
+		fmt.Fprintf(w, "\nWorkloads com proporção de requests desbalanceada:\n")
+		for _, sw := range report.Workloads {
+			resource := "CPU"
+			if sw.Heavy == "memory" {
+				resource = "memória"
+			}
+			fmt.Fprintf(w, "- %s (Namespace: %s): intensivo em %s (%.0fMi por core), %d pods em %d nodes fragmentados\n",
+				sw.Name, sw.Namespace, resource, sw.MemoryPerCore, sw.Pods, len(sw.Nodes))
+		}
+	}
+
+	fmt.Fprintf(w, "\nRecomendação: Revisar os requests dos workloads desbalanceados (requests superdimensionados no recurso esgotado), "+
+		"usar pools de nodes com proporção CPU/memória adequada a eles, ou misturar workloads de perfis opostos nos mesmos nodes\n")
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var argoApplicationResource = schema.GroupVersionResource{
+	Group:    "argoproj.io",
+	Version:  "v1alpha1",
+	Resource: "applications",
+}
+
+// Rastreamento de recursos do ArgoCD: por anotação ("<app>:<grupo>/<kind>:<ns>/<nome>") ou pela label padrão
+const (
+	argoTrackingAnnotation = "argocd.argoproj.io/tracking-id"
+	argoTrackingLabel      = "app.kubernetes.io/instance"
+)
+
+// Ferramenta de origem dos workloads gerenciados pelo ArgoCD
+const sourceToolArgo = "ArgoCD Application"
+
+// WorkloadSource is where the manifests of a workload are maintained (GitOps application, chart...)
+type WorkloadSource struct {
+	// Ferramenta e tipo de objeto que gerencia o workload (ex: ArgoCD Application)
+	Tool string
+	// Objeto da ferramenta, como namespace/nome
+	Name     string
+	Repo     string
+	Path     string
+	Revision string
+}
+
+// String describes the source in a single line, pointing to where the change has to be made
+func (s *WorkloadSource) String() string {
+	if s == nil {
+		return ""
+	}
+	parts := []string{fmt.Sprintf("%s %s", s.Tool, s.Name)}
+	if s.Repo != "" {
+		parts = append(parts, "repo "+s.Repo)
+	}
+	if s.Path != "" {
+		parts = append(parts, "path "+s.Path)
+	}
+	if s.Revision != "" {
+		parts = append(parts, "revisão "+s.Revision)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// listArgoApplications indexes the ArgoCD Applications by name and by "<namespace>_<nome>" (tracking of
+// applications outside the ArgoCD namespace). A missing Application CRD is not an error
+func listArgoApplications(dynamicClient dynamic.Interface) (map[string]*WorkloadSource, error) {
+	list, err := dynamicClient.Resource(argoApplicationResource).Namespace("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("erro ao listar Applications do ArgoCD: %v", err)
+	}
+
+	apps := make(map[string]*WorkloadSource, len(list.Items))
+	for _, item := range list.Items {
+		app := &WorkloadSource{Tool: sourceToolArgo, Name: item.GetNamespace() + "/" + item.GetName()}
+
+		// Aplicações com várias fontes (spec.sources) usam a primeira com path ou chart
+		source, found, _ := unstructured.NestedMap(item.Object, "spec", "source")
+		if !found {
+			sources, _, _ := unstructured.NestedSlice(item.Object, "spec", "sources")
+			for _, s := range sources {
+				if m, ok := s.(map[string]interface{}); ok && (m["path"] != nil || m["chart"] != nil) {
+					source = m
+					break
+				}
+			}
+		}
+		app.Repo, _, _ = unstructured.NestedString(source, "repoURL")
+		app.Path, _, _ = unstructured.NestedString(source, "path")
+		if chart, _, _ := unstructured.NestedString(source, "chart"); chart != "" && app.Path == "" {
+			app.Path = "chart " + chart
+		}
+		app.Revision, _, _ = unstructured.NestedString(source, "targetRevision")
+
+		apps[item.GetName()] = app
+		apps[item.GetNamespace()+"_"+item.GetName()] = app
+	}
+	return apps, nil
+}
+
+// argoApplicationFor returns the Application that manages the deployment, if any
+func argoApplicationFor(d *appsv1.Deployment, apps map[string]*WorkloadSource) *WorkloadSource {
+	if tracking := d.Annotations[argoTrackingAnnotation]; tracking != "" {
+		name, _, _ := strings.Cut(tracking, ":")
+		if app, exists := apps[name]; exists {
+			return app
+		}
+	}
+	// A label também é usada pelo Helm: só vale quando existe uma Application com esse nome
+	if app, exists := apps[d.Labels[argoTrackingLabel]]; exists {
+		return app
+	}
+	return nil
+}
+
+// mapArgoApplications sets the source of each deployment managed by ArgoCD, returning how many were mapped
+func mapArgoApplications(deploymentMetrics map[string]*DeploymentMetrics, deployments map[string]*appsv1.Deployment, apps map[string]*WorkloadSource) int {
+	mapped := 0
+	for key, dm := range deploymentMetrics {
+		d, exists := deployments[key]
+		if !exists {
+			continue
+		}
+		if app := argoApplicationFor(d, apps); app != nil {
+			dm.Source = app
+			mapped++
+		}
+	}
+	return mapped
+}
+
+// writeWorkloadSources lists the deployments grouped by the application that manages them
+func writeWorkloadSources(w io.Writer, deploymentMetrics map[string]*DeploymentMetrics) {
+	fmt.Fprintf(w, "\n=== Origem dos Manifestos (GitOps) ===\n")
+	fmt.Fprintf(w, "--------------------------------------\n")
+
+	bySource := make(map[*WorkloadSource][]string)
+	var sources []*WorkloadSource
+	unmanaged := 0
+	for key, dm := range deploymentMetrics {
+		if dm.Source == nil {
+			unmanaged++
+			continue
+		}
+		if _, exists := bySource[dm.Source]; !exists {
+			sources = append(sources, dm.Source)
+		}
+		bySource[dm.Source] = append(bySource[dm.Source], key)
+	}
+
+	if len(sources) == 0 {
+		fmt.Fprintf(w, "Nenhum deployment gerenciado por GitOps identificado\n")
+		return
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
+	for _, source := range sources {
+		fmt.Fprintf(w, "\n%s\n", source)
+		deployments := bySource[source]
+		sort.Strings(deployments)
+		for _, key := range deployments {
+			fmt.Fprintf(w, "- %s\n", key)
+		}
+	}
+	fmt.Fprintf(w, "\nDeployments sem origem identificada: %d\n", unmanaged)
+	fmt.Fprintf(w, "\nRecomendação: Aplicar as alterações de recursos no repositório de origem; patches aplicados "+
+		"diretamente no cluster são revertidos na próxima sincronização.\n")
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Número mínimo de pontos (execuções anteriores + atual) e intervalo coberto para projetar crescimento
+const (
+	minGrowthPoints = 3
+	minGrowthSpan   = 24 * time.Hour
+)
+
+var growthHorizons = []int{30, 60, 90}
+
+// GrowthProjection holds the projected peak usage of a deployment at each horizon
+type GrowthProjection struct {
+	Name            string
+	Namespace       string
+	Points          int
+	LimitCPU        int64
+	LimitMemory     int64
+	ProjectedCPU    map[int]int64
+	ProjectedMemory map[int]int64
+	// Primeiro horizonte (em dias) em que a projeção excede o limite recomendado, 0 se nenhum
+	ExceedsCPUAt    int
+	ExceedsMemoryAt int
+}
+
+// projectValue fits a line through the points and evaluates it days after now
+func projectValue(times []time.Time, values []int64, now time.Time, days int) (int64, bool) {
+	xs := make([]float64, len(times))
+	ys := make([]float64, len(values))
+	for i := range times {
+		xs[i] = times[i].Sub(times[0]).Hours() / 24
+		ys[i] = float64(values[i])
+	}
+
+	slope, intercept, ok := linearRegression(xs, ys)
+	if !ok {
+		return 0, false
+	}
+
+	x := now.Sub(times[0]).Hours()/24 + float64(days)
+	projected := slope*x + intercept
+	if projected < 0 {
+		projected = 0
+	}
+	return int64(projected), true
+}
+
+// projectDeploymentGrowth projects the peak usage of each deployment with enough history
+func projectDeploymentGrowth(history []HistoryRecord, deploymentMetrics map[string]*DeploymentMetrics, now time.Time) []GrowthProjection {
+	var projections []GrowthProjection
+
+	for key, dm := range deploymentMetrics {
+		if dm.MaxCPU == 0 && dm.MaxMemory == 0 {
+			continue
+		}
+
+		var times []time.Time
+		var cpus, memories []int64
+		for _, record := range history {
+			usage, exists := record.Deployments[key]
+			if !exists {
+				continue
+			}
+			times = append(times, record.Timestamp)
+			cpus = append(cpus, usage.MaxCPU)
+			memories = append(memories, usage.MaxMemory)
+		}
+		times = append(times, now)
+		cpus = append(cpus, dm.MaxCPU)
+		memories = append(memories, dm.MaxMemory)
+
+		if len(times) < minGrowthPoints || now.Sub(times[0]) < minGrowthSpan {
+			continue
+		}
+
+		projection := GrowthProjection{
+			Name:            dm.Name,
+			Namespace:       dm.Namespace,
+			Points:          len(times),
+			LimitCPU:        dm.MaxCPU,
+			LimitMemory:     dm.MaxMemory,
+			ProjectedCPU:    make(map[int]int64),
+			ProjectedMemory: make(map[int]int64),
+		}
+
+		for _, days := range growthHorizons {
+			if cpu, ok := projectValue(times, cpus, now, days); ok {
+				projection.ProjectedCPU[days] = cpu
+				if projection.ExceedsCPUAt == 0 && cpu > projection.LimitCPU {
+					projection.ExceedsCPUAt = days
+				}
+			}
+			if memory, ok := projectValue(times, memories, now, days); ok {
+				projection.ProjectedMemory[days] = memory
+				if projection.ExceedsMemoryAt == 0 && memory > projection.LimitMemory {
+					projection.ExceedsMemoryAt = days
+				}
+			}
+		}
+
+		projections = append(projections, projection)
+	}
+
+	sort.Slice(projections, func(i, j int) bool {
+		if projections[i].Namespace != projections[j].Namespace {
+			return projections[i].Namespace < projections[j].Namespace
+		}
+		return projections[i].Name < projections[j].Name
+	})
+
+	return projections
+}
+
+func writeGrowthProjections(w io.Writer, projections []GrowthProjection) {
+	fmt.Fprintf(w, "\n=== Projeção de Crescimento por Deployment ===\n")
+	fmt.Fprintf(w, "----------------------------------------------\n")
+
+	if len(projections) == 0 {
+		fmt.Fprintf(w, "Histórico insuficiente para projeção (mínimo de %d execuções cobrindo %v)\n", minGrowthPoints, minGrowthSpan)
+		return
+	}
+
+	for _, p := range projections {
+		fmt.Fprintf(w, "\nDeployment: %s (Namespace: %s) - %d execuções\n", p.Name, p.Namespace, p.Points)
+		fmt.Fprintf(w, "  Limites recomendados: CPU %s, Memory %s\n", formatCPU(p.LimitCPU), formatMemory(p.LimitMemory))
+		for _, days := range growthHorizons {
+			fmt.Fprintf(w, "  %d dias: CPU %s, Memory %s\n", days, formatCPU(p.ProjectedCPU[days]), formatMemory(p.ProjectedMemory[days]))
+		}
+
+		if p.ExceedsCPUAt > 0 || p.ExceedsMemoryAt > 0 {
+			fmt.Fprintf(w, "  Problema: uso projetado excede os limites recomendados")
+			if p.ExceedsCPUAt > 0 {
+				fmt.Fprintf(w, " (CPU em %d dias)", p.ExceedsCPUAt)
+			}
+			if p.ExceedsMemoryAt > 0 {
+				fmt.Fprintf(w, " (Memory em %d dias)", p.ExceedsMemoryAt)
+			}
+			fmt.Fprintf(w, "\n")
+			fmt.Fprintf(w, "  Recomendação: Planejar aumento de limites ou de réplicas antes do horizonte indicado\n")
+		}
+	}
+}
+
+// countGrowthRisks returns how many projections exceed the recommended limits within the horizons
+func countGrowthRisks(projections []GrowthProjection) int {
+	count := 0
+	for _, p := range projections {
+		if p.ExceedsCPUAt > 0 || p.ExceedsMemoryAt > 0 {
+			count++
+		}
+	}
+	return count
+}
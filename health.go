@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+// Sem chamadas ao API server nem leituras por esse tempo, a execução é considerada travada
+const livenessTimeout = 5 * time.Minute
+
+// Resultado da verificação de conectividade reaproveitado entre probes
+const readinessCacheTTL = 10 * time.Second
+
+// HealthServer exposes /healthz and /readyz while the analyzer runs, so a long collection running in a
+// pod can be restarted by the kubelet when it stops making progress. In serve there is no collection to
+// follow (stats is nil) and only the connectivity is checked
+type HealthServer struct {
+	stats     *CollectionStats
+	discovery discovery.DiscoveryInterface
+
+	mu       sync.Mutex
+	checked  time.Time
+	readyErr error
+}
+
+// newHealthServer creates the server. Connectivity is checked with its own client, outside the
+// instrumentation, so the probes do not count as activity of the collection
+func newHealthServer(config *rest.Config, stats *CollectionStats) (*HealthServer, error) {
+	client, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar cliente para as verificações de saúde: %v", err)
+	}
+	return &HealthServer{stats: stats, discovery: client}, nil
+}
+
+// ready checks that the API server answers, reusing a recent result
+func (h *HealthServer) ready() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if time.Since(h.checked) < readinessCacheTTL {
+		return h.readyErr
+	}
+	_, err := h.discovery.ServerVersion()
+	h.checked, h.readyErr = time.Now(), err
+	return err
+}
+
+func (h *HealthServer) healthz(w http.ResponseWriter, r *http.Request) {
+	if h.stats == nil {
+		fmt.Fprintln(w, "ok")
+		return
+	}
+	if idle := h.stats.idle(); idle > livenessTimeout {
+		http.Error(w, fmt.Sprintf("sem atividade há %v", idle.Round(time.Second)), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (h *HealthServer) readyz(w http.ResponseWriter, r *http.Request) {
+	if err := h.ready(); err != nil {
+		http.Error(w, fmt.Sprintf("API server indisponível: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (h *HealthServer) server(addr string, api *AnalysisAPI) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", h.healthz)
+	mux.HandleFunc("/readyz", h.readyz)
+	if api != nil {
+		api.register(mux)
+	}
+	return &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+}
+
+// start serves the probes in the background for the rest of the run
+func (h *HealthServer) start(addr string) {
+	server := h.server(addr, nil)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("⚠️  Aviso: erro no servidor de verificações de saúde: %v\n", err)
+		}
+	}()
+}
+
+// serve serves the probes and the API until the process ends, over HTTPS when a certificate is given
+func (h *HealthServer) serve(addr string, api *AnalysisAPI, certFile, keyFile string) error {
+	server := h.server(addr, api)
+	var err error
+	if certFile != "" {
+		err = server.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("erro no servidor da API: %v", err)
+	}
+	return nil
+}
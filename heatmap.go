@@ -0,0 +1,369 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dias distintos com leituras para montar o mapa de calor de um workload
+const minHeatmapDays = 2
+
+// HourlyCell accumulates the readings of a workload in one hour of one day of the week. CPU and memory
+// are the totals of the pods of the workload in each reading
+type HourlyCell struct {
+	Readings  int   `json:"readings"`
+	SumCPU    int64 `json:"sum_cpu"`
+	SumMemory int64 `json:"sum_memory"`
+	MaxCPU    int64 `json:"max_cpu"`
+	MaxMemory int64 `json:"max_memory"`
+	SumPods   int   `json:"sum_pods"`
+	MaxPods   int   `json:"max_pods"`
+}
+
+func (c HourlyCell) avgCPU() int64 {
+	if c.Readings == 0 {
+		return 0
+	}
+	return c.SumCPU / int64(c.Readings)
+}
+
+func (c HourlyCell) avgMemory() int64 {
+	if c.Readings == 0 {
+		return 0
+	}
+	return c.SumMemory / int64(c.Readings)
+}
+
+func (c HourlyCell) avgPods() float64 {
+	if c.Readings == 0 {
+		return 0
+	}
+	return float64(c.SumPods) / float64(c.Readings)
+}
+
+// add records the workload totals of a reading
+func (c *HourlyCell) add(r *workloadReading) {
+	c.Readings++
+	c.SumCPU += r.cpu
+	c.SumMemory += r.memory
+	c.MaxCPU = max(c.MaxCPU, r.cpu)
+	c.MaxMemory = max(c.MaxMemory, r.memory)
+	c.SumPods += len(r.pods)
+	c.MaxPods = max(c.MaxPods, len(r.pods))
+}
+
+// merge adds the readings of another cell
+func (c *HourlyCell) merge(o HourlyCell) {
+	c.Readings += o.Readings
+	c.SumCPU += o.SumCPU
+	c.SumMemory += o.SumMemory
+	c.MaxCPU = max(c.MaxCPU, o.MaxCPU)
+	c.MaxMemory = max(c.MaxMemory, o.MaxMemory)
+	c.SumPods += o.SumPods
+	c.MaxPods = max(c.MaxPods, o.MaxPods)
+}
+
+// HourlyUsage is the usage of a workload by day of the week and hour of the day, in the report timezone
+type HourlyUsage struct {
+	Namespace string            `json:"namespace"`
+	Workload  string            `json:"workload"`
+	Cells     [7][24]HourlyCell `json:"cells"`
+	// Datas (AAAA-MM-DD) com leituras
+	Days []string `json:"days"`
+}
+
+// workloadReading is the usage of a workload in the current reading of the collection
+type workloadReading struct {
+	cpu, memory int64
+	pods        map[string]bool
+}
+
+// recordWorkloadReading adds the usage of a container to the total of its workload in the current reading
+func recordWorkloadReading(metrics *MetricsData, podName, namespace string, cpu, memory int64) {
+	if metrics.reading == nil {
+		metrics.reading = make(map[string]*workloadReading)
+	}
+	key := namespace + "/" + workloadFromPodName(podName)
+	r, exists := metrics.reading[key]
+	if !exists {
+		r = &workloadReading{pods: make(map[string]bool)}
+		metrics.reading[key] = r
+	}
+	r.cpu += cpu
+	r.memory += memory
+	r.pods[podName] = true
+}
+
+// recordHourly adds the workload totals of the reading taken at t to the hour of the day of the week
+func (m *MetricsData) recordHourly(t time.Time) {
+	if m.Hourly == nil {
+		m.Hourly = make(map[string]*HourlyUsage)
+	}
+	day := t.Format("2006-01-02")
+	for key, r := range m.reading {
+		h, exists := m.Hourly[key]
+		if !exists {
+			namespace, workload, _ := strings.Cut(key, "/")
+			h = &HourlyUsage{Namespace: namespace, Workload: workload}
+			m.Hourly[key] = h
+		}
+		if len(h.Days) == 0 || h.Days[len(h.Days)-1] != day {
+			h.addDays(day)
+		}
+		h.Cells[t.Weekday()][t.Hour()].add(r)
+		if m.StatsWindow > 0 {
+			m.recordHourlySlot(key, t, r)
+		}
+	}
+	clear(m.reading)
+}
+
+// addDays adds the dates not yet recorded, keeping them sorted
+func (h *HourlyUsage) addDays(days ...string) {
+	for _, day := range days {
+		i := sort.SearchStrings(h.Days, day)
+		if i < len(h.Days) && h.Days[i] == day {
+			continue
+		}
+		h.Days = append(h.Days, "")
+		copy(h.Days[i+1:], h.Days[i:])
+		h.Days[i] = day
+	}
+}
+
+// merge adds the cells of the same workload from another run
+func (h *HourlyUsage) merge(other *HourlyUsage) {
+	for day := range h.Cells {
+		for hour := range h.Cells[day] {
+			h.Cells[day][hour].merge(other.Cells[day][hour])
+		}
+	}
+	h.addDays(other.Days...)
+}
+
+// HourSlot is an hour of a day of the week
+type HourSlot struct {
+	Day  time.Weekday
+	Hour int
+}
+
+func (s HourSlot) String() string {
+	return fmt.Sprintf("%s %02dh", string([]rune(weekdayLabels[s.Day])[:3]), s.Hour)
+}
+
+// extremes returns the hours with the highest and the lowest average CPU among the hours with readings
+func (h *HourlyUsage) extremes() (peak, valley HourSlot, ok bool) {
+	var peakCPU, valleyCPU int64 = -1, -1
+	for day := range h.Cells {
+		for hour, c := range h.Cells[day] {
+			if c.Readings == 0 {
+				continue
+			}
+			if avg := c.avgCPU(); avg > peakCPU {
+				peakCPU, peak = avg, HourSlot{time.Weekday(day), hour}
+			}
+			if avg := c.avgCPU(); valleyCPU < 0 || avg < valleyCPU {
+				valleyCPU, valley = avg, HourSlot{time.Weekday(day), hour}
+			}
+		}
+	}
+	return peak, valley, peakCPU >= 0
+}
+
+func (h *HourlyUsage) cell(s HourSlot) HourlyCell {
+	return h.Cells[s.Day][s.Hour]
+}
+
+// diurnalRatio is the average CPU of the peak hour over that of the valley hour (0 without usage)
+func (h *HourlyUsage) diurnalRatio() float64 {
+	peak, valley, ok := h.extremes()
+	if !ok || h.cell(valley).avgCPU() == 0 {
+		return 0
+	}
+	return float64(h.cell(peak).avgCPU()) / float64(h.cell(valley).avgCPU())
+}
+
+// heatmapUsages returns the hourly usage of the deployments with readings on minHeatmapDays days or more
+func heatmapUsages(metrics *MetricsData, deploymentMetrics map[string]*DeploymentMetrics) []*HourlyUsage {
+	var usages []*HourlyUsage
+	for key, h := range metrics.Hourly {
+		if _, exists := deploymentMetrics[key]; exists && len(h.Days) >= minHeatmapDays {
+			usages = append(usages, h)
+		}
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Namespace != usages[j].Namespace {
+			return usages[i].Namespace < usages[j].Namespace
+		}
+		return usages[i].Workload < usages[j].Workload
+	})
+	return usages
+}
+
+// heatmapDays orders the rows of the heatmap from Monday to Sunday
+var heatmapDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+// HeatmapCell is a cell of the heatmap as rendered in the HTML
+type HeatmapCell struct {
+	Style template.CSS
+	Text  string
+	Title string
+}
+
+// HeatmapRow is a day of the week of the heatmap
+type HeatmapRow struct {
+	Day   string
+	Cells []HeatmapCell
+}
+
+// HeatmapWorkload is the heatmap of a workload as rendered in the HTML
+type HeatmapWorkload struct {
+	Name      string
+	Namespace string
+	Days      int
+	Summary   string
+	CPU       []HeatmapRow
+	Memory    []HeatmapRow
+}
+
+// heatColor shades the value from light yellow (low) to dark red (the highest value of the workload)
+func heatColor(value, highest int64) template.CSS {
+	if highest <= 0 {
+		return template.CSS("background: hsl(55, 90%, 92%)")
+	}
+	f := float64(value) / float64(highest)
+	return template.CSS(fmt.Sprintf("background: hsl(%.0f, 90%%, %.0f%%)", 55-55*f, 92-52*f))
+}
+
+// heatmapRows renders the average of each hour, shaded by the highest average of the workload
+func heatmapRows(h *HourlyUsage, value func(HourlyCell) int64, format func(int64) string) []HeatmapRow {
+	var highest int64
+	for day := range h.Cells {
+		for _, c := range h.Cells[day] {
+			highest = max(highest, value(c))
+		}
+	}
+	rows := make([]HeatmapRow, 0, len(heatmapDays))
+	for _, day := range heatmapDays {
+		row := HeatmapRow{Day: weekdayLabels[day]}
+		for hour, c := range h.Cells[day] {
+			if c.Readings == 0 {
+				row.Cells = append(row.Cells, HeatmapCell{Style: "background: #eee", Title: "sem leituras"})
+				continue
+			}
+			row.Cells = append(row.Cells, HeatmapCell{
+				Style: heatColor(value(c), highest),
+				Text:  format(value(c)),
+				Title: fmt.Sprintf("%s %02dh: média CPU %s, Memory %s; pico CPU %s, Memory %s; %.1f pods em média; %d leituras",
+					weekdayLabels[day], hour, formatCPU(c.avgCPU()), formatMemory(c.avgMemory()),
+					formatCPU(c.MaxCPU), formatMemory(c.MaxMemory), c.avgPods(), c.Readings),
+			})
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// hourlySummary describes the peak and the valley hours of the workload
+func hourlySummary(h *HourlyUsage) string {
+	peak, valley, ok := h.extremes()
+	if !ok {
+		return ""
+	}
+	s := fmt.Sprintf("pico em %s (CPU %s, %.1f pods), vale em %s (CPU %s, %.1f pods)",
+		peak, formatCPU(h.cell(peak).avgCPU()), h.cell(peak).avgPods(),
+		valley, formatCPU(h.cell(valley).avgCPU()), h.cell(valley).avgPods())
+	if ratio := h.diurnalRatio(); ratio > 0 {
+		s += fmt.Sprintf(", razão %.1fx", ratio)
+	}
+	return s
+}
+
+var heatmapTemplate = template.Must(template.New("heatmap").Parse(`<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+<meta charset="utf-8">
+<title>Uso por hora - {{.Cluster}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; font-size: 0.75em; }
+th, td { border: 1px solid #fff; padding: 3px 4px; text-align: center; min-width: 2.6em; }
+th { background: #f0f0f0; }
+td.day { text-align: left; background: #f0f0f0; font-weight: bold; }
+.summary { color: #555; }
+</style>
+</head>
+<body>
+<h1>Uso por dia da semana e hora do dia</h1>
+<p>Cluster {{.Cluster}}, gerado em {{.Generated}} ({{.Timezone}}). Cada célula mostra a média do uso total dos pods do deployment nas leituras daquela hora; passe o mouse para ver picos, pods e leituras.</p>
+{{range .Workloads}}
+<h2>{{.Namespace}}/{{.Name}}</h2>
+<p class="summary">{{.Days}} dias com leituras: {{.Summary}}</p>
+<h3>CPU</h3>
+<table>
+<tr><th></th>{{range $.Hours}}<th>{{.}}</th>{{end}}</tr>
+{{range .CPU}}<tr><td class="day">{{.Day}}</td>{{range .Cells}}<td style="{{.Style}}" title="{{.Title}}">{{.Text}}</td>{{end}}</tr>
+{{end}}</table>
+<h3>Memória</h3>
+<table>
+<tr><th></th>{{range $.Hours}}<th>{{.}}</th>{{end}}</tr>
+{{range .Memory}}<tr><td class="day">{{.Day}}</td>{{range .Cells}}<td style="{{.Style}}" title="{{.Title}}">{{.Text}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// writeHeatmapHTML writes the heatmaps of the workloads, anonymizing the names when an anonymizer is given
+func writeHeatmapHTML(path, cluster string, usages []*HourlyUsage, location *time.Location, anonymizer *Anonymizer) error {
+	data := struct {
+		Cluster   string
+		Generated string
+		Timezone  string
+		Hours     []string
+		Workloads []HeatmapWorkload
+	}{Cluster: cluster, Generated: time.Now().In(location).Format("2006-01-02 15:04"), Timezone: location.String()}
+	for hour := 0; hour < 24; hour++ {
+		data.Hours = append(data.Hours, fmt.Sprintf("%02d", hour))
+	}
+	for _, h := range usages {
+		data.Workloads = append(data.Workloads, HeatmapWorkload{
+			Name:      h.Workload,
+			Namespace: h.Namespace,
+			Days:      len(h.Days),
+			Summary:   hourlySummary(h),
+			CPU:       heatmapRows(h, HourlyCell.avgCPU, formatCPU),
+			Memory:    heatmapRows(h, HourlyCell.avgMemory, formatMemory),
+		})
+	}
+
+	var b strings.Builder
+	if err := heatmapTemplate.Execute(&b, data); err != nil {
+		return fmt.Errorf("erro ao gerar mapa de calor: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(anonymizer.Anonymize(b.String())), 0644); err != nil {
+		return fmt.Errorf("erro ao escrever mapa de calor: %v", err)
+	}
+	return nil
+}
+
+func writeHourlyPatterns(w io.Writer, usages []*HourlyUsage, htmlFile string) {
+	fmt.Fprintf(w, "\n=== Uso por Hora do Dia ===\n")
+	fmt.Fprintf(w, "---------------------------\n")
+
+	if len(usages) == 0 {
+		fmt.Fprintf(w, "Leituras insuficientes para o mapa de calor (mínimo de %d dias com leituras por deployment)\n", minHeatmapDays)
+		return
+	}
+	for _, h := range usages {
+		fmt.Fprintf(w, "%s/%s (%d dias): %s\n", h.Namespace, h.Workload, len(h.Days), hourlySummary(h))
+	}
+	if htmlFile != "" {
+		fmt.Fprintf(w, "\nMapa de calor por dia da semana e hora: %s\n", htmlFile)
+	}
+}
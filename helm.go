@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// Anotações e labels que identificam o release do Helm de um objeto
+const (
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+	helmManagedByLabel             = "app.kubernetes.io/managed-by"
+	helmInstanceLabel              = "app.kubernetes.io/instance"
+	helmChartLabel                 = "helm.sh/chart"
+	// Charts antigos usam release/heritage em vez das labels recomendadas
+	helmLegacyReleaseLabel  = "release"
+	helmLegacyHeritageLabel = "heritage"
+)
+
+// HelmReleaseSummary aggregates the deployments and recommendations of a Helm release
+type HelmReleaseSummary struct {
+	Name              string
+	Namespace         string
+	Chart             string
+	Deployments       []string
+	Replicas          int32
+	PodsWithoutLimits int
+	// Requests totais (todas as réplicas) atuais e recomendados
+	CurrentCPU        int64
+	CurrentMemory     int64
+	RecommendedCPU    int64
+	RecommendedMemory int64
+	Patches           []ResourcePatch
+}
+
+// helmRelease returns the release name and namespace of a deployment installed by Helm
+func helmRelease(d *appsv1.Deployment) (string, string, bool) {
+	if name := d.Annotations[helmReleaseNameAnnotation]; name != "" {
+		namespace := d.Annotations[helmReleaseNamespaceAnnotation]
+		if namespace == "" {
+			namespace = d.Namespace
+		}
+		return name, namespace, true
+	}
+	if d.Labels[helmManagedByLabel] == "Helm" && d.Labels[helmInstanceLabel] != "" {
+		return d.Labels[helmInstanceLabel], d.Namespace, true
+	}
+	if heritage := d.Labels[helmLegacyHeritageLabel]; (heritage == "Helm" || heritage == "Tiller") && d.Labels[helmLegacyReleaseLabel] != "" {
+		return d.Labels[helmLegacyReleaseLabel], d.Namespace, true
+	}
+	return "", "", false
+}
+
+// groupByHelmRelease aggregates the deployments installed by Helm and their patches by release
+func groupByHelmRelease(deploymentMetrics map[string]*DeploymentMetrics, deployments map[string]*appsv1.Deployment, patches []ResourcePatch) []*HelmReleaseSummary {
+	patchByDeployment := make(map[string]ResourcePatch, len(patches))
+	for _, p := range patches {
+		patchByDeployment[p.Namespace+"/"+p.Deployment] = p
+	}
+
+	releases := make(map[string]*HelmReleaseSummary)
+	for key, d := range deployments {
+		name, namespace, found := helmRelease(d)
+		if !found {
+			continue
+		}
+		release, exists := releases[namespace+"/"+name]
+		if !exists {
+			release = &HelmReleaseSummary{Name: name, Namespace: namespace, Chart: d.Labels[helmChartLabel]}
+			releases[namespace+"/"+name] = release
+		}
+		release.Deployments = append(release.Deployments, key)
+
+		replicas := int32(1)
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		release.Replicas += replicas
+		if dm, exists := deploymentMetrics[key]; exists {
+			release.PodsWithoutLimits += dm.PodsWithoutLimits
+		}
+
+		// Containers sem recomendação mantêm os requests atuais
+		p, patched := patchByDeployment[key]
+		recommended := make(map[string]ContainerRecommendation)
+		if patched {
+			release.Patches = append(release.Patches, p)
+			for _, c := range p.Containers {
+				recommended[c.Container] = c
+			}
+		}
+		for i := range d.Spec.Template.Spec.Containers {
+			container := &d.Spec.Template.Spec.Containers[i]
+			current := containerTotals(container)
+			release.CurrentCPU += current[quotaRequestsCPU] * int64(replicas)
+			release.CurrentMemory += current[quotaRequestsMemory] * int64(replicas)
+			if c, exists := recommended[container.Name]; exists {
+				release.RecommendedCPU += c.projectedValue(quotaRequestsCPU) * int64(replicas)
+				release.RecommendedMemory += c.projectedValue(quotaRequestsMemory) * int64(replicas)
+			} else {
+				release.RecommendedCPU += current[quotaRequestsCPU] * int64(replicas)
+				release.RecommendedMemory += current[quotaRequestsMemory] * int64(replicas)
+			}
+		}
+	}
+
+	result := make([]*HelmReleaseSummary, 0, len(releases))
+	for _, release := range releases {
+		sort.Strings(release.Deployments)
+		sort.Slice(release.Patches, func(i, j int) bool { return release.Patches[i].Deployment < release.Patches[j].Deployment })
+		result = append(result, release)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func writeHelmReleases(w io.Writer, releases []*HelmReleaseSummary) {
+	fmt.Fprintf(w, "\n=== Recomendações por Release do Helm ===\n")
+	fmt.Fprintf(w, "------------------------------------------\n")
+
+	if len(releases) == 0 {
+		fmt.Fprintf(w, "Nenhum deployment instalado pelo Helm identificado\n")
+		return
+	}
+	for _, release := range releases {
+		fmt.Fprintf(w, "\nRelease: %s (Namespace: %s)\n", release.Name, release.Namespace)
+		if release.Chart != "" {
+			fmt.Fprintf(w, "Chart: %s\n", release.Chart)
+		}
+		fmt.Fprintf(w, "Deployments: %d (%d réplicas)\n", len(release.Deployments), release.Replicas)
+		for _, key := range release.Deployments {
+			fmt.Fprintf(w, "- %s\n", key)
+		}
+		if release.PodsWithoutLimits > 0 {
+			fmt.Fprintf(w, "Pods sem limites: %d\n", release.PodsWithoutLimits)
+		}
+		fmt.Fprintf(w, "Requests totais: CPU %s -> %s, Memory %s -> %s\n",
+			formatCPU(release.CurrentCPU), formatCPU(release.RecommendedCPU), formatMemory(release.CurrentMemory), formatMemory(release.RecommendedMemory))
+
+		if len(release.Patches) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "Valores sugeridos por componente (resources nos values do chart):\n")
+		for _, p := range release.Patches {
+			for _, c := range p.Containers {
+				fmt.Fprintf(w, "  %s/%s: requests CPU %s, Memory %s; limits CPU %s, Memory %s\n",
+					p.Deployment, c.Container,
+					formatCPU(c.projectedValue(quotaRequestsCPU)), formatMemory(c.projectedValue(quotaRequestsMemory)),
+					formatCPU(c.projectedValue(quotaLimitsCPU)), formatMemory(c.projectedValue(quotaLimitsMemory)))
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HistoryRecord is the summary of a single run persisted to the history file
+type HistoryRecord struct {
+	Timestamp         time.Time `json:"timestamp"`
+	Context           string    `json:"context"`
+	Period            string    `json:"period"`
+	AllocatableCPU    int64     `json:"allocatable_cpu_millis"`
+	AllocatableMemory int64     `json:"allocatable_memory_bytes"`
+	AvgCPU            int64     `json:"avg_cpu_millis"`
+	AvgMemory         int64     `json:"avg_memory_bytes"`
+	MaxCPU            int64     `json:"max_cpu_millis"`
+	MaxMemory         int64     `json:"max_memory_bytes"`
+	// Uso por deployment, indexado por "namespace/nome"
+	Deployments map[string]DeploymentUsage `json:"deployments,omitempty"`
+	// Resumo da execução exibido no índice dos relatórios (ausente em registros importados)
+	Report         string         `json:"report,omitempty"`
+	HealthScore    *int           `json:"health_score,omitempty"`
+	Findings       map[string]int `json:"findings,omitempty"`
+	WasteCPUCores  float64        `json:"waste_cpu_cores,omitempty"`
+	WasteMemoryGiB float64        `json:"waste_memory_gib,omitempty"`
+}
+
+// DeploymentUsage is the usage of a deployment observed during a run
+type DeploymentUsage struct {
+	AvgCPU    int64 `json:"avg_cpu_millis"`
+	AvgMemory int64 `json:"avg_memory_bytes"`
+	MaxCPU    int64 `json:"max_cpu_millis"`
+	MaxMemory int64 `json:"max_memory_bytes"`
+	// Uso de cada container, pelo nome (ausente em registros antigos e importados)
+	Containers map[string]ContainerUsage `json:"containers,omitempty"`
+	// Recursos propostos para cada container no patch da execução, após as políticas e a validação de
+	// quotas (ausente sem patch e em registros antigos)
+	Recommendations []ContainerRecommendation `json:"recommendations,omitempty"`
+}
+
+// ContainerUsage is the usage of a container of a deployment across its pods: the mean of the readings
+// and the peak
+type ContainerUsage struct {
+	AvgCPU    int64 `json:"avg_cpu_millis"`
+	AvgMemory int64 `json:"avg_memory_bytes"`
+	MaxCPU    int64 `json:"max_cpu_millis"`
+	MaxMemory int64 `json:"max_memory_bytes"`
+}
+
+// deploymentContainerUsage combines the readings of each container over the pods of the deployment
+func deploymentContainerUsage(dm *DeploymentMetrics, metrics *MetricsData) map[string]ContainerUsage {
+	cpu := make(map[string]*usageVariation)
+	memory := make(map[string]*usageVariation)
+	usage := make(map[string]ContainerUsage)
+	for _, podName := range dm.Pods {
+		pm, exists := metrics.PodMetrics[podName]
+		if !exists || pm.Namespace != dm.Namespace {
+			continue
+		}
+		for name, cm := range pm.Containers {
+			if cpu[name] == nil {
+				cpu[name], memory[name] = &usageVariation{}, &usageVariation{}
+			}
+			cpu[name].merge(cm.cpuVariation)
+			memory[name].merge(cm.memoryVariation)
+			u := usage[name]
+			u.MaxCPU = max(u.MaxCPU, cm.MaxCPU)
+			u.MaxMemory = max(u.MaxMemory, cm.MaxMemory)
+			usage[name] = u
+		}
+	}
+	for name, u := range usage {
+		u.AvgCPU, u.AvgMemory = cpu[name].mean(), memory[name].mean()
+		usage[name] = u
+	}
+	return usage
+}
+
+// historyFilePath returns the path of the history file for the given (sanitized) context
+func historyFilePath(reportDir, sanitizedContext string) string {
+	return filepath.Join(reportDir, fmt.Sprintf("history-%s.jsonl", sanitizedContext))
+}
+
+// loadHistory reads all records from the history file. A missing file yields no records
+func loadHistory(path string) ([]HistoryRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("erro ao abrir histórico: %v", err)
+	}
+	defer f.Close()
+
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("erro ao ler histórico (linha %d): %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler histórico: %v", err)
+	}
+
+	return records, nil
+}
+
+// appendHistory appends a record to the history file
+func appendHistory(path string, record HistoryRecord) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir histórico: %v", err)
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar histórico: %v", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("erro ao gravar histórico: %v", err)
+	}
+	return nil
+}
+
+// writeHistory replaces the history file with the given records
+func writeHistory(path string, records []HistoryRecord) error {
+	var buf bytes.Buffer
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("erro ao serializar histórico: %v", err)
+		}
+		buf.Write(append(data, '\n'))
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("erro ao gravar histórico: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("erro ao gravar histórico: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Requests sugeridos quando não há métricas do container
+const (
+	defaultCPURequest    = 100
+	defaultMemoryRequest = 128 * 1024 * 1024
+)
+
+// HPAMissingRequest is a container scaled by an HPA on resource utilization that has no request for
+// that resource: the utilization cannot be computed and the HPA does not scale
+type HPAMissingRequest struct {
+	HPA        string
+	Namespace  string
+	TargetKind string
+	TargetName string
+	Container  string
+	Resource   corev1.ResourceName
+	// Request sugerido, em millicores (cpu) ou bytes (memory)
+	Suggested int64
+	// O valor sugerido vem do uso observado (e não do padrão)
+	Observed bool
+}
+
+// quantity formats the suggested request as a Kubernetes quantity
+func (m HPAMissingRequest) quantity() string {
+	if m.Resource == corev1.ResourceCPU {
+		return resource.NewMilliQuantity(m.Suggested, resource.DecimalSI).String()
+	}
+	return resource.NewQuantity(roundUpMiB(m.Suggested), resource.BinarySI).String()
+}
+
+// fixCommand returns the command that sets the missing request
+func (m HPAMissingRequest) fixCommand() string {
+	return fmt.Sprintf("kubectl set resources %s/%s -n %s -c %s --requests=%s=%s",
+		m.TargetKind, m.TargetName, m.Namespace, m.Container, m.Resource, m.quantity())
+}
+
+// utilizationTargets returns the resources the HPA scales on by utilization, with the container the
+// metric is restricted to ("" for all the containers of the pod)
+func utilizationTargets(hpa *autoscalingv2.HorizontalPodAutoscaler) map[corev1.ResourceName]string {
+	targets := make(map[corev1.ResourceName]string)
+	for _, m := range hpa.Spec.Metrics {
+		switch {
+		case m.Type == autoscalingv2.ResourceMetricSourceType && m.Resource != nil &&
+			m.Resource.Target.Type == autoscalingv2.UtilizationMetricType:
+			targets[m.Resource.Name] = ""
+		case m.Type == autoscalingv2.ContainerResourceMetricSourceType && m.ContainerResource != nil &&
+			m.ContainerResource.Target.Type == autoscalingv2.UtilizationMetricType:
+			if _, exists := targets[m.ContainerResource.Name]; !exists {
+				targets[m.ContainerResource.Name] = m.ContainerResource.Container
+			}
+		}
+	}
+	return targets
+}
+
+// hpaTargetTemplate returns the pod template of the HPA target (Deployment or StatefulSet)
+func hpaTargetTemplate(clientset *kubernetes.Clientset, hpa *autoscalingv2.HorizontalPodAutoscaler, deployments map[string]*appsv1.Deployment) (*corev1.PodSpec, error) {
+	target := hpa.Spec.ScaleTargetRef
+	switch target.Kind {
+	case "Deployment":
+		if d, exists := deployments[hpa.Namespace+"/"+target.Name]; exists {
+			return &d.Spec.Template.Spec, nil
+		}
+		return nil, nil
+	case "StatefulSet":
+		sts, err := clientset.AppsV1().StatefulSets(hpa.Namespace).Get(context.TODO(), target.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("erro ao obter statefulset %s/%s: %v", hpa.Namespace, target.Name, err)
+		}
+		return &sts.Spec.Template.Spec, nil
+	}
+	return nil, nil
+}
+
+// findHPAMissingRequests cross-checks the HPAs that scale on CPU or memory utilization against the
+// requests of the target containers. The suggested request comes from the resource patches when available
+func findHPAMissingRequests(clientset *kubernetes.Clientset, deployments map[string]*appsv1.Deployment, patches []ResourcePatch) ([]HPAMissingRequest, error) {
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar HPAs: %v", err)
+	}
+
+	recommended := make(map[string]ContainerRecommendation)
+	for _, p := range patches {
+		for _, c := range p.Containers {
+			recommended[p.Namespace+"/"+p.Deployment+"/"+c.Container] = c
+		}
+	}
+
+	var missing []HPAMissingRequest
+	for i := range hpas.Items {
+		hpa := &hpas.Items[i]
+		targets := utilizationTargets(hpa)
+		if len(targets) == 0 {
+			continue
+		}
+		spec, err := hpaTargetTemplate(clientset, hpa, deployments)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+			continue
+		}
+		if spec == nil {
+			continue
+		}
+
+		target := hpa.Spec.ScaleTargetRef
+		for resourceName, only := range targets {
+			for _, container := range spec.Containers {
+				if only != "" && container.Name != only {
+					continue
+				}
+				if _, exists := container.Resources.Requests[resourceName]; exists {
+					continue
+				}
+
+				m := HPAMissingRequest{
+					HPA:        hpa.Name,
+					Namespace:  hpa.Namespace,
+					TargetKind: target.Kind,
+					TargetName: target.Name,
+					Container:  container.Name,
+					Resource:   resourceName,
+				}
+				c, observed := recommended[hpa.Namespace+"/"+target.Name+"/"+container.Name]
+				switch {
+				case resourceName == corev1.ResourceCPU && observed && c.RequestCPU > 0:
+					m.Suggested, m.Observed = c.RequestCPU, true
+				case resourceName == corev1.ResourceMemory && observed && c.RequestMemory > 0:
+					m.Suggested, m.Observed = c.RequestMemory, true
+				case resourceName == corev1.ResourceCPU:
+					m.Suggested = defaultCPURequest
+				default:
+					m.Suggested = defaultMemoryRequest
+				}
+				missing = append(missing, m)
+			}
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		a, b := missing[i], missing[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.HPA != b.HPA {
+			return a.HPA < b.HPA
+		}
+		if a.Container != b.Container {
+			return a.Container < b.Container
+		}
+		return a.Resource < b.Resource
+	})
+	return missing, nil
+}
+
+// hpaMissingRequestFindings turns each HPA without the requests it depends on into a critical finding
+func hpaMissingRequestFindings(missing []HPAMissingRequest) []Finding {
+	byHPA := make(map[string][]HPAMissingRequest)
+	var keys []string
+	for _, m := range missing {
+		key := m.Namespace + "/" + m.HPA
+		if _, exists := byHPA[key]; !exists {
+			keys = append(keys, key)
+		}
+		byHPA[key] = append(byHPA[key], m)
+	}
+
+	var findings []Finding
+	for _, key := range keys {
+		items := byHPA[key]
+		first := items[0]
+		body := fmt.Sprintf("O HPA %s escala %s/%s pela utilização de recursos, mas containers do alvo não têm o request correspondente. "+
+			"Sem o request a utilização não pode ser calculada e o HPA não escala.\n\nCorreção:\n", first.HPA, first.TargetKind, first.TargetName)
+		for _, m := range items {
+			body += m.fixCommand() + "\n"
+		}
+		findings = append(findings, Finding{
+			Kind:      "hpa-sem-requests",
+			Severity:  SeverityCritical,
+			Title:     fmt.Sprintf("HPA %s/%s não consegue escalar: containers sem requests", first.Namespace, first.HPA),
+			Namespace: first.Namespace,
+			Workload:  first.TargetName,
+			Body:      body,
+		})
+	}
+	return findings
+}
+
+func writeHPAMissingRequests(w io.Writer, missing []HPAMissingRequest) {
+	fmt.Fprintf(w, "\n=== HPAs sem Requests ===\n")
+	fmt.Fprintf(w, "-------------------------\n")
+
+	if len(missing) == 0 {
+		fmt.Fprintf(w, "Todos os HPAs por utilização têm os requests de que dependem\n")
+		return
+	}
+	for i, m := range missing {
+		fmt.Fprintf(w, "\n%d. HPA %s -> %s/%s (Namespace: %s)\n", i+1, m.HPA, m.TargetKind, m.TargetName, m.Namespace)
+		fmt.Fprintf(w, "   Problema: O container %s não tem request de %s; o HPA escala pela utilização de %s e fica sem dados para decidir\n",
+			m.Container, m.Resource, m.Resource)
+		origin := "baseado no uso observado"
+		if !m.Observed {
+			origin = "valor inicial, sem métricas do container"
+		}
+		fmt.Fprintf(w, "   Recomendação: %s (%s)\n", m.fixCommand(), origin)
+		fmt.Fprintf(w, "   Prioridade: Crítica\n")
+	}
+}
+
+// Eventos de reescala do HPA considerados antes do início da coleta (o TTL padrão dos eventos é 1h)
+const hpaEventLookback = time.Hour
+
+// Reescalas na janela a partir das quais um HPA que sobe e desce é considerado instável
+const hpaFlappingMinRescales = 4
+
+// Janelas de estabilização recomendadas para HPAs instáveis, em segundos
+const (
+	recommendedScaleDownStabilization = 600
+	recommendedScaleUpStabilization   = 60
+)
+
+// HPAScalingActivity summarizes the scaling of an HPA during the analysis window
+type HPAScalingActivity struct {
+	Name            string
+	Namespace       string
+	Target          string
+	CurrentReplicas int32
+	DesiredReplicas int32
+	MinReplicas     int32
+	MaxReplicas     int32
+	Rescales        int
+	ScaleUps        int
+	ScaleDowns      int
+	// Tamanhos definidos pelas reescalas, em ordem
+	Sizes []int32
+	// Janelas de estabilização configuradas (nil quando o padrão do Kubernetes é usado)
+	ScaleUpStabilization   *int32
+	ScaleDownStabilization *int32
+	Flapping               bool
+}
+
+// rescaleSize extracts the new size of a SuccessfulRescale event ("New size: 5; reason: ...")
+func rescaleSize(message string) (int32, bool) {
+	var size int32
+	if _, err := fmt.Sscanf(message, "New size: %d", &size); err != nil {
+		return 0, false
+	}
+	return size, true
+}
+
+// eventTime returns when the event last happened
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// analyzeHPAScaling correlates the status of the HPAs with their rescale events since the given time,
+// flagging the autoscalers that keep scaling up and down
+func analyzeHPAScaling(clientset *kubernetes.Clientset, since time.Time) ([]HPAScalingActivity, error) {
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar HPAs: %v", err)
+	}
+	events, err := clientset.CoreV1().Events("").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "reason=SuccessfulRescale,involvedObject.kind=HorizontalPodAutoscaler",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar eventos de reescala: %v", err)
+	}
+
+	byHPA := make(map[string][]corev1.Event)
+	for _, event := range events.Items {
+		if eventTime(&event).Before(since) {
+			continue
+		}
+		key := event.InvolvedObject.Namespace + "/" + event.InvolvedObject.Name
+		byHPA[key] = append(byHPA[key], event)
+	}
+
+	var activities []HPAScalingActivity
+	for _, hpa := range hpas.Items {
+		activity := HPAScalingActivity{
+			Name:            hpa.Name,
+			Namespace:       hpa.Namespace,
+			Target:          hpa.Spec.ScaleTargetRef.Kind + "/" + hpa.Spec.ScaleTargetRef.Name,
+			CurrentReplicas: hpa.Status.CurrentReplicas,
+			DesiredReplicas: hpa.Status.DesiredReplicas,
+			MaxReplicas:     hpa.Spec.MaxReplicas,
+			MinReplicas:     1,
+		}
+		if hpa.Spec.MinReplicas != nil {
+			activity.MinReplicas = *hpa.Spec.MinReplicas
+		}
+		if behavior := hpa.Spec.Behavior; behavior != nil {
+			if behavior.ScaleUp != nil {
+				activity.ScaleUpStabilization = behavior.ScaleUp.StabilizationWindowSeconds
+			}
+			if behavior.ScaleDown != nil {
+				activity.ScaleDownStabilization = behavior.ScaleDown.StabilizationWindowSeconds
+			}
+		}
+
+		rescales := byHPA[hpa.Namespace+"/"+hpa.Name]
+		sort.Slice(rescales, func(i, j int) bool { return eventTime(&rescales[i]).Before(eventTime(&rescales[j])) })
+		for _, event := range rescales {
+			size, ok := rescaleSize(event.Message)
+			if !ok {
+				continue
+			}
+			count := int(event.Count)
+			if count == 0 {
+				count = 1
+			}
+			// Eventos agregados repetem o mesmo tamanho: o HPA saiu dele e voltou, nos dois sentidos
+			if count > 1 {
+				activity.ScaleUps += count - 1
+				activity.ScaleDowns += count - 1
+			}
+			if n := len(activity.Sizes); n > 0 {
+				if size > activity.Sizes[n-1] {
+					activity.ScaleUps++
+				} else if size < activity.Sizes[n-1] {
+					activity.ScaleDowns++
+				}
+			}
+			activity.Rescales += count
+			activity.Sizes = append(activity.Sizes, size)
+		}
+		activity.Flapping = activity.Rescales >= hpaFlappingMinRescales && activity.ScaleUps > 0 && activity.ScaleDowns > 0
+
+		if activity.Rescales > 0 || activity.CurrentReplicas != activity.DesiredReplicas {
+			activities = append(activities, activity)
+		}
+	}
+
+	sort.Slice(activities, func(i, j int) bool {
+		if activities[i].Rescales != activities[j].Rescales {
+			return activities[i].Rescales > activities[j].Rescales
+		}
+		if activities[i].Namespace != activities[j].Namespace {
+			return activities[i].Namespace < activities[j].Namespace
+		}
+		return activities[i].Name < activities[j].Name
+	})
+	return activities, nil
+}
+
+// behaviorPatch returns the command that sets the stabilization windows and a gradual scale-down
+// policy on a flapping HPA, keeping longer windows already configured
+func (a HPAScalingActivity) behaviorPatch() string {
+	scaleDown := int32(recommendedScaleDownStabilization)
+	if a.ScaleDownStabilization != nil && *a.ScaleDownStabilization > scaleDown {
+		scaleDown = *a.ScaleDownStabilization
+	}
+	scaleUp := int32(recommendedScaleUpStabilization)
+	if a.ScaleUpStabilization != nil && *a.ScaleUpStabilization > scaleUp {
+		scaleUp = *a.ScaleUpStabilization
+	}
+	return fmt.Sprintf(`kubectl patch hpa %s -n %s --type merge -p '{"spec":{"behavior":{"scaleDown":{"stabilizationWindowSeconds":%d,`+
+		`"policies":[{"type":"Percent","value":10,"periodSeconds":60}]},"scaleUp":{"stabilizationWindowSeconds":%d}}}}'`,
+		a.Name, a.Namespace, scaleDown, scaleUp)
+}
+
+// hpaFlappingFindings turns each flapping HPA into a finding
+func hpaFlappingFindings(activities []HPAScalingActivity) []Finding {
+	var findings []Finding
+	for _, a := range activities {
+		if !a.Flapping {
+			continue
+		}
+		findings = append(findings, Finding{
+			Kind:      "hpa-instavel",
+			Severity:  SeverityMedium,
+			Title:     fmt.Sprintf("HPA %s/%s instável: %d reescalas na janela analisada", a.Namespace, a.Name, a.Rescales),
+			Namespace: a.Namespace,
+			Workload:  a.Name,
+			Body: fmt.Sprintf("O HPA %s (%s) reescalou %d vezes (%d para cima, %d para baixo), passando pelos tamanhos %v. "+
+				"Réplicas que sobem e descem em sequência geram reinícios de pods e instabilidade.\n\nCorreção:\n%s\n",
+				a.Name, a.Target, a.Rescales, a.ScaleUps, a.ScaleDowns, a.Sizes, a.behaviorPatch()),
+		})
+	}
+	return findings
+}
+
+// stabilizationString describes a configured stabilization window
+func stabilizationString(seconds *int32, fallback string) string {
+	if seconds == nil {
+		return fallback
+	}
+	return fmt.Sprintf("%ds", *seconds)
+}
+
+func writeHPAScaling(w io.Writer, activities []HPAScalingActivity, since time.Time) {
+	fmt.Fprintf(w, "\n=== Comportamento dos HPAs ===\n")
+	fmt.Fprintf(w, "------------------------------\n")
+	fmt.Fprintf(w, "Reescalas desde %s\n", since.Format("2006-01-02 15:04:05"))
+
+	if len(activities) == 0 {
+		fmt.Fprintf(w, "Nenhuma reescala de HPA na janela analisada\n")
+		return
+	}
+	for _, a := range activities {
+		fmt.Fprintf(w, "\nHPA: %s -> %s (Namespace: %s)\n", a.Name, a.Target, a.Namespace)
+		fmt.Fprintf(w, "Réplicas: atual %d, desejada %d (mín %d, máx %d)\n", a.CurrentReplicas, a.DesiredReplicas, a.MinReplicas, a.MaxReplicas)
+		fmt.Fprintf(w, "Reescalas: %d (%d para cima, %d para baixo)", a.Rescales, a.ScaleUps, a.ScaleDowns)
+		if len(a.Sizes) > 0 {
+			fmt.Fprintf(w, ", tamanhos %v", a.Sizes)
+		}
+		fmt.Fprintf(w, "\n")
+		fmt.Fprintf(w, "Estabilização: scale-up %s, scale-down %s\n",
+			stabilizationString(a.ScaleUpStabilization, "padrão (0s)"), stabilizationString(a.ScaleDownStabilization, "padrão (300s)"))
+
+		if a.Flapping {
+			fmt.Fprintf(w, "   Problema: O HPA sobe e desce réplicas repetidamente (flapping)\n")
+			fmt.Fprintf(w, "   Recomendação: Aumentar as janelas de estabilização e limitar a redução por período: %s\n", a.behaviorPatch())
+			fmt.Fprintf(w, "   Prioridade: Média\n")
+		} else if a.CurrentReplicas != a.DesiredReplicas {
+			fmt.Fprintf(w, "   Observação: Réplicas atuais diferentes das desejadas; verificar se o alvo consegue agendar os novos pods\n")
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Critérios para considerar um namespace esquecido: idade, tempo sem novos pods e uso de pico de CPU
+// em relação aos requests
+const (
+	forgottenNamespaceAge    = 90 * 24 * time.Hour
+	forgottenNamespaceUsePct = 5.0
+)
+
+// Anotações do kube-janitor com o tempo de vida ou a data de expiração do namespace
+const (
+	janitorTTLAnnotation     = "janitor/ttl"
+	janitorExpiresAnnotation = "janitor/expires"
+)
+
+// parseJanitorTTL parses a kube-janitor TTL (ex: 30m, 12h, 7d, 2w)
+func parseJanitorTTL(ttl string) (time.Duration, error) {
+	units := map[byte]time.Duration{'s': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(ttl) < 2 {
+		return 0, fmt.Errorf("TTL inválido: %q", ttl)
+	}
+	unit, exists := units[ttl[len(ttl)-1]]
+	value, err := strconv.Atoi(ttl[:len(ttl)-1])
+	if !exists || err != nil || value <= 0 {
+		return 0, fmt.Errorf("TTL inválido: %q", ttl)
+	}
+	return time.Duration(value) * unit, nil
+}
+
+// namespaceExpiry returns when the namespace expires according to the kube-janitor annotations
+func namespaceExpiry(ns *corev1.Namespace) (time.Time, bool) {
+	if expires := ns.Annotations[janitorExpiresAnnotation]; expires != "" {
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+			if t, err := time.Parse(layout, expires); err == nil {
+				return t, true
+			}
+		}
+	}
+	if ttl, err := parseJanitorTTL(ns.Annotations[janitorTTLAnnotation]); err == nil {
+		return ns.CreationTimestamp.Add(ttl), true
+	}
+	return time.Time{}, false
+}
+
+// NamespaceHygiene is a namespace left behind: without workloads but with quotas or volumes, forgotten
+// while reserving capacity, or past its kube-janitor expiry
+type NamespaceHygiene struct {
+	Namespace   string
+	Age         time.Duration
+	RunningPods int
+	Quotas      []string
+	PVCs        int
+	PVCStorage  int64
+	// Requests e picos dos pods em execução
+	RequestedCPU    int64
+	RequestedMemory int64
+	MaxCPU          int64
+	// Início do pod mais recente
+	LastPodStart time.Time
+	Forgotten    bool
+	ExpiredAt    time.Time
+}
+
+// residual reports whether the namespace runs nothing but still holds quotas or volumes
+func (h *NamespaceHygiene) residual() bool {
+	return h.RunningPods == 0 && (len(h.Quotas) > 0 || h.PVCs > 0)
+}
+
+// issues describes the problems of the namespace
+func (h *NamespaceHygiene) issues(now time.Time) []string {
+	var issues []string
+	if h.residual() {
+		issues = append(issues, fmt.Sprintf("nenhum pod em execução, mas com %d ResourceQuotas e %d PVCs (%s)", len(h.Quotas), h.PVCs, formatStorage(h.PVCStorage)))
+	}
+	if h.Forgotten {
+		issues = append(issues, fmt.Sprintf("sem novos pods há %d dias e pico de CPU em %.1f%% dos requests, reservando CPU %s e Memory %s",
+			int(now.Sub(h.LastPodStart).Hours()/24), percent(h.MaxCPU, h.RequestedCPU), formatCPU(h.RequestedCPU), formatMemory(h.RequestedMemory)))
+	}
+	if !h.ExpiredAt.IsZero() {
+		issues = append(issues, fmt.Sprintf("expirado em %s pelas anotações do kube-janitor", h.ExpiredAt.Format("2006-01-02 15:04")))
+	}
+	return issues
+}
+
+// recommendation returns what to do with the namespace
+func (h *NamespaceHygiene) recommendation() string {
+	switch {
+	case !h.ExpiredAt.IsZero():
+		return fmt.Sprintf("verificar se o kube-janitor está em execução e remover o namespace (kubectl delete namespace %s)", h.Namespace)
+	case h.residual():
+		return fmt.Sprintf("confirmar com o time responsável, salvar os dados dos PVCs e remover o namespace (kubectl delete namespace %s)", h.Namespace)
+	default:
+		return "confirmar com o time responsável se os workloads ainda são usados; reduzir réplicas e requests ou remover o namespace"
+	}
+}
+
+// analyzeNamespaceHygiene finds the namespaces left behind, skipping the system and platform namespaces
+func analyzeNamespaceHygiene(clientset *kubernetes.Clientset, pods []corev1.Pod, metrics *MetricsData, now time.Time) ([]*NamespaceHygiene, error) {
+	namespaces, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar namespaces: %v", err)
+	}
+	quotas, err := clientset.CoreV1().ResourceQuotas("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar ResourceQuotas: %v", err)
+	}
+	pvcs, err := clientset.CoreV1().PersistentVolumeClaims("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar PVCs: %v", err)
+	}
+
+	hygiene := make(map[string]*NamespaceHygiene)
+	expiry := make(map[string]time.Time)
+	for i := range namespaces.Items {
+		ns := &namespaces.Items[i]
+		if strings.HasPrefix(ns.Name, "kube-") || systemAdjacentNamespaces[ns.Name] || ns.Status.Phase == corev1.NamespaceTerminating {
+			continue
+		}
+		hygiene[ns.Name] = &NamespaceHygiene{Namespace: ns.Name, Age: now.Sub(ns.CreationTimestamp.Time)}
+		if t, exists := namespaceExpiry(ns); exists {
+			expiry[ns.Name] = t
+		}
+	}
+	for _, q := range quotas.Items {
+		if h, exists := hygiene[q.Namespace]; exists {
+			h.Quotas = append(h.Quotas, q.Name)
+		}
+	}
+	for _, pvc := range pvcs.Items {
+		if h, exists := hygiene[pvc.Namespace]; exists {
+			h.PVCs++
+			h.PVCStorage += pvc.Spec.Resources.Requests.Storage().Value()
+		}
+	}
+
+	// Pods sem métricas impedem afirmar que o namespace está ocioso
+	unmeasured := make(map[string]bool)
+	for i := range pods {
+		pod := &pods[i]
+		h, exists := hygiene[pod.Namespace]
+		if !exists || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		h.RunningPods++
+		cpu, memory := podRequests(pod)
+		h.RequestedCPU += cpu
+		h.RequestedMemory += memory
+		if pod.Status.StartTime != nil && pod.Status.StartTime.After(h.LastPodStart) {
+			h.LastPodStart = pod.Status.StartTime.Time
+		}
+		if pm, exists := metrics.PodMetrics[pod.Name]; exists && pm.Namespace == pod.Namespace {
+			h.MaxCPU += pm.MaxCPU
+		} else {
+			unmeasured[pod.Namespace] = true
+		}
+	}
+
+	var result []*NamespaceHygiene
+	for name, h := range hygiene {
+		h.Forgotten = h.RunningPods > 0 && !unmeasured[name] && h.RequestedCPU > 0 &&
+			h.Age >= forgottenNamespaceAge && now.Sub(h.LastPodStart) >= forgottenNamespaceAge &&
+			percent(h.MaxCPU, h.RequestedCPU) < forgottenNamespaceUsePct
+		if t, exists := expiry[name]; exists && t.Before(now) {
+			h.ExpiredAt = t
+		}
+		if h.residual() || h.Forgotten || !h.ExpiredAt.IsZero() {
+			result = append(result, h)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Namespace < result[j].Namespace
+	})
+	return result, nil
+}
+
+// namespaceHygieneFindings turns the namespaces left behind into findings; those reserving capacity or
+// past their expiry have medium severity
+func namespaceHygieneFindings(hygiene []*NamespaceHygiene, now time.Time) []Finding {
+	var findings []Finding
+	for _, h := range hygiene {
+		severity := SeverityLow
+		if h.Forgotten || !h.ExpiredAt.IsZero() {
+			severity = SeverityMedium
+		}
+		findings = append(findings, Finding{
+			Kind:      "higiene-namespace",
+			Severity:  severity,
+			Title:     fmt.Sprintf("Namespace %s abandonado", h.Namespace),
+			Namespace: h.Namespace,
+			Body:      fmt.Sprintf("%s.\n\nRecomendação: %s.", strings.Join(h.issues(now), "; "), h.recommendation()),
+		})
+	}
+	return findings
+}
+
+func writeNamespaceHygiene(w io.Writer, hygiene []*NamespaceHygiene, now time.Time) {
+	fmt.Fprintf(w, "\n=== Higiene do Cluster ===\n")
+	fmt.Fprintf(w, "--------------------------\n")
+
+	if len(hygiene) == 0 {
+		fmt.Fprintf(w, "Nenhum namespace abandonado, esquecido ou expirado\n")
+		return
+	}
+
+	for _, h := range hygiene {
+		fmt.Fprintf(w, "\nNamespace: %s (criado há %d dias)\n", h.Namespace, int(h.Age.Hours()/24))
+		for _, issue := range h.issues(now) {
+			fmt.Fprintf(w, "- %s\n", issue)
+		}
+		if len(h.Quotas) > 0 {
+			fmt.Fprintf(w, "ResourceQuotas: %s\n", strings.Join(h.Quotas, ", "))
+		}
+		fmt.Fprintf(w, "Recomendação: %s\n", h.recommendation())
+	}
+	fmt.Fprintf(w, "\nObservação: namespaces de sistema e de plataforma (kube-*, monitoring, ingress-nginx, etc.) não são avaliados;\n")
+	fmt.Fprintf(w, "um namespace é esquecido com mais de %d dias, sem novos pods no mesmo período e pico de CPU abaixo de %.0f%% dos requests\n",
+		int(forgottenNamespaceAge.Hours()/24), forgottenNamespaceUsePct)
+}
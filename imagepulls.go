@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Duração a partir da qual um pull de imagem atrasa o rollout
+const slowImagePull = 30 * time.Second
+
+// Evento Pulled do kubelet: Successfully pulled image "nginx:1.27" in 1.234s (1.5s including waiting)
+var pulledImagePattern = regexp.MustCompile(`pulled image "([^"]+)" in ([0-9.]+[a-zµ]+)`)
+
+// Imagem citada nos eventos de falha: Failed to pull image "nginx:1.27": ...
+var failedImagePattern = regexp.MustCompile(`image "([^"]+)"`)
+
+// imageRegistry returns the registry host of an image reference, docker.io when the reference has none
+func imageRegistry(image string) string {
+	host, _, found := strings.Cut(image, "/")
+	if !found || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		return "docker.io"
+	}
+	return host
+}
+
+// isImagePullFailure reports whether a pod event or waiting reason is an image pull failure
+func isImagePullFailure(reason, message string) bool {
+	switch reason {
+	case "ErrImagePull", "ImagePullBackOff", "ErrImageNeverPull", "InvalidImageName":
+		return true
+	case "Failed", "BackOff":
+		return strings.Contains(message, "pull") || strings.Contains(message, "ErrImagePull")
+	}
+	return false
+}
+
+// ImagePullStats aggregates the pulls and pull failures of a registry or a workload
+type ImagePullStats struct {
+	Pulls    int
+	Failures int
+	Total    time.Duration
+	Slowest  time.Duration
+	// Imagem do pull mais lento
+	SlowestImage string
+}
+
+func (s *ImagePullStats) addPull(image string, duration time.Duration) {
+	s.Pulls++
+	s.Total += duration
+	if duration > s.Slowest {
+		s.Slowest = duration
+		s.SlowestImage = image
+	}
+}
+
+// average returns the mean duration of the successful pulls
+func (s *ImagePullStats) average() time.Duration {
+	if s.Pulls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Pulls)
+}
+
+// WorkloadImagePulls is the pull history of the pods of a workload
+type WorkloadImagePulls struct {
+	Namespace string
+	Workload  string
+	ImagePullStats
+	// Imagens com falha de pull e pods aguardando a imagem agora (ImagePullBackOff/ErrImagePull)
+	FailedImages []string
+	Waiting      int
+}
+
+// ImagePullReport groups the image pulls seen in the events by registry and by workload
+type ImagePullReport struct {
+	Registries map[string]*ImagePullStats
+	Workloads  []*WorkloadImagePulls
+}
+
+// problems returns the workloads with pull failures, pods waiting for the image or slow pulls
+func (r *ImagePullReport) problems() []*WorkloadImagePulls {
+	var problems []*WorkloadImagePulls
+	for _, w := range r.Workloads {
+		if w.Failures > 0 || w.Waiting > 0 || w.Slowest >= slowImagePull {
+			problems = append(problems, w)
+		}
+	}
+	return problems
+}
+
+// analyzeImagePulls aggregates the Pulled, Failed and BackOff events of the pods and the containers
+// currently waiting for their image, by registry and by workload
+func analyzeImagePulls(clientset *kubernetes.Clientset, pods []corev1.Pod, deploymentIndex map[string]*DeploymentMetrics) (*ImagePullReport, error) {
+	report := &ImagePullReport{Registries: make(map[string]*ImagePullStats)}
+	workloads := make(map[string]*WorkloadImagePulls)
+	podIndex := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		podIndex[pods[i].Namespace+"/"+pods[i].Name] = &pods[i]
+	}
+	workloadOf := func(namespace, podName string) *WorkloadImagePulls {
+		workload := workloadFromPodName(podName)
+		if pod, exists := podIndex[namespace+"/"+podName]; exists {
+			workload = workloadForPod(pod, deploymentIndex)
+		}
+		key := namespace + "/" + workload
+		if _, exists := workloads[key]; !exists {
+			workloads[key] = &WorkloadImagePulls{Namespace: namespace, Workload: workload}
+		}
+		return workloads[key]
+	}
+	registryOf := func(image string) *ImagePullStats {
+		registry := imageRegistry(image)
+		if _, exists := report.Registries[registry]; !exists {
+			report.Registries[registry] = &ImagePullStats{}
+		}
+		return report.Registries[registry]
+	}
+	addFailure := func(w *WorkloadImagePulls, image string) {
+		w.Failures++
+		registryOf(image).Failures++
+		for _, failed := range w.FailedImages {
+			if failed == image {
+				return
+			}
+		}
+		w.FailedImages = append(w.FailedImages, image)
+	}
+
+	for _, reason := range []string{"Pulled", "Failed", "BackOff"} {
+		events, err := clientset.CoreV1().Events("").List(context.TODO(), metav1.ListOptions{
+			FieldSelector: "involvedObject.kind=Pod,reason=" + reason,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar eventos de pull de imagem: %v", err)
+		}
+		for _, event := range events.Items {
+			w := workloadOf(event.InvolvedObject.Namespace, event.InvolvedObject.Name)
+			if reason == "Pulled" {
+				match := pulledImagePattern.FindStringSubmatch(event.Message)
+				if match == nil {
+					// "Container image ... already present on machine": sem pull
+					continue
+				}
+				duration, err := time.ParseDuration(match[2])
+				if err != nil {
+					continue
+				}
+				w.addPull(match[1], duration)
+				registryOf(match[1]).addPull(match[1], duration)
+				continue
+			}
+			if !isImagePullFailure(reason, event.Message) {
+				continue
+			}
+			image := ""
+			if match := failedImagePattern.FindStringSubmatch(event.Message); match != nil {
+				image = match[1]
+			}
+			// Eventos repetidos são compactados pelo API server em Count
+			for range max(event.Count, 1) {
+				addFailure(w, image)
+			}
+		}
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		for _, status := range pod.Status.ContainerStatuses {
+			if waiting := status.State.Waiting; waiting != nil && isImagePullFailure(waiting.Reason, waiting.Message) {
+				w := workloadOf(pod.Namespace, pod.Name)
+				w.Waiting++
+				if w.Failures == 0 {
+					addFailure(w, status.Image)
+				}
+			}
+		}
+	}
+
+	for _, w := range workloads {
+		sort.Strings(w.FailedImages)
+		report.Workloads = append(report.Workloads, w)
+	}
+	sort.Slice(report.Workloads, func(i, j int) bool {
+		a, b := report.Workloads[i], report.Workloads[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		if a.Slowest != b.Slowest {
+			return a.Slowest > b.Slowest
+		}
+		return a.Namespace+"/"+a.Workload < b.Namespace+"/"+b.Workload
+	})
+	return report, nil
+}
+
+// imagePullFindings turns the workloads with pull failures or slow pulls into findings
+func imagePullFindings(r *ImagePullReport) []Finding {
+	if r == nil {
+		return nil
+	}
+	var findings []Finding
+	for _, w := range r.problems() {
+		if w.Failures > 0 || w.Waiting > 0 {
+			severity := SeverityMedium
+			if w.Waiting > 0 {
+				severity = SeverityHigh
+			}
+			findings = append(findings, Finding{
+				Kind:      "pull-imagem",
+				Severity:  severity,
+				Title:     fmt.Sprintf("Falhas de pull de imagem em %s/%s", w.Namespace, w.Workload),
+				Namespace: w.Namespace,
+				Workload:  w.Workload,
+				Body: fmt.Sprintf("%d falhas de pull (ErrImagePull/ImagePullBackOff) nos eventos, %d containers aguardando a imagem agora. Imagens: %s.\n\nRecomendação: verificar se a tag existe, o imagePullSecret do service account e os limites de taxa do registry.",
+					w.Failures, w.Waiting, strings.Join(w.FailedImages, ", ")),
+			})
+			continue
+		}
+		findings = append(findings, Finding{
+			Kind:      "pull-lento",
+			Severity:  SeverityLow,
+			Title:     fmt.Sprintf("Pull de imagem lento em %s/%s", w.Namespace, w.Workload),
+			Namespace: w.Namespace,
+			Workload:  w.Workload,
+			Body: fmt.Sprintf("O pull de %s levou %v (média de %v em %d pulls), atrasando o rollout e o reagendamento dos pods.\n\nRecomendação: reduzir o tamanho da imagem, usar um mirror do registry próximo ao cluster ou pré-carregar a imagem nos nodes.",
+				w.SlowestImage, w.Slowest.Round(time.Second), w.average().Round(time.Second), w.Pulls),
+		})
+	}
+	return findings
+}
+
+func writeImagePulls(w io.Writer, r *ImagePullReport) {
+	fmt.Fprintf(w, "\n=== Pull de Imagens ===\n")
+	fmt.Fprintf(w, "-----------------------\n")
+
+	if len(r.Registries) == 0 {
+		fmt.Fprintf(w, "Nenhum pull de imagem nos eventos retidos pelo API server\n")
+		return
+	}
+
+	registries := make([]string, 0, len(r.Registries))
+	for registry := range r.Registries {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "REGISTRY\tPULLS\tMÉDIA\tMAIS LENTO\tFALHAS\n")
+	for _, registry := range registries {
+		s := r.Registries[registry]
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%d\n", registry, s.Pulls, s.average().Round(time.Millisecond), s.Slowest.Round(time.Millisecond), s.Failures)
+	}
+	tw.Flush()
+
+	problems := r.problems()
+	if len(problems) == 0 {
+		fmt.Fprintf(w, "\nNenhum workload com falhas de pull ou pulls acima de %v\n", slowImagePull)
+		return
+	}
+	fmt.Fprintf(w, "\nWorkloads com falhas de pull ou pulls acima de %v:\n", slowImagePull)
+	for _, p := range problems {
+		var details []string
+		if p.Failures > 0 {
+			details = append(details, fmt.Sprintf("%d falhas (%s)", p.Failures, strings.Join(p.FailedImages, ", ")))
+		}
+		if p.Waiting > 0 {
+			details = append(details, fmt.Sprintf("%d containers aguardando a imagem", p.Waiting))
+		}
+		if p.Slowest >= slowImagePull {
+			details = append(details, fmt.Sprintf("pull mais lento %v (%s)", p.Slowest.Round(time.Second), p.SlowestImage))
+		}
+		fmt.Fprintf(w, "- %s/%s: %s\n", p.Namespace, p.Workload, strings.Join(details, "; "))
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Limiares para considerar um node "quente" em relação à média do cluster
+const (
+	hotNodeMinUtilization = 70.0
+	hotNodeDeviation      = 20.0
+	maxSkewedWorkloads    = 5
+)
+
+// NodeUtilization holds the peak utilization of a node as a percentage of its allocatable
+type NodeUtilization struct {
+	Name      string
+	CPUPct    float64
+	MemoryPct float64
+}
+
+// SkewedWorkload is a workload contributing to the load of a hot node
+type SkewedWorkload struct {
+	Name      string
+	Namespace string
+	Pods      int
+	CPU       int64
+	Memory    int64
+}
+
+// HotNode is a node running far hotter than the cluster average
+type HotNode struct {
+	NodeUtilization
+	Workloads []SkewedWorkload
+}
+
+// NodeImbalance summarizes the utilization spread across nodes
+type NodeImbalance struct {
+	Nodes        []NodeUtilization
+	MeanCPU      float64
+	MeanMemory   float64
+	StdDevCPU    float64
+	StdDevMemory float64
+	HotNodes     []HotNode
+}
+
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+// workloadForPod returns the name of the workload owning the pod (deployment when known)
+func workloadForPod(pod *corev1.Pod, deploymentIndex map[string]*DeploymentMetrics) string {
+	if dm, exists := deploymentIndex[pod.Namespace+"/"+pod.Name]; exists {
+		return dm.Name
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller {
+			return owner.Name
+		}
+	}
+	return pod.Name
+}
+
+func detectNodeImbalance(nodes []corev1.Node, pods []corev1.Pod, metrics *MetricsData, deploymentIndex map[string]*DeploymentMetrics) *NodeImbalance {
+	imbalance := &NodeImbalance{}
+
+	var cpuValues, memoryValues []float64
+	for _, node := range nodes {
+		nodeMetrics, exists := metrics.NodeMetrics[node.Name]
+		if !exists {
+			continue
+		}
+		allocatableCPU := node.Status.Allocatable.Cpu().MilliValue()
+		allocatableMemory := node.Status.Allocatable.Memory().Value()
+		if allocatableCPU == 0 || allocatableMemory == 0 {
+			continue
+		}
+
+		utilization := NodeUtilization{
+			Name:      node.Name,
+			CPUPct:    float64(nodeMetrics.MaxCPU) * 100 / float64(allocatableCPU),
+			MemoryPct: float64(nodeMetrics.MaxMemory) * 100 / float64(allocatableMemory),
+		}
+		imbalance.Nodes = append(imbalance.Nodes, utilization)
+		cpuValues = append(cpuValues, utilization.CPUPct)
+		memoryValues = append(memoryValues, utilization.MemoryPct)
+	}
+
+	// Com menos de 3 nodes não faz sentido falar em desbalanceamento
+	if len(imbalance.Nodes) < 3 {
+		return imbalance
+	}
+
+	imbalance.MeanCPU, imbalance.StdDevCPU = meanStdDev(cpuValues)
+	imbalance.MeanMemory, imbalance.StdDevMemory = meanStdDev(memoryValues)
+
+	hot := make(map[string]*HotNode)
+	for _, n := range imbalance.Nodes {
+		cpuHot := n.CPUPct >= hotNodeMinUtilization && n.CPUPct-imbalance.MeanCPU >= hotNodeDeviation
+		memoryHot := n.MemoryPct >= hotNodeMinUtilization && n.MemoryPct-imbalance.MeanMemory >= hotNodeDeviation
+		if cpuHot || memoryHot {
+			hot[n.Name] = &HotNode{NodeUtilization: n}
+		}
+	}
+
+	// Identificar os workloads que concentram carga nos nodes quentes
+	workloads := make(map[string]map[string]*SkewedWorkload)
+	for i := range pods {
+		pod := &pods[i]
+		if _, exists := hot[pod.Spec.NodeName]; !exists {
+			continue
+		}
+		podMetrics, exists := metrics.PodMetrics[pod.Name]
+		if !exists {
+			continue
+		}
+
+		name := workloadForPod(pod, deploymentIndex)
+		key := pod.Namespace + "/" + name
+		if workloads[pod.Spec.NodeName] == nil {
+			workloads[pod.Spec.NodeName] = make(map[string]*SkewedWorkload)
+		}
+		w, exists := workloads[pod.Spec.NodeName][key]
+		if !exists {
+			w = &SkewedWorkload{Name: name, Namespace: pod.Namespace}
+			workloads[pod.Spec.NodeName][key] = w
+		}
+		w.Pods++
+		for _, c := range podMetrics.Containers {
+			w.CPU += c.MaxCPU
+			w.Memory += c.MaxMemory
+		}
+	}
+
+	for nodeName, hotNode := range hot {
+		for _, w := range workloads[nodeName] {
+			hotNode.Workloads = append(hotNode.Workloads, *w)
+		}
+		sort.Slice(hotNode.Workloads, func(i, j int) bool {
+			return hotNode.Workloads[i].CPU > hotNode.Workloads[j].CPU
+		})
+		if len(hotNode.Workloads) > maxSkewedWorkloads {
+			hotNode.Workloads = hotNode.Workloads[:maxSkewedWorkloads]
+		}
+		imbalance.HotNodes = append(imbalance.HotNodes, *hotNode)
+	}
+
+	sort.Slice(imbalance.HotNodes, func(i, j int) bool {
+		return imbalance.HotNodes[i].CPUPct > imbalance.HotNodes[j].CPUPct
+	})
+
+	return imbalance
+}
+
+func writeNodeImbalance(w io.Writer, imbalance *NodeImbalance) {
+	fmt.Fprintf(w, "\n=== Desbalanceamento entre Nodes ===\n")
+	fmt.Fprintf(w, "------------------------------------\n")
+
+	if len(imbalance.Nodes) < 3 {
+		fmt.Fprintf(w, "Métricas de nodes insuficientes para análise (mínimo de 3 nodes)\n")
+		return
+	}
+
+	fmt.Fprintf(w, "Utilização de pico média: CPU %.1f%% (desvio %.1f), Memory %.1f%% (desvio %.1f)\n",
+		imbalance.MeanCPU, imbalance.StdDevCPU, imbalance.MeanMemory, imbalance.StdDevMemory)
+
+	if len(imbalance.HotNodes) == 0 {
+		fmt.Fprintf(w, "Nenhum node significativamente mais carregado que os demais\n")
+		return
+	}
+
+	for _, n := range imbalance.HotNodes {
+		fmt.Fprintf(w, "\nNode quente: %s (CPU %.1f%%, Memory %.1f%%)\n", n.Name, n.CPUPct, n.MemoryPct)
+		if len(n.Workloads) > 0 {
+			fmt.Fprintf(w, "  Workloads concentrados:\n")
+			for _, wl := range n.Workloads {
+				fmt.Fprintf(w, "  - %s (Namespace: %s): %d pods, CPU %s, Memory %s\n",
+					wl.Name, wl.Namespace, wl.Pods, formatCPU(wl.CPU), formatMemory(wl.Memory))
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\nRecomendação: Usar o descheduler (estratégia LowNodeUtilization) para redistribuir os pods,\n")
+	fmt.Fprintf(w, "e revisar nodeAffinity/nodeSelector e topologySpreadConstraints dos workloads listados\n")
+	fmt.Fprintf(w, "Prioridade: Média\n")
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Picos dos init containers no período, com subconsulta para capturar execuções de poucos segundos
+const (
+	initContainerCPUPeakQuery    = `max by (namespace, pod, container) (max_over_time(rate(container_cpu_usage_seconds_total{container=~"%s"}[1m])[%s:15s]))`
+	initContainerMemoryPeakQuery = `max by (namespace, pod, container) (max_over_time(container_memory_working_set_bytes{container=~"%s"}[%s]))`
+)
+
+// Margem sobre o pico observado ao sugerir os requests de um init container
+const initContainerHeadroomPct = 20
+
+// InitContainerSpike is an init container whose requests set the effective request of the pods of a
+// workload above the requests of their app containers (max() rule)
+type InitContainerSpike struct {
+	Namespace     string
+	Workload      string
+	Container     string
+	Pods          int
+	RequestCPU    int64
+	RequestMemory int64
+	// Sidecars iniciados antes do init container, em execução junto com ele
+	SidecarCPU    int64
+	SidecarMemory int64
+	// Soma dos requests dos containers da aplicação e dos sidecars (init containers com restartPolicy Always)
+	AppRequestCPU    int64
+	AppRequestMemory int64
+	// Maior duração observada no status dos pods e execuções recentes nos eventos do kubelet
+	Duration time.Duration
+	Runs     int
+	// Pico medido durante a execução (amostras da coleta ou Prometheus)
+	Measured  bool
+	MaxCPU    int64
+	MaxMemory int64
+	// Requests sugeridos e reserva liberada somando os pods
+	SuggestedCPU    int64
+	SuggestedMemory int64
+	WasteCPU        int64
+	WasteMemory     int64
+}
+
+// isSidecar reports whether the init container keeps running with the app containers
+func isSidecar(c *corev1.Container) bool {
+	return c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways
+}
+
+// initContainerDuration returns how long the last completed run of the init container took
+func initContainerDuration(pod *corev1.Pod, name string) time.Duration {
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name == name && status.State.Terminated != nil {
+			return status.State.Terminated.FinishedAt.Sub(status.State.Terminated.StartedAt.Time)
+		}
+	}
+	return 0
+}
+
+// countInitContainerRuns counts the Started events of the init containers by "namespace/pod/container".
+// The events are kept by the API server for one hour by default
+func countInitContainerRuns(clientset *kubernetes.Clientset) (map[string]int, error) {
+	events, err := clientset.CoreV1().Events("").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=Pod,reason=Started",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar eventos de início de init containers: %v", err)
+	}
+	runs := make(map[string]int)
+	for _, event := range events.Items {
+		fieldPath := event.InvolvedObject.FieldPath
+		if !strings.HasPrefix(fieldPath, "spec.initContainers{") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(fieldPath, "spec.initContainers{"), "}")
+		runs[event.InvolvedObject.Namespace+"/"+event.InvolvedObject.Name+"/"+name] += max(1, int(event.Count))
+	}
+	return runs, nil
+}
+
+// queryInitContainerPeaks returns the CPU (millicores) and memory peaks of the init containers in the
+// period, by "namespace/pod/container"
+func queryInitContainerPeaks(baseURL string, period time.Duration, names []string) (map[string][2]int64, error) {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	pattern := strings.Join(quoted, "|")
+	window := fmt.Sprintf("%ds", int(period.Seconds()))
+
+	peaks := make(map[string][2]int64)
+	for i, query := range []string{initContainerCPUPeakQuery, initContainerMemoryPeakQuery} {
+		result, err := queryPrometheus(baseURL, fmt.Sprintf(query, pattern, window))
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range result {
+			value, err := sample.value()
+			if err != nil {
+				continue
+			}
+			key := sample.Metric["namespace"] + "/" + sample.Metric["pod"] + "/" + sample.Metric["container"]
+			peak := peaks[key]
+			if i == 0 {
+				peak[0] = int64(value * 1000)
+			} else {
+				peak[1] = int64(value)
+			}
+			peaks[key] = peak
+		}
+	}
+	return peaks, nil
+}
+
+// analyzeInitContainers flags the init containers whose requests dominate the effective request of the
+// pod, measuring their runs with the collected samples, the Prometheus peaks (when promURL is set), the
+// container status timings and the Started events
+func analyzeInitContainers(clientset *kubernetes.Clientset, pods []corev1.Pod, metrics *MetricsData, promURL string, period time.Duration, deploymentIndex map[string]*DeploymentMetrics) ([]*InitContainerSpike, []error) {
+	var errs []error
+	runs, err := countInitContainerRuns(clientset)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	var peaks map[string][2]int64
+	if promURL != "" {
+		names := make(map[string]bool)
+		for i := range pods {
+			for _, c := range pods[i].Spec.InitContainers {
+				if !isSidecar(&c) {
+					names[c.Name] = true
+				}
+			}
+		}
+		if len(names) > 0 {
+			list := make([]string, 0, len(names))
+			for name := range names {
+				list = append(list, name)
+			}
+			sort.Strings(list)
+			if peaks, err = queryInitContainerPeaks(promURL, period, list); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	spikes := make(map[string]*InitContainerSpike)
+	for i := range pods {
+		pod := &pods[i]
+		if len(pod.Spec.InitContainers) == 0 || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		var appCPU, appMemory int64
+		for _, c := range pod.Spec.Containers {
+			appCPU += c.Resources.Requests.Cpu().MilliValue()
+			appMemory += c.Resources.Requests.Memory().Value()
+		}
+		// Sidecars ficam em execução com os init containers seguintes e com a aplicação
+		for _, c := range pod.Spec.InitContainers {
+			if isSidecar(&c) {
+				appCPU += c.Resources.Requests.Cpu().MilliValue()
+				appMemory += c.Resources.Requests.Memory().Value()
+			}
+		}
+
+		var sidecarCPU, sidecarMemory int64
+
+		for _, c := range pod.Spec.InitContainers {
+			cpu, memory := c.Resources.Requests.Cpu().MilliValue(), c.Resources.Requests.Memory().Value()
+			if isSidecar(&c) {
+				sidecarCPU += cpu
+				sidecarMemory += memory
+				continue
+			}
+			if cpu+sidecarCPU <= appCPU && memory+sidecarMemory <= appMemory {
+				continue
+			}
+
+			workload := workloadForPod(pod, deploymentIndex)
+			key := pod.Namespace + "/" + workload + "/" + c.Name
+			s, exists := spikes[key]
+			if !exists {
+				s = &InitContainerSpike{Namespace: pod.Namespace, Workload: workload, Container: c.Name,
+					RequestCPU: cpu, RequestMemory: memory, SidecarCPU: sidecarCPU, SidecarMemory: sidecarMemory,
+					AppRequestCPU: appCPU, AppRequestMemory: appMemory}
+				spikes[key] = s
+			}
+			s.Pods++
+			s.Duration = max(s.Duration, initContainerDuration(pod, c.Name))
+			s.Runs += runs[pod.Namespace+"/"+pod.Name+"/"+c.Name]
+			if pm, exists := metrics.PodMetrics[pod.Name]; exists && pm.Namespace == pod.Namespace {
+				if cm, exists := pm.Containers[c.Name]; exists {
+					s.Measured = true
+					s.MaxCPU = max(s.MaxCPU, cm.MaxCPU)
+					s.MaxMemory = max(s.MaxMemory, cm.MaxMemory)
+				}
+			}
+			if peak, exists := peaks[pod.Namespace+"/"+pod.Name+"/"+c.Name]; exists {
+				s.Measured = true
+				s.MaxCPU = max(s.MaxCPU, peak[0])
+				s.MaxMemory = max(s.MaxMemory, peak[1])
+			}
+		}
+	}
+
+	result := make([]*InitContainerSpike, 0, len(spikes))
+	for _, s := range spikes {
+		// Sem medição, o maior valor que não aumenta o request efetivo do pod
+		s.SuggestedCPU = min(s.RequestCPU, max(0, s.AppRequestCPU-s.SidecarCPU))
+		s.SuggestedMemory = min(s.RequestMemory, max(0, s.AppRequestMemory-s.SidecarMemory))
+		if s.Measured {
+			s.SuggestedCPU = min(s.RequestCPU, max(s.SuggestedCPU, withHeadroomPct(s.MaxCPU, initContainerHeadroomPct)))
+			s.SuggestedMemory = min(s.RequestMemory, max(s.SuggestedMemory, roundUpMiB(withHeadroomPct(s.MaxMemory, initContainerHeadroomPct))))
+		}
+		s.WasteCPU = max(0, s.RequestCPU+s.SidecarCPU-max(s.SuggestedCPU+s.SidecarCPU, s.AppRequestCPU)) * int64(s.Pods)
+		s.WasteMemory = max(0, s.RequestMemory+s.SidecarMemory-max(s.SuggestedMemory+s.SidecarMemory, s.AppRequestMemory)) * int64(s.Pods)
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		if result[i].Workload != result[j].Workload {
+			return result[i].Workload < result[j].Workload
+		}
+		return result[i].Container < result[j].Container
+	})
+	return result, errs
+}
+
+// initContainerFindings reports the init containers that hold capacity beyond what their runs use
+func initContainerFindings(spikes []*InitContainerSpike) []Finding {
+	var findings []Finding
+	for _, s := range spikes {
+		if s.WasteCPU <= 0 && s.WasteMemory <= 0 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Kind:      "init-container",
+			Severity:  SeverityMedium,
+			Title:     fmt.Sprintf("Init container %s domina o request efetivo de %s/%s", s.Container, s.Namespace, s.Workload),
+			Namespace: s.Namespace,
+			Workload:  s.Workload,
+			Body: fmt.Sprintf("Os requests do init container (CPU %s, Memory %s) superam os da aplicação (CPU %s, Memory %s) e ficam reservados durante toda a vida dos %d pods.\n\nRecomendação: reduzir os requests do init container para CPU %s e Memory %s, liberando CPU %s e Memory %s.",
+				formatCPU(s.RequestCPU), formatMemory(s.RequestMemory), formatCPU(s.AppRequestCPU), formatMemory(s.AppRequestMemory), s.Pods,
+				formatCPU(s.SuggestedCPU), formatMemory(s.SuggestedMemory), formatCPU(s.WasteCPU), formatMemory(s.WasteMemory)),
+		})
+	}
+	return findings
+}
+
+func writeInitContainers(w io.Writer, spikes []*InitContainerSpike) {
+	fmt.Fprintf(w, "\n=== Picos de Init Containers ===\n")
+	fmt.Fprintf(w, "--------------------------------\n")
+
+	if len(spikes) == 0 {
+		fmt.Fprintf(w, "Nenhum init container com requests acima dos containers da aplicação\n")
+		return
+	}
+
+	for _, s := range spikes {
+		fmt.Fprintf(w, "\nWorkload: %s (Namespace: %s) - init container %s, %d pods\n", s.Workload, s.Namespace, s.Container, s.Pods)
+		fmt.Fprintf(w, "Requests do init container: CPU %s, Memory %s; da aplicação: CPU %s, Memory %s\n",
+			formatCPU(s.RequestCPU), formatMemory(s.RequestMemory), formatCPU(s.AppRequestCPU), formatMemory(s.AppRequestMemory))
+		if s.SidecarCPU > 0 || s.SidecarMemory > 0 {
+			fmt.Fprintf(w, "Sidecars em execução junto com o init container: CPU %s, Memory %s\n", formatCPU(s.SidecarCPU), formatMemory(s.SidecarMemory))
+		}
+		if s.Duration > 0 || s.Runs > 0 {
+			fmt.Fprintf(w, "Execução: duração de %v, %d execuções recentes\n", s.Duration.Round(time.Second), s.Runs)
+		}
+		if s.Measured {
+			fmt.Fprintf(w, "Pico medido: CPU %s, Memory %s\n", formatCPU(s.MaxCPU), formatMemory(s.MaxMemory))
+		} else {
+			fmt.Fprintf(w, "Pico medido: nenhuma leitura durante a execução (use -prometheus-url para capturar execuções curtas)\n")
+		}
+		if s.WasteCPU > 0 || s.WasteMemory > 0 {
+			fmt.Fprintf(w, "Recomendação: reduzir os requests do init container para CPU %s e Memory %s, liberando CPU %s e Memory %s\n",
+				formatCPU(s.SuggestedCPU), formatMemory(s.SuggestedMemory), formatCPU(s.WasteCPU), formatMemory(s.WasteMemory))
+		} else {
+			fmt.Fprintf(w, "Recomendação: o pico medido justifica os requests; manter\n")
+		}
+	}
+	fmt.Fprintf(w, "\nObservação: o request efetivo do pod é o maior entre a soma dos containers da aplicação e cada init container,\n")
+	fmt.Fprintf(w, "e fica reservado no node mesmo depois que os init containers terminam\n")
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Provedores de repositório suportados
+const (
+	issueProviderGitHub = "github"
+	issueProviderGitLab = "gitlab"
+)
+
+// Resultados por página nas listagens de issues
+const issuesPerPage = 100
+
+// IssuesConfig configures the creation of issues in a GitHub or GitLab repository. The token comes
+// from the GITHUB_TOKEN or GITLAB_TOKEN environment variable
+type IssuesConfig struct {
+	Provider string `json:"provider,omitempty"`
+	// URL da API (padrão: https://api.github.com ou https://gitlab.com)
+	URL string `json:"url,omitempty"`
+	// Repositório (owner/nome no GitHub, caminho do projeto no GitLab)
+	Repository string `json:"repository,omitempty"`
+	// Severidade mínima dos problemas que viram issues (padrão: crítica)
+	MinSeverity string `json:"min_severity,omitempty"`
+	// Labels adicionais das issues
+	Labels []string `json:"labels,omitempty"`
+}
+
+// WorkloadIssue gathers the findings of one deployment (or namespace) into a single issue
+type WorkloadIssue struct {
+	Namespace   string
+	Workload    string
+	Fingerprint string
+	Findings    []Finding
+}
+
+// groupIssues groups the findings at or above the minimum severity by workload
+func groupIssues(cluster string, findings []Finding, minimum Severity) []*WorkloadIssue {
+	index := make(map[string]*WorkloadIssue)
+	var issues []*WorkloadIssue
+	for _, f := range filterFindings(findings, minimum) {
+		key := f.Namespace + "/" + f.Workload
+		issue, exists := index[key]
+		if !exists {
+			issue = &WorkloadIssue{Namespace: f.Namespace, Workload: f.Workload, Fingerprint: trackerFingerprint(cluster, key)}
+			index[key] = issue
+			issues = append(issues, issue)
+		}
+		issue.Findings = append(issue.Findings, f)
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].Namespace+"/"+issues[i].Workload < issues[j].Namespace+"/"+issues[j].Workload
+	})
+	return issues
+}
+
+// title returns the title of the issue
+func (w *WorkloadIssue) title(cluster string) string {
+	if w.Workload == "" {
+		return fmt.Sprintf("[%s] Namespace %s: %d problema(s)", cluster, w.Namespace, len(w.Findings))
+	}
+	if w.Namespace == "" {
+		return fmt.Sprintf("[%s] Node %s: %d problema(s)", cluster, w.Workload, len(w.Findings))
+	}
+	return fmt.Sprintf("[%s] %s/%s: %d problema(s)", cluster, w.Namespace, w.Workload, len(w.Findings))
+}
+
+// marker is the hidden comment used to recognize the issue among the open ones
+func (w *WorkloadIssue) marker() string {
+	return fmt.Sprintf("<!-- %s:%s -->", trackerLabel, w.Fingerprint)
+}
+
+// body renders the issue in Markdown, one section per finding
+func (w *WorkloadIssue) body(cluster string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Problemas encontrados pelo k8s-performance-analyzer no cluster `%s`.\n", cluster)
+	for _, f := range w.Findings {
+		fmt.Fprintf(&b, "\n### %s\n\n", f.Title)
+		fmt.Fprintf(&b, "**Severidade:** %s\n\n", f.Severity)
+		fmt.Fprintf(&b, "%s\n", f.Body)
+	}
+	fmt.Fprintf(&b, "\n%s\n", w.marker())
+	return b.String()
+}
+
+// issueClient talks to the issues API of the configured provider
+type issueClient struct {
+	config IssuesConfig
+	header http.Header
+	base   string
+}
+
+func newIssueClient(config IssuesConfig) (*issueClient, error) {
+	client := &issueClient{config: config, header: http.Header{}}
+	switch config.Provider {
+	case issueProviderGitHub:
+		client.base = "https://api.github.com"
+		if config.URL != "" {
+			client.base = strings.TrimRight(config.URL, "/")
+		}
+		client.base += "/repos/" + config.Repository + "/issues"
+		client.header.Set("Accept", "application/vnd.github+json")
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			client.header.Set("Authorization", "Bearer "+token)
+		}
+	case issueProviderGitLab:
+		client.base = "https://gitlab.com"
+		if config.URL != "" {
+			client.base = strings.TrimRight(config.URL, "/")
+		}
+		client.base += "/api/v4/projects/" + url.PathEscape(config.Repository) + "/issues"
+		if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+			client.header.Set("PRIVATE-TOKEN", token)
+		}
+	default:
+		return nil, fmt.Errorf("provedor de issues inválido: %s (use github ou gitlab)", config.Provider)
+	}
+	return client, nil
+}
+
+// openBodies returns the bodies of the open issues carrying the marker label
+func (c *issueClient) openBodies() ([]string, error) {
+	var bodies []string
+	for page := 1; ; page++ {
+		query := url.Values{"labels": {trackerLabel}, "per_page": {fmt.Sprint(issuesPerPage)}, "page": {fmt.Sprint(page)}}
+		if c.config.Provider == issueProviderGitHub {
+			query.Set("state", "open")
+		} else {
+			query.Set("state", "opened")
+		}
+
+		var result []struct {
+			Body        string `json:"body"`
+			Description string `json:"description"`
+		}
+		if err := trackerRequest(http.MethodGet, c.base+"?"+query.Encode(), c.header, nil, &result); err != nil {
+			return nil, err
+		}
+		for _, issue := range result {
+			bodies = append(bodies, issue.Body+issue.Description)
+		}
+		if len(result) < issuesPerPage {
+			return bodies, nil
+		}
+	}
+}
+
+// create opens an issue, returning its URL
+func (c *issueClient) create(title, body string) (string, error) {
+	labels := append([]string{trackerLabel}, c.config.Labels...)
+	var request map[string]interface{}
+	if c.config.Provider == issueProviderGitHub {
+		request = map[string]interface{}{"title": title, "body": body, "labels": labels}
+	} else {
+		request = map[string]interface{}{"title": title, "description": body, "labels": strings.Join(labels, ",")}
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+		WebURL  string `json:"web_url"`
+	}
+	if err := trackerRequest(http.MethodPost, c.base, c.header, request, &created); err != nil {
+		return "", err
+	}
+	return created.HTMLURL + created.WebURL, nil
+}
+
+// createRepositoryIssues opens one issue per workload with findings at or above the configured
+// severity, unless an open issue with the marker label already covers it
+func createRepositoryIssues(config IssuesConfig, cluster string, findings []Finding) (*TicketResult, error) {
+	minimum := SeverityCritical
+	if config.MinSeverity != "" {
+		var err error
+		if minimum, err = parseSeverity(config.MinSeverity); err != nil {
+			return nil, err
+		}
+	}
+	client, err := newIssueClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TicketResult{}
+	issues := groupIssues(cluster, findings, minimum)
+	if len(issues) == 0 {
+		return result, nil
+	}
+	bodies, err := client.openBodies()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar issues abertas em %s: %v", config.Repository, err)
+	}
+
+	for _, issue := range issues {
+		exists := false
+		for _, body := range bodies {
+			if strings.Contains(body, issue.marker()) {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			result.Existing++
+			continue
+		}
+		link, err := client.create(issue.title(cluster), issue.body(cluster))
+		if err != nil {
+			return result, fmt.Errorf("erro ao criar issue em %s: %v", config.Repository, err)
+		}
+		result.Created = append(result.Created, link)
+	}
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// JiraConfig configures the creation of Jira issues for the findings. The credentials come from the
+// JIRA_USER and JIRA_API_TOKEN environment variables (or only JIRA_API_TOKEN, as a bearer token)
+type JiraConfig struct {
+	URL       string `json:"url,omitempty"`
+	Project   string `json:"project,omitempty"`
+	IssueType string `json:"issue_type,omitempty"`
+	// Severidade mínima dos problemas que viram tickets (padrão: alta)
+	MinSeverity string `json:"min_severity,omitempty"`
+	// Labels do deployment que indicam o responsável, em ordem de preferência
+	OwnerLabels []string `json:"owner_labels,omitempty"`
+	// Labels adicionais dos tickets
+	Labels []string `json:"labels,omitempty"`
+}
+
+// ownerLabels returns the configured owner labels or the defaults
+func (c JiraConfig) ownerLabels() []string {
+	if len(c.OwnerLabels) > 0 {
+		return c.OwnerLabels
+	}
+	return []string{"owner", "team"}
+}
+
+// findingFingerprint identifies a finding of a cluster in the tracker, so it is not reported twice
+func findingFingerprint(cluster string, f Finding) string {
+	return trackerFingerprint(cluster, f.key())
+}
+
+// findingDescription renders the text of the ticket for a finding
+func findingDescription(cluster string, f Finding) string {
+	var b strings.Builder
+	b.WriteString(f.Body)
+	b.WriteString("\n\n")
+	fmt.Fprintf(&b, "Severidade: %s\n", f.Severity)
+	fmt.Fprintf(&b, "Cluster: %s\n", cluster)
+	fmt.Fprintf(&b, "Namespace: %s\n", f.Namespace)
+	if f.Workload != "" {
+		fmt.Fprintf(&b, "Workload: %s\n", f.Workload)
+	}
+	if f.Owner != "" {
+		fmt.Fprintf(&b, "Responsável: %s\n", f.Owner)
+	}
+	b.WriteString("\nGerado pelo k8s-performance-analyzer")
+	return b.String()
+}
+
+// jiraRequest sends a request to the Jira REST API with the credentials from the environment
+func jiraRequest(config JiraConfig, method, path string, body interface{}, result interface{}) error {
+	header := http.Header{}
+	if user := os.Getenv("JIRA_USER"); user != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(user + ":" + os.Getenv("JIRA_API_TOKEN")))
+		header.Set("Authorization", "Basic "+credentials)
+	} else if token := os.Getenv("JIRA_API_TOKEN"); token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return trackerRequest(method, strings.TrimRight(config.URL, "/")+path, header, body, result)
+}
+
+// jiraIssueExists checks whether there is an unresolved issue carrying the fingerprint label
+func jiraIssueExists(config JiraConfig, fingerprint string) (bool, error) {
+	jql := fmt.Sprintf(`project = "%s" AND labels = "%s" AND statusCategory != Done`, config.Project, fingerprint)
+	var result struct {
+		Total int `json:"total"`
+	}
+	err := jiraRequest(config, http.MethodGet, "/rest/api/2/search?"+url.Values{"jql": {jql}, "maxResults": {"0"}}.Encode(), nil, &result)
+	if err != nil {
+		return false, err
+	}
+	return result.Total > 0, nil
+}
+
+// createJiraIssues opens one issue per finding at or above the configured severity, skipping the
+// findings that already have an unresolved issue
+func createJiraIssues(config JiraConfig, cluster string, findings []Finding) (*TicketResult, error) {
+	minimum := SeverityHigh
+	if config.MinSeverity != "" {
+		var err error
+		if minimum, err = parseSeverity(config.MinSeverity); err != nil {
+			return nil, err
+		}
+	}
+	issueType := config.IssueType
+	if issueType == "" {
+		issueType = "Task"
+	}
+
+	result := &TicketResult{}
+	for _, f := range filterFindings(findings, minimum) {
+		fingerprint := findingFingerprint(cluster, f)
+		exists, err := jiraIssueExists(config, fingerprint)
+		if err != nil {
+			return result, fmt.Errorf("erro ao consultar tickets existentes no Jira: %v", err)
+		}
+		if exists {
+			result.Existing++
+			continue
+		}
+
+		labels := append([]string{trackerLabel, fingerprint}, config.Labels...)
+		issue := map[string]interface{}{
+			"fields": map[string]interface{}{
+				"project":     map[string]string{"key": config.Project},
+				"issuetype":   map[string]string{"name": issueType},
+				"summary":     f.Title,
+				"description": findingDescription(cluster, f),
+				"labels":      labels,
+			},
+		}
+		var created struct {
+			Key string `json:"key"`
+		}
+		if err := jiraRequest(config, http.MethodPost, "/rest/api/2/issue", issue, &created); err != nil {
+			return result, fmt.Errorf("erro ao criar ticket no Jira para %s: %v", f.Title, err)
+		}
+		result.Created = append(result.Created, created.Key)
+	}
+	return result, nil
+}
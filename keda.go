@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var kedaScaledObjectResource = schema.GroupVersionResource{
+	Group:    "keda.sh",
+	Version:  "v1alpha1",
+	Resource: "scaledobjects",
+}
+
+// Réplicas padrão do KEDA quando o ScaledObject não define min/maxReplicaCount
+const (
+	kedaDefaultMinReplicas = 0
+	kedaDefaultMaxReplicas = 100
+)
+
+// KEDAScaler is a KEDA ScaledObject and the scaling observed through the HPA it manages
+type KEDAScaler struct {
+	Name        string
+	Namespace   string
+	TargetKind  string
+	TargetName  string
+	MinReplicas int64
+	MaxReplicas int64
+	Triggers    []string
+	// HPA criado pelo KEDA para o ScaledObject
+	HPA    string
+	Active bool
+	// Reescalas do HPA na janela analisada (nil quando não houve atividade)
+	Activity *HPAScalingActivity
+	// Containers do alvo sem requests de CPU ou memória
+	MissingRequests []string
+}
+
+// resourceTriggered reports whether the scaler uses a cpu or memory trigger, which depends on the requests
+func (s *KEDAScaler) resourceTriggered() bool {
+	for _, trigger := range s.Triggers {
+		if trigger == "cpu" || trigger == "memory" {
+			return true
+		}
+	}
+	return false
+}
+
+// listKEDAScaledObjects lists the KEDA ScaledObjects. A missing ScaledObject CRD is not an error
+func listKEDAScaledObjects(dynamicClient dynamic.Interface) ([]*KEDAScaler, error) {
+	list, err := dynamicClient.Resource(kedaScaledObjectResource).Namespace("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("erro ao listar ScaledObjects do KEDA: %v", err)
+	}
+
+	scalers := make([]*KEDAScaler, 0, len(list.Items))
+	for _, item := range list.Items {
+		scaler := &KEDAScaler{
+			Name:        item.GetName(),
+			Namespace:   item.GetNamespace(),
+			TargetKind:  "Deployment",
+			MinReplicas: kedaDefaultMinReplicas,
+			MaxReplicas: kedaDefaultMaxReplicas,
+			HPA:         "keda-hpa-" + item.GetName(),
+		}
+		scaler.TargetName, _, _ = unstructured.NestedString(item.Object, "spec", "scaleTargetRef", "name")
+		if kind, _, _ := unstructured.NestedString(item.Object, "spec", "scaleTargetRef", "kind"); kind != "" {
+			scaler.TargetKind = kind
+		}
+		if min, found, _ := unstructured.NestedInt64(item.Object, "spec", "minReplicaCount"); found {
+			scaler.MinReplicas = min
+		}
+		if max, found, _ := unstructured.NestedInt64(item.Object, "spec", "maxReplicaCount"); found {
+			scaler.MaxReplicas = max
+		}
+		triggers, _, _ := unstructured.NestedSlice(item.Object, "spec", "triggers")
+		for _, t := range triggers {
+			if m, ok := t.(map[string]interface{}); ok {
+				if triggerType, _, _ := unstructured.NestedString(m, "type"); triggerType != "" {
+					scaler.Triggers = append(scaler.Triggers, triggerType)
+				}
+			}
+		}
+		if hpa, _, _ := unstructured.NestedString(item.Object, "status", "hpaName"); hpa != "" {
+			scaler.HPA = hpa
+		}
+		conditions, _, _ := unstructured.NestedSlice(item.Object, "status", "conditions")
+		for _, c := range conditions {
+			if m, ok := c.(map[string]interface{}); ok && m["type"] == "Active" && m["status"] == "True" {
+				scaler.Active = true
+			}
+		}
+		scalers = append(scalers, scaler)
+	}
+	return scalers, nil
+}
+
+// mapKEDAScalers attaches the ScaledObjects to the deployments they scale, with the activity of their
+// HPA and the containers without requests, returning how many were mapped
+func mapKEDAScalers(deploymentMetrics map[string]*DeploymentMetrics, deployments map[string]*appsv1.Deployment, scalers []*KEDAScaler, activities []HPAScalingActivity) int {
+	activityByHPA := make(map[string]*HPAScalingActivity, len(activities))
+	for i := range activities {
+		activityByHPA[activities[i].Namespace+"/"+activities[i].Name] = &activities[i]
+	}
+
+	mapped := 0
+	for _, scaler := range scalers {
+		if scaler.TargetKind != "Deployment" {
+			continue
+		}
+		key := scaler.Namespace + "/" + scaler.TargetName
+		scaler.Activity = activityByHPA[scaler.Namespace+"/"+scaler.HPA]
+		if d, exists := deployments[key]; exists {
+			for _, container := range d.Spec.Template.Spec.Containers {
+				_, cpu := container.Resources.Requests[corev1.ResourceCPU]
+				_, memory := container.Resources.Requests[corev1.ResourceMemory]
+				if !cpu || !memory {
+					scaler.MissingRequests = append(scaler.MissingRequests, container.Name)
+				}
+			}
+		}
+		if dm, exists := deploymentMetrics[key]; exists {
+			dm.Scaler = scaler
+			mapped++
+		}
+	}
+	return mapped
+}
+
+// kedaMissingRequestFindings flags the workloads scaled by KEDA whose containers have no requests: every
+// replica added is scheduled blindly and cpu/memory triggers cannot compute the utilization
+func kedaMissingRequestFindings(scalers []*KEDAScaler) []Finding {
+	var findings []Finding
+	for _, scaler := range scalers {
+		if len(scaler.MissingRequests) == 0 {
+			continue
+		}
+		severity := SeverityHigh
+		impact := "As réplicas criadas pelo KEDA são agendadas sem reserva de recursos e podem sobrecarregar os nodes."
+		if scaler.resourceTriggered() {
+			severity = SeverityCritical
+			impact = "Os gatilhos cpu/memory dependem dos requests para calcular a utilização: sem eles o workload não escala."
+		}
+		findings = append(findings, Finding{
+			Kind:      "keda-sem-requests",
+			Severity:  severity,
+			Title:     fmt.Sprintf("%s/%s escalado pelo KEDA sem requests", scaler.Namespace, scaler.TargetName),
+			Namespace: scaler.Namespace,
+			Workload:  scaler.TargetName,
+			Body: fmt.Sprintf("O ScaledObject %s (gatilhos: %s) escala %s/%s entre %d e %d réplicas, mas os containers %s "+
+				"não têm requests de CPU e memória. %s\n\nCorreção: Definir requests de CPU e memória em todos os containers do alvo.\n",
+				scaler.Name, strings.Join(scaler.Triggers, ", "), scaler.TargetKind, scaler.TargetName,
+				scaler.MinReplicas, scaler.MaxReplicas, strings.Join(scaler.MissingRequests, ", "), impact),
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].key() < findings[j].key() })
+	return findings
+}
+
+// writeKEDAScaler writes the KEDA configuration and scaling of a deployment in its recommendation block
+func writeKEDAScaler(w io.Writer, scaler *KEDAScaler) {
+	state := "inativo"
+	if scaler.Active {
+		state = "ativo"
+	}
+	fmt.Fprintf(w, "Escalonamento KEDA: ScaledObject %s (%s), réplicas %d-%d, gatilhos: %s\n",
+		scaler.Name, state, scaler.MinReplicas, scaler.MaxReplicas, strings.Join(scaler.Triggers, ", "))
+	if a := scaler.Activity; a != nil {
+		fmt.Fprintf(w, "  Réplicas atual/desejada: %d/%d, reescalas na janela: %d (%d para cima, %d para baixo)\n",
+			a.CurrentReplicas, a.DesiredReplicas, a.Rescales, a.ScaleUps, a.ScaleDowns)
+	} else {
+		fmt.Fprintf(w, "  Nenhuma reescala na janela analisada\n")
+	}
+	if len(scaler.MissingRequests) > 0 {
+		fmt.Fprintf(w, "  Problema: Containers sem requests de CPU e memória: %s\n", strings.Join(scaler.MissingRequests, ", "))
+		if scaler.resourceTriggered() {
+			fmt.Fprintf(w, "  Recomendação: Definir os requests; os gatilhos cpu/memory não funcionam sem eles\n")
+			fmt.Fprintf(w, "  Prioridade: Crítica\n")
+		} else {
+			fmt.Fprintf(w, "  Recomendação: Definir os requests para que as réplicas adicionadas pelo KEDA reservem recursos\n")
+			fmt.Fprintf(w, "  Prioridade: Alta\n")
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Subconjunto do formato de /stats/summary do kubelet (k8s.io/kubelet/pkg/apis/stats/v1alpha1)
+
+type KubeletSummary struct {
+	Node KubeletNodeStats  `json:"node"`
+	Pods []KubeletPodStats `json:"pods"`
+}
+
+type KubeletNodeStats struct {
+	NodeName string              `json:"nodeName"`
+	CPU      *KubeletCPUStats    `json:"cpu,omitempty"`
+	Memory   *KubeletMemoryStats `json:"memory,omitempty"`
+	Fs       *KubeletFsStats     `json:"fs,omitempty"`
+	Rlimit   *KubeletRlimitStats `json:"rlimit,omitempty"`
+}
+
+// KubeletRlimitStats is the PID limit of the node and the number of processes running on it
+type KubeletRlimitStats struct {
+	MaxPID                *int64 `json:"maxpid,omitempty"`
+	NumOfRunningProcesses *int64 `json:"curproc,omitempty"`
+}
+
+type KubeletProcessStats struct {
+	ProcessCount *uint64 `json:"process_count,omitempty"`
+}
+
+type KubeletCPUStats struct {
+	UsageNanoCores *uint64 `json:"usageNanoCores,omitempty"`
+}
+
+type KubeletMemoryStats struct {
+	UsageBytes      *uint64 `json:"usageBytes,omitempty"`
+	WorkingSetBytes *uint64 `json:"workingSetBytes,omitempty"`
+	RSSBytes        *uint64 `json:"rssBytes,omitempty"`
+	PageFaults      *uint64 `json:"pageFaults,omitempty"`
+	MajorPageFaults *uint64 `json:"majorPageFaults,omitempty"`
+}
+
+type KubeletFsStats struct {
+	UsedBytes     *uint64 `json:"usedBytes,omitempty"`
+	CapacityBytes *uint64 `json:"capacityBytes,omitempty"`
+}
+
+type KubeletPodReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type KubeletPodStats struct {
+	PodRef           KubeletPodReference     `json:"podRef"`
+	Containers       []KubeletContainerStats `json:"containers"`
+	EphemeralStorage *KubeletFsStats         `json:"ephemeral-storage,omitempty"`
+	Volumes          []KubeletVolumeStats    `json:"volume,omitempty"`
+	ProcessStats     *KubeletProcessStats    `json:"process_stats,omitempty"`
+}
+
+// KubeletVolumeStats is the usage of a pod volume; PVCRef is set for volumes backed by a PVC
+type KubeletVolumeStats struct {
+	KubeletFsStats
+	Name   string               `json:"name"`
+	PVCRef *KubeletPodReference `json:"pvcRef,omitempty"`
+}
+
+type KubeletContainerStats struct {
+	Name   string              `json:"name"`
+	CPU    *KubeletCPUStats    `json:"cpu,omitempty"`
+	Memory *KubeletMemoryStats `json:"memory,omitempty"`
+	Rootfs *KubeletFsStats     `json:"rootfs,omitempty"`
+	Logs   *KubeletFsStats     `json:"logs,omitempty"`
+}
+
+func uint64Value(v *uint64) int64 {
+	if v == nil {
+		return 0
+	}
+	return int64(*v)
+}
+
+// fetchKubeletSummary queries /stats/summary of the node through the API server proxy
+func fetchKubeletSummary(clientset *kubernetes.Clientset, nodeName string) (*KubeletSummary, error) {
+	data, err := clientset.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", nodeName, "proxy", "stats", "summary").
+		DoRaw(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar o summary do kubelet no node %s: %v", nodeName, err)
+	}
+
+	summary := &KubeletSummary{}
+	if err := json.Unmarshal(data, summary); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar o summary do kubelet no node %s: %v", nodeName, err)
+	}
+	return summary, nil
+}
+
+// listNodeNames returns the names of all nodes in the cluster
+func listNodeNames(clientset *kubernetes.Clientset) ([]string, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar nodes: %v", err)
+	}
+	names := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		names = append(names, node.Name)
+	}
+	return names, nil
+}
+
+// collectKubeletSummaries scrapes the summary of every node and updates the detailed maxima
+func collectKubeletSummaries(clientset *kubernetes.Clientset, nodeNames []string, metrics *MetricsData) {
+	for _, nodeName := range nodeNames {
+		summary, err := fetchKubeletSummary(clientset, nodeName)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+			continue
+		}
+		recordKubeletDeepMetrics(summary, metrics)
+	}
+}
+
+// sampleKubelet reads CPU and memory usage from the kubelet summaries, used when the Metrics Server is absent
+func sampleKubelet(clientset *kubernetes.Clientset, nodeNames []string, metrics *MetricsData) (UsageSample, error) {
+	sample := UsageSample{Time: time.Now()}
+
+	failures := 0
+	for _, nodeName := range nodeNames {
+		summary, err := fetchKubeletSummary(clientset, nodeName)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+			failures++
+			continue
+		}
+
+		for _, pod := range summary.Pods {
+			markPodSeen(metrics, pod.PodRef.Name, pod.PodRef.Namespace, sample.Time)
+			for _, container := range pod.Containers {
+				var cpu, memory int64
+				if container.CPU != nil {
+					cpu = uint64Value(container.CPU.UsageNanoCores) / 1000000
+				}
+				if container.Memory != nil {
+					memory = uint64Value(container.Memory.WorkingSetBytes)
+				}
+				recordContainerUsage(metrics, pod.PodRef.Name, pod.PodRef.Namespace, container.Name, cpu, memory)
+			}
+		}
+
+		var nodeCPU, nodeMemory int64
+		if summary.Node.CPU != nil {
+			nodeCPU = uint64Value(summary.Node.CPU.UsageNanoCores) / 1000000
+		}
+		if summary.Node.Memory != nil {
+			nodeMemory = uint64Value(summary.Node.Memory.WorkingSetBytes)
+		}
+		sample.CPU += nodeCPU
+		sample.Memory += nodeMemory
+		recordNodeUsage(metrics, nodeName, nodeCPU, nodeMemory)
+
+		recordKubeletDeepMetrics(summary, metrics)
+	}
+
+	if failures > 0 && failures == len(nodeNames) {
+		return sample, fmt.Errorf("erro ao consultar o summary do kubelet em todos os nodes")
+	}
+	return sample, nil
+}
+
+// recordKubeletDeepMetrics updates the detailed maxima with a kubelet summary
+func recordKubeletDeepMetrics(summary *KubeletSummary, metrics *MetricsData) {
+	recordKubeletPIDs(summary, metrics)
+	for _, pod := range summary.Pods {
+		if _, exists := metrics.PodMetrics[pod.PodRef.Name]; !exists {
+			metrics.PodMetrics[pod.PodRef.Name] = &PodMetrics{
+				Namespace:  pod.PodRef.Namespace,
+				Containers: make(map[string]*ContainerMetrics),
+			}
+		}
+		pm := metrics.PodMetrics[pod.PodRef.Name]
+
+		if pod.EphemeralStorage != nil {
+			used := uint64Value(pod.EphemeralStorage.UsedBytes)
+			if used > pm.MaxEphemeralStorage {
+				pm.MaxEphemeralStorage = used
+			}
+			if b := metrics.windowBucket(&pm.window, time.Now()); b != nil {
+				b.ephemeralStorage = max(b.ephemeralStorage, used)
+			}
+		}
+
+		for _, container := range pod.Containers {
+			if _, exists := pm.Containers[container.Name]; !exists {
+				pm.Containers[container.Name] = &ContainerMetrics{}
+			}
+			cm := pm.Containers[container.Name]
+			if container.Memory == nil {
+				continue
+			}
+
+			if v := uint64Value(container.Memory.WorkingSetBytes); v > cm.MaxWorkingSet {
+				cm.MaxWorkingSet = v
+			}
+			if v := uint64Value(container.Memory.RSSBytes); v > cm.MaxRSS {
+				cm.MaxRSS = v
+			}
+			if b := metrics.windowBucket(&cm.window, time.Now()); b != nil {
+				b.workingSet = max(b.workingSet, uint64Value(container.Memory.WorkingSetBytes))
+				b.rss = max(b.rss, uint64Value(container.Memory.RSSBytes))
+			}
+			// Page faults são contadores cumulativos: manter a última leitura
+			if v := uint64Value(container.Memory.PageFaults); v > cm.PageFaults {
+				cm.PageFaults = v
+			}
+			if v := uint64Value(container.Memory.MajorPageFaults); v > cm.MajorPageFaults {
+				cm.MajorPageFaults = v
+			}
+		}
+	}
+}
+
+// DeepDeploymentMetrics aggregates the kubelet summary metrics of a deployment
+type DeepDeploymentMetrics struct {
+	Name                string
+	Namespace           string
+	MaxWorkingSet       int64
+	MaxRSS              int64
+	PageFaults          int64
+	MajorPageFaults     int64
+	MaxEphemeralStorage int64
+}
+
+func aggregateDeepMetrics(deploymentMetrics map[string]*DeploymentMetrics, metrics *MetricsData) []DeepDeploymentMetrics {
+	var result []DeepDeploymentMetrics
+	for _, dm := range deploymentMetrics {
+		deep := DeepDeploymentMetrics{Name: dm.Name, Namespace: dm.Namespace}
+		for _, podName := range dm.Pods {
+			pm, exists := metrics.PodMetrics[podName]
+			if !exists {
+				continue
+			}
+			if pm.MaxEphemeralStorage > deep.MaxEphemeralStorage {
+				deep.MaxEphemeralStorage = pm.MaxEphemeralStorage
+			}
+			for _, cm := range pm.Containers {
+				if cm.MaxWorkingSet > deep.MaxWorkingSet {
+					deep.MaxWorkingSet = cm.MaxWorkingSet
+				}
+				if cm.MaxRSS > deep.MaxRSS {
+					deep.MaxRSS = cm.MaxRSS
+				}
+				deep.PageFaults += cm.PageFaults
+				deep.MajorPageFaults += cm.MajorPageFaults
+			}
+		}
+		result = append(result, deep)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func writeDeepMetrics(w io.Writer, deep []DeepDeploymentMetrics) {
+	fmt.Fprintf(w, "\n=== Métricas Detalhadas do Kubelet ===\n")
+	fmt.Fprintf(w, "--------------------------------------\n")
+
+	for _, d := range deep {
+		fmt.Fprintf(w, "\nDeployment: %s (Namespace: %s)\n", d.Name, d.Namespace)
+		fmt.Fprintf(w, "  Working set máximo: %s\n", formatMemory(d.MaxWorkingSet))
+		fmt.Fprintf(w, "  RSS máximo: %s\n", formatMemory(d.MaxRSS))
+		fmt.Fprintf(w, "  Page faults: %d (major: %d)\n", d.PageFaults, d.MajorPageFaults)
+		fmt.Fprintf(w, "  Ephemeral storage máximo: %s\n", formatMemory(d.MaxEphemeralStorage))
+	}
+}
@@ -4,17 +4,22 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
+	_ "time/tzdata"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
@@ -32,28 +37,80 @@ type PerformanceRecommendation struct {
 	Namespace      string
 	Issue          string
 	Recommendation string
+	Priority       string
 }
 
 type MetricsData struct {
-	PodMetrics  map[string]*PodMetrics
-	NodeMetrics map[string]*NodeMetrics
+	PodMetrics     map[string]*PodMetrics
+	NodeMetrics    map[string]*NodeMetrics
+	ClusterSamples []UsageSample
+	// Fonte usada na coleta: metrics-server, kubelet ou prometheus
+	Source string
+	// Leituras ignoradas por estarem fora das janelas de coleta
+	SkippedSamples int
+	// Janela, intervalo de scrape e leituras repetidas do Metrics Server (nil com outras fontes)
+	Resolution *MetricsResolution
+	// Janela deslizante dos máximos (0 = período inteiro da coleta)
+	StatsWindow time.Duration
+	// Uso de cada workload ("namespace/workload") por dia da semana e hora do dia
+	Hourly map[string]*HourlyUsage
+	// Leituras da iteração atual a enviar ao sink (somente com sink configurado)
+	sinking bool
+	pending []SamplePoint
+	// Uso total de cada workload na iteração atual
+	reading map[string]*workloadReading
+	// Uso de cada workload por hora da coleta, usado com -stats-window
+	hourlyWindow map[string][]hourlySlot
+}
+
+// UsageSample is the total usage observed at a point in time
+type UsageSample struct {
+	Time   time.Time
+	CPU    int64
+	Memory int64
 }
 
 type PodMetrics struct {
-	MaxCPU     int64
-	MaxMemory  int64
-	Namespace  string
-	Containers map[string]*ContainerMetrics
+	MaxCPU              int64
+	MaxMemory           int64
+	MaxEphemeralStorage int64
+	Namespace           string
+	Containers          map[string]*ContainerMetrics
+	// Maior número de processos do pod, do summary do kubelet
+	MaxProcesses int64
+	// Primeira e última leitura do pod e número de leituras na janela
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Samples   int
+	// Estatísticas recentes, usadas com -stats-window
+	window *usageRing
 }
 
 type ContainerMetrics struct {
 	MaxCPU    int64
 	MaxMemory int64
+	// Métricas do summary do kubelet (apenas com -deep-metrics)
+	MaxWorkingSet   int64
+	MaxRSS          int64
+	PageFaults      int64
+	MajorPageFaults int64
+	// Estatísticas recentes, usadas com -stats-window
+	window *usageRing
+	// Leituras acumuladas para calcular a variação do uso
+	cpuVariation    usageVariation
+	memoryVariation usageVariation
 }
 
 type NodeMetrics struct {
 	MaxCPU    int64
 	MaxMemory int64
+	// Maior número de processos e limite de PIDs do node, do summary do kubelet
+	MaxProcesses int64
+	MaxPIDs      int64
+	// Leituras de uso do node na coleta
+	Samples int
+	// Estatísticas recentes, usadas com -stats-window
+	window *usageRing
 }
 
 type DeploymentMetrics struct {
@@ -67,6 +124,18 @@ type DeploymentMetrics struct {
 	TotalPods         int
 	PodsWithoutLimits int
 	Recommendations   []string
+	// Aplicação (GitOps, chart) de onde vêm os manifestos, quando identificada
+	Source *WorkloadSource
+	// ScaledObject do KEDA que escala o deployment, quando existe
+	Scaler *KEDAScaler
+	// Confiança na recomendação, pela quantidade e variação das leituras
+	Confidence *RecommendationConfidence
+	// Motivo pelo qual não há leituras suficientes para recomendar requests e limites
+	InsufficientData string
+	// Nível de criticidade do label do deployment (critical, standard, best-effort); vazio sem o label
+	Tier string
+	// Idade e rollouts do deployment, pelos ReplicaSets
+	History *WorkloadHistory
 }
 
 // sanitizeFilename removes or replaces characters that are not safe for filenames
@@ -85,117 +154,354 @@ func sanitizeFilename(name string) string {
 	return sanitized
 }
 
-func checkMetricsServer(metricsClient *metricsv.Clientset) error {
+func checkMetricsServer(metricsClient *MetricsAPI) error {
 	// Tentar listar métricas dos nodes para verificar se o Metrics Server está disponível
-	_, err := metricsClient.MetricsV1beta1().NodeMetricses().List(context.TODO(), metav1.ListOptions{})
+	_, err := metricsClient.nodeMetrics()
 	if err != nil {
 		return fmt.Errorf("erro ao conectar com o Metrics Server: %v\nCertifique-se de que o Metrics Server está instalado e funcionando no cluster", err)
 	}
 	return nil
 }
 
-func collectMetrics(clientset *kubernetes.Clientset, metricsClient *metricsv.Clientset, period time.Duration) (*MetricsData, error) {
+// Fontes de métricas suportadas pela coleta
+const (
+	sourceMetricsServer = "metrics-server"
+	sourceKubelet       = "kubelet"
+	sourcePrometheus    = "prometheus"
+)
+
+// recordContainerUsage updates the container maxima with a new reading
+func recordContainerUsage(metrics *MetricsData, podName, namespace, containerName string, cpu, memory int64) {
+	if _, exists := metrics.PodMetrics[podName]; !exists {
+		metrics.PodMetrics[podName] = &PodMetrics{
+			Namespace:  namespace,
+			Containers: make(map[string]*ContainerMetrics),
+		}
+	}
+	if _, exists := metrics.PodMetrics[podName].Containers[containerName]; !exists {
+		metrics.PodMetrics[podName].Containers[containerName] = &ContainerMetrics{}
+	}
+
+	// Atualizar máximos
+	cm := metrics.PodMetrics[podName].Containers[containerName]
+	recordWorkloadReading(metrics, podName, namespace, cpu, memory)
+	if metrics.sinking {
+		metrics.pending = append(metrics.pending, SamplePoint{Time: time.Now(), Namespace: namespace, Pod: podName,
+			Container: containerName, CPU: cpu, Memory: memory})
+	}
+	if b := metrics.windowBucket(&cm.window, time.Now()); b != nil {
+		b.addUsage(cpu, memory)
+	}
+	cm.cpuVariation.add(cpu)
+	cm.memoryVariation.add(memory)
+	if cpu > cm.MaxCPU {
+		cm.MaxCPU = cpu
+	}
+	if memory > cm.MaxMemory {
+		cm.MaxMemory = memory
+	}
+}
+
+// markPodSeen records that the pod was present in the reading taken at t
+func markPodSeen(metrics *MetricsData, podName, namespace string, t time.Time) {
+	if _, exists := metrics.PodMetrics[podName]; !exists {
+		metrics.PodMetrics[podName] = &PodMetrics{
+			Namespace:  namespace,
+			Containers: make(map[string]*ContainerMetrics),
+		}
+	}
+
+	pm := metrics.PodMetrics[podName]
+	if pm.FirstSeen.IsZero() {
+		pm.FirstSeen = t
+	}
+	pm.LastSeen = t
+	pm.Samples++
+	if b := metrics.windowBucket(&pm.window, t); b != nil {
+		b.readings++
+	}
+}
+
+// recordNodeUsage updates the node maxima with a new reading
+func recordNodeUsage(metrics *MetricsData, nodeName string, cpu, memory int64) {
+	if _, exists := metrics.NodeMetrics[nodeName]; !exists {
+		metrics.NodeMetrics[nodeName] = &NodeMetrics{}
+	}
+
+	// Atualizar máximos
+	nm := metrics.NodeMetrics[nodeName]
+	nm.Samples++
+	if metrics.sinking {
+		metrics.pending = append(metrics.pending, SamplePoint{Time: time.Now(), Node: nodeName, CPU: cpu, Memory: memory})
+	}
+	if b := metrics.windowBucket(&nm.window, time.Now()); b != nil {
+		b.addUsage(cpu, memory)
+	}
+	if cpu > nm.MaxCPU {
+		nm.MaxCPU = cpu
+	}
+	if memory > nm.MaxMemory {
+		nm.MaxMemory = memory
+	}
+}
+
+// sampleMetricsServer reads pod and node usage from the Metrics Server
+func sampleMetricsServer(metricsClient *MetricsAPI, metrics *MetricsData) (UsageSample, error) {
+	sample := UsageSample{Time: time.Now()}
+
+	// Coletar métricas dos pods
+	err := streamPodMetrics(metricsClient, func(pod *metricsv1beta1.PodMetrics) {
+		markPodSeen(metrics, pod.Name, pod.Namespace, sample.Time)
+		// Leituras que repetem o scrape anterior ficariam duplicadas nos máximos e médias
+		if !metrics.Resolution.fresh("pod/"+pod.Namespace+"/"+pod.Name, pod.Timestamp.Time, pod.Window.Duration, sample.Time) {
+			return
+		}
+		for _, container := range pod.Containers {
+			recordContainerUsage(metrics, pod.Name, pod.Namespace, container.Name,
+				container.Usage.Cpu().MilliValue(), container.Usage.Memory().Value())
+		}
+	})
+	if err != nil {
+		return sample, fmt.Errorf("erro ao coletar métricas dos pods: %v", err)
+	}
+
+	// Coletar métricas dos nodes
+	nodeMetrics, err := metricsClient.nodeMetrics()
+	if err != nil {
+		return sample, fmt.Errorf("erro ao coletar métricas dos nodes: %v", err)
+	}
+
+	repeated := len(nodeMetrics) > 0
+	for _, node := range nodeMetrics {
+		sample.CPU += node.Usage.Cpu().MilliValue()
+		sample.Memory += node.Usage.Memory().Value()
+		if metrics.Resolution.fresh("node/"+node.Name, node.Timestamp.Time, node.Window.Duration, sample.Time) {
+			repeated = false
+			recordNodeUsage(metrics, node.Name, node.Usage.Cpu().MilliValue(), node.Usage.Memory().Value())
+		}
+	}
+	if metrics.Resolution != nil {
+		metrics.Resolution.repeatedReading = repeated
+	}
+
+	return sample, nil
+}
+
+// selectMetricsSource picks the Metrics Server when available, falling back to Prometheus
+// (when configured) and then to the kubelet summary API
+func selectMetricsSource(clientset *kubernetes.Clientset, metricsClient *MetricsAPI, prometheusURL string, nodeNames []string) (string, error) {
+	// Verificar se o Metrics Server está disponível
+	err := checkMetricsServer(metricsClient)
+	if err == nil {
+		if version, _ := metricsClient.Version(); version != metricsAPIVersions[0] {
+			fmt.Printf("   Usando %s/%s do Metrics Server\n", metricsGroup, version)
+		}
+		return sourceMetricsServer, nil
+	}
+	fmt.Printf("⚠️  Aviso: %v\n", err)
+
+	if prometheusURL != "" {
+		if err := checkPrometheus(prometheusURL); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		} else {
+			fmt.Printf("   Usando Prometheus (%s) como fonte de métricas\n", prometheusURL)
+			return sourcePrometheus, nil
+		}
+	}
+
+	if len(nodeNames) > 0 {
+		if _, err := fetchKubeletSummary(clientset, nodeNames[0]); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		} else {
+			fmt.Println("   Usando o summary do kubelet como fonte de métricas")
+			return sourceKubelet, nil
+		}
+	}
+
+	return "", fmt.Errorf("nenhuma fonte de métricas disponível (Metrics Server, Prometheus ou kubelet)")
+}
+
+// Intervalo entre as leituras da coleta
+const collectionInterval = 30 * time.Second
+
+// CollectionOptions controls how and when metrics are sampled
+type CollectionOptions struct {
+	Period        time.Duration
+	DeepMetrics   bool
+	PrometheusURL string
+	// Janelas de coleta (vazio = sempre) e períodos ignorados
+	Windows   []TimeWindow
+	Blackouts []TimeWindow
+	// Fuso horário usado nas janelas e nos horários das amostras
+	Location *time.Location
+	// Desempenho da própria coleta (opcional)
+	Stats *CollectionStats
+	// Spans de cada leitura (opcional)
+	Tracer *Tracer
+	// Janela deslizante dos máximos (0 = período inteiro)
+	StatsWindow time.Duration
+	// Banco de séries temporais que recebe cada leitura (opcional)
+	Sink *SampleSink
+	// Arquivo que recebe cada leitura em JSONL ou Parquet (opcional)
+	RawSamples *RawSampleWriter
+	// Condições dos nodes lidas a cada leitura (opcional)
+	NodeConditions *NodeConditionTracker
+}
+
+func collectMetrics(clientset *kubernetes.Clientset, metricsClient *MetricsAPI, opts CollectionOptions) (*MetricsData, error) {
+	period := opts.Period
+	deepMetrics := opts.DeepMetrics
+	prometheusURL := opts.PrometheusURL
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+
 	metrics := &MetricsData{
 		PodMetrics:  make(map[string]*PodMetrics),
 		NodeMetrics: make(map[string]*NodeMetrics),
+		StatsWindow: opts.StatsWindow,
+		sinking:     opts.Sink != nil || opts.RawSamples != nil,
 	}
 
-	// Verificar se o Metrics Server está disponível
-	if err := checkMetricsServer(metricsClient); err != nil {
+	// Listar os nodes uma única vez para consultar o summary do kubelet
+	nodeNames, err := listNodeNames(clientset)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+
+	source, err := selectMetricsSource(clientset, metricsClient, prometheusURL, nodeNames)
+	if err != nil {
 		return nil, err
 	}
+	metrics.Source = source
+	if source == sourceMetricsServer {
+		metrics.Resolution = newMetricsResolution()
+	}
+
+	if deepMetrics && len(nodeNames) == 0 {
+		fmt.Println("⚠️  Aviso: nenhum node encontrado - métricas detalhadas desativadas")
+		deepMetrics = false
+	}
 
-	interval := 30 * time.Second
+	interval := collectionInterval
 	iterations := int(period / interval)
 
 	fmt.Printf("📊 Coletando métricas por %v (intervalo de %v)\n", period, interval)
 
 	for i := 0; i < iterations; i++ {
-		fmt.Printf("   Coleta %d/%d...\n", i+1, iterations)
-
-		// Coletar métricas dos pods
-		podMetrics, err := metricsClient.MetricsV1beta1().PodMetricses("").List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			fmt.Printf("⚠️  Aviso: Erro ao coletar métricas dos pods: %v\n", err)
+		// Ignorar leituras fora das janelas de coleta ou em períodos bloqueados
+		if !sampleAllowed(time.Now().In(opts.Location), opts.Windows, opts.Blackouts) {
+			fmt.Printf("   Coleta %d/%d ignorada (fora da janela de coleta)\n", i+1, iterations)
+			metrics.SkippedSamples++
+			opts.Stats.touch()
+			time.Sleep(interval)
 			continue
 		}
 
-		for _, pod := range podMetrics.Items {
-			if _, exists := metrics.PodMetrics[pod.Name]; !exists {
-				metrics.PodMetrics[pod.Name] = &PodMetrics{
-					Namespace:  pod.Namespace,
-					Containers: make(map[string]*ContainerMetrics),
+		fmt.Printf("   Coleta %d/%d...\n", i+1, iterations)
+		iterationStart := time.Now()
+		span := opts.Tracer.phase("leitura")
+		span.setAttribute("iteration", i+1)
+		span.setAttribute("source", source)
+
+		var sample UsageSample
+		var err error
+		switch source {
+		case sourcePrometheus:
+			sample, err = samplePrometheus(prometheusURL, metrics)
+		case sourceKubelet:
+			// O summary já inclui as métricas detalhadas
+			sample, err = sampleKubelet(clientset, nodeNames, metrics)
+		default:
+			sample, err = sampleMetricsServer(metricsClient, metrics)
+		}
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+			span.setError(err)
+		} else if metrics.Resolution != nil && metrics.Resolution.repeatedReading {
+			// Sem dados novos nos nodes, a leitura do cluster repetiria a anterior
+			fmt.Println("   Leitura repetida descartada (Metrics Server sem dados novos)")
+		} else {
+			sample.Time = sample.Time.In(opts.Location)
+			metrics.ClusterSamples = append(metrics.ClusterSamples, sample)
+			metrics.recordHourly(sample.Time)
+			if opts.Sink != nil {
+				if err := opts.Sink.write(metrics.pending, sample); err != nil {
+					fmt.Printf("⚠️  Aviso: %v\n", err)
 				}
 			}
-
-			for _, container := range pod.Containers {
-				if _, exists := metrics.PodMetrics[pod.Name].Containers[container.Name]; !exists {
-					metrics.PodMetrics[pod.Name].Containers[container.Name] = &ContainerMetrics{}
-				}
-
-				// Atualizar máximos
-				if container.Usage.Cpu().MilliValue() > metrics.PodMetrics[pod.Name].Containers[container.Name].MaxCPU {
-					metrics.PodMetrics[pod.Name].Containers[container.Name].MaxCPU = container.Usage.Cpu().MilliValue()
-				}
-				if container.Usage.Memory().Value() > metrics.PodMetrics[pod.Name].Containers[container.Name].MaxMemory {
-					metrics.PodMetrics[pod.Name].Containers[container.Name].MaxMemory = container.Usage.Memory().Value()
+			if opts.RawSamples != nil {
+				if err := opts.RawSamples.write(metrics.pending, sample); err != nil {
+					fmt.Printf("⚠️  Aviso: %v\n", err)
 				}
 			}
 		}
+		metrics.pending = metrics.pending[:0]
+		clear(metrics.reading)
 
-		// Coletar métricas dos nodes
-		nodeMetrics, err := metricsClient.MetricsV1beta1().NodeMetricses().List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			fmt.Printf("⚠️  Aviso: Erro ao coletar métricas dos nodes: %v\n", err)
-			continue
-		}
-
-		for _, node := range nodeMetrics.Items {
-			if _, exists := metrics.NodeMetrics[node.Name]; !exists {
-				metrics.NodeMetrics[node.Name] = &NodeMetrics{}
+		if opts.NodeConditions != nil {
+			if err := opts.NodeConditions.sample(clientset, time.Now()); err != nil {
+				fmt.Printf("⚠️  Aviso: %v\n", err)
 			}
+		}
 
-			// Atualizar máximos
-			if node.Usage.Cpu().MilliValue() > metrics.NodeMetrics[node.Name].MaxCPU {
-				metrics.NodeMetrics[node.Name].MaxCPU = node.Usage.Cpu().MilliValue()
-			}
-			if node.Usage.Memory().Value() > metrics.NodeMetrics[node.Name].MaxMemory {
-				metrics.NodeMetrics[node.Name].MaxMemory = node.Usage.Memory().Value()
-			}
+		// Coletar métricas detalhadas do kubelet
+		if deepMetrics && source != sourceKubelet {
+			collectKubeletSummaries(clientset, nodeNames, metrics)
 		}
+		opts.Stats.recordIteration(time.Since(iterationStart))
+		span.end()
 
 		time.Sleep(interval)
 	}
 
+	metrics.Resolution.warn(interval)
+
+	// Manter apenas as estatísticas da janela mais recente
+	if metrics.StatsWindow > 0 {
+		applyStatsWindow(metrics, time.Now())
+	}
+
 	return metrics, nil
 }
 
-func getDeploymentForPod(clientset *kubernetes.Clientset, pod *corev1.Pod) (string, error) {
-	// Verificar se o pod pertence a um deployment
-	for _, owner := range pod.OwnerReferences {
-		if owner.Kind == "ReplicaSet" {
-			// Buscar o ReplicaSet para encontrar o deployment
-			rs, err := clientset.AppsV1().ReplicaSets(pod.Namespace).Get(context.TODO(), owner.Name, metav1.GetOptions{})
-			if err != nil {
-				continue
-			}
-			for _, rsOwner := range rs.OwnerReferences {
-				if rsOwner.Kind == "Deployment" {
-					return rsOwner.Name, nil
-				}
-			}
+// aggregateDeploymentMetrics groups the pods and their metrics by deployment, leaving out the containers
+// with overrides. The namespaces are processed in parallel by the given number of workers; a namespace
+// that fails is left out
+func aggregateDeploymentMetrics(clientset *kubernetes.Clientset, pods []corev1.Pod, metrics *MetricsData, workers int, overrides ContainerOverrides) map[string]*DeploymentMetrics {
+	deploymentMetrics := make(map[string]*DeploymentMetrics)
+	var mu sync.Mutex
+
+	namespaces, index := podsByNamespace(pods)
+	errs := forEachNamespace(namespaces, workers, func(namespace string) error {
+		owners, err := replicaSetOwners(clientset, namespace)
+		if err != nil {
+			return err
 		}
-	}
-	return "", nil
+		partial := aggregateNamespaceMetrics(index[namespace], owners, metrics, overrides)
+		mu.Lock()
+		defer mu.Unlock()
+		for key, dm := range partial {
+			deploymentMetrics[key] = dm
+		}
+		return nil
+	})
+	warnNamespaceErrors("erro ao agrupar pods por deployment", errs)
+
+	return deploymentMetrics
 }
 
-func aggregateDeploymentMetrics(clientset *kubernetes.Clientset, pods []corev1.Pod, metrics *MetricsData) map[string]*DeploymentMetrics {
+// aggregateNamespaceMetrics groups the pods of a namespace by deployment, resolving the owner through
+// the ReplicaSets of the namespace
+func aggregateNamespaceMetrics(pods []*corev1.Pod, owners map[string]string, metrics *MetricsData, overrides ContainerOverrides) map[string]*DeploymentMetrics {
 	deploymentMetrics := make(map[string]*DeploymentMetrics)
 
 	for _, pod := range pods {
-		deploymentName, err := getDeploymentForPod(clientset, &pod)
-		if err != nil {
-			continue
+		deploymentName := ""
+		for _, owner := range pod.OwnerReferences {
+			if owner.Kind == "ReplicaSet" {
+				deploymentName = owners[owner.Name]
+			}
 		}
 
 		// Se não pertence a um deployment, pular
@@ -219,6 +525,9 @@ func aggregateDeploymentMetrics(clientset *kubernetes.Clientset, pods []corev1.P
 		// Verificar se o pod tem limites definidos
 		hasLimits := true
 		for _, container := range pod.Spec.Containers {
+			if overrides.skip(pod.Namespace, container.Name) {
+				continue
+			}
 			if container.Resources.Limits.Cpu().IsZero() || container.Resources.Limits.Memory().IsZero() {
 				hasLimits = false
 				break
@@ -230,8 +539,11 @@ func aggregateDeploymentMetrics(clientset *kubernetes.Clientset, pods []corev1.P
 
 		// Agregar métricas do pod
 		if podMetrics, exists := metrics.PodMetrics[pod.Name]; exists {
-			var totalCPU, totalMemory int64
-			for _, containerMetrics := range podMetrics.Containers {
+			var totalCPU, totalMemory, containers int64
+			for name, containerMetrics := range podMetrics.Containers {
+				if overrides.skip(pod.Namespace, name) {
+					continue
+				}
 				if containerMetrics.MaxCPU > dm.MaxCPU {
 					dm.MaxCPU = containerMetrics.MaxCPU
 				}
@@ -240,17 +552,114 @@ func aggregateDeploymentMetrics(clientset *kubernetes.Clientset, pods []corev1.P
 				}
 				totalCPU += containerMetrics.MaxCPU
 				totalMemory += containerMetrics.MaxMemory
+				containers++
+			}
+			if containers > 0 {
+				dm.AvgCPU = totalCPU / containers
+				dm.AvgMemory = totalMemory / containers
 			}
-			dm.AvgCPU = totalCPU / int64(len(podMetrics.Containers))
-			dm.AvgMemory = totalMemory / int64(len(podMetrics.Containers))
 		}
 	}
 
 	return deploymentMetrics
 }
 
+// writeDeploymentRecommendation writes the metrics, issues and suggested resources of a deployment, taken
+// from its patch (nil when no change is proposed). The list of monitored pods is left out with onlyIssues
+func writeDeploymentRecommendation(w io.Writer, dm *DeploymentMetrics, patch *ResourcePatch, period time.Duration, onlyIssues bool) {
+	fmt.Fprintf(w, "\nDeployment: %s (Namespace: %s)\n", dm.Name, dm.Namespace)
+	fmt.Fprintf(w, "Total de Pods: %d\n", dm.TotalPods)
+	fmt.Fprintf(w, "Pods sem Limites: %d\n", dm.PodsWithoutLimits)
+	if dm.Source != nil {
+		fmt.Fprintf(w, "Origem: %s\n", dm.Source)
+	}
+	if dm.Tier != "" {
+		fmt.Fprintf(w, "Criticidade: %s\n", dm.Tier)
+	}
+	if dm.History != nil {
+		fmt.Fprintf(w, "Histórico: %s\n", dm.History)
+	}
+	if dm.Scaler != nil {
+		writeKEDAScaler(w, dm.Scaler)
+	}
+
+	if dm.MaxCPU > 0 || dm.MaxMemory > 0 {
+		fmt.Fprintf(w, "\nMétricas (período de %v):\n", period)
+		fmt.Fprintf(w, "  Máximo:\n")
+		fmt.Fprintf(w, "    CPU: %s\n", formatCPU(dm.MaxCPU))
+		fmt.Fprintf(w, "    Memory: %s\n", formatMemory(dm.MaxMemory))
+		fmt.Fprintf(w, "  Média:\n")
+		fmt.Fprintf(w, "    CPU: %s\n", formatCPU(dm.AvgCPU))
+		fmt.Fprintf(w, "    Memory: %s\n", formatMemory(dm.AvgMemory))
+	}
+
+	if dm.PodsWithoutLimits > 0 {
+		fmt.Fprintf(w, "\nProblemas Identificados:\n")
+		fmt.Fprintf(w, "1. %d pods sem limites de recursos definidos\n", dm.PodsWithoutLimits)
+		fmt.Fprintf(w, "   Recomendação: Definir limites de recursos (CPU e Memory) para evitar consumo excessivo\n")
+		fmt.Fprintf(w, "   Impacto: Alto - Pode causar problemas de performance no cluster\n")
+		fmt.Fprintf(w, "   Prioridade: Alta\n")
+	}
+
+	// Adicionar recomendações baseadas nas métricas
+	if dm.InsufficientData != "" {
+		fmt.Fprintf(w, "\nRecomendações de Recursos: dados insuficientes (%s)\n", dm.InsufficientData)
+		fmt.Fprintf(w, "   Nenhum request ou limite sugerido; repita a análise com um período maior (-periodo)\n")
+	} else if dm.MaxCPU > 0 || dm.MaxMemory > 0 {
+		fmt.Fprintf(w, "\nRecomendações de Recursos:\n")
+		if patch == nil {
+			fmt.Fprintf(w, "   Nenhuma alteração sugerida (valores atuais mantidos, containers excluídos ou deployment não encontrado)\n")
+		} else {
+			// Valores do patch: requests pela média dos picos e limites pelo maior pico, com as folgas do
+			// namespace e da criticidade e ajustados às políticas de requests iguais aos limits
+			for i, c := range patch.Containers {
+				fmt.Fprintf(w, "%d. Container %s:\n", i+1, c.Container)
+				fmt.Fprintf(w, "   Requests sugeridos: CPU %s, Memory %s (atuais: CPU %s, Memory %s)\n",
+					formatCPU(c.RequestCPU), formatMemory(c.RequestMemory), formatCPU(c.CurrentRequestCPU), formatMemory(c.CurrentRequestMemory))
+				fmt.Fprintf(w, "   Limites sugeridos: CPU %s, Memory %s (atuais: CPU %s, Memory %s)\n",
+					formatCPU(c.LimitCPU), formatMemory(c.LimitMemory), formatCPU(c.CurrentLimitCPU), formatMemory(c.CurrentLimitMemory))
+			}
+		}
+		if dm.Confidence != nil {
+			fmt.Fprintf(w, "   Confiança: %s\n", dm.Confidence)
+		}
+	}
+
+	if len(dm.Recommendations) > 0 {
+		fmt.Fprintf(w, "\nObservações:\n")
+		for _, note := range dm.Recommendations {
+			fmt.Fprintf(w, "- %s\n", note)
+		}
+	}
+
+	if !onlyIssues {
+		fmt.Fprintf(w, "\nPods Monitorados:\n")
+		for _, podName := range dm.Pods {
+			fmt.Fprintf(w, "- %s\n", podName)
+		}
+	}
+	fmt.Fprintf(w, "\n%s\n", strings.Repeat("-", 80))
+}
+
 func printUsage() {
-	fmt.Println("Uso: k8s-performance-analyzer [opções]")
+	fmt.Println("Uso: k8s-performance-analyzer [comando] [opções]")
+	fmt.Println("\nComandos:")
+	fmt.Println("  simulate")
+	fmt.Println("        Aplica os requests recomendados em memória e simula o agendamento nos nodes atuais")
+	fmt.Println("  apply")
+	fmt.Println("        Gera as recomendações e aplica os patches de recursos no cluster (requer -interactive, -dry-run=server ou -confirm)")
+	fmt.Println("  rollback <arquivo>")
+	fmt.Println("        Restaura os requests/limits anteriores a partir de um pacote de rollback gerado pelo apply")
+	fmt.Println("  watch")
+	fmt.Println("        Atualiza no terminal, a cada -watch-interval, os maiores consumidores e os problemas à medida que surgem")
+	fmt.Println("  admission")
+	fmt.Println("        Serve um webhook de admissão que injeta os requests/limits recomendados nos pods criados sem eles (requer -tls-cert e -tls-key)")
+	fmt.Println("  serve")
+	fmt.Println("        Serve uma API HTTP/JSON para disparar análises, consultar as recomendações do último relatório e acompanhar o uso (em -health-addr, padrão: 127.0.0.1:8080), com a chave de acesso de ANALYZER_API_TOKEN ou de performance-reports/.api-token")
+	fmt.Println("  merge <execução1> <execução2> ...")
+	fmt.Println("        Combina as amostras (samples.json ou pacotes do -bundle) de várias coletas curtas em um único conjunto, analisado com -samples")
+	fmt.Println("  config validate [arquivo]")
+	fmt.Println("        Verifica o arquivo de configuração (ou o de -config) e as opções informadas, sem executar a análise")
 	fmt.Println("\nOpções:")
 	fmt.Println("  -help")
 	fmt.Println("        Mostra esta mensagem de ajuda")
@@ -260,10 +669,99 @@ func printUsage() {
 	fmt.Println("        (opcional) Nome do contexto do Kubernetes a ser usado")
 	fmt.Println("  -periodo string")
 	fmt.Println("        (opcional) Período de coleta de métricas (ex: 30m, 1h) (padrão: 5m)")
+	fmt.Println("  -deep-metrics")
+	fmt.Println("        (opcional) Coleta métricas detalhadas (working set, RSS, page faults, ephemeral storage) via summary do kubelet")
+	fmt.Println("  -prometheus-url string")
+	fmt.Println("        (opcional) URL do Prometheus usado como fonte de métricas quando o Metrics Server não está disponível")
+	fmt.Println("  -window string")
+	fmt.Println("        (opcional) Janelas de coleta representativas, separadas por \";\" (ex: \"Mon-Fri 09:00-18:00\")")
+	fmt.Println("  -blackout string")
+	fmt.Println("        (opcional) Períodos ignorados na coleta, no mesmo formato de -window (ex: \"Sun 00:00-06:00\")")
+	fmt.Println("  -timezone string")
+	fmt.Println("        (opcional) Fuso horário IANA para horários do relatório e janelas de coleta (ex: America/Sao_Paulo) (padrão: fuso local)")
+	fmt.Println("  -headroom int")
+	fmt.Println("        (opcional) Folga mínima de capacidade, em percentual, usada na previsão (padrão: 20)")
+	fmt.Println("  -interactive")
+	fmt.Println("        (apply) Valida cada alteração no servidor, mostra e pede confirmação antes de aplicá-la")
+	fmt.Println("  -dry-run string")
+	fmt.Println("        (apply) Use \"server\" para validar todos os patches na admissão do API server sem alterar nada (não combina com -interactive e -confirm)")
+	fmt.Println("  -confirm")
+	fmt.Println("        (apply) Aplica todos os patches aceitos pela validação do servidor, sem confirmação individual")
+	fmt.Println("  -justification string")
+	fmt.Println("        (apply/rollback) Justificativa registrada no log de auditoria (padrão: período de uso observado)")
+	fmt.Println("  -audit-configmap string")
+	fmt.Println("        (apply/rollback) ConfigMap (namespace/nome) que também recebe o log de auditoria")
+	fmt.Println("  -force-context")
+	fmt.Println("        (rollback) Restaura o pacote mesmo quando ele foi gerado para outro contexto")
+	fmt.Println("  -config string")
+	fmt.Println("        (opcional) Caminho do arquivo de configuração (YAML ou JSON), ex: orçamentos por namespace")
+	fmt.Println("  -cost-basis string")
+	fmt.Println("        (opcional) Base do rateio de custos dos nodes: requests ou usage (padrão: requests)")
+	fmt.Println("  -cpu-cost float")
+	fmt.Println("        (opcional) Preço por core de CPU por hora, usado no rateio de custos (padrão: 0.0316)")
+	fmt.Println("  -memory-cost float")
+	fmt.Println("        (opcional) Preço por GiB de memória por hora, usado no rateio de custos (padrão: 0.0042)")
+	fmt.Println("  -cost-label string")
+	fmt.Println("        (opcional) Label dos pods usada para agrupar os custos por time (ex: team)")
+	fmt.Println("  -anonymize")
+	fmt.Println("        (opcional) Substitui nomes de namespaces, deployments e pods por hashes consistentes no relatório")
+	fmt.Println("  -split-by string")
+	fmt.Println("        (opcional) Gera um relatório por namespace (namespace) ou por time (label:<chave>), com um índice")
+	fmt.Println("  -bundle")
+	fmt.Println("        (opcional) Reúne os relatórios, patches e amostras coletadas em um único arquivo zip")
+	fmt.Println("  -workers int")
+	fmt.Println("        (opcional) Número de namespaces processados em paralelo na listagem dos pods e no agrupamento por deployment (padrão: 4)")
+	fmt.Println("  -stats-window string")
+	fmt.Println("        (opcional) Janela deslizante dos máximos em coletas longas: as estatísticas refletem apenas as últimas horas (ex: 6h)")
+	fmt.Println("  -pushgateway-url string")
+	fmt.Println("        (opcional) URL do Pushgateway que recebe o resumo de cada execução (desperdício, problemas, pontuação de saúde)")
+	fmt.Println("  -otlp-endpoint string")
+	fmt.Println("        (opcional) Coletor OpenTelemetry (OTLP/HTTP) que recebe os spans da execução (padrão: OTEL_EXPORTER_OTLP_ENDPOINT)")
+	fmt.Println("  -health-addr string")
+	fmt.Println("        (opcional) Endereço em que /healthz e /readyz são servidos durante a execução (ex: :8080)")
+	fmt.Println("  -only-issues")
+	fmt.Println("        (opcional) Relatório curto: apenas deployments com problemas e seções com problemas encontrados, sem listas de pods")
+	fmt.Println("  -units string")
+	fmt.Println("        (opcional) Unidades de CPU e memória nas saídas: milli (m e Mi), cores (cores e Gi) ou auto (padrão: milli)")
+	fmt.Println("  -watch-interval string")
+	fmt.Println("        (watch) Intervalo entre as atualizações (padrão: 10s)")
+	fmt.Println("  -min-samples int")
+	fmt.Println("        (opcional) Leituras mínimas de um pod para recomendar requests e limites do deployment (padrão: 3)")
+	fmt.Println("  -admission-addr string")
+	fmt.Println("        (admission) Endereço HTTPS do webhook de admissão (padrão: :8443)")
+	fmt.Println("  -tls-cert string")
+	fmt.Println("        (admission, serve) Certificado TLS do webhook de admissão ou da API do serve")
+	fmt.Println("  -tls-key string")
+	fmt.Println("        (admission, serve) Chave privada do certificado TLS")
+	fmt.Println("  -vpa-output")
+	fmt.Println("        (opcional) Grava também as recomendações como objetos VerticalPodAutoscaler com o status no formato do recommender")
+	fmt.Println("  -node-group-label string")
+	fmt.Println("        (opcional) Label usado para agrupar os nodes (ex: topology.kubernetes.io/zone; padrão: label de pool do provedor ou instance type)")
+	fmt.Println("  -samples string")
+	fmt.Println("        (opcional) Analisa as amostras de um samples.json, de um pacote do -bundle ou da saída do merge em vez de coletar métricas")
+	fmt.Println("  -tier-label string")
+	fmt.Println("        (opcional) Label de criticidade dos deployments (critical, standard ou best-effort), que define o perfil das recomendações (padrão: tier)")
+	fmt.Println("  -raw-samples string")
+	fmt.Println("        (opcional) Grava cada leitura de container, node e do cluster em performance-reports/raw-samples-*: jsonl ou parquet (colunar, para DuckDB e Spark)")
+	fmt.Println("  -estimate")
+	fmt.Println("        (opcional) Apenas estima as chamadas ao API server da execução pelo tamanho do cluster, sem coletar")
+	fmt.Println("  -executive-pdf")
+	fmt.Println("        (opcional) Gera um PDF com uma página por namespace (saúde, 3 principais ações e desperdício mensal) para gestores")
+	fmt.Println("  -suppressions string")
+	fmt.Println("        (opcional) Arquivo YAML com problemas aceitos (id, expires, reason), omitidos do relatório e das integrações até expirar")
+	fmt.Println("  -import-range string")
+	fmt.Println("        (import-history) Período de histórico importado via remote-read (padrão: 672h)")
 	fmt.Println("\nExemplos:")
 	fmt.Println("  ./k8s-performance-analyzer")
 	fmt.Println("  ./k8s-performance-analyzer -context meu-cluster -periodo 30m")
 	fmt.Println("  ./k8s-performance-analyzer -kubeconfig /caminho/para/kubeconfig")
+	fmt.Println("  ./k8s-performance-analyzer simulate -periodo 30m")
+	fmt.Println("  ./k8s-performance-analyzer apply -interactive -periodo 1h")
+	fmt.Println("  ./k8s-performance-analyzer apply -dry-run=server -periodo 1h")
+	fmt.Println("  ./k8s-performance-analyzer -split-by label:team")
+	fmt.Println("  ./k8s-performance-analyzer rollback performance-reports/rollback-meu-cluster-2025-01-01-10-00-00.json")
+	fmt.Println("  ./k8s-performance-analyzer import-history http://prometheus:9090/api/v1/read")
+	fmt.Println("  ./k8s-performance-analyzer merge dia1/samples.json dia2/bundle-prod.zip")
 }
 
 func main() {
@@ -273,6 +771,47 @@ func main() {
 	var kubeconfig *string
 	var k8sContext *string
 	var period *string
+	var headroom *int
+	var deepMetrics *bool
+	var prometheusURL *string
+	var window *string
+	var blackout *string
+	var timezone *string
+	var interactive *bool
+	var dryRun *string
+	var confirm *bool
+	var justification *string
+	var auditConfigMap *string
+	var forceContext *bool
+	var configFile *string
+	var costBasis *string
+	var cpuCost *float64
+	var memoryCost *float64
+	var costLabel *string
+	var anonymize *bool
+	var splitBy *string
+	var bundle *bool
+	var workers *int
+	var statsWindow *string
+	var pushgatewayURL *string
+	var otlpEndpoint *string
+	var healthAddr *string
+	var onlyIssues *bool
+	var units *string
+	var watchInterval *string
+	var minSamples *int
+	var admissionAddr *string
+	var tlsCert *string
+	var tlsKey *string
+	var vpaOutput *bool
+	var nodeGroupLabel *string
+	var tierLabel *string
+	var samplesFile *string
+	var rawSamples *string
+	var estimate *bool
+	var executivePDF *bool
+	var suppressionsFile *string
+	var importRange *string
 	var help *bool
 
 	if home := homedir.HomeDir(); home != "" {
@@ -283,11 +822,65 @@ func main() {
 
 	k8sContext = flag.String("context", "", "(opcional) nome do contexto do Kubernetes a ser usado")
 	period = flag.String("periodo", "5m", "(opcional) período de coleta de métricas (ex: 30m, 1h)")
+	headroom = flag.Int("headroom", 20, "(opcional) folga mínima de capacidade, em percentual, usada na previsão")
+	deepMetrics = flag.Bool("deep-metrics", false, "(opcional) coleta métricas detalhadas via summary do kubelet")
+	prometheusURL = flag.String("prometheus-url", "", "(opcional) URL do Prometheus usado como fonte de métricas alternativa")
+	window = flag.String("window", "", "(opcional) janelas de coleta representativas (ex: \"Mon-Fri 09:00-18:00\")")
+	blackout = flag.String("blackout", "", "(opcional) períodos ignorados na coleta (ex: \"Sun 00:00-06:00\")")
+	timezone = flag.String("timezone", "", "(opcional) fuso horário IANA para horários do relatório e janelas de coleta")
+	interactive = flag.Bool("interactive", false, "(apply) pede confirmação para cada alteração")
+	dryRun = flag.String("dry-run", "", "(apply) \"server\" valida os patches no API server sem aplicá-los")
+	confirm = flag.Bool("confirm", false, "(apply) aplica todos os patches sem confirmação individual")
+	justification = flag.String("justification", "", "(apply/rollback) justificativa registrada no log de auditoria")
+	auditConfigMap = flag.String("audit-configmap", "", "(apply/rollback) ConfigMap (namespace/nome) que também recebe o log de auditoria; quando cheio, continua em <nome>-1, <nome>-2...")
+	forceContext = flag.Bool("force-context", false, "(rollback) restaura o pacote mesmo quando ele foi gerado para outro contexto")
+	configFile = flag.String("config", "", "(opcional) caminho do arquivo de configuração (YAML ou JSON); o sink timescaledb requer o psql no PATH")
+	costBasis = flag.String("cost-basis", costBasisRequests, "(opcional) base do rateio de custos dos nodes: requests ou usage")
+	cpuCost = flag.Float64("cpu-cost", 0.0316, "(opcional) preço por core de CPU por hora")
+	memoryCost = flag.Float64("memory-cost", 0.0042, "(opcional) preço por GiB de memória por hora")
+	costLabel = flag.String("cost-label", "", "(opcional) label dos pods usada para agrupar os custos por time")
+	anonymize = flag.Bool("anonymize", false, "(opcional) substitui nomes de namespaces, deployments e pods por hashes no relatório")
+	splitBy = flag.String("split-by", "", "(opcional) gera um relatório por grupo: namespace ou label:<chave>")
+	bundle = flag.Bool("bundle", false, "(opcional) reúne relatórios, patches e amostras em um arquivo zip")
+	workers = flag.Int("workers", defaultWorkers, "(opcional) número de namespaces processados em paralelo")
+	statsWindow = flag.String("stats-window", "", "(opcional) janela deslizante dos máximos em coletas longas (ex: 6h)")
+	pushgatewayURL = flag.String("pushgateway-url", "", "(opcional) URL do Pushgateway que recebe o resumo da execução")
+	otlpEndpoint = flag.String("otlp-endpoint", "", "(opcional) coletor OpenTelemetry (OTLP/HTTP) que recebe os spans da execução")
+	healthAddr = flag.String("health-addr", "", "(opcional) endereço das verificações /healthz e /readyz durante a execução")
+	onlyIssues = flag.Bool("only-issues", false, "(opcional) relatório apenas com os problemas encontrados")
+	units = flag.String("units", unitsMilli, "(opcional) unidades de CPU e memória nas saídas: milli, cores ou auto")
+	watchInterval = flag.String("watch-interval", defaultWatchInterval.String(), "(watch) intervalo entre as atualizações")
+	minSamples = flag.Int("min-samples", defaultMinSamples, "(opcional) leituras mínimas de um pod para recomendar requests e limites")
+	admissionAddr = flag.String("admission-addr", ":8443", "(admission) endereço HTTPS do webhook de admissão")
+	tlsCert = flag.String("tls-cert", "", "(admission, serve) certificado TLS do webhook de admissão ou da API")
+	tlsKey = flag.String("tls-key", "", "(admission, serve) chave privada do certificado TLS")
+	vpaOutput = flag.Bool("vpa-output", false, "(opcional) grava as recomendações também no formato de status do VerticalPodAutoscaler")
+	nodeGroupLabel = flag.String("node-group-label", "", "(opcional) label usado para agrupar os nodes (padrão: label de pool do provedor ou instance type)")
+	samplesFile = flag.String("samples", "", "(opcional) analisa as amostras gravadas (samples.json, pacote ou saída do merge) em vez de coletar")
+	tierLabel = flag.String("tier-label", defaultTierLabel, "(opcional) label de criticidade dos deployments: critical, standard ou best-effort")
+	rawSamples = flag.String("raw-samples", "", "(opcional) grava cada leitura da coleta em um arquivo: jsonl ou parquet")
+	estimate = flag.Bool("estimate", false, "(opcional) estima as chamadas ao API server da execução sem coletar")
+	executivePDF = flag.Bool("executive-pdf", false, "(opcional) gera um resumo executivo em PDF com uma página por namespace")
+	suppressionsFile = flag.String("suppressions", "", "(opcional) arquivo YAML com os problemas aceitos e a data de expiração de cada um")
+	importRange = flag.String("import-range", defaultImportRange.String(), "(import-history) período de histórico importado via remote-read")
 	help = flag.Bool("help", false, "mostra a mensagem de ajuda")
 
 	// Configurar o flag.Usage para usar nossa função personalizada
 	flag.Usage = printUsage
 
+	// Verificar se um comando foi informado antes das opções
+	command := ""
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		command = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	// O comando config tem subcomandos (config validate)
+	subcommand := ""
+	if command == "config" && len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		subcommand = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	flag.Parse()
 
 	// Verificar se a flag help foi usada
@@ -296,6 +889,64 @@ func main() {
 		os.Exit(0)
 	}
 
+	switch command {
+	case "", "simulate", "apply", "rollback", "import-history", "config", "watch", "admission", "merge", "serve":
+	default:
+		fmt.Printf("❌ Comando desconhecido: %s\n", command)
+		printUsage()
+		os.Exit(1)
+	}
+
+	if *dryRun != "" && *dryRun != "server" {
+		fmt.Printf("❌ Valor inválido para -dry-run: %s (apenas \"server\" é suportado)\n", *dryRun)
+		os.Exit(1)
+	}
+	if command == "apply" && !*interactive && *dryRun == "" && !*confirm {
+		fmt.Printf("❌ O comando apply requer -interactive, -dry-run=server ou -confirm\n")
+		os.Exit(1)
+	}
+	// -interactive e -confirm alteram o cluster: combinados com o dry-run, o dry-run seria ignorado
+	if *dryRun != "" && (*interactive || *confirm) {
+		fmt.Printf("❌ -dry-run=server não pode ser combinado com -interactive ou -confirm (ambos já validam cada patch no servidor antes de aplicá-lo)\n")
+		os.Exit(1)
+	}
+
+	if command == "rollback" && flag.NArg() != 1 {
+		fmt.Printf("❌ Informe o pacote de rollback: rollback <arquivo>\n")
+		os.Exit(1)
+	}
+
+	if command == "config" && (subcommand != "validate" || flag.NArg() > 1) {
+		fmt.Printf("❌ Informe o subcomando: config validate [arquivo]\n")
+		os.Exit(1)
+	}
+
+	if command == "admission" && (*tlsCert == "" || *tlsKey == "") {
+		fmt.Printf("❌ O comando admission requer -tls-cert e -tls-key (o API server só chama webhooks via HTTPS)\n")
+		os.Exit(1)
+	}
+
+	if command == "merge" && flag.NArg() < 2 {
+		fmt.Printf("❌ Informe ao menos duas execuções: merge <samples.json|bundle.zip> <samples.json|bundle.zip> ...\n")
+		os.Exit(1)
+	}
+
+	if command == "import-history" && flag.NArg() != 1 {
+		fmt.Printf("❌ Informe o endpoint de remote-read: import-history <url>\n")
+		os.Exit(1)
+	}
+	importRangeDuration, err := time.ParseDuration(*importRange)
+	if err != nil || importRangeDuration < importDay {
+		fmt.Printf("❌ Período de importação inválido: %s (mínimo de 24h)\n", *importRange)
+		os.Exit(1)
+	}
+
+	watchIntervalDuration, err := time.ParseDuration(*watchInterval)
+	if err != nil || watchIntervalDuration < time.Second {
+		fmt.Printf("❌ Intervalo do watch inválido: %s (mínimo de 1s)\n", *watchInterval)
+		os.Exit(1)
+	}
+
 	// Converter período para duração
 	collectionPeriod, err := time.ParseDuration(*period)
 	if err != nil {
@@ -303,12 +954,185 @@ func main() {
 		os.Exit(1)
 	}
 
+	var statsWindowDuration time.Duration
+	if *statsWindow != "" {
+		statsWindowDuration, err = time.ParseDuration(*statsWindow)
+		if err != nil || statsWindowDuration <= 0 {
+			fmt.Printf("❌ Janela de estatísticas inválida: %s (ex: 6h)\n", *statsWindow)
+			os.Exit(1)
+		}
+		// O watch não tem período de coleta: a janela vale enquanto ele estiver aberto
+		if command != "watch" && statsWindowDuration >= collectionPeriod {
+			fmt.Printf("⚠️  Aviso: -stats-window (%v) não é menor que o período de coleta (%v) e não tem efeito\n", statsWindowDuration, collectionPeriod)
+			statsWindowDuration = 0
+		}
+	}
+
+	if *headroom < 0 || *headroom >= 100 {
+		fmt.Printf("❌ Folga mínima inválida: %d (use um valor entre 0 e 99)\n", *headroom)
+		os.Exit(1)
+	}
+
+	if *costBasis != costBasisRequests && *costBasis != costBasisUsage {
+		fmt.Printf("❌ Base de rateio de custos inválida: %s (use requests ou usage)\n", *costBasis)
+		os.Exit(1)
+	}
+	if *workers < 1 {
+		fmt.Printf("❌ Número de workers inválido: %d (use 1 ou mais)\n", *workers)
+		os.Exit(1)
+	}
+	if *minSamples < 1 {
+		fmt.Printf("❌ Número mínimo de leituras inválido: %d (use 1 ou mais)\n", *minSamples)
+		os.Exit(1)
+	}
+	if err := setOutputUnits(*units); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	if err := validateSplitBy(*splitBy); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	if err := validateRawSamplesFormat(*rawSamples); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	// Validar o arquivo de configuração sem executar a análise
+	if command == "config" {
+		path := *configFile
+		if flag.NArg() == 1 {
+			path = flag.Arg(0)
+		}
+		if path == "" {
+			fmt.Printf("❌ Informe o arquivo de configuração: config validate <arquivo> ou -config <arquivo>\n")
+			os.Exit(1)
+		}
+		problems, err := validateConfigFile(path)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		if printConfigProblems(path, problems) > 0 {
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Configuração válida: %s\n", path)
+		os.Exit(0)
+	}
+
+	// Combinar as amostras de várias execuções em um único conjunto, sem acessar o cluster
+	if command == "merge" {
+		var runs []*BundleSamples
+		for _, path := range flag.Args() {
+			samples, err := loadSamples(path)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				os.Exit(1)
+			}
+			runs = append(runs, samples)
+		}
+		merged, summary := mergeSamples(flag.Args(), runs)
+		if err := os.MkdirAll("performance-reports", 0755); err != nil {
+			fmt.Printf("❌ Erro ao criar diretório de relatórios: %v\n", err)
+			os.Exit(1)
+		}
+		mergedFile := filepath.Join("performance-reports", fmt.Sprintf("samples-merged-%s.json", time.Now().Format("2006-01-02-15-04-05")))
+		if err := writeMergedSamples(mergedFile, merged); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		writeMergeSummary(os.Stdout, summary)
+		fmt.Printf("\n✅ %d execuções combinadas em %s (analise com -samples %s)\n", len(runs), mergedFile, mergedFile)
+		os.Exit(0)
+	}
+
+	// Carregar o arquivo de configuração
+	analyzerConfig, err := loadConfig(*configFile)
+	if err != nil {
+		fmt.Printf("❌ Erro ao carregar configuração: %v\n", err)
+		os.Exit(1)
+	}
+	for _, problem := range analyzerConfig.problems() {
+		fmt.Printf("⚠️  Aviso: configuração: %v\n", problem)
+	}
+	// O sink só recebe as leituras dos comandos que fazem a coleta
+	collects := *samplesFile == "" && command != "rollback" && command != "watch" && command != "admission" &&
+		command != "serve" && command != "import-history"
+	if err := analyzerConfig.Sink.checkBinaries(); collects && err != nil {
+		fmt.Printf("❌ Erro na configuração do sink: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Carregar o fuso horário usado nos horários do relatório e nas janelas de coleta
+	location := time.Local
+	if *timezone != "" {
+		location, err = time.LoadLocation(*timezone)
+		if err != nil {
+			fmt.Printf("❌ Erro ao carregar fuso horário: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Carregar os problemas aceitos, que deixam de aparecer até a supressão expirar
+	var suppressions *Suppressions
+	if *suppressionsFile != "" {
+		suppressions, err = loadSuppressions(*suppressionsFile, time.Now().In(location))
+		if err != nil {
+			fmt.Printf("❌ Erro ao carregar supressões: %v\n", err)
+			os.Exit(1)
+		}
+		for _, suppression := range suppressions.Expired {
+			fmt.Printf("⚠️  Aviso: supressão %s expirou em %s\n", suppression.ID, suppression.Expires)
+		}
+	}
+
+	// Converter janelas de coleta e períodos ignorados
+	collectionWindows, err := parseTimeWindows(*window)
+	if err != nil {
+		fmt.Printf("❌ Erro ao analisar janela de coleta: %v\n", err)
+		os.Exit(1)
+	}
+	blackoutWindows, err := parseTimeWindows(*blackout)
+	if err != nil {
+		fmt.Printf("❌ Erro ao analisar período ignorado: %v\n", err)
+		os.Exit(1)
+	}
+
 	fmt.Printf("📋 Configurando conexão com o cluster...\n")
 	fmt.Printf("   - Kubeconfig: %s\n", *kubeconfig)
 	if *k8sContext != "" {
 		fmt.Printf("   - Contexto: %s\n", *k8sContext)
 	}
 	fmt.Printf("   - Período de coleta: %v\n", collectionPeriod)
+	fmt.Printf("   - Fuso horário: %s\n", location)
+	if statsWindowDuration > 0 {
+		fmt.Printf("   - Janela das estatísticas: últimas %v\n", statsWindowDuration)
+	}
+	if analyzerConfig.Sink.Type != "" {
+		fmt.Printf("   - Sink das amostras: %s\n", analyzerConfig.Sink.Type)
+	}
+	if len(analyzerConfig.Containers) > 0 {
+		fmt.Printf("   - Containers com recomendação fixa ou excluídos: %d regras\n", len(analyzerConfig.Containers))
+	}
+	if len(collectionWindows) > 0 {
+		fmt.Printf("   - Janela de coleta: %s\n", formatTimeWindows(collectionWindows))
+	}
+	if len(blackoutWindows) > 0 {
+		fmt.Printf("   - Períodos ignorados: %s\n", formatTimeWindows(blackoutWindows))
+	}
+
+	// Código de saída definido pelas políticas; os defers registrados depois rodam antes do encerramento
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// Registrar os spans da execução quando há um coletor OpenTelemetry
+	tracer := newTracer(*otlpEndpoint)
+	defer tracer.shutdown()
+	phase := tracer.phase("conexão")
 
 	// Configurar o cliente Kubernetes
 	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
@@ -318,7 +1142,7 @@ func main() {
 
 	if err != nil {
 		fmt.Printf("❌ Erro ao carregar kubeconfig: %v\n", err)
-		os.Exit(1)
+		tracer.exit(1)
 	}
 
 	// Obter o contexto atual se não foi especificado
@@ -329,49 +1153,299 @@ func main() {
 		).RawConfig()
 		if err != nil {
 			fmt.Printf("❌ Erro ao obter configuração: %v\n", err)
-			os.Exit(1)
+			tracer.exit(1)
 		}
 		*k8sContext = rawConfig.CurrentContext
 		fmt.Printf("   - Usando contexto padrão: %s\n", *k8sContext)
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
+	// Registrar as chamadas feitas pelo próprio analisador
+	collectionStats := newCollectionStats()
+
+	clientset, err := kubernetes.NewForConfig(instrumentConfig(traceConfig(withProtobuf(config), tracer), collectionStats))
 	if err != nil {
 		fmt.Printf("❌ Erro ao criar cliente Kubernetes: %v\n", err)
-		os.Exit(1)
+		tracer.exit(1)
 	}
 
 	// Criar cliente de métricas
-	metricsClient, err := metricsv.NewForConfig(config)
+	metricsClientset, err := metricsv.NewForConfig(instrumentConfig(traceConfig(config, tracer), collectionStats))
 	if err != nil {
 		fmt.Printf("❌ Erro ao criar cliente de métricas: %v\n", err)
-		os.Exit(1)
+		tracer.exit(1)
+	}
+	// Versão de metrics.k8s.io negociada com o cluster na primeira leitura
+	metricsClient := newMetricsAPI(metricsClientset)
+
+	// Criar cliente dinâmico para CRDs (ex: VPA)
+	dynamicClient, err := dynamic.NewForConfig(instrumentConfig(traceConfig(config, tracer), collectionStats))
+	if err != nil {
+		fmt.Printf("❌ Erro ao criar cliente dinâmico: %v\n", err)
+		tracer.exit(1)
 	}
 
 	fmt.Println("✅ Conexão estabelecida com sucesso!")
+	phase.end()
 
-	// Criar diretório para relatórios
-	reportDir := "performance-reports"
-	if err := os.MkdirAll(reportDir, 0755); err != nil {
-		fmt.Printf("❌ Erro ao criar diretório de relatórios: %v\n", err)
-		os.Exit(1)
+	// Servir as verificações de saúde durante coletas longas (no serve, junto com a API)
+	if *healthAddr != "" && command != "serve" {
+		health, err := newHealthServer(config, collectionStats)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		} else {
+			health.start(*healthAddr)
+			fmt.Printf("   - Verificações de saúde em %s (/healthz, /readyz)\n", *healthAddr)
+		}
 	}
 
-	// Gerar nome do arquivo de recomendações com timestamp e contexto sanitizado
-	timestamp := time.Now().Format("2006-01-02-15-04-05")
-	sanitizedContext := sanitizeFilename(*k8sContext)
-	recommendationsFile := filepath.Join(reportDir, fmt.Sprintf("recommendations-%s-%s.txt", sanitizedContext, timestamp))
+	// Restaurar os valores anteriores sem executar a coleta
+	if command == "rollback" {
+		bundle, err := loadRollbackBundle(flag.Arg(0))
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			tracer.exit(1)
+		}
+		// Restaurar em outro cluster sobrescreveria deployments homônimos com valores alheios
+		if bundle.Context != *k8sContext {
+			if !*forceContext {
+				fmt.Printf("❌ Pacote gerado para o contexto %s, diferente do contexto atual %s; use -context %s ou -force-context para restaurar mesmo assim\n",
+					bundle.Context, *k8sContext, bundle.Context)
+				tracer.exit(1)
+			}
+			fmt.Printf("⚠️  Aviso: pacote gerado para o contexto %s, aplicando em %s (-force-context)\n", bundle.Context, *k8sContext)
+		}
+		// Cada deployment restaurado é registrado no log de auditoria em seguida
+		reason := *justification
+		if reason == "" {
+			reason = fmt.Sprintf("Rollback de %s", flag.Arg(0))
+		}
+		if err := os.MkdirAll("performance-reports", 0755); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+		audit := newAuditLog(clientset, auditFilePath("performance-reports", sanitizeFilename(*k8sContext)), *auditConfigMap,
+			*k8sContext, reason, location)
+
+		fmt.Printf("\n↩️  Restaurando %d deployments de %s...\n", len(bundle.Entries), flag.Arg(0))
+		failures := rollback(clientset, bundle, audit)
+		if audit.Recorded > 0 {
+			fmt.Printf("   - Log de auditoria: %s\n", audit.Path)
+		}
+
+		if failures > 0 {
+			fmt.Printf("❌ %d deployments não foram restaurados\n", failures)
+			tracer.exit(1)
+		}
+		return
+	}
+
+	// Acompanhar o cluster ao vivo sem gerar relatório
+	if command == "watch" {
+		if err := checkMetricsServer(metricsClient); err != nil {
+			fmt.Printf("❌ O watch requer o Metrics Server: %v\n", err)
+			tracer.exit(1)
+		}
+		thresholds, errs := loadNamespaceThresholds(clientset)
+		for _, err := range errs {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+		watch := &Watch{
+			clientset:     clientset,
+			metricsClient: metricsClient,
+			context:       *k8sContext,
+			interval:      watchIntervalDuration,
+			workers:       *workers,
+			overcommitPct: analyzerConfig.Alerting.overcommitPct(),
+			thresholds:    thresholds,
+			location:      location,
+			suppressions:  suppressions,
+			overrides:     analyzerConfig.Containers,
+			window:        statsWindowDuration,
+		}
+		watch.run()
+		return
+	}
+
+	// Criar diretório para relatórios
+	reportDir := "performance-reports"
+	if err := os.MkdirAll(reportDir, 0755); err != nil {
+		fmt.Printf("❌ Erro ao criar diretório de relatórios: %v\n", err)
+		tracer.exit(1)
+	}
+
+	// Injetar as recomendações do histórico nos pods criados sem recursos, sem executar a coleta
+	if command == "admission" {
+		historyFile := historyFilePath(reportDir, sanitizeFilename(*k8sContext))
+		fmt.Printf("\n🛡️  Webhook de admissão em %s (/mutate), recomendações de %s\n", *admissionAddr, historyFile)
+		if err := newAdmissionServer(clientset, historyFile, analyzerConfig.Containers).run(*admissionAddr, *tlsCert, *tlsKey); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			tracer.exit(1)
+		}
+		return
+	}
+
+	// Servir a API de análises, recomendações e amostras, sem executar a coleta
+	if command == "serve" {
+		addr := *healthAddr
+		if addr == "" {
+			addr = defaultServeAddr
+		}
+		health, err := newHealthServer(config, nil)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			tracer.exit(1)
+		}
+		token, tokenSource, err := loadAPIToken(reportDir)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			tracer.exit(1)
+		}
+		if (*tlsCert == "") != (*tlsKey == "") {
+			fmt.Printf("❌ -tls-cert e -tls-key devem ser informados juntos\n")
+			tracer.exit(1)
+		}
+		// Fora da própria máquina, a chave de acesso só deve trafegar com TLS
+		if *tlsCert == "" && !isLoopbackAddr(addr) {
+			fmt.Printf("⚠️  Aviso: a API em %s aceita conexões de outras máquinas sem TLS; a chave de acesso trafega em texto claro (use -tls-cert e -tls-key)\n", addr)
+		}
+		api := newAnalysisAPI(metricsClient, reportDir, sanitizeFilename(*k8sContext), os.Args[1:], watchIntervalDuration, token)
+		fmt.Printf("\n🌐 API em %s (/api/v1/analyses, /api/v1/recommendations, /api/v1/samples, /healthz, /readyz)\n", addr)
+		fmt.Printf("   - Chave de acesso (Authorization: Bearer): %s\n", tokenSource)
+		if err := health.serve(addr, api, *tlsCert, *tlsKey); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			tracer.exit(1)
+		}
+		return
+	}
+
+	// Importar o histórico do Prometheus sem executar a coleta
+	if command == "import-history" {
+		historyFile := historyFilePath(reportDir, sanitizeFilename(*k8sContext))
+		history, err := loadHistory(historyFile)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			tracer.exit(1)
+		}
+		// Importar apenas o período anterior ao primeiro registro existente
+		until := time.Now()
+		if len(history) > 0 && history[0].Timestamp.Before(until) {
+			until = history[0].Timestamp
+		}
+		fmt.Printf("\n📥 Importando %v de histórico de %s...\n", importRangeDuration, flag.Arg(0))
+		imported, err := importHistory(clientset, flag.Arg(0), importRangeDuration, until, location)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+		if len(imported) == 0 {
+			fmt.Println("❌ Nenhum dia de histórico importado")
+			tracer.exit(1)
+		}
+		// A capacidade atual do cluster é usada como referência dos dias importados
+		if nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{}); err != nil {
+			fmt.Printf("⚠️  Aviso: erro ao listar nodes: %v\n", err)
+		} else {
+			allocatableCPU, allocatableMemory := clusterAllocatable(nodes.Items)
+			for i := range imported {
+				imported[i].AllocatableCPU = allocatableCPU
+				imported[i].AllocatableMemory = allocatableMemory
+			}
+		}
+		for i := range imported {
+			imported[i].Context = *k8sContext
+		}
+		if err := writeHistory(historyFile, append(imported, history...)); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			tracer.exit(1)
+		}
+		fmt.Printf("✅ %d dias importados em %s\n", len(imported), historyFile)
+		if _, err := writeReportIndex(reportDir); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+		return
+	}
+
+	// Estimar as chamadas ao API server antes de executar em control planes frágeis ou com rate limit
+	if *estimate {
+		census, err := takeClusterCensus(clientset)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			tracer.exit(1)
+		}
+		source := sourceMetricsServer
+		if err := checkMetricsServer(metricsClient); err != nil {
+			source = sourceKubelet
+			if *prometheusURL != "" {
+				source = sourcePrometheus
+			}
+		}
+		e := estimateAPIRequests(census, source, collectionPeriod, *deepMetrics, *workers, *samplesFile != "", config.QPS, config.Burst)
+		writeAPIEstimate(os.Stdout, e, collectionStats.totalCalls())
+		return
+	}
+
+	// Gerar nome do arquivo de recomendações com timestamp e contexto sanitizado
+	timestamp := time.Now().In(location).Format("2006-01-02-15-04-05")
+	sanitizedContext := sanitizeFilename(*k8sContext)
+	recommendationsFile := filepath.Join(reportDir, fmt.Sprintf("recommendations-%s-%s.txt", sanitizedContext, timestamp))
 
 	// Abrir arquivo de recomendações para escrita
-	rec, err := os.Create(recommendationsFile)
+	recFile, err := os.Create(recommendationsFile)
 	if err != nil {
 		fmt.Printf("❌ Erro ao criar arquivo de recomendações: %v\n", err)
-		os.Exit(1)
+		tracer.exit(1)
 	}
-	defer rec.Close()
+	defer recFile.Close()
+	var rec io.Writer = recFile
+
+	collectionStart := time.Now()
+	phase = tracer.phase("coleta")
 
-	// Coletar métricas ao longo do período especificado
-	metrics, err := collectMetrics(clientset, metricsClient, collectionPeriod)
+	// Registrar a contagem de reinícios no início da coleta
+	nodeConditions := newNodeConditionTracker()
+	restartsBefore, err := takeRestartSnapshot(clientset, nodeConditions.recordPod)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+
+	// Coletar métricas ao longo do período especificado, ou usar as amostras gravadas de outras execuções
+	var metrics *MetricsData
+	var rawSampleWriter *RawSampleWriter
+	rawSamplesFile := ""
+	if *samplesFile != "" {
+		fmt.Printf("📊 Usando as amostras de %s\n", *samplesFile)
+		var samples *BundleSamples
+		samples, err = loadSamples(*samplesFile)
+		if err == nil {
+			metrics = samples.metricsData()
+		}
+	} else {
+		// Amostras brutas para análise externa
+		if *rawSamples != "" {
+			rawSamplesFile = filepath.Join(reportDir, fmt.Sprintf("raw-samples-%s-%s.%s", sanitizedContext, timestamp, *rawSamples))
+			if rawSampleWriter, err = newRawSampleWriter(*rawSamples, rawSamplesFile, *k8sContext); err != nil {
+				fmt.Printf("⚠️  Aviso: %v\n", err)
+				rawSamplesFile = ""
+			}
+		}
+		metrics, err = collectMetrics(clientset, metricsClient, CollectionOptions{
+			Period:         collectionPeriod,
+			DeepMetrics:    *deepMetrics,
+			PrometheusURL:  *prometheusURL,
+			Windows:        collectionWindows,
+			Blackouts:      blackoutWindows,
+			Location:       location,
+			Stats:          collectionStats,
+			StatsWindow:    statsWindowDuration,
+			Sink:           newSampleSink(analyzerConfig.Sink, *k8sContext),
+			RawSamples:     rawSampleWriter,
+			Tracer:         tracer,
+			NodeConditions: nodeConditions,
+		})
+		if rawSampleWriter != nil {
+			if err := rawSampleWriter.close(); err != nil {
+				fmt.Printf("⚠️  Aviso: %v\n", err)
+			}
+		}
+	}
 	if err != nil {
 		fmt.Printf("⚠️  Aviso: %v\n", err)
 		fmt.Println("Continuando com a análise sem métricas...")
@@ -381,14 +1455,17 @@ func main() {
 		}
 	}
 
+	phase.end()
+
 	fmt.Println("\n📊 Analisando recursos do cluster...")
+	phase = tracer.phase("listagem")
 
 	// Analisar pods
 	fmt.Println("   - Listando pods...")
-	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	pods, err := listPods(clientset, *workers)
 	if err != nil {
 		fmt.Printf("❌ Erro ao listar pods: %v\n", err)
-		os.Exit(1)
+		tracer.exit(1)
 	}
 	fmt.Printf("   ✅ Encontrados %d pods\n", len(pods.Items))
 
@@ -397,71 +1474,1007 @@ func main() {
 	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		fmt.Printf("❌ Erro ao listar nodes: %v\n", err)
-		os.Exit(1)
+		tracer.exit(1)
 	}
 	fmt.Printf("   ✅ Encontrados %d nodes\n", len(nodes.Items))
 
+	// Descontar do allocatable a margem dos limites de despejo do kubelet no planejamento de capacidade
+	evictionThresholds, evictionErrs := readEvictionThresholds(clientset, nodes.Items, *workers)
+	if len(evictionErrs) > 0 {
+		fmt.Printf("⚠️  Aviso: configuração do kubelet indisponível em %d nodes, limites de despejo padrão assumidos: %v\n",
+			len(evictionErrs), evictionErrs[0])
+	}
+	capacityNodes := usableNodes(nodes.Items, evictionThresholds)
+
+	// Substituir os nomes do cluster por hashes para compartilhar o relatório
+	var anonymizer *Anonymizer
+	var anonymizingRec *anonymizingWriter
+	if *anonymize {
+		key, err := loadAnonymizeKey(reportDir)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			tracer.exit(1)
+		}
+		anonymizer = newAnonymizer(key)
+		anonymizer.register("cluster", *k8sContext)
+		anonymizingRec = newAnonymizingWriter(recFile, anonymizer)
+		defer anonymizingRec.Flush()
+		rec = anonymizingRec
+	}
+
 	fmt.Println("\n📝 Gerando recomendações...")
 
 	// Escrever cabeçalho do arquivo de recomendações
 	fmt.Fprintf(rec, "Recomendações de Otimização do Kubernetes\n")
 	fmt.Fprintf(rec, "Contexto: %s\n", *k8sContext)
 	fmt.Fprintf(rec, "Período de análise: %v\n", collectionPeriod)
-	fmt.Fprintf(rec, "Gerado em: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	if metrics.Source != "" {
+		fmt.Fprintf(rec, "Fonte de métricas: %s\n", metrics.Source)
+	}
+	if *samplesFile != "" {
+		fmt.Fprintf(rec, "Amostras gravadas: %s (%d leituras do cluster)\n", *samplesFile, len(metrics.ClusterSamples))
+	}
+	if len(collectionWindows) > 0 {
+		fmt.Fprintf(rec, "Janela de coleta: %s\n", formatTimeWindows(collectionWindows))
+	}
+	if len(blackoutWindows) > 0 {
+		fmt.Fprintf(rec, "Períodos ignorados: %s\n", formatTimeWindows(blackoutWindows))
+	}
+	if metrics.SkippedSamples > 0 {
+		fmt.Fprintf(rec, "Leituras ignoradas (fora da janela): %d\n", metrics.SkippedSamples)
+	}
+	writeMetricsResolution(rec, metrics.Resolution, collectionInterval)
+	fmt.Fprintf(rec, "Gerado em: %s\n\n", time.Now().In(location).Format("2006-01-02 15:04:05 MST"))
+
+	phase.end()
 
 	// Após coletar as métricas, agregar por deployment
-	deploymentMetrics := aggregateDeploymentMetrics(clientset, pods.Items, metrics)
+	phase = tracer.phase("agregação")
+	deploymentMetrics := aggregateDeploymentMetrics(clientset, pods.Items, metrics, *workers, analyzerConfig.Containers)
+	if *samplesFile != "" {
+		attached := attachSampledPods(deploymentMetrics, metrics, analyzerConfig.Containers)
+		fmt.Printf("   - Pods das amostras gravadas associados aos deployments atuais: %d\n", attached)
+	}
+
+	// Segmentar as métricas por revisão quando houve rollout durante a coleta
+	replicaSets, err := listReplicaSets(clientset)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	rollouts := segmentByRevision(replicaSets, deploymentMetrics, metrics, analyzerConfig.Containers)
+	applyLatestRevision(deploymentMetrics, rollouts)
+	assessConfidence(deploymentMetrics, metrics, analyzerConfig.Containers)
+	insufficientData := guardInsufficientData(deploymentMetrics, metrics, *minSamples)
+
+	// Identificar a aplicação GitOps de onde vem cada deployment
+	deployments, err := listDeployments(clientset)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	argoApplications, err := listArgoApplications(dynamicClient)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	mapArgoApplications(deploymentMetrics, deployments, argoApplications)
+	fluxSources, err := listFluxSources(dynamicClient)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	mapFluxSources(deploymentMetrics, deployments, fluxSources)
+
+	// Ler o nível de criticidade dos deployments, que define o perfil das recomendações
+	tieredDeployments, tierErrs := assignTiers(deploymentMetrics, deployments, *tierLabel)
+	for _, err := range tierErrs {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+
+	// Idade e frequência de rollouts dos deployments; deployments recentes têm recomendações menos confiáveis
+	assignWorkloadHistory(deploymentMetrics, deployments, replicaSets, time.Now())
+	newWorkloads := lowerConfidenceForNewWorkloads(deploymentMetrics, metrics, time.Now())
+
+	// Analisar as reescalas dos HPAs na janela e associar os ScaledObjects do KEDA aos deployments
+	hpaSince := collectionStart.Add(-hpaEventLookback)
+	hpaActivities, err := analyzeHPAScaling(clientset, hpaSince)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	kedaScalers, err := listKEDAScaledObjects(dynamicClient)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	mapKEDAScalers(deploymentMetrics, deployments, kedaScalers, hpaActivities)
+
+	// Todos os nomes que os relatórios podem conter, antes de escrevê-los
+	anonymizedLabels := []string{*costLabel, strings.TrimPrefix(*splitBy, splitByLabelPrefix)}
+	for _, err := range anonymizer.registerCluster(clientset, dynamicClient, pods.Items, replicaSets, deployments, deploymentMetrics, kedaScalers, anonymizedLabels) {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+
+	phase.end()
+	phase = tracer.phase("análises")
+
+	// Ler os limites dos namespaces que substituem os padrões via anotações
+	namespaceThresholds, thresholdErrs := loadNamespaceThresholds(clientset)
+	for _, err := range thresholdErrs {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+
+	// Gerar os patches de recursos (validados adiante contra as quotas e a capacidade do cluster), que
+	// também definem os valores sugeridos de cada deployment no relatório
+	patches := buildResourcePatches(deploymentMetrics, metrics, deployments, namespaceThresholds, analyzerConfig.Containers)
+
+	// Ajustar as recomendações às políticas que exigem requests iguais aos limits
+	requestLimitPolicies, errs := listRequestLimitPolicies(clientset, dynamicClient)
+	for _, err := range errs {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	policyWastes := requestLimitPolicies.adaptPatches(patches, deployments)
+	patchIndex := indexPatches(patches)
+
+	// Verificar se os requests sugeridos cabem em algum node
+	unsatisfiable := findUnsatisfiableRecommendations(deploymentMetrics, pods.Items, capacityNodes, patchIndex)
 
 	// Modificar a geração do relatório de recomendações
 	fmt.Fprintf(rec, "\n=== Recomendações por Deployment ===\n")
 	fmt.Fprintf(rec, "------------------------------------\n")
 
+	// Com -only-issues, o relatório traz apenas os deployments e as seções com problemas a resolver
+	full := !*onlyIssues
+	unsatisfiableDeployments := unsatisfiableSet(unsatisfiable)
 	for _, dm := range deploymentMetrics {
-		fmt.Fprintf(rec, "\nDeployment: %s (Namespace: %s)\n", dm.Name, dm.Namespace)
-		fmt.Fprintf(rec, "Total de Pods: %d\n", dm.TotalPods)
-		fmt.Fprintf(rec, "Pods sem Limites: %d\n", dm.PodsWithoutLimits)
-
-		if dm.MaxCPU > 0 || dm.MaxMemory > 0 {
-			fmt.Fprintf(rec, "\nMétricas (período de %v):\n", collectionPeriod)
-			fmt.Fprintf(rec, "  Máximo:\n")
-			fmt.Fprintf(rec, "    CPU: %dm\n", dm.MaxCPU)
-			fmt.Fprintf(rec, "    Memory: %dMi\n", dm.MaxMemory/1024/1024)
-			fmt.Fprintf(rec, "  Média:\n")
-			fmt.Fprintf(rec, "    CPU: %dm\n", dm.AvgCPU)
-			fmt.Fprintf(rec, "    Memory: %dMi\n", dm.AvgMemory/1024/1024)
-		}
-
-		if dm.PodsWithoutLimits > 0 {
-			fmt.Fprintf(rec, "\nProblemas Identificados:\n")
-			fmt.Fprintf(rec, "1. %d pods sem limites de recursos definidos\n", dm.PodsWithoutLimits)
-			fmt.Fprintf(rec, "   Recomendação: Definir limites de recursos (CPU e Memory) para evitar consumo excessivo\n")
-			fmt.Fprintf(rec, "   Impacto: Alto - Pode causar problemas de performance no cluster\n")
-			fmt.Fprintf(rec, "   Prioridade: Alta\n")
-		}
-
-		// Adicionar recomendações baseadas nas métricas
-		if dm.MaxCPU > 0 || dm.MaxMemory > 0 {
-			fmt.Fprintf(rec, "\nRecomendações de Recursos:\n")
-			fmt.Fprintf(rec, "1. Limites sugeridos baseados no uso máximo observado:\n")
-			fmt.Fprintf(rec, "   CPU: %dm (máximo observado)\n", dm.MaxCPU)
-			fmt.Fprintf(rec, "   Memory: %dMi (máximo observado)\n", dm.MaxMemory/1024/1024)
-			fmt.Fprintf(rec, "2. Requests sugeridos baseados na média de uso:\n")
-			fmt.Fprintf(rec, "   CPU: %dm (média observada)\n", dm.AvgCPU)
-			fmt.Fprintf(rec, "   Memory: %dMi (média observada)\n", dm.AvgMemory/1024/1024)
-		}
-
-		fmt.Fprintf(rec, "\nPods Monitorados:\n")
-		for _, podName := range dm.Pods {
-			fmt.Fprintf(rec, "- %s\n", podName)
+		if full || deploymentHasIssues(dm, unsatisfiableDeployments) {
+			writeDeploymentRecommendation(rec, dm, patchIndex[dm.Namespace+"/"+dm.Name], collectionPeriod, *onlyIssues)
+		}
+	}
+
+	if full {
+		writeWorkloadSources(rec, deploymentMetrics)
+		writeRollouts(rec, rollouts)
+	}
+	if full || len(unsatisfiable) > 0 {
+		writeUnsatisfiableRecommendations(rec, unsatisfiable)
+	}
+
+	// Comparar os reinícios do início e do fim da coleta
+	deploymentIndex := deploymentsByPod(deploymentMetrics)
+	restartDeltas := computeRestartDeltas(restartsBefore, pods.Items, deploymentIndex)
+	if full || len(restartDeltas) > 0 {
+		writeRestartDeltas(rec, restartDeltas, restartsBefore != nil)
+	}
+
+	// Agrupar os últimos encerramentos dos containers reiniciados por código de saída
+	exitCodes := exitCodeBreakdown(pods.Items, deploymentIndex)
+	if full || len(exitCodes) > 0 {
+		writeExitCodeBreakdown(rec, exitCodes)
+	}
+
+	// Containers reiniciados com limite de memória abaixo do mínimo do runtime (JVM, Node.js, ...)
+	runtimeMemoryFloors := findRuntimeMemoryFloors(pods.Items, metrics, deploymentIndex, analyzerConfig.Containers)
+	if full || len(runtimeMemoryFloors) > 0 {
+		writeRuntimeMemoryFloors(rec, runtimeMemoryFloors)
+	}
+
+	// Identificar init containers cujos requests dominam o request efetivo dos pods
+	initContainers, errs := analyzeInitContainers(clientset, pods.Items, metrics, *prometheusURL, collectionPeriod, deploymentIndex)
+	for _, err := range errs {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	if full || len(initContainerFindings(initContainers)) > 0 {
+		writeInitContainers(rec, initContainers)
+	}
+
+	// Agregar falhas e duração dos pulls de imagem por registry e por workload
+	imagePulls, err := analyzeImagePulls(clientset, pods.Items, deploymentIndex)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	} else {
+		if full || len(imagePulls.problems()) > 0 {
+			writeImagePulls(rec, imagePulls)
+		}
+	}
+
+	// Analisar pods despejados por pressão de recursos nos nodes
+	evictedPods := findEvictedPods(clientset, pods.Items, deploymentIndex, collectionStart)
+	if full || len(evictedPods) > 0 {
+		writeEvictions(rec, evictedPods)
+	}
+
+	// Relacionar as condições dos nodes durante a coleta com os pods despejados ou removidos deles
+	nodeConditionReports := nodeConditions.nodeConditionReports(pods.Items, evictedPods)
+	if full || len(nodeConditionReports) > 0 {
+		writeNodeConditions(rec, nodeConditionReports, nodeConditions.readings, location)
+	}
+
+	// Criação, reinícios e cordons dos nodes, explicando as lacunas nas leituras de uso
+	nodeHistories := nodeConditions.nodeHistories(pods.Items, metrics)
+	if full || len(nodeChurnFindings(nodeHistories)) > 0 {
+		writeNodeHistory(rec, nodeHistories, location, time.Now())
+	}
+
+	// Versões do kubelet, runtime e kernel dos nodes
+	nodeInventory := buildNodeInventory(nodes.Items)
+	if full || len(nodeInventory.Issues) > 0 {
+		writeNodeInventory(rec, nodeInventory)
+	}
+
+	// Correlacionar preempções com as PriorityClasses dos workloads
+	preemptions, err := analyzePreemptions(clientset, deployments)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	} else {
+		if full || len(preemptions.Preempted) > 0 {
+			writePreemptions(rec, preemptions)
+		}
+	}
+
+	// Auditar o uso de PriorityClass por namespace
+	priorityCoverage, err := analyzePriorityCoverage(clientset, pods.Items, deploymentIndex)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	} else {
+		if full || len(priorityCoverage.Critical) > 0 {
+			writePriorityCoverage(rec, priorityCoverage)
+		}
+	}
+
+	if len(namespaceThresholds) > 0 {
+		writeNamespaceThresholds(rec, namespaceThresholds)
+	}
+	if full || tieredDeployments > 0 {
+		writeWorkloadTiers(rec, deploymentMetrics, *tierLabel)
+	}
+
+	if full || len(policyWastes) > 0 {
+		writeRequestLimitPolicies(rec, requestLimitPolicies, policyWastes)
+	}
+
+	quotaValidation, quotaErrs := validatePatchesAgainstQuotas(clientset, patches, pods.Items, nodes.Items, deploymentIndex)
+	for _, err := range quotaErrs {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	if full || len(quotaValidation.Violations) > 0 || quotaValidation.ClusterOvercommit || len(quotaValidation.Unchecked) > 0 {
+		writeQuotaValidation(rec, quotaValidation)
+	}
+	writeProposedChanges(rec, patches)
+	writeFluxPatches(rec, patches, deploymentMetrics)
+
+	// Agregar as recomendações por release do Helm
+	helmReleases := groupByHelmRelease(deploymentMetrics, deployments, patches)
+	if full {
+		writeHelmReleases(rec, helmReleases)
+	}
+	patchFile, ssaFile, vpaFile := "", "", ""
+	if len(patches) > 0 {
+		patchFile = filepath.Join(reportDir, fmt.Sprintf("patches-%s-%s.sh", sanitizedContext, timestamp))
+		if err := writePatchScript(patchFile, patches); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+			patchFile = ""
+		}
+		ssaFile = filepath.Join(reportDir, fmt.Sprintf("ssa-%s-%s.yaml", sanitizedContext, timestamp))
+		if err := writeSSAManifests(ssaFile, patches); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+			ssaFile = ""
+		}
+		if *vpaOutput {
+			vpaFile = filepath.Join(reportDir, fmt.Sprintf("vpa-%s-%s.yaml", sanitizedContext, timestamp))
+			if err := writeVPARecommendations(vpaFile, patches); err != nil {
+				fmt.Printf("⚠️  Aviso: %v\n", err)
+				vpaFile = ""
+			}
+		}
+	}
+
+	// Adicionar métricas detalhadas do kubelet
+	if *deepMetrics {
+		writeDeepMetrics(rec, aggregateDeepMetrics(deploymentMetrics, metrics))
+	}
+
+	// Carregar o histórico de execuções anteriores deste contexto
+	historyFile := historyFilePath(reportDir, sanitizedContext)
+	history, err := loadHistory(historyFile)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+
+	// Projetar o crescimento de uso por deployment
+	growthProjections := projectDeploymentGrowth(history, deploymentMetrics, time.Now().In(location))
+	if full || countGrowthRisks(growthProjections) > 0 {
+		writeGrowthProjections(rec, growthProjections)
+	}
+
+	// Separar os picos de dias úteis e fim de semana para não cortar picos semanais
+	weeklyProfiles := buildWeeklyProfiles(history, deploymentMetrics, time.Now().In(location))
+	if full || countClippedWeeklyPeaks(weeklyProfiles) > 0 {
+		writeWeeklyProfiles(rec, weeklyProfiles)
+	}
+
+	// Mapa de calor do uso por dia da semana e hora, quando há leituras de mais de um dia
+	hourlyUsages := heatmapUsages(metrics, deploymentMetrics)
+	heatmapFile := ""
+	if len(hourlyUsages) > 0 {
+		heatmapFile = filepath.Join(reportDir, fmt.Sprintf("heatmap-%s-%s.html", sanitizedContext, timestamp))
+		if err := writeHeatmapHTML(heatmapFile, *k8sContext, hourlyUsages, location, anonymizer); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+			heatmapFile = ""
+		}
+	}
+	if full || len(hourlyUsages) > 0 {
+		writeHourlyPatterns(rec, hourlyUsages, heatmapFile)
+	}
+
+	// Sugerir escalonamento por horário para deployments com padrão diário forte
+	hpaTargets, err := listHPATargets(clientset)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	scalingSchedules := suggestScalingSchedules(hourlyUsages, deploymentMetrics, hpaTargets)
+	scheduleFile := ""
+	if len(scalingSchedules) > 0 {
+		scheduleFile = filepath.Join(reportDir, fmt.Sprintf("schedules-%s-%s.yaml", sanitizedContext, timestamp))
+		if err := writeScalingSchedules(scheduleFile, scalingSchedules, location); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+			scheduleFile = ""
+		}
+	}
+	if full || len(scalingSchedules) > 0 {
+		writeScheduleSection(rec, scalingSchedules, scheduleFile)
+	}
+
+	// Requests de CPU pequenos demais distorcem o scheduler e a utilização vista pelos HPAs
+	tinyCPURequests := findTinyCPURequests(deploymentMetrics, metrics, deployments, hpaTargets, analyzerConfig.Containers)
+	if full || len(tinyCPURequests) > 0 {
+		writeTinyCPURequests(rec, tinyCPURequests)
+	}
+
+	// Deployments de produção sem redundância
+	var singleReplicas []*SingleReplicaDeployment
+	if len(analyzerConfig.ProductionNamespaces) > 0 {
+		singleReplicas = findSingleReplicaDeployments(analyzerConfig.ProductionNamespaces, deploymentMetrics, metrics, deployments, hpaTargets, analyzerConfig.Containers)
+		if full || len(singleReplicas) > 0 {
+			writeSingleReplicaDeployments(rec, singleReplicas, analyzerConfig.ProductionNamespaces)
+		}
+	}
+
+	// Skew dos kubelets e APIs obsoletas, escritos ao final do relatório como adendo de atualização
+	upgradeReadiness, err := analyzeUpgradeReadiness(clientset, nodeInventory, deployments, hpaTargets)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+
+	// Detectar nodes muito mais carregados que os demais
+	nodeImbalance := detectNodeImbalance(nodes.Items, pods.Items, metrics, deploymentIndex)
+	if full || len(nodeImbalance.HotNodes) > 0 {
+		writeNodeImbalance(rec, nodeImbalance)
+	}
+
+	// Analisar capacidade e distribuição por zona
+	zoneReport := analyzeZones(nodes.Items, pods.Items, metrics, deploymentMetrics)
+	if full || len(zoneReport.Concentrated) > 0 {
+		writeZoneReport(rec, zoneReport)
+	}
+
+	// Propor restrições de topologia prontas para os deployments com réplicas concentradas
+	topologySuggestions, errs := suggestTopology(zoneReport, nodeImbalance, pods.Items, deploymentMetrics, deployments)
+	for _, err := range errs {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	if full || len(topologySuggestions) > 0 {
+		writeTopologySuggestions(rec, topologySuggestions)
+	}
+
+	// Analisar a densidade de pods por node
+	podDensity := analyzePodDensity(nodes.Items, pods.Items)
+	if full || len(nodesNearPodExhaustion(podDensity)) > 0 {
+		writePodDensity(rec, podDensity)
+	}
+
+	// Agregar a utilização por grupo de nodes (pool, instance type, zona), a unidade que é redimensionada
+	groupLabel := resolveNodeGroupLabel(nodes.Items, *nodeGroupLabel)
+	nodeGroups := analyzeNodeGroups(nodes.Items, pods.Items, metrics, groupLabel, nodeImbalance, podDensity)
+	if full || countNodeGroupsToResize(nodeGroups) > 0 {
+		writeNodeGroups(rec, groupLabel, nodeGroups)
+	}
+
+	// Comparar as alocações de hugepages e recursos estendidos com o allocatable dos nodes
+	extendedResources := analyzeExtendedResources(nodes.Items, pods.Items, deploymentIndex)
+	if full || len(extendedResourceFindings(extendedResources)) > 0 {
+		writeExtendedResources(rec, extendedResources)
+	}
+
+	// Identificar capacidade ociosa por fragmentação de CPU e memória
+	fragmentation := analyzeFragmentation(nodes.Items, pods.Items, deploymentIndex)
+	if full {
+		writeFragmentation(rec, fragmentation)
+	}
+
+	// Ratear o custo dos nodes entre namespaces e times
+	costModel := CostModel{
+		CPUHourly:    *cpuCost,
+		MemoryHourly: *memoryCost,
+		Basis:        *costBasis,
+		Label:        *costLabel,
+	}
+	costReport := allocateCosts(costModel, nodes.Items, pods.Items, metrics, deploymentIndex)
+	if full {
+		writeCostAllocation(rec, costReport)
+	}
+
+	// Comparar o custo projetado com os orçamentos configurados
+	var budgetAlerts []BudgetAlert
+	if len(analyzerConfig.Budgets) > 0 {
+		budgetAlerts = checkBudgets(analyzerConfig.Budgets, costReport)
+		if full || len(budgetAlerts) > 0 {
+			writeBudgetAlerts(rec, analyzerConfig.Budgets, budgetAlerts)
+		}
+	}
+	costFile := filepath.Join(reportDir, fmt.Sprintf("cost-allocation-%s-%s.csv", sanitizedContext, timestamp))
+	if err := writeCostCSV(costFile, costReport, anonymizer); err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+		costFile = ""
+	}
+
+	// Dividir as recomendações em um relatório por namespace ou time
+	splitIndex := ""
+	splitDir := ""
+	if *splitBy != "" {
+		groups := groupFindings(*splitBy, deploymentMetrics, deployments, unsatisfiable, evictedPods, patches, costReport)
+		splitDir = filepath.Join(reportDir, fmt.Sprintf("split-%s-%s", sanitizedContext, timestamp))
+		splitIndex, err = writeSplitReports(splitDir, groups, *k8sContext, collectionPeriod, *onlyIssues, anonymizer)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+	}
+
+	// Detectar conflitos entre HPA e VPA
+	fmt.Println("   - Verificando conflitos HPA x VPA...")
+	hpaVpaConflicts, err := detectHPAVPAConflicts(clientset, dynamicClient)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	if full || len(hpaVpaConflicts) > 0 {
+		writeHPAVPAConflicts(rec, hpaVpaConflicts)
+	}
+
+	if full || countSoftEvictionNodes(evictionThresholds) > 0 {
+		writeEvictionThresholds(rec, evictionThresholds)
+	}
+
+	// Comparar os processos dos pods e nodes com os limites de PIDs; sem o kubelet na coleta, ler o summary uma vez
+	if !hasProcessMetrics(metrics) {
+		if errs := snapshotPIDs(clientset, nodes.Items, metrics); len(errs) > 0 {
+			fmt.Printf("⚠️  Aviso: contagem de processos indisponível em %d nodes: %v\n", len(errs), errs[0])
+		}
+	}
+	pidPressure := analyzePIDPressure(pods.Items, metrics, evictionThresholds, deploymentIndex)
+	if *prometheusURL != "" {
+		pidPressure.FileDescriptors, err = queryFileDescriptorUsage(*prometheusURL, collectionPeriod, pods.Items, deploymentIndex)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		} else {
+			pidPressure.DescriptorsMeasured = true
+		}
+	}
+	if full || pidPressure.issues() > 0 {
+		writePIDPressure(rec, pidPressure)
+	}
+
+	// Simular o agendamento com os requests recomendados
+	var simulation *SimulationResult
+	if command == "simulate" {
+		fmt.Println("   - Simulando agendamento com os requests recomendados...")
+		simulation = simulateScheduling(pods.Items, capacityNodes, deploymentIndex, patchIndex)
+		writeSimulationReport(rec, simulation)
+	}
+
+	// Planejar a consolidação de nodes com base na simulação
+	consolidationSimulation := simulation
+	if consolidationSimulation == nil {
+		consolidationSimulation = simulateScheduling(pods.Items, capacityNodes, deploymentIndex, patchIndex)
+	}
+	consolidation := planConsolidation(pods.Items, capacityNodes, consolidationSimulation)
+	if full {
+		writeConsolidationPlan(rec, consolidation)
+	}
+
+	// Apontar deployments ociosos durante toda a janela que poderiam ser suspensos ou escalar a zero
+	var proxyTraffic map[string]ProxyTraffic
+	if *prometheusURL != "" {
+		proxyTraffic, err = queryProxyTraffic(*prometheusURL, collectionPeriod)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+	}
+	scaleToZero := findScaleToZeroCandidates(deploymentMetrics, pods.Items, metrics, proxyTraffic)
+	if full || len(scaleToZero) > 0 {
+		writeScaleToZeroCandidates(rec, scaleToZero, proxyTraffic != nil)
+	}
+
+	// Dimensionar os volumes das StatefulSets pelo uso dos PVCs
+	statefulSetStorage, storageWarnings, err := analyzeStatefulSetStorage(clientset, pods.Items)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	for _, warning := range storageWarnings {
+		fmt.Printf("⚠️  Aviso: %v\n", warning)
+	}
+	if full || len(statefulSetStorage) > 0 {
+		writeStatefulSetStorage(rec, statefulSetStorage)
+	}
+
+	// Encontrar namespaces abandonados, esquecidos ou expirados
+	now := time.Now().In(location)
+	namespaceHygiene, err := analyzeNamespaceHygiene(clientset, pods.Items, metrics, now)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	if full || len(namespaceHygiene) > 0 {
+		writeNamespaceHygiene(rec, namespaceHygiene, now)
+	}
+
+	// Estimar a pegada de carbono e a redução possível com as recomendações
+	carbonReport := estimateEmissions(analyzerConfig.Carbon, nodes.Items, pods.Items, metrics, deploymentIndex, patchIndex, simulation)
+	if full {
+		writeCarbonReport(rec, carbonReport)
+	}
+
+	// Detectar riscos iminentes e alertar o plantão
+	risks := detectRisks(nodes.Items, pods.Items, metrics, deploymentIndex, analyzerConfig.Alerting.overcommitPct(), namespaceThresholds, analyzerConfig.Containers)
+	risks = suppressions.filter(risks)
+	if full || len(risks) > 0 {
+		writeRisks(rec, risks)
+	}
+	alertsSent := 0
+	if analyzerConfig.Alerting.Provider != "" && len(risks) > 0 {
+		fmt.Printf("   - Enviando alertas para %s...\n", analyzerConfig.Alerting.Provider)
+		alertsSent, err = sendAlerts(analyzerConfig.Alerting, *k8sContext, risks)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+	}
+
+	// HPAs por utilização cujos alvos não têm requests não conseguem escalar
+	hpaMissingRequests, err := findHPAMissingRequests(clientset, deployments, patches)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	if full || len(hpaMissingRequests) > 0 {
+		writeHPAMissingRequests(rec, hpaMissingRequests)
+	}
+
+	// Apontar os HPAs instáveis na janela
+	hpaFlapping := hpaFlappingFindings(hpaActivities)
+	if full || len(hpaFlapping) > 0 {
+		writeHPAScaling(rec, hpaActivities, hpaSince)
+	}
+
+	// Dimensionar os addons críticos do cluster (CoreDNS, kube-proxy, CNI, metrics-server)
+	addons, err := analyzeAddons(clientset, nodes.Items, pods.Items, metrics, deploymentIndex)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+	if full || len(addonFindings(addons)) > 0 {
+		writeAddons(rec, addons)
+	}
+
+	// Identificar DaemonSets cujo uso acompanha o tamanho ou a ocupação do node
+	daemonSetScaling := analyzeDaemonSetScaling(nodes.Items, pods.Items, metrics, groupLabel)
+	if full || len(daemonSetScaling) > 0 {
+		writeDaemonSetScaling(rec, daemonSetScaling)
+	}
+
+	// Saturação do API server e tamanho do etcd, quando o Prometheus coleta o control plane
+	var controlPlane *ControlPlaneReport
+	if *prometheusURL != "" {
+		controlPlane, err = analyzeControlPlane(*prometheusURL)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		} else {
+			if full || len(controlPlane.Issues) > 0 {
+				writeControlPlane(rec, controlPlane)
+			}
 		}
-		fmt.Fprintf(rec, "\n%s\n", strings.Repeat("-", 80))
 	}
 
+	phase.end()
+	phase = tracer.phase("integrações")
+
+	// Consolidar os problemas encontrados e abrir tickets para os mais graves
+	findings := collectFindings(deploymentMetrics, unsatisfiable, evictedPods, preemptions, priorityCoverage, budgetAlerts,
+		risks, hpaMissingRequestFindings(hpaMissingRequests), hpaFlapping,
+		kedaMissingRequestFindings(kedaScalers), addonFindings(addons), scaleToZeroFindings(scaleToZero),
+		storageFindings(statefulSetStorage), weeklyPeakFindings(weeklyProfiles), nodeConditionFindings(nodeConditionReports),
+		nodeChurnFindings(nodeHistories), controlPlaneFindings(controlPlane), pidFindings(pidPressure),
+		imagePullFindings(imagePulls), initContainerFindings(initContainers),
+		extendedResourceFindings(extendedResources), namespaceHygieneFindings(namespaceHygiene, now),
+		tinyCPURequestFindings(tinyCPURequests), runtimeMemoryFloorFindings(runtimeMemoryFloors), nodeInventoryFindings(nodeInventory), upgradeReadinessFindings(upgradeReadiness), singleReplicaFindings(singleReplicas))
+	weighFindings(findings, deploymentMetrics)
+	findings = suppressions.filter(findings)
+
+	// Avaliar as políticas da configuração; as violações entram nos problemas e podem falhar a execução
+	var policyViolations []PolicyViolation
+	if len(analyzerConfig.Policies) > 0 {
+		var errs []error
+		policySummary := summarizeRun(*k8sContext, findings, len(risks), len(deploymentMetrics), consolidationSimulation, collectionStats.Start)
+		policyViolations, errs = evaluatePolicies(analyzerConfig.Policies, policySummary, deploymentMetrics, deployments, patches, findings)
+		for _, err := range errs {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+		policyViolations = unsuppressedViolations(policyViolations, suppressions)
+		findings = append(findings, policyFindings(policyViolations)...)
+		writePolicies(rec, analyzerConfig.Policies, policyViolations)
+		if len(failedPolicies(policyViolations)) > 0 {
+			exitCode = policyFailureExitCode
+		}
+	}
+	if suppressions != nil {
+		writeSuppressions(rec, suppressions, time.Now().In(location))
+	}
+	var jiraTickets *TicketResult
+	if analyzerConfig.Jira.URL != "" {
+		fmt.Println("   - Abrindo tickets no Jira...")
+		assignOwners(findings, deployments, analyzerConfig.Jira.ownerLabels())
+		jiraTickets, err = createJiraIssues(analyzerConfig.Jira, *k8sContext, findings)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+	}
+	var repositoryIssues *TicketResult
+	if analyzerConfig.Issues.Provider != "" {
+		fmt.Printf("   - Abrindo issues em %s...\n", analyzerConfig.Issues.Repository)
+		repositoryIssues, err = createRepositoryIssues(analyzerConfig.Issues, *k8sContext, findings)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+	}
+
+	phase.end()
+	phase = tracer.phase("saídas")
+	defer phase.end()
+
+	if upgradeReadiness != nil && (full || len(upgradeReadinessFindings(upgradeReadiness)) > 0) {
+		writeUpgradeReadiness(rec, upgradeReadiness)
+	}
+	if full {
+		writeCollectionStats(rec, collectionStats)
+	}
+	runSummary := summarizeRun(*k8sContext, findings, len(risks), len(deploymentMetrics), consolidationSimulation, collectionStats.Start)
+
 	// Adicionar seção de resumo no arquivo de recomendações
 	fmt.Fprintf(rec, "\n=== Resumo das Recomendações ===\n")
 	fmt.Fprintf(rec, "Total de deployments analisados: %d\n", len(deploymentMetrics))
 	fmt.Fprintf(rec, "Total de nodes monitorados: %d\n", len(nodes.Items))
+	fmt.Fprintf(rec, "Problemas de severidade alta ou crítica: %d\n", len(filterFindings(findings, SeverityHigh)))
+	if suppressions != nil {
+		fmt.Fprintf(rec, "Problemas suprimidos: %d\n", len(suppressions.Suppressed))
+	}
+	fmt.Fprintf(rec, "Pontuação de saúde: %d/100\n", runSummary.HealthScore)
+	fmt.Fprintf(rec, "Requests liberados com as recomendações: %s, %s\n",
+		formatCPU(int64(runSummary.WasteCPUCores*1000)), formatMemory(int64(runSummary.WasteMemoryGiB*gibibyte)))
+	fmt.Fprintf(rec, "Riscos iminentes: %d\n", len(risks))
+	fmt.Fprintf(rec, "Containers sem requests em alvos de HPA: %d\n", len(hpaMissingRequests))
+	fmt.Fprintf(rec, "HPAs instáveis (flapping): %d\n", len(hpaFlapping))
+	fmt.Fprintf(rec, "ScaledObjects do KEDA: %d\n", len(kedaScalers))
+	fmt.Fprintf(rec, "Addons do cluster analisados: %d\n", len(addons))
+	if len(analyzerConfig.Policies) > 0 {
+		fmt.Fprintf(rec, "Violações de políticas: %d\n", len(policyViolations))
+	}
+	fmt.Fprintf(rec, "Grupos de nodes a redimensionar: %d\n", countNodeGroupsToResize(nodeGroups))
+	fmt.Fprintf(rec, "Recursos estendidos com pods pendentes: %d\n", len(extendedResourceFindings(extendedResources)))
+	fmt.Fprintf(rec, "DaemonSets com uso proporcional ao node: %d\n", len(daemonSetScaling))
+	fmt.Fprintf(rec, "Recomendações que nenhum node comporta: %d\n", len(unsatisfiable))
+	fmt.Fprintf(rec, "Patches de recursos gerados: %d (quotas excedidas: %d)\n", len(patches), len(quotaValidation.Violations))
+	fmt.Fprintf(rec, "Recomendações de baixa confiança: %d\n", countLowConfidence(deploymentMetrics))
+	fmt.Fprintf(rec, "Deployments recentes com confiança reduzida: %d\n", newWorkloads)
+	fmt.Fprintf(rec, "Deployments sem recomendação por dados insuficientes: %d\n", insufficientData)
+	fmt.Fprintf(rec, "Releases do Helm analisados: %d\n", len(helmReleases))
+	fmt.Fprintf(rec, "Nodes com carga desbalanceada: %d\n", len(nodeImbalance.HotNodes))
+	fmt.Fprintf(rec, "Zonas: %d (deployments concentrados em uma zona: %d)\n", len(zoneReport.Zones), len(zoneReport.Concentrated))
+	fmt.Fprintf(rec, "Nodes próximos do limite de pods: %d\n", len(nodesNearPodExhaustion(podDensity)))
+	fmt.Fprintf(rec, "Custo mensal estimado: %.2f (ocioso: %.2f)\n", costReport.TotalCost, costReport.IdleCost)
+	if len(analyzerConfig.Budgets) > 0 {
+		fmt.Fprintf(rec, "Namespaces acima do orçamento: %d/%d\n", len(budgetAlerts), len(analyzerConfig.Budgets))
+	}
+	fmt.Fprintf(rec, "Nodes a drenar no plano de consolidação: %d\n", len(consolidation.Steps))
+	fmt.Fprintf(rec, "Candidatos a scale-to-zero: %d\n", len(scaleToZero))
+	fmt.Fprintf(rec, "Volumes de StatefulSets a redimensionar: %d\n", len(statefulSetStorage))
+	fmt.Fprintf(rec, "Namespaces abandonados, esquecidos ou expirados: %d\n", len(namespaceHygiene))
+	fmt.Fprintf(rec, "Emissões mensais estimadas: %.1f kgCO2e\n", carbonReport.TotalKgCO2e)
+	fmt.Fprintf(rec, "Capacidade ociosa por fragmentação: CPU %s, Memory %s em %d nodes\n",
+		formatCPU(fragmentation.StrandedCPU), formatMemory(fragmentation.StrandedMemory), len(fragmentation.Nodes))
+	fmt.Fprintf(rec, "Conflitos HPA x VPA: %d\n", len(hpaVpaConflicts))
+	fmt.Fprintf(rec, "Namespaces com requests elevados por políticas de requests iguais aos limits: %d\n", len(policyWastes))
+	fmt.Fprintf(rec, "Deployments com rollout durante a coleta: %d\n", len(rollouts))
+	fmt.Fprintf(rec, "Containers reiniciados durante a coleta: %d\n", len(restartDeltas))
+	fmt.Fprintf(rec, "Containers reiniciados com limite de memória abaixo do mínimo do runtime: %d\n", len(runtimeMemoryFloors))
+	fmt.Fprintf(rec, "Workloads com containers reiniciados, por código de saída: %d\n", len(exitCodes))
+	if imagePulls != nil {
+		fmt.Fprintf(rec, "Workloads com falhas ou lentidão no pull de imagens: %d\n", len(imagePulls.problems()))
+	}
+	fmt.Fprintf(rec, "Init containers dominando o request efetivo dos pods: %d\n", len(initContainerFindings(initContainers)))
+	fmt.Fprintf(rec, "Pods despejados (evicted): %d\n", len(evictedPods))
+	fmt.Fprintf(rec, "Nodes com despejo suave antes do allocatable: %d\n", countSoftEvictionNodes(evictionThresholds))
+	fmt.Fprintf(rec, "Nodes com condições instáveis durante a coleta: %d\n", len(nodeConditionFindings(nodeConditionReports)))
+	fmt.Fprintf(rec, "Nodes criados, removidos, reiniciados ou em cordon durante a coleta: %d\n", len(nodeChurnFindings(nodeHistories)))
+	fmt.Fprintf(rec, "Problemas de versão nos nodes (kubelet, runtime ou kernel): %d\n", len(nodeInventory.Issues))
+	fmt.Fprintf(rec, "Bloqueios para a atualização do cluster (skew dos kubelets e APIs obsoletas): %d\n", len(upgradeReadinessFindings(upgradeReadiness)))
+	if len(analyzerConfig.ProductionNamespaces) > 0 {
+		fmt.Fprintf(rec, "Deployments de produção com uma única réplica: %d\n", len(singleReplicas))
+	}
+	fmt.Fprintf(rec, "Pods e nodes próximos do limite de PIDs ou descritores de arquivo: %d\n", pidPressure.issues())
+	if preemptions != nil {
+		fmt.Fprintf(rec, "Workloads preemptados: %d\n", len(preemptions.Preempted))
+		fmt.Fprintf(rec, "Deployments sem priorityClassName: %d\n", len(preemptions.WithoutPriority))
+	}
+	if priorityCoverage != nil {
+		fmt.Fprintf(rec, "Workloads de plataforma com prioridade padrão: %d\n", len(priorityCoverage.Critical))
+	}
+	fmt.Fprintf(rec, "Deployments com crescimento acima dos limites recomendados: %d\n", countGrowthRisks(growthProjections))
+	fmt.Fprintf(rec, "Deployments com pico semanal acima dos limites recomendados: %d\n", countClippedWeeklyPeaks(weeklyProfiles))
+	fmt.Fprintf(rec, "Deployments no mapa de calor por hora: %d\n", len(hourlyUsages))
+	fmt.Fprintf(rec, "Deployments com escalonamento por horário sugerido: %d\n", len(scalingSchedules))
+	fmt.Fprintf(rec, "Containers com request de CPU abaixo de %s: %d\n", formatCPU(minUsableCPURequest), len(tinyCPURequests))
+
+	// Prever quando a folga de capacidade ficará abaixo do limite
+	forecasts := forecastCapacity(history, metrics.ClusterSamples, capacityNodes, *headroom, time.Now().In(location))
+	writeCapacityForecast(rec, forecasts, *headroom, len(history))
+
+	// Registrar esta execução no histórico
+	if len(metrics.ClusterSamples) > 0 {
+		allocatableCPU, allocatableMemory := clusterAllocatable(nodes.Items)
+		avgCPU, avgMemory, maxCPU, maxMemory := averageSamples(metrics.ClusterSamples)
+		record := HistoryRecord{
+			Timestamp:         time.Now().In(location),
+			Context:           *k8sContext,
+			Period:            collectionPeriod.String(),
+			AllocatableCPU:    allocatableCPU,
+			AllocatableMemory: allocatableMemory,
+			AvgCPU:            avgCPU,
+			AvgMemory:         avgMemory,
+			MaxCPU:            maxCPU,
+			MaxMemory:         maxMemory,
+			Deployments:       make(map[string]DeploymentUsage),
+			Report:            filepath.Base(recommendationsFile),
+			HealthScore:       &runSummary.HealthScore,
+			Findings:          make(map[string]int),
+			WasteCPUCores:     runSummary.WasteCPUCores,
+			WasteMemoryGiB:    runSummary.WasteMemoryGiB,
+		}
+		for severity, count := range runSummary.Findings {
+			record.Findings[severity.String()] = count
+		}
+		for key, dm := range deploymentMetrics {
+			if dm.MaxCPU == 0 && dm.MaxMemory == 0 {
+				continue
+			}
+			usage := DeploymentUsage{
+				AvgCPU:     dm.AvgCPU,
+				AvgMemory:  dm.AvgMemory,
+				MaxCPU:     dm.MaxCPU,
+				MaxMemory:  dm.MaxMemory,
+				Containers: deploymentContainerUsage(dm, metrics),
+			}
+			if patch := patchIndex[key]; patch != nil {
+				usage.Recommendations = patch.Containers
+			}
+			record.Deployments[key] = usage
+		}
+		if err := appendHistory(historyFile, record); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+	}
+
+	// Atualizar o índice navegável das execuções
+	indexFile, err := writeReportIndex(reportDir)
+	if err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+	}
+
+	// Resumo executivo por namespace para gestores
+	executiveFile := ""
+	if *executivePDF {
+		executiveFile = filepath.Join(reportDir, fmt.Sprintf("executive-%s-%s.pdf", sanitizedContext, timestamp))
+		summaries := summarizeNamespaces(deploymentMetrics, deployments, findings, patches, costReport)
+		if err := writeExecutivePDF(executiveFile, *k8sContext, summaries, time.Now().In(location), anonymizer); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+			executiveFile = ""
+		}
+	}
 
+	// Relatório JSON para integrações, com ids estáveis dos problemas
+	jsonFile := filepath.Join(reportDir, fmt.Sprintf("report-%s-%s.json", sanitizedContext, timestamp))
+	if err := writeJSONReport(jsonFile, newReport(runSummary, findings, patches), anonymizer); err != nil {
+		fmt.Printf("⚠️  Aviso: %v\n", err)
+		jsonFile = ""
+	}
+
+	// Publicar o resumo da execução no Pushgateway
+	if *pushgatewayURL != "" {
+		if err := pushSummary(*pushgatewayURL, runSummary); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+	}
+
+	// Reunir as saídas desta execução em um único arquivo
+	bundleFile := ""
+	if *bundle {
+		if anonymizingRec != nil {
+			anonymizingRec.Flush()
+		}
+		files := []string{recommendationsFile}
+		// O script de patches e os manifestos usam os nomes reais e ficam fora do pacote anônimo
+		if patchFile != "" && anonymizer == nil {
+			files = append(files, patchFile)
+		}
+		if ssaFile != "" && anonymizer == nil {
+			files = append(files, ssaFile)
+		}
+		if vpaFile != "" && anonymizer == nil {
+			files = append(files, vpaFile)
+		}
+		if jsonFile != "" {
+			files = append(files, jsonFile)
+		}
+		// As amostras brutas também usam os nomes reais
+		if rawSamplesFile != "" && anonymizer == nil {
+			files = append(files, rawSamplesFile)
+		}
+		if costFile != "" {
+			files = append(files, costFile)
+		}
+		if executiveFile != "" {
+			files = append(files, executiveFile)
+		}
+		if heatmapFile != "" {
+			files = append(files, heatmapFile)
+		}
+		// Os manifestos de escalonamento também usam os nomes reais
+		if scheduleFile != "" && anonymizer == nil {
+			files = append(files, scheduleFile)
+		}
+		if splitIndex != "" {
+			files = append(files, splitDir)
+		}
+		bundleFile = filepath.Join(reportDir, fmt.Sprintf("bundle-%s-%s.zip", sanitizedContext, timestamp))
+		if err := writeBundle(bundleFile, files, metrics, anonymizer); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+			bundleFile = ""
+		}
+	}
+
+	// Avisar os destinos configurados que o relatório está pronto
+	webhooksSent := 0
+	if len(analyzerConfig.Webhooks) > 0 {
+		var errs []error
+		webhooksSent, errs = sendWebhooks(analyzerConfig.Webhooks, newWebhookEvent(runSummary, findings, recommendationsFile, bundleFile))
+		for _, err := range errs {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		}
+	}
+
+	fmt.Printf("\n📈 Chamadas ao API server: %d (429: %d, esperas no rate limiter: %d), pico de memória: %s\n",
+		collectionStats.totalCalls(), collectionStats.ServerThrottled, collectionStats.ClientThrottled, formatMemory(int64(collectionStats.PeakSys)))
 	fmt.Printf("\n✅ Relatório de recomendações gerado com sucesso:\n")
 	fmt.Printf("   - Recomendações: %s\n", recommendationsFile)
+	if patchFile != "" {
+		fmt.Printf("   - Patches de recursos: %s\n", patchFile)
+	}
+	if ssaFile != "" {
+		fmt.Printf("   - Manifestos para Server-Side Apply: %s\n", ssaFile)
+	}
+	if vpaFile != "" {
+		fmt.Printf("   - Recomendações no formato do VPA: %s\n", vpaFile)
+	}
+	if jsonFile != "" {
+		fmt.Printf("   - Relatório JSON (schema %s): %s\n", ReportSchemaVersion, jsonFile)
+	}
+	if costFile != "" {
+		fmt.Printf("   - Rateio de custos (CSV): %s\n", costFile)
+	}
+	if executiveFile != "" {
+		fmt.Printf("   - Resumo executivo por namespace (PDF): %s\n", executiveFile)
+	}
+	if heatmapFile != "" {
+		fmt.Printf("   - Mapa de calor por hora (HTML): %s\n", heatmapFile)
+	}
+	if scheduleFile != "" {
+		fmt.Printf("   - Escalonamento por horário (KEDA/HPA): %s\n", scheduleFile)
+	}
+	if rawSamplesFile != "" {
+		fmt.Printf("   - Amostras brutas (%s, %d linhas): %s\n", *rawSamples, rawSampleWriter.Rows, rawSamplesFile)
+	}
+	if splitIndex != "" {
+		fmt.Printf("   - Relatórios por grupo: %s\n", splitIndex)
+	}
+	if indexFile != "" {
+		fmt.Printf("   - Índice dos relatórios: %s\n", indexFile)
+	}
+	if bundleFile != "" {
+		fmt.Printf("   - Pacote: %s\n", bundleFile)
+	}
+	if len(risks) > 0 {
+		fmt.Printf("\n🚨 Riscos iminentes detectados: %d", len(risks))
+		if alertsSent > 0 {
+			fmt.Printf(" (%d alertas enviados para %s)", alertsSent, analyzerConfig.Alerting.Provider)
+		}
+		fmt.Println()
+	}
+	if failed := failedPolicies(policyViolations); len(failed) > 0 {
+		fmt.Printf("\n❌ Políticas violadas: %s (código de saída %d)\n", strings.Join(failed, ", "), policyFailureExitCode)
+	}
+	if webhooksSent > 0 {
+		fmt.Printf("   - Webhooks notificados: %d/%d\n", webhooksSent, len(analyzerConfig.Webhooks))
+	}
+	if jiraTickets != nil {
+		fmt.Printf("   - Tickets no Jira: %d criados %v, %d já abertos\n", len(jiraTickets.Created), jiraTickets.Created, jiraTickets.Existing)
+	}
+	if repositoryIssues != nil {
+		fmt.Printf("   - Issues em %s: %d criadas, %d já abertas\n", analyzerConfig.Issues.Repository, len(repositoryIssues.Created), repositoryIssues.Existing)
+		for _, link := range repositoryIssues.Created {
+			fmt.Printf("     %s\n", link)
+		}
+	}
+
+	if simulation != nil {
+		fmt.Printf("\n🧪 Simulação de agendamento:\n")
+		fmt.Printf("   - Pods agendados: %d/%d\n", simulation.ScheduledPods, simulation.TotalPods)
+		fmt.Printf("   - Nodes que poderiam ser drenados: %d/%d\n", len(simulation.DrainableNodes), simulation.TotalNodes)
+	}
+
+	// Aplicar os patches (interativamente, em dry-run no servidor ou confirmados)
+	if command == "apply" {
+		if len(patches) == 0 {
+			fmt.Printf("\nNenhum patch de recursos para aplicar\n")
+			return
+		}
+
+		// Os valores anteriores entram no pacote de rollback antes de cada patch e cada alteração
+		// aplicada é registrada no log de auditoria logo em seguida
+		journal := &ApplyJournal{
+			RollbackPath: filepath.Join(reportDir, fmt.Sprintf("rollback-%s-%s.json", sanitizedContext, timestamp)),
+			Bundle:       &RollbackBundle{Context: *k8sContext, CreatedAt: time.Now().In(location)},
+		}
+		if *interactive || *confirm {
+			reason := *justification
+			if reason == "" {
+				reason = fmt.Sprintf("Right-sizing baseado no uso observado durante %v", collectionPeriod)
+			}
+			journal.Audit = newAuditLog(clientset, auditFilePath(reportDir, sanitizedContext), *auditConfigMap, *k8sContext, reason, location)
+		}
+
+		var result *ApplyResult
+		switch {
+		case *interactive:
+			fmt.Printf("\n🔧 Aplicando patches de recursos (%d deployments)...\n", len(patches))
+			result = applyInteractive(clientset, patches, os.Stdin, journal)
+		case *confirm:
+			fmt.Printf("\n🔧 Validando e aplicando patches de recursos (%d deployments)...\n", len(patches))
+			result = applyAll(clientset, patches, false, journal)
+		default:
+			fmt.Printf("\n🔍 Validando patches de recursos no servidor (dry-run, %d deployments)...\n", len(patches))
+			result = applyAll(clientset, patches, true, journal)
+		}
+
+		if *dryRun == "server" {
+			fmt.Printf("\nAceitos: %d, rejeitados: %d (nenhuma alteração foi feita; use -confirm para aplicar)\n",
+				len(result.Applied), len(result.Failed))
+		} else {
+			fmt.Printf("\nAplicados: %d, ignorados: %d, com erro: %d\n", len(result.Applied), len(result.Skipped), len(result.Failed))
+		}
+
+		if journal.Audit != nil && journal.Audit.Recorded > 0 {
+			fmt.Printf("   - Log de auditoria: %s\n", journal.Audit.Path)
+		}
+		if len(journal.Bundle.Entries) > 0 {
+			fmt.Printf("   - Pacote de rollback: %s (desfazer com: rollback %s)\n", journal.RollbackPath, journal.RollbackPath)
+		}
+
+		if pending := append(result.Skipped, result.Failed...); len(pending) > 0 {
+			skippedFile := filepath.Join(reportDir, fmt.Sprintf("skipped-patches-%s-%s.json", sanitizedContext, timestamp))
+			if err := writeSkippedPatches(skippedFile, pending); err != nil {
+				fmt.Printf("⚠️  Aviso: %v\n", err)
+			} else {
+				fmt.Printf("   - Patches pendentes: %s\n", skippedFile)
+			}
+		}
+	}
 }
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// loadSamples reads the samples of a run: a samples.json or the bundle zip that contains it
+func loadSamples(path string) (*BundleSamples, error) {
+	var data []byte
+	if strings.HasSuffix(path, ".zip") {
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao abrir pacote %s: %v", path, err)
+		}
+		defer archive.Close()
+		file, err := archive.Open("samples.json")
+		if err != nil {
+			return nil, fmt.Errorf("pacote %s sem samples.json: %v", path, err)
+		}
+		defer file.Close()
+		if data, err = io.ReadAll(file); err != nil {
+			return nil, fmt.Errorf("erro ao ler amostras de %s: %v", path, err)
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("erro ao ler amostras: %v", err)
+		}
+	}
+
+	samples := &BundleSamples{}
+	if err := json.Unmarshal(data, samples); err != nil {
+		return nil, fmt.Errorf("erro ao analisar amostras de %s: %v", path, err)
+	}
+	if samples.Pods == nil {
+		samples.Pods = make(map[string]*PodMetrics)
+	}
+	if samples.Nodes == nil {
+		samples.Nodes = make(map[string]*NodeMetrics)
+	}
+	return samples, nil
+}
+
+// metricsData turns the stored samples into the metrics of a collection
+func (s *BundleSamples) metricsData() *MetricsData {
+	return &MetricsData{
+		PodMetrics:     s.Pods,
+		NodeMetrics:    s.Nodes,
+		ClusterSamples: s.ClusterSamples,
+		Source:         s.Source,
+		Hourly:         s.Hourly,
+	}
+}
+
+// MergedRun describes one of the merged runs
+type MergedRun struct {
+	Path    string
+	Source  string
+	Start   time.Time
+	End     time.Time
+	Samples int
+	Pods    int
+}
+
+// MergedWorkload is the spread of the peaks of a workload across the merged runs
+type MergedWorkload struct {
+	Namespace string
+	Workload  string
+	// Picos de CPU e memória do workload em cada execução em que apareceu
+	CPUPeaks    []float64
+	MemoryPeaks []float64
+}
+
+// MergeSummary describes the merged dataset
+type MergeSummary struct {
+	Runs      []MergedRun
+	Workloads []*MergedWorkload
+	// Pods com o mesmo nome em namespaces diferentes, mantidos pela execução com mais leituras
+	Conflicts []string
+}
+
+// mergePodMetrics folds the metrics of the same pod seen in another run
+func mergePodMetrics(into, from *PodMetrics) {
+	into.MaxCPU = max(into.MaxCPU, from.MaxCPU)
+	into.MaxMemory = max(into.MaxMemory, from.MaxMemory)
+	into.MaxEphemeralStorage = max(into.MaxEphemeralStorage, from.MaxEphemeralStorage)
+	into.MaxProcesses = max(into.MaxProcesses, from.MaxProcesses)
+	if !from.FirstSeen.IsZero() && (into.FirstSeen.IsZero() || from.FirstSeen.Before(into.FirstSeen)) {
+		into.FirstSeen = from.FirstSeen
+	}
+	if from.LastSeen.After(into.LastSeen) {
+		into.LastSeen = from.LastSeen
+	}
+	into.Samples += from.Samples
+	if into.Containers == nil {
+		into.Containers = make(map[string]*ContainerMetrics)
+	}
+	for name, cm := range from.Containers {
+		existing, exists := into.Containers[name]
+		if !exists {
+			into.Containers[name] = cm
+			continue
+		}
+		existing.MaxCPU = max(existing.MaxCPU, cm.MaxCPU)
+		existing.MaxMemory = max(existing.MaxMemory, cm.MaxMemory)
+		existing.MaxWorkingSet = max(existing.MaxWorkingSet, cm.MaxWorkingSet)
+		existing.MaxRSS = max(existing.MaxRSS, cm.MaxRSS)
+		existing.PageFaults += cm.PageFaults
+		existing.MajorPageFaults += cm.MajorPageFaults
+	}
+}
+
+// mergeSamples combines the samples of several runs: the cluster samples are joined in time order, the
+// pods and nodes seen in more than one run keep the highest peaks and add up their readings
+func mergeSamples(paths []string, runs []*BundleSamples) (*BundleSamples, *MergeSummary) {
+	merged := &BundleSamples{
+		Pods:   make(map[string]*PodMetrics),
+		Nodes:  make(map[string]*NodeMetrics),
+		Hourly: make(map[string]*HourlyUsage),
+	}
+	summary := &MergeSummary{}
+	var sources []string
+	seenSources := make(map[string]bool)
+	workloads := make(map[string]*MergedWorkload)
+
+	for i, run := range runs {
+		info := MergedRun{Path: paths[i], Source: run.Source, Samples: len(run.ClusterSamples), Pods: len(run.Pods)}
+		for _, sample := range run.ClusterSamples {
+			if info.Start.IsZero() || sample.Time.Before(info.Start) {
+				info.Start = sample.Time
+			}
+			if sample.Time.After(info.End) {
+				info.End = sample.Time
+			}
+		}
+		summary.Runs = append(summary.Runs, info)
+		if run.Source != "" && !seenSources[run.Source] {
+			seenSources[run.Source] = true
+			sources = append(sources, run.Source)
+		}
+		merged.ClusterSamples = append(merged.ClusterSamples, run.ClusterSamples...)
+
+		// Picos de cada workload nesta execução
+		peaks := make(map[string][2]int64)
+		for name, pm := range run.Pods {
+			key := pm.Namespace + "/" + workloadFromPodName(name)
+			peak := peaks[key]
+			peaks[key] = [2]int64{max(peak[0], pm.MaxCPU), max(peak[1], pm.MaxMemory)}
+
+			existing, exists := merged.Pods[name]
+			switch {
+			case !exists:
+				copied := *pm
+				copied.Containers = make(map[string]*ContainerMetrics, len(pm.Containers))
+				for container, cm := range pm.Containers {
+					c := *cm
+					copied.Containers[container] = &c
+				}
+				merged.Pods[name] = &copied
+			case existing.Namespace != pm.Namespace:
+				summary.Conflicts = append(summary.Conflicts, name)
+				if pm.Samples > existing.Samples {
+					merged.Pods[name] = pm
+				}
+			default:
+				mergePodMetrics(existing, pm)
+			}
+		}
+		for key, peak := range peaks {
+			w, exists := workloads[key]
+			if !exists {
+				namespace, workload, _ := strings.Cut(key, "/")
+				w = &MergedWorkload{Namespace: namespace, Workload: workload}
+				workloads[key] = w
+			}
+			w.CPUPeaks = append(w.CPUPeaks, float64(peak[0]))
+			w.MemoryPeaks = append(w.MemoryPeaks, float64(peak[1]))
+		}
+
+		for key, h := range run.Hourly {
+			existing, exists := merged.Hourly[key]
+			if !exists {
+				copied := *h
+				copied.Days = append([]string(nil), h.Days...)
+				merged.Hourly[key] = &copied
+				continue
+			}
+			existing.merge(h)
+		}
+
+		for name, nm := range run.Nodes {
+			existing, exists := merged.Nodes[name]
+			if !exists {
+				copied := *nm
+				merged.Nodes[name] = &copied
+				continue
+			}
+			existing.MaxCPU = max(existing.MaxCPU, nm.MaxCPU)
+			existing.MaxMemory = max(existing.MaxMemory, nm.MaxMemory)
+			existing.MaxProcesses = max(existing.MaxProcesses, nm.MaxProcesses)
+			existing.MaxPIDs = max(existing.MaxPIDs, nm.MaxPIDs)
+			existing.Samples += nm.Samples
+		}
+	}
+
+	sort.Slice(merged.ClusterSamples, func(i, j int) bool {
+		return merged.ClusterSamples[i].Time.Before(merged.ClusterSamples[j].Time)
+	})
+	merged.Source = strings.Join(sources, ",")
+	for _, w := range workloads {
+		summary.Workloads = append(summary.Workloads, w)
+	}
+	sort.Slice(summary.Workloads, func(i, j int) bool {
+		a, b := summary.Workloads[i], summary.Workloads[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Workload < b.Workload
+	})
+	sort.Strings(summary.Conflicts)
+	return merged, summary
+}
+
+// writeMergedSamples writes the merged dataset in the samples.json format, readable by -samples
+func writeMergedSamples(path string, samples *BundleSamples) error {
+	data, err := json.MarshalIndent(samples, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar amostras: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("erro ao escrever amostras combinadas: %v", err)
+	}
+	return nil
+}
+
+// attachSampledPods adds to the deployments the pods of stored samples that no longer exist, matched by
+// the name of the pod (deployment-<hash>-<sufixo>), and recomputes the peaks of the deployments
+func attachSampledPods(deploymentMetrics map[string]*DeploymentMetrics, metrics *MetricsData, overrides ContainerOverrides) int {
+	known := make(map[string]bool)
+	for _, dm := range deploymentMetrics {
+		for _, name := range dm.Pods {
+			known[name] = true
+		}
+	}
+	names := make([]string, 0, len(metrics.PodMetrics))
+	for name := range metrics.PodMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attached := 0
+	for _, name := range names {
+		pm := metrics.PodMetrics[name]
+		dm, exists := deploymentMetrics[pm.Namespace+"/"+workloadFromPodName(name)]
+		if known[name] || !exists {
+			continue
+		}
+		dm.Pods = append(dm.Pods, name)
+		attached++
+		for container, cm := range pm.Containers {
+			if overrides.skip(pm.Namespace, container) {
+				continue
+			}
+			dm.MaxCPU = max(dm.MaxCPU, cm.MaxCPU)
+			dm.MaxMemory = max(dm.MaxMemory, cm.MaxMemory)
+		}
+	}
+	return attached
+}
+
+func writeMergeSummary(w io.Writer, summary *MergeSummary) {
+	fmt.Fprintf(w, "\n=== Execuções Combinadas ===\n")
+	fmt.Fprintf(w, "----------------------------\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ARQUIVO\tFONTE\tINÍCIO\tFIM\tLEITURAS\tPODS\n")
+	for _, run := range summary.Runs {
+		start, end := "-", "-"
+		if !run.Start.IsZero() {
+			start, end = run.Start.Format("2006-01-02 15:04"), run.End.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\n", filepath.Base(run.Path), run.Source, start, end, run.Samples, run.Pods)
+	}
+	tw.Flush()
+
+	if len(summary.Workloads) > 0 {
+		fmt.Fprintf(w, "\nPicos por workload entre as execuções (média ± desvio padrão, maior):\n")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "NAMESPACE\tWORKLOAD\tEXECUÇÕES\tCPU\tMEMÓRIA\n")
+		for _, wl := range summary.Workloads {
+			cpuMean, cpuStd := meanStdDev(wl.CPUPeaks)
+			memMean, memStd := meanStdDev(wl.MemoryPeaks)
+			cpuMax, memMax := 0.0, 0.0
+			for i := range wl.CPUPeaks {
+				cpuMax = max(cpuMax, wl.CPUPeaks[i])
+				memMax = max(memMax, wl.MemoryPeaks[i])
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d/%d\t%s ± %s, %s\t%s ± %s, %s\n", wl.Namespace, wl.Workload, len(wl.CPUPeaks), len(summary.Runs),
+				formatCPU(int64(cpuMean)), formatCPU(int64(cpuStd)), formatCPU(int64(cpuMax)),
+				formatMemory(int64(memMean)), formatMemory(int64(memStd)), formatMemory(int64(memMax)))
+		}
+		tw.Flush()
+	}
+
+	if len(summary.Conflicts) > 0 {
+		fmt.Fprintf(w, "\nPods com o mesmo nome em namespaces diferentes (mantida a execução com mais leituras): %s\n", strings.Join(summary.Conflicts, ", "))
+	}
+	fmt.Fprintf(w, "\nObservação: workloads presentes em poucas execuções ou com desvio alto entre os picos pedem mais coletas antes de aplicar as recomendações\n")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Grupo da API de métricas de recursos servida pelo Metrics Server
+const metricsGroup = "metrics.k8s.io"
+
+// Versões de metrics.k8s.io conhecidas, em ordem de preferência. PodMetrics e NodeMetrics têm os
+// mesmos campos (timestamp, window, usage) em todas elas e são decodificados com os tipos de v1beta1
+var metricsAPIVersions = []string{"v1beta1", "v1alpha1"}
+
+// MetricsAPI reads the resource metrics API at the version the cluster serves, negotiated through the
+// discovery API on first use instead of assuming v1beta1
+type MetricsAPI struct {
+	client *metricsv.Clientset
+
+	once    sync.Once
+	version string
+	err     error
+}
+
+func newMetricsAPI(client *metricsv.Clientset) *MetricsAPI {
+	return &MetricsAPI{client: client}
+}
+
+// negotiateMetricsVersion picks the preferred version of the server when it is known, otherwise the
+// first known version served. Clusters serving only newer versions get the preferred one, read with
+// the fields of v1beta1
+func negotiateMetricsVersion(group metav1.APIGroup) string {
+	if slices.Contains(metricsAPIVersions, group.PreferredVersion.Version) {
+		return group.PreferredVersion.Version
+	}
+	for _, known := range metricsAPIVersions {
+		for _, served := range group.Versions {
+			if served.Version == known {
+				return known
+			}
+		}
+	}
+	if group.PreferredVersion.Version != "" {
+		return group.PreferredVersion.Version
+	}
+	if len(group.Versions) > 0 {
+		return group.Versions[0].Version
+	}
+	return ""
+}
+
+// Version returns the negotiated version of metrics.k8s.io, querying the groups of the cluster once
+func (m *MetricsAPI) Version() (string, error) {
+	m.once.Do(func() {
+		groups, err := m.client.Discovery().ServerGroups()
+		if err != nil {
+			m.err = fmt.Errorf("erro ao consultar as APIs do cluster: %v", err)
+			return
+		}
+		for _, group := range groups.Groups {
+			if group.Name == metricsGroup {
+				m.version = negotiateMetricsVersion(group)
+				break
+			}
+		}
+		if m.version == "" {
+			m.err = fmt.Errorf("a API %s não é servida pelo cluster", metricsGroup)
+		}
+	})
+	return m.version, m.err
+}
+
+// get builds a request for a resource of the negotiated version, in JSON
+func (m *MetricsAPI) get(resource string) (*rest.Request, error) {
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	return m.client.MetricsV1beta1().RESTClient().Get().
+		AbsPath("/apis", metricsGroup, version, resource).
+		SetHeader("Accept", "application/json"), nil
+}
+
+// streamPods opens the list of pod metrics of all namespaces
+func (m *MetricsAPI) streamPods() (io.ReadCloser, error) {
+	req, err := m.get("pods")
+	if err != nil {
+		return nil, err
+	}
+	return req.Stream(context.TODO())
+}
+
+// nodeMetrics lists the usage of the nodes
+func (m *MetricsAPI) nodeMetrics() ([]metricsv1beta1.NodeMetrics, error) {
+	req, err := m.get("nodes")
+	if err != nil {
+		return nil, err
+	}
+	data, err := req.DoRaw(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	var list metricsv1beta1.NodeMetricsList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar métricas dos nodes: %v", err)
+	}
+	return list.Items, nil
+}
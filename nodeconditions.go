@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Condições de node acompanhadas durante a coleta; NotReady é a condição Ready diferente de True
+var trackedNodeConditions = []corev1.NodeConditionType{
+	corev1.NodeReady,
+	corev1.NodeMemoryPressure,
+	corev1.NodeDiskPressure,
+	corev1.NodePIDPressure,
+}
+
+// conditionLabel names the bad state of a condition
+func conditionLabel(condition corev1.NodeConditionType) string {
+	if condition == corev1.NodeReady {
+		return "NotReady"
+	}
+	return string(condition)
+}
+
+// NodeConditionTransition is a change of a node condition seen between two readings
+type NodeConditionTransition struct {
+	Time      time.Time
+	Condition corev1.NodeConditionType
+	// Condição passou para o estado ruim (pressão ou NotReady); falso quando normalizou
+	Active bool
+}
+
+// NodeConditionTracker samples the node conditions at each reading of the collection and remembers the
+// node of each pod at the start, to relate the pods that left a node to its conditions
+type NodeConditionTracker struct {
+	mu          sync.Mutex
+	readings    int
+	initial     map[string]map[corev1.NodeConditionType]bool
+	last        map[string]map[corev1.NodeConditionType]bool
+	transitions map[string][]NodeConditionTransition
+	// Node de cada pod ("namespace/nome") no início da coleta
+	podNodes map[string]string
+	// Ciclo de vida de cada node e horário da última leitura
+	history     map[string]*NodeHistory
+	lastReading time.Time
+}
+
+func newNodeConditionTracker() *NodeConditionTracker {
+	return &NodeConditionTracker{
+		initial:     make(map[string]map[corev1.NodeConditionType]bool),
+		last:        make(map[string]map[corev1.NodeConditionType]bool),
+		transitions: make(map[string][]NodeConditionTransition),
+		podNodes:    make(map[string]string),
+		history:     make(map[string]*NodeHistory),
+	}
+}
+
+// recordPod remembers the node the pod runs on at the start of the collection
+func (t *NodeConditionTracker) recordPod(pod *corev1.Pod) {
+	if pod.Spec.NodeName == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.podNodes[pod.Namespace+"/"+pod.Name] = pod.Spec.NodeName
+}
+
+// nodeConditionStates returns which tracked conditions of the node are in the bad state
+func nodeConditionStates(node *corev1.Node) map[corev1.NodeConditionType]bool {
+	states := make(map[corev1.NodeConditionType]bool, len(trackedNodeConditions))
+	for _, condition := range node.Status.Conditions {
+		switch condition.Type {
+		case corev1.NodeReady:
+			states[condition.Type] = condition.Status != corev1.ConditionTrue
+		case corev1.NodeMemoryPressure, corev1.NodeDiskPressure, corev1.NodePIDPressure:
+			states[condition.Type] = condition.Status == corev1.ConditionTrue
+		}
+	}
+	return states
+}
+
+// sample lists the nodes and records the conditions that changed since the previous reading
+func (t *NodeConditionTracker) sample(clientset *kubernetes.Clientset, now time.Time) error {
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("erro ao listar nodes para acompanhar as condições: %v", err)
+	}
+	t.observe(nodes.Items, now)
+	return nil
+}
+
+// observe records the conditions of the nodes in one reading
+func (t *NodeConditionTracker) observe(nodes []corev1.Node, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.readings++
+	for i := range nodes {
+		states := nodeConditionStates(&nodes[i])
+		name := nodes[i].Name
+		previous, seen := t.last[name]
+		if !seen {
+			t.initial[name] = states
+		} else {
+			for _, condition := range trackedNodeConditions {
+				if states[condition] != previous[condition] {
+					t.transitions[name] = append(t.transitions[name], NodeConditionTransition{Time: now, Condition: condition, Active: states[condition]})
+				}
+			}
+		}
+		t.last[name] = states
+		t.recordHistory(&nodes[i], now)
+	}
+	t.lastReading = now
+}
+
+// NodeConditionReport summarizes the conditions of a node that flapped or stayed bad during the window
+type NodeConditionReport struct {
+	Node        string
+	Transitions []NodeConditionTransition
+	// Condições ruins no início e no fim da coleta
+	AtStart []corev1.NodeConditionType
+	AtEnd   []corev1.NodeConditionType
+	// Pods despejados do node durante a coleta
+	Evicted []EvictedPod
+	// Pods que estavam no node no início e não existem mais (removidos ou reagendados)
+	Removed []string
+}
+
+// flapped reports whether some condition of the node changed during the window
+func (r *NodeConditionReport) flapped() bool {
+	return len(r.Transitions) > 0
+}
+
+// badConditions lists the conditions in the bad state, in the tracked order
+func badConditions(states map[corev1.NodeConditionType]bool) []corev1.NodeConditionType {
+	var bad []corev1.NodeConditionType
+	for _, condition := range trackedNodeConditions {
+		if states[condition] {
+			bad = append(bad, condition)
+		}
+	}
+	return bad
+}
+
+// conditionList formats the conditions by the name of their bad state
+func conditionList(conditions []corev1.NodeConditionType) string {
+	if len(conditions) == 0 {
+		return "nenhuma"
+	}
+	labels := make([]string, len(conditions))
+	for i, condition := range conditions {
+		labels[i] = conditionLabel(condition)
+	}
+	return strings.Join(labels, ", ")
+}
+
+// nodeConditionReports lists the nodes whose conditions flapped or were bad during the window, with the
+// pods evicted from them in the window and the pods that were on them at the start and are gone
+func (t *NodeConditionTracker) nodeConditionReports(pods []corev1.Pod, evicted []EvictedPod) []*NodeConditionReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current := make(map[string]bool, len(pods))
+	for i := range pods {
+		current[pods[i].Namespace+"/"+pods[i].Name] = true
+	}
+
+	var reports []*NodeConditionReport
+	for node, states := range t.last {
+		r := &NodeConditionReport{
+			Node:        node,
+			Transitions: t.transitions[node],
+			AtStart:     badConditions(t.initial[node]),
+			AtEnd:       badConditions(states),
+		}
+		if !r.flapped() && len(r.AtEnd) == 0 {
+			continue
+		}
+		for _, e := range evicted {
+			if e.Node == node && e.DuringWindow {
+				r.Evicted = append(r.Evicted, e)
+			}
+		}
+		for pod, podNode := range t.podNodes {
+			if podNode == node && !current[pod] {
+				r.Removed = append(r.Removed, pod)
+			}
+		}
+		sort.Strings(r.Removed)
+		reports = append(reports, r)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].flapped() != reports[j].flapped() {
+			return reports[i].flapped()
+		}
+		return reports[i].Node < reports[j].Node
+	})
+	return reports
+}
+
+// nodeConditionFindings turns the nodes that flapped into findings
+func nodeConditionFindings(reports []*NodeConditionReport) []Finding {
+	var findings []Finding
+	for _, r := range reports {
+		if !r.flapped() {
+			continue
+		}
+		seen := make(map[corev1.NodeConditionType]bool)
+		var conditions []corev1.NodeConditionType
+		for _, transition := range r.Transitions {
+			if !seen[transition.Condition] {
+				seen[transition.Condition] = true
+				conditions = append(conditions, transition.Condition)
+			}
+		}
+		findings = append(findings, Finding{
+			Kind:     "node-instavel",
+			Severity: SeverityHigh,
+			Title:    fmt.Sprintf("Node %s com condições instáveis durante a coleta (%s)", r.Node, conditionList(conditions)),
+			Workload: r.Node,
+			Body: fmt.Sprintf("%d mudanças de condição durante a coleta, %d pods despejados e %d pods removidos do node.\n\nRecomendação: verificar os requests dos pods do node e a capacidade de disco e PIDs (kubectl describe node %s).",
+				len(r.Transitions), len(r.Evicted), len(r.Removed), r.Node),
+		})
+	}
+	return findings
+}
+
+func writeNodeConditions(w io.Writer, reports []*NodeConditionReport, readings int, location *time.Location) {
+	fmt.Fprintf(w, "\n=== Condições dos Nodes Durante a Coleta ===\n")
+	fmt.Fprintf(w, "--------------------------------------------\n")
+	fmt.Fprintf(w, "Leituras das condições: %d\n", readings)
+
+	if len(reports) == 0 {
+		fmt.Fprintf(w, "Nenhum node com NotReady, MemoryPressure, DiskPressure ou PIDPressure durante a coleta\n")
+		return
+	}
+
+	for _, r := range reports {
+		if r.flapped() {
+			fmt.Fprintf(w, "\nNode: %s (instável: %d mudanças de condição)\n", r.Node, len(r.Transitions))
+		} else {
+			fmt.Fprintf(w, "\nNode: %s (condição ruim durante toda a coleta)\n", r.Node)
+		}
+		fmt.Fprintf(w, "  Início: %s; fim: %s\n", conditionList(r.AtStart), conditionList(r.AtEnd))
+		for _, transition := range r.Transitions {
+			state := "normalizou"
+			if transition.Active {
+				state = "ativa"
+			}
+			fmt.Fprintf(w, "  - %s: %s %s\n", transition.Time.In(location).Format("15:04:05"), conditionLabel(transition.Condition), state)
+		}
+		if len(r.Evicted) > 0 {
+			fmt.Fprintf(w, "  Pods despejados durante a coleta: %d\n", len(r.Evicted))
+			for _, e := range r.Evicted {
+				fmt.Fprintf(w, "    - %s/%s (%s)\n", e.Namespace, e.Name, e.Resource)
+			}
+		}
+		if len(r.Removed) > 0 {
+			fmt.Fprintf(w, "  Pods removidos ou reagendados desde o início da coleta: %d\n", len(r.Removed))
+			for _, pod := range r.Removed {
+				fmt.Fprintf(w, "    - %s\n", pod)
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Labels de pool de nodes dos provedores e do Karpenter, na ordem em que são procurados quando
+// -node-group-label não é informado
+var nodePoolLabels = []string{
+	"eks.amazonaws.com/nodegroup",
+	"cloud.google.com/gke-nodepool",
+	"kubernetes.azure.com/agentpool",
+	"karpenter.sh/nodepool",
+}
+
+// Limiares para sugerir aumentar ou reduzir um grupo de nodes
+const (
+	nodeGroupGrowPeakPct      = 80.0
+	nodeGroupGrowRequestedPct = 90.0
+	nodeGroupShrinkPeakPct    = 30.0
+)
+
+// resolveNodeGroupLabel returns the configured label, or the first known node pool label present on the
+// nodes, falling back to the instance type
+func resolveNodeGroupLabel(nodes []corev1.Node, configured string) string {
+	if configured != "" {
+		return configured
+	}
+	for _, label := range nodePoolLabels {
+		for i := range nodes {
+			if nodes[i].Labels[label] != "" {
+				return label
+			}
+		}
+	}
+	return instanceTypeLabel
+}
+
+// nodeGroup returns the group of the node by the label, using the beta label for the instance type
+func nodeGroup(node *corev1.Node, label string) string {
+	group := node.Labels[label]
+	if group == "" && label == instanceTypeLabel {
+		group = nodeInstanceType(node)
+	}
+	if group == "" {
+		return "(sem " + label + ")"
+	}
+	return group
+}
+
+// NodeGroupUtilization aggregates the capacity, the requests and the peak usage of the nodes of a group
+type NodeGroupUtilization struct {
+	Name              string
+	Nodes             []string
+	AllocatableCPU    int64
+	AllocatableMemory int64
+	RequestedCPU      int64
+	RequestedMemory   int64
+	// Soma dos picos dos nodes do grupo
+	MaxCPU    int64
+	MaxMemory int64
+	// Nodes do grupo apontados pelas análises de desbalanceamento e de densidade de pods
+	HotNodes            []string
+	NearPodExhaustion   []string
+	NodesWithoutMetrics int
+}
+
+// recommendation returns the resize suggested for the group, or an empty string
+func (g *NodeGroupUtilization) recommendation() string {
+	cpuPeak, memoryPeak := percent(g.MaxCPU, g.AllocatableCPU), percent(g.MaxMemory, g.AllocatableMemory)
+	cpuRequested, memoryRequested := percent(g.RequestedCPU, g.AllocatableCPU), percent(g.RequestedMemory, g.AllocatableMemory)
+	switch {
+	case cpuPeak >= nodeGroupGrowPeakPct || memoryPeak >= nodeGroupGrowPeakPct:
+		return fmt.Sprintf("aumentar o grupo: pico de CPU %.0f%% e Memory %.0f%% do allocatable", cpuPeak, memoryPeak)
+	case cpuRequested >= nodeGroupGrowRequestedPct || memoryRequested >= nodeGroupGrowRequestedPct:
+		return fmt.Sprintf("aumentar o grupo ou revisar os requests: %.0f%% da CPU e %.0f%% da Memory já reservados", cpuRequested, memoryRequested)
+	case len(g.Nodes) > 1 && g.NodesWithoutMetrics == 0 && cpuPeak < nodeGroupShrinkPeakPct && memoryPeak < nodeGroupShrinkPeakPct:
+		return fmt.Sprintf("reduzir o grupo ou usar instâncias menores: pico de CPU %.0f%% e Memory %.0f%% do allocatable", cpuPeak, memoryPeak)
+	}
+	return ""
+}
+
+// analyzeNodeGroups aggregates the nodes by the value of the label, attaching the hot nodes and the
+// nodes near the max-pods limit to their groups
+func analyzeNodeGroups(nodes []corev1.Node, pods []corev1.Pod, metrics *MetricsData, label string, imbalance *NodeImbalance, densities []NodePodDensity) []*NodeGroupUtilization {
+	groups := make(map[string]*NodeGroupUtilization)
+	nodeGroups := make(map[string]*NodeGroupUtilization, len(nodes))
+	for i := range nodes {
+		node := &nodes[i]
+		name := nodeGroup(node, label)
+		g, exists := groups[name]
+		if !exists {
+			g = &NodeGroupUtilization{Name: name}
+			groups[name] = g
+		}
+		nodeGroups[node.Name] = g
+		g.Nodes = append(g.Nodes, node.Name)
+		g.AllocatableCPU += node.Status.Allocatable.Cpu().MilliValue()
+		g.AllocatableMemory += node.Status.Allocatable.Memory().Value()
+		if nm, exists := metrics.NodeMetrics[node.Name]; exists {
+			g.MaxCPU += nm.MaxCPU
+			g.MaxMemory += nm.MaxMemory
+		} else {
+			g.NodesWithoutMetrics++
+		}
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		g, exists := nodeGroups[pod.Spec.NodeName]
+		if !exists || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		cpu, memory := podRequests(pod)
+		g.RequestedCPU += cpu
+		g.RequestedMemory += memory
+	}
+
+	if imbalance != nil {
+		for _, hot := range imbalance.HotNodes {
+			if g, exists := nodeGroups[hot.Name]; exists {
+				g.HotNodes = append(g.HotNodes, hot.Name)
+			}
+		}
+	}
+	for _, d := range nodesNearPodExhaustion(densities) {
+		if g, exists := nodeGroups[d.Name]; exists {
+			g.NearPodExhaustion = append(g.NearPodExhaustion, d.Name)
+		}
+	}
+
+	result := make([]*NodeGroupUtilization, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// countNodeGroupsToResize returns the number of groups with a resize recommendation
+func countNodeGroupsToResize(groups []*NodeGroupUtilization) int {
+	count := 0
+	for _, g := range groups {
+		if g.recommendation() != "" {
+			count++
+		}
+	}
+	return count
+}
+
+func writeNodeGroups(w io.Writer, label string, groups []*NodeGroupUtilization) {
+	fmt.Fprintf(w, "\n=== Utilização por Grupo de Nodes ===\n")
+	fmt.Fprintf(w, "--------------------------------------\n")
+	fmt.Fprintf(w, "Agrupamento: %s\n\n", label)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "GRUPO\tNODES\tCPU ALLOC\tCPU REQ\tCPU PICO\tMEM ALLOC\tMEM REQ\tMEM PICO\n")
+	for _, g := range groups {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%.0f%%\t%.0f%%\t%s\t%.0f%%\t%.0f%%\n", g.Name, len(g.Nodes),
+			formatCPU(g.AllocatableCPU), percent(g.RequestedCPU, g.AllocatableCPU), percent(g.MaxCPU, g.AllocatableCPU),
+			formatMemory(g.AllocatableMemory), percent(g.RequestedMemory, g.AllocatableMemory), percent(g.MaxMemory, g.AllocatableMemory))
+	}
+	tw.Flush()
+
+	for _, g := range groups {
+		recommendation := g.recommendation()
+		if recommendation == "" && len(g.HotNodes) == 0 && len(g.NearPodExhaustion) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\nGrupo: %s\n", g.Name)
+		if len(g.HotNodes) > 0 {
+			fmt.Fprintf(w, "- Nodes quentes: %s\n", strings.Join(g.HotNodes, ", "))
+		}
+		if len(g.NearPodExhaustion) > 0 {
+			fmt.Fprintf(w, "- Nodes próximos do limite de pods: %s\n", strings.Join(g.NearPodExhaustion, ", "))
+		}
+		if g.NodesWithoutMetrics > 0 {
+			fmt.Fprintf(w, "- Nodes sem métricas (fora do pico do grupo): %d\n", g.NodesWithoutMetrics)
+		}
+		if recommendation != "" {
+			fmt.Fprintf(w, "Recomendação: %s\n", recommendation)
+		}
+	}
+	fmt.Fprintf(w, "\nObservação: o pico do grupo soma os picos de cada node, que podem ter ocorrido em momentos diferentes\n")
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Cobertura mínima das leituras de um node para não explicar as lacunas no relatório
+const nodeCoverageWarnPct = 90
+
+// Eventos do ciclo de vida dos nodes observados entre as leituras
+const (
+	nodeEventJoined   = "entrou no cluster"
+	nodeEventLeft     = "saiu do cluster"
+	nodeEventReboot   = "reiniciado"
+	nodeEventCordon   = "cordon"
+	nodeEventUncordon = "uncordon"
+)
+
+// NodeEvent is a change in the lifecycle of a node seen between two readings
+type NodeEvent struct {
+	Time time.Time
+	Kind string
+}
+
+// NodeHistory is the lifecycle of a node during the collection: when it was created, the readings that
+// saw it, cordons and reboots (a new boot ID reported by the kubelet)
+type NodeHistory struct {
+	Node    string
+	Created time.Time
+	// Primeira e última leitura em que o node existia
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Cordon (spec.unschedulable) no início e no fim da coleta
+	CordonedAtStart bool
+	Cordoned        bool
+	Events          []NodeEvent
+	bootID          string
+	// Preenchidos no relatório: leituras de uso do node, leituras da coleta e pods que saíram do node
+	Samples     int
+	Readings    int
+	RemovedPods int
+	NotReady    []time.Time
+}
+
+// recordHistory updates the lifecycle of the node in one reading; called by observe with the lock held
+func (t *NodeConditionTracker) recordHistory(node *corev1.Node, now time.Time) {
+	h, seen := t.history[node.Name]
+	if !seen {
+		h = &NodeHistory{
+			Node:            node.Name,
+			Created:         node.CreationTimestamp.Time,
+			FirstSeen:       now,
+			CordonedAtStart: node.Spec.Unschedulable,
+			Cordoned:        node.Spec.Unschedulable,
+			bootID:          node.Status.NodeInfo.BootID,
+		}
+		t.history[node.Name] = h
+		// Nodes que aparecem depois da primeira leitura entraram no cluster durante a coleta
+		if t.readings > 1 {
+			h.Events = append(h.Events, NodeEvent{Time: now, Kind: nodeEventJoined})
+		}
+	} else if h.LastSeen.Before(t.lastReading) {
+		// Node voltou depois de sumir de uma leitura (ex: recriado com o mesmo nome)
+		h.Events = append(h.Events, NodeEvent{Time: h.LastSeen, Kind: nodeEventLeft}, NodeEvent{Time: now, Kind: nodeEventJoined})
+	}
+	h.LastSeen = now
+	if node.Spec.Unschedulable != h.Cordoned {
+		kind := nodeEventUncordon
+		if node.Spec.Unschedulable {
+			kind = nodeEventCordon
+		}
+		h.Events = append(h.Events, NodeEvent{Time: now, Kind: kind})
+		h.Cordoned = node.Spec.Unschedulable
+	}
+	if bootID := node.Status.NodeInfo.BootID; bootID != "" && h.bootID != "" && bootID != h.bootID {
+		h.Events = append(h.Events, NodeEvent{Time: now, Kind: nodeEventReboot})
+	}
+	if node.Status.NodeInfo.BootID != "" {
+		h.bootID = node.Status.NodeInfo.BootID
+	}
+}
+
+// churned reports whether the node joined, left, rebooted or was cordoned during the collection
+func (h *NodeHistory) churned() bool {
+	return len(h.Events) > 0
+}
+
+// drained reports whether the node was cordoned and lost pods during the collection
+func (h *NodeHistory) drained() bool {
+	return h.RemovedPods > 0 && (h.CordonedAtStart || h.count(nodeEventCordon) > 0)
+}
+
+func (h *NodeHistory) count(kind string) int {
+	n := 0
+	for _, e := range h.Events {
+		if e.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// coverage is the percentage of the readings of the collection with the usage of the node
+func (h *NodeHistory) coverage() int {
+	if h.Readings == 0 {
+		return 100
+	}
+	return min(100, h.Samples*100/h.Readings)
+}
+
+// gapReasons explains the readings without usage of the node from its lifecycle and conditions
+func (h *NodeHistory) gapReasons(location *time.Location) []string {
+	var reasons []string
+	for _, e := range h.Events {
+		switch e.Kind {
+		case nodeEventJoined, nodeEventLeft, nodeEventReboot:
+			reasons = append(reasons, fmt.Sprintf("%s às %s", e.Kind, e.Time.In(location).Format("15:04:05")))
+		}
+	}
+	for _, t := range h.NotReady {
+		reasons = append(reasons, fmt.Sprintf("NotReady às %s", t.In(location).Format("15:04:05")))
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, "nenhuma mudança no node; verificar o kubelet e a fonte de métricas")
+	}
+	return reasons
+}
+
+// nodeHistories lists the lifecycle of every node seen in the collection, with the coverage of its usage
+// readings and the pods that were on it at the start and are gone
+func (t *NodeConditionTracker) nodeHistories(pods []corev1.Pod, metrics *MetricsData) []*NodeHistory {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current := make(map[string]bool, len(pods))
+	for i := range pods {
+		current[pods[i].Namespace+"/"+pods[i].Name] = true
+	}
+	removed := make(map[string]int)
+	for pod, node := range t.podNodes {
+		if !current[pod] {
+			removed[node]++
+		}
+	}
+
+	histories := make([]*NodeHistory, 0, len(t.history))
+	for node, h := range t.history {
+		// Nodes ausentes na última leitura saíram do cluster
+		if h.LastSeen.Before(t.lastReading) && (len(h.Events) == 0 || h.Events[len(h.Events)-1].Kind != nodeEventLeft) {
+			h.Events = append(h.Events, NodeEvent{Time: h.LastSeen, Kind: nodeEventLeft})
+		}
+		sort.SliceStable(h.Events, func(i, j int) bool {
+			return h.Events[i].Time.Before(h.Events[j].Time)
+		})
+		h.Readings = len(metrics.ClusterSamples)
+		if nm, exists := metrics.NodeMetrics[node]; exists {
+			h.Samples = nm.Samples
+		}
+		h.RemovedPods = removed[node]
+		h.NotReady = nil
+		for _, transition := range t.transitions[node] {
+			if transition.Condition == corev1.NodeReady && transition.Active {
+				h.NotReady = append(h.NotReady, transition.Time)
+			}
+		}
+		histories = append(histories, h)
+	}
+	sort.Slice(histories, func(i, j int) bool {
+		if histories[i].churned() != histories[j].churned() {
+			return histories[i].churned()
+		}
+		return histories[i].Node < histories[j].Node
+	})
+	return histories
+}
+
+// nodeChurnFindings turns the nodes that rebooted, were drained or were replaced into findings
+func nodeChurnFindings(histories []*NodeHistory) []Finding {
+	var findings []Finding
+	for _, h := range histories {
+		if !h.churned() {
+			continue
+		}
+		severity := SeverityLow
+		if h.count(nodeEventReboot) > 0 || h.drained() {
+			severity = SeverityMedium
+		}
+		findings = append(findings, Finding{
+			Kind:     "node-rotatividade",
+			Severity: severity,
+			Title:    fmt.Sprintf("Node %s com rotatividade durante a coleta (%s)", h.Node, h.eventSummary()),
+			Workload: h.Node,
+			Body: fmt.Sprintf("Leituras de uso do node: %d/%d (%d%%), %d pods removidos do node.\n\nAs recomendações dos workloads que rodavam no node consideram apenas as leituras feitas enquanto ele estava disponível; reinícios e drenagens frequentes indicam manutenção, atualizações automáticas ou instâncias spot.",
+				h.Samples, h.Readings, h.coverage(), h.RemovedPods),
+		})
+	}
+	return findings
+}
+
+// eventSummary counts the events of the node by kind, in the order they are listed in the report
+func (h *NodeHistory) eventSummary() string {
+	var parts []string
+	for _, kind := range []string{nodeEventJoined, nodeEventLeft, nodeEventReboot, nodeEventCordon, nodeEventUncordon} {
+		if n := h.count(kind); n == 1 {
+			parts = append(parts, kind)
+		} else if n > 1 {
+			parts = append(parts, fmt.Sprintf("%s %dx", kind, n))
+		}
+	}
+	if h.drained() {
+		parts = append(parts, "drenado")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatAge formats an age in days, hours or minutes
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
+
+func writeNodeHistory(w io.Writer, histories []*NodeHistory, location *time.Location, now time.Time) {
+	fmt.Fprintf(w, "\n=== Histórico dos Nodes ===\n")
+	fmt.Fprintf(w, "---------------------------\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NODE\tCRIADO\tIDADE\tLEITURAS\tCOBERTURA\tEVENTOS\n")
+	for _, h := range histories {
+		if !h.churned() && h.coverage() >= nodeCoverageWarnPct && !h.CordonedAtStart {
+			continue
+		}
+		events := h.eventSummary()
+		if h.CordonedAtStart {
+			events = strings.TrimPrefix(events+", em cordon desde antes da coleta", ", ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d/%d\t%d%%\t%s\n", h.Node, h.Created.In(location).Format("2006-01-02 15:04"),
+			formatAge(now.Sub(h.Created)), h.Samples, h.Readings, h.coverage(), events)
+	}
+	tw.Flush()
+
+	stable := 0
+	for _, h := range histories {
+		if !h.churned() {
+			stable++
+		}
+	}
+	fmt.Fprintf(w, "\nNodes sem mudanças durante a coleta: %d/%d\n", stable, len(histories))
+
+	for _, h := range histories {
+		if !h.churned() && h.coverage() >= nodeCoverageWarnPct {
+			continue
+		}
+		fmt.Fprintf(w, "\nNode: %s\n", h.Node)
+		for _, e := range h.Events {
+			fmt.Fprintf(w, "  - %s: %s\n", e.Time.In(location).Format("15:04:05"), e.Kind)
+		}
+		if h.drained() {
+			fmt.Fprintf(w, "  Drenado: %d pods que estavam no node no início da coleta foram removidos\n", h.RemovedPods)
+		}
+		if h.coverage() < nodeCoverageWarnPct {
+			fmt.Fprintf(w, "  Lacunas nas leituras (%d de %d leituras sem uso do node): %s\n", h.Readings-h.Samples, h.Readings,
+				strings.Join(h.gapReasons(location), "; "))
+		}
+	}
+	fmt.Fprintf(w, "\nObservação: reinícios são detectados pela troca do boot ID informado pelo kubelet e cordons pelo spec.unschedulable, a cada leitura da coleta\n")
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// Versões mínimas com suporte: containerd anterior a 1.7 não recebe mais correções e o cgroup v2,
+// usado pelo kubelet para PSI e limites de memória mais precisos, requer kernel 5.8
+var (
+	minContainerdVersion = utilversion.MustParseGeneric("1.7")
+	minKernelVersion     = utilversion.MustParseGeneric("5.8")
+)
+
+// Nodes (ou objetos) listados por problema no relatório
+const nodeInventoryListed = 5
+
+// NodeVersions is the software of a node, as reported by the kubelet
+type NodeVersions struct {
+	Kubelet      string
+	Runtime      string
+	Kernel       string
+	OSImage      string
+	Architecture string
+}
+
+// NodeInventoryGroup is a set of nodes running the same versions
+type NodeInventoryGroup struct {
+	NodeVersions
+	Nodes []string
+}
+
+// NodeVersionIssue is a version problem shared by a set of nodes: kubelets behind the newest one,
+// deprecated container runtimes or kernels without full cgroup v2 support
+type NodeVersionIssue struct {
+	// Versão afetada (ex: "docker://20.10.17", "kubelet v1.27", "kernel 5.4.0")
+	Subject        string
+	Severity       Severity
+	Problem        string
+	Recommendation string
+	Nodes          []string
+}
+
+// NodeInventory is the software inventory of the nodes and its problems
+type NodeInventory struct {
+	Groups []*NodeInventoryGroup
+	Issues []*NodeVersionIssue
+	// Versão minor mais nova dos kubelets (ex: "1.30")
+	NewestKubelet string
+}
+
+// buildNodeInventory groups the nodes by their versions and checks the kubelets, runtimes and kernels
+func buildNodeInventory(nodes []corev1.Node) *NodeInventory {
+	inventory := &NodeInventory{}
+	groups := make(map[NodeVersions]*NodeInventoryGroup)
+	var newest *utilversion.Version
+	for _, node := range nodes {
+		info := node.Status.NodeInfo
+		versions := NodeVersions{Kubelet: info.KubeletVersion, Runtime: info.ContainerRuntimeVersion, Kernel: info.KernelVersion,
+			OSImage: info.OSImage, Architecture: info.Architecture}
+		group, exists := groups[versions]
+		if !exists {
+			group = &NodeInventoryGroup{NodeVersions: versions}
+			groups[versions] = group
+			inventory.Groups = append(inventory.Groups, group)
+		}
+		group.Nodes = append(group.Nodes, node.Name)
+		if v, err := utilversion.ParseGeneric(info.KubeletVersion); err == nil && (newest == nil || newest.LessThan(v)) {
+			newest = v
+		}
+	}
+	sort.Slice(inventory.Groups, func(i, j int) bool {
+		if len(inventory.Groups[i].Nodes) != len(inventory.Groups[j].Nodes) {
+			return len(inventory.Groups[i].Nodes) > len(inventory.Groups[j].Nodes)
+		}
+		return inventory.Groups[i].Kubelet < inventory.Groups[j].Kubelet
+	})
+	if newest != nil {
+		inventory.NewestKubelet = fmt.Sprintf("%d.%d", newest.Major(), newest.Minor())
+	}
+
+	issues := make(map[string]*NodeVersionIssue)
+	add := func(issue NodeVersionIssue, nodes []string) {
+		existing, exists := issues[issue.Subject]
+		if !exists {
+			existing = &issue
+			issues[issue.Subject] = existing
+			inventory.Issues = append(inventory.Issues, existing)
+		}
+		existing.Nodes = append(existing.Nodes, nodes...)
+	}
+	for _, group := range inventory.Groups {
+		kubelet, kubeletErr := utilversion.ParseGeneric(group.Kubelet)
+		if kubeletErr == nil && newest != nil && kubelet.Minor() < newest.Minor() {
+			behind := newest.Minor() - kubelet.Minor()
+			severity, lag := SeverityLow, "uma versão minor"
+			if behind > 1 {
+				severity, lag = SeverityMedium, fmt.Sprintf("%d versões minor", behind)
+			}
+			add(NodeVersionIssue{
+				Subject:  fmt.Sprintf("kubelet v%d.%d", kubelet.Major(), kubelet.Minor()),
+				Severity: severity,
+				Problem: fmt.Sprintf("kubelet %s atrás do node mais novo (v%s): nodes com versões diferentes têm comportamentos diferentes de scheduling, despejo e cgroups, o que distorce a comparação do uso entre eles",
+					lag, inventory.NewestKubelet),
+				Recommendation: fmt.Sprintf("concluir a atualização dos nodes para v%s.", inventory.NewestKubelet),
+			}, group.Nodes)
+		}
+
+		runtime, runtimeVersion, _ := strings.Cut(group.Runtime, "://")
+		parsed, runtimeErr := utilversion.ParseGeneric(runtimeVersion)
+		switch {
+		case runtime == "docker":
+			add(NodeVersionIssue{
+				Subject:  group.Runtime,
+				Severity: SeverityMedium,
+				Problem: "o dockershim foi removido no Kubernetes 1.24; o Docker Engine só funciona com o cri-dockerd, " +
+					"uma camada extra em cada chamada do kubelet ao runtime (inícios de pods e coleta de estatísticas mais lentos)",
+				Recommendation: "migrar os nodes para containerd ou CRI-O.",
+			}, group.Nodes)
+		case runtime == "containerd" && runtimeErr == nil && parsed.LessThan(minContainerdVersion):
+			add(NodeVersionIssue{
+				Subject:        group.Runtime,
+				Severity:       SeverityMedium,
+				Problem:        fmt.Sprintf("containerd %s fora de suporte (anterior a %s), sem correções de segurança e de desempenho", runtimeVersion, minContainerdVersion),
+				Recommendation: fmt.Sprintf("atualizar o containerd para %s ou mais novo.", minContainerdVersion),
+			}, group.Nodes)
+		case runtime == "cri-o" && runtimeErr == nil && kubeletErr == nil && parsed.Minor() != kubelet.Minor():
+			add(NodeVersionIssue{
+				Subject:  group.Runtime + " com kubelet " + group.Kubelet,
+				Severity: SeverityLow,
+				Problem: fmt.Sprintf("o CRI-O acompanha as versões do Kubernetes e o %s é testado com o kubelet 1.%d, não com o %s",
+					runtimeVersion, parsed.Minor(), group.Kubelet),
+				Recommendation: fmt.Sprintf("usar o CRI-O 1.%d, a mesma versão minor do kubelet.", kubelet.Minor()),
+			}, group.Nodes)
+		}
+
+		if kernel, err := utilversion.ParseGeneric(group.Kernel); err == nil && kernel.LessThan(minKernelVersion) {
+			add(NodeVersionIssue{
+				Subject:  "kernel " + kernel.String(),
+				Severity: SeverityLow,
+				Problem: fmt.Sprintf("kernel anterior a %s, sem suporte completo a cgroup v2: o kubelet não usa memory.high nem PSI e o OOM killer age sobre o cgroup inteiro",
+					minKernelVersion),
+				Recommendation: fmt.Sprintf("atualizar a imagem dos nodes para um kernel %s ou mais novo.", minKernelVersion),
+			}, group.Nodes)
+		}
+	}
+	sort.SliceStable(inventory.Issues, func(i, j int) bool {
+		return inventory.Issues[i].Severity > inventory.Issues[j].Severity
+	})
+	return inventory
+}
+
+// listedNames returns the first names of the list and how many were left out
+func listedNames(names []string) string {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	if len(sorted) <= nodeInventoryListed {
+		return strings.Join(sorted, ", ")
+	}
+	return fmt.Sprintf("%s e mais %d", strings.Join(sorted[:nodeInventoryListed], ", "), len(sorted)-nodeInventoryListed)
+}
+
+func nodeCount(n int) string {
+	if n == 1 {
+		return "1 node"
+	}
+	return fmt.Sprintf("%d nodes", n)
+}
+
+// nodeInventoryFindings reports each version problem once, with the affected nodes
+func nodeInventoryFindings(inventory *NodeInventory) []Finding {
+	var findings []Finding
+	for _, issue := range inventory.Issues {
+		findings = append(findings, Finding{
+			Kind:     "node-versao",
+			Severity: issue.Severity,
+			Title:    fmt.Sprintf("%s em %s", issue.Subject, nodeCount(len(issue.Nodes))),
+			Workload: issue.Subject,
+			Body: fmt.Sprintf("Nodes: %s.\n\nAnomalias de desempenho costumam se concentrar nesses nodes: %s.\n\nRecomendação: %s",
+				listedNames(issue.Nodes), issue.Problem, issue.Recommendation),
+		})
+	}
+	return findings
+}
+
+func writeNodeInventory(w io.Writer, inventory *NodeInventory) {
+	fmt.Fprintf(w, "\n=== Inventário dos Nodes ===\n")
+	fmt.Fprintf(w, "----------------------------\n")
+
+	if len(inventory.Groups) == 0 {
+		fmt.Fprintf(w, "Nenhum node encontrado\n")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NODES\tKUBELET\tRUNTIME\tKERNEL\tSISTEMA\tARQUITETURA\n")
+	for _, g := range inventory.Groups {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", len(g.Nodes), g.Kubelet, g.Runtime, g.Kernel, g.OSImage, g.Architecture)
+	}
+	tw.Flush()
+
+	if len(inventory.Issues) == 0 {
+		fmt.Fprintf(w, "\nNenhum problema de versão nos nodes\n")
+		return
+	}
+	fmt.Fprintf(w, "\nProblemas de versão:\n")
+	for _, issue := range inventory.Issues {
+		fmt.Fprintf(w, "- [%s] %s (%s: %s): %s\n", issue.Severity, issue.Subject, nodeCount(len(issue.Nodes)), listedNames(issue.Nodes), issue.Problem)
+	}
+}
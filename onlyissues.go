@@ -0,0 +1,19 @@
+package main
+
+import "fmt"
+
+// unsatisfiableSet indexes the deployments whose suggested requests fit no node
+func unsatisfiableSet(unsatisfiable []UnsatisfiableRecommendation) map[string]bool {
+	set := make(map[string]bool, len(unsatisfiable))
+	for _, u := range unsatisfiable {
+		set[fmt.Sprintf("%s/%s", u.Namespace, u.Deployment)] = true
+	}
+	return set
+}
+
+// deploymentHasIssues reports whether the block of the deployment has something to act on: pods
+// without limits or suggested requests that no node can hold. Used by -only-issues to leave out the
+// healthy deployments
+func deploymentHasIssues(dm *DeploymentMetrics, unsatisfiable map[string]bool) bool {
+	return dm.PodsWithoutLimits > 0 || unsatisfiable[fmt.Sprintf("%s/%s", dm.Namespace, dm.Name)]
+}
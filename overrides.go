@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"path"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ContainerOverride pins the recommendation of the containers matching the name (and the namespace,
+// when set) to fixed values, or excludes them from the recommendations and findings. Both names accept
+// path.Match wildcards (ex: "*-proxy")
+type ContainerOverride struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// Ignorar o container nas recomendações, nos patches e nos problemas
+	Exclude bool `json:"exclude,omitempty"`
+	// Valores fixos recomendados; recursos omitidos mantêm o valor atual do container
+	Requests ResourceValues `json:"requests,omitempty"`
+	Limits   ResourceValues `json:"limits,omitempty"`
+}
+
+// ResourceValues are CPU and memory quantities in the Kubernetes notation (ex: 100m, 128Mi)
+type ResourceValues struct {
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+// quantities returns the CPU in millicores and the memory in bytes, 0 for the omitted values. The
+// values are checked by validate when the configuration is loaded
+func (v ResourceValues) quantities() (int64, int64) {
+	var cpu, memory int64
+	if q, err := resource.ParseQuantity(v.CPU); err == nil {
+		cpu = q.MilliValue()
+	}
+	if q, err := resource.ParseQuantity(v.Memory); err == nil {
+		memory = q.Value()
+	}
+	return cpu, memory
+}
+
+func (o *ContainerOverride) pinned() bool {
+	return o.Requests != (ResourceValues{}) || o.Limits != (ResourceValues{})
+}
+
+func (o *ContainerOverride) validate() error {
+	if o.Name == "" {
+		return fmt.Errorf("name é obrigatório")
+	}
+	for _, pattern := range []string{o.Name, o.Namespace} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("padrão inválido: %q", pattern)
+		}
+	}
+	if o.Exclude && o.pinned() {
+		return fmt.Errorf("exclude e valores fixos (requests/limits) não podem ser combinados")
+	}
+	if !o.Exclude && !o.pinned() {
+		return fmt.Errorf("informe exclude ou os valores fixos (requests/limits)")
+	}
+	for _, value := range []string{o.Requests.CPU, o.Requests.Memory, o.Limits.CPU, o.Limits.Memory} {
+		if value == "" {
+			continue
+		}
+		if q, err := resource.ParseQuantity(value); err != nil || q.Sign() <= 0 {
+			return fmt.Errorf("quantidade inválida: %q", value)
+		}
+	}
+	requestCPU, requestMemory := o.Requests.quantities()
+	limitCPU, limitMemory := o.Limits.quantities()
+	if (limitCPU > 0 && requestCPU > limitCPU) || (limitMemory > 0 && requestMemory > limitMemory) {
+		return fmt.Errorf("requests acima dos limits")
+	}
+	return nil
+}
+
+// ContainerOverrides are the overrides of the configuration, in the order they are matched
+type ContainerOverrides []ContainerOverride
+
+// match returns the first override of the container, or nil
+func (o ContainerOverrides) match(namespace, container string) *ContainerOverride {
+	for i := range o {
+		if matched, _ := path.Match(o[i].Name, container); !matched {
+			continue
+		}
+		if o[i].Namespace != "" {
+			if matched, _ := path.Match(o[i].Namespace, namespace); !matched {
+				continue
+			}
+		}
+		return &o[i]
+	}
+	return nil
+}
+
+// skip reports whether the usage of the container is left out of the deployment recommendations and
+// findings, either because it is excluded or because its values are pinned
+func (o ContainerOverrides) skip(namespace, container string) bool {
+	return o.match(namespace, container) != nil
+}
+
+// pinnedRecommendation fills the recommendation of a pinned container with the fixed values, keeping
+// the current value of the omitted ones
+func (o *ContainerOverride) pinnedRecommendation(r *ContainerRecommendation) {
+	requestCPU, requestMemory := o.Requests.quantities()
+	limitCPU, limitMemory := o.Limits.quantities()
+	pick := func(pinned, current int64) int64 {
+		if pinned > 0 {
+			return pinned
+		}
+		return current
+	}
+	r.RequestCPU = pick(requestCPU, r.CurrentRequestCPU)
+	r.RequestMemory = pick(requestMemory, r.CurrentRequestMemory)
+	r.LimitCPU = pick(limitCPU, r.CurrentLimitCPU)
+	r.LimitMemory = pick(limitMemory, r.CurrentLimitMemory)
+}
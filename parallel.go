@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Número padrão de workers que processam os namespaces em paralelo
+const defaultWorkers = 4
+
+// forEachNamespace runs fn for each namespace on a pool of workers. A failure (or panic) in one
+// namespace does not stop the others: the errors are returned by namespace
+func forEachNamespace(namespaces []string, workers int, fn func(namespace string) error) map[string]error {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan string)
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	run := func(namespace string) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("falha inesperada: %v", r)
+			}
+		}()
+		return fn(namespace)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for namespace := range jobs {
+				if err := run(namespace); err != nil {
+					mu.Lock()
+					errs[namespace] = err
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, namespace := range namespaces {
+		jobs <- namespace
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
+
+// warnNamespaceErrors prints the namespaces that failed, in order
+func warnNamespaceErrors(step string, errs map[string]error) {
+	namespaces := make([]string, 0, len(errs))
+	for namespace := range errs {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		fmt.Printf("⚠️  Aviso: %s no namespace %s: %v\n", step, namespace, errs[namespace])
+	}
+}
+
+// listNamespaceNames returns the names of the namespaces of the cluster
+func listNamespaceNames(clientset *kubernetes.Clientset) ([]string, error) {
+	list, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar namespaces: %v", err)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, ns := range list.Items {
+		names = append(names, ns.Name)
+	}
+	return names, nil
+}
+
+// podsByNamespace indexes the pods by namespace, in the order of the namespaces found
+func podsByNamespace(pods []corev1.Pod) ([]string, map[string][]*corev1.Pod) {
+	var namespaces []string
+	index := make(map[string][]*corev1.Pod)
+	for i := range pods {
+		pod := &pods[i]
+		if _, exists := index[pod.Namespace]; !exists {
+			namespaces = append(namespaces, pod.Namespace)
+		}
+		index[pod.Namespace] = append(index[pod.Namespace], pod)
+	}
+	return namespaces, index
+}
+
+// replicaSetOwners maps the ReplicaSets of a namespace to the deployment that owns them
+func replicaSetOwners(clientset *kubernetes.Clientset, namespace string) (map[string]string, error) {
+	owners := make(map[string]string)
+	opts := metav1.ListOptions{Limit: podListPageSize}
+	for {
+		page, err := clientset.AppsV1().ReplicaSets(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar replicasets: %v", err)
+		}
+		for _, rs := range page.Items {
+			for _, owner := range rs.OwnerReferences {
+				if owner.Kind == "Deployment" {
+					owners[rs.Name] = owner.Name
+				}
+			}
+		}
+		if page.Continue == "" {
+			return owners, nil
+		}
+		opts.Continue = page.Continue
+	}
+}
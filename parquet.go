@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Escrita mínima do formato Parquet, sem dependências: colunas obrigatórias INT64 ou BYTE_ARRAY (UTF8),
+// uma página por coluna em cada row group, codificação PLAIN e sem compressão. Os metadados usam o
+// protocolo compacto do Thrift, como na especificação (parquet.thrift)
+
+// Tipos físicos, tipos convertidos e demais enums de parquet.thrift
+const (
+	parquetInt64           = 2
+	parquetByteArray       = 6
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+	parquetRequired        = 0
+	parquetEncodingPlain   = 0
+	parquetEncodingRLE     = 3
+	parquetUncompressed    = 0
+	parquetDataPage        = 0
+)
+
+// parquetColumn is a column of a row group. Timestamps are INT64 microseconds since the epoch
+type parquetColumn struct {
+	Name      string
+	Int64s    []int64
+	Strings   []string
+	String    bool
+	Timestamp bool
+}
+
+func (c parquetColumn) physicalType() int32 {
+	if c.String {
+		return parquetByteArray
+	}
+	return parquetInt64
+}
+
+// plain encodes the values of the column with the PLAIN encoding
+func (c parquetColumn) plain() []byte {
+	var b bytes.Buffer
+	if c.String {
+		for _, s := range c.Strings {
+			binary.Write(&b, binary.LittleEndian, uint32(len(s)))
+			b.WriteString(s)
+		}
+		return b.Bytes()
+	}
+	for _, v := range c.Int64s {
+		binary.Write(&b, binary.LittleEndian, v)
+	}
+	return b.Bytes()
+}
+
+// Tipos do protocolo compacto do Thrift
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the Thrift compact protocol
+type thriftWriter struct {
+	buf bytes.Buffer
+	// Último id de campo de cada struct aberta, para os deltas dos cabeçalhos
+	last []int16
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// list writes the header of a list field with size elements of the type
+func (t *thriftWriter) list(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elem)
+		return
+	}
+	t.buf.WriteByte(0xF0 | elem)
+	t.varint(uint64(size))
+}
+
+// begin opens a struct: a field when id > 0, otherwise an element of a list or the top-level struct
+func (t *thriftWriter) begin(id int16) {
+	if id > 0 {
+		t.field(id, thriftStruct)
+	}
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+// parquetChunk is the position of a column chunk in the file
+type parquetChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// parquetWriter writes row groups as they arrive and the metadata when closed
+type parquetWriter struct {
+	w      io.Writer
+	offset int64
+	schema []parquetColumn
+	groups [][]parquetChunk
+	rows   []int64
+}
+
+func newParquetWriter(w io.Writer, schema []parquetColumn) (*parquetWriter, error) {
+	if _, err := io.WriteString(w, "PAR1"); err != nil {
+		return nil, err
+	}
+	return &parquetWriter{w: w, offset: 4, schema: schema}, nil
+}
+
+// writeRowGroup writes the columns, in the order of the schema, as a row group
+func (p *parquetWriter) writeRowGroup(columns []parquetColumn, rows int) error {
+	if len(columns) != len(p.schema) {
+		return fmt.Errorf("row group com %d colunas, esquema com %d", len(columns), len(p.schema))
+	}
+	chunks := make([]parquetChunk, 0, len(columns))
+	for _, c := range columns {
+		data := c.plain()
+		header := &thriftWriter{}
+		header.begin(0)
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.begin(5)
+		header.i32(1, int32(rows))
+		header.i32(2, parquetEncodingPlain)
+		header.i32(3, parquetEncodingRLE)
+		header.i32(4, parquetEncodingRLE)
+		header.end()
+		header.end()
+
+		chunk := parquetChunk{offset: p.offset, size: int64(header.buf.Len() + len(data)), values: int64(rows)}
+		if _, err := p.w.Write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if _, err := p.w.Write(data); err != nil {
+			return err
+		}
+		p.offset += chunk.size
+		chunks = append(chunks, chunk)
+	}
+	p.groups = append(p.groups, chunks)
+	p.rows = append(p.rows, int64(rows))
+	return nil
+}
+
+// close writes the file metadata (schema, row groups and column chunks) and the footer
+func (p *parquetWriter) close() error {
+	var total int64
+	for _, rows := range p.rows {
+		total += rows
+	}
+
+	meta := &thriftWriter{}
+	meta.begin(0)
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, len(p.schema)+1)
+	meta.begin(0)
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(p.schema)))
+	meta.end()
+	for _, c := range p.schema {
+		meta.begin(0)
+		meta.i32(1, c.physicalType())
+		meta.i32(3, parquetRequired)
+		meta.binary(4, c.Name)
+		switch {
+		case c.String:
+			meta.i32(6, parquetUTF8)
+		case c.Timestamp:
+			meta.i32(6, parquetTimestampMicros)
+		}
+		meta.end()
+	}
+	meta.i64(3, total)
+	meta.list(4, thriftStruct, len(p.groups))
+	for g, chunks := range p.groups {
+		var size int64
+		meta.begin(0)
+		meta.list(1, thriftStruct, len(chunks))
+		for i, chunk := range chunks {
+			meta.begin(0)
+			meta.i64(2, chunk.offset)
+			meta.begin(3)
+			meta.i32(1, p.schema[i].physicalType())
+			meta.list(2, thriftI32, 1)
+			meta.zigzag(parquetEncodingPlain)
+			meta.list(3, thriftBinary, 1)
+			meta.varint(uint64(len(p.schema[i].Name)))
+			meta.buf.WriteString(p.schema[i].Name)
+			meta.i32(4, parquetUncompressed)
+			meta.i64(5, chunk.values)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.end()
+			meta.end()
+			size += chunk.size
+		}
+		meta.i64(2, size)
+		meta.i64(3, p.rows[g])
+		meta.end()
+	}
+	meta.binary(6, "k8s-performance-analyzer")
+	meta.end()
+
+	if _, err := p.w.Write(meta.buf.Bytes()); err != nil {
+		return err
+	}
+	if err := binary.Write(p.w, binary.LittleEndian, uint32(meta.buf.Len())); err != nil {
+		return err
+	}
+	_, err := io.WriteString(p.w, "PAR1")
+	return err
+}
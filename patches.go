@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ContainerRecommendation holds the current and the suggested resources of a container
+type ContainerRecommendation struct {
+	Container            string `json:"container"`
+	CurrentRequestCPU    int64  `json:"current_request_cpu_millis"`
+	CurrentRequestMemory int64  `json:"current_request_memory_bytes"`
+	CurrentLimitCPU      int64  `json:"current_limit_cpu_millis"`
+	CurrentLimitMemory   int64  `json:"current_limit_memory_bytes"`
+	RequestCPU           int64  `json:"request_cpu_millis"`
+	RequestMemory        int64  `json:"request_memory_bytes"`
+	LimitCPU             int64  `json:"limit_cpu_millis"`
+	LimitMemory          int64  `json:"limit_memory_bytes"`
+}
+
+// ResourcePatch is the set of container resource changes proposed for a deployment
+type ResourcePatch struct {
+	Deployment string                    `json:"deployment"`
+	Namespace  string                    `json:"namespace"`
+	Containers []ContainerRecommendation `json:"containers"`
+	// Anotações da validação (quotas, capacidade do cluster)
+	Notes []string `json:"notes,omitempty"`
+	// Origem dos manifestos do deployment, onde a alteração deve ser feita
+	Source string `json:"source,omitempty"`
+	// Confiança nas leituras usadas na recomendação
+	Confidence *RecommendationConfidence `json:"confidence,omitempty"`
+}
+
+// roundUpMiB rounds a memory value up to a whole MiB, so the patch uses readable quantities
+func roundUpMiB(bytes int64) int64 {
+	const mib = 1024 * 1024
+	return (bytes + mib - 1) / mib * mib
+}
+
+// buildResourcePatches derives per-container requests (mean of the peaks observed in each pod)
+// and limits (highest peak, plus the headroom of the namespace) for the deployments with metrics.
+// Excluded containers are left out and pinned containers get the values of their override
+func buildResourcePatches(deploymentMetrics map[string]*DeploymentMetrics, metrics *MetricsData, deployments map[string]*appsv1.Deployment, thresholds NamespaceThresholds, overrides ContainerOverrides) []ResourcePatch {
+	var patches []ResourcePatch
+	for key, dm := range deploymentMetrics {
+		deployment, exists := deployments[key]
+		if !exists || (dm.MaxCPU == 0 && dm.MaxMemory == 0) || dm.InsufficientData != "" {
+			continue
+		}
+
+		type containerUsage struct {
+			totalCPU, totalMemory, maxCPU, maxMemory, samples int64
+		}
+		usage := make(map[string]*containerUsage)
+		for _, podName := range dm.Pods {
+			pm, exists := metrics.PodMetrics[podName]
+			if !exists || pm.Namespace != dm.Namespace {
+				continue
+			}
+			for name, cm := range pm.Containers {
+				u, exists := usage[name]
+				if !exists {
+					u = &containerUsage{}
+					usage[name] = u
+				}
+				u.totalCPU += cm.MaxCPU
+				u.totalMemory += cm.MaxMemory
+				u.maxCPU = max(u.maxCPU, cm.MaxCPU)
+				u.maxMemory = max(u.maxMemory, cm.MaxMemory)
+				u.samples++
+			}
+		}
+
+		t := thresholds.forNamespace(dm.Namespace).withTier(dm.Tier)
+		profile := tierProfile(dm.Tier)
+		patch := ResourcePatch{Deployment: dm.Name, Namespace: dm.Namespace, Source: dm.Source.String(), Confidence: dm.Confidence}
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			recommendation := ContainerRecommendation{
+				Container:            container.Name,
+				CurrentRequestCPU:    container.Resources.Requests.Cpu().MilliValue(),
+				CurrentRequestMemory: container.Resources.Requests.Memory().Value(),
+				CurrentLimitCPU:      container.Resources.Limits.Cpu().MilliValue(),
+				CurrentLimitMemory:   container.Resources.Limits.Memory().Value(),
+			}
+			if override := overrides.match(dm.Namespace, container.Name); override != nil {
+				if override.Exclude {
+					continue
+				}
+				// Valores fixos: o patch só é gerado quando diferem dos atuais
+				override.pinnedRecommendation(&recommendation)
+				if recommendation.RequestCPU != recommendation.CurrentRequestCPU || recommendation.RequestMemory != recommendation.CurrentRequestMemory ||
+					recommendation.LimitCPU != recommendation.CurrentLimitCPU || recommendation.LimitMemory != recommendation.CurrentLimitMemory {
+					patch.Containers = append(patch.Containers, recommendation)
+				}
+				continue
+			}
+			u, exists := usage[container.Name]
+			if !exists || u.samples == 0 {
+				continue
+			}
+			recommendation.RequestCPU = withHeadroomPct(u.totalCPU/u.samples, profile.RequestHeadroomPct)
+			recommendation.RequestMemory = roundUpMiB(withHeadroomPct(u.totalMemory/u.samples, profile.RequestHeadroomPct))
+			recommendation.LimitCPU = withHeadroomPct(u.maxCPU, t.CPUHeadroomPct)
+			recommendation.LimitMemory = roundUpMiB(withHeadroomPct(u.maxMemory, t.MemoryHeadroomPct))
+			patch.Containers = append(patch.Containers, recommendation)
+		}
+		if len(patch.Containers) > 0 {
+			if profile.RequestHeadroomPct > 0 {
+				patch.Notes = append(patch.Notes, fmt.Sprintf("Workload %s: requests com margem de %d%% e limites com folga mínima de CPU %d%% e memória %d%%",
+					dm.Tier, profile.RequestHeadroomPct, t.CPUHeadroomPct, t.MemoryHeadroomPct))
+			}
+			patches = append(patches, patch)
+		}
+	}
+
+	sort.Slice(patches, func(i, j int) bool {
+		if patches[i].Namespace != patches[j].Namespace {
+			return patches[i].Namespace < patches[j].Namespace
+		}
+		return patches[i].Deployment < patches[j].Deployment
+	})
+	return patches
+}
+
+// indexPatches maps the patches by "namespace/deployment"
+func indexPatches(patches []ResourcePatch) map[string]*ResourcePatch {
+	index := make(map[string]*ResourcePatch, len(patches))
+	for i := range patches {
+		index[patches[i].Namespace+"/"+patches[i].Deployment] = &patches[i]
+	}
+	return index
+}
+
+// container returns the recommendation of the container in the patch, or nil (also for a nil patch)
+func (p *ResourcePatch) container(name string) *ContainerRecommendation {
+	if p == nil {
+		return nil
+	}
+	for i := range p.Containers {
+		if p.Containers[i].Container == name {
+			return &p.Containers[i]
+		}
+	}
+	return nil
+}
+
+// resourceList builds the requests or limits of a container, omitting values that were not observed
+func resourceList(cpu, memory int64) map[string]string {
+	list := make(map[string]string)
+	if cpu > 0 {
+		list[string(corev1.ResourceCPU)] = resource.NewMilliQuantity(cpu, resource.DecimalSI).String()
+	}
+	if memory > 0 {
+		list[string(corev1.ResourceMemory)] = resource.NewQuantity(memory, resource.BinarySI).String()
+	}
+	return list
+}
+
+// strategicMergePatch returns the strategic merge patch that sets the container resources
+func (p ResourcePatch) strategicMergePatch() ([]byte, error) {
+	containers := make([]map[string]interface{}, 0, len(p.Containers))
+	for _, c := range p.Containers {
+		containers = append(containers, map[string]interface{}{
+			"name": c.Container,
+			"resources": map[string]interface{}{
+				"requests": resourceList(c.RequestCPU, c.RequestMemory),
+				"limits":   resourceList(c.LimitCPU, c.LimitMemory),
+			},
+		})
+	}
+	return json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": containers,
+				},
+			},
+		},
+	})
+}
+
+// writePatchScript writes one kubectl patch command per deployment, preceded by its validation notes
+func writePatchScript(path string, patches []ResourcePatch) error {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Patches de recursos gerados pelo k8s-performance-analyzer\n")
+	b.WriteString("# Revise cada alteração antes de executar\n")
+	for _, p := range patches {
+		data, err := p.strategicMergePatch()
+		if err != nil {
+			return fmt.Errorf("erro ao gerar patch de %s/%s: %v", p.Namespace, p.Deployment, err)
+		}
+		b.WriteString("\n")
+		if p.Source != "" {
+			fmt.Fprintf(&b, "# Gerenciado por %s: aplique a alteração na origem, o patch será revertido na sincronização\n", p.Source)
+		}
+		writePatchDiffs(&b, p, "# ")
+		if p.Confidence != nil {
+			fmt.Fprintf(&b, "# Confiança: %s\n", p.Confidence)
+		}
+		for _, note := range p.Notes {
+			fmt.Fprintf(&b, "# %s\n", note)
+		}
+		fmt.Fprintf(&b, "kubectl patch deployment %s -n %s --type strategic -p '%s'\n", p.Deployment, p.Namespace, data)
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0755); err != nil {
+		return fmt.Errorf("erro ao escrever arquivo de patches: %v", err)
+	}
+	return nil
+}
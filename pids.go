@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Uso, em percentual do limite, a partir do qual PIDs ou descritores de arquivo são considerados em risco
+const pidPressurePct = 80
+
+// Descritores de arquivo abertos em relação ao limite do processo, exportados pelas aplicações instrumentadas
+// (client_golang, client_java, process-exporter) com as labels namespace e pod do service discovery
+const fileDescriptorUsageQuery = `max by (namespace, pod) (max_over_time((process_open_fds / process_max_fds)[%s:]))`
+
+// recordKubeletPIDs updates the process maxima of the pods and of the node with a kubelet summary
+func recordKubeletPIDs(summary *KubeletSummary, metrics *MetricsData) {
+	for _, pod := range summary.Pods {
+		if pod.ProcessStats == nil || pod.ProcessStats.ProcessCount == nil {
+			continue
+		}
+		if _, exists := metrics.PodMetrics[pod.PodRef.Name]; !exists {
+			metrics.PodMetrics[pod.PodRef.Name] = &PodMetrics{
+				Namespace:  pod.PodRef.Namespace,
+				Containers: make(map[string]*ContainerMetrics),
+			}
+		}
+		pm := metrics.PodMetrics[pod.PodRef.Name]
+		pm.MaxProcesses = max(pm.MaxProcesses, uint64Value(pod.ProcessStats.ProcessCount))
+		if b := metrics.windowBucket(&pm.window, time.Now()); b != nil {
+			b.processes = max(b.processes, uint64Value(pod.ProcessStats.ProcessCount))
+		}
+	}
+
+	if rlimit := summary.Node.Rlimit; rlimit != nil && summary.Node.NodeName != "" {
+		if _, exists := metrics.NodeMetrics[summary.Node.NodeName]; !exists {
+			metrics.NodeMetrics[summary.Node.NodeName] = &NodeMetrics{}
+		}
+		nm := metrics.NodeMetrics[summary.Node.NodeName]
+		if rlimit.NumOfRunningProcesses != nil {
+			nm.MaxProcesses = max(nm.MaxProcesses, *rlimit.NumOfRunningProcesses)
+			if b := metrics.windowBucket(&nm.window, time.Now()); b != nil {
+				b.processes = max(b.processes, *rlimit.NumOfRunningProcesses)
+			}
+		}
+		if rlimit.MaxPID != nil {
+			nm.MaxPIDs = *rlimit.MaxPID
+		}
+	}
+}
+
+// hasProcessMetrics reports whether some summary with process counts was read during the collection
+func hasProcessMetrics(metrics *MetricsData) bool {
+	for _, nm := range metrics.NodeMetrics {
+		if nm.MaxPIDs > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotPIDs reads the process counts once from the summary of each node, for collections that did
+// not use the kubelet
+func snapshotPIDs(clientset *kubernetes.Clientset, nodes []corev1.Node, metrics *MetricsData) []error {
+	var errs []error
+	for _, node := range nodes {
+		summary, err := fetchKubeletSummary(clientset, node.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		recordKubeletPIDs(summary, metrics)
+	}
+	return errs
+}
+
+// PodPIDUsage is a pod close to the process limit of the kubelet
+type PodPIDUsage struct {
+	Namespace string
+	Pod       string
+	Workload  string
+	Node      string
+	Processes int64
+	Limit     int64
+}
+
+// NodePIDUsage is a node close to its PID limit
+type NodePIDUsage struct {
+	Node      string
+	Processes int64
+	MaxPIDs   int64
+}
+
+// FileDescriptorUsage is a pod whose processes are close to the open file limit
+type FileDescriptorUsage struct {
+	Namespace string
+	Pod       string
+	Workload  string
+	// Maior fração de descritores abertos em relação ao limite na janela
+	Ratio float64
+}
+
+// PIDPressure lists the pods and nodes approaching their process limits and the pods approaching the
+// open file limit
+type PIDPressure struct {
+	Pods            []PodPIDUsage
+	Nodes           []NodePIDUsage
+	FileDescriptors []FileDescriptorUsage
+	// Há leituras de processos e de descritores de arquivo
+	Measured            bool
+	DescriptorsMeasured bool
+	// Pods medidos cujo node não tem podPidsLimit
+	Unlimited int
+}
+
+// analyzePIDPressure compares the process count of each pod with the podPidsLimit of its node and the
+// processes of each node with its PID limit
+func analyzePIDPressure(pods []corev1.Pod, metrics *MetricsData, kubelet map[string]*EvictionThresholds, deploymentIndex map[string]*DeploymentMetrics) *PIDPressure {
+	pressure := &PIDPressure{Measured: hasProcessMetrics(metrics)}
+	for i := range pods {
+		pod := &pods[i]
+		pm, exists := metrics.PodMetrics[pod.Name]
+		if !exists || pm.Namespace != pod.Namespace || pm.MaxProcesses == 0 {
+			continue
+		}
+		var limit int64
+		if e, exists := kubelet[pod.Spec.NodeName]; exists {
+			limit = e.PodPidsLimit
+		}
+		if limit == 0 {
+			pressure.Unlimited++
+			continue
+		}
+		if percent(pm.MaxProcesses, limit) < pidPressurePct {
+			continue
+		}
+		pressure.Pods = append(pressure.Pods, PodPIDUsage{
+			Namespace: pod.Namespace,
+			Pod:       pod.Name,
+			Workload:  workloadForPod(pod, deploymentIndex),
+			Node:      pod.Spec.NodeName,
+			Processes: pm.MaxProcesses,
+			Limit:     limit,
+		})
+	}
+
+	for name, nm := range metrics.NodeMetrics {
+		if nm.MaxPIDs > 0 && percent(nm.MaxProcesses, nm.MaxPIDs) >= pidPressurePct {
+			pressure.Nodes = append(pressure.Nodes, NodePIDUsage{Node: name, Processes: nm.MaxProcesses, MaxPIDs: nm.MaxPIDs})
+		}
+	}
+
+	sort.Slice(pressure.Pods, func(i, j int) bool {
+		return percent(pressure.Pods[i].Processes, pressure.Pods[i].Limit) > percent(pressure.Pods[j].Processes, pressure.Pods[j].Limit)
+	})
+	sort.Slice(pressure.Nodes, func(i, j int) bool { return pressure.Nodes[i].Node < pressure.Nodes[j].Node })
+	return pressure
+}
+
+// queryFileDescriptorUsage reads from Prometheus the pods whose processes used at least pidPressurePct
+// of the open file limit in the window
+func queryFileDescriptorUsage(baseURL string, period time.Duration, pods []corev1.Pod, deploymentIndex map[string]*DeploymentMetrics) ([]FileDescriptorUsage, error) {
+	result, err := queryPrometheus(baseURL, fmt.Sprintf(fileDescriptorUsageQuery, fmt.Sprintf("%ds", int(period.Seconds()))))
+	if err != nil {
+		return nil, err
+	}
+	podIndex := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		podIndex[pods[i].Namespace+"/"+pods[i].Name] = &pods[i]
+	}
+
+	var usage []FileDescriptorUsage
+	for _, sample := range result {
+		ratio, err := sample.value()
+		if err != nil || ratio*100 < pidPressurePct {
+			continue
+		}
+		u := FileDescriptorUsage{Namespace: sample.Metric["namespace"], Pod: sample.Metric["pod"], Ratio: ratio}
+		u.Workload = workloadFromPodName(u.Pod)
+		if pod, exists := podIndex[u.Namespace+"/"+u.Pod]; exists {
+			u.Workload = workloadForPod(pod, deploymentIndex)
+		}
+		usage = append(usage, u)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Ratio > usage[j].Ratio })
+	return usage, nil
+}
+
+// issues returns how many pods and nodes are close to a limit
+func (p *PIDPressure) issues() int {
+	return len(p.Pods) + len(p.Nodes) + len(p.FileDescriptors)
+}
+
+// pidFindings turns the workloads and nodes close to their limits into findings, one per workload
+func pidFindings(p *PIDPressure) []Finding {
+	var findings []Finding
+	seen := make(map[string]bool)
+	for _, u := range p.Pods {
+		if seen[u.Namespace+"/"+u.Workload] {
+			continue
+		}
+		seen[u.Namespace+"/"+u.Workload] = true
+		findings = append(findings, Finding{
+			Kind:      "pids",
+			Severity:  SeverityHigh,
+			Title:     fmt.Sprintf("Workload %s/%s próximo do limite de processos por pod", u.Namespace, u.Workload),
+			Namespace: u.Namespace,
+			Workload:  u.Workload,
+			Body: fmt.Sprintf("O pod %s chegou a %d processos, %.0f%% do podPidsLimit (%d) do node %s. Ao atingir o limite, novos processos e threads falham.\n\nRecomendação: investigar vazamento de processos ou threads (ex: processos zumbis sem init) ou aumentar o podPidsLimit do pool de nodes.",
+				u.Pod, u.Processes, percent(u.Processes, u.Limit), u.Limit, u.Node),
+		})
+	}
+	for _, n := range p.Nodes {
+		findings = append(findings, Finding{
+			Kind:     "node-pids",
+			Severity: SeverityHigh,
+			Title:    fmt.Sprintf("Node %s próximo do limite de PIDs", n.Node),
+			Workload: n.Node,
+			Body: fmt.Sprintf("%d processos em execução para um limite de %d (%.0f%%).\n\nRecomendação: definir podPidsLimit no kubelet para que um pod não esgote os PIDs do node e identificar os pods com mais processos.",
+				n.Processes, n.MaxPIDs, percent(n.Processes, n.MaxPIDs)),
+		})
+	}
+	seen = make(map[string]bool)
+	for _, u := range p.FileDescriptors {
+		if seen[u.Namespace+"/"+u.Workload] {
+			continue
+		}
+		seen[u.Namespace+"/"+u.Workload] = true
+		findings = append(findings, Finding{
+			Kind:      "descritores",
+			Severity:  SeverityMedium,
+			Title:     fmt.Sprintf("Workload %s/%s próximo do limite de arquivos abertos", u.Namespace, u.Workload),
+			Namespace: u.Namespace,
+			Workload:  u.Workload,
+			Body: fmt.Sprintf("O pod %s chegou a %.0f%% do limite de descritores de arquivo do processo.\n\nRecomendação: verificar vazamento de conexões ou arquivos e, se o uso for legítimo, aumentar o limite (ulimit -n) da imagem.",
+				u.Pod, u.Ratio*100),
+		})
+	}
+	return findings
+}
+
+func writePIDPressure(w io.Writer, p *PIDPressure) {
+	fmt.Fprintf(w, "\n=== Pressão de PIDs e Descritores de Arquivo ===\n")
+	fmt.Fprintf(w, "------------------------------------------------\n")
+
+	if !p.Measured {
+		fmt.Fprintf(w, "Contagem de processos indisponível (summary do kubelet inacessível)\n")
+	} else {
+		if p.Unlimited > 0 {
+			fmt.Fprintf(w, "Pods medidos em nodes sem podPidsLimit (ou com a configuração do kubelet inacessível): %d\n", p.Unlimited)
+		}
+		if len(p.Pods) == 0 && len(p.Nodes) == 0 {
+			fmt.Fprintf(w, "Nenhum pod ou node acima de %d%% do limite de processos\n", pidPressurePct)
+		}
+	}
+	if len(p.Pods) > 0 {
+		fmt.Fprintf(w, "\nPods próximos do limite de processos por pod (podPidsLimit):\n")
+		for _, u := range p.Pods {
+			fmt.Fprintf(w, "- %s/%s (Workload: %s, node %s): %d de %d processos (%.0f%%)\n",
+				u.Namespace, u.Pod, u.Workload, u.Node, u.Processes, u.Limit, percent(u.Processes, u.Limit))
+		}
+	}
+	if len(p.Nodes) > 0 {
+		fmt.Fprintf(w, "\nNodes próximos do limite de PIDs:\n")
+		for _, n := range p.Nodes {
+			fmt.Fprintf(w, "- %s: %d de %d processos (%.0f%%)\n", n.Node, n.Processes, n.MaxPIDs, percent(n.Processes, n.MaxPIDs))
+		}
+	}
+
+	if !p.DescriptorsMeasured {
+		fmt.Fprintf(w, "\nDescritores de arquivo: requer -prometheus-url com as métricas process_open_fds e process_max_fds das aplicações\n")
+	} else if len(p.FileDescriptors) == 0 {
+		fmt.Fprintf(w, "\nNenhum pod acima de %d%% do limite de descritores de arquivo\n", pidPressurePct)
+	} else {
+		fmt.Fprintf(w, "\nPods próximos do limite de descritores de arquivo:\n")
+		for _, u := range p.FileDescriptors {
+			fmt.Fprintf(w, "- %s/%s (Workload: %s): %.0f%% do limite\n", u.Namespace, u.Pod, u.Workload, u.Ratio*100)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// Código de saída da execução quando uma política com fail é violada, distinto dos erros (1)
+const policyFailureExitCode = 3
+
+// Escopos das políticas: cada deployment, cada problema encontrado ou o cluster inteiro
+const (
+	policyScopeDeployment = "deployment"
+	policyScopeFinding    = "finding"
+	policyScopeCluster    = "cluster"
+)
+
+// PolicyRule is a policy expression evaluated over the analysis data. It is violated when the expression
+// is true; the violation becomes a finding and, with Fail, makes the run exit with policyFailureExitCode.
+// With Fail, an expression that cannot be evaluated is also a violation
+type PolicyRule struct {
+	Name string `json:"name"`
+	// deployment (padrão), finding ou cluster
+	Scope      string `json:"scope,omitempty"`
+	Expression string `json:"expression"`
+	// Severidade do problema gerado pela violação (padrão: média)
+	Severity string `json:"severity,omitempty"`
+	// Mensagem exibida na violação; por padrão, a própria expressão
+	Message string `json:"message,omitempty"`
+	Fail    bool   `json:"fail,omitempty"`
+}
+
+func (r PolicyRule) scope() string {
+	if r.Scope == "" {
+		return policyScopeDeployment
+	}
+	return r.Scope
+}
+
+func (r PolicyRule) severity() Severity {
+	severity, err := parseSeverity(r.Severity)
+	if err != nil {
+		return SeverityMedium
+	}
+	return severity
+}
+
+// policyProblems validates the rules of the configuration, compiling their expressions
+func policyProblems(rules []PolicyRule) []ConfigProblem {
+	var problems []ConfigProblem
+	names := make(map[string]bool)
+	for i, rule := range rules {
+		invalid := func(message string, key string) {
+			problems = append(problems, ConfigProblem{Path: []string{"policies", fmt.Sprint(i), key}, Message: message})
+		}
+		if rule.Name == "" {
+			invalid("obrigatório", "name")
+		} else if names[rule.Name] {
+			invalid(fmt.Sprintf("política %q repetida", rule.Name), "name")
+		}
+		names[rule.Name] = true
+		switch rule.scope() {
+		case policyScopeDeployment, policyScopeFinding, policyScopeCluster:
+		default:
+			invalid(fmt.Sprintf("escopo inválido: %s (use deployment, finding ou cluster)", rule.Scope), "scope")
+		}
+		if rule.Expression == "" {
+			invalid("obrigatório", "expression")
+		} else if _, err := compileExpression(rule.Expression); err != nil {
+			invalid(err.Error(), "expression")
+		}
+		if rule.Severity != "" {
+			if _, err := parseSeverity(rule.Severity); err != nil {
+				invalid(err.Error(), "severity")
+			}
+		}
+	}
+	return problems
+}
+
+// PolicyViolation is a rule whose expression was true for a deployment, a finding or the cluster, or a
+// rule with Fail whose expression could not be evaluated there
+type PolicyViolation struct {
+	Rule      PolicyRule
+	Namespace string
+	// Deployment ou workload do problema avaliado; vazio no escopo do cluster
+	Workload string
+	// Erro da avaliação, quando a violação vem de uma política com fail que não pôde ser avaliada
+	Error string
+}
+
+func (v PolicyViolation) finding() Finding {
+	message := v.Rule.Message
+	if message == "" {
+		message = v.Rule.Expression
+	}
+	subject := "do cluster"
+	if v.Workload != "" {
+		subject = "em " + strings.TrimPrefix(v.Namespace+"/"+v.Workload, "/")
+	}
+	title := fmt.Sprintf("Política %s violada %s", v.Rule.Name, subject)
+	body := fmt.Sprintf("%s.\n\nExpressão: %s", strings.TrimSuffix(message, "."), v.Rule.Expression)
+	if v.Error != "" {
+		// Uma política com fail que não pode ser avaliada não passa em silêncio
+		title = fmt.Sprintf("Política %s não avaliada %s", v.Rule.Name, subject)
+		body = fmt.Sprintf("Erro ao avaliar a expressão: %s.\n\nExpressão: %s", v.Error, v.Rule.Expression)
+	}
+	if v.Rule.Fail {
+		body += "\n\nA violação desta política falha a execução."
+	}
+	return Finding{
+		Kind:      "politica-" + v.Rule.Name,
+		Severity:  v.Rule.severity(),
+		Title:     title,
+		Namespace: v.Namespace,
+		Workload:  v.Workload,
+		Body:      body,
+	}
+}
+
+// policyFindings turns the violations into findings, so they reach the trackers and the webhooks
+func policyFindings(violations []PolicyViolation) []Finding {
+	findings := make([]Finding, 0, len(violations))
+	for _, v := range violations {
+		findings = append(findings, v.finding())
+	}
+	return findings
+}
+
+// unsuppressedViolations drops the violations whose findings are suppressed, so accepted violations do
+// not fail the run
+func unsuppressedViolations(violations []PolicyViolation, suppressions *Suppressions) []PolicyViolation {
+	var kept []PolicyViolation
+	for _, v := range violations {
+		if len(suppressions.filter([]Finding{v.finding()})) > 0 {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// failedPolicies returns the names of the rules with fail that were violated
+func failedPolicies(violations []PolicyViolation) []string {
+	failed := make(map[string]bool)
+	for _, v := range violations {
+		if v.Rule.Fail {
+			failed[v.Rule.Name] = true
+		}
+	}
+	names := make([]string, 0, len(failed))
+	for name := range failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// clusterPolicyVars are the fields of the cluster variable, available in every scope
+func clusterPolicyVars(summary RunSummary) map[string]interface{} {
+	findings := make(map[string]interface{})
+	for severity := SeverityLow; severity <= SeverityCritical; severity++ {
+		findings[severityCodes[severity]] = int64(summary.Findings[severity])
+	}
+	return map[string]interface{}{
+		"name":             summary.Cluster,
+		"health_score":     int64(summary.HealthScore),
+		"risks":            int64(summary.Risks),
+		"deployments":      int64(summary.Deployments),
+		"waste_cpu_cores":  summary.WasteCPUCores,
+		"waste_memory_gib": summary.WasteMemoryGiB,
+		"findings":         findings,
+	}
+}
+
+// deploymentPolicyVars are the fields of the deployment variable. Requests are the totals of the
+// replicas; the waste is what the recommendations would free
+func deploymentPolicyVars(dm *DeploymentMetrics, deployment *appsv1.Deployment, patch *ResourcePatch) map[string]interface{} {
+	replicas := int64(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = int64(*deployment.Spec.Replicas)
+	}
+	var requestCPU, requestMemory, limitCPU, limitMemory, wasteCPU, wasteMemory int64
+	for _, c := range deployment.Spec.Template.Spec.Containers {
+		requestCPU += c.Resources.Requests.Cpu().MilliValue()
+		requestMemory += c.Resources.Requests.Memory().Value()
+		limitCPU += c.Resources.Limits.Cpu().MilliValue()
+		limitMemory += c.Resources.Limits.Memory().Value()
+	}
+	if patch != nil {
+		for _, c := range patch.Containers {
+			wasteCPU += max(0, c.CurrentRequestCPU-c.RequestCPU)
+			wasteMemory += max(0, c.CurrentRequestMemory-c.RequestMemory)
+		}
+	}
+	labels := make(map[string]interface{}, len(deployment.Labels))
+	for k, v := range deployment.Labels {
+		labels[k] = v
+	}
+	source := ""
+	if dm.Source != nil {
+		source = dm.Source.Tool
+	}
+	var ageHours, rollouts int64
+	if dm.History != nil {
+		ageHours = int64(time.Since(dm.History.Created).Hours())
+		rollouts = int64(dm.History.Rollouts)
+	}
+	return map[string]interface{}{
+		"name":                 dm.Name,
+		"namespace":            dm.Namespace,
+		"labels":               labels,
+		"replicas":             replicas,
+		"pods":                 int64(dm.TotalPods),
+		"pods_without_limits":  int64(dm.PodsWithoutLimits),
+		"request_cpu_millis":   requestCPU * replicas,
+		"request_memory_bytes": requestMemory * replicas,
+		"limit_cpu_millis":     limitCPU * replicas,
+		"limit_memory_bytes":   limitMemory * replicas,
+		"max_cpu_millis":       dm.MaxCPU,
+		"max_memory_bytes":     dm.MaxMemory,
+		"avg_cpu_millis":       dm.AvgCPU,
+		"avg_memory_bytes":     dm.AvgMemory,
+		"waste_cpu_millis":     wasteCPU * replicas,
+		"waste_memory_bytes":   wasteMemory * replicas,
+		"has_recommendation":   patch != nil,
+		"source":               source,
+		"age_hours":            ageHours,
+		"rollouts_30d":         rollouts,
+	}
+}
+
+// findingPolicyVars are the fields of the finding variable, with the same ids and codes of the JSON report
+func findingPolicyVars(f Finding) map[string]interface{} {
+	category := f.category()
+	return map[string]interface{}{
+		"id":        f.id(),
+		"code":      category.Code,
+		"category":  category.Category,
+		"kind":      f.Kind,
+		"severity":  severityCodes[f.Severity],
+		"title":     f.Title,
+		"namespace": f.Namespace,
+		"workload":  f.Workload,
+		"owner":     f.Owner,
+	}
+}
+
+// evaluatePolicies evaluates each rule over its scope. An expression that fails to evaluate for an item
+// (ex: missing field) is reported once per rule and does not count as a violation
+func evaluatePolicies(rules []PolicyRule, summary RunSummary, deploymentMetrics map[string]*DeploymentMetrics,
+	deployments map[string]*appsv1.Deployment, patches []ResourcePatch, findings []Finding) ([]PolicyViolation, []error) {
+	patchIndex := make(map[string]*ResourcePatch)
+	for i := range patches {
+		patchIndex[patches[i].Namespace+"/"+patches[i].Deployment] = &patches[i]
+	}
+	keys := make([]string, 0, len(deploymentMetrics))
+	for key := range deploymentMetrics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	cluster := clusterPolicyVars(summary)
+
+	var violations []PolicyViolation
+	var errs []error
+	for _, rule := range rules {
+		expr, err := compileExpression(rule.Expression)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("política %s: %v", rule.Name, err))
+			if rule.Fail {
+				violations = append(violations, PolicyViolation{Rule: rule, Error: err.Error()})
+			}
+			continue
+		}
+		var evalErr error
+		check := func(vars map[string]interface{}, namespace, workload string) {
+			violated, err := expr.evalBool(vars)
+			if err != nil {
+				if evalErr == nil {
+					evalErr = fmt.Errorf("política %s: erro ao avaliar em %s: %v", rule.Name, strings.Trim(namespace+"/"+workload, "/"), err)
+				}
+				// Com fail, o erro conta como violação para que a execução não passe sem a verificação
+				if rule.Fail {
+					violations = append(violations, PolicyViolation{Rule: rule, Namespace: namespace, Workload: workload, Error: err.Error()})
+				}
+				return
+			}
+			if violated {
+				violations = append(violations, PolicyViolation{Rule: rule, Namespace: namespace, Workload: workload})
+			}
+		}
+
+		switch rule.scope() {
+		case policyScopeDeployment:
+			for _, key := range keys {
+				dm := deploymentMetrics[key]
+				deployment, exists := deployments[key]
+				if !exists {
+					continue
+				}
+				check(map[string]interface{}{
+					"deployment": deploymentPolicyVars(dm, deployment, patchIndex[key]),
+					"namespace":  dm.Namespace,
+					"cluster":    cluster,
+				}, dm.Namespace, dm.Name)
+			}
+		case policyScopeFinding:
+			for _, f := range findings {
+				check(map[string]interface{}{
+					"finding":   findingPolicyVars(f),
+					"namespace": f.Namespace,
+					"cluster":   cluster,
+				}, f.Namespace, f.Workload)
+			}
+		case policyScopeCluster:
+			check(map[string]interface{}{"cluster": cluster}, "", "")
+		}
+		if evalErr != nil {
+			errs = append(errs, evalErr)
+		}
+	}
+	return violations, errs
+}
+
+func writePolicies(w io.Writer, rules []PolicyRule, violations []PolicyViolation) {
+	fmt.Fprintf(w, "\n=== Políticas ===\n")
+	fmt.Fprintf(w, "-----------------\n")
+
+	byRule := make(map[string][]PolicyViolation)
+	for _, v := range violations {
+		byRule[v.Rule.Name] = append(byRule[v.Rule.Name], v)
+	}
+	for _, rule := range rules {
+		status := "ok"
+		if n := len(byRule[rule.Name]); n > 0 {
+			status = fmt.Sprintf("%d violações", n)
+			if rule.Fail {
+				status += ", falha a execução"
+			}
+		}
+		fmt.Fprintf(w, "\nPolítica: %s (escopo: %s, severidade: %s) - %s\n", rule.Name, rule.scope(), rule.severity(), status)
+		fmt.Fprintf(w, "Expressão: %s\n", rule.Expression)
+		if rule.Message != "" && len(byRule[rule.Name]) > 0 {
+			fmt.Fprintf(w, "Mensagem: %s\n", rule.Message)
+		}
+		for _, v := range byRule[rule.Name] {
+			subject := "cluster"
+			if v.Workload != "" {
+				subject = strings.TrimPrefix(v.Namespace+"/"+v.Workload, "/")
+			}
+			if v.Error != "" {
+				subject += fmt.Sprintf(" (erro de avaliação: %s)", v.Error)
+			}
+			fmt.Fprintf(w, "- %s\n", subject)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEvaluatePoliciesEvaluationErrors(t *testing.T) {
+	deploymentMetrics := map[string]*DeploymentMetrics{
+		"shop/checkout": {Name: "checkout", Namespace: "shop", MaxCPU: 500},
+		"shop/cart":     {Name: "cart", Namespace: "shop", MaxCPU: 100},
+	}
+	deployments := map[string]*appsv1.Deployment{
+		"shop/checkout": {ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "shop", Labels: map[string]string{"owner": "payments"}}},
+		"shop/cart":     {ObjectMeta: metav1.ObjectMeta{Name: "cart", Namespace: "shop"}},
+	}
+	// O label owner só existe em checkout: em cart, o acesso sem has() é erro de avaliação
+	rules := []PolicyRule{
+		{Name: "owner-fail", Expression: "deployment.labels.owner == 'nobody'", Fail: true},
+		{Name: "owner-warn", Expression: "deployment.labels.owner == 'nobody'"},
+		{Name: "cpu-fail", Expression: "deployment.max_cpu_millis > 200", Fail: true},
+	}
+
+	violations, errs := evaluatePolicies(rules, RunSummary{}, deploymentMetrics, deployments, nil, nil)
+	if len(errs) != 2 {
+		t.Fatalf("esperados os erros das duas políticas com owner, obtidos %v", errs)
+	}
+
+	got := make(map[string]PolicyViolation)
+	for _, v := range violations {
+		got[v.Rule.Name+" "+v.Workload] = v
+	}
+	if len(got) != 2 {
+		t.Fatalf("esperadas duas violações, obtidas %+v", violations)
+	}
+	if v, exists := got["owner-fail cart"]; !exists || !strings.Contains(v.Error, "use has() para campos opcionais") {
+		t.Errorf("erro de avaliação da política com fail não virou violação: %+v", violations)
+	}
+	if v, exists := got["cpu-fail checkout"]; !exists || v.Error != "" {
+		t.Errorf("violação de cpu-fail ausente: %+v", violations)
+	}
+	if failed := failedPolicies(violations); strings.Join(failed, ",") != "cpu-fail,owner-fail" {
+		t.Errorf("políticas que falham a execução: %v", failed)
+	}
+
+	finding := got["owner-fail cart"].finding()
+	if !strings.Contains(finding.Title, "não avaliada") || !strings.Contains(finding.Body, "Erro ao avaliar a expressão") {
+		t.Errorf("problema sem o erro de avaliação: %+v", finding)
+	}
+	var report bytes.Buffer
+	writePolicies(&report, rules, violations)
+	if !strings.Contains(report.String(), "- shop/cart (erro de avaliação: ") {
+		t.Errorf("relatório sem o erro de avaliação:\n%s", report.String())
+	}
+}
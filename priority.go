@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Número de preempções a partir do qual um workload é considerado preemptado recorrentemente
+const recurrentPreemptions = 2
+
+// PreemptedWorkload aggregates the Preempted events of a workload
+type PreemptedWorkload struct {
+	Name          string
+	Namespace     string
+	Preemptions   int
+	PriorityClass string
+	Priority      int32
+}
+
+// PreemptionReport correlates preemptions with the PriorityClass of the workloads
+type PreemptionReport struct {
+	Preempted       []PreemptedWorkload
+	WithoutPriority []string
+	DefaultClass    string
+	DefaultPriority int32
+}
+
+// priorityClassIndex lists the PriorityClasses, returning their values and the global default
+func priorityClassIndex(clientset *kubernetes.Clientset) (map[string]*schedulingv1.PriorityClass, *schedulingv1.PriorityClass, error) {
+	classes, err := clientset.SchedulingV1().PriorityClasses().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao listar PriorityClasses: %v", err)
+	}
+
+	index := make(map[string]*schedulingv1.PriorityClass)
+	var globalDefault *schedulingv1.PriorityClass
+	for i := range classes.Items {
+		pc := &classes.Items[i]
+		index[pc.Name] = pc
+		if pc.GlobalDefault {
+			globalDefault = pc
+		}
+	}
+	return index, globalDefault, nil
+}
+
+// listDeployments returns all deployments indexed by "namespace/nome"
+func listDeployments(clientset *kubernetes.Clientset) (map[string]*appsv1.Deployment, error) {
+	deployments, err := clientset.AppsV1().Deployments("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar deployments: %v", err)
+	}
+	index := make(map[string]*appsv1.Deployment, len(deployments.Items))
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		index[d.Namespace+"/"+d.Name] = d
+	}
+	return index, nil
+}
+
+// analyzePreemptions correlates Preempted events with the PriorityClass of the preempted workloads
+func analyzePreemptions(clientset *kubernetes.Clientset, deployments map[string]*appsv1.Deployment) (*PreemptionReport, error) {
+	classes, globalDefault, err := priorityClassIndex(clientset)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PreemptionReport{}
+	if globalDefault != nil {
+		report.DefaultClass = globalDefault.Name
+		report.DefaultPriority = globalDefault.Value
+	}
+
+	events, err := clientset.CoreV1().Events("").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "reason=Preempted,involvedObject.kind=Pod",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar eventos de preempção: %v", err)
+	}
+
+	preempted := make(map[string]*PreemptedWorkload)
+	for _, event := range events.Items {
+		name := workloadFromPodName(event.InvolvedObject.Name)
+		key := event.InvolvedObject.Namespace + "/" + name
+		pw, exists := preempted[key]
+		if !exists {
+			pw = &PreemptedWorkload{Name: name, Namespace: event.InvolvedObject.Namespace}
+			preempted[key] = pw
+
+			// Pods preemptados já foram removidos: usar o template do deployment
+			if d, exists := deployments[key]; exists {
+				pw.PriorityClass = d.Spec.Template.Spec.PriorityClassName
+			}
+			if pc, exists := classes[pw.PriorityClass]; exists {
+				pw.Priority = pc.Value
+			} else {
+				pw.Priority = report.DefaultPriority
+			}
+		}
+		count := int(event.Count)
+		if count == 0 {
+			count = 1
+		}
+		pw.Preemptions += count
+	}
+
+	for _, pw := range preempted {
+		report.Preempted = append(report.Preempted, *pw)
+	}
+	sort.Slice(report.Preempted, func(i, j int) bool {
+		return report.Preempted[i].Preemptions > report.Preempted[j].Preemptions
+	})
+
+	for key, d := range deployments {
+		if d.Spec.Template.Spec.PriorityClassName == "" {
+			report.WithoutPriority = append(report.WithoutPriority, key)
+		}
+	}
+	sort.Strings(report.WithoutPriority)
+
+	return report, nil
+}
+
+func writePreemptions(w io.Writer, report *PreemptionReport) {
+	fmt.Fprintf(w, "\n=== Preempções e PriorityClass ===\n")
+	fmt.Fprintf(w, "----------------------------------\n")
+
+	if len(report.Preempted) == 0 {
+		fmt.Fprintf(w, "Nenhum evento de preempção encontrado\n")
+	} else {
+		fmt.Fprintf(w, "Workloads preemptados:\n")
+		for _, pw := range report.Preempted {
+			class := pw.PriorityClass
+			if class == "" {
+				class = "(nenhuma)"
+			}
+			fmt.Fprintf(w, "- %s (Namespace: %s): %d preempções, PriorityClass %s (prioridade %d)\n",
+				pw.Name, pw.Namespace, pw.Preemptions, class, pw.Priority)
+		}
+
+		recurrent := 0
+		for _, pw := range report.Preempted {
+			if pw.Preemptions >= recurrentPreemptions {
+				recurrent++
+			}
+		}
+		if recurrent > 0 {
+			fmt.Fprintf(w, "\nProblemas Identificados:\n")
+			fmt.Fprintf(w, "1. %d workloads preemptados recorrentemente\n", recurrent)
+			fmt.Fprintf(w, "   Recomendação: Atribuir uma PriorityClass adequada à criticidade do workload, "+
+				"ou adicionar capacidade para que pods de maior prioridade não precisem despejá-los\n")
+			fmt.Fprintf(w, "   Prioridade: Média\n")
+		}
+	}
+
+	fmt.Fprintf(w, "\nDeployments sem priorityClassName: %d", len(report.WithoutPriority))
+	if report.DefaultClass != "" {
+		fmt.Fprintf(w, " (usam a classe padrão global %s, prioridade %d)\n", report.DefaultClass, report.DefaultPriority)
+	} else {
+		fmt.Fprintf(w, " (prioridade 0, sem classe padrão global)\n")
+	}
+	for _, key := range report.WithoutPriority {
+		fmt.Fprintf(w, "- %s\n", key)
+	}
+}
+
+// Namespaces de componentes de plataforma cujos pods não devem rodar com a prioridade padrão
+var systemAdjacentNamespaces = map[string]bool{
+	"kube-system":       true,
+	"ingress-nginx":     true,
+	"cert-manager":      true,
+	"monitoring":        true,
+	"istio-system":      true,
+	"linkerd":           true,
+	"calico-system":     true,
+	"tigera-operator":   true,
+	"kube-flannel":      true,
+	"metallb-system":    true,
+	"gatekeeper-system": true,
+	"kyverno":           true,
+}
+
+// Trechos de nome de workloads de plataforma (DNS, rede, storage, ingress, métricas)
+var systemAdjacentKeywords = []string{
+	"coredns", "kube-dns", "kube-proxy", "metrics-server", "ingress", "calico", "cilium",
+	"flannel", "csi", "istiod", "cert-manager", "external-dns", "cluster-autoscaler",
+}
+
+// NamespacePriorityCoverage is the distribution of priorityClassName among the pods of a namespace
+type NamespacePriorityCoverage struct {
+	Namespace    string
+	Pods         int
+	WithoutClass int
+	Classes      map[string]int
+}
+
+// CriticalWorkload is a system-adjacent workload running at the default priority
+type CriticalWorkload struct {
+	Name           string
+	Namespace      string
+	Reason         string
+	SuggestedClass string
+}
+
+// PriorityCoverage summarizes the PriorityClass usage in the cluster
+type PriorityCoverage struct {
+	Namespaces []NamespacePriorityCoverage
+	Critical   []CriticalWorkload
+}
+
+// systemAdjacentReason returns why a pod is considered system-adjacent, or "" when it is not
+func systemAdjacentReason(pod *corev1.Pod, workload string) string {
+	if systemAdjacentNamespaces[pod.Namespace] {
+		return fmt.Sprintf("namespace de sistema %s", pod.Namespace)
+	}
+	for _, keyword := range systemAdjacentKeywords {
+		if strings.Contains(workload, keyword) {
+			return fmt.Sprintf("componente de plataforma (%s)", keyword)
+		}
+	}
+	return ""
+}
+
+// suggestPriorityClass picks the class a system-adjacent pod should use. The built-in system classes
+// are suggested for kube-system; elsewhere the highest user-defined class, when one exists
+func suggestPriorityClass(pod *corev1.Pod, classes map[string]*schedulingv1.PriorityClass) string {
+	if pod.Namespace == "kube-system" {
+		if isDaemonSetPod(pod) {
+			return "system-node-critical"
+		}
+		return "system-cluster-critical"
+	}
+
+	var best *schedulingv1.PriorityClass
+	for _, pc := range classes {
+		if strings.HasPrefix(pc.Name, "system-") {
+			continue
+		}
+		if best == nil || pc.Value > best.Value {
+			best = pc
+		}
+	}
+	if best != nil && best.Value > 0 {
+		return best.Name
+	}
+	return "uma PriorityClass dedicada de plataforma (ex: platform-critical)"
+}
+
+// analyzePriorityCoverage reports the priorityClassName distribution per namespace and the
+// system-adjacent workloads left at the default priority
+func analyzePriorityCoverage(clientset *kubernetes.Clientset, pods []corev1.Pod, deploymentIndex map[string]*DeploymentMetrics) (*PriorityCoverage, error) {
+	classes, globalDefault, err := priorityClassIndex(clientset)
+	if err != nil {
+		return nil, err
+	}
+
+	coverage := &PriorityCoverage{}
+	namespaces := make(map[string]*NamespacePriorityCoverage)
+	flagged := make(map[string]bool)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		nc, exists := namespaces[pod.Namespace]
+		if !exists {
+			nc = &NamespacePriorityCoverage{Namespace: pod.Namespace, Classes: make(map[string]int)}
+			namespaces[pod.Namespace] = nc
+		}
+		nc.Pods++
+
+		class := pod.Spec.PriorityClassName
+		defaultPriority := class == "" || (globalDefault != nil && class == globalDefault.Name)
+		if class == "" {
+			nc.WithoutClass++
+		} else {
+			nc.Classes[class]++
+		}
+		if !defaultPriority {
+			continue
+		}
+
+		workload := workloadForPod(pod, deploymentIndex)
+		key := pod.Namespace + "/" + workload
+		if flagged[key] {
+			continue
+		}
+		if reason := systemAdjacentReason(pod, workload); reason != "" {
+			flagged[key] = true
+			coverage.Critical = append(coverage.Critical, CriticalWorkload{
+				Name:           workload,
+				Namespace:      pod.Namespace,
+				Reason:         reason,
+				SuggestedClass: suggestPriorityClass(pod, classes),
+			})
+		}
+	}
+
+	for _, nc := range namespaces {
+		coverage.Namespaces = append(coverage.Namespaces, *nc)
+	}
+	sort.Slice(coverage.Namespaces, func(i, j int) bool {
+		return coverage.Namespaces[i].Namespace < coverage.Namespaces[j].Namespace
+	})
+	sort.Slice(coverage.Critical, func(i, j int) bool {
+		if coverage.Critical[i].Namespace != coverage.Critical[j].Namespace {
+			return coverage.Critical[i].Namespace < coverage.Critical[j].Namespace
+		}
+		return coverage.Critical[i].Name < coverage.Critical[j].Name
+	})
+
+	return coverage, nil
+}
+
+func writePriorityCoverage(w io.Writer, coverage *PriorityCoverage) {
+	fmt.Fprintf(w, "\n=== Cobertura de PriorityClass ===\n")
+	fmt.Fprintf(w, "----------------------------------\n")
+
+	for _, nc := range coverage.Namespaces {
+		fmt.Fprintf(w, "- %s: %d pods, %d sem priorityClassName (%.0f%%)", nc.Namespace, nc.Pods, nc.WithoutClass,
+			percent(int64(nc.WithoutClass), int64(nc.Pods)))
+		classNames := make([]string, 0, len(nc.Classes))
+		for class := range nc.Classes {
+			classNames = append(classNames, class)
+		}
+		sort.Strings(classNames)
+		for i, class := range classNames {
+			if i == 0 {
+				fmt.Fprintf(w, " |")
+			}
+			fmt.Fprintf(w, " %s: %d", class, nc.Classes[class])
+		}
+		fmt.Fprintf(w, "\n")
+	}
+
+	if len(coverage.Critical) == 0 {
+		fmt.Fprintf(w, "\nNenhum workload de plataforma rodando com a prioridade padrão\n")
+		return
+	}
+
+	fmt.Fprintf(w, "\nWorkloads de plataforma com prioridade padrão:\n")
+	for _, cw := range coverage.Critical {
+		fmt.Fprintf(w, "- %s (Namespace: %s): %s\n", cw.Name, cw.Namespace, cw.Reason)
+		fmt.Fprintf(w, "  Classe sugerida: %s\n", cw.SuggestedClass)
+	}
+	fmt.Fprintf(w, "\nRecomendação: Atribuir uma PriorityClass a componentes de plataforma para que não sejam "+
+		"preemptados ou despejados antes das aplicações que dependem deles\n")
+	fmt.Fprintf(w, "Prioridade: Alta\n")
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Consultas usadas quando o Prometheus é a fonte de métricas (métricas do cAdvisor)
+const (
+	promContainerCPUQuery    = `sum by (namespace, pod, container) (rate(container_cpu_usage_seconds_total{container!="",container!="POD"}[5m]))`
+	promContainerMemoryQuery = `sum by (namespace, pod, container) (container_memory_working_set_bytes{container!="",container!="POD"})`
+	promNodeCPUQuery         = `sum by (node) (rate(container_cpu_usage_seconds_total{id="/"}[5m]))`
+	promNodeMemoryQuery      = `sum by (node) (container_memory_working_set_bytes{id="/"})`
+)
+
+var prometheusHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+type promResponse struct {
+	Status    string   `json:"status"`
+	ErrorType string   `json:"errorType"`
+	Error     string   `json:"error"`
+	Data      promData `json:"data"`
+}
+
+type promData struct {
+	ResultType string       `json:"resultType"`
+	Result     []promVector `json:"result"`
+}
+
+type promVector struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+// value returns the sample value of an instant vector element
+func (v promVector) value() (float64, error) {
+	if len(v.Value) != 2 {
+		return 0, fmt.Errorf("amostra inválida")
+	}
+	s, ok := v.Value[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("amostra inválida")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// queryPrometheus runs an instant query against the Prometheus HTTP API
+func queryPrometheus(baseURL, query string) ([]promVector, error) {
+	endpoint := strings.TrimRight(baseURL, "/") + "/api/v1/query?" + url.Values{"query": {query}}.Encode()
+	resp, err := prometheusHTTPClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar o Prometheus: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result promResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar resposta do Prometheus (HTTP %d): %v", resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("erro na consulta ao Prometheus: %s: %s", result.ErrorType, result.Error)
+	}
+	if result.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("tipo de resultado inesperado do Prometheus: %s", result.Data.ResultType)
+	}
+	return result.Data.Result, nil
+}
+
+// checkPrometheus verifies that the Prometheus API answers and has cAdvisor metrics
+func checkPrometheus(baseURL string) error {
+	result, err := queryPrometheus(baseURL, promContainerMemoryQuery)
+	if err != nil {
+		return err
+	}
+	if len(result) == 0 {
+		return fmt.Errorf("o Prometheus em %s não possui métricas do cAdvisor (container_memory_working_set_bytes)", baseURL)
+	}
+	return nil
+}
+
+// samplePrometheus reads pod and node usage from Prometheus, used when the Metrics Server is absent
+func samplePrometheus(baseURL string, metrics *MetricsData) (UsageSample, error) {
+	sample := UsageSample{Time: time.Now()}
+
+	type containerKey struct{ namespace, pod, container string }
+	usage := make(map[containerKey][2]int64)
+
+	cpuResult, err := queryPrometheus(baseURL, promContainerCPUQuery)
+	if err != nil {
+		return sample, err
+	}
+	for _, v := range cpuResult {
+		cores, err := v.value()
+		if err != nil {
+			continue
+		}
+		key := containerKey{v.Metric["namespace"], v.Metric["pod"], v.Metric["container"]}
+		u := usage[key]
+		u[0] = int64(cores * 1000)
+		usage[key] = u
+	}
+
+	memoryResult, err := queryPrometheus(baseURL, promContainerMemoryQuery)
+	if err != nil {
+		return sample, err
+	}
+	for _, v := range memoryResult {
+		bytes, err := v.value()
+		if err != nil {
+			continue
+		}
+		key := containerKey{v.Metric["namespace"], v.Metric["pod"], v.Metric["container"]}
+		u := usage[key]
+		u[1] = int64(bytes)
+		usage[key] = u
+	}
+
+	seen := make(map[string]bool)
+	for key, u := range usage {
+		if key.pod == "" || key.container == "" {
+			continue
+		}
+		if !seen[key.namespace+"/"+key.pod] {
+			seen[key.namespace+"/"+key.pod] = true
+			markPodSeen(metrics, key.pod, key.namespace, sample.Time)
+		}
+		recordContainerUsage(metrics, key.pod, key.namespace, key.container, u[0], u[1])
+	}
+
+	nodeUsage := make(map[string][2]int64)
+	nodeCPU, err := queryPrometheus(baseURL, promNodeCPUQuery)
+	if err != nil {
+		return sample, err
+	}
+	for _, v := range nodeCPU {
+		cores, err := v.value()
+		if err != nil || v.Metric["node"] == "" {
+			continue
+		}
+		u := nodeUsage[v.Metric["node"]]
+		u[0] = int64(cores * 1000)
+		nodeUsage[v.Metric["node"]] = u
+	}
+
+	nodeMemory, err := queryPrometheus(baseURL, promNodeMemoryQuery)
+	if err != nil {
+		return sample, err
+	}
+	for _, v := range nodeMemory {
+		bytes, err := v.value()
+		if err != nil || v.Metric["node"] == "" {
+			continue
+		}
+		u := nodeUsage[v.Metric["node"]]
+		u[1] = int64(bytes)
+		nodeUsage[v.Metric["node"]] = u
+	}
+
+	for node, u := range nodeUsage {
+		sample.CPU += u[0]
+		sample.Memory += u[1]
+		recordNodeUsage(metrics, node, u[0], u[1])
+	}
+
+	return sample, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Job sob o qual as métricas do analisador são publicadas no Pushgateway
+const pushgatewayJob = "k8s-performance-analyzer"
+
+// RunSummary holds the headline numbers of a run, published to the Pushgateway
+type RunSummary struct {
+	Cluster string
+	// Requests que podem ser liberados com as recomendações
+	WasteCPUCores  float64
+	WasteMemoryGiB float64
+	// Problemas encontrados por severidade
+	Findings    map[Severity]int
+	Risks       int
+	HealthScore int
+	Deployments int
+	Duration    time.Duration
+	Finished    time.Time
+}
+
+// wasteFraction returns the share of the current requests that the recommendations would free
+func wasteFraction(current, proposed int64) float64 {
+	if current <= 0 || proposed >= current {
+		return 0
+	}
+	return float64(current-proposed) / float64(current)
+}
+
+// healthScore rates the cluster from 0 to 100: half the score is the share of deployments without
+// high or critical findings, the other half is the share of CPU and memory requests actually needed
+func healthScore(findings []Finding, deployments int, simulation *SimulationResult) int {
+	affected := make(map[string]bool)
+	for _, f := range filterFindings(findings, SeverityHigh) {
+		if f.Namespace != "" && f.Workload != "" {
+			affected[f.Namespace+"/"+f.Workload] = true
+		}
+	}
+	score := 50.0
+	if deployments > 0 {
+		score = 50 * (1 - float64(min(len(affected), deployments))/float64(deployments))
+	}
+	waste := 0.0
+	if simulation != nil {
+		waste = (wasteFraction(simulation.CurrentCPURequest, simulation.ProposedCPURequest) +
+			wasteFraction(simulation.CurrentMemRequest, simulation.ProposedMemRequest)) / 2
+	}
+	score += 50 * (1 - waste)
+	return int(score + 0.5)
+}
+
+// summarizeRun gathers the headline numbers of the run
+func summarizeRun(cluster string, findings []Finding, risks int, deployments int, simulation *SimulationResult, start time.Time) RunSummary {
+	summary := RunSummary{
+		Cluster:     cluster,
+		Findings:    make(map[Severity]int),
+		Risks:       risks,
+		HealthScore: healthScore(findings, deployments, simulation),
+		Deployments: deployments,
+		Finished:    time.Now(),
+	}
+	summary.Duration = summary.Finished.Sub(start)
+	if simulation != nil {
+		if simulation.CurrentCPURequest > simulation.ProposedCPURequest {
+			summary.WasteCPUCores = float64(simulation.CurrentCPURequest-simulation.ProposedCPURequest) / 1000
+		}
+		if simulation.CurrentMemRequest > simulation.ProposedMemRequest {
+			summary.WasteMemoryGiB = float64(simulation.CurrentMemRequest-simulation.ProposedMemRequest) / (1024 * 1024 * 1024)
+		}
+	}
+	for _, f := range findings {
+		summary.Findings[f.Severity]++
+	}
+	return summary
+}
+
+// exposition renders the summary in the Prometheus text format
+func (s RunSummary) exposition() []byte {
+	var b bytes.Buffer
+	gauge := func(name, help string, value float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, value)
+	}
+	gauge("k8s_analyzer_waste_cpu_cores", "Requests de CPU que podem ser liberados com as recomendações, em cores.", s.WasteCPUCores)
+	gauge("k8s_analyzer_waste_memory_gib", "Requests de memória que podem ser liberados com as recomendações, em GiB.", s.WasteMemoryGiB)
+	gauge("k8s_analyzer_health_score", "Pontuação de saúde do cluster (0 a 100).", float64(s.HealthScore))
+	gauge("k8s_analyzer_risks", "Riscos iminentes detectados.", float64(s.Risks))
+	gauge("k8s_analyzer_deployments", "Deployments analisados.", float64(s.Deployments))
+	gauge("k8s_analyzer_run_duration_seconds", "Duração da execução do analisador.", s.Duration.Seconds())
+	gauge("k8s_analyzer_last_run_timestamp_seconds", "Horário do fim da última execução.", float64(s.Finished.Unix()))
+
+	fmt.Fprintf(&b, "# HELP k8s_analyzer_violations Problemas encontrados, por severidade.\n# TYPE k8s_analyzer_violations gauge\n")
+	for severity := SeverityLow; severity <= SeverityCritical; severity++ {
+		fmt.Fprintf(&b, "k8s_analyzer_violations{severity=%q} %d\n", severity.String(), s.Findings[severity])
+	}
+	return b.Bytes()
+}
+
+// pushgatewayGroupURL returns the URL of the job/cluster group. Label values with "/" use the base64
+// encoding of the Pushgateway
+func pushgatewayGroupURL(baseURL, cluster string) string {
+	value := "cluster/" + cluster
+	if cluster == "" || strings.Contains(cluster, "/") {
+		value = "cluster@base64/" + base64.RawURLEncoding.EncodeToString([]byte(cluster))
+		if cluster == "" {
+			value = "cluster@base64/="
+		}
+	}
+	return strings.TrimRight(baseURL, "/") + "/metrics/job/" + pushgatewayJob + "/" + value
+}
+
+// pushSummary publishes the summary to the Pushgateway, replacing the metrics of the previous run of
+// the same cluster
+func pushSummary(baseURL string, summary RunSummary) error {
+	req, err := http.NewRequest(http.MethodPut, pushgatewayGroupURL(baseURL, summary.Cluster), bytes.NewReader(summary.exposition()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := trackerHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao publicar métricas no Pushgateway: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("erro ao publicar métricas no Pushgateway: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
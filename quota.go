@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Índices dos recursos comparados com as quotas
+const (
+	quotaRequestsCPU = iota
+	quotaRequestsMemory
+	quotaLimitsCPU
+	quotaLimitsMemory
+	quotaResourceCount
+)
+
+// Nomes de cada recurso na ResourceQuota ("cpu" e "memory" equivalem a requests)
+var quotaResourceNames = [quotaResourceCount][]corev1.ResourceName{
+	quotaRequestsCPU:    {corev1.ResourceRequestsCPU, corev1.ResourceCPU},
+	quotaRequestsMemory: {corev1.ResourceRequestsMemory, corev1.ResourceMemory},
+	quotaLimitsCPU:      {corev1.ResourceLimitsCPU},
+	quotaLimitsMemory:   {corev1.ResourceLimitsMemory},
+}
+
+// QuotaViolation is a resource whose projected namespace total would exceed a ResourceQuota
+type QuotaViolation struct {
+	Namespace string
+	Quota     string
+	Resource  corev1.ResourceName
+	Hard      int64
+	Projected int64
+	// As recomendações foram reduzidas para caber na quota
+	Downscaled bool
+}
+
+// QuotaValidation is the outcome of checking the patches against quotas and cluster capacity
+type QuotaValidation struct {
+	Violations        []QuotaViolation
+	ClusterCPU        int64
+	ClusterMemory     int64
+	ProjectedCPU      int64
+	ProjectedMemory   int64
+	ClusterOvercommit bool
+	// Namespaces cujas ResourceQuotas não puderam ser listadas e não foram verificadas
+	Unchecked []string
+}
+
+// value returns the suggested value of the given quota resource
+func (c *ContainerRecommendation) value(index int) int64 {
+	switch index {
+	case quotaRequestsCPU:
+		return c.RequestCPU
+	case quotaRequestsMemory:
+		return c.RequestMemory
+	case quotaLimitsCPU:
+		return c.LimitCPU
+	default:
+		return c.LimitMemory
+	}
+}
+
+// current returns the current value of the given quota resource
+func (c *ContainerRecommendation) current(index int) int64 {
+	switch index {
+	case quotaRequestsCPU:
+		return c.CurrentRequestCPU
+	case quotaRequestsMemory:
+		return c.CurrentRequestMemory
+	case quotaLimitsCPU:
+		return c.CurrentLimitCPU
+	default:
+		return c.CurrentLimitMemory
+	}
+}
+
+func (c *ContainerRecommendation) setValue(index int, v int64) {
+	switch index {
+	case quotaRequestsCPU:
+		c.RequestCPU = v
+	case quotaRequestsMemory:
+		c.RequestMemory = roundUpMiB(v)
+	case quotaLimitsCPU:
+		c.LimitCPU = v
+	default:
+		c.LimitMemory = roundUpMiB(v)
+	}
+}
+
+// projectedValue returns the value a container would have after the patch (unchanged when not suggested)
+func (c *ContainerRecommendation) projectedValue(index int) int64 {
+	if v := c.value(index); v > 0 {
+		return v
+	}
+	return c.current(index)
+}
+
+// containerTotals returns the current requests/limits of a container indexed by quota resource
+func containerTotals(container *corev1.Container) [quotaResourceCount]int64 {
+	return [quotaResourceCount]int64{
+		quotaRequestsCPU:    container.Resources.Requests.Cpu().MilliValue(),
+		quotaRequestsMemory: container.Resources.Requests.Memory().Value(),
+		quotaLimitsCPU:      container.Resources.Limits.Cpu().MilliValue(),
+		quotaLimitsMemory:   container.Resources.Limits.Memory().Value(),
+	}
+}
+
+// quotaHard returns the hard limit of a quota resource in millicores or bytes
+func quotaHard(quota *corev1.ResourceQuota, index int) (corev1.ResourceName, int64, bool) {
+	for _, name := range quotaResourceNames[index] {
+		hard, exists := quota.Spec.Hard[name]
+		if !exists {
+			continue
+		}
+		if index == quotaRequestsCPU || index == quotaLimitsCPU {
+			return name, hard.MilliValue(), true
+		}
+		return name, hard.Value(), true
+	}
+	return "", 0, false
+}
+
+// validatePatchesAgainstQuotas projects the namespace totals with the suggested resources and, when a
+// ResourceQuota would be exceeded, scales down the request increases to fit or annotates the patches.
+// It also annotates increases when the cluster allocatable cannot hold the projected requests. A
+// namespace whose quotas cannot be listed is reported and skipped
+func validatePatchesAgainstQuotas(clientset *kubernetes.Clientset, patches []ResourcePatch, pods []corev1.Pod, nodes []corev1.Node, deploymentIndex map[string]*DeploymentMetrics) (*QuotaValidation, []error) {
+	var errs []error
+	validation := &QuotaValidation{}
+	validation.ClusterCPU, validation.ClusterMemory = clusterAllocatable(nodes)
+
+	patchIndex := make(map[string]*ResourcePatch)
+	for i := range patches {
+		patchIndex[patches[i].Namespace+"/"+patches[i].Deployment] = &patches[i]
+	}
+
+	// Totais projetados e aumentos propostos por namespace
+	projected := make(map[string]*[quotaResourceCount]int64)
+	increases := make(map[string]*[quotaResourceCount]int64)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if _, exists := projected[pod.Namespace]; !exists {
+			projected[pod.Namespace] = &[quotaResourceCount]int64{}
+			increases[pod.Namespace] = &[quotaResourceCount]int64{}
+		}
+
+		var patch *ResourcePatch
+		if dm, exists := deploymentIndex[pod.Namespace+"/"+pod.Name]; exists {
+			patch = patchIndex[dm.Namespace+"/"+dm.Name]
+		}
+		for j := range pod.Spec.Containers {
+			totals := containerTotals(&pod.Spec.Containers[j])
+			rec := patch.container(pod.Spec.Containers[j].Name)
+			for r := 0; r < quotaResourceCount; r++ {
+				v := totals[r]
+				if rec != nil {
+					v = rec.projectedValue(r)
+					if v > totals[r] {
+						increases[pod.Namespace][r] += v - totals[r]
+					}
+				}
+				projected[pod.Namespace][r] += v
+			}
+		}
+	}
+
+	for namespace, totals := range projected {
+		quotas, err := clientset.CoreV1().ResourceQuotas(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("erro ao listar ResourceQuotas do namespace %s: %v", namespace, err))
+			validation.Unchecked = append(validation.Unchecked, namespace)
+			validation.ProjectedCPU += totals[quotaRequestsCPU]
+			validation.ProjectedMemory += totals[quotaRequestsMemory]
+			continue
+		}
+
+		for i := range quotas.Items {
+			quota := &quotas.Items[i]
+			// Quotas com escopo se aplicam apenas a parte dos pods e não são projetadas
+			if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+				continue
+			}
+			for r := 0; r < quotaResourceCount; r++ {
+				name, hard, exists := quotaHard(quota, r)
+				if !exists || totals[r] <= hard {
+					continue
+				}
+
+				violation := QuotaViolation{Namespace: namespace, Quota: quota.Name, Resource: name, Hard: hard, Projected: totals[r]}
+				base := totals[r] - increases[namespace][r]
+				if r == quotaLimitsCPU || r == quotaLimitsMemory {
+					// Limites reduzidos ficariam abaixo do pico observado (OOMKill ou throttling): apenas anotados
+					annotateNamespace(patches, namespace,
+						fmt.Sprintf("Total projetado de %s no namespace excede a ResourceQuota %s; os limites sugeridos cobrem o pico observado e não foram reduzidos", name, quota.Name))
+				} else if increases[namespace][r] > 0 && base <= hard {
+					factor := float64(hard-base) / float64(increases[namespace][r])
+					downscaleIncreases(patches, namespace, r, factor,
+						fmt.Sprintf("Aumento de %s reduzido a %.0f%% do sugerido para caber na ResourceQuota %s", name, factor*100, quota.Name))
+					violation.Downscaled = true
+					totals[r] = hard
+				} else {
+					annotateNamespace(patches, namespace,
+						fmt.Sprintf("Total projetado de %s no namespace excede a ResourceQuota %s mesmo sem os aumentos sugeridos", name, quota.Name))
+				}
+				validation.Violations = append(validation.Violations, violation)
+			}
+		}
+
+		validation.ProjectedCPU += totals[quotaRequestsCPU]
+		validation.ProjectedMemory += totals[quotaRequestsMemory]
+	}
+
+	if validation.ProjectedCPU > validation.ClusterCPU || validation.ProjectedMemory > validation.ClusterMemory {
+		validation.ClusterOvercommit = true
+		for i := range patches {
+			for _, c := range patches[i].Containers {
+				if c.RequestCPU > c.CurrentRequestCPU || c.RequestMemory > c.CurrentRequestMemory {
+					patches[i].Notes = append(patches[i].Notes,
+						"Requests projetados do cluster excedem o allocatable dos nodes: aplique este aumento apenas após adicionar capacidade")
+					break
+				}
+			}
+		}
+	}
+
+	sort.Strings(validation.Unchecked)
+	return validation, errs
+}
+
+// downscaleIncreases scales the suggested increases of a request in a namespace by factor; limits are
+// never downscaled, since they cover the observed peak
+func downscaleIncreases(patches []ResourcePatch, namespace string, index int, factor float64, note string) {
+	if index == quotaLimitsCPU || index == quotaLimitsMemory {
+		return
+	}
+	for i := range patches {
+		if patches[i].Namespace != namespace {
+			continue
+		}
+		changed := false
+		for j := range patches[i].Containers {
+			c := &patches[i].Containers[j]
+			current, suggested := c.current(index), c.value(index)
+			if suggested <= current {
+				continue
+			}
+			c.setValue(index, current+int64(float64(suggested-current)*factor))
+			// Requests não podem ultrapassar os limites
+			if c.LimitCPU > 0 && c.RequestCPU > c.LimitCPU {
+				c.RequestCPU = c.LimitCPU
+			}
+			if c.LimitMemory > 0 && c.RequestMemory > c.LimitMemory {
+				c.RequestMemory = c.LimitMemory
+			}
+			changed = true
+		}
+		if changed {
+			patches[i].Notes = append(patches[i].Notes, note)
+		}
+	}
+}
+
+func annotateNamespace(patches []ResourcePatch, namespace, note string) {
+	for i := range patches {
+		if patches[i].Namespace == namespace {
+			patches[i].Notes = append(patches[i].Notes, note)
+		}
+	}
+}
+
+// formatQuotaValue formats a quota value as CPU or memory in the output units
+func formatQuotaValue(name corev1.ResourceName, v int64) string {
+	switch name {
+	case corev1.ResourceCPU, corev1.ResourceRequestsCPU, corev1.ResourceLimitsCPU:
+		return formatCPU(v)
+	default:
+		return formatMemory(v)
+	}
+}
+
+func writeQuotaValidation(w io.Writer, validation *QuotaValidation) {
+	fmt.Fprintf(w, "\n=== Validação de Quotas e Capacidade ===\n")
+	fmt.Fprintf(w, "----------------------------------------\n")
+
+	fmt.Fprintf(w, "Requests projetados do cluster: CPU %s/%s, Memory %s/%s\n",
+		formatCPU(validation.ProjectedCPU), formatCPU(validation.ClusterCPU), formatMemory(validation.ProjectedMemory), formatMemory(validation.ClusterMemory))
+	if validation.ClusterOvercommit {
+		fmt.Fprintf(w, "⚠️  Os requests projetados excedem o allocatable do cluster; os aumentos foram anotados nos patches\n")
+	}
+
+	if len(validation.Unchecked) > 0 {
+		fmt.Fprintf(w, "⚠️  ResourceQuotas não verificadas (erro ao listar): %s\n", strings.Join(validation.Unchecked, ", "))
+	}
+
+	if len(validation.Violations) == 0 {
+		if len(validation.Unchecked) > 0 {
+			fmt.Fprintf(w, "As recomendações dos demais namespaces cabem nas ResourceQuotas\n")
+			return
+		}
+		fmt.Fprintf(w, "Todas as recomendações cabem nas ResourceQuotas dos namespaces\n")
+		return
+	}
+
+	for _, v := range validation.Violations {
+		fmt.Fprintf(w, "- %s (ResourceQuota %s): %s projetado %s, limite %s",
+			v.Namespace, v.Quota, v.Resource, formatQuotaValue(v.Resource, v.Projected), formatQuotaValue(v.Resource, v.Hard))
+		if v.Downscaled {
+			fmt.Fprintf(w, " - aumentos reduzidos para caber na quota\n")
+		} else if v.Resource == corev1.ResourceLimitsCPU || v.Resource == corev1.ResourceLimitsMemory {
+			fmt.Fprintf(w, " - limites não reduzidos para não ficarem abaixo do pico observado; revise a quota\n")
+		} else {
+			fmt.Fprintf(w, " - excede mesmo sem os aumentos; revise a quota\n")
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Formatos do arquivo de amostras brutas
+const (
+	rawSamplesJSONL   = "jsonl"
+	rawSamplesParquet = "parquet"
+)
+
+// Linhas acumuladas antes de gravar um row group no Parquet
+const parquetRowGroupRows = 100000
+
+// RawSample is one reading of a container, a node or the whole cluster, as written to the raw samples file
+type RawSample struct {
+	Time    time.Time `json:"time"`
+	Cluster string    `json:"cluster"`
+	// container, node ou cluster
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+	Node      string `json:"node,omitempty"`
+	CPU       int64  `json:"cpu_millis"`
+	Memory    int64  `json:"memory_bytes"`
+}
+
+// rawSampleColumns is the schema of the Parquet file, in the order of the fields of RawSample
+var rawSampleColumns = []parquetColumn{
+	{Name: "time", Timestamp: true},
+	{Name: "cluster", String: true},
+	{Name: "kind", String: true},
+	{Name: "namespace", String: true},
+	{Name: "pod", String: true},
+	{Name: "container", String: true},
+	{Name: "node", String: true},
+	{Name: "cpu_millis"},
+	{Name: "memory_bytes"},
+}
+
+// RawSampleWriter writes every reading of the collection to a file, one row per container, node and
+// cluster total, for analysis outside the analyzer (jq, DuckDB, Spark, pandas)
+type RawSampleWriter struct {
+	format  string
+	cluster string
+	file    *os.File
+	lines   *bufio.Writer
+	parquet *parquetWriter
+	// Linhas do row group em construção (Parquet)
+	rows []RawSample
+	// Total de linhas gravadas
+	Rows int
+}
+
+func validateRawSamplesFormat(format string) error {
+	switch format {
+	case "", rawSamplesJSONL, rawSamplesParquet:
+		return nil
+	}
+	return fmt.Errorf("formato de amostras brutas inválido: %s (use jsonl ou parquet)", format)
+}
+
+// newRawSampleWriter creates the file in the format; an empty format disables the raw samples
+func newRawSampleWriter(format, path, cluster string) (*RawSampleWriter, error) {
+	if format == "" {
+		return nil, nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar arquivo de amostras brutas: %v", err)
+	}
+	w := &RawSampleWriter{format: format, cluster: cluster, file: file}
+	if format == rawSamplesParquet {
+		if w.parquet, err = newParquetWriter(file, rawSampleColumns); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("erro ao escrever amostras brutas: %v", err)
+		}
+	} else {
+		w.lines = bufio.NewWriter(file)
+	}
+	return w, nil
+}
+
+// write adds the readings of one collection iteration, including the cluster total
+func (w *RawSampleWriter) write(points []SamplePoint, total UsageSample) error {
+	samples := make([]RawSample, 0, len(points)+1)
+	for _, p := range points {
+		sample := RawSample{Time: p.Time, Cluster: w.cluster, Kind: "container", Namespace: p.Namespace, Pod: p.Pod,
+			Container: p.Container, Node: p.Node, CPU: p.CPU, Memory: p.Memory}
+		if p.Pod == "" {
+			sample.Kind = "node"
+		}
+		samples = append(samples, sample)
+	}
+	samples = append(samples, RawSample{Time: total.Time, Cluster: w.cluster, Kind: "cluster", CPU: total.CPU, Memory: total.Memory})
+	w.Rows += len(samples)
+
+	if w.parquet != nil {
+		w.rows = append(w.rows, samples...)
+		if len(w.rows) >= parquetRowGroupRows {
+			return w.flush()
+		}
+		return nil
+	}
+	for _, sample := range samples {
+		data, err := json.Marshal(sample)
+		if err != nil {
+			return fmt.Errorf("erro ao serializar amostra: %v", err)
+		}
+		w.lines.Write(data)
+		if err := w.lines.WriteByte('\n'); err != nil {
+			return fmt.Errorf("erro ao escrever amostras brutas: %v", err)
+		}
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a Parquet row group
+func (w *RawSampleWriter) flush() error {
+	if len(w.rows) == 0 {
+		return nil
+	}
+	columns := make([]parquetColumn, len(rawSampleColumns))
+	copy(columns, rawSampleColumns)
+	for i := range columns {
+		if columns[i].String {
+			columns[i].Strings = make([]string, 0, len(w.rows))
+		} else {
+			columns[i].Int64s = make([]int64, 0, len(w.rows))
+		}
+	}
+	for _, r := range w.rows {
+		columns[0].Int64s = append(columns[0].Int64s, r.Time.UnixMicro())
+		for i, value := range []string{r.Cluster, r.Kind, r.Namespace, r.Pod, r.Container, r.Node} {
+			columns[i+1].Strings = append(columns[i+1].Strings, value)
+		}
+		columns[7].Int64s = append(columns[7].Int64s, r.CPU)
+		columns[8].Int64s = append(columns[8].Int64s, r.Memory)
+	}
+	rows := len(w.rows)
+	w.rows = w.rows[:0]
+	if err := w.parquet.writeRowGroup(columns, rows); err != nil {
+		return fmt.Errorf("erro ao escrever amostras brutas: %v", err)
+	}
+	return nil
+}
+
+// close writes the pending rows and the footer of the file
+func (w *RawSampleWriter) close() error {
+	var err error
+	if w.parquet != nil {
+		if err = w.flush(); err == nil {
+			if err = w.parquet.close(); err != nil {
+				err = fmt.Errorf("erro ao escrever amostras brutas: %v", err)
+			}
+		}
+	} else if err = w.lines.Flush(); err != nil {
+		err = fmt.Errorf("erro ao escrever amostras brutas: %v", err)
+	}
+	if closeErr := w.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("erro ao fechar arquivo de amostras brutas: %v", closeErr)
+	}
+	return err
+}
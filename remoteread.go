@@ -0,0 +1,482 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Parâmetros da importação do histórico via remote-read: um registro por dia, com leituras agregadas
+// em passos de 5 minutos, como as consultas de rate do Prometheus
+const (
+	defaultImportRange = 28 * 24 * time.Hour
+	importDay          = 24 * time.Hour
+	importStep         = 5 * time.Minute
+)
+
+// Tipos de matcher do protocolo remote-read
+const (
+	matchEqual    = 0
+	matchNotEqual = 1
+)
+
+// Limite de tamanho de uma resposta descomprimida do remote-read
+const maxRemoteReadSize = 1 << 30
+
+var remoteReadHTTPClient = &http.Client{Timeout: 5 * time.Minute}
+
+// remoteMatcher is a label matcher of a remote-read query
+type remoteMatcher struct {
+	kind  uint64
+	name  string
+	value string
+}
+
+// remoteSample is a raw sample returned by remote-read
+type remoteSample struct {
+	Time  int64 // milissegundos
+	Value float64
+}
+
+// remoteSeries is a raw time series returned by remote-read
+type remoteSeries struct {
+	Labels  map[string]string
+	Samples []remoteSample
+}
+
+// Séries lidas na importação: uso por container e uso total de cada node (cgroup raiz)
+var (
+	importContainerFilter = []remoteMatcher{{matchNotEqual, "container", ""}, {matchNotEqual, "container", "POD"}}
+	importNodeFilter      = []remoteMatcher{{matchEqual, "id", "/"}}
+)
+
+// encodeReadRequest encodes a ReadRequest with a single query (prometheus/prompb/remote.proto)
+func encodeReadRequest(start, end time.Time, matchers []remoteMatcher) []byte {
+	var query []byte
+	query = protowire.AppendTag(query, 1, protowire.VarintType)
+	query = protowire.AppendVarint(query, uint64(start.UnixMilli()))
+	query = protowire.AppendTag(query, 2, protowire.VarintType)
+	query = protowire.AppendVarint(query, uint64(end.UnixMilli()))
+	for _, m := range matchers {
+		var matcher []byte
+		matcher = protowire.AppendTag(matcher, 1, protowire.VarintType)
+		matcher = protowire.AppendVarint(matcher, m.kind)
+		matcher = protowire.AppendTag(matcher, 2, protowire.BytesType)
+		matcher = protowire.AppendString(matcher, m.name)
+		matcher = protowire.AppendTag(matcher, 3, protowire.BytesType)
+		matcher = protowire.AppendString(matcher, m.value)
+		query = protowire.AppendTag(query, 3, protowire.BytesType)
+		query = protowire.AppendBytes(query, matcher)
+	}
+	var request []byte
+	request = protowire.AppendTag(request, 1, protowire.BytesType)
+	return protowire.AppendBytes(request, query)
+}
+
+// protoFields calls fn for each field of a protobuf message, with the raw bytes of length-delimited
+// fields and the value of varint and fixed64 fields
+func protoFields(b []byte, fn func(num protowire.Number, bytes []byte, value uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var raw []byte
+		var value uint64
+		switch typ {
+		case protowire.BytesType:
+			raw, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			value, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			value, n = protowire.ConsumeFixed64(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(num, raw, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeReadResponse decodes the time series of a ReadResponse
+func decodeReadResponse(b []byte) ([]remoteSeries, error) {
+	var series []remoteSeries
+	err := protoFields(b, func(num protowire.Number, result []byte, _ uint64) error {
+		if num != 1 {
+			return nil
+		}
+		return protoFields(result, func(num protowire.Number, ts []byte, _ uint64) error {
+			if num != 1 {
+				return nil
+			}
+			s := remoteSeries{Labels: make(map[string]string)}
+			err := protoFields(ts, func(num protowire.Number, field []byte, _ uint64) error {
+				switch num {
+				case 1:
+					var name, value string
+					err := protoFields(field, func(num protowire.Number, raw []byte, _ uint64) error {
+						switch num {
+						case 1:
+							name = string(raw)
+						case 2:
+							value = string(raw)
+						}
+						return nil
+					})
+					s.Labels[name] = value
+					return err
+				case 2:
+					var sample remoteSample
+					err := protoFields(field, func(num protowire.Number, _ []byte, value uint64) error {
+						switch num {
+						case 1:
+							sample.Value = math.Float64frombits(value)
+						case 2:
+							sample.Time = int64(value)
+						}
+						return nil
+					})
+					s.Samples = append(s.Samples, sample)
+					return err
+				}
+				return nil
+			})
+			series = append(series, s)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao decodificar resposta do remote-read: %v", err)
+	}
+	return series, nil
+}
+
+// snappyEncode compresses in the snappy block format using only literals: valid for any decoder and
+// enough for the small read requests
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		chunk := src
+		if len(chunk) > 65536 {
+			chunk = chunk[:65536]
+		}
+		n := len(chunk) - 1
+		switch {
+		case n < 60:
+			dst = append(dst, byte(n)<<2)
+		case n < 1<<8:
+			dst = append(dst, 60<<2, byte(n))
+		default:
+			dst = append(dst, 61<<2, byte(n), byte(n>>8))
+		}
+		dst = append(dst, chunk...)
+		src = src[len(chunk):]
+	}
+	return dst
+}
+
+// snappyDecode decompresses a snappy block (literals and copies)
+func snappyDecode(src []byte) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 || length > maxRemoteReadSize {
+		return nil, fmt.Errorf("bloco snappy inválido")
+	}
+	src = src[n:]
+	dst := make([]byte, 0, length)
+	for len(src) > 0 {
+		tag := src[0]
+		var size, offset int
+		switch tag & 3 {
+		case 0:
+			size = int(tag >> 2)
+			src = src[1:]
+			if size >= 60 {
+				extra := size - 59
+				if len(src) < extra {
+					return nil, fmt.Errorf("bloco snappy truncado")
+				}
+				size = 0
+				for i := extra - 1; i >= 0; i-- {
+					size = size<<8 | int(src[i])
+				}
+				src = src[extra:]
+			}
+			size++
+			if size > len(src) {
+				return nil, fmt.Errorf("bloco snappy truncado")
+			}
+			dst = append(dst, src[:size]...)
+			src = src[size:]
+			continue
+		case 1:
+			if len(src) < 2 {
+				return nil, fmt.Errorf("bloco snappy truncado")
+			}
+			size = 4 + int(tag>>2)&7
+			offset = int(tag&0xe0)<<3 | int(src[1])
+			src = src[2:]
+		case 2:
+			if len(src) < 3 {
+				return nil, fmt.Errorf("bloco snappy truncado")
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+		case 3:
+			if len(src) < 5 {
+				return nil, fmt.Errorf("bloco snappy truncado")
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+		if offset <= 0 || offset > len(dst) {
+			return nil, fmt.Errorf("bloco snappy inválido")
+		}
+		// A cópia pode sobrepor o próprio trecho copiado
+		start := len(dst) - offset
+		for i := 0; i < size; i++ {
+			dst = append(dst, dst[start+i])
+		}
+	}
+	if uint64(len(dst)) != length {
+		return nil, fmt.Errorf("bloco snappy com tamanho inesperado")
+	}
+	return dst, nil
+}
+
+// remoteRead fetches the raw samples of the series that match the query from a remote-read endpoint
+// (Prometheus /api/v1/read, Thanos, Cortex, Mimir, VictoriaMetrics)
+func remoteRead(readURL string, start, end time.Time, matchers []remoteMatcher) ([]remoteSeries, error) {
+	body := snappyEncode(encodeReadRequest(start, end, matchers))
+	req, err := http.NewRequest(http.MethodPost, readURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Read-Version", "0.1.0")
+
+	resp, err := remoteReadHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar o remote-read: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteReadSize))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler resposta do remote-read: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("erro no remote-read (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	data, err = snappyDecode(data)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao descomprimir resposta do remote-read: %v", err)
+	}
+	return decodeReadResponse(data)
+}
+
+// stepUsage reduces raw series to one value per step and key: the rate of counters (CPU) or the
+// maximum of gauges (memory), summed across the series of the same key
+func stepUsage(series []remoteSeries, start time.Time, counter bool, key func(labels map[string]string) string) map[string]map[int64]float64 {
+	usage := make(map[string]map[int64]float64)
+	stepMillis := importStep.Milliseconds()
+	for _, s := range series {
+		k := key(s.Labels)
+		if k == "" {
+			continue
+		}
+		values := make(map[int64]float64)
+		if counter {
+			increase := make(map[int64]float64)
+			elapsed := make(map[int64]float64)
+			for i := 1; i < len(s.Samples); i++ {
+				prev, cur := s.Samples[i-1], s.Samples[i]
+				delta := cur.Value - prev.Value
+				if delta < 0 {
+					// Contador reiniciado
+					delta = cur.Value
+				}
+				step := (cur.Time - start.UnixMilli()) / stepMillis
+				increase[step] += delta
+				elapsed[step] += float64(cur.Time-prev.Time) / 1000
+			}
+			for step, inc := range increase {
+				if elapsed[step] > 0 {
+					values[step] = inc / elapsed[step]
+				}
+			}
+		} else {
+			for _, sample := range s.Samples {
+				step := (sample.Time - start.UnixMilli()) / stepMillis
+				values[step] = max(values[step], sample.Value)
+			}
+		}
+		if usage[k] == nil {
+			usage[k] = make(map[int64]float64)
+		}
+		for step, v := range values {
+			usage[k][step] += v
+		}
+	}
+	return usage
+}
+
+// deploymentForPod guesses the deployment of a pod from its name (<deployment>-<hash>-<sufixo>), keeping
+// only deployments that still exist in the cluster
+func deploymentForPod(namespace, pod string, deployments map[string]bool) string {
+	parts := strings.Split(pod, "-")
+	if len(parts) < 3 {
+		return ""
+	}
+	key := namespace + "/" + strings.Join(parts[:len(parts)-2], "-")
+	if !deployments[key] {
+		return ""
+	}
+	return key
+}
+
+// importDayRecord reads one day of history and summarizes it like a run of the analyzer
+func importDayRecord(readURL string, start time.Time, deployments map[string]bool) (*HistoryRecord, error) {
+	end := start.Add(importDay)
+	read := func(metric string, filter []remoteMatcher) ([]remoteSeries, error) {
+		matchers := append([]remoteMatcher{{matchEqual, "__name__", metric}}, filter...)
+		return remoteRead(readURL, start, end, matchers)
+	}
+	containerKey := func(labels map[string]string) string {
+		deployment := deploymentForPod(labels["namespace"], labels["pod"], deployments)
+		if deployment == "" {
+			return ""
+		}
+		return deployment + "/" + labels["pod"] + "/" + labels["container"]
+	}
+	clusterKey := func(map[string]string) string { return "cluster" }
+
+	record := &HistoryRecord{
+		Timestamp:   end,
+		Period:      importDay.String(),
+		Deployments: make(map[string]DeploymentUsage),
+	}
+
+	// Total do cluster: soma do uso dos nodes em cada passo
+	nodeCPU, err := read("container_cpu_usage_seconds_total", importNodeFilter)
+	if err != nil {
+		return nil, err
+	}
+	nodeMemory, err := read("container_memory_working_set_bytes", importNodeFilter)
+	if err != nil {
+		return nil, err
+	}
+	var samples []UsageSample
+	cpuSteps := stepUsage(nodeCPU, start, true, clusterKey)["cluster"]
+	memorySteps := stepUsage(nodeMemory, start, false, clusterKey)["cluster"]
+	for step, cpu := range cpuSteps {
+		memory, exists := memorySteps[step]
+		if !exists {
+			continue
+		}
+		samples = append(samples, UsageSample{CPU: int64(cpu * 1000), Memory: int64(memory)})
+	}
+	if len(samples) > 0 {
+		record.AvgCPU, record.AvgMemory, record.MaxCPU, record.MaxMemory = averageSamples(samples)
+	}
+
+	// Por deployment: pico de cada container, como nas execuções do analisador
+	containerCPU, err := read("container_cpu_usage_seconds_total", importContainerFilter)
+	if err != nil {
+		return nil, err
+	}
+	containerMemory, err := read("container_memory_working_set_bytes", importContainerFilter)
+	if err != nil {
+		return nil, err
+	}
+	peaks := make(map[string][2]int64)
+	for k, steps := range stepUsage(containerCPU, start, true, containerKey) {
+		for _, cpu := range steps {
+			p := peaks[k]
+			p[0] = max(p[0], int64(cpu*1000))
+			peaks[k] = p
+		}
+	}
+	for k, steps := range stepUsage(containerMemory, start, false, containerKey) {
+		for _, memory := range steps {
+			p := peaks[k]
+			p[1] = max(p[1], int64(memory))
+			peaks[k] = p
+		}
+	}
+	totals := make(map[string][3]int64)
+	for k, p := range peaks {
+		parts := strings.SplitN(k, "/", 3)
+		deployment := parts[0] + "/" + parts[1]
+		usage := record.Deployments[deployment]
+		usage.MaxCPU = max(usage.MaxCPU, p[0])
+		usage.MaxMemory = max(usage.MaxMemory, p[1])
+		record.Deployments[deployment] = usage
+		t := totals[deployment]
+		t[0] += p[0]
+		t[1] += p[1]
+		t[2]++
+		totals[deployment] = t
+	}
+	for deployment, t := range totals {
+		usage := record.Deployments[deployment]
+		usage.AvgCPU = t[0] / t[2]
+		usage.AvgMemory = t[1] / t[2]
+		record.Deployments[deployment] = usage
+	}
+
+	if len(samples) == 0 && len(record.Deployments) == 0 {
+		return nil, nil
+	}
+	return record, nil
+}
+
+// importHistory reads the history available through remote-read, one day at a time, up to the first
+// record already in the history (or now), so the forecasts have data from the first run
+func importHistory(clientset *kubernetes.Clientset, readURL string, importRange time.Duration, until time.Time, location *time.Location) ([]HistoryRecord, error) {
+	list, err := clientset.AppsV1().Deployments("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar deployments: %v", err)
+	}
+	deployments := make(map[string]bool, len(list.Items))
+	for _, d := range list.Items {
+		deployments[d.Namespace+"/"+d.Name] = true
+	}
+
+	var records []HistoryRecord
+	days := int(importRange / importDay)
+	for i := days; i > 0; i-- {
+		start := until.Add(-time.Duration(i) * importDay)
+		fmt.Printf("   Importando %s...\n", start.In(location).Format("2006-01-02"))
+		record, err := importDayRecord(readURL, start, deployments)
+		if err != nil {
+			return records, err
+		}
+		if record == nil {
+			continue
+		}
+		record.Timestamp = record.Timestamp.In(location)
+		records = append(records, *record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Timestamp.Before(records[j].Timestamp) })
+	return records, nil
+}
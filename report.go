@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ReportSchemaVersion is the version of the JSON report. The minor version grows when fields are added;
+// the major version only when fields are removed or change meaning
+const ReportSchemaVersion = "1.0"
+
+// FindingCategory is the machine-readable classification of a finding kind. Codes never change once
+// published, even if the kind or the title of the finding are reworded
+type FindingCategory struct {
+	Code     string `json:"code"`
+	Category string `json:"category"`
+}
+
+// Categorias dos tipos de problema; novos tipos recebem o próximo código livre da categoria
+var findingCategories = map[string]FindingCategory{
+	"sem-limites":            {"KPA-RES-001", "recursos"},
+	"memoria-no-limite":      {"KPA-RES-002", "recursos"},
+	"nao-agendavel":          {"KPA-RES-003", "recursos"},
+	"pico-semanal":           {"KPA-RES-004", "recursos"},
+	"ocioso":                 {"KPA-RES-005", "recursos"},
+	"init-container":         {"KPA-RES-006", "recursos"},
+	"recurso-estendido":      {"KPA-RES-007", "recursos"},
+	"pids":                   {"KPA-RES-008", "recursos"},
+	"descritores":            {"KPA-RES-009", "recursos"},
+	"cpu-request-minimo":     {"KPA-RES-010", "recursos"},
+	"memoria-runtime":        {"KPA-RES-011", "recursos"},
+	"despejo":                {"KPA-STB-001", "estabilidade"},
+	"reinicio":               {"KPA-STB-002", "estabilidade"},
+	"preempcao":              {"KPA-STB-003", "estabilidade"},
+	"pendente":               {"KPA-STB-004", "estabilidade"},
+	"pull-imagem":            {"KPA-STB-005", "estabilidade"},
+	"pull-lento":             {"KPA-STB-006", "estabilidade"},
+	"replica-unica":          {"KPA-STB-007", "estabilidade"},
+	"node-sobrecomprometido": {"KPA-NOD-001", "nodes"},
+	"node-instavel":          {"KPA-NOD-002", "nodes"},
+	"node-pids":              {"KPA-NOD-003", "nodes"},
+	"node-rotatividade":      {"KPA-NOD-004", "nodes"},
+	"node-versao":            {"KPA-NOD-005", "nodes"},
+	"hpa-sem-requests":       {"KPA-ASC-001", "autoscaling"},
+	"hpa-instavel":           {"KPA-ASC-002", "autoscaling"},
+	"keda-sem-requests":      {"KPA-ASC-003", "autoscaling"},
+	"addon-subdimensionado":  {"KPA-PLT-001", "plataforma"},
+	"control-plane":          {"KPA-PLT-002", "plataforma"},
+	"prioridade":             {"KPA-PLT-003", "plataforma"},
+	"skew-kubelet":           {"KPA-PLT-004", "plataforma"},
+	"api-obsoleta":           {"KPA-PLT-005", "plataforma"},
+	"orcamento":              {"KPA-CST-001", "custos"},
+	"volume-cheio":           {"KPA-STO-001", "storage"},
+	"higiene-namespace":      {"KPA-HYG-001", "higiene"},
+}
+
+// category returns the category of the finding. The node conditions of the watch mode
+// ("node-<condição>") and the violations of the policies of the configuration share a code; unknown
+// kinds fall in the generic category
+func (f Finding) category() FindingCategory {
+	if c, exists := findingCategories[f.Kind]; exists {
+		return c
+	}
+	if strings.HasPrefix(f.Kind, "node-") {
+		return FindingCategory{"KPA-NOD-100", "nodes"}
+	}
+	if strings.HasPrefix(f.Kind, "politica-") {
+		return FindingCategory{"KPA-POL-001", "politicas"}
+	}
+	return FindingCategory{"KPA-GEN-000", "geral"}
+}
+
+// id is the stable identifier of the finding: the category code and a hash of the kind, the namespace
+// and the workload. It stays the same across runs and is independent of the title and the text
+func (f Finding) id() string {
+	sum := sha256.Sum256([]byte(f.key()))
+	return f.category().Code + "-" + hex.EncodeToString(sum[:])[:12]
+}
+
+// Nomes estáveis das severidades no JSON, independentes do idioma do relatório
+var severityCodes = []string{"low", "medium", "high", "critical"}
+
+// ReportFinding is a finding in the JSON report
+type ReportFinding struct {
+	// Identificador estável entre execuções (ex: KPA-RES-001-3f2a9c1b7d4e)
+	ID       string `json:"id"`
+	Code     string `json:"code"`
+	Category string `json:"category"`
+	// Tipo interno do problema, também usado nos ids das supressões (tipo/namespace/workload)
+	Kind string `json:"kind"`
+	// low, medium, high ou critical
+	Severity  string `json:"severity"`
+	Title     string `json:"title"`
+	Namespace string `json:"namespace,omitempty"`
+	Workload  string `json:"workload,omitempty"`
+	Owner     string `json:"owner,omitempty"`
+	Body      string `json:"body"`
+}
+
+// ReportSummary holds the totals of the run in the JSON report
+type ReportSummary struct {
+	Deployments    int     `json:"deployments"`
+	HealthScore    int     `json:"health_score"`
+	WasteCPUCores  float64 `json:"waste_cpu_cores"`
+	WasteMemoryGiB float64 `json:"waste_memory_gib"`
+	Risks          int     `json:"risks"`
+	// Problemas por severidade (low, medium, high, critical)
+	Findings map[string]int `json:"findings"`
+}
+
+// Report is the JSON report of a run, meant for integrations: the fields are stable within a major
+// schema version, unlike the wording of the text report
+type Report struct {
+	SchemaVersion   string          `json:"schema_version"`
+	Cluster         string          `json:"cluster"`
+	GeneratedAt     time.Time       `json:"generated_at"`
+	Summary         ReportSummary   `json:"summary"`
+	Findings        []ReportFinding `json:"findings"`
+	Recommendations []ResourcePatch `json:"recommendations"`
+}
+
+func newReport(summary RunSummary, findings []Finding, patches []ResourcePatch) Report {
+	report := Report{
+		SchemaVersion: ReportSchemaVersion,
+		Cluster:       summary.Cluster,
+		GeneratedAt:   summary.Finished,
+		Summary: ReportSummary{
+			Deployments:    summary.Deployments,
+			HealthScore:    summary.HealthScore,
+			WasteCPUCores:  summary.WasteCPUCores,
+			WasteMemoryGiB: summary.WasteMemoryGiB,
+			Risks:          summary.Risks,
+			Findings:       make(map[string]int),
+		},
+		Findings:        make([]ReportFinding, 0, len(findings)),
+		Recommendations: patches,
+	}
+	for severity := SeverityLow; severity <= SeverityCritical; severity++ {
+		report.Summary.Findings[severityCodes[severity]] = summary.Findings[severity]
+	}
+	for _, f := range findings {
+		category := f.category()
+		report.Findings = append(report.Findings, ReportFinding{
+			ID:        f.id(),
+			Code:      category.Code,
+			Category:  category.Category,
+			Kind:      f.Kind,
+			Severity:  severityCodes[f.Severity],
+			Title:     f.Title,
+			Namespace: f.Namespace,
+			Workload:  f.Workload,
+			Owner:     f.Owner,
+			Body:      f.Body,
+		})
+	}
+	if report.Recommendations == nil {
+		report.Recommendations = []ResourcePatch{}
+	}
+	return report
+}
+
+// writeJSONReport writes the report, anonymizing the names when an anonymizer is given
+func writeJSONReport(path string, report Report, anonymizer *Anonymizer) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao gerar relatório JSON: %v", err)
+	}
+	if anonymizer != nil {
+		data = []byte(anonymizer.Anonymize(string(data)))
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("erro ao escrever relatório JSON: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// IndexRun is a run listed in the HTML index, with the changes since the previous run of the cluster
+type IndexRun struct {
+	Record HistoryRecord
+	// Relatório da execução, quando ainda existe no diretório
+	Report string
+	// Alterações em relação à execução anterior do mesmo cluster
+	HealthDelta    *int
+	CPUDeltaPct    *float64
+	MemoryDeltaPct *float64
+	SevereDelta    *int
+}
+
+// IndexCluster groups the runs of a cluster, most recent first
+type IndexCluster struct {
+	Context string
+	Runs    []IndexRun
+}
+
+// severeFindings returns the high and critical findings of a record
+func severeFindings(record HistoryRecord) (int, bool) {
+	if record.Findings == nil {
+		return 0, false
+	}
+	return record.Findings[SeverityHigh.String()] + record.Findings[SeverityCritical.String()], true
+}
+
+// changePct returns the relative change between two values, if the previous one is known
+func changePct(previous, current int64) *float64 {
+	if previous <= 0 {
+		return nil
+	}
+	pct := float64(current-previous) / float64(previous) * 100
+	return &pct
+}
+
+// indexRuns computes the deltas between consecutive runs of a cluster
+func indexRuns(reportDir string, records []HistoryRecord) []IndexRun {
+	sort.Slice(records, func(i, j int) bool { return records[i].Timestamp.Before(records[j].Timestamp) })
+	runs := make([]IndexRun, 0, len(records))
+	for i, record := range records {
+		run := IndexRun{Record: record}
+		if record.Report != "" {
+			if _, err := os.Stat(filepath.Join(reportDir, record.Report)); err == nil {
+				run.Report = record.Report
+			}
+		}
+		if i > 0 {
+			previous := records[i-1]
+			if record.HealthScore != nil && previous.HealthScore != nil {
+				delta := *record.HealthScore - *previous.HealthScore
+				run.HealthDelta = &delta
+			}
+			run.CPUDeltaPct = changePct(previous.AvgCPU, record.AvgCPU)
+			run.MemoryDeltaPct = changePct(previous.AvgMemory, record.AvgMemory)
+			if severe, ok := severeFindings(record); ok {
+				if previousSevere, ok := severeFindings(previous); ok {
+					delta := severe - previousSevere
+					run.SevereDelta = &delta
+				}
+			}
+		}
+		runs = append(runs, run)
+	}
+	// Mais recentes primeiro
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+	return runs
+}
+
+var reportIndexTemplate = template.Must(template.New("index").Funcs(template.FuncMap{
+	"signed": func(v interface{}) string {
+		switch n := v.(type) {
+		case *int:
+			if n == nil {
+				return ""
+			}
+			return fmt.Sprintf("%+d", *n)
+		case *float64:
+			if n == nil {
+				return ""
+			}
+			return fmt.Sprintf("%+.1f%%", *n)
+		}
+		return ""
+	},
+	"severe": func(record HistoryRecord) string {
+		if severe, ok := severeFindings(record); ok {
+			return fmt.Sprint(severe)
+		}
+		return "—"
+	},
+	"cores": func(millis int64) string { return fmt.Sprintf("%.1f", float64(millis)/1000) },
+	"gib":   func(bytes int64) string { return fmt.Sprintf("%.1f", float64(bytes)/(1024*1024*1024)) },
+}).Parse(`<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+<meta charset="utf-8">
+<title>Relatórios de performance do Kubernetes</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: right; }
+th { background: #f0f0f0; }
+td:first-child, th:first-child { text-align: left; }
+.delta { color: #666; font-size: 0.85em; margin-left: 4px; }
+</style>
+</head>
+<body>
+<h1>Relatórios de performance do Kubernetes</h1>
+{{range .}}
+<h2>{{.Context}}</h2>
+<table>
+<tr><th>Data</th><th>Período</th><th>Saúde</th><th>Problemas altos/críticos</th><th>CPU média (cores)</th><th>Memória média (GiB)</th><th>Requests liberáveis</th><th>Relatório</th></tr>
+{{range .Runs}}<tr>
+<td>{{.Record.Timestamp.Format "2006-01-02 15:04"}}</td>
+<td>{{.Record.Period}}</td>
+<td>{{with .Record.HealthScore}}{{.}}{{else}}—{{end}}<span class="delta">{{signed .HealthDelta}}</span></td>
+<td>{{severe .Record}}<span class="delta">{{signed .SevereDelta}}</span></td>
+<td>{{cores .Record.AvgCPU}}<span class="delta">{{signed .CPUDeltaPct}}</span></td>
+<td>{{gib .Record.AvgMemory}}<span class="delta">{{signed .MemoryDeltaPct}}</span></td>
+<td>{{if .Record.HealthScore}}{{printf "%.1f cores, %.1f GiB" .Record.WasteCPUCores .Record.WasteMemoryGiB}}{{else}}—{{end}}</td>
+<td>{{if .Report}}<a href="{{.Report}}">{{.Report}}</a>{{else}}—{{end}}</td>
+</tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// writeReportIndex regenerates index.html in the reports directory from the history files of every
+// cluster, so the directory can be browsed as a history of runs
+func writeReportIndex(reportDir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(reportDir, "history-*.jsonl"))
+	if err != nil {
+		return "", fmt.Errorf("erro ao listar históricos: %v", err)
+	}
+	var clusters []IndexCluster
+	for _, file := range files {
+		records, err := loadHistory(file)
+		if err != nil {
+			return "", err
+		}
+		if len(records) == 0 {
+			continue
+		}
+		clusters = append(clusters, IndexCluster{Context: records[len(records)-1].Context, Runs: indexRuns(reportDir, records)})
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Context < clusters[j].Context })
+
+	path := filepath.Join(reportDir, "index.html")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar índice dos relatórios: %v", err)
+	}
+	defer f.Close()
+	if err := reportIndexTemplate.Execute(f, clusters); err != nil {
+		return "", fmt.Errorf("erro ao gerar índice dos relatórios: %v", err)
+	}
+	return path, nil
+}
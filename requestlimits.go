@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	kyvernoClusterPolicyResource = schema.GroupVersionResource{Group: "kyverno.io", Version: "v1", Resource: "clusterpolicies"}
+	kyvernoPolicyResource        = schema.GroupVersionResource{Group: "kyverno.io", Version: "v1", Resource: "policies"}
+	// Constraint da biblioteca do Gatekeeper que limita a razão limits/requests
+	gatekeeperContainerRatiosResource = schema.GroupVersionResource{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Resource: "k8scontainerratios"}
+)
+
+// Referência a resources.requests.<recurso> ou resources.limits.<recurso> em uma variável do Kyverno
+// ({{ }}), usada para exigir que um valor seja igual ao outro
+var kyvernoResourceReference = regexp.MustCompile(`\{\{[^}]*resources\.(?:requests|limits)\.(cpu|memory)[^}]*\}\}`)
+
+// RequestLimitPolicy is a LimitRange or an admission policy that forces the requests of the containers
+// to be equal to their limits
+type RequestLimitPolicy struct {
+	// Ex: "LimitRange app/limits", "Kyverno ClusterPolicy require-equal"
+	Source string
+	// Namespaces em que a política se aplica (padrões do path.Match); vazio = todos
+	Namespaces []string
+	Excluded   []string
+	CPU        bool
+	Memory     bool
+}
+
+func (p *RequestLimitPolicy) appliesTo(namespace string) bool {
+	for _, pattern := range p.Excluded {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return false
+		}
+	}
+	if len(p.Namespaces) == 0 {
+		return true
+	}
+	for _, pattern := range p.Namespaces {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestLimitPolicies are the policies found in the cluster
+type RequestLimitPolicies []RequestLimitPolicy
+
+// forNamespace returns which resources must have requests equal to limits in the namespace and the
+// policies that force them
+func (p RequestLimitPolicies) forNamespace(namespace string) (bool, bool, []string) {
+	var cpu, memory bool
+	var sources []string
+	for i := range p {
+		if !p[i].appliesTo(namespace) {
+			continue
+		}
+		cpu = cpu || p[i].CPU
+		memory = memory || p[i].Memory
+		sources = append(sources, p[i].Source)
+	}
+	return cpu, memory, sources
+}
+
+// ratioForcesEquality reports whether a maximum limit/request ratio only admits limits equal to requests
+func ratioForcesEquality(ratio resource.Quantity) bool {
+	return !ratio.IsZero() && ratio.Cmp(*resource.NewQuantity(1, resource.DecimalSI)) <= 0
+}
+
+// listLimitRangePolicies returns the LimitRanges whose maxLimitRequestRatio of the containers is 1
+func listLimitRangePolicies(clientset *kubernetes.Clientset) (RequestLimitPolicies, error) {
+	list, err := clientset.CoreV1().LimitRanges("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar LimitRanges: %v", err)
+	}
+	var policies RequestLimitPolicies
+	for _, lr := range list.Items {
+		for _, item := range lr.Spec.Limits {
+			if item.Type != corev1.LimitTypeContainer {
+				continue
+			}
+			policy := RequestLimitPolicy{
+				Source:     fmt.Sprintf("LimitRange %s/%s", lr.Namespace, lr.Name),
+				Namespaces: []string{lr.Namespace},
+				CPU:        ratioForcesEquality(item.MaxLimitRequestRatio[corev1.ResourceCPU]),
+				Memory:     ratioForcesEquality(item.MaxLimitRequestRatio[corev1.ResourceMemory]),
+			}
+			if policy.CPU || policy.Memory {
+				policies = append(policies, policy)
+			}
+		}
+	}
+	return policies, nil
+}
+
+// kyvernoNamespaces collects the namespaces of the resources blocks of a match or exclude clause
+func kyvernoNamespaces(clause map[string]interface{}) []string {
+	var namespaces []string
+	collect := func(block map[string]interface{}) {
+		values, _, _ := unstructured.NestedStringSlice(block, "resources", "namespaces")
+		namespaces = append(namespaces, values...)
+	}
+	collect(clause)
+	for _, key := range []string{"any", "all"} {
+		blocks, _, _ := unstructured.NestedSlice(clause, key)
+		for _, b := range blocks {
+			if block, ok := b.(map[string]interface{}); ok {
+				collect(block)
+			}
+		}
+	}
+	return namespaces
+}
+
+// kyvernoPolicies returns the enforced Kyverno rules whose validation ties the requests to the limits
+func kyvernoPolicies(items []unstructured.Unstructured, kind string) RequestLimitPolicies {
+	var policies RequestLimitPolicies
+	for _, item := range items {
+		action, _, _ := unstructured.NestedString(item.Object, "spec", "validationFailureAction")
+		rules, _, _ := unstructured.NestedSlice(item.Object, "spec", "rules")
+		for _, r := range rules {
+			rule, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			validate, found, _ := unstructured.NestedMap(rule, "validate")
+			if !found {
+				continue
+			}
+			ruleAction := action
+			if failureAction, _, _ := unstructured.NestedString(validate, "failureAction"); failureAction != "" {
+				ruleAction = failureAction
+			}
+			// Em modo Audit a política apenas reporta, sem rejeitar os pods
+			if !strings.EqualFold(ruleAction, "Enforce") {
+				continue
+			}
+			data, err := json.Marshal(validate)
+			if err != nil {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(rule, "name")
+			policy := RequestLimitPolicy{Source: fmt.Sprintf("Kyverno %s %s (regra %s)", kind, item.GetName(), name)}
+			for _, match := range kyvernoResourceReference.FindAllStringSubmatch(string(data), -1) {
+				policy.CPU = policy.CPU || match[1] == "cpu"
+				policy.Memory = policy.Memory || match[1] == "memory"
+			}
+			if !policy.CPU && !policy.Memory {
+				continue
+			}
+			if item.GetNamespace() != "" {
+				policy.Namespaces = []string{item.GetNamespace()}
+			} else {
+				match, _, _ := unstructured.NestedMap(rule, "match")
+				policy.Namespaces = kyvernoNamespaces(match)
+			}
+			exclude, _, _ := unstructured.NestedMap(rule, "exclude")
+			policy.Excluded = kyvernoNamespaces(exclude)
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+// gatekeeperRatioPolicies returns the K8sContainerRatios constraints that deny a limit/request ratio
+// above 1. The ratio applies to memory, and to CPU unless cpuRatio is set
+func gatekeeperRatioPolicies(items []unstructured.Unstructured) RequestLimitPolicies {
+	var policies RequestLimitPolicies
+	atMostOne := func(value string) bool {
+		ratio, err := strconv.ParseFloat(value, 64)
+		return err == nil && ratio > 0 && ratio <= 1
+	}
+	for _, item := range items {
+		if action, _, _ := unstructured.NestedString(item.Object, "spec", "enforcementAction"); action != "" && action != "deny" {
+			continue
+		}
+		ratio, _, _ := unstructured.NestedString(item.Object, "spec", "parameters", "ratio")
+		cpuRatio, hasCPURatio, _ := unstructured.NestedString(item.Object, "spec", "parameters", "cpuRatio")
+		policy := RequestLimitPolicy{
+			Source: "Gatekeeper K8sContainerRatios " + item.GetName(),
+			Memory: atMostOne(ratio),
+			CPU:    (hasCPURatio && atMostOne(cpuRatio)) || (!hasCPURatio && atMostOne(ratio)),
+		}
+		if !policy.CPU && !policy.Memory {
+			continue
+		}
+		policy.Namespaces, _, _ = unstructured.NestedStringSlice(item.Object, "spec", "match", "namespaces")
+		policy.Excluded, _, _ = unstructured.NestedStringSlice(item.Object, "spec", "match", "excludedNamespaces")
+		policies = append(policies, policy)
+	}
+	return policies
+}
+
+// listRequestLimitPolicies finds the LimitRanges, Kyverno policies and Gatekeeper constraints that force
+// requests equal to limits. Policy engines that are not installed are skipped
+func listRequestLimitPolicies(clientset *kubernetes.Clientset, dynamicClient dynamic.Interface) (RequestLimitPolicies, []error) {
+	var errs []error
+	policies, err := listLimitRangePolicies(clientset)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, source := range []struct {
+		resource schema.GroupVersionResource
+		kind     string
+	}{
+		{kyvernoClusterPolicyResource, "ClusterPolicy"},
+		{kyvernoPolicyResource, "Policy"},
+		{gatekeeperContainerRatiosResource, "K8sContainerRatios"},
+	} {
+		list, err := dynamicClient.Resource(source.resource).Namespace("").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("erro ao listar %s: %v", source.kind, err))
+			}
+			continue
+		}
+		if source.resource == gatekeeperContainerRatiosResource {
+			policies = append(policies, gatekeeperRatioPolicies(list.Items)...)
+		} else {
+			policies = append(policies, kyvernoPolicies(list.Items, source.kind)...)
+		}
+	}
+	return policies, errs
+}
+
+// PolicyWaste is the capacity a namespace reserves beyond the recommended requests because a policy
+// forces requests equal to limits
+type PolicyWaste struct {
+	Namespace   string
+	Sources     []string
+	CPU         bool
+	Memory      bool
+	Deployments []string
+	// Reserva adicional somando todas as réplicas
+	WasteCPU    int64
+	WasteMemory int64
+}
+
+// adaptPatches raises the recommended requests to the limits where a policy forces them to be equal, so
+// the patches are not rejected at admission, and returns the extra requests this costs per namespace
+func (p RequestLimitPolicies) adaptPatches(patches []ResourcePatch, deployments map[string]*appsv1.Deployment) []*PolicyWaste {
+	wastes := make(map[string]*PolicyWaste)
+	for i := range patches {
+		patch := &patches[i]
+		cpu, memory, sources := p.forNamespace(patch.Namespace)
+		if !cpu && !memory {
+			continue
+		}
+		replicas := int64(1)
+		if d, exists := deployments[patch.Namespace+"/"+patch.Deployment]; exists && d.Spec.Replicas != nil {
+			replicas = int64(*d.Spec.Replicas)
+		}
+
+		var wasteCPU, wasteMemory int64
+		for j := range patch.Containers {
+			c := &patch.Containers[j]
+			if cpu && c.LimitCPU > 0 && c.RequestCPU != c.LimitCPU {
+				wasteCPU += (c.LimitCPU - c.RequestCPU) * replicas
+				c.RequestCPU = c.LimitCPU
+			}
+			if memory && c.LimitMemory > 0 && c.RequestMemory != c.LimitMemory {
+				wasteMemory += (c.LimitMemory - c.RequestMemory) * replicas
+				c.RequestMemory = c.LimitMemory
+			}
+		}
+		if wasteCPU == 0 && wasteMemory == 0 {
+			continue
+		}
+		patch.Notes = append(patch.Notes, fmt.Sprintf("Requests iguais aos limits exigidos por %s", strings.Join(sources, ", ")))
+
+		w, exists := wastes[patch.Namespace]
+		if !exists {
+			w = &PolicyWaste{Namespace: patch.Namespace, Sources: sources, CPU: cpu, Memory: memory}
+			wastes[patch.Namespace] = w
+		}
+		w.Deployments = append(w.Deployments, patch.Deployment)
+		w.WasteCPU += max(0, wasteCPU)
+		w.WasteMemory += max(0, wasteMemory)
+	}
+
+	result := make([]*PolicyWaste, 0, len(wastes))
+	for _, w := range wastes {
+		result = append(result, w)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Namespace < result[j].Namespace
+	})
+	return result
+}
+
+func writeRequestLimitPolicies(w io.Writer, policies RequestLimitPolicies, wastes []*PolicyWaste) {
+	fmt.Fprintf(w, "\n=== Políticas de Requests Iguais aos Limits ===\n")
+	fmt.Fprintf(w, "-----------------------------------------------\n")
+
+	if len(policies) == 0 {
+		fmt.Fprintf(w, "Nenhuma LimitRange ou política de admissão exigindo requests iguais aos limits\n")
+		return
+	}
+
+	fmt.Fprintf(w, "Políticas encontradas:\n")
+	for _, p := range policies {
+		var resources []string
+		if p.CPU {
+			resources = append(resources, "CPU")
+		}
+		if p.Memory {
+			resources = append(resources, "Memory")
+		}
+		scope := "todos os namespaces"
+		if len(p.Namespaces) > 0 {
+			scope = strings.Join(p.Namespaces, ", ")
+		}
+		if len(p.Excluded) > 0 {
+			scope += " (exceto " + strings.Join(p.Excluded, ", ") + ")"
+		}
+		fmt.Fprintf(w, "- %s: %s em %s\n", p.Source, strings.Join(resources, " e "), scope)
+	}
+
+	if len(wastes) == 0 {
+		fmt.Fprintf(w, "\nNenhuma recomendação alterada pelas políticas\n")
+		return
+	}
+	fmt.Fprintf(w, "\nRecomendações ajustadas (requests elevados aos limits):\n")
+	for _, waste := range wastes {
+		fmt.Fprintf(w, "\nNamespace: %s\n", waste.Namespace)
+		fmt.Fprintf(w, "Deployments: %s\n", strings.Join(waste.Deployments, ", "))
+		fmt.Fprintf(w, "Reserva adicional causada pela política: CPU %s, Memory %s\n", formatCPU(waste.WasteCPU), formatMemory(waste.WasteMemory))
+		if waste.CPU {
+			fmt.Fprintf(w, "Recomendação: permitir limits de CPU acima dos requests (ex: maxLimitRequestRatio de CPU maior que 1) em %s;\n", strings.Join(waste.Sources, ", "))
+			fmt.Fprintf(w, "a igualdade é necessária apenas para pods Guaranteed com CPUs exclusivas (CPU Manager static)\n")
+		} else {
+			fmt.Fprintf(w, "Recomendação: revisar a exigência de memória igual ao limite em %s para os workloads com pico muito acima da média\n", strings.Join(waste.Sources, ", "))
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Leituras do Metrics Server mais antigas que isto indicam um metrics-server ou kubelet atrasado
+const metricsStaleAge = 2 * time.Minute
+
+// MetricsResolution tracks the timestamps of the Metrics Server readings: the window each reading
+// averages, the interval between scrapes and the readings that repeat data already collected, which are
+// left out of the maxima and averages
+type MetricsResolution struct {
+	// Janela de cada leitura informada pelo Metrics Server (--metric-resolution)
+	Window time.Duration
+	// Leituras de pods e nodes e as que repetiam o timestamp anterior
+	Readings int
+	Repeated int
+	// Maior atraso de uma leitura (momento da coleta menos o timestamp do Metrics Server)
+	MaxAge time.Duration
+
+	// Timestamp da última leitura de cada pod ("pod/namespace/nome") e node ("node/nome")
+	scraped map[string]time.Time
+	// Intervalos entre timestamps novos de um mesmo objeto, em segundos, e quantas vezes ocorreram
+	intervals map[int64]int
+	// Nenhum node trouxe dados novos na leitura atual
+	repeatedReading bool
+}
+
+func newMetricsResolution() *MetricsResolution {
+	return &MetricsResolution{scraped: make(map[string]time.Time), intervals: make(map[int64]int)}
+}
+
+// fresh records the timestamp of a reading of the object, reporting false when it repeats the previous
+// one. Readings without timestamp are always fresh
+func (r *MetricsResolution) fresh(key string, timestamp time.Time, window time.Duration, now time.Time) bool {
+	if r == nil || timestamp.IsZero() {
+		return true
+	}
+	r.Readings++
+	if window > 0 {
+		r.Window = window
+	}
+	r.MaxAge = max(r.MaxAge, now.Sub(timestamp))
+	previous, exists := r.scraped[key]
+	if exists && !timestamp.After(previous) {
+		r.Repeated++
+		return false
+	}
+	r.scraped[key] = timestamp
+	if exists {
+		r.intervals[int64(timestamp.Sub(previous).Round(time.Second)/time.Second)]++
+	}
+	return true
+}
+
+// ScrapeInterval is the most frequent interval between new readings of the same object. It is never
+// below the collection interval, which bounds what can be observed
+func (r *MetricsResolution) ScrapeInterval() time.Duration {
+	var mode int64
+	count := 0
+	for seconds, n := range r.intervals {
+		if n > count || (n == count && seconds < mode) {
+			mode, count = seconds, n
+		}
+	}
+	return time.Duration(mode) * time.Second
+}
+
+// oversampled reports whether the collection reads more often than the Metrics Server scrapes
+func (r *MetricsResolution) oversampled(interval time.Duration) bool {
+	return r != nil && r.Repeated > 0 && r.ScrapeInterval() > interval
+}
+
+// stale reports whether some reading was older than metricsStaleAge
+func (r *MetricsResolution) stale() bool {
+	return r != nil && r.MaxAge > metricsStaleAge
+}
+
+// warn prints the collection warnings: readings taken faster than the Metrics Server scrapes and
+// readings delayed by a stalled metrics-server or kubelet
+func (r *MetricsResolution) warn(interval time.Duration) {
+	if r.oversampled(interval) {
+		fmt.Printf("⚠️  Aviso: o Metrics Server atualiza as métricas a cada ~%v, intervalo maior que o da coleta (%v): %d de %d leituras repetiam dados já coletados e foram descartadas das estatísticas\n",
+			r.ScrapeInterval(), interval, r.Repeated, r.Readings)
+	}
+	if r.stale() {
+		fmt.Printf("⚠️  Aviso: leituras do Metrics Server com até %v de atraso; verifique o metrics-server e os kubelets\n", r.MaxAge.Round(time.Second))
+	}
+}
+
+// writeMetricsResolution writes the resolution lines of the report header
+func writeMetricsResolution(w io.Writer, r *MetricsResolution, interval time.Duration) {
+	if r == nil || r.Readings == 0 {
+		return
+	}
+	var parts []string
+	if r.Window > 0 {
+		parts = append(parts, fmt.Sprintf("janela de %v", r.Window))
+	}
+	if scrape := r.ScrapeInterval(); scrape > interval {
+		parts = append(parts, fmt.Sprintf("dados atualizados a cada ~%v", scrape))
+	}
+	parts = append(parts, fmt.Sprintf("atraso máximo de %v", r.MaxAge.Round(time.Second)))
+	fmt.Fprintf(w, "Resolução do Metrics Server: %s\n", strings.Join(parts, ", "))
+	if r.Repeated > 0 {
+		fmt.Fprintf(w, "Leituras repetidas descartadas: %d de %d (intervalo da coleta de %v)\n", r.Repeated, r.Readings, interval)
+	}
+	if r.stale() {
+		fmt.Fprintf(w, "Atenção: leituras com mais de %v de atraso; o uso pode não refletir o período analisado\n", metricsStaleAge)
+	}
+}
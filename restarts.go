@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RestartSnapshot maps "namespace/pod/container" to the container restart count
+type RestartSnapshot map[string]int32
+
+// RestartDelta compares the restart count of a container at the start and end of the window
+type RestartDelta struct {
+	Namespace string
+	Pod       string
+	Container string
+	Workload  string
+	Before    int32
+	After     int32
+	During    int32
+	// Pod criado durante a janela: todos os reinícios ocorreram na coleta
+	NewPod bool
+}
+
+func restartKey(namespace, pod, container string) string {
+	return namespace + "/" + pod + "/" + container
+}
+
+// record adds the restart counts of the pod containers to the snapshot
+func (s RestartSnapshot) record(pod *corev1.Pod) {
+	for _, status := range pod.Status.ContainerStatuses {
+		s[restartKey(pod.Namespace, pod.Name, status.Name)] = status.RestartCount
+	}
+}
+
+// takeRestartSnapshot lists the pods page by page and records their restart counts. The observers
+// receive each pod of the same listing
+func takeRestartSnapshot(clientset *kubernetes.Clientset, observers ...func(pod *corev1.Pod)) (RestartSnapshot, error) {
+	snapshot := make(RestartSnapshot)
+	err := forEachPod(clientset, "", func(pod *corev1.Pod) {
+		snapshot.record(pod)
+		for _, observe := range observers {
+			observe(pod)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar pods para registrar reinícios: %v", err)
+	}
+	return snapshot, nil
+}
+
+// computeRestartDeltas returns the containers that restarted during the collection window
+func computeRestartDeltas(before RestartSnapshot, pods []corev1.Pod, deploymentIndex map[string]*DeploymentMetrics) []RestartDelta {
+	var deltas []RestartDelta
+	for i := range pods {
+		pod := &pods[i]
+		for _, status := range pod.Status.ContainerStatuses {
+			previous, existed := before[restartKey(pod.Namespace, pod.Name, status.Name)]
+			delta := RestartDelta{
+				Namespace: pod.Namespace,
+				Pod:       pod.Name,
+				Container: status.Name,
+				Workload:  workloadForPod(pod, deploymentIndex),
+				Before:    previous,
+				After:     status.RestartCount,
+				NewPod:    !existed,
+			}
+			delta.During = delta.After - delta.Before
+			if delta.During <= 0 {
+				continue
+			}
+			deltas = append(deltas, delta)
+		}
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		if deltas[i].During != deltas[j].During {
+			return deltas[i].During > deltas[j].During
+		}
+		return restartKey(deltas[i].Namespace, deltas[i].Pod, deltas[i].Container) <
+			restartKey(deltas[j].Namespace, deltas[j].Pod, deltas[j].Container)
+	})
+	return deltas
+}
+
+func writeRestartDeltas(w io.Writer, deltas []RestartDelta, snapshotTaken bool) {
+	fmt.Fprintf(w, "\n=== Reinícios Durante a Coleta ===\n")
+	fmt.Fprintf(w, "----------------------------------\n")
+
+	if !snapshotTaken {
+		fmt.Fprintf(w, "Contagem inicial de reinícios indisponível\n")
+		return
+	}
+	if len(deltas) == 0 {
+		fmt.Fprintf(w, "Nenhum container reiniciou durante a coleta\n")
+		return
+	}
+
+	for _, d := range deltas {
+		fmt.Fprintf(w, "- %s/%s (Workload: %s, Namespace: %s): %d reinícios durante a coleta",
+			d.Pod, d.Container, d.Workload, d.Namespace, d.During)
+		if d.NewPod {
+			fmt.Fprintf(w, " (pod criado durante a coleta)\n")
+		} else {
+			fmt.Fprintf(w, " (histórico anterior: %d)\n", d.Before)
+		}
+	}
+	fmt.Fprintf(w, "\nRecomendação: Investigar os reinícios recentes (kubectl describe pod / logs --previous); "+
+		"reinícios por OOMKilled indicam limite de memória insuficiente\n")
+}
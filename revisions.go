@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// RevisionMetrics holds the usage of the pods of one deployment revision (ReplicaSet)
+type RevisionMetrics struct {
+	Revision     int64
+	ReplicaSet   string
+	TemplateHash string
+	Pods         int
+	MaxCPU       int64
+	MaxMemory    int64
+	AvgCPU       int64
+	AvgMemory    int64
+	FirstSeen    time.Time
+	LastSeen     time.Time
+}
+
+// DeploymentRollout lists the revisions of a deployment observed during the collection window
+type DeploymentRollout struct {
+	Name      string
+	Namespace string
+	Revisions []RevisionMetrics
+}
+
+// replicaSetRevision returns the deployment revision recorded on the ReplicaSet
+func replicaSetRevision(rs *appsv1.ReplicaSet) int64 {
+	revision, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+func replicaSetDeployment(rs *appsv1.ReplicaSet) string {
+	for _, owner := range rs.OwnerReferences {
+		if owner.Kind == "Deployment" {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+// listReplicaSets lists the ReplicaSets of all namespaces
+func listReplicaSets(clientset *kubernetes.Clientset) ([]appsv1.ReplicaSet, error) {
+	replicaSets, err := clientset.AppsV1().ReplicaSets("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar ReplicaSets: %v", err)
+	}
+	return replicaSets.Items, nil
+}
+
+// segmentByRevision groups the collected pod metrics by the ReplicaSet (pod template hash) they
+// belong to, including pods of old revisions that no longer exist at the end of the window. Containers
+// with overrides are left out, as in the deployment metrics
+func segmentByRevision(replicaSets []appsv1.ReplicaSet, deploymentMetrics map[string]*DeploymentMetrics, metrics *MetricsData, overrides ContainerOverrides) []DeploymentRollout {
+	revisions := make(map[string][]RevisionMetrics)
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		deploymentName := replicaSetDeployment(rs)
+		if deploymentName == "" {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s", rs.Namespace, deploymentName)
+		if _, exists := deploymentMetrics[key]; !exists {
+			continue
+		}
+
+		rm := RevisionMetrics{
+			Revision:     replicaSetRevision(rs),
+			ReplicaSet:   rs.Name,
+			TemplateHash: rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey],
+		}
+
+		// Pods de um ReplicaSet são nomeados "<replicaset>-<sufixo>"
+		var totalCPU, totalMemory, containers int64
+		prefix := rs.Name + "-"
+		for podName, pm := range metrics.PodMetrics {
+			if pm.Namespace != rs.Namespace || !strings.HasPrefix(podName, prefix) {
+				continue
+			}
+			rm.Pods++
+			if rm.FirstSeen.IsZero() || (!pm.FirstSeen.IsZero() && pm.FirstSeen.Before(rm.FirstSeen)) {
+				rm.FirstSeen = pm.FirstSeen
+			}
+			if pm.LastSeen.After(rm.LastSeen) {
+				rm.LastSeen = pm.LastSeen
+			}
+			for name, cm := range pm.Containers {
+				if overrides.skip(rs.Namespace, name) {
+					continue
+				}
+				if cm.MaxCPU > rm.MaxCPU {
+					rm.MaxCPU = cm.MaxCPU
+				}
+				if cm.MaxMemory > rm.MaxMemory {
+					rm.MaxMemory = cm.MaxMemory
+				}
+				totalCPU += cm.MaxCPU
+				totalMemory += cm.MaxMemory
+				containers++
+			}
+		}
+		if rm.Pods == 0 {
+			continue
+		}
+		if containers > 0 {
+			rm.AvgCPU = totalCPU / containers
+			rm.AvgMemory = totalMemory / containers
+		}
+
+		revisions[key] = append(revisions[key], rm)
+	}
+
+	var rollouts []DeploymentRollout
+	for key, revs := range revisions {
+		// Apenas deployments com métricas de mais de uma revisão tiveram rollout na janela
+		if len(revs) < 2 {
+			continue
+		}
+		sort.Slice(revs, func(i, j int) bool {
+			return revs[i].Revision < revs[j].Revision
+		})
+		dm := deploymentMetrics[key]
+		rollouts = append(rollouts, DeploymentRollout{
+			Name:      dm.Name,
+			Namespace: dm.Namespace,
+			Revisions: revs,
+		})
+	}
+
+	sort.Slice(rollouts, func(i, j int) bool {
+		if rollouts[i].Namespace != rollouts[j].Namespace {
+			return rollouts[i].Namespace < rollouts[j].Namespace
+		}
+		return rollouts[i].Name < rollouts[j].Name
+	})
+
+	return rollouts
+}
+
+// applyLatestRevision makes the deployment statistics reflect only its most recent revision,
+// so that the recommendation does not blend the behavior of old and new versions
+func applyLatestRevision(deploymentMetrics map[string]*DeploymentMetrics, rollouts []DeploymentRollout) {
+	for _, rollout := range rollouts {
+		dm, exists := deploymentMetrics[fmt.Sprintf("%s/%s", rollout.Namespace, rollout.Name)]
+		if !exists {
+			continue
+		}
+		latest := rollout.Revisions[len(rollout.Revisions)-1]
+		dm.MaxCPU = latest.MaxCPU
+		dm.MaxMemory = latest.MaxMemory
+		dm.AvgCPU = latest.AvgCPU
+		dm.AvgMemory = latest.AvgMemory
+		dm.Recommendations = append(dm.Recommendations,
+			fmt.Sprintf("Rollout detectado durante a coleta: métricas consideram apenas a revisão %d (%s)", latest.Revision, latest.ReplicaSet))
+	}
+}
+
+func writeRollouts(w io.Writer, rollouts []DeploymentRollout) {
+	fmt.Fprintf(w, "\n=== Rollouts Durante a Coleta ===\n")
+	fmt.Fprintf(w, "---------------------------------\n")
+
+	if len(rollouts) == 0 {
+		fmt.Fprintf(w, "Nenhum rollout detectado durante a coleta\n")
+		return
+	}
+
+	for _, rollout := range rollouts {
+		fmt.Fprintf(w, "\nDeployment: %s (Namespace: %s)\n", rollout.Name, rollout.Namespace)
+		for _, rev := range rollout.Revisions {
+			fmt.Fprintf(w, "  Revisão %d (%s): %d pods, de %s a %s\n", rev.Revision, rev.TemplateHash, rev.Pods,
+				rev.FirstSeen.Format("15:04:05"), rev.LastSeen.Format("15:04:05"))
+			fmt.Fprintf(w, "    Máximo: CPU %s, Memory %s | Média: CPU %s, Memory %s\n",
+				formatCPU(rev.MaxCPU), formatMemory(rev.MaxMemory), formatCPU(rev.AvgCPU), formatMemory(rev.AvgMemory))
+		}
+		latest := rollout.Revisions[len(rollout.Revisions)-1]
+		fmt.Fprintf(w, "  Recomendações de recursos baseadas apenas na revisão %d\n", latest.Revision)
+	}
+}
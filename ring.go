@@ -0,0 +1,219 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Número de intervalos em que a janela deslizante é dividida: o uso de memória por container fica
+// fixo, independente da duração da coleta
+const statsWindowBuckets = 24
+
+// usageBucket holds the statistics observed during one slice of the window
+type usageBucket struct {
+	start time.Time
+	// Primeira leitura do intervalo e número de leituras (pods)
+	first    time.Time
+	readings int
+	cpu      int64
+	memory   int64
+	// Métricas do summary do kubelet (containers e pods)
+	workingSet       int64
+	rss              int64
+	processes        int64
+	ephemeralStorage int64
+	// Leituras para a variação do uso (containers)
+	cpuVariation    usageVariation
+	memoryVariation usageVariation
+}
+
+// addUsage records a CPU and memory reading in the bucket
+func (b *usageBucket) addUsage(cpu, memory int64) {
+	b.cpu = max(b.cpu, cpu)
+	b.memory = max(b.memory, memory)
+	b.cpuVariation.add(cpu)
+	b.memoryVariation.add(memory)
+}
+
+// merge adds the statistics of another bucket
+func (b *usageBucket) merge(other usageBucket) {
+	if b.first.IsZero() || (!other.first.IsZero() && other.first.Before(b.first)) {
+		b.first = other.first
+	}
+	b.readings += other.readings
+	b.cpu = max(b.cpu, other.cpu)
+	b.memory = max(b.memory, other.memory)
+	b.workingSet = max(b.workingSet, other.workingSet)
+	b.rss = max(b.rss, other.rss)
+	b.processes = max(b.processes, other.processes)
+	b.ephemeralStorage = max(b.ephemeralStorage, other.ephemeralStorage)
+	b.cpuVariation.merge(other.cpuVariation)
+	b.memoryVariation.merge(other.memoryVariation)
+}
+
+// usageRing is a ring buffer of usage statistics covering the last window, so long collections report
+// the recent peaks instead of all-time maxima that become stale
+type usageRing struct {
+	window  time.Duration
+	width   time.Duration
+	buckets [statsWindowBuckets]usageBucket
+}
+
+func newUsageRing(window time.Duration) *usageRing {
+	width := window / statsWindowBuckets
+	if width <= 0 {
+		width = time.Nanosecond
+	}
+	return &usageRing{window: window, width: width}
+}
+
+// bucket returns the bucket of the slice containing t, reusing the bucket of a slice that already left
+// the window
+func (r *usageRing) bucket(t time.Time) *usageBucket {
+	start := t.Truncate(r.width)
+	b := &r.buckets[(start.UnixNano()/int64(r.width))%statsWindowBuckets]
+	if !b.start.Equal(start) {
+		*b = usageBucket{start: start}
+	}
+	if b.first.IsZero() {
+		b.first = t
+	}
+	return b
+}
+
+// stats merges the buckets inside the window ending at now
+func (r *usageRing) stats(now time.Time) (usageBucket, bool) {
+	var stats usageBucket
+	found := false
+	for _, b := range r.buckets {
+		if b.start.IsZero() || !b.start.Add(r.width).After(now.Add(-r.window)) {
+			continue
+		}
+		stats.merge(b)
+		found = true
+	}
+	return stats, found
+}
+
+// windowBucket returns the bucket of the ring for a reading taken at t, creating the ring on the first
+// reading; nil without a stats window
+func (m *MetricsData) windowBucket(ring **usageRing, t time.Time) *usageBucket {
+	if m.StatsWindow <= 0 {
+		return nil
+	}
+	if *ring == nil {
+		*ring = newUsageRing(m.StatsWindow)
+	}
+	return (*ring).bucket(t)
+}
+
+// hourlySlot is the usage of a workload during one hour of the collection, kept for the stats window
+type hourlySlot struct {
+	start time.Time
+	cell  HourlyCell
+}
+
+// recordHourlySlot adds a workload reading to the slot of its hour, dropping the hours that already left
+// the window
+func (m *MetricsData) recordHourlySlot(key string, t time.Time, r *workloadReading) {
+	if m.hourlyWindow == nil {
+		m.hourlyWindow = make(map[string][]hourlySlot)
+	}
+	start := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	slots := m.hourlyWindow[key]
+	if len(slots) == 0 || !slots[len(slots)-1].start.Equal(start) {
+		slots = append(slots, hourlySlot{start: start})
+	}
+	slots[len(slots)-1].cell.add(r)
+
+	cutoff := t.Add(-m.StatsWindow)
+	expired := 0
+	for expired < len(slots) && !slots[expired].start.Add(time.Hour).After(cutoff) {
+		expired++
+	}
+	m.hourlyWindow[key] = slots[expired:]
+}
+
+// windowHourly rebuilds the hourly usage from the hours inside the window ending at now
+func (m *MetricsData) windowHourly(now time.Time) {
+	cutoff := now.Add(-m.StatsWindow)
+	m.Hourly = make(map[string]*HourlyUsage)
+	for key, slots := range m.hourlyWindow {
+		for _, slot := range slots {
+			if !slot.start.Add(time.Hour).After(cutoff) {
+				continue
+			}
+			h, exists := m.Hourly[key]
+			if !exists {
+				namespace, workload, _ := strings.Cut(key, "/")
+				h = &HourlyUsage{Namespace: namespace, Workload: workload}
+				m.Hourly[key] = h
+			}
+			h.Cells[slot.start.Weekday()][slot.start.Hour()].merge(slot.cell)
+			h.addDays(slot.start.Format("2006-01-02"))
+		}
+	}
+}
+
+// applyStatsWindow replaces the collection statistics of containers, pods and nodes (maxima, variation,
+// kubelet metrics and number of readings), the cluster samples and the hourly usage with those of the
+// last window. Pods and nodes not seen during the window are dropped
+func applyStatsWindow(metrics *MetricsData, now time.Time) {
+	for podName, pm := range metrics.PodMetrics {
+		removed := false
+		for containerName, cm := range pm.Containers {
+			if cm.window == nil {
+				continue
+			}
+			stats, found := cm.window.stats(now)
+			if !found {
+				delete(pm.Containers, containerName)
+				removed = true
+				continue
+			}
+			cm.MaxCPU, cm.MaxMemory = stats.cpu, stats.memory
+			cm.MaxWorkingSet, cm.MaxRSS = stats.workingSet, stats.rss
+			cm.cpuVariation, cm.memoryVariation = stats.cpuVariation, stats.memoryVariation
+		}
+		if removed && len(pm.Containers) == 0 {
+			delete(metrics.PodMetrics, podName)
+			continue
+		}
+		if pm.window == nil {
+			continue
+		}
+		stats, found := pm.window.stats(now)
+		if !found {
+			delete(metrics.PodMetrics, podName)
+			continue
+		}
+		pm.Samples = stats.readings
+		if stats.first.After(pm.FirstSeen) {
+			pm.FirstSeen = stats.first
+		}
+		pm.MaxProcesses, pm.MaxEphemeralStorage = stats.processes, stats.ephemeralStorage
+	}
+	for nodeName, nm := range metrics.NodeMetrics {
+		if nm.window == nil {
+			continue
+		}
+		stats, found := nm.window.stats(now)
+		if !found {
+			delete(metrics.NodeMetrics, nodeName)
+			continue
+		}
+		nm.MaxCPU, nm.MaxMemory = stats.cpu, stats.memory
+		nm.MaxProcesses = stats.processes
+	}
+
+	cutoff := now.Add(-metrics.StatsWindow)
+	expired := 0
+	for expired < len(metrics.ClusterSamples) && !metrics.ClusterSamples[expired].Time.After(cutoff) {
+		expired++
+	}
+	metrics.ClusterSamples = metrics.ClusterSamples[expired:]
+
+	if metrics.hourlyWindow != nil {
+		metrics.windowHourly(now)
+	}
+}
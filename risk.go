@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Uso de memória, em percentual do limite, a partir do qual o container está prestes a sofrer OOMKill
+const memoryLimitRiskPct = 95
+
+// Soma dos limites de memória, em percentual do allocatable, a partir da qual o node é considerado
+// sobrecomprometido (padrão de alerting.overcommit_pct)
+const defaultOvercommitRiskPct = 150
+
+// detectRisks looks for imminent risk conditions: containers whose memory peak is above the risk
+// threshold of their namespace (memoryLimitRiskPct by default) and nodes whose memory limits exceed
+// overcommitPct of the allocatable. Containers with overrides are not checked for memory, but their limits
+// still count towards the node
+func detectRisks(nodes []corev1.Node, pods []corev1.Pod, metrics *MetricsData, deploymentIndex map[string]*DeploymentMetrics, overcommitPct int, thresholds NamespaceThresholds, overrides ContainerOverrides) []Finding {
+	var risks []Finding
+
+	type memoryRisk struct {
+		namespace  string
+		workload   string
+		threshold  int
+		containers []string
+	}
+	atLimit := make(map[string]*memoryRisk)
+	limits := make(map[string]int64)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		pm, measured := metrics.PodMetrics[pod.Name]
+		threshold := thresholds.forNamespace(pod.Namespace).MemoryLimitRiskPct
+		for _, container := range pod.Spec.Containers {
+			limit := container.Resources.Limits.Memory().Value()
+			limits[pod.Spec.NodeName] += limit
+			if !measured || pm.Namespace != pod.Namespace || limit == 0 || overrides.skip(pod.Namespace, container.Name) {
+				continue
+			}
+			cm, exists := pm.Containers[container.Name]
+			if !exists || percent(cm.MaxMemory, limit) < float64(threshold) {
+				continue
+			}
+
+			workload := workloadForPod(pod, deploymentIndex)
+			key := pod.Namespace + "/" + workload
+			risk, exists := atLimit[key]
+			if !exists {
+				risk = &memoryRisk{namespace: pod.Namespace, workload: workload, threshold: threshold}
+				atLimit[key] = risk
+			}
+			risk.containers = append(risk.containers, fmt.Sprintf("%s/%s: %s de %s (%.0f%%)",
+				pod.Name, container.Name, formatMemory(cm.MaxMemory), formatMemory(limit), percent(cm.MaxMemory, limit)))
+		}
+	}
+
+	for _, risk := range atLimit {
+		body := fmt.Sprintf("Containers com pico de memória acima de %d%% do limite, prestes a serem encerrados por OOMKill:\n", risk.threshold)
+		for _, container := range risk.containers {
+			body += "- " + container + "\n"
+		}
+		body += "\nRecomendação: aumentar o limite de memória (ou investigar vazamento de memória) antes que os containers sejam encerrados."
+		risks = append(risks, Finding{
+			Kind:      "memoria-no-limite",
+			Severity:  SeverityCritical,
+			Title:     fmt.Sprintf("Memória de %s/%s acima de %d%% do limite", risk.namespace, risk.workload, risk.threshold),
+			Namespace: risk.namespace,
+			Workload:  risk.workload,
+			Body:      body,
+		})
+	}
+
+	for _, node := range nodes {
+		allocatable := node.Status.Allocatable.Memory().Value()
+		if allocatable == 0 {
+			continue
+		}
+		overcommit := percent(limits[node.Name], allocatable)
+		if overcommit < float64(overcommitPct) {
+			continue
+		}
+		risks = append(risks, Finding{
+			Kind:     "node-sobrecomprometido",
+			Severity: SeverityCritical,
+			Title:    fmt.Sprintf("Node %s com limites de memória em %.0f%% do allocatable", node.Name, overcommit),
+			Workload: node.Name,
+			Body: fmt.Sprintf("A soma dos limites de memória dos pods (%s) chega a %.0f%% do allocatable do node (%s), acima do limite de %d%%. "+
+				"Se os pods usarem o que os limites permitem, o node entra em pressão de memória e começa a despejar pods.\n\n"+
+				"Recomendação: aproximar os limites do uso real ou redistribuir os pods para outros nodes.",
+				formatMemory(limits[node.Name]), overcommit, formatMemory(allocatable), overcommitPct),
+		})
+	}
+
+	sort.Slice(risks, func(i, j int) bool { return risks[i].key() < risks[j].key() })
+	return risks
+}
+
+func writeRisks(w io.Writer, risks []Finding) {
+	fmt.Fprintf(w, "\n=== Riscos Iminentes ===\n")
+	fmt.Fprintf(w, "------------------------\n")
+
+	if len(risks) == 0 {
+		fmt.Fprintf(w, "Nenhuma condição de risco iminente detectada\n")
+		return
+	}
+	for i, risk := range risks {
+		fmt.Fprintf(w, "\n%d. %s\n", i+1, risk.Title)
+		fmt.Fprintf(w, "%s\n", risk.Body)
+		fmt.Fprintf(w, "Prioridade: Crítica\n")
+	}
+}
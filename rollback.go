@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ContainerResources is the complete resources stanza of a container before a patch
+type ContainerResources struct {
+	Container string                      `json:"container"`
+	Resources corev1.ResourceRequirements `json:"resources"`
+}
+
+// RollbackEntry holds the previous resources of the containers of a patched deployment
+type RollbackEntry struct {
+	Deployment string               `json:"deployment"`
+	Namespace  string               `json:"namespace"`
+	Containers []ContainerResources `json:"containers"`
+}
+
+// RollbackBundle captures the resources replaced by an apply, so it can be undone
+type RollbackBundle struct {
+	Context   string          `json:"context"`
+	CreatedAt time.Time       `json:"created_at"`
+	Entries   []RollbackEntry `json:"entries"`
+}
+
+// captureRollback reads the live deployment and records the resources of the containers the patch changes
+func captureRollback(clientset *kubernetes.Clientset, p ResourcePatch) (RollbackEntry, error) {
+	entry := RollbackEntry{Deployment: p.Deployment, Namespace: p.Namespace}
+	deployment, err := clientset.AppsV1().Deployments(p.Namespace).Get(context.TODO(), p.Deployment, metav1.GetOptions{})
+	if err != nil {
+		return entry, fmt.Errorf("erro ao registrar valores anteriores de %s/%s: %v", p.Namespace, p.Deployment, err)
+	}
+
+	patched := make(map[string]bool)
+	for _, c := range p.Containers {
+		patched[c.Container] = true
+	}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if patched[container.Name] {
+			entry.Containers = append(entry.Containers, ContainerResources{
+				Container: container.Name,
+				Resources: container.Resources,
+			})
+		}
+	}
+	return entry, nil
+}
+
+// writeRollbackBundle saves the bundle used by the rollback command. The file is replaced atomically,
+// so a bundle rewritten during the apply is never left truncated
+func writeRollbackBundle(path string, bundle *RollbackBundle) error {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar pacote de rollback: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("erro ao escrever pacote de rollback: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("erro ao escrever pacote de rollback: %v", err)
+	}
+	return nil
+}
+
+func loadRollbackBundle(path string) (*RollbackBundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler pacote de rollback: %v", err)
+	}
+	bundle := &RollbackBundle{}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, fmt.Errorf("erro ao analisar pacote de rollback %s: %v", path, err)
+	}
+	return bundle, nil
+}
+
+// restoreEntry replaces the resources stanza of each container with its previous value. A JSON patch
+// is used (instead of a merge) so that requests/limits added by the apply are removed as well
+func restoreEntry(clientset *kubernetes.Clientset, entry RollbackEntry) error {
+	deployment, err := clientset.AppsV1().Deployments(entry.Namespace).Get(context.TODO(), entry.Deployment, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("erro ao obter deployment %s/%s: %v", entry.Namespace, entry.Deployment, err)
+	}
+
+	var operations []map[string]interface{}
+	for _, previous := range entry.Containers {
+		for i, container := range deployment.Spec.Template.Spec.Containers {
+			if container.Name != previous.Container {
+				continue
+			}
+			path := fmt.Sprintf("/spec/template/spec/containers/%d", i)
+			operations = append(operations,
+				map[string]interface{}{"op": "test", "path": path + "/name", "value": container.Name},
+				map[string]interface{}{"op": "replace", "path": path + "/resources", "value": previous.Resources},
+			)
+		}
+	}
+	if len(operations) == 0 {
+		return fmt.Errorf("nenhum container do pacote encontrado em %s/%s", entry.Namespace, entry.Deployment)
+	}
+
+	data, err := json.Marshal(operations)
+	if err != nil {
+		return fmt.Errorf("erro ao gerar patch de rollback de %s/%s: %v", entry.Namespace, entry.Deployment, err)
+	}
+	_, err = clientset.AppsV1().Deployments(entry.Namespace).Patch(context.TODO(), entry.Deployment,
+		types.JSONPatchType, data, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("erro ao restaurar %s/%s: %v", entry.Namespace, entry.Deployment, err)
+	}
+	return nil
+}
+
+// rollback restores every deployment of the bundle, recording each one in the audit log as soon as it
+// is restored, and returns the number of failures
+func rollback(clientset *kubernetes.Clientset, bundle *RollbackBundle, audit *AuditLog) int {
+	failures := 0
+	for _, entry := range bundle.Entries {
+		if err := restoreEntry(clientset, entry); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			failures++
+			continue
+		}
+		fmt.Printf("✅ %s/%s restaurado\n", entry.Namespace, entry.Deployment)
+		audit.record(AuditEntry{Action: auditActionRollback, Namespace: entry.Namespace, Deployment: entry.Deployment, Restored: entry.Containers})
+	}
+	return failures
+}
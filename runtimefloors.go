@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Containers que rodaram ao menos este tempo antes do OOM provavelmente têm vazamento de memória; abaixo
+// disso o runtime não cabe no limite já na inicialização
+const memoryLeakMinUptime = 30 * time.Minute
+
+// Causas prováveis dos reinícios de um container com limite abaixo do mínimo do runtime
+const (
+	memoryCauseLowLimit = "limite baixo"
+	memoryCauseLeak     = "vazamento"
+)
+
+// RuntimeFloor is the least memory a language runtime needs to start and serve, before the application
+type RuntimeFloor struct {
+	Runtime string
+	// Padrões (path.Match) do nome da imagem, sem registry e tag, que identificam o runtime
+	Images []string
+	// Variáveis de ambiente e comandos que identificam o runtime
+	Env      []string
+	Commands []string
+	Memory   int64
+	// Por que o runtime precisa dessa memória
+	Reason string
+}
+
+// runtimeFloors are the known runtimes, in the order they are matched
+var runtimeFloors = []RuntimeFloor{
+	{Runtime: "JVM", Images: []string{"*openjdk*", "*jdk*", "*jre*", "*temurin*", "*corretto*", "*zulu*", "java", "tomcat", "jetty", "wildfly", "keycloak", "elasticsearch", "kafka"},
+		Env: []string{"JAVA_OPTS", "JAVA_TOOL_OPTIONS", "JDK_JAVA_OPTIONS"}, Commands: []string{"java"},
+		Memory: 256 * 1024 * 1024, Reason: "heap padrão de 25% do limite, mais metaspace, code cache e pilhas das threads"},
+	{Runtime: ".NET", Images: []string{"dotnet*", "aspnet"}, Env: []string{"DOTNET_GCHeapHardLimit", "ASPNETCORE_URLS"}, Commands: []string{"dotnet"},
+		Memory: 128 * 1024 * 1024, Reason: "runtime, JIT e heaps do GC por núcleo"},
+	{Runtime: "Node.js", Images: []string{"node", "nodejs*"}, Env: []string{"NODE_OPTIONS", "NODE_ENV"}, Commands: []string{"node", "npm", "yarn"},
+		Memory: 128 * 1024 * 1024, Reason: "V8 e heap inicial do Node.js"},
+	{Runtime: "Python", Images: []string{"python", "python3*"}, Commands: []string{"python", "python3", "gunicorn", "uvicorn"},
+		Memory: 64 * 1024 * 1024, Reason: "interpretador e módulos importados na inicialização"},
+}
+
+// detectRuntime identifies the runtime of a container by its image, environment and command
+func detectRuntime(c *corev1.Container) *RuntimeFloor {
+	image := c.Image
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	image = path.Base(image)
+	if i := strings.LastIndex(image, ":"); i >= 0 {
+		image = image[:i]
+	}
+	var command string
+	if len(c.Command) > 0 {
+		command = path.Base(c.Command[0])
+	}
+	for i := range runtimeFloors {
+		floor := &runtimeFloors[i]
+		for _, name := range floor.Images {
+			if matched, _ := path.Match(name, image); matched {
+				return floor
+			}
+		}
+		for _, env := range c.Env {
+			for _, name := range floor.Env {
+				if env.Name == name {
+					return floor
+				}
+			}
+		}
+		for _, name := range floor.Commands {
+			if command == name {
+				return floor
+			}
+		}
+	}
+	return nil
+}
+
+// jvmMaxHeap returns the -Xmx set in the JVM options of the container, in bytes (0 when not set)
+func jvmMaxHeap(c *corev1.Container) int64 {
+	options := append([]string{}, c.Args...)
+	options = append(options, c.Command...)
+	for _, env := range c.Env {
+		options = append(options, strings.Fields(env.Value)...)
+	}
+	for _, option := range options {
+		value, found := strings.CutPrefix(option, "-Xmx")
+		if !found || value == "" {
+			continue
+		}
+		// Sufixos da JVM (k, m, g) em unidades binárias
+		value = strings.ToLower(value)
+		if last := value[len(value)-1]; last == 'k' || last == 'm' || last == 'g' {
+			value = value[:len(value)-1] + strings.ToUpper(string(last)) + "i"
+		}
+		if q, err := resource.ParseQuantity(value); err == nil {
+			return q.Value()
+		}
+	}
+	return 0
+}
+
+// RuntimeMemoryFloor is a container whose memory limit is below the floor of its runtime and that
+// restarted, with the probable cause from how long it ran before being killed
+type RuntimeMemoryFloor struct {
+	Namespace   string
+	Workload    string
+	Container   string
+	Runtime     string
+	Limit       int64
+	Floor       int64
+	FloorReason string
+	// -Xmx configurado (JVM)
+	MaxHeap int64
+	// Reinícios somados e encerramentos por OOM entre os pods
+	Pods     int
+	Restarts int32
+	OOMKills int
+	// Menor e maior tempo de execução antes do último encerramento
+	ShortestRun time.Duration
+	LongestRun  time.Duration
+	// Uso médio e pico observados na coleta
+	AvgMemory int64
+	MaxMemory int64
+	Cause     string
+	Suggested int64
+}
+
+// findRuntimeMemoryFloors lists the containers of each workload with a memory limit below the floor of
+// their runtime that restarted or were OOMKilled. Containers that ran at least memoryLeakMinUptime before
+// their last termination point to a leak; the others to a limit too low for the runtime
+func findRuntimeMemoryFloors(pods []corev1.Pod, metrics *MetricsData, deploymentIndex map[string]*DeploymentMetrics, overrides ContainerOverrides) []*RuntimeMemoryFloor {
+	floors := make(map[string]*RuntimeMemoryFloor)
+	usage := make(map[string]*usageVariation)
+	for i := range pods {
+		pod := &pods[i]
+		statuses := make(map[string]corev1.ContainerStatus, len(pod.Status.ContainerStatuses))
+		for _, status := range pod.Status.ContainerStatuses {
+			statuses[status.Name] = status
+		}
+		for j := range pod.Spec.Containers {
+			c := &pod.Spec.Containers[j]
+			limit := c.Resources.Limits.Memory().Value()
+			runtime := detectRuntime(c)
+			if runtime == nil || limit <= 0 || limit >= runtime.Memory || overrides.skip(pod.Namespace, c.Name) {
+				continue
+			}
+			status, exists := statuses[c.Name]
+			if !exists || status.RestartCount == 0 {
+				continue
+			}
+			workload := workloadForPod(pod, deploymentIndex)
+			key := pod.Namespace + "/" + workload + "/" + c.Name
+			f, exists := floors[key]
+			if !exists {
+				f = &RuntimeMemoryFloor{Namespace: pod.Namespace, Workload: workload, Container: c.Name, Runtime: runtime.Runtime,
+					Limit: limit, Floor: runtime.Memory, FloorReason: runtime.Reason}
+				if runtime.Runtime == "JVM" {
+					f.MaxHeap = jvmMaxHeap(c)
+				}
+				floors[key] = f
+				usage[key] = &usageVariation{}
+			}
+			f.Pods++
+			f.Restarts += status.RestartCount
+			if terminated := status.LastTerminationState.Terminated; terminated != nil {
+				if exitClass(terminated.ExitCode, terminated.Reason) == exitClassOOM {
+					f.OOMKills++
+				}
+				if run := terminated.FinishedAt.Sub(terminated.StartedAt.Time); run > 0 {
+					if f.ShortestRun == 0 || run < f.ShortestRun {
+						f.ShortestRun = run
+					}
+					f.LongestRun = max(f.LongestRun, run)
+				}
+			}
+			if pm, exists := metrics.PodMetrics[pod.Name]; exists && pm.Namespace == pod.Namespace {
+				if cm, exists := pm.Containers[c.Name]; exists {
+					usage[key].merge(cm.memoryVariation)
+					f.MaxMemory = max(f.MaxMemory, cm.MaxMemory)
+				}
+			}
+		}
+	}
+
+	result := make([]*RuntimeMemoryFloor, 0, len(floors))
+	for key, f := range floors {
+		if u := usage[key]; u.count > 0 {
+			f.AvgMemory = int64(u.sum / u.count)
+		}
+		f.Cause = memoryCauseLowLimit
+		if f.ShortestRun >= memoryLeakMinUptime && (f.MaxHeap == 0 || f.MaxHeap < f.Limit) {
+			f.Cause = memoryCauseLeak
+		}
+		f.Suggested = roundUpMiB(max(f.Floor, withHeadroomPct(f.MaxMemory, 20), f.MaxHeap+f.Floor/2))
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Restarts != result[j].Restarts {
+			return result[i].Restarts > result[j].Restarts
+		}
+		return result[i].Namespace+"/"+result[i].Workload+"/"+result[i].Container < result[j].Namespace+"/"+result[j].Workload+"/"+result[j].Container
+	})
+	return result
+}
+
+// diagnosis explains the cause of the restarts and the fix
+func (f *RuntimeMemoryFloor) diagnosis() string {
+	if f.Cause == memoryCauseLeak {
+		return fmt.Sprintf("os containers rodaram ao menos %s antes de serem encerrados, o que indica um vazamento de memória: aumentar o limite para %s apenas adia os reinícios; investigar o crescimento da memória (heap dump, profiler)",
+			formatAge(f.ShortestRun), formatMemory(f.Suggested))
+	}
+	if f.MaxHeap >= f.Limit {
+		return fmt.Sprintf("o -Xmx de %s não cabe no limite de %s: aumentar o limite para %s ou reduzir o -Xmx", formatMemory(f.MaxHeap), formatMemory(f.Limit), formatMemory(f.Suggested))
+	}
+	return fmt.Sprintf("o runtime não cabe no limite já na inicialização: aumentar o limite para %s", formatMemory(f.Suggested))
+}
+
+// runtimeMemoryFloorFindings reports each container; OOMKills make the finding high severity
+func runtimeMemoryFloorFindings(floors []*RuntimeMemoryFloor) []Finding {
+	var findings []Finding
+	for _, f := range floors {
+		severity := SeverityMedium
+		if f.OOMKills > 0 {
+			severity = SeverityHigh
+		}
+		findings = append(findings, Finding{
+			Kind:      "memoria-runtime",
+			Severity:  severity,
+			Title:     fmt.Sprintf("Limite de memória de %s abaixo do mínimo do runtime %s em %s/%s (%s)", formatMemory(f.Limit), f.Runtime, f.Namespace, f.Workload, f.Container),
+			Namespace: f.Namespace,
+			Workload:  f.Workload,
+			Body: fmt.Sprintf("O runtime %s precisa de ao menos %s (%s). %d pods com %d reinícios, %d por OOM; uso médio %s e pico %s na coleta.\n\nRecomendação (%s): %s.",
+				f.Runtime, formatMemory(f.Floor), f.FloorReason, f.Pods, f.Restarts, f.OOMKills, formatMemory(f.AvgMemory), formatMemory(f.MaxMemory),
+				f.Cause, f.diagnosis()),
+		})
+	}
+	return findings
+}
+
+func writeRuntimeMemoryFloors(w io.Writer, floors []*RuntimeMemoryFloor) {
+	fmt.Fprintf(w, "\n=== Limites de Memória Abaixo do Mínimo do Runtime ===\n")
+	fmt.Fprintf(w, "------------------------------------------------------\n")
+
+	if len(floors) == 0 {
+		fmt.Fprintf(w, "Nenhum container reiniciado com limite de memória abaixo do mínimo do seu runtime\n")
+		return
+	}
+
+	for _, f := range floors {
+		fmt.Fprintf(w, "\nWorkload: %s (Namespace: %s) - container %s, %s\n", f.Workload, f.Namespace, f.Container, f.Runtime)
+		fmt.Fprintf(w, "  Limite: %s (mínimo do runtime: %s)", formatMemory(f.Limit), formatMemory(f.Floor))
+		if f.MaxHeap > 0 {
+			fmt.Fprintf(w, ", -Xmx %s", formatMemory(f.MaxHeap))
+		}
+		fmt.Fprintf(w, "\n  Reinícios: %d em %d pods, %d por OOM\n", f.Restarts, f.Pods, f.OOMKills)
+		if f.LongestRun > 0 {
+			fmt.Fprintf(w, "  Tempo de execução antes do último encerramento: %s a %s\n", formatAge(f.ShortestRun), formatAge(f.LongestRun))
+		}
+		fmt.Fprintf(w, "  Uso observado: média %s, pico %s\n", formatMemory(f.AvgMemory), formatMemory(f.MaxMemory))
+		fmt.Fprintf(w, "  Causa provável: %s; %s\n", f.Cause, f.diagnosis())
+	}
+	fmt.Fprintf(w, "\nObservação: o runtime é identificado pela imagem, variáveis de ambiente e comando do container; containers que rodaram %s ou mais antes do encerramento são tratados como vazamento\n",
+		formatAge(memoryLeakMinUptime))
+}
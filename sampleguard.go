@@ -0,0 +1,43 @@
+package main
+
+import "fmt"
+
+// Número mínimo padrão de leituras de um pod para recomendar requests e limites
+const defaultMinSamples = 3
+
+// guardInsufficientData marks the deployments whose usage is not enough to recommend requests and limits:
+// no pod read at least minSamples times, or every pod started after the first reading of the collection.
+// Their metrics are still reported, but no patch or suggested value is derived from them
+func guardInsufficientData(deploymentMetrics map[string]*DeploymentMetrics, metrics *MetricsData, minSamples int) int {
+	if len(metrics.ClusterSamples) == 0 {
+		return 0
+	}
+	windowStart := metrics.ClusterSamples[0].Time
+
+	guarded := 0
+	for _, dm := range deploymentMetrics {
+		if dm.MaxCPU == 0 && dm.MaxMemory == 0 {
+			continue
+		}
+		samples := 0
+		fromStart := false
+		for _, podName := range dm.Pods {
+			pm, exists := metrics.PodMetrics[podName]
+			if !exists || pm.Namespace != dm.Namespace {
+				continue
+			}
+			samples = max(samples, pm.Samples)
+			fromStart = fromStart || !pm.FirstSeen.After(windowStart)
+		}
+		switch {
+		case samples < minSamples:
+			dm.InsufficientData = fmt.Sprintf("%d leituras, mínimo de %d", samples, minSamples)
+		case !fromStart:
+			dm.InsufficientData = "todos os pods iniciaram durante a coleta"
+		default:
+			continue
+		}
+		guarded++
+	}
+	return guarded
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Pico de CPU, em millicores, abaixo do qual um container é considerado ocioso
+const idleCPUMillicores = 5
+
+// Requisições recebidas pelos proxies do service mesh na janela, por workload de destino
+const (
+	istioInboundRequestsQuery   = `sum by (destination_workload_namespace, destination_workload) (increase(istio_requests_total{reporter="destination"}[%s]))`
+	linkerdInboundRequestsQuery = `sum by (namespace, deployment) (increase(request_total{direction="inbound"}[%s]))`
+)
+
+// ProxyTraffic is the number of requests a deployment received through the mesh proxies in the window
+type ProxyTraffic struct {
+	Mesh     string
+	Requests float64
+}
+
+// ScaleToZeroCandidate is a deployment that stayed idle during the whole window while reserving requests
+type ScaleToZeroCandidate struct {
+	Namespace  string
+	Deployment string
+	Replicas   int
+	MaxCPU     int64
+	// Requests reservados por todas as réplicas
+	RequestCPU    int64
+	RequestMemory int64
+	// Tráfego visto pelos proxies; nil quando não há métricas de proxy para o deployment
+	Traffic *ProxyTraffic
+	// ScaledObject do KEDA que já escala o deployment, com mínimo acima de zero
+	Scaler *KEDAScaler
+}
+
+// queryProxyTraffic reads the inbound requests of each deployment from the Istio and Linkerd metrics in
+// Prometheus. A mesh without metrics is simply absent from the result
+func queryProxyTraffic(baseURL string, period time.Duration) (map[string]ProxyTraffic, error) {
+	window := fmt.Sprintf("%ds", int(period.Seconds()))
+	traffic := make(map[string]ProxyTraffic)
+	for _, mesh := range []struct {
+		name, query, namespaceLabel, workloadLabel string
+	}{
+		{"istio", istioInboundRequestsQuery, "destination_workload_namespace", "destination_workload"},
+		{"linkerd", linkerdInboundRequestsQuery, "namespace", "deployment"},
+	} {
+		result, err := queryPrometheus(baseURL, fmt.Sprintf(mesh.query, window))
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range result {
+			requests, err := sample.value()
+			if err != nil {
+				continue
+			}
+			key := sample.Metric[mesh.namespaceLabel] + "/" + sample.Metric[mesh.workloadLabel]
+			t := traffic[key]
+			t.Requests += requests
+			if t.Mesh == "" {
+				t.Mesh = mesh.name
+			}
+			traffic[key] = t
+		}
+	}
+	return traffic, nil
+}
+
+// findScaleToZeroCandidates lists the deployments whose containers never passed idleCPUMillicores and
+// that received no requests through the mesh proxies (when there are proxy metrics), considering only
+// deployments with a pod measured in every sample of the window. System namespaces and deployments
+// that KEDA already scales to zero are left out
+func findScaleToZeroCandidates(deploymentMetrics map[string]*DeploymentMetrics, pods []corev1.Pod, metrics *MetricsData, traffic map[string]ProxyTraffic) []ScaleToZeroCandidate {
+	podIndex := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		podIndex[pods[i].Namespace+"/"+pods[i].Name] = &pods[i]
+	}
+	samples := len(metrics.ClusterSamples)
+
+	var candidates []ScaleToZeroCandidate
+	for key, dm := range deploymentMetrics {
+		if systemAdjacentNamespaces[dm.Namespace] || dm.MaxCPU > idleCPUMillicores || samples == 0 {
+			continue
+		}
+		if dm.Scaler != nil && dm.Scaler.MinReplicas == 0 {
+			continue
+		}
+		var t *ProxyTraffic
+		if observed, exists := traffic[key]; exists {
+			if observed.Requests > 0 {
+				continue
+			}
+			t = &observed
+		}
+
+		candidate := ScaleToZeroCandidate{Namespace: dm.Namespace, Deployment: dm.Name, MaxCPU: dm.MaxCPU, Traffic: t, Scaler: dm.Scaler}
+		wholeWindow := false
+		for _, podName := range dm.Pods {
+			pod, exists := podIndex[dm.Namespace+"/"+podName]
+			if !exists || pod.Status.Phase != corev1.PodRunning {
+				continue
+			}
+			if pm, measured := metrics.PodMetrics[podName]; measured && pm.Namespace == dm.Namespace && pm.Samples >= samples {
+				wholeWindow = true
+			}
+			cpu, memory := podRequests(pod)
+			candidate.RequestCPU += cpu
+			candidate.RequestMemory += memory
+			candidate.Replicas++
+		}
+		if !wholeWindow || candidate.Replicas == 0 {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].RequestCPU != candidates[j].RequestCPU {
+			return candidates[i].RequestCPU > candidates[j].RequestCPU
+		}
+		return candidates[i].Namespace+"/"+candidates[i].Deployment < candidates[j].Namespace+"/"+candidates[j].Deployment
+	})
+	return candidates
+}
+
+// recommendation describes how to stop reserving capacity for the deployment
+func (c ScaleToZeroCandidate) recommendation() string {
+	if c.Scaler != nil {
+		return fmt.Sprintf("Definir minReplicaCount: 0 no ScaledObject %s para o KEDA desligar o deployment sem carga", c.Scaler.Name)
+	}
+	return fmt.Sprintf("Suspender (kubectl scale deployment %s -n %s --replicas=0) ou criar um ScaledObject do KEDA com minReplicaCount: 0 e um trigger de tráfego",
+		c.Deployment, c.Namespace)
+}
+
+// scaleToZeroFindings turns the candidates into low severity findings
+func scaleToZeroFindings(candidates []ScaleToZeroCandidate) []Finding {
+	findings := make([]Finding, 0, len(candidates))
+	for _, c := range candidates {
+		findings = append(findings, Finding{
+			Kind:      "ocioso",
+			Severity:  SeverityLow,
+			Title:     fmt.Sprintf("Deployment %s/%s ocioso durante toda a janela", c.Namespace, c.Deployment),
+			Namespace: c.Namespace,
+			Workload:  c.Deployment,
+			Body: fmt.Sprintf("Pico de CPU de %s em %d réplicas, reservando requests de CPU %s e Memory %s.\n\nRecomendação: %s.",
+				formatCPU(c.MaxCPU), c.Replicas, formatCPU(c.RequestCPU), formatMemory(c.RequestMemory), c.recommendation()),
+		})
+	}
+	return findings
+}
+
+func writeScaleToZeroCandidates(w io.Writer, candidates []ScaleToZeroCandidate, trafficChecked bool) {
+	fmt.Fprintf(w, "\n=== Candidatos a Scale-to-Zero ===\n")
+	fmt.Fprintf(w, "----------------------------------\n")
+	if !trafficChecked {
+		fmt.Fprintf(w, "Sem métricas de proxy (-prometheus-url): candidatos identificados apenas pelo uso de CPU\n")
+	}
+
+	if len(candidates) == 0 {
+		fmt.Fprintf(w, "Nenhum deployment ocioso durante toda a janela\n")
+		return
+	}
+
+	var totalCPU, totalMemory int64
+	for i, c := range candidates {
+		totalCPU += c.RequestCPU
+		totalMemory += c.RequestMemory
+		fmt.Fprintf(w, "\n%d. Deployment: %s (Namespace: %s)\n", i+1, c.Deployment, c.Namespace)
+		fmt.Fprintf(w, "   Uso: pico de CPU de %s em %d réplicas durante toda a janela\n", formatCPU(c.MaxCPU), c.Replicas)
+		if c.Traffic != nil {
+			fmt.Fprintf(w, "   Tráfego: nenhuma requisição recebida (%s)\n", c.Traffic.Mesh)
+		} else if trafficChecked {
+			fmt.Fprintf(w, "   Tráfego: sem métricas de proxy para o deployment\n")
+		}
+		fmt.Fprintf(w, "   Requests reservados: CPU %s, Memory %s\n", formatCPU(c.RequestCPU), formatMemory(c.RequestMemory))
+		fmt.Fprintf(w, "   Recomendação: %s\n", c.recommendation())
+	}
+	fmt.Fprintf(w, "\nTotal reservado pelos candidatos: CPU %s, Memory %s\n", formatCPU(totalCPU), formatMemory(totalMemory))
+}
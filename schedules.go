@@ -0,0 +1,424 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// Razão mínima entre o uso médio da hora de pico e o da hora de menor uso para sugerir escalonamento por horário
+const scheduleMinRatio = 2.0
+
+// Horas com uso médio de CPU a partir desta fração da hora de pico são horas de pico
+const scheduleBusyFraction = 0.5
+
+// Horas de antecedência com que as réplicas de pico são ativadas antes do horário de pico
+const scheduleWarmupHours = 1
+
+// Formas de aplicar o escalonamento por horário
+const (
+	// Gatilhos cron adicionados ao ScaledObject do KEDA existente
+	scheduleKEDATriggers = "keda-triggers"
+	// CronJobs que alteram o minReplicas do HPA existente
+	scheduleHPAMin = "hpa-min-replicas"
+	// Novo ScaledObject do KEDA apenas com gatilhos cron
+	scheduleKEDA = "keda"
+)
+
+// ScalingWindow is a block of peak hours on some days of the week; End is exclusive, up to 24
+type ScalingWindow struct {
+	Days  []time.Weekday
+	Start int
+	End   int
+}
+
+// cronDays formats the days of the window for a cron expression (0 = domingo)
+func (w ScalingWindow) cronDays() string {
+	days := make([]string, len(w.Days))
+	for i, day := range w.Days {
+		days[i] = strconv.Itoa(int(day))
+	}
+	return strings.Join(days, ",")
+}
+
+// cron returns the start and end cron expressions of the window. A window that ends at midnight ends at
+// 23:59, so the end stays in the same day
+func (w ScalingWindow) cron() (string, string) {
+	end := fmt.Sprintf("0 %d * * %s", w.End, w.cronDays())
+	if w.End == 24 {
+		end = fmt.Sprintf("59 23 * * %s", w.cronDays())
+	}
+	return fmt.Sprintf("0 %d * * %s", w.Start, w.cronDays()), end
+}
+
+func (w ScalingWindow) String() string {
+	labels := make([]string, len(w.Days))
+	for i, day := range w.Days {
+		labels[i] = string([]rune(weekdayLabels[day])[:3])
+	}
+	return fmt.Sprintf("%s %02d:00-%02d:00", strings.Join(labels, ","), w.Start, w.End%24)
+}
+
+// ScalingSchedule is the suggested time-based scaling of a deployment with a strong daily pattern: the peak
+// replicas during the windows and the base replicas outside them, from the CPU each pod handled at the peak
+type ScalingSchedule struct {
+	Namespace  string
+	Deployment string
+	Ratio      float64
+	// CPU atendida por pod no pico, usada para calcular as réplicas de cada hora
+	CPUPerPod    int64
+	PeakReplicas int32
+	BaseReplicas int32
+	Windows      []ScalingWindow
+	// Réplicas-hora por semana liberadas fora das janelas de pico
+	FreedPodHours int
+	// Dias da semana sem leituras, que herdaram as janelas de dias do mesmo tipo
+	Inferred []time.Weekday
+	Mode     string
+	// HPA ou ScaledObject existente
+	Autoscaler string
+}
+
+// listHPATargets maps the deployments to the HPA that scales them
+func listHPATargets(clientset *kubernetes.Clientset) (map[string]*autoscalingv2.HorizontalPodAutoscaler, error) {
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar HPAs: %v", err)
+	}
+	targets := make(map[string]*autoscalingv2.HorizontalPodAutoscaler)
+	for i := range hpas.Items {
+		hpa := &hpas.Items[i]
+		if hpa.Spec.ScaleTargetRef.Kind == "Deployment" {
+			targets[hpa.Namespace+"/"+hpa.Spec.ScaleTargetRef.Name] = hpa
+		}
+	}
+	return targets, nil
+}
+
+// busyHours marks the peak hours of each day of the week. Days without readings take the peak hours of
+// the observed days of the same kind (weekday or weekend), or the whole day when there are none
+func busyHours(h *HourlyUsage, threshold int64) ([7][24]bool, []time.Weekday) {
+	var busy [7][24]bool
+	var observed [7]bool
+	for day := range h.Cells {
+		for hour, c := range h.Cells[day] {
+			if c.Readings > 0 {
+				observed[day] = true
+				busy[day][hour] = c.avgCPU() >= threshold
+			}
+		}
+	}
+	var inferred []time.Weekday
+	for day := range busy {
+		if observed[day] {
+			continue
+		}
+		inferred = append(inferred, time.Weekday(day))
+		found := false
+		for other := range busy {
+			if observed[other] && isWeekend(time.Weekday(other)) == isWeekend(time.Weekday(day)) {
+				found = true
+				for hour := range busy[day] {
+					busy[day][hour] = busy[day][hour] || busy[other][hour]
+				}
+			}
+		}
+		if !found {
+			for hour := range busy[day] {
+				busy[day][hour] = true
+			}
+		}
+	}
+	// Ativar as réplicas de pico antes do horário de pico
+	for day := range busy {
+		warm := busy[day]
+		for hour := range busy[day] {
+			for ahead := 1; ahead <= scheduleWarmupHours && hour+ahead < 24; ahead++ {
+				warm[hour] = warm[hour] || busy[day][hour+ahead]
+			}
+		}
+		busy[day] = warm
+	}
+	return busy, inferred
+}
+
+// scheduleWindows groups the peak hours into blocks, joining the days with the same blocks
+func scheduleWindows(busy [7][24]bool) []ScalingWindow {
+	byBlock := make(map[[2]int][]time.Weekday)
+	var blocks [][2]int
+	for _, day := range heatmapDays {
+		for hour := 0; hour < 24; {
+			if !busy[day][hour] {
+				hour++
+				continue
+			}
+			start := hour
+			for hour < 24 && busy[day][hour] {
+				hour++
+			}
+			block := [2]int{start, hour}
+			if _, exists := byBlock[block]; !exists {
+				blocks = append(blocks, block)
+			}
+			byBlock[block] = append(byBlock[block], day)
+		}
+	}
+	windows := make([]ScalingWindow, 0, len(blocks))
+	for _, block := range blocks {
+		windows = append(windows, ScalingWindow{Days: byBlock[block], Start: block[0], End: block[1]})
+	}
+	sort.SliceStable(windows, func(i, j int) bool {
+		if windows[i].Days[0] != windows[j].Days[0] {
+			// Segunda primeiro, domingo por último, como no mapa de calor
+			return (windows[i].Days[0]+6)%7 < (windows[j].Days[0]+6)%7
+		}
+		return windows[i].Start < windows[j].Start
+	})
+	return windows
+}
+
+// suggestScalingSchedules derives a two-level schedule for the deployments whose average CPU at the peak
+// hour is at least scheduleMinRatio times that of the quietest hour. The replicas of each hour are its peak
+// CPU over the CPU each pod handled at the busiest hour; the base replicas cover every hour outside the
+// peak windows
+func suggestScalingSchedules(usages []*HourlyUsage, deploymentMetrics map[string]*DeploymentMetrics, hpaTargets map[string]*autoscalingv2.HorizontalPodAutoscaler) []*ScalingSchedule {
+	var schedules []*ScalingSchedule
+	for _, h := range usages {
+		ratio := h.diurnalRatio()
+		if ratio < scheduleMinRatio {
+			continue
+		}
+		peak, _, _ := h.extremes()
+
+		// CPU atendida por pod na hora de maior pico
+		var busiest HourlyCell
+		for day := range h.Cells {
+			for _, c := range h.Cells[day] {
+				if c.MaxCPU > busiest.MaxCPU && c.MaxPods > 0 {
+					busiest = c
+				}
+			}
+		}
+		if busiest.MaxPods == 0 || busiest.MaxCPU == 0 {
+			continue
+		}
+		perPod := busiest.MaxCPU / int64(busiest.MaxPods)
+		replicas := func(c HourlyCell) int32 {
+			return int32(max(1, math.Ceil(float64(c.MaxCPU)/float64(perPod))))
+		}
+
+		threshold := int64(float64(h.cell(peak).avgCPU()) * scheduleBusyFraction)
+		busy, inferred := busyHours(h, threshold)
+		s := &ScalingSchedule{Namespace: h.Namespace, Deployment: h.Workload, Ratio: ratio, CPUPerPod: perPod, Inferred: inferred}
+		for day := range h.Cells {
+			for hour, c := range h.Cells[day] {
+				if c.Readings == 0 {
+					continue
+				}
+				s.PeakReplicas = max(s.PeakReplicas, replicas(c))
+				if !busy[day][hour] {
+					s.BaseReplicas = max(s.BaseReplicas, replicas(c))
+				}
+			}
+		}
+		s.BaseReplicas = max(s.BaseReplicas, 1)
+		if s.PeakReplicas <= s.BaseReplicas {
+			continue
+		}
+		for day := range busy {
+			for hour := range busy[day] {
+				if !busy[day][hour] {
+					s.FreedPodHours += int(s.PeakReplicas - s.BaseReplicas)
+				}
+			}
+		}
+		s.Windows = scheduleWindows(busy)
+
+		key := h.Namespace + "/" + h.Workload
+		switch dm := deploymentMetrics[key]; {
+		case dm != nil && dm.Scaler != nil:
+			s.Mode, s.Autoscaler = scheduleKEDATriggers, dm.Scaler.Name
+		case hpaTargets[key] != nil:
+			s.Mode, s.Autoscaler = scheduleHPAMin, hpaTargets[key].Name
+		default:
+			s.Mode = scheduleKEDA
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules
+}
+
+// cronTriggers returns the KEDA cron triggers of the windows, in the timezone of the schedule
+func (s *ScalingSchedule) cronTriggers(timezone string) []map[string]interface{} {
+	triggers := make([]map[string]interface{}, 0, len(s.Windows))
+	for _, w := range s.Windows {
+		start, end := w.cron()
+		triggers = append(triggers, map[string]interface{}{
+			"type": "cron",
+			"metadata": map[string]string{
+				"timezone":        timezone,
+				"start":           start,
+				"end":             end,
+				"desiredReplicas": strconv.Itoa(int(s.PeakReplicas)),
+			},
+		})
+	}
+	return triggers
+}
+
+// scheduleObjects returns the manifests that apply the schedule: the cron triggers to add to the existing
+// ScaledObject, a new ScaledObject, or CronJobs that raise and lower the minReplicas of the HPA
+func (s *ScalingSchedule) scheduleObjects(timezone string) []map[string]interface{} {
+	metadata := func(name string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":        name,
+			"namespace":   s.Namespace,
+			"annotations": map[string]string{"performance-analyzer.io/source": "k8s-performance-analyzer"},
+		}
+	}
+	switch s.Mode {
+	case scheduleKEDATriggers:
+		// Apenas os gatilhos: o KEDA usa o maior número de réplicas entre os gatilhos existentes e os novos
+		return []map[string]interface{}{{
+			"apiVersion": "keda.sh/v1alpha1",
+			"kind":       "ScaledObject",
+			"metadata":   metadata(s.Autoscaler),
+			"spec":       map[string]interface{}{"triggers": s.cronTriggers(timezone)},
+		}}
+	case scheduleHPAMin:
+		var objects []map[string]interface{}
+		for i, w := range s.Windows {
+			start, end := w.cron()
+			for _, phase := range []struct {
+				suffix   string
+				schedule string
+				replicas int32
+			}{{"up", start, s.PeakReplicas}, {"down", end, s.BaseReplicas}} {
+				patch := fmt.Sprintf(`{"spec":{"minReplicas":%d}}`, phase.replicas)
+				objects = append(objects, map[string]interface{}{
+					"apiVersion": "batch/v1",
+					"kind":       "CronJob",
+					"metadata":   metadata(fmt.Sprintf("%s-schedule-%d-%s", s.Autoscaler, i+1, phase.suffix)),
+					"spec": map[string]interface{}{
+						"schedule":          phase.schedule,
+						"timeZone":          timezone,
+						"concurrencyPolicy": "Replace",
+						"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+							"serviceAccountName": "hpa-scheduler",
+							"restartPolicy":      "OnFailure",
+							"containers": []map[string]interface{}{{
+								"name":    "kubectl",
+								"image":   "bitnami/kubectl",
+								"command": []string{"kubectl", "patch", "hpa", s.Autoscaler, "-n", s.Namespace, "--type=merge", "-p", patch},
+							}},
+						}}}},
+					},
+				})
+			}
+		}
+		return objects
+	}
+	return []map[string]interface{}{{
+		"apiVersion": "keda.sh/v1alpha1",
+		"kind":       "ScaledObject",
+		"metadata":   metadata(s.Deployment + "-schedule"),
+		"spec": map[string]interface{}{
+			"scaleTargetRef":  map[string]string{"name": s.Deployment},
+			"minReplicaCount": s.BaseReplicas,
+			"maxReplicaCount": s.PeakReplicas,
+			"triggers":        s.cronTriggers(timezone),
+		},
+	}}
+}
+
+// scheduleTimezone returns the IANA name of the report timezone for the cron expressions
+func scheduleTimezone(location *time.Location) string {
+	if name := location.String(); name != "Local" {
+		return name
+	}
+	if tz := os.Getenv("TZ"); tz != "" {
+		return tz
+	}
+	return "UTC"
+}
+
+// writeScalingSchedules writes the manifests of the schedules in a multi-document YAML
+func writeScalingSchedules(path string, schedules []*ScalingSchedule, location *time.Location) error {
+	timezone := scheduleTimezone(location)
+	var b strings.Builder
+	b.WriteString("# Escalonamento por horário sugerido pelo k8s-performance-analyzer\n")
+	b.WriteString(fmt.Sprintf("# Horários no fuso %s; revise as réplicas e as janelas antes de aplicar\n", timezone))
+	for _, s := range schedules {
+		b.WriteString(fmt.Sprintf("# %s/%s: %d réplicas em %s, %d fora delas\n", s.Namespace, s.Deployment, s.PeakReplicas, scheduleWindowList(s.Windows), s.BaseReplicas))
+		switch s.Mode {
+		case scheduleKEDATriggers:
+			b.WriteString(fmt.Sprintf("# Adicione os gatilhos abaixo aos existentes no ScaledObject %s\n", s.Autoscaler))
+		case scheduleHPAMin:
+			b.WriteString("# Os CronJobs precisam da ServiceAccount hpa-scheduler com permissão de patch em horizontalpodautoscalers\n")
+		}
+		for _, object := range s.scheduleObjects(timezone) {
+			data, err := yaml.Marshal(object)
+			if err != nil {
+				return fmt.Errorf("erro ao gerar escalonamento de %s/%s: %v", s.Namespace, s.Deployment, err)
+			}
+			b.WriteString("---\n")
+			b.Write(data)
+		}
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("erro ao escrever escalonamento por horário: %v", err)
+	}
+	return nil
+}
+
+func scheduleWindowList(windows []ScalingWindow) string {
+	labels := make([]string, len(windows))
+	for i, w := range windows {
+		labels[i] = w.String()
+	}
+	return strings.Join(labels, "; ")
+}
+
+func writeScheduleSection(w io.Writer, schedules []*ScalingSchedule, scheduleFile string) {
+	fmt.Fprintf(w, "\n=== Escalonamento por Horário ===\n")
+	fmt.Fprintf(w, "---------------------------------\n")
+
+	if len(schedules) == 0 {
+		fmt.Fprintf(w, "Nenhum deployment com uso na hora de pico %.0fx maior que na hora de menor uso\n", scheduleMinRatio)
+		return
+	}
+	for _, s := range schedules {
+		fmt.Fprintf(w, "\nDeployment: %s (Namespace: %s) - razão pico/vale %.1fx\n", s.Deployment, s.Namespace, s.Ratio)
+		fmt.Fprintf(w, "  Réplicas: %d nas janelas de pico, %d fora delas (CPU por pod no pico: %s)\n", s.PeakReplicas, s.BaseReplicas, formatCPU(s.CPUPerPod))
+		for _, window := range s.Windows {
+			fmt.Fprintf(w, "  - %s\n", window)
+		}
+		if len(s.Inferred) > 0 {
+			fmt.Fprintf(w, "  Dias sem leituras, com as janelas de dias do mesmo tipo: %s\n", weekdayList(s.Inferred))
+		}
+		switch s.Mode {
+		case scheduleKEDATriggers:
+			fmt.Fprintf(w, "  Aplicação: gatilhos cron no ScaledObject %s\n", s.Autoscaler)
+		case scheduleHPAMin:
+			fmt.Fprintf(w, "  Aplicação: CronJobs que alteram o minReplicas do HPA %s\n", s.Autoscaler)
+		default:
+			fmt.Fprintf(w, "  Aplicação: novo ScaledObject do KEDA com gatilhos cron\n")
+		}
+		fmt.Fprintf(w, "  Economia: %d réplicas-hora por semana\n", s.FreedPodHours)
+	}
+	if scheduleFile != "" {
+		fmt.Fprintf(w, "\nManifestos: %s\n", scheduleFile)
+	}
+	fmt.Fprintf(w, "\nObservação: as réplicas de pico são ativadas %dh antes do horário de pico; as réplicas de cada hora vêm do pico de CPU da hora dividido pela CPU atendida por pod na hora mais carregada\n", scheduleWarmupHours)
+}
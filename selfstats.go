@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// Espera no rate limiter do cliente a partir da qual a chamada é contada como limitada
+const clientThrottleThreshold = 10 * time.Millisecond
+
+// CollectionStats records the analyzer's own behavior: API calls, throttling, collection latency and
+// memory, to size the run against large clusters
+type CollectionStats struct {
+	mu    sync.Mutex
+	Start time.Time
+	// Chamadas ao API server por método HTTP
+	APICalls   map[string]int
+	APIErrors  int
+	APILatency time.Duration
+	MaxLatency time.Duration
+	// Respostas 429 do API server e esperas no rate limiter do cliente (QPS/burst)
+	ServerThrottled  int
+	ClientThrottled  int
+	ClientThrottling time.Duration
+	// Duração de cada leitura da coleta
+	Iterations []time.Duration
+	PeakHeap   uint64
+	PeakSys    uint64
+	// Última resposta do API server ou leitura concluída, usada na verificação de liveness
+	LastActivity time.Time
+}
+
+func newCollectionStats() *CollectionStats {
+	now := time.Now()
+	return &CollectionStats{Start: now, LastActivity: now, APICalls: make(map[string]int)}
+}
+
+// instrumentedTransport counts the API calls and their outcome
+type instrumentedTransport struct {
+	next  http.RoundTripper
+	stats *CollectionStats
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	t.stats.mu.Lock()
+	defer t.stats.mu.Unlock()
+	t.stats.APICalls[req.Method]++
+	t.stats.LastActivity = time.Now()
+	t.stats.APILatency += elapsed
+	if elapsed > t.stats.MaxLatency {
+		t.stats.MaxLatency = elapsed
+	}
+	switch {
+	case err != nil:
+		t.stats.APIErrors++
+	case resp.StatusCode == http.StatusTooManyRequests:
+		t.stats.ServerThrottled++
+	case resp.StatusCode >= 500:
+		t.stats.APIErrors++
+	}
+	return resp, err
+}
+
+// instrumentedRateLimiter measures the time spent waiting on the client-side rate limiter
+type instrumentedRateLimiter struct {
+	flowcontrol.RateLimiter
+	stats *CollectionStats
+}
+
+func (l *instrumentedRateLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+	err := l.RateLimiter.Wait(ctx)
+	if waited := time.Since(start); waited >= clientThrottleThreshold {
+		l.stats.mu.Lock()
+		l.stats.ClientThrottled++
+		l.stats.ClientThrottling += waited
+		l.stats.mu.Unlock()
+	}
+	return err
+}
+
+// instrumentConfig returns a copy of the config whose calls are recorded in stats. Each client gets
+// its own rate limiter, with the same QPS and burst it would have without instrumentation
+func instrumentConfig(config *rest.Config, stats *CollectionStats) *rest.Config {
+	instrumented := rest.CopyConfig(config)
+	instrumented.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &instrumentedTransport{next: rt, stats: stats}
+	})
+	if instrumented.RateLimiter == nil {
+		qps, burst := instrumented.QPS, instrumented.Burst
+		if qps == 0 {
+			qps = rest.DefaultQPS
+		}
+		if burst == 0 {
+			burst = rest.DefaultBurst
+		}
+		instrumented.RateLimiter = &instrumentedRateLimiter{flowcontrol.NewTokenBucketRateLimiter(qps, burst), stats}
+	}
+	return instrumented
+}
+
+// sampleMemory updates the memory peaks of the process
+func (s *CollectionStats) sampleMemory() {
+	if s == nil {
+		return
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.PeakHeap = max(s.PeakHeap, m.HeapAlloc)
+	s.PeakSys = max(s.PeakSys, m.Sys)
+}
+
+// recordIteration records the duration of a collection reading
+func (s *CollectionStats) recordIteration(elapsed time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.Iterations = append(s.Iterations, elapsed)
+	s.LastActivity = time.Now()
+	s.mu.Unlock()
+	s.sampleMemory()
+}
+
+// touch records that the collection loop is making progress without taking a reading
+func (s *CollectionStats) touch() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.LastActivity = time.Now()
+	s.mu.Unlock()
+}
+
+// idle returns how long ago the last API call or collection reading happened
+func (s *CollectionStats) idle() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.LastActivity)
+}
+
+// totalCalls returns the number of API calls made
+func (s *CollectionStats) totalCalls() int {
+	total := 0
+	for _, count := range s.APICalls {
+		total += count
+	}
+	return total
+}
+
+func writeCollectionStats(w io.Writer, s *CollectionStats) {
+	s.sampleMemory()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(w, "\n=== Desempenho da Coleta ===\n")
+	fmt.Fprintf(w, "----------------------------\n")
+	fmt.Fprintf(w, "Duração total: %v\n", time.Since(s.Start).Round(time.Second))
+
+	total := s.totalCalls()
+	fmt.Fprintf(w, "Chamadas ao API server: %d", total)
+	methods := make([]string, 0, len(s.APICalls))
+	for method := range s.APICalls {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		fmt.Fprintf(w, " (%s: %d)", method, s.APICalls[method])
+	}
+	fmt.Fprintf(w, "\n")
+	if total > 0 {
+		fmt.Fprintf(w, "Latência das chamadas: média %v, máxima %v\n",
+			(s.APILatency / time.Duration(total)).Round(time.Millisecond), s.MaxLatency.Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "Erros (rede ou 5xx): %d\n", s.APIErrors)
+	fmt.Fprintf(w, "Respostas 429 do API server: %d\n", s.ServerThrottled)
+	fmt.Fprintf(w, "Esperas no rate limiter do cliente: %d (%v no total)\n", s.ClientThrottled, s.ClientThrottling.Round(time.Millisecond))
+
+	if len(s.Iterations) > 0 {
+		var sum, slowest time.Duration
+		for _, d := range s.Iterations {
+			sum += d
+			slowest = max(slowest, d)
+		}
+		fmt.Fprintf(w, "Leituras da coleta: %d, latência média %v, máxima %v\n",
+			len(s.Iterations), (sum / time.Duration(len(s.Iterations))).Round(time.Millisecond), slowest.Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "Memória: pico do heap %s, reservada do sistema %s\n", formatMemory(int64(s.PeakHeap)), formatMemory(int64(s.PeakSys)))
+
+	if s.ServerThrottled > 0 || s.ClientThrottling > time.Second {
+		fmt.Fprintf(w, "\nObservação: A coleta foi limitada pelo API server ou pelo rate limiter do cliente; em clusters grandes, "+
+			"prefira o Prometheus como fonte de métricas (-prometheus-url) e evite -deep-metrics.\n")
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SimulationResult holds the outcome of re-packing the pods with the proposed requests
+type SimulationResult struct {
+	TotalPods          int
+	ScheduledPods      int
+	UnschedulablePods  []string
+	TotalNodes         int
+	NodesUsed          int
+	DrainableNodes     []string
+	CurrentCPURequest  int64
+	CurrentMemRequest  int64
+	ProposedCPURequest int64
+	ProposedMemRequest int64
+	// Ocupação simulada de cada node, na ordem de preenchimento
+	Nodes []SimulatedNode
+}
+
+// SimulatedNode is the allocatable and simulated requests of a node after re-packing
+type SimulatedNode struct {
+	Name       string
+	CPU        int64
+	Memory     int64
+	UsedCPU    int64
+	UsedMemory int64
+	Pods       int64
+}
+
+type simNode struct {
+	Name        string
+	CPU         int64
+	Memory      int64
+	Pods        int64
+	UsedCPU     int64
+	UsedMem     int64
+	UsedPods    int64
+	Taints      []corev1.Taint
+	Labels      map[string]string
+	Schedulable bool
+}
+
+type simPod struct {
+	Name         string
+	Namespace    string
+	CPU          int64
+	Memory       int64
+	Tolerations  []corev1.Toleration
+	NodeSelector map[string]string
+}
+
+// podRequests returns the CPU (millicores) and memory (bytes) requests the scheduler reserves for the pod
+func podRequests(pod *corev1.Pod) (int64, int64) {
+	return effectivePodRequests(pod, func(c *corev1.Container) (int64, int64) {
+		return c.Resources.Requests.Cpu().MilliValue(), c.Resources.Requests.Memory().Value()
+	})
+}
+
+// effectivePodRequests combines the requests of the containers as the scheduler does: the app containers
+// and the sidecars (init containers that keep running) together, or the largest regular init container
+// plus the sidecars started before it, whichever is higher
+func effectivePodRequests(pod *corev1.Pod, requests func(c *corev1.Container) (int64, int64)) (int64, int64) {
+	var sidecarCPU, sidecarMemory, initCPU, initMemory int64
+	for i := range pod.Spec.InitContainers {
+		c := &pod.Spec.InitContainers[i]
+		cpu, memory := requests(c)
+		if isSidecar(c) {
+			sidecarCPU += cpu
+			sidecarMemory += memory
+			continue
+		}
+		initCPU = max(initCPU, sidecarCPU+cpu)
+		initMemory = max(initMemory, sidecarMemory+memory)
+	}
+	cpu, memory := sidecarCPU, sidecarMemory
+	for i := range pod.Spec.Containers {
+		c, m := requests(&pod.Spec.Containers[i])
+		cpu += c
+		memory += m
+	}
+	return max(cpu, initCPU), max(memory, initMemory)
+}
+
+// podPatch returns the resource patch of the deployment of the pod, if any
+func podPatch(pod *corev1.Pod, deploymentForPod map[string]*DeploymentMetrics, patchIndex map[string]*ResourcePatch) *ResourcePatch {
+	dm, exists := deploymentForPod[pod.Namespace+"/"+pod.Name]
+	if !exists {
+		return nil
+	}
+	return patchIndex[dm.Namespace+"/"+dm.Name]
+}
+
+// proposedPodRequests returns the requests the pod would have with the recommendation of each container in
+// the patch (overrides, tier headroom and policies included); containers without one, such as init
+// containers, keep their current requests
+func proposedPodRequests(pod *corev1.Pod, patch *ResourcePatch) (int64, int64) {
+	return effectivePodRequests(pod, func(c *corev1.Container) (int64, int64) {
+		if rec := patch.container(c.Name); rec != nil {
+			return rec.projectedValue(quotaRequestsCPU), rec.projectedValue(quotaRequestsMemory)
+		}
+		return c.Resources.Requests.Cpu().MilliValue(), c.Resources.Requests.Memory().Value()
+	})
+}
+
+func isDaemonSetPod(pod *corev1.Pod) bool {
+	return daemonSetOwner(pod) != ""
+}
+
+func toleratesTaints(tolerations []corev1.Toleration, taints []corev1.Taint) bool {
+	for i := range taints {
+		taint := &taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for j := range tolerations {
+			if tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// eligible reports whether the pod may run on the node: the node accepts pods, its taints are tolerated
+// and its labels match the nodeSelector of the pod
+func (n *simNode) eligible(p *simPod) bool {
+	if !n.Schedulable || !toleratesTaints(p.Tolerations, n.Taints) {
+		return false
+	}
+	for key, value := range p.NodeSelector {
+		if n.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *simNode) fits(p *simPod) bool {
+	return n.eligible(p) && n.UsedCPU+p.CPU <= n.CPU && n.UsedMem+p.Memory <= n.Memory && n.UsedPods+1 <= n.Pods
+}
+
+// simulateScheduling applies the proposed requests in memory and runs a first-fit decreasing
+// bin-packing pass over the current nodes
+func simulateScheduling(pods []corev1.Pod, nodes []corev1.Node, deploymentForPod map[string]*DeploymentMetrics, patchIndex map[string]*ResourcePatch) *SimulationResult {
+	result := &SimulationResult{TotalNodes: len(nodes)}
+
+	simNodes := make([]*simNode, 0, len(nodes))
+	for _, node := range nodes {
+		simNodes = append(simNodes, &simNode{
+			Name:        node.Name,
+			CPU:         node.Status.Allocatable.Cpu().MilliValue(),
+			Memory:      node.Status.Allocatable.Memory().Value(),
+			Pods:        node.Status.Allocatable.Pods().Value(),
+			Taints:      node.Spec.Taints,
+			Labels:      node.Labels,
+			Schedulable: !node.Spec.Unschedulable,
+		})
+	}
+
+	// Maiores nodes primeiro, para concentrar a carga e liberar os menores
+	sort.SliceStable(simNodes, func(i, j int) bool {
+		if simNodes[i].CPU != simNodes[j].CPU {
+			return simNodes[i].CPU > simNodes[j].CPU
+		}
+		return simNodes[i].Memory > simNodes[j].Memory
+	})
+
+	simPods := make([]*simPod, 0, len(pods))
+	// Um pod de cada DaemonSet ("namespace/nome"), com os maiores requests propostos entre os seus pods
+	daemonSets := make(map[string]*simPod)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		currentCPU, currentMem := podRequests(pod)
+		result.CurrentCPURequest += currentCPU
+		result.CurrentMemRequest += currentMem
+
+		cpu, memory := proposedPodRequests(pod, podPatch(pod, deploymentForPod, patchIndex))
+		result.ProposedCPURequest += cpu
+		result.ProposedMemRequest += memory
+
+		p := &simPod{
+			Name:         pod.Name,
+			Namespace:    pod.Namespace,
+			CPU:          cpu,
+			Memory:       memory,
+			Tolerations:  pod.Spec.Tolerations,
+			NodeSelector: pod.Spec.NodeSelector,
+		}
+		result.TotalPods++
+		if owner := daemonSetOwner(pod); owner != "" {
+			key := pod.Namespace + "/" + owner
+			if existing, exists := daemonSets[key]; exists {
+				existing.CPU = max(existing.CPU, p.CPU)
+				existing.Memory = max(existing.Memory, p.Memory)
+			} else {
+				daemonSets[key] = p
+			}
+			result.ScheduledPods++
+			continue
+		}
+		simPods = append(simPods, p)
+	}
+
+	// Pods de DaemonSet rodam em todos os nodes elegíveis: os requests deles são reservados em cada node
+	// antes do bin-packing, mas não definem quais nodes são necessários
+	for _, ds := range daemonSets {
+		for _, n := range simNodes {
+			if n.eligible(ds) {
+				n.UsedCPU += ds.CPU
+				n.UsedMem += ds.Memory
+				n.UsedPods++
+			}
+		}
+	}
+
+	// Pods maiores primeiro (first-fit decreasing)
+	sort.SliceStable(simPods, func(i, j int) bool {
+		if simPods[i].CPU != simPods[j].CPU {
+			return simPods[i].CPU > simPods[j].CPU
+		}
+		return simPods[i].Memory > simPods[j].Memory
+	})
+
+	usedNodes := make(map[string]bool)
+	for _, p := range simPods {
+		placed := false
+		for _, n := range simNodes {
+			if n.fits(p) {
+				n.UsedCPU += p.CPU
+				n.UsedMem += p.Memory
+				n.UsedPods++
+				usedNodes[n.Name] = true
+				placed = true
+				break
+			}
+		}
+		if placed {
+			result.ScheduledPods++
+		} else {
+			result.UnschedulablePods = append(result.UnschedulablePods, fmt.Sprintf("%s/%s", p.Namespace, p.Name))
+		}
+	}
+
+	result.NodesUsed = len(usedNodes)
+	for _, n := range simNodes {
+		if !usedNodes[n.Name] {
+			result.DrainableNodes = append(result.DrainableNodes, n.Name)
+		}
+		result.Nodes = append(result.Nodes, SimulatedNode{
+			Name:       n.Name,
+			CPU:        n.CPU,
+			Memory:     n.Memory,
+			UsedCPU:    n.UsedCPU,
+			UsedMemory: n.UsedMem,
+			Pods:       n.UsedPods,
+		})
+	}
+
+	return result
+}
+
+func writeSimulationReport(w io.Writer, result *SimulationResult) {
+	fmt.Fprintf(w, "\n=== Simulação de Agendamento (what-if) ===\n")
+	fmt.Fprintf(w, "------------------------------------------\n")
+	fmt.Fprintf(w, "Requests atuais: CPU %s, Memory %s\n", formatCPU(result.CurrentCPURequest), formatMemory(result.CurrentMemRequest))
+	fmt.Fprintf(w, "Requests propostos: CPU %s, Memory %s\n", formatCPU(result.ProposedCPURequest), formatMemory(result.ProposedMemRequest))
+	fmt.Fprintf(w, "Pods agendados: %d/%d\n", result.ScheduledPods, result.TotalPods)
+
+	if len(result.UnschedulablePods) == 0 {
+		fmt.Fprintf(w, "Todos os pods continuariam agendáveis com os requests propostos\n")
+	} else {
+		fmt.Fprintf(w, "Pods que não seriam agendados:\n")
+		for _, name := range result.UnschedulablePods {
+			fmt.Fprintf(w, "- %s\n", name)
+		}
+	}
+
+	fmt.Fprintf(w, "Nodes necessários: %d/%d\n", result.NodesUsed, result.TotalNodes)
+	fmt.Fprintf(w, "Nodes que poderiam ser drenados: %d\n", len(result.DrainableNodes))
+	for _, name := range result.DrainableNodes {
+		fmt.Fprintf(w, "- %s\n", name)
+	}
+	fmt.Fprintf(w, "\nObservação: a simulação considera os requests, o limite de pods, os taints e o nodeSelector de cada node, "+
+		"e reserva os requests dos DaemonSets em todos os nodes elegíveis. Afinidade e antiafinidade, topology spread constraints, "+
+		"portas do host, volumes ligados a zonas e PodDisruptionBudgets não são considerados: confirme os nodes drenáveis antes de removê-los\n")
+}
+
+// deploymentsByPod indexes the deployment metrics by "namespace/pod"
+func deploymentsByPod(deploymentMetrics map[string]*DeploymentMetrics) map[string]*DeploymentMetrics {
+	index := make(map[string]*DeploymentMetrics)
+	for _, dm := range deploymentMetrics {
+		for _, podName := range dm.Pods {
+			index[dm.Namespace+"/"+podName] = dm
+		}
+	}
+	return index
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func simulateTestContainer(name string, cpu int64, memoryMiB int64) corev1.Container {
+	return corev1.Container{Name: name, Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(cpu, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(memoryMiB*1024*1024, resource.BinarySI),
+	}}}
+}
+
+func TestProposedPodRequests(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	sidecar := simulateTestContainer("mesh", 100, 64)
+	sidecar.RestartPolicy = &always
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		InitContainers: []corev1.Container{sidecar, simulateTestContainer("migrate", 1500, 512)},
+		Containers:     []corev1.Container{simulateTestContainer("app", 800, 1024), simulateTestContainer("proxy", 200, 128)},
+	}}
+
+	// Atuais: CPU pelo init container (1500 + sidecar 100), memória pelos containers (1024 + 128 + 64)
+	if cpu, memory := podRequests(pod); cpu != 1600 || memory != 1216*1024*1024 {
+		t.Fatalf("requests atuais %d/%d", cpu, memory)
+	}
+
+	// Só app tem patch: proxy, o sidecar e o init container mantêm os requests atuais
+	patch := &ResourcePatch{Containers: []ContainerRecommendation{
+		{Container: "app", CurrentRequestCPU: 800, CurrentRequestMemory: 1024 * 1024 * 1024, RequestCPU: 2000, RequestMemory: 256 * 1024 * 1024},
+	}}
+	if cpu, memory := proposedPodRequests(pod, patch); cpu != 2300 || memory != 512*1024*1024+64*1024*1024 {
+		t.Fatalf("requests propostos %d/%d", cpu, memory)
+	}
+	if cpu, memory := proposedPodRequests(pod, nil); cpu != 1600 || memory != 1216*1024*1024 {
+		t.Fatalf("sem patch, requests %d/%d diferentes dos atuais", cpu, memory)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+)
+
+// isProductionNamespace reports whether the namespace matches one of the production_namespaces patterns
+func isProductionNamespace(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// SingleReplicaDeployment is a production deployment running one replica, with the check of whether two
+// replicas with half the requests (the same total cost) would hold the usage observed in the collection
+type SingleReplicaDeployment struct {
+	Namespace  string
+	Deployment string
+	// HPA com minReplicas 1 que escala o deployment (vazio sem HPA)
+	HPA string
+	// Requests atuais do pod e pico de uso observado
+	RequestCPU    int64
+	RequestMemory int64
+	MaxCPU        int64
+	MaxMemory     int64
+	// Duas réplicas com metade dos requests comportam o uso observado
+	Splittable bool
+	// Motivo quando não comportam
+	Reason string
+	// Requests por réplica com duas réplicas: metade dos atuais, ou o necessário para o pico de memória
+	SplitCPU    int64
+	SplitMemory int64
+}
+
+// findSingleReplicaDeployments lists the deployments of the production namespaces with one replica and
+// no HPA keeping at least two. Memory does not split between replicas: each of the two replicas needs the
+// peak memory of every container within half its request, while the CPU is shared by the two
+func findSingleReplicaDeployments(patterns []string, deploymentMetrics map[string]*DeploymentMetrics, metrics *MetricsData,
+	deployments map[string]*appsv1.Deployment, hpaTargets map[string]*autoscalingv2.HorizontalPodAutoscaler, overrides ContainerOverrides) []*SingleReplicaDeployment {
+	var single []*SingleReplicaDeployment
+	for key, d := range deployments {
+		if !isProductionNamespace(patterns, d.Namespace) || d.Spec.Replicas == nil || *d.Spec.Replicas != 1 {
+			continue
+		}
+		s := &SingleReplicaDeployment{Namespace: d.Namespace, Deployment: d.Name, Splittable: true}
+		if hpa := hpaTargets[key]; hpa != nil {
+			if hpa.Spec.MinReplicas != nil && *hpa.Spec.MinReplicas > 1 {
+				continue
+			}
+			s.HPA = hpa.Name
+		}
+
+		for _, container := range d.Spec.Template.Spec.Containers {
+			if overrides.skip(d.Namespace, container.Name) {
+				continue
+			}
+			requestCPU := container.Resources.Requests.Cpu().MilliValue()
+			requestMemory := container.Resources.Requests.Memory().Value()
+			limitMemory := container.Resources.Limits.Memory().Value()
+			s.RequestCPU += requestCPU
+			s.RequestMemory += requestMemory
+
+			var maxCPU, maxMemory int64
+			measured := false
+			if dm, exists := deploymentMetrics[key]; exists {
+				for _, podName := range dm.Pods {
+					pm, exists := metrics.PodMetrics[podName]
+					if !exists || pm.Namespace != dm.Namespace {
+						continue
+					}
+					if cm, exists := pm.Containers[container.Name]; exists {
+						measured = true
+						maxCPU = max(maxCPU, cm.MaxCPU)
+						maxMemory = max(maxMemory, cm.MaxMemory)
+					}
+				}
+			}
+			s.MaxCPU += maxCPU
+			s.MaxMemory += maxMemory
+			s.SplitCPU += max(requestCPU/2, minUsableCPURequest)
+			s.SplitMemory += max(requestMemory/2, roundUpMiB(maxMemory))
+
+			var reason string
+			switch {
+			case !measured:
+				reason = fmt.Sprintf("container %s sem métricas na coleta", container.Name)
+			case requestMemory == 0:
+				reason = fmt.Sprintf("container %s sem request de memória", container.Name)
+			case maxMemory > requestMemory/2:
+				reason = fmt.Sprintf("pico de memória do container %s (%s) acima da metade do request (%s)",
+					container.Name, formatMemory(maxMemory), formatMemory(requestMemory/2))
+			case limitMemory > 0 && maxMemory > limitMemory/2:
+				reason = fmt.Sprintf("pico de memória do container %s (%s) acima da metade do limite (%s)",
+					container.Name, formatMemory(maxMemory), formatMemory(limitMemory/2))
+			}
+			if reason != "" && s.Splittable {
+				s.Splittable, s.Reason = false, reason
+			}
+		}
+		single = append(single, s)
+	}
+	sort.Slice(single, func(i, j int) bool {
+		if single[i].Namespace != single[j].Namespace {
+			return single[i].Namespace < single[j].Namespace
+		}
+		return single[i].Deployment < single[j].Deployment
+	})
+	return single
+}
+
+// singleReplicaFindings reports each deployment as a medium availability risk: a node drain, an eviction
+// or a failed rollout takes the service down
+func singleReplicaFindings(single []*SingleReplicaDeployment) []Finding {
+	var findings []Finding
+	for _, s := range single {
+		body := fmt.Sprintf("O deployment roda uma única réplica em um namespace de produção: drenagens de nodes, despejos e falhas no rollout deixam o serviço indisponível. Requests atuais: CPU %s, Memory %s; pico observado: CPU %s, Memory %s.",
+			formatCPU(s.RequestCPU), formatMemory(s.RequestMemory), formatCPU(s.MaxCPU), formatMemory(s.MaxMemory))
+		if s.HPA != "" {
+			body += fmt.Sprintf(" O HPA %s permite reduzir para 1 réplica (minReplicas 1).", s.HPA)
+		}
+		var recommendation string
+		if s.Splittable {
+			recommendation = fmt.Sprintf("2 réplicas com CPU %s e Memory %s cada, sem aumento de custo, e um PodDisruptionBudget com minAvailable 1.",
+				formatCPU(s.SplitCPU), formatMemory(s.SplitMemory))
+		} else {
+			body += fmt.Sprintf(" Duas réplicas com metade dos requests não comportam o uso: %s.", s.Reason)
+			recommendation = fmt.Sprintf("2 réplicas com CPU %s e Memory %s cada (memória acima da metade do request atual, com aumento de custo), e um PodDisruptionBudget com minAvailable 1.",
+				formatCPU(s.SplitCPU), formatMemory(s.SplitMemory))
+		}
+		if s.HPA != "" {
+			recommendation += fmt.Sprintf(" No HPA %s, minReplicas 2.", s.HPA)
+		}
+		findings = append(findings, Finding{
+			Kind:      "replica-unica",
+			Severity:  SeverityMedium,
+			Title:     fmt.Sprintf("Deployment de produção %s/%s com uma única réplica", s.Namespace, s.Deployment),
+			Namespace: s.Namespace,
+			Workload:  s.Deployment,
+			Body:      body + "\n\nRecomendação: " + recommendation,
+		})
+	}
+	return findings
+}
+
+func writeSingleReplicaDeployments(w io.Writer, single []*SingleReplicaDeployment, patterns []string) {
+	fmt.Fprintf(w, "\n=== Deployments de Produção com Réplica Única ===\n")
+	fmt.Fprintf(w, "-------------------------------------------------\n")
+	fmt.Fprintf(w, "Namespaces de produção: %s\n", strings.Join(patterns, ", "))
+
+	if len(single) == 0 {
+		fmt.Fprintf(w, "Nenhum deployment de produção com uma única réplica\n")
+		return
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NAMESPACE\tDEPLOYMENT\tREQUESTS\tPICO\t2 RÉPLICAS (CADA)\tMESMO CUSTO\n")
+	for _, s := range single {
+		splittable := "sim"
+		if !s.Splittable {
+			splittable = "não: " + s.Reason
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s / %s\t%s / %s\t%s / %s\t%s\n", s.Namespace, s.Deployment,
+			formatCPU(s.RequestCPU), formatMemory(s.RequestMemory), formatCPU(s.MaxCPU), formatMemory(s.MaxMemory),
+			formatCPU(s.SplitCPU), formatMemory(s.SplitMemory), splittable)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nObservação: a CPU é dividida entre as duas réplicas, mas a memória não: cada réplica precisa do pico de memória observado dentro da metade do request e do limite atuais\n")
+}
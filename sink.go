@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Destinos suportados para as amostras da coleta
+const (
+	sinkInfluxDB    = "influxdb"
+	sinkTimescaleDB = "timescaledb"
+)
+
+// Tabela padrão das amostras no TimescaleDB
+const defaultSinkTable = "k8s_usage_samples"
+
+var sinkTablePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.]*$`)
+
+// SinkConfig configures the time-series database that receives every sample of the collection. The
+// InfluxDB token comes from INFLUX_TOKEN; the TimescaleDB password from the DSN,
+// PGPASSWORD or ~/.pgpass, and the TimescaleDB sink requires the psql binary
+type SinkConfig struct {
+	Type string `json:"type,omitempty"`
+	// InfluxDB: URL da API e bucket/org (v2) ou database (v1)
+	URL      string `json:"url,omitempty"`
+	Org      string `json:"org,omitempty"`
+	Bucket   string `json:"bucket,omitempty"`
+	Database string `json:"database,omitempty"`
+	// TimescaleDB: string de conexão do PostgreSQL e tabela das amostras
+	DSN   string `json:"dsn,omitempty"`
+	Table string `json:"table,omitempty"`
+}
+
+// table returns the configured TimescaleDB table or the default
+func (c SinkConfig) table() string {
+	if c.Table != "" {
+		return c.Table
+	}
+	return defaultSinkTable
+}
+
+// validate checks the fields required by the sink type
+func (c SinkConfig) validate() error {
+	switch c.Type {
+	case "":
+		return nil
+	case sinkInfluxDB:
+		if c.URL == "" || (c.Bucket == "" && c.Database == "") {
+			return fmt.Errorf("url e bucket (InfluxDB 2) ou database (InfluxDB 1) são obrigatórios")
+		}
+		if c.Bucket != "" && c.Org == "" {
+			return fmt.Errorf("org é obrigatório com bucket")
+		}
+	case sinkTimescaleDB:
+		if c.DSN == "" {
+			return fmt.Errorf("dsn é obrigatório")
+		}
+		if _, err := pgEnv(c.DSN); err != nil {
+			return err
+		}
+		if !sinkTablePattern.MatchString(c.table()) {
+			return fmt.Errorf("nome de tabela inválido: %s", c.Table)
+		}
+	default:
+		return fmt.Errorf("type deve ser influxdb ou timescaledb")
+	}
+	return nil
+}
+
+// checkBinaries fails when an external binary required by the sink is missing, so the run stops before
+// the collection instead of losing every sample
+func (c SinkConfig) checkBinaries() error {
+	if c.Type != sinkTimescaleDB {
+		return nil
+	}
+	if _, err := exec.LookPath("psql"); err != nil {
+		return fmt.Errorf("o sink do TimescaleDB requer o psql instalado no PATH: %v", err)
+	}
+	return nil
+}
+
+// SamplePoint is one reading of a container or node
+type SamplePoint struct {
+	Time      time.Time
+	Namespace string
+	Pod       string
+	Container string
+	Node      string
+	CPU       int64
+	Memory    int64
+}
+
+// SampleSink writes the readings of the collection to a time-series database, keeping the long-term
+// history outside the analyzer
+type SampleSink struct {
+	config  SinkConfig
+	cluster string
+	// Tabela criada nesta execução (TimescaleDB)
+	prepared bool
+}
+
+func newSampleSink(config SinkConfig, cluster string) *SampleSink {
+	if config.Type == "" {
+		return nil
+	}
+	return &SampleSink{config: config, cluster: cluster}
+}
+
+// write sends the readings of one collection iteration, including the cluster total
+func (s *SampleSink) write(points []SamplePoint, total UsageSample) error {
+	switch s.config.Type {
+	case sinkInfluxDB:
+		return s.writeInflux(points, total)
+	case sinkTimescaleDB:
+		return s.writeTimescale(points, total)
+	}
+	return nil
+}
+
+// influxEscape escapes commas, spaces and equal signs in tag values
+var influxEscape = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+func (s *SampleSink) writeInflux(points []SamplePoint, total UsageSample) error {
+	var body bytes.Buffer
+	cluster := influxEscape.Replace(s.cluster)
+	for _, p := range points {
+		if p.Node != "" {
+			fmt.Fprintf(&body, "k8s_node_usage,cluster=%s,node=%s", cluster, influxEscape.Replace(p.Node))
+		} else {
+			fmt.Fprintf(&body, "k8s_container_usage,cluster=%s,namespace=%s,pod=%s,container=%s", cluster,
+				influxEscape.Replace(p.Namespace), influxEscape.Replace(p.Pod), influxEscape.Replace(p.Container))
+		}
+		fmt.Fprintf(&body, " cpu_millicores=%di,memory_bytes=%di %d\n", p.CPU, p.Memory, p.Time.Unix())
+	}
+	fmt.Fprintf(&body, "k8s_cluster_usage,cluster=%s cpu_millicores=%di,memory_bytes=%di %d\n", cluster, total.CPU, total.Memory, total.Time.Unix())
+
+	base := strings.TrimRight(s.config.URL, "/")
+	var endpoint string
+	if s.config.Bucket != "" {
+		endpoint = base + "/api/v2/write?" + url.Values{"org": {s.config.Org}, "bucket": {s.config.Bucket}, "precision": {"s"}}.Encode()
+	} else {
+		endpoint = base + "/write?" + url.Values{"db": {s.config.Database}, "precision": {"s"}}.Encode()
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token := os.Getenv("INFLUX_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+	resp, err := trackerHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar amostras no InfluxDB: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("erro ao gravar amostras no InfluxDB: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// pgEnvironment maps the connection parameters of libpq to the environment variables read by psql
+var pgEnvironment = map[string]string{
+	"host":                 "PGHOST",
+	"hostaddr":             "PGHOSTADDR",
+	"port":                 "PGPORT",
+	"dbname":               "PGDATABASE",
+	"user":                 "PGUSER",
+	"password":             "PGPASSWORD",
+	"passfile":             "PGPASSFILE",
+	"service":              "PGSERVICE",
+	"options":              "PGOPTIONS",
+	"application_name":     "PGAPPNAME",
+	"connect_timeout":      "PGCONNECT_TIMEOUT",
+	"sslmode":              "PGSSLMODE",
+	"sslcert":              "PGSSLCERT",
+	"sslkey":               "PGSSLKEY",
+	"sslrootcert":          "PGSSLROOTCERT",
+	"sslcrl":               "PGSSLCRL",
+	"target_session_attrs": "PGTARGETSESSIONATTRS",
+}
+
+// pgEnv converts a DSN (URL postgres://... or key=value) into PG* variables, so that the password is
+// not passed to psql on the command line, where any user of the host sees it in ps
+func pgEnv(dsn string) ([]string, error) {
+	params := map[string]string{}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		// O erro do url.Parse repete a URL, com a senha; não é incluído na mensagem
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("dsn inválido: URL malformada")
+		}
+		if host := u.Hostname(); host != "" {
+			params["host"] = host
+		}
+		if port := u.Port(); port != "" {
+			params["port"] = port
+		}
+		if u.User != nil {
+			params["user"] = u.User.Username()
+			if password, ok := u.User.Password(); ok {
+				params["password"] = password
+			}
+		}
+		if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+			params["dbname"] = db
+		}
+		for key, values := range u.Query() {
+			params[key] = values[len(values)-1]
+		}
+	} else {
+		var err error
+		if params, err = parsePGKeywords(dsn); err != nil {
+			return nil, err
+		}
+	}
+
+	env := make([]string, 0, len(params))
+	for key, value := range params {
+		name, ok := pgEnvironment[key]
+		if !ok {
+			return nil, fmt.Errorf("parâmetro do dsn não suportado: %s", key)
+		}
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// parsePGKeywords parses a DSN in the key=value format of libpq, with values optionally in single
+// quotes and backslash escapes
+func parsePGKeywords(dsn string) (map[string]string, error) {
+	params := map[string]string{}
+	rest := strings.TrimSpace(dsn)
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("dsn inválido: esperado chave=valor")
+		}
+		key := strings.TrimSpace(rest[:eq])
+		rest = strings.TrimLeft(rest[eq+1:], " ")
+
+		var value strings.Builder
+		quoted := strings.HasPrefix(rest, "'")
+		if quoted {
+			rest = rest[1:]
+		}
+		i := 0
+		for ; i < len(rest); i++ {
+			c := rest[i]
+			if c == '\\' && i+1 < len(rest) {
+				i++
+				value.WriteByte(rest[i])
+				continue
+			}
+			if (quoted && c == '\'') || (!quoted && c == ' ') {
+				break
+			}
+			value.WriteByte(c)
+		}
+		if quoted {
+			if i >= len(rest) {
+				return nil, fmt.Errorf("dsn inválido: aspas não fechadas no valor de %s", key)
+			}
+			i++
+		}
+		params[key] = value.String()
+		rest = strings.TrimSpace(rest[i:])
+	}
+	return params, nil
+}
+
+// psql runs a command in psql (no PostgreSQL driver is linked into the analyzer), with stdin as input;
+// the connection goes through the PG* variables of the child process
+func (s *SampleSink) psql(command string, stdin []byte) error {
+	env, err := pgEnv(s.config.DSN)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("psql", "-v", "ON_ERROR_STOP=1", "-q", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (s *SampleSink) writeTimescale(points []SamplePoint, total UsageSample) error {
+	table := s.config.table()
+	if !s.prepared {
+		create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (time timestamptz NOT NULL, cluster text, namespace text, "+
+			"pod text, container text, node text, cpu_millicores bigint, memory_bytes bigint); "+
+			"SELECT create_hypertable('%s', 'time', if_not_exists => TRUE);", table, table)
+		if err := s.psql(create, nil); err != nil {
+			return fmt.Errorf("erro ao criar a tabela %s no TimescaleDB: %v", table, err)
+		}
+		s.prepared = true
+	}
+
+	var body bytes.Buffer
+	w := csv.NewWriter(&body)
+	for _, p := range points {
+		w.Write([]string{p.Time.UTC().Format(time.RFC3339), s.cluster, p.Namespace, p.Pod, p.Container, p.Node,
+			strconv.FormatInt(p.CPU, 10), strconv.FormatInt(p.Memory, 10)})
+	}
+	// Total do cluster: linha sem namespace, pod e node
+	w.Write([]string{total.Time.UTC().Format(time.RFC3339), s.cluster, "", "", "", "",
+		strconv.FormatInt(total.CPU, 10), strconv.FormatInt(total.Memory, 10)})
+	w.Flush()
+
+	copyCommand := fmt.Sprintf("COPY %s (time, cluster, namespace, pod, container, node, cpu_millicores, memory_bytes) FROM STDIN WITH (FORMAT csv)", table)
+	if err := s.psql(copyCommand, body.Bytes()); err != nil {
+		return fmt.Errorf("erro ao gravar amostras no TimescaleDB: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// Modos de divisão do relatório
+const (
+	splitByNamespace   = "namespace"
+	splitByLabelPrefix = "label:"
+	// Grupo dos deployments sem a label usada na divisão
+	unlabeledGroup = "sem-label"
+)
+
+// ReportGroup holds the findings of one namespace or team, written to its own report file
+type ReportGroup struct {
+	Name          string
+	Deployments   []*DeploymentMetrics
+	Unsatisfiable []UnsatisfiableRecommendation
+	Evictions     []EvictedPod
+	Patches       []ResourcePatch
+	Cost          *CostAllocation
+	File          string
+}
+
+// validateSplitBy checks the value of -split-by: "namespace" or "label:<chave>"
+func validateSplitBy(splitBy string) error {
+	if splitBy == "" || splitBy == splitByNamespace {
+		return nil
+	}
+	if label, found := strings.CutPrefix(splitBy, splitByLabelPrefix); found && label != "" {
+		return nil
+	}
+	return fmt.Errorf("valor inválido para -split-by: %s (use namespace ou label:<chave>)", splitBy)
+}
+
+// splitGroup returns the group of a workload: its namespace, or the value of the split label on the
+// deployment (or on its pod template)
+func splitGroup(splitBy, namespace, name string, deployments map[string]*appsv1.Deployment) string {
+	if splitBy == splitByNamespace {
+		return namespace
+	}
+	label := strings.TrimPrefix(splitBy, splitByLabelPrefix)
+	if d, exists := deployments[namespace+"/"+name]; exists {
+		if value := d.Labels[label]; value != "" {
+			return value
+		}
+		if value := d.Spec.Template.Labels[label]; value != "" {
+			return value
+		}
+	}
+	return unlabeledGroup
+}
+
+// groupFindings distributes the deployment-level findings among the groups, sorted by name
+func groupFindings(splitBy string, deploymentMetrics map[string]*DeploymentMetrics, deployments map[string]*appsv1.Deployment,
+	unsatisfiable []UnsatisfiableRecommendation, evicted []EvictedPod, patches []ResourcePatch, costReport *CostReport) []*ReportGroup {
+	groups := make(map[string]*ReportGroup)
+	group := func(namespace, name string) *ReportGroup {
+		key := splitGroup(splitBy, namespace, name, deployments)
+		g, exists := groups[key]
+		if !exists {
+			g = &ReportGroup{Name: key}
+			groups[key] = g
+		}
+		return g
+	}
+
+	for _, dm := range deploymentMetrics {
+		g := group(dm.Namespace, dm.Name)
+		g.Deployments = append(g.Deployments, dm)
+	}
+	for _, u := range unsatisfiable {
+		g := group(u.Namespace, u.Deployment)
+		g.Unsatisfiable = append(g.Unsatisfiable, u)
+	}
+	for _, e := range evicted {
+		g := group(e.Namespace, e.Workload)
+		g.Evictions = append(g.Evictions, e)
+	}
+	for _, p := range patches {
+		g := group(p.Namespace, p.Deployment)
+		g.Patches = append(g.Patches, p)
+	}
+
+	// O custo só é atribuído quando o rateio usa o mesmo agrupamento
+	var allocations []CostAllocation
+	if splitBy == splitByNamespace {
+		allocations = costReport.ByNamespace
+	} else if costReport.Model.Label == strings.TrimPrefix(splitBy, splitByLabelPrefix) {
+		allocations = costReport.ByLabel
+	}
+	for i := range allocations {
+		if g, exists := groups[allocations[i].Name]; exists {
+			g.Cost = &allocations[i]
+		}
+	}
+
+	result := make([]*ReportGroup, 0, len(groups))
+	for _, g := range groups {
+		sort.Slice(g.Deployments, func(i, j int) bool {
+			if g.Deployments[i].Namespace != g.Deployments[j].Namespace {
+				return g.Deployments[i].Namespace < g.Deployments[j].Namespace
+			}
+			return g.Deployments[i].Name < g.Deployments[j].Name
+		})
+		result = append(result, g)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// writeGroupReport writes the report of a single group. With onlyIssues the healthy deployments and the
+// empty sections are left out
+func writeGroupReport(w io.Writer, g *ReportGroup, context string, period time.Duration, onlyIssues bool) {
+	fmt.Fprintf(w, "Recomendações de Otimização do Kubernetes\n")
+	fmt.Fprintf(w, "Contexto: %s\n", context)
+	fmt.Fprintf(w, "Grupo: %s\n", g.Name)
+	fmt.Fprintf(w, "Período de análise: %v\n", period)
+
+	fmt.Fprintf(w, "\n=== Recomendações por Deployment ===\n")
+	fmt.Fprintf(w, "------------------------------------\n")
+	unsatisfiable := unsatisfiableSet(g.Unsatisfiable)
+	patchIndex := indexPatches(g.Patches)
+	for _, dm := range g.Deployments {
+		if onlyIssues && !deploymentHasIssues(dm, unsatisfiable) {
+			continue
+		}
+		writeDeploymentRecommendation(w, dm, patchIndex[dm.Namespace+"/"+dm.Name], period, onlyIssues)
+	}
+
+	if !onlyIssues || len(g.Unsatisfiable) > 0 {
+		writeUnsatisfiableRecommendations(w, g.Unsatisfiable)
+	}
+	if !onlyIssues || len(g.Evictions) > 0 {
+		writeEvictions(w, g.Evictions)
+	}
+	writeProposedChanges(w, g.Patches)
+
+	if g.Cost != nil && !onlyIssues {
+		fmt.Fprintf(w, "\n=== Custo Mensal Estimado ===\n")
+		fmt.Fprintf(w, "-----------------------------\n")
+		fmt.Fprintf(w, "%.2f (CPU %.2f, Memory %.2f, %d pods)\n", g.Cost.Total(), g.Cost.CPUCost, g.Cost.MemoryCost, g.Cost.Pods)
+	}
+}
+
+// writeSplitReports writes one report per group and an index into dir, returning the index path.
+// With an anonymizer the group names (also used in the file names) are replaced by their aliases
+func writeSplitReports(dir string, groups []*ReportGroup, context string, period time.Duration, onlyIssues bool, anonymizer *Anonymizer) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("erro ao criar diretório dos relatórios por grupo: %v", err)
+	}
+
+	for _, g := range groups {
+		g.File = sanitizeFilename(anonymizer.Anonymize(g.Name)) + ".txt"
+		if err := writeAnonymizedFile(filepath.Join(dir, g.File), anonymizer, func(w io.Writer) {
+			writeGroupReport(w, g, context, period, onlyIssues)
+		}); err != nil {
+			return "", fmt.Errorf("erro ao escrever relatório do grupo %s: %v", g.Name, err)
+		}
+	}
+
+	indexFile := filepath.Join(dir, "index.txt")
+	err := writeAnonymizedFile(indexFile, anonymizer, func(w io.Writer) {
+		fmt.Fprintf(w, "Relatórios por Grupo\n")
+		fmt.Fprintf(w, "Contexto: %s\n", context)
+		fmt.Fprintf(w, "--------------------\n")
+		for _, g := range groups {
+			fmt.Fprintf(w, "\nGrupo: %s\n", g.Name)
+			fmt.Fprintf(w, "  Deployments: %d\n", len(g.Deployments))
+			fmt.Fprintf(w, "  Patches propostos: %d\n", len(g.Patches))
+			fmt.Fprintf(w, "  Recomendações não agendáveis: %d\n", len(g.Unsatisfiable))
+			fmt.Fprintf(w, "  Pods despejados: %d\n", len(g.Evictions))
+			if g.Cost != nil {
+				fmt.Fprintf(w, "  Custo mensal estimado: %.2f\n", g.Cost.Total())
+			}
+			fmt.Fprintf(w, "  Arquivo: %s\n", g.File)
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("erro ao escrever índice dos relatórios por grupo: %v", err)
+	}
+	return indexFile, nil
+}
+
+// writeAnonymizedFile creates path and runs write on it, anonymizing the content when an anonymizer is set
+func writeAnonymizedFile(path string, anonymizer *Anonymizer, write func(w io.Writer)) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if anonymizer == nil {
+		write(f)
+		return nil
+	}
+	aw := newAnonymizingWriter(f, anonymizer)
+	write(aw)
+	return aw.Flush()
+}
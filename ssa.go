@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Field manager dos manifestos de Server-Side Apply: o API server registra em managedFields apenas os
+// campos de recursos dos containers como deste gerenciador
+const ssaFieldManager = "k8s-performance-analyzer"
+
+// applyConfiguration renders the patch as a partial Deployment for Server-Side Apply, containing only
+// the identity of the object and the resources of the recommended containers. Containers are merged by
+// name, so the fields owned by other managers (image, env, probes, replicas) are left untouched
+func (p ResourcePatch) applyConfiguration() ([]byte, error) {
+	containers := make([]map[string]interface{}, 0, len(p.Containers))
+	for _, c := range p.Containers {
+		containers = append(containers, map[string]interface{}{
+			"name": c.Container,
+			"resources": map[string]interface{}{
+				"requests": resourceList(c.RequestCPU, c.RequestMemory),
+				"limits":   resourceList(c.LimitCPU, c.LimitMemory),
+			},
+		})
+	}
+	return yaml.Marshal(map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]string{"name": p.Deployment, "namespace": p.Namespace},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": containers,
+				},
+			},
+		},
+	})
+}
+
+// writeSSAManifests writes the patches as a multi-document YAML to be applied with
+// kubectl apply --server-side under ssaFieldManager
+func writeSSAManifests(path string, patches []ResourcePatch) error {
+	var b strings.Builder
+	b.WriteString("# Manifestos parciais de recursos gerados pelo k8s-performance-analyzer\n")
+	fmt.Fprintf(&b, "# Aplicar com: kubectl apply --server-side --field-manager=%s -f %s\n", ssaFieldManager, path)
+	b.WriteString("# Conflitos indicam campos de recursos de outro gerenciador (kubectl, Helm, GitOps); use --force-conflicts\n")
+	b.WriteString("# apenas para transferir a posse desses campos para o analisador\n")
+	for _, p := range patches {
+		data, err := p.applyConfiguration()
+		if err != nil {
+			return fmt.Errorf("erro ao gerar manifesto de %s/%s: %v", p.Namespace, p.Deployment, err)
+		}
+		b.WriteString("---\n")
+		if p.Source != "" {
+			fmt.Fprintf(&b, "# Gerenciado por %s: aplique a alteração na origem, o manifesto será revertido na sincronização\n", p.Source)
+		}
+		writePatchDiffs(&b, p, "# ")
+		if p.Confidence != nil {
+			fmt.Fprintf(&b, "# Confiança: %s\n", p.Confidence)
+		}
+		for _, note := range p.Notes {
+			fmt.Fprintf(&b, "# %s\n", note)
+		}
+		b.Write(data)
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("erro ao escrever arquivo de manifestos: %v", err)
+	}
+	return nil
+}
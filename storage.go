@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Limites de utilização dos volumes das StatefulSets
+const (
+	// Utilização desejada do volume após o ajuste de tamanho
+	storageTargetPct = 70
+	// Abaixo disso o volumeClaimTemplate está superdimensionado
+	storageShrinkBelowPct = 30
+	// Acima disso o volume precisa crescer
+	storageGrowAbovePct = 80
+)
+
+// Anotação que marca a StorageClass padrão do cluster
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// PVCUsage is the usage of one PVC created from a volumeClaimTemplate
+type PVCUsage struct {
+	Name     string
+	Capacity int64
+	Used     int64
+	// Uso lido do summary do kubelet (falso quando o pod não está rodando)
+	Measured bool
+}
+
+// StatefulSetStorage compares the size of a volumeClaimTemplate with the usage of its PVCs
+type StatefulSetStorage struct {
+	Namespace    string
+	StatefulSet  string
+	Template     string
+	StorageClass string
+	// Tamanho pedido no volumeClaimTemplate
+	Requested int64
+	PVCs      []PVCUsage
+	PeakUsed  int64
+	PeakPct   float64
+	// Tamanho sugerido, 0 quando o atual é adequado
+	Recommended int64
+	// A StorageClass permite expandir os PVCs existentes
+	Expandable bool
+	// StorageClasses do mesmo provisionador que permitem expansão
+	Alternatives []string
+}
+
+// grow reports whether the recommendation is to increase the size
+func (s *StatefulSetStorage) grow() bool {
+	return s.Recommended > s.Requested
+}
+
+// roundUpGiB rounds a size up to a whole GiB
+func roundUpGiB(bytes int64) int64 {
+	return (bytes + gibibyte - 1) / gibibyte * gibibyte
+}
+
+// pvcVolumeUsage reads the usage of the PVCs mounted by pods on the given nodes from the kubelet summary
+func pvcVolumeUsage(clientset *kubernetes.Clientset, nodeNames []string) (map[string]KubeletFsStats, []error) {
+	usage := make(map[string]KubeletFsStats)
+	var errs []error
+	for _, nodeName := range nodeNames {
+		summary, err := fetchKubeletSummary(clientset, nodeName)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, pod := range summary.Pods {
+			for _, volume := range pod.Volumes {
+				if volume.PVCRef != nil {
+					usage[volume.PVCRef.Namespace+"/"+volume.PVCRef.Name] = volume.KubeletFsStats
+				}
+			}
+		}
+	}
+	return usage, errs
+}
+
+// analyzeStatefulSetStorage sizes the volumeClaimTemplates of the StatefulSets from the usage of their
+// PVCs: templates whose PVCs use less than storageShrinkBelowPct are shrunk and those above
+// storageGrowAbovePct are grown, both to storageTargetPct of the peak. Failures reading the usage of a
+// node are returned as warnings
+func analyzeStatefulSetStorage(clientset *kubernetes.Clientset, pods []corev1.Pod) ([]*StatefulSetStorage, []error, error) {
+	statefulSets, err := clientset.AppsV1().StatefulSets("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao listar StatefulSets: %v", err)
+	}
+	var withTemplates []*appsv1.StatefulSet
+	for i := range statefulSets.Items {
+		if len(statefulSets.Items[i].Spec.VolumeClaimTemplates) > 0 {
+			withTemplates = append(withTemplates, &statefulSets.Items[i])
+		}
+	}
+	if len(withTemplates) == 0 {
+		return nil, nil, nil
+	}
+
+	pvcs, err := clientset.CoreV1().PersistentVolumeClaims("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao listar PVCs: %v", err)
+	}
+	pvcIndex := make(map[string]*corev1.PersistentVolumeClaim, len(pvcs.Items))
+	for i := range pvcs.Items {
+		pvcIndex[pvcs.Items[i].Namespace+"/"+pvcs.Items[i].Name] = &pvcs.Items[i]
+	}
+
+	classes, err := clientset.StorageV1().StorageClasses().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao listar StorageClasses: %v", err)
+	}
+	classIndex := make(map[string]*storagev1.StorageClass, len(classes.Items))
+	defaultClass := ""
+	for i := range classes.Items {
+		class := &classes.Items[i]
+		classIndex[class.Name] = class
+		if class.Annotations[defaultStorageClassAnnotation] == "true" {
+			defaultClass = class.Name
+		}
+	}
+
+	// Ler o uso apenas nos nodes que rodam pods de StatefulSets
+	nodeSet := make(map[string]bool)
+	for i := range pods {
+		for _, owner := range pods[i].OwnerReferences {
+			if owner.Kind == "StatefulSet" && pods[i].Spec.NodeName != "" {
+				nodeSet[pods[i].Spec.NodeName] = true
+			}
+		}
+	}
+	nodeNames := make([]string, 0, len(nodeSet))
+	for name := range nodeSet {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+	volumeUsage, warnings := pvcVolumeUsage(clientset, nodeNames)
+
+	var result []*StatefulSetStorage
+	for _, sts := range withTemplates {
+		replicas := 1
+		if sts.Spec.Replicas != nil {
+			replicas = int(*sts.Spec.Replicas)
+		}
+		for _, template := range sts.Spec.VolumeClaimTemplates {
+			storage := &StatefulSetStorage{
+				Namespace:   sts.Namespace,
+				StatefulSet: sts.Name,
+				Template:    template.Name,
+				Requested:   template.Spec.Resources.Requests.Storage().Value(),
+			}
+			if template.Spec.StorageClassName != nil {
+				storage.StorageClass = *template.Spec.StorageClassName
+			} else {
+				storage.StorageClass = defaultClass
+			}
+
+			for ordinal := 0; ordinal < replicas; ordinal++ {
+				name := fmt.Sprintf("%s-%s-%d", template.Name, sts.Name, ordinal)
+				pvc, exists := pvcIndex[sts.Namespace+"/"+name]
+				if !exists {
+					continue
+				}
+				usage := PVCUsage{Name: name, Capacity: pvc.Status.Capacity.Storage().Value()}
+				if fs, measured := volumeUsage[sts.Namespace+"/"+name]; measured {
+					usage.Measured = true
+					usage.Used = uint64Value(fs.UsedBytes)
+					if capacity := uint64Value(fs.CapacityBytes); capacity > 0 {
+						usage.Capacity = capacity
+					}
+					if usage.Capacity > 0 && percent(usage.Used, usage.Capacity) > storage.PeakPct {
+						storage.PeakPct = percent(usage.Used, usage.Capacity)
+					}
+					storage.PeakUsed = max(storage.PeakUsed, usage.Used)
+				}
+				storage.PVCs = append(storage.PVCs, usage)
+			}
+
+			measured := false
+			for _, pvc := range storage.PVCs {
+				measured = measured || pvc.Measured
+			}
+			if !measured || storage.Requested == 0 {
+				continue
+			}
+
+			target := roundUpGiB(storage.PeakUsed * 100 / storageTargetPct)
+			switch {
+			case storage.PeakPct > storageGrowAbovePct:
+				storage.Recommended = max(target, storage.Requested+gibibyte)
+			case storage.PeakPct < storageShrinkBelowPct && target < storage.Requested:
+				storage.Recommended = max(target, gibibyte)
+			default:
+				continue
+			}
+
+			if class, exists := classIndex[storage.StorageClass]; exists {
+				storage.Expandable = class.AllowVolumeExpansion != nil && *class.AllowVolumeExpansion
+				if storage.grow() && !storage.Expandable {
+					for _, other := range classes.Items {
+						if other.Provisioner == class.Provisioner && other.AllowVolumeExpansion != nil && *other.AllowVolumeExpansion {
+							storage.Alternatives = append(storage.Alternatives, other.Name)
+						}
+					}
+				}
+			}
+			result = append(result, storage)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].grow() != result[j].grow() {
+			return result[i].grow()
+		}
+		return result[i].Namespace+"/"+result[i].StatefulSet+"/"+result[i].Template <
+			result[j].Namespace+"/"+result[j].StatefulSet+"/"+result[j].Template
+	})
+	return result, warnings, nil
+}
+
+// storageFindings turns the volumes close to full into findings
+func storageFindings(storage []*StatefulSetStorage) []Finding {
+	var findings []Finding
+	for _, s := range storage {
+		if !s.grow() {
+			continue
+		}
+		findings = append(findings, Finding{
+			Kind:      "volume-cheio",
+			Severity:  SeverityHigh,
+			Title:     fmt.Sprintf("Volumes %s da StatefulSet %s/%s com %.0f%% de uso", s.Template, s.Namespace, s.StatefulSet, s.PeakPct),
+			Namespace: s.Namespace,
+			Workload:  s.StatefulSet,
+			Body: fmt.Sprintf("O PVC mais cheio do volumeClaimTemplate %s usa %s (%.0f%%).\n\nRecomendação: %s",
+				s.Template, formatStorage(s.PeakUsed), s.PeakPct, s.expansionAdvice()),
+		})
+	}
+	return findings
+}
+
+// formatStorage formats a volume size in Gi
+func formatStorage(bytes int64) string {
+	return fmt.Sprintf("%.1fGi", float64(bytes)/gibibyte)
+}
+
+// expansionAdvice explains how to grow the volumes, which depends on the StorageClass
+func (s *StatefulSetStorage) expansionAdvice() string {
+	size := fmt.Sprintf("%dGi", s.Recommended/gibibyte)
+	if s.Expandable {
+		return fmt.Sprintf("expandir cada PVC para %s (kubectl patch pvc <pvc> -n %s -p '{\"spec\":{\"resources\":{\"requests\":{\"storage\":\"%s\"}}}}'). "+
+			"O volumeClaimTemplates da StatefulSet é imutável: para as novas réplicas usarem o novo tamanho, recrie a StatefulSet com "+
+			"kubectl delete statefulset %s -n %s --cascade=orphan e reaplique o manifesto com o tamanho atualizado",
+			size, s.Namespace, size, s.StatefulSet, s.Namespace)
+	}
+	advice := fmt.Sprintf("a StorageClass %s não permite expansão (allowVolumeExpansion): os dados precisam ser migrados para PVCs de %s", s.StorageClass, size)
+	if len(s.Alternatives) > 0 {
+		advice += fmt.Sprintf(", de preferência em uma classe que permita expansão futura (%s)", strings.Join(s.Alternatives, ", "))
+	}
+	return advice
+}
+
+func writeStatefulSetStorage(w io.Writer, storage []*StatefulSetStorage) {
+	fmt.Fprintf(w, "\n=== Armazenamento das StatefulSets ===\n")
+	fmt.Fprintf(w, "--------------------------------------\n")
+
+	if len(storage) == 0 {
+		fmt.Fprintf(w, "Todos os volumeClaimTemplates medidos estão entre %d%% e %d%% de uso\n", storageShrinkBelowPct, storageGrowAbovePct)
+		return
+	}
+	for _, s := range storage {
+		fmt.Fprintf(w, "\nStatefulSet: %s (Namespace: %s), volumeClaimTemplate %s\n", s.StatefulSet, s.Namespace, s.Template)
+		fmt.Fprintf(w, "StorageClass: %s (expansão: %s)\n", s.StorageClass, map[bool]string{true: "permitida", false: "não permitida"}[s.Expandable])
+		fmt.Fprintf(w, "Tamanho do template: %s; pico de uso: %s (%.0f%%)\n", formatStorage(s.Requested), formatStorage(s.PeakUsed), s.PeakPct)
+		for _, pvc := range s.PVCs {
+			if pvc.Measured {
+				fmt.Fprintf(w, "  - %s: %s de %s (%.0f%%)\n", pvc.Name, formatStorage(pvc.Used), formatStorage(pvc.Capacity), percent(pvc.Used, pvc.Capacity))
+			} else {
+				fmt.Fprintf(w, "  - %s: %s, uso não medido (pod fora de execução)\n", pvc.Name, formatStorage(pvc.Capacity))
+			}
+		}
+		if s.grow() {
+			fmt.Fprintf(w, "   Problema: Volume acima de %d%% de uso\n", storageGrowAbovePct)
+			fmt.Fprintf(w, "   Recomendação: Aumentar para %dGi: %s\n", s.Recommended/gibibyte, s.expansionAdvice())
+			fmt.Fprintf(w, "   Prioridade: Alta\n")
+		} else {
+			fmt.Fprintf(w, "   Problema: Volume superdimensionado (abaixo de %d%% de uso)\n", storageShrinkBelowPct)
+			fmt.Fprintf(w, "   Recomendação: Reduzir o volumeClaimTemplate para %dGi. PVCs não podem ser reduzidos: o novo tamanho vale para as réplicas criadas depois de recriar a StatefulSet (--cascade=orphan); as existentes precisam de migração dos dados (backup e restore ou substituição réplica a réplica)\n",
+				s.Recommended/gibibyte)
+			fmt.Fprintf(w, "   Prioridade: Baixa\n")
+		}
+	}
+}
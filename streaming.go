@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// Pods por página ao listar os pods do cluster
+const podListPageSize = 500
+
+// Protobuf nas requisições dos tipos nativos, com JSON como alternativa
+const (
+	protobufContentType       = "application/vnd.kubernetes.protobuf"
+	protobufAcceptContentType = protobufContentType + ",application/json"
+)
+
+// Anotação com o manifesto completo gravada pelo kubectl apply, que não é usada na análise
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// withProtobuf returns a copy of the config that negotiates protobuf, much smaller and cheaper to decode
+// than JSON on large pod lists. Only the built-in types support it: CRDs (dynamic client) and the
+// Metrics API keep using JSON
+func withProtobuf(config *rest.Config) *rest.Config {
+	protobuf := rest.CopyConfig(config)
+	protobuf.ContentType = protobufContentType
+	protobuf.AcceptContentTypes = protobufAcceptContentType
+	return protobuf
+}
+
+// forEachPod lists the pods of the namespace ("" for all) page by page, so only one page is decoded at a time
+func forEachPod(clientset *kubernetes.Clientset, namespace string, fn func(pod *corev1.Pod)) error {
+	opts := metav1.ListOptions{Limit: podListPageSize}
+	for {
+		page, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), opts)
+		if err != nil {
+			return err
+		}
+		for i := range page.Items {
+			fn(&page.Items[i])
+		}
+		if page.Continue == "" {
+			return nil
+		}
+		opts.Continue = page.Continue
+	}
+}
+
+// slimPod drops the fields of the pod that the analyses do not use (managed fields, environment,
+// commands, probes, volumes), which are most of the size of a pod in large clusters
+func slimPod(pod *corev1.Pod) {
+	pod.ManagedFields = nil
+	delete(pod.Annotations, lastAppliedAnnotation)
+	pod.Spec.Volumes = nil
+	pod.Spec.EphemeralContainers = nil
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			c := &containers[i]
+			c.Command, c.Args = nil, nil
+			c.Env, c.EnvFrom = nil, nil
+			c.VolumeMounts, c.VolumeDevices = nil, nil
+			c.LivenessProbe, c.ReadinessProbe, c.StartupProbe = nil, nil, nil
+			c.Lifecycle = nil
+		}
+	}
+}
+
+// listPods lists the pods of the cluster keeping only the fields used by the analyses. With more than
+// one worker the namespaces are listed in parallel; a namespace that fails is left out with a warning
+func listPods(clientset *kubernetes.Clientset, workers int) (*corev1.PodList, error) {
+	pods := &corev1.PodList{}
+	var namespaces []string
+	if workers > 1 {
+		var err error
+		namespaces, err = listNamespaceNames(clientset)
+		if err != nil {
+			fmt.Printf("⚠️  Aviso: %v (listando os pods de todos os namespaces de uma vez)\n", err)
+		}
+	}
+	if len(namespaces) == 0 {
+		err := forEachPod(clientset, "", func(pod *corev1.Pod) {
+			slimPod(pod)
+			pods.Items = append(pods.Items, *pod)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return pods, nil
+	}
+
+	byNamespace := make(map[string][]corev1.Pod, len(namespaces))
+	var mu sync.Mutex
+	errs := forEachNamespace(namespaces, workers, func(namespace string) error {
+		var items []corev1.Pod
+		err := forEachPod(clientset, namespace, func(pod *corev1.Pod) {
+			slimPod(pod)
+			items = append(items, *pod)
+		})
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		byNamespace[namespace] = items
+		mu.Unlock()
+		return nil
+	})
+	if len(errs) == len(namespaces) {
+		return nil, errs[namespaces[0]]
+	}
+	warnNamespaceErrors("erro ao listar pods", errs)
+	for _, namespace := range namespaces {
+		pods.Items = append(pods.Items, byNamespace[namespace]...)
+	}
+	return pods, nil
+}
+
+// streamPodMetrics decodes the pod metrics of the Metrics Server one item at a time, without
+// holding the whole list in memory
+func streamPodMetrics(metricsClient *MetricsAPI, fn func(pm *metricsv1beta1.PodMetrics)) error {
+	stream, err := metricsClient.streamPods()
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	decoder := json.NewDecoder(stream)
+	found, err := seekJSONArray(decoder, "items")
+	if err != nil || !found {
+		return err
+	}
+	for decoder.More() {
+		var pm metricsv1beta1.PodMetrics
+		if err := decoder.Decode(&pm); err != nil {
+			return fmt.Errorf("erro ao decodificar métricas de pod: %v", err)
+		}
+		fn(&pm)
+	}
+	return nil
+}
+
+// seekJSONArray advances the decoder to the first element of the array in the given top-level field,
+// reporting false when the field is null or absent
+func seekJSONArray(decoder *json.Decoder, field string) (bool, error) {
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return false, fmt.Errorf("resposta inesperada: esperado um objeto JSON")
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return false, err
+		}
+		if token != field {
+			// Descartar o valor de outros campos (kind, apiVersion, metadata)
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return false, err
+			}
+			continue
+		}
+		token, err = decoder.Token()
+		switch {
+		case err != nil:
+			return false, err
+		case token == nil:
+			return false, nil
+		case token != json.Delim('['):
+			return false, fmt.Errorf("resposta inesperada: campo %s não é uma lista", field)
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Suppressions a poucos dias de expirar são destacadas no relatório
+const suppressionExpiryWarningDays = 7
+
+// Suppression accepts a known finding until it expires
+type Suppression struct {
+	// Identificador do problema (tipo/namespace/workload), aceita curingas (ex: sem-limites/legado/*)
+	ID string `json:"id"`
+	// Último dia (AAAA-MM-DD) em que o problema fica suprimido
+	Expires string `json:"expires"`
+	Reason  string `json:"reason"`
+
+	expiry time.Time
+}
+
+// SuppressionFile is the format of the suppressions file
+type SuppressionFile struct {
+	Suppressions []Suppression `json:"suppressions"`
+}
+
+// SuppressedFinding is a finding left out of the report by a suppression
+type SuppressedFinding struct {
+	Finding
+	Suppression *Suppression
+}
+
+// Suppressions holds the suppressions in effect and the findings they removed
+type Suppressions struct {
+	Active  []*Suppression
+	Expired []*Suppression
+	// Problemas suprimidos, por identificador
+	Suppressed map[string]SuppressedFinding
+}
+
+// loadSuppressions reads the suppressions file. Expired suppressions are kept apart so the report can
+// point them out, and the findings they covered show up again
+func loadSuppressions(filePath string, now time.Time) (*Suppressions, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler arquivo de supressões: %v", err)
+	}
+	var file SuppressionFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("erro ao analisar arquivo de supressões %s: %v", filePath, err)
+	}
+
+	s := &Suppressions{Suppressed: make(map[string]SuppressedFinding)}
+	for i := range file.Suppressions {
+		suppression := &file.Suppressions[i]
+		if strings.Count(suppression.ID, "/") != 2 {
+			return nil, fmt.Errorf("supressão %d: id inválido %q (use tipo/namespace/workload)", i+1, suppression.ID)
+		}
+		if _, err := path.Match(suppression.ID, ""); err != nil {
+			return nil, fmt.Errorf("supressão %s: padrão inválido: %v", suppression.ID, err)
+		}
+		if strings.TrimSpace(suppression.Reason) == "" {
+			return nil, fmt.Errorf("supressão %s: reason é obrigatório", suppression.ID)
+		}
+		expiry, err := time.ParseInLocation("2006-01-02", suppression.Expires, now.Location())
+		if err != nil {
+			return nil, fmt.Errorf("supressão %s: expires inválido %q (use AAAA-MM-DD)", suppression.ID, suppression.Expires)
+		}
+		// A data de expiração é inclusiva: a supressão vale até o fim do dia
+		suppression.expiry = expiry.AddDate(0, 0, 1)
+		if !now.Before(suppression.expiry) {
+			s.Expired = append(s.Expired, suppression)
+			continue
+		}
+		s.Active = append(s.Active, suppression)
+	}
+	return s, nil
+}
+
+// match returns the active suppression covering the finding, if any
+func (s *Suppressions) match(f Finding) *Suppression {
+	for _, suppression := range s.Active {
+		if matched, _ := path.Match(suppression.ID, f.key()); matched {
+			return suppression
+		}
+	}
+	return nil
+}
+
+// filter removes the suppressed findings, recording them for the report. A nil Suppressions keeps
+// every finding
+func (s *Suppressions) filter(findings []Finding) []Finding {
+	if s == nil {
+		return findings
+	}
+	kept := findings[:0:0]
+	for _, f := range findings {
+		if suppression := s.match(f); suppression != nil {
+			s.Suppressed[f.key()] = SuppressedFinding{Finding: f, Suppression: suppression}
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// unused returns the active suppressions that matched no finding, candidates for removal
+func (s *Suppressions) unused() []*Suppression {
+	used := make(map[*Suppression]bool)
+	for _, f := range s.Suppressed {
+		used[f.Suppression] = true
+	}
+	var result []*Suppression
+	for _, suppression := range s.Active {
+		if !used[suppression] {
+			result = append(result, suppression)
+		}
+	}
+	return result
+}
+
+func writeSuppressions(w io.Writer, s *Suppressions, now time.Time) {
+	fmt.Fprintf(w, "\n=== Problemas Suprimidos ===\n")
+	fmt.Fprintf(w, "----------------------------\n")
+	fmt.Fprintf(w, "Supressões ativas: %d, expiradas: %d\n", len(s.Active), len(s.Expired))
+
+	keys := make([]string, 0, len(s.Suppressed))
+	for key := range s.Suppressed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f := s.Suppressed[key]
+		days := int(f.Suppression.expiry.Sub(now).Hours() / 24)
+		fmt.Fprintf(w, "- [%s] %s\n", f.Severity, f.Title)
+		fmt.Fprintf(w, "  ID: %s, até %s: %s\n", key, f.Suppression.Expires, f.Suppression.Reason)
+		if days < suppressionExpiryWarningDays {
+			fmt.Fprintf(w, "  ⚠️  A supressão expira em %d dias\n", days)
+		}
+	}
+
+	if unused := s.unused(); len(unused) > 0 {
+		fmt.Fprintf(w, "\nSupressões sem nenhum problema correspondente (podem ser removidas):\n")
+		for _, suppression := range unused {
+			fmt.Fprintf(w, "- %s (até %s)\n", suppression.ID, suppression.Expires)
+		}
+	}
+	if len(s.Expired) > 0 {
+		fmt.Fprintf(w, "\nSupressões expiradas (os problemas voltam a ser reportados):\n")
+		for _, suppression := range s.Expired {
+			fmt.Fprintf(w, "- %s (expirou em %s): %s\n", suppression.ID, suppression.Expires, suppression.Reason)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Anotações de namespace que substituem os limites globais para os workloads do namespace
+const (
+	// Folga, em percentual do pico observado, somada ao limite de CPU sugerido
+	cpuHeadroomAnnotation = "performance-analyzer.io/cpu-headroom"
+	// Folga, em percentual do pico observado, somada ao limite de memória sugerido
+	memoryHeadroomAnnotation = "performance-analyzer.io/memory-headroom"
+	// Uso de memória, em percentual do limite, a partir do qual o container é considerado em risco de OOMKill
+	memoryRiskAnnotation = "performance-analyzer.io/memory-limit-risk"
+)
+
+// Thresholds are the sensitivity settings applied to the workloads of a namespace
+type Thresholds struct {
+	CPUHeadroomPct     int
+	MemoryHeadroomPct  int
+	MemoryLimitRiskPct int
+}
+
+// Limites usados nos namespaces sem anotações
+var defaultThresholds = Thresholds{MemoryLimitRiskPct: memoryLimitRiskPct}
+
+// NamespaceThresholds holds the thresholds of the namespaces that override the defaults
+type NamespaceThresholds map[string]Thresholds
+
+// forNamespace returns the thresholds of the namespace, or the defaults
+func (n NamespaceThresholds) forNamespace(namespace string) Thresholds {
+	if t, exists := n[namespace]; exists {
+		return t
+	}
+	return defaultThresholds
+}
+
+// withHeadroomPct adds pct percent to the value
+func withHeadroomPct(value int64, pct int) int64 {
+	return value + value*int64(pct)/100
+}
+
+// parsePercentAnnotation reads a percentage annotation within [min, max]
+func parsePercentAnnotation(annotations map[string]string, key string, min, max int, value *int) error {
+	raw, exists := annotations[key]
+	if !exists {
+		return nil
+	}
+	pct, err := strconv.Atoi(raw)
+	if err != nil || pct < min || pct > max {
+		return fmt.Errorf("valor inválido para %s: %q (use um percentual entre %d e %d)", key, raw, min, max)
+	}
+	*value = pct
+	return nil
+}
+
+// loadNamespaceThresholds reads the threshold annotations of every namespace. An invalid annotation is
+// reported and the default is kept for it
+func loadNamespaceThresholds(clientset *kubernetes.Clientset) (NamespaceThresholds, []error) {
+	list, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, []error{fmt.Errorf("erro ao listar namespaces: %v", err)}
+	}
+
+	thresholds := make(NamespaceThresholds)
+	var errs []error
+	for _, ns := range list.Items {
+		t := defaultThresholds
+		for _, annotation := range []struct {
+			key      string
+			min, max int
+			value    *int
+		}{
+			{cpuHeadroomAnnotation, 0, 1000, &t.CPUHeadroomPct},
+			{memoryHeadroomAnnotation, 0, 1000, &t.MemoryHeadroomPct},
+			{memoryRiskAnnotation, 1, 100, &t.MemoryLimitRiskPct},
+		} {
+			if err := parsePercentAnnotation(ns.Annotations, annotation.key, annotation.min, annotation.max, annotation.value); err != nil {
+				errs = append(errs, fmt.Errorf("namespace %s: %v", ns.Name, err))
+			}
+		}
+		if t != defaultThresholds {
+			thresholds[ns.Name] = t
+		}
+	}
+	return thresholds, errs
+}
+
+func writeNamespaceThresholds(w io.Writer, thresholds NamespaceThresholds) {
+	fmt.Fprintf(w, "\n=== Limites por Namespace ===\n")
+	fmt.Fprintf(w, "-----------------------------\n")
+	fmt.Fprintf(w, "Padrão: folga de CPU %d%%, folga de memória %d%%, risco de OOMKill a partir de %d%% do limite\n",
+		defaultThresholds.CPUHeadroomPct, defaultThresholds.MemoryHeadroomPct, defaultThresholds.MemoryLimitRiskPct)
+
+	namespaces := make([]string, 0, len(thresholds))
+	for namespace := range thresholds {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		t := thresholds[namespace]
+		fmt.Fprintf(w, "- %s: folga de CPU %d%%, folga de memória %d%%, risco de OOMKill a partir de %d%% do limite\n",
+			namespace, t.CPUHeadroomPct, t.MemoryHeadroomPct, t.MemoryLimitRiskPct)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Requests de CPU abaixo deste valor (millicores) não refletem o uso de nenhum processo real
+const minUsableCPURequest = 10
+
+// Requests sugeridos são arredondados para múltiplos deste valor (millicores)
+const cpuRequestStep = 5
+
+// TinyCPURequest is a container whose CPU request is too small to mean anything: the scheduler packs its
+// pods as if they used no CPU, and an HPA on CPU utilization sees the usage as many times the request
+type TinyCPURequest struct {
+	Namespace  string
+	Deployment string
+	Container  string
+	Replicas   int32
+	RequestCPU int64
+	// Uso médio (ocioso) e pico do container nos pods do deployment
+	AvgCPU int64
+	MaxCPU int64
+	// Request sugerido: uso médio arredondado, nunca abaixo de minUsableCPURequest
+	SuggestedCPU int64
+	// HPA que escala o deployment pela utilização de CPU e a utilização que ele vê com o uso médio
+	HPA         string
+	Utilization int64
+}
+
+// findTinyCPURequests lists the containers with a CPU request above zero and below minUsableCPURequest,
+// with the mean usage observed in the collection as the suggested floor. Containers with overrides are
+// left out
+func findTinyCPURequests(deploymentMetrics map[string]*DeploymentMetrics, metrics *MetricsData, deployments map[string]*appsv1.Deployment,
+	hpaTargets map[string]*autoscalingv2.HorizontalPodAutoscaler, overrides ContainerOverrides) []*TinyCPURequest {
+	var tiny []*TinyCPURequest
+	for key, d := range deployments {
+		for _, container := range d.Spec.Template.Spec.Containers {
+			request := container.Resources.Requests.Cpu().MilliValue()
+			if request <= 0 || request >= minUsableCPURequest || overrides.skip(d.Namespace, container.Name) {
+				continue
+			}
+			t := &TinyCPURequest{Namespace: d.Namespace, Deployment: d.Name, Container: container.Name, Replicas: 1, RequestCPU: request}
+			if d.Spec.Replicas != nil {
+				t.Replicas = *d.Spec.Replicas
+			}
+
+			var usage usageVariation
+			if dm, exists := deploymentMetrics[key]; exists {
+				for _, podName := range dm.Pods {
+					pm, exists := metrics.PodMetrics[podName]
+					if !exists || pm.Namespace != dm.Namespace {
+						continue
+					}
+					if cm, exists := pm.Containers[container.Name]; exists {
+						usage.merge(cm.cpuVariation)
+						t.MaxCPU = max(t.MaxCPU, cm.MaxCPU)
+					}
+				}
+			}
+			if usage.count > 0 {
+				t.AvgCPU = int64(usage.sum / usage.count)
+			}
+			t.SuggestedCPU = max(minUsableCPURequest, (t.AvgCPU+cpuRequestStep-1)/cpuRequestStep*cpuRequestStep)
+
+			if hpa := hpaTargets[key]; hpa != nil {
+				// Sem restrição de container, a utilização do HPA é a do pod inteiro; o container pequeno a distorce da mesma forma
+				if target, exists := utilizationTargets(hpa)[corev1.ResourceCPU]; exists && (target == "" || target == container.Name) {
+					t.HPA = hpa.Name
+					t.Utilization = t.AvgCPU * 100 / request
+				}
+			}
+			tiny = append(tiny, t)
+		}
+	}
+	sort.Slice(tiny, func(i, j int) bool {
+		if tiny[i].Namespace != tiny[j].Namespace {
+			return tiny[i].Namespace < tiny[j].Namespace
+		}
+		if tiny[i].Deployment != tiny[j].Deployment {
+			return tiny[i].Deployment < tiny[j].Deployment
+		}
+		return tiny[i].Container < tiny[j].Container
+	})
+	return tiny
+}
+
+// tinyCPURequestFindings reports each container; those scaled by an HPA on CPU utilization are medium
+// severity, since the HPA keeps the deployment at the maximum replicas
+func tinyCPURequestFindings(tiny []*TinyCPURequest) []Finding {
+	var findings []Finding
+	for _, t := range tiny {
+		severity := SeverityLow
+		body := fmt.Sprintf("O container %s pede CPU %s, mas usa em média %s (pico %s): o scheduler distribui os %d pods como se não usassem CPU, sobrecarregando os nodes.",
+			t.Container, formatCPU(t.RequestCPU), formatCPU(t.AvgCPU), formatCPU(t.MaxCPU), t.Replicas)
+		if t.HPA != "" {
+			severity = SeverityMedium
+			body += fmt.Sprintf(" O HPA %s calcula a utilização sobre esse request e vê %d%% com o uso médio, escalando para o máximo de réplicas.", t.HPA, t.Utilization)
+		}
+		findings = append(findings, Finding{
+			Kind:      "cpu-request-minimo",
+			Severity:  severity,
+			Title:     fmt.Sprintf("Request de CPU de %s em %s/%s (%s)", formatCPU(t.RequestCPU), t.Namespace, t.Deployment, t.Container),
+			Namespace: t.Namespace,
+			Workload:  t.Deployment,
+			Body: body + fmt.Sprintf("\n\nRecomendação: request de CPU de %s (uso médio observado, mínimo de %s).",
+				formatCPU(t.SuggestedCPU), formatCPU(minUsableCPURequest)),
+		})
+	}
+	return findings
+}
+
+func writeTinyCPURequests(w io.Writer, tiny []*TinyCPURequest) {
+	fmt.Fprintf(w, "\n=== Requests de CPU Abaixo do Mínimo Utilizável ===\n")
+	fmt.Fprintf(w, "---------------------------------------------------\n")
+
+	if len(tiny) == 0 {
+		fmt.Fprintf(w, "Nenhum container com request de CPU abaixo de %s\n", formatCPU(minUsableCPURequest))
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NAMESPACE\tDEPLOYMENT\tCONTAINER\tREQUEST\tUSO MÉDIO\tPICO\tSUGERIDO\tHPA\n")
+	for _, t := range tiny {
+		hpa := "-"
+		if t.HPA != "" {
+			hpa = fmt.Sprintf("%s (%d%%)", t.HPA, t.Utilization)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.Namespace, t.Deployment, t.Container, formatCPU(t.RequestCPU),
+			formatCPU(t.AvgCPU), formatCPU(t.MaxCPU), formatCPU(t.SuggestedCPU), hpa)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nObservação: o request sugerido é o uso médio observado arredondado para %s, com mínimo de %s; a coluna HPA mostra a utilização de CPU que o HPA vê com o uso médio\n",
+		formatCPU(cpuRequestStep), formatCPU(minUsableCPURequest))
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// TopologySuggestion is the spread configuration proposed for a deployment whose replicas are
+// concentrated in a zone or a node
+type TopologySuggestion struct {
+	Namespace  string
+	Deployment string
+	Reasons    []string
+	// Distribuir entre zonas (topologySpreadConstraints) e entre nodes (podAntiAffinity)
+	Zone bool
+	Node bool
+	// Trecho de spec.template.spec pronto para colar no manifesto do deployment
+	Snippet string
+}
+
+// hasSpreadConstraint reports whether the pod template already spreads by the topology key
+func hasSpreadConstraint(spec *corev1.PodSpec, topologyKey string) bool {
+	for _, c := range spec.TopologySpreadConstraints {
+		if c.TopologyKey == topologyKey {
+			return true
+		}
+	}
+	if spec.Affinity != nil && spec.Affinity.PodAntiAffinity != nil {
+		for _, term := range spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			if term.TopologyKey == topologyKey {
+				return true
+			}
+		}
+		for _, term := range spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			if term.PodAffinityTerm.TopologyKey == topologyKey {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// topologySnippet renders the constraints with the selector of the deployment. Both are soft
+// (ScheduleAnyway and a preferred anti-affinity), so a scale-up beyond the zones or nodes is never blocked
+func topologySnippet(deployment *appsv1.Deployment, zone, node bool) (string, error) {
+	spec := map[string]interface{}{}
+	if zone {
+		spec["topologySpreadConstraints"] = []interface{}{map[string]interface{}{
+			"maxSkew":           1,
+			"topologyKey":       corev1.LabelTopologyZone,
+			"whenUnsatisfiable": corev1.ScheduleAnyway,
+			"labelSelector":     deployment.Spec.Selector,
+			"matchLabelKeys":    []string{appsv1.DefaultDeploymentUniqueLabelKey},
+		}}
+	}
+	if node {
+		spec["affinity"] = map[string]interface{}{
+			"podAntiAffinity": map[string]interface{}{
+				"preferredDuringSchedulingIgnoredDuringExecution": []interface{}{map[string]interface{}{
+					"weight": 100,
+					"podAffinityTerm": map[string]interface{}{
+						"topologyKey":   corev1.LabelHostname,
+						"labelSelector": deployment.Spec.Selector,
+					},
+				}},
+			},
+		}
+	}
+	data, err := yaml.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": spec}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("erro ao gerar restrições de topologia de %s/%s: %v", deployment.Namespace, deployment.Name, err)
+	}
+	return string(data), nil
+}
+
+// suggestTopology proposes spread constraints for the deployments with every replica in a single zone,
+// every replica on a single node, or several replicas on a hot node, skipping the topologies the pod
+// template already spreads by
+func suggestTopology(zones *ZoneReport, imbalance *NodeImbalance, pods []corev1.Pod, deploymentMetrics map[string]*DeploymentMetrics, deployments map[string]*appsv1.Deployment) ([]*TopologySuggestion, []error) {
+	suggestions := make(map[string]*TopologySuggestion)
+	suggestion := func(namespace, name string) *TopologySuggestion {
+		key := namespace + "/" + name
+		if _, exists := suggestions[key]; !exists {
+			suggestions[key] = &TopologySuggestion{Namespace: namespace, Deployment: name}
+		}
+		return suggestions[key]
+	}
+
+	for _, c := range zones.Concentrated {
+		s := suggestion(c.Namespace, c.Name)
+		s.Zone = true
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%d réplicas na zona %s", c.Pods, c.Zone))
+	}
+
+	podNodes := make(map[string]string, len(pods))
+	for i := range pods {
+		if pods[i].Spec.NodeName != "" && pods[i].Status.Phase == corev1.PodRunning {
+			podNodes[pods[i].Namespace+"/"+pods[i].Name] = pods[i].Spec.NodeName
+		}
+	}
+	for _, dm := range deploymentMetrics {
+		nodes := make(map[string]int)
+		for _, podName := range dm.Pods {
+			if node, exists := podNodes[dm.Namespace+"/"+podName]; exists {
+				nodes[node]++
+			}
+		}
+		for node, count := range nodes {
+			if len(nodes) == 1 && count >= 2 {
+				s := suggestion(dm.Namespace, dm.Name)
+				s.Node = true
+				s.Reasons = append(s.Reasons, fmt.Sprintf("todas as %d réplicas no node %s", count, node))
+			}
+		}
+	}
+
+	if imbalance != nil {
+		for _, hot := range imbalance.HotNodes {
+			for _, wl := range hot.Workloads {
+				if wl.Pods < 2 {
+					continue
+				}
+				if _, exists := deployments[wl.Namespace+"/"+wl.Name]; !exists {
+					continue
+				}
+				s := suggestion(wl.Namespace, wl.Name)
+				s.Node = true
+				s.Reasons = append(s.Reasons, fmt.Sprintf("%d réplicas no node quente %s", wl.Pods, hot.Name))
+			}
+		}
+	}
+
+	var result []*TopologySuggestion
+	var errs []error
+	for key, s := range suggestions {
+		deployment, exists := deployments[key]
+		if !exists || deployment.Spec.Selector == nil {
+			continue
+		}
+		s.Zone = s.Zone && !hasSpreadConstraint(&deployment.Spec.Template.Spec, corev1.LabelTopologyZone)
+		s.Node = s.Node && !hasSpreadConstraint(&deployment.Spec.Template.Spec, corev1.LabelHostname)
+		if !s.Zone && !s.Node {
+			continue
+		}
+		snippet, err := topologySnippet(deployment, s.Zone, s.Node)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.Snippet = snippet
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Deployment < result[j].Deployment
+	})
+	return result, errs
+}
+
+func writeTopologySuggestions(w io.Writer, suggestions []*TopologySuggestion) {
+	fmt.Fprintf(w, "\n=== Restrições de Topologia Sugeridas ===\n")
+	fmt.Fprintf(w, "-----------------------------------------\n")
+
+	if len(suggestions) == 0 {
+		fmt.Fprintf(w, "Nenhum deployment com réplicas concentradas em uma zona ou node sem restrição de topologia\n")
+		return
+	}
+
+	for _, s := range suggestions {
+		fmt.Fprintf(w, "\nDeployment: %s (Namespace: %s)\n", s.Deployment, s.Namespace)
+		fmt.Fprintf(w, "Problema: %s\n", strings.Join(s.Reasons, "; "))
+		fmt.Fprintf(w, "Adicionar ao manifesto:\n")
+		for _, line := range strings.Split(strings.TrimRight(s.Snippet, "\n"), "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+	fmt.Fprintf(w, "\nObservação: whenUnsatisfiable ScheduleAnyway e a anti-afinidade preferida distribuem as réplicas sem impedir o agendamento quando não há zonas ou nodes suficientes;\n")
+	fmt.Fprintf(w, "use DoNotSchedule para exigir a distribuição. matchLabelKeys requer Kubernetes 1.27 ou superior\n")
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// Nome do serviço nos traces, substituível por OTEL_SERVICE_NAME
+const defaultServiceName = "k8s-performance-analyzer"
+
+// Spans enviados por requisição ao coletor OTLP
+const otlpBatchSize = 1000
+
+// Intervalo de envio dos spans acumulados, para que watch e admission, que não terminam, também
+// exportem os seus
+const otlpFlushInterval = 10 * time.Second
+
+// Spans mantidos enquanto o coletor está indisponível; acima disso os mais antigos são descartados
+const otlpMaxBuffered = 10 * otlpBatchSize
+
+// Tipos de span e códigos de status do OTLP
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+	statusCodeError  = 2
+)
+
+// Tracer records the spans of a run (collection iterations, API calls and analysis phases) and exports
+// them to an OpenTelemetry collector over OTLP/HTTP (JSON encoding), so a slow run can be diagnosed.
+// Finished spans are exported in the background every otlpFlushInterval or as soon as a batch is full
+type Tracer struct {
+	mu       sync.Mutex
+	endpoint string
+	headers  map[string]string
+	service  string
+	traceID  string
+	root     *Span
+	// Fases abertas: as chamadas ao API server ficam sob a fase mais recente
+	stack []*Span
+	spans []otlpSpan
+	// Spans descartados com o coletor indisponível e se o último envio falhou
+	dropped int
+	failing bool
+
+	// Serializa os envios do exportador em segundo plano e do encerramento
+	exporting sync.Mutex
+	// Sinaliza um lote completo ao exportador em segundo plano
+	full chan struct{}
+}
+
+// Span is an operation in progress
+type Span struct {
+	tracer *Tracer
+	id     string
+	parent string
+	name   string
+	kind   int
+	start  time.Time
+	attrs  map[string]string
+	err    error
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// otlpHeaders parses OTEL_EXPORTER_OTLP_HEADERS (chave=valor separados por vírgula)
+func otlpHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, found := strings.Cut(pair, "=")
+		if found && strings.TrimSpace(key) != "" {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+	}
+	return headers
+}
+
+// newTracer creates the tracer of the run. Without an endpoint (flag or OTEL_EXPORTER_OTLP_ENDPOINT)
+// tracing is disabled and nil is returned; every method accepts a nil tracer
+func newTracer(endpoint string) *Tracer {
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		return nil
+	}
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = defaultServiceName
+	}
+	t := &Tracer{
+		endpoint: strings.TrimRight(endpoint, "/") + "/v1/traces",
+		headers:  otlpHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		service:  service,
+		traceID:  randomHex(16),
+		full:     make(chan struct{}, 1),
+	}
+	t.root = t.phase("execução")
+	go t.exportLoop()
+	return t
+}
+
+// exportLoop exports the finished spans periodically and whenever a batch fills up
+func (t *Tracer) exportLoop() {
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.full:
+		}
+		t.flush()
+	}
+}
+
+// start begins a span under the most recent open phase
+func (t *Tracer) start(name string, kind int) *Span {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &Span{tracer: t, id: randomHex(8), name: name, kind: kind, start: time.Now(), attrs: make(map[string]string)}
+	if len(t.stack) > 0 {
+		span.parent = t.stack[len(t.stack)-1].id
+	}
+	return span
+}
+
+// phase begins a span that becomes the parent of the spans started until it ends
+func (t *Tracer) phase(name string) *Span {
+	span := t.start(name, spanKindInternal)
+	if span != nil {
+		t.mu.Lock()
+		t.stack = append(t.stack, span)
+		t.mu.Unlock()
+	}
+	return span
+}
+
+func (s *Span) setAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	s.attrs[key] = fmt.Sprint(value)
+	s.tracer.mu.Unlock()
+}
+
+func (s *Span) setError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	s.err = err
+	s.tracer.mu.Unlock()
+}
+
+// traceparent returns the W3C trace context header that identifies the span
+func (s *Span) traceparent() string {
+	return "00-" + s.tracer.traceID + "-" + s.id + "-01"
+}
+
+// end finishes the span, closing it as a phase if it was one
+func (s *Span) end() {
+	if s == nil {
+		return
+	}
+	t := s.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.stack) - 1; i >= 0; i-- {
+		if t.stack[i] == s {
+			t.stack = append(t.stack[:i], t.stack[i+1:]...)
+			break
+		}
+	}
+	span := otlpSpan{
+		TraceID:           t.traceID,
+		SpanID:            s.id,
+		ParentSpanID:      s.parent,
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(time.Now().UnixNano(), 10),
+	}
+	for key, value := range s.attrs {
+		span.Attributes = append(span.Attributes, otlpAttribute{Key: key, Value: otlpValue{value}})
+	}
+	if s.err != nil {
+		span.Status = otlpStatus{Code: statusCodeError, Message: s.err.Error()}
+	}
+	t.spans = append(t.spans, span)
+	if excess := len(t.spans) - otlpMaxBuffered; excess > 0 {
+		t.spans = t.spans[excess:]
+		t.dropped += excess
+	}
+	if len(t.spans) >= otlpBatchSize {
+		select {
+		case t.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush exports the finished spans in batches. The spans of a failed batch are kept for the next
+// flush; the warning is printed once until an export succeeds again
+func (t *Tracer) flush() error {
+	t.exporting.Lock()
+	defer t.exporting.Unlock()
+
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+
+	for len(spans) > 0 {
+		batch := spans[:min(len(spans), otlpBatchSize)]
+		if err := t.export(batch); err != nil {
+			t.mu.Lock()
+			t.spans = append(spans, t.spans...)
+			warn := !t.failing
+			t.failing = true
+			t.mu.Unlock()
+			if warn {
+				fmt.Printf("⚠️  Aviso: %v\n", err)
+			}
+			return err
+		}
+		spans = spans[len(batch):]
+	}
+	t.mu.Lock()
+	t.failing = false
+	t.mu.Unlock()
+	return nil
+}
+
+// shutdown ends the root span and exports the spans not yet sent
+func (t *Tracer) shutdown() {
+	if t == nil {
+		return
+	}
+	t.root.end()
+	if err := t.flush(); err != nil {
+		return
+	}
+	t.mu.Lock()
+	dropped := t.dropped
+	t.mu.Unlock()
+	if dropped > 0 {
+		fmt.Printf("⚠️  Aviso: %d spans descartados com o coletor OTLP indisponível\n", dropped)
+	}
+	fmt.Printf("🔭 Trace %s enviado para %s\n", t.traceID, t.endpoint)
+}
+
+// exit marks the run as failed, exports the spans and terminates the process. os.Exit skips the
+// deferred shutdown, and failed runs are the ones that most need their trace
+func (t *Tracer) exit(code int) {
+	if t != nil {
+		t.root.setError(fmt.Errorf("execução encerrada com código de saída %d", code))
+		t.shutdown()
+	}
+	os.Exit(code)
+}
+
+func (t *Tracer) export(spans []otlpSpan) error {
+	payload := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []otlpAttribute{{Key: "service.name", Value: otlpValue{t.service}}},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": defaultServiceName},
+				"spans": spans,
+			}},
+		}},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar spans: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := trackerHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao enviar spans para o coletor OTLP: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("erro ao enviar spans para o coletor OTLP: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// tracingTransport records a client span for each API call and propagates the trace context, so the
+// calls can be matched with the audit log of the API server
+type tracingTransport struct {
+	next   http.RoundTripper
+	tracer *Tracer
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	span := t.tracer.start("HTTP "+req.Method, spanKindClient)
+	span.setAttribute("http.request.method", req.Method)
+	span.setAttribute("url.path", req.URL.Path)
+	span.setAttribute("server.address", req.URL.Host)
+	req = req.Clone(req.Context())
+	req.Header.Set("traceparent", span.traceparent())
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		span.setError(err)
+	} else {
+		span.setAttribute("http.response.status_code", resp.StatusCode)
+		if resp.StatusCode >= 400 {
+			span.setError(fmt.Errorf("HTTP %d", resp.StatusCode))
+		}
+	}
+	span.end()
+	return resp, err
+}
+
+// traceConfig returns a copy of the config whose calls are traced, or the config itself without a tracer
+func traceConfig(config *rest.Config, tracer *Tracer) *rest.Config {
+	if tracer == nil {
+		return config
+	}
+	traced := rest.CopyConfig(config)
+	traced.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &tracingTransport{next: rt, tracer: tracer}
+	})
+	return traced
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Label comum a todos os tickets e issues abertos pelo analisador
+const trackerLabel = "k8s-performance-analyzer"
+
+var trackerHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// TicketResult counts the tickets opened and the findings that already had an open ticket
+type TicketResult struct {
+	Created  []string
+	Existing int
+}
+
+// trackerFingerprint identifies an item of a cluster in the tracker, so it is not reported twice
+func trackerFingerprint(cluster, key string) string {
+	sum := sha256.Sum256([]byte(cluster + "/" + key))
+	return "kpa-" + hex.EncodeToString(sum[:])[:12]
+}
+
+// trackerRequest sends a JSON request to the API of an issue tracker and decodes the answer into result
+func trackerRequest(method, endpoint string, header http.Header, body interface{}, result interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, endpoint, payload)
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	resp, err := trackerHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Unidades de CPU e memória nas saídas do analisador
+const (
+	// Millicores e Mi (padrão)
+	unitsMilli = "milli"
+	// Cores e Gi
+	unitsCores = "cores"
+	// Millicores e Mi para valores pequenos, cores e Gi a partir de 1 core ou 1Gi
+	unitsAuto = "auto"
+)
+
+// Unidades usadas por formatCPU e formatMemory, definidas por -units
+var outputUnits = unitsMilli
+
+const (
+	mebibyte = 1024 * 1024
+	gibibyte = 1024 * mebibyte
+)
+
+// setOutputUnits selects the units of every report, console and summary output
+func setOutputUnits(units string) error {
+	switch units {
+	case unitsMilli, unitsCores, unitsAuto:
+		outputUnits = units
+		return nil
+	}
+	return fmt.Errorf("unidades inválidas: %s (use milli, cores ou auto)", units)
+}
+
+// formatCPU formats millicores in the selected units, rounding to the nearest unit shown
+func formatCPU(millis int64) string {
+	if outputUnits == unitsCores || (outputUnits == unitsAuto && abs64(millis) >= 1000) {
+		return fmt.Sprintf("%.2f cores", float64(millis)/1000)
+	}
+	return fmt.Sprintf("%dm", millis)
+}
+
+// formatMemory formats bytes in the selected units, rounding to the nearest unit shown
+func formatMemory(bytes int64) string {
+	if outputUnits == unitsCores || (outputUnits == unitsAuto && abs64(bytes) >= gibibyte) {
+		return fmt.Sprintf("%.2fGi", float64(bytes)/gibibyte)
+	}
+	return fmt.Sprintf("%dMi", int64(math.Round(float64(bytes)/mebibyte)))
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
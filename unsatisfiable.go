@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// UnsatisfiableRecommendation is a suggested pod request that no current node could accommodate
+type UnsatisfiableRecommendation struct {
+	Deployment string
+	Namespace  string
+	PodCPU     int64
+	PodMemory  int64
+	// Maior allocatable disponível entre os nodes agendáveis
+	LargestCPU    int64
+	LargestMemory int64
+}
+
+// findUnsatisfiableRecommendations checks the requests of each deployment pod with its resource patch
+// against the allocatable of the schedulable nodes and notes the ones that would leave the pod pending
+func findUnsatisfiableRecommendations(deploymentMetrics map[string]*DeploymentMetrics, pods []corev1.Pod, nodes []corev1.Node, patchIndex map[string]*ResourcePatch) []UnsatisfiableRecommendation {
+	var largestCPU, largestMemory int64
+	var schedulable []corev1.Node
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		schedulable = append(schedulable, node)
+		largestCPU = max(largestCPU, node.Status.Allocatable.Cpu().MilliValue())
+		largestMemory = max(largestMemory, node.Status.Allocatable.Memory().Value())
+	}
+	if len(schedulable) == 0 {
+		return nil
+	}
+
+	podIndex := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		podIndex[pods[i].Namespace+"/"+pods[i].Name] = &pods[i]
+	}
+
+	var result []UnsatisfiableRecommendation
+	for _, dm := range deploymentMetrics {
+		patch := patchIndex[dm.Namespace+"/"+dm.Name]
+		if patch == nil {
+			continue
+		}
+		var pod *corev1.Pod
+		for _, podName := range dm.Pods {
+			if p, exists := podIndex[dm.Namespace+"/"+podName]; exists {
+				pod = p
+				break
+			}
+		}
+		if pod == nil {
+			continue
+		}
+
+		cpu, memory := proposedPodRequests(pod, patch)
+		fits := false
+		for _, node := range schedulable {
+			if cpu <= node.Status.Allocatable.Cpu().MilliValue() && memory <= node.Status.Allocatable.Memory().Value() {
+				fits = true
+				break
+			}
+		}
+		if fits {
+			continue
+		}
+
+		result = append(result, UnsatisfiableRecommendation{
+			Deployment:    dm.Name,
+			Namespace:     dm.Namespace,
+			PodCPU:        cpu,
+			PodMemory:     memory,
+			LargestCPU:    largestCPU,
+			LargestMemory: largestMemory,
+		})
+		dm.Recommendations = append(dm.Recommendations,
+			fmt.Sprintf("Os requests sugeridos (CPU %s, Memory %s por pod) não cabem em nenhum node atual: o pod ficaria Pending",
+				formatCPU(cpu), formatMemory(memory)))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Deployment < result[j].Deployment
+	})
+	return result
+}
+
+func writeUnsatisfiableRecommendations(w io.Writer, result []UnsatisfiableRecommendation) {
+	fmt.Fprintf(w, "\n=== Recomendações Não Agendáveis ===\n")
+	fmt.Fprintf(w, "------------------------------------\n")
+
+	if len(result) == 0 {
+		fmt.Fprintf(w, "Todos os requests sugeridos cabem em pelo menos um node\n")
+		return
+	}
+
+	for _, r := range result {
+		fmt.Fprintf(w, "\nDeployment: %s (Namespace: %s)\n", r.Deployment, r.Namespace)
+		fmt.Fprintf(w, "Problema: Requests sugeridos por pod (CPU %s, Memory %s) excedem o maior node disponível (CPU %s, Memory %s)\n",
+			formatCPU(r.PodCPU), formatMemory(r.PodMemory), formatCPU(r.LargestCPU), formatMemory(r.LargestMemory))
+		// Node sugerido com folga para DaemonSets e para o overhead do sistema
+		cores := (r.PodCPU*5/4 + 999) / 1000
+		gib := (r.PodMemory*5/4 + 1<<30 - 1) >> 30
+		fmt.Fprintf(w, "Recomendação: Adicionar um pool de nodes com ao menos %d cores e %dGi de memória allocatable, "+
+			"ou dividir o workload em mais réplicas menores\n", cores, gib)
+		fmt.Fprintf(w, "Prioridade: Alta\n")
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Versões minor que o kubelet pode estar atrás do API server (política de skew a partir do 1.28)
+const maxKubeletSkew = 3
+
+// APIs removidas lidas nas próximas versões minor após a atual
+const upgradeHorizon = 2
+
+// Métrica do API server com as APIs obsoletas chamadas desde que a instância iniciou
+const deprecatedAPIsMetric = "apiserver_requested_deprecated_apis"
+
+var metricLabelPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// RemovedAPI is an API version removed in a Kubernetes release. An empty kind covers all the kinds of
+// the version
+type RemovedAPI struct {
+	APIVersion  string
+	Kind        string
+	Removed     string
+	Replacement string
+}
+
+// APIs removidas desde o 1.16, consultadas nos manifestos aplicados com kubectl apply
+var removedAPIs = []RemovedAPI{
+	{"extensions/v1beta1", "Ingress", "1.22", "networking.k8s.io/v1"},
+	{"extensions/v1beta1", "", "1.16", "apps/v1"},
+	{"apps/v1beta1", "", "1.16", "apps/v1"},
+	{"apps/v1beta2", "", "1.16", "apps/v1"},
+	{"networking.k8s.io/v1beta1", "", "1.22", "networking.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", "", "1.22", "admissionregistration.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", "", "1.22", "apiextensions.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", "", "1.22", "scheduling.k8s.io/v1"},
+	{"batch/v1beta1", "CronJob", "1.25", "batch/v1"},
+	{"policy/v1beta1", "", "1.25", "policy/v1"},
+	{"autoscaling/v2beta1", "", "1.25", "autoscaling/v2"},
+	{"discovery.k8s.io/v1beta1", "", "1.25", "discovery.k8s.io/v1"},
+	{"node.k8s.io/v1beta1", "", "1.25", "node.k8s.io/v1"},
+	{"autoscaling/v2beta2", "", "1.26", "autoscaling/v2"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "", "1.26", "flowcontrol.apiserver.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSIStorageCapacity", "1.27", "storage.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "", "1.29", "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", "", "1.32", "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// Origens do uso de uma API obsoleta
+const (
+	deprecatedSourceAPIServer = "api-server"
+	deprecatedSourceManifest  = "manifesto"
+)
+
+// DeprecatedAPIUsage is an API removed in the current version or in the next upgradeHorizon versions
+// that is still in use: called by some client, as recorded by the API server, or declared in the
+// manifest applied to a workload
+type DeprecatedAPIUsage struct {
+	APIVersion string
+	// Kind do manifesto ou recurso chamado no API server
+	Kind        string
+	Removed     string
+	Replacement string
+	Source      string
+	// Objetos com o manifesto obsoleto ("namespace/nome")
+	Objects []string
+}
+
+// KubeletSkew is a kubelet version outside the skew policy or that blocks the next control-plane upgrade
+type KubeletSkew struct {
+	Kubelet string
+	Nodes   []string
+	// Versões minor de diferença para o API server; negativo quando o kubelet é mais novo
+	Behind         int
+	Severity       Severity
+	Problem        string
+	Recommendation string
+}
+
+// UpgradeReadiness is the upgrade-readiness addendum of the report: the kubelet skew against the
+// control plane and the deprecated APIs still in use
+type UpgradeReadiness struct {
+	ServerVersion string
+	Skews         []*KubeletSkew
+	Deprecated    []*DeprecatedAPIUsage
+	// Erro ao ler as métricas do API server (sem permissão em /metrics, por exemplo)
+	MetricsErr error
+}
+
+// analyzeUpgradeReadiness compares the kubelets of the inventory with the API server version and
+// looks for deprecated APIs in the API server metrics and in the manifests of the deployments and HPAs
+func analyzeUpgradeReadiness(clientset *kubernetes.Clientset, inventory *NodeInventory, deployments map[string]*appsv1.Deployment,
+	hpaTargets map[string]*autoscalingv2.HorizontalPodAutoscaler) (*UpgradeReadiness, error) {
+	info, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar a versão do API server: %v", err)
+	}
+	server, err := utilversion.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao interpretar a versão do API server %q: %v", info.GitVersion, err)
+	}
+	r := &UpgradeReadiness{ServerVersion: info.GitVersion}
+
+	for _, group := range inventory.Groups {
+		kubelet, err := utilversion.ParseGeneric(group.Kubelet)
+		if err != nil {
+			continue
+		}
+		skew := &KubeletSkew{Kubelet: group.Kubelet, Behind: int(server.Minor()) - int(kubelet.Minor())}
+		switch {
+		case skew.Behind < 0:
+			skew.Severity = SeverityHigh
+			skew.Problem = "é mais novo que o API server, fora da política de skew do Kubernetes"
+			skew.Recommendation = "atualizar o control plane antes dos nodes; o kubelet nunca pode ser mais novo que o API server."
+		case skew.Behind > maxKubeletSkew:
+			skew.Severity = SeverityHigh
+			skew.Problem = fmt.Sprintf("está %d versões minor atrás do API server, além das %d suportadas", skew.Behind, maxKubeletSkew)
+			skew.Recommendation = fmt.Sprintf("atualizar os nodes para %s antes de qualquer nova atualização do control plane.", info.GitVersion)
+		case skew.Behind == maxKubeletSkew:
+			skew.Severity = SeverityMedium
+			skew.Problem = fmt.Sprintf("está no limite de %d versões minor atrás do API server: a próxima atualização do control plane o deixa fora da política de skew", maxKubeletSkew)
+			skew.Recommendation = "atualizar os nodes antes da próxima atualização do control plane."
+		default:
+			continue
+		}
+		// Grupos do inventário com a mesma versão do kubelet entram em um único item
+		merged := false
+		for _, existing := range r.Skews {
+			if existing.Kubelet == skew.Kubelet {
+				existing.Nodes = append(existing.Nodes, group.Nodes...)
+				merged = true
+			}
+		}
+		if !merged {
+			skew.Nodes = append(skew.Nodes, group.Nodes...)
+			r.Skews = append(r.Skews, skew)
+		}
+	}
+
+	relevant := func(removed string) bool {
+		v, err := utilversion.ParseGeneric(removed)
+		return err == nil && v.Minor() <= server.Minor()+upgradeHorizon
+	}
+
+	usages := make(map[string]*DeprecatedAPIUsage)
+	add := func(usage DeprecatedAPIUsage, object string) {
+		key := usage.Source + "|" + usage.APIVersion + "|" + usage.Kind
+		existing, exists := usages[key]
+		if !exists {
+			existing = &usage
+			usages[key] = existing
+			r.Deprecated = append(r.Deprecated, existing)
+		}
+		if object != "" {
+			existing.Objects = append(existing.Objects, object)
+		}
+	}
+
+	deprecated, err := requestedDeprecatedAPIs(clientset)
+	if err != nil {
+		r.MetricsErr = err
+	}
+	for _, usage := range deprecated {
+		if relevant(usage.Removed) {
+			add(usage, "")
+		}
+	}
+
+	manifests := make(map[string]map[string]string)
+	for key, d := range deployments {
+		manifests[key] = d.Annotations
+	}
+	for _, hpa := range hpaTargets {
+		manifests["hpa:"+hpa.Namespace+"/"+hpa.Name] = hpa.Annotations
+	}
+	for key, annotations := range manifests {
+		applied, exists := annotations[lastAppliedAnnotation]
+		if !exists {
+			continue
+		}
+		var manifest struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}
+		if err := json.Unmarshal([]byte(applied), &manifest); err != nil {
+			continue
+		}
+		api, found := findRemovedAPI(manifest.APIVersion, manifest.Kind)
+		if !found || !relevant(api.Removed) {
+			continue
+		}
+		add(DeprecatedAPIUsage{APIVersion: manifest.APIVersion, Kind: manifest.Kind, Removed: api.Removed,
+			Replacement: api.Replacement, Source: deprecatedSourceManifest}, strings.TrimPrefix(key, "hpa:"))
+	}
+
+	sort.Slice(r.Deprecated, func(i, j int) bool {
+		if r.Deprecated[i].Removed != r.Deprecated[j].Removed {
+			return utilversion.MustParseGeneric(r.Deprecated[i].Removed).LessThan(utilversion.MustParseGeneric(r.Deprecated[j].Removed))
+		}
+		return r.Deprecated[i].APIVersion+r.Deprecated[i].Kind < r.Deprecated[j].APIVersion+r.Deprecated[j].Kind
+	})
+	for _, usage := range r.Deprecated {
+		sort.Strings(usage.Objects)
+	}
+	return r, nil
+}
+
+// findRemovedAPI looks up the removal of the API version and kind, preferring the entries of the kind
+func findRemovedAPI(apiVersion, kind string) (RemovedAPI, bool) {
+	for _, api := range removedAPIs {
+		if api.APIVersion == apiVersion && api.Kind == kind {
+			return api, true
+		}
+	}
+	for _, api := range removedAPIs {
+		if api.APIVersion == apiVersion && api.Kind == "" {
+			return api, true
+		}
+	}
+	return RemovedAPI{}, false
+}
+
+// requestedDeprecatedAPIs reads apiserver_requested_deprecated_apis from the metrics of the API server
+// instance that answers the call: the deprecated APIs requested by any client since it started
+func requestedDeprecatedAPIs(clientset *kubernetes.Clientset) ([]DeprecatedAPIUsage, error) {
+	data, err := clientset.CoreV1().RESTClient().Get().AbsPath("/metrics").DoRaw(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler as métricas do API server: %v", err)
+	}
+	var usages []DeprecatedAPIUsage
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, deprecatedAPIsMetric+"{") {
+			continue
+		}
+		labels := make(map[string]string)
+		for _, match := range metricLabelPattern.FindAllStringSubmatch(line, -1) {
+			labels[match[1]] = match[2]
+		}
+		apiVersion := labels["version"]
+		if labels["group"] != "" {
+			apiVersion = labels["group"] + "/" + apiVersion
+		}
+		resource := labels["resource"]
+		if labels["subresource"] != "" {
+			resource += "/" + labels["subresource"]
+		}
+		usage := DeprecatedAPIUsage{APIVersion: apiVersion, Kind: resource, Removed: labels["removed_release"], Source: deprecatedSourceAPIServer}
+		if api, found := findRemovedAPI(apiVersion, ""); found {
+			usage.Replacement = api.Replacement
+		}
+		// APIs obsoletas sem data de remoção não bloqueiam a atualização
+		if usage.Removed != "" {
+			usages = append(usages, usage)
+		}
+	}
+	return usages, scanner.Err()
+}
+
+// severity is high for APIs already removed (the manifest fails when applied again) and medium for
+// those removed in the next versions
+func (u *DeprecatedAPIUsage) severity(server string) Severity {
+	current, err := utilversion.ParseGeneric(server)
+	removed, removedErr := utilversion.ParseGeneric(u.Removed)
+	if err == nil && removedErr == nil && removed.Minor() <= current.Minor() {
+		return SeverityHigh
+	}
+	return SeverityMedium
+}
+
+// upgradeReadinessFindings reports each kubelet version out of the skew policy and each deprecated API in use
+func upgradeReadinessFindings(r *UpgradeReadiness) []Finding {
+	if r == nil {
+		return nil
+	}
+	var findings []Finding
+	for _, skew := range r.Skews {
+		findings = append(findings, Finding{
+			Kind:     "skew-kubelet",
+			Severity: skew.Severity,
+			Title:    fmt.Sprintf("Kubelet %s com skew em relação ao API server %s (%s)", skew.Kubelet, r.ServerVersion, nodeCount(len(skew.Nodes))),
+			Workload: skew.Kubelet,
+			Body: fmt.Sprintf("Nodes: %s.\n\nO kubelet %s %s.\n\nRecomendação: %s",
+				listedNames(skew.Nodes), skew.Kubelet, skew.Problem, skew.Recommendation),
+		})
+	}
+	for _, usage := range r.Deprecated {
+		var body string
+		if usage.Source == deprecatedSourceAPIServer {
+			body = fmt.Sprintf("O API server registrou chamadas a %s em %s, removida no Kubernetes %s; o cliente que faz as chamadas para de funcionar na atualização.",
+				usage.Kind, usage.APIVersion, usage.Removed)
+		} else {
+			body = fmt.Sprintf("Os manifestos aplicados com kubectl apply declaram %s %s, removida no Kubernetes %s: %s.",
+				usage.Kind, usage.APIVersion, usage.Removed, strings.Join(usage.Objects, ", "))
+		}
+		recommendation := "migrar para a versão estável da API."
+		if usage.Replacement != "" {
+			recommendation = fmt.Sprintf("migrar para %s.", usage.Replacement)
+		}
+		findings = append(findings, Finding{
+			Kind:     "api-obsoleta",
+			Severity: usage.severity(r.ServerVersion),
+			Title:    fmt.Sprintf("API %s (%s) removida no Kubernetes %s em uso", usage.APIVersion, usage.Kind, usage.Removed),
+			Workload: usage.Source + "/" + usage.APIVersion + "/" + usage.Kind,
+			Body:     body + "\n\nRecomendação: " + recommendation,
+		})
+	}
+	return findings
+}
+
+func writeUpgradeReadiness(w io.Writer, r *UpgradeReadiness) {
+	fmt.Fprintf(w, "\n=== Prontidão para Atualização ===\n")
+	fmt.Fprintf(w, "----------------------------------\n")
+	fmt.Fprintf(w, "Versão do API server: %s\n", r.ServerVersion)
+
+	fmt.Fprintf(w, "\nSkew dos kubelets:\n")
+	if len(r.Skews) == 0 {
+		fmt.Fprintf(w, "Todos os kubelets dentro da política de skew (até %d versões minor atrás do API server)\n", maxKubeletSkew)
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "KUBELET\tNODES\tDIFERENÇA\tPROBLEMA\n")
+		for _, skew := range r.Skews {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", skew.Kubelet, len(skew.Nodes), skew.Behind, skew.Problem)
+		}
+		tw.Flush()
+	}
+
+	fmt.Fprintf(w, "\nAPIs obsoletas em uso (removidas até %d versões minor após a atual):\n", upgradeHorizon)
+	if r.MetricsErr != nil {
+		fmt.Fprintf(w, "Chamadas do API server não verificadas: %v\n", r.MetricsErr)
+	}
+	if len(r.Deprecated) == 0 {
+		fmt.Fprintf(w, "Nenhuma API obsoleta encontrada\n")
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "API\tRECURSO\tREMOVIDA EM\tSUBSTITUTA\tORIGEM\tOBJETOS\n")
+		for _, usage := range r.Deprecated {
+			replacement, objects := usage.Replacement, "-"
+			if replacement == "" {
+				replacement = "-"
+			}
+			if len(usage.Objects) > 0 {
+				objects = listedNames(usage.Objects)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", usage.APIVersion, usage.Kind, usage.Removed, replacement, usage.Source, objects)
+		}
+		tw.Flush()
+	}
+	fmt.Fprintf(w, "\nObservação: as chamadas vêm das métricas da instância do API server que respondeu, desde o seu último início; os manifestos são os gravados pelo kubectl apply nos deployments e HPAs\n")
+}
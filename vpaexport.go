@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// vpaRecommendation renders the patch as the status.recommendation of a VerticalPodAutoscaler
+// (RecommendedPodResources): target and uncappedTarget are the recommended requests, lowerBound the
+// smaller of the recommended and current requests and upperBound the recommended limits
+func (p ResourcePatch) vpaRecommendation() map[string]interface{} {
+	containers := make([]map[string]interface{}, 0, len(p.Containers))
+	for _, c := range p.Containers {
+		lowerCPU, lowerMemory := c.RequestCPU, c.RequestMemory
+		if c.CurrentRequestCPU > 0 {
+			lowerCPU = min(lowerCPU, c.CurrentRequestCPU)
+		}
+		if c.CurrentRequestMemory > 0 {
+			lowerMemory = min(lowerMemory, c.CurrentRequestMemory)
+		}
+		containers = append(containers, map[string]interface{}{
+			"containerName":  c.Container,
+			"target":         resourceList(c.RequestCPU, c.RequestMemory),
+			"lowerBound":     resourceList(lowerCPU, lowerMemory),
+			"upperBound":     resourceList(max(c.LimitCPU, c.RequestCPU), max(c.LimitMemory, c.RequestMemory)),
+			"uncappedTarget": resourceList(c.RequestCPU, c.RequestMemory),
+		})
+	}
+	return map[string]interface{}{"containerRecommendations": containers}
+}
+
+// vpaObject returns a VerticalPodAutoscaler in recommendation-only mode (updateMode Off) targeting the
+// deployment, with the recommendation of the analyzer in its status
+func (p ResourcePatch) vpaObject() ([]byte, error) {
+	return yaml.Marshal(map[string]interface{}{
+		"apiVersion": "autoscaling.k8s.io/v1",
+		"kind":       "VerticalPodAutoscaler",
+		"metadata": map[string]interface{}{
+			"name":      p.Deployment,
+			"namespace": p.Namespace,
+			"annotations": map[string]string{
+				"performance-analyzer.io/source": "k8s-performance-analyzer",
+			},
+		},
+		"spec": map[string]interface{}{
+			"targetRef":    map[string]string{"apiVersion": "apps/v1", "kind": "Deployment", "name": p.Deployment},
+			"updatePolicy": map[string]string{"updateMode": "Off"},
+		},
+		"status": map[string]interface{}{
+			"recommendation": p.vpaRecommendation(),
+		},
+	})
+}
+
+// writeVPARecommendations writes the patches as VerticalPodAutoscaler objects, in a multi-document YAML
+// readable by the tools built around the VPA recommender output
+func writeVPARecommendations(path string, patches []ResourcePatch) error {
+	var b strings.Builder
+	b.WriteString("# Recomendações do k8s-performance-analyzer no formato de status do VerticalPodAutoscaler\n")
+	b.WriteString("# O status é ignorado pelo kubectl apply: leia os objetos diretamente ou grave o status pelo subresource /status\n")
+	for _, p := range patches {
+		data, err := p.vpaObject()
+		if err != nil {
+			return fmt.Errorf("erro ao gerar recomendação VPA de %s/%s: %v", p.Namespace, p.Deployment, err)
+		}
+		b.WriteString("---\n")
+		b.Write(data)
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("erro ao escrever arquivo de recomendações VPA: %v", err)
+	}
+	return nil
+}
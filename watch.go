@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Intervalo padrão entre as atualizações do watch
+const defaultWatchInterval = 10 * time.Second
+
+// Pods exibidos em cada tabela de maiores consumidores
+const watchTopPods = 10
+
+// Pods pendentes há mais tempo que isso aparecem como problema
+const watchPendingAfter = time.Minute
+
+// Sequência ANSI que limpa o terminal e volta o cursor ao início
+const clearScreen = "\033[H\033[2J"
+
+// WatchIssue is a finding shown by watch, with the moment it first appeared
+type WatchIssue struct {
+	Finding
+	FirstSeen time.Time
+	// Surgiu depois do início do watch
+	New bool
+}
+
+// PodUsage is the current usage of a pod compared with its limits (0 when a container has no limit)
+type PodUsage struct {
+	Namespace   string
+	Name        string
+	CPU         int64
+	Memory      int64
+	LimitCPU    int64
+	LimitMemory int64
+}
+
+// Watch refreshes a compact view of the top consumers and of the issues as they appear, for operators
+// following an incident live instead of waiting for a report
+type Watch struct {
+	clientset     *kubernetes.Clientset
+	metricsClient *MetricsAPI
+	context       string
+	interval      time.Duration
+	workers       int
+	overcommitPct int
+	thresholds    NamespaceThresholds
+	location      *time.Location
+	suppressions  *Suppressions
+	overrides     ContainerOverrides
+	// Janela deslizante dos picos (0 = apenas a leitura atual)
+	window time.Duration
+
+	refreshes int
+	restarts  RestartSnapshot
+	issues    map[string]*WatchIssue
+	// Leituras acumuladas entre as atualizações, com window
+	metrics *MetricsData
+}
+
+// currentPodUsage sums the usage and the limits of the containers of each measured pod
+func currentPodUsage(pods []corev1.Pod, metrics *MetricsData) []PodUsage {
+	var usage []PodUsage
+	for i := range pods {
+		pod := &pods[i]
+		pm, exists := metrics.PodMetrics[pod.Name]
+		if !exists || pm.Namespace != pod.Namespace {
+			continue
+		}
+		u := PodUsage{Namespace: pod.Namespace, Name: pod.Name}
+		for _, cm := range pm.Containers {
+			u.CPU += cm.MaxCPU
+			u.Memory += cm.MaxMemory
+		}
+		limitedCPU, limitedMemory := true, true
+		for _, container := range pod.Spec.Containers {
+			limitedCPU = limitedCPU && !container.Resources.Limits.Cpu().IsZero()
+			limitedMemory = limitedMemory && !container.Resources.Limits.Memory().IsZero()
+			u.LimitCPU += container.Resources.Limits.Cpu().MilliValue()
+			u.LimitMemory += container.Resources.Limits.Memory().Value()
+		}
+		if !limitedCPU {
+			u.LimitCPU = 0
+		}
+		if !limitedMemory {
+			u.LimitMemory = 0
+		}
+		usage = append(usage, u)
+	}
+	return usage
+}
+
+// watchFindings detects the issues visible in the current state: imminent risks, containers restarting
+// since the watch started, pods pending for too long and nodes with bad conditions
+func (w *Watch) watchFindings(nodes []corev1.Node, pods []corev1.Pod, metrics *MetricsData, now time.Time) []Finding {
+	findings := detectRisks(nodes, pods, metrics, nil, w.overcommitPct, w.thresholds, w.overrides)
+
+	for _, delta := range computeRestartDeltas(w.restarts, pods, nil) {
+		findings = append(findings, Finding{
+			Kind:      "reinicio",
+			Severity:  SeverityHigh,
+			Title:     fmt.Sprintf("Container %s/%s/%s reiniciou %d vezes", delta.Namespace, delta.Pod, delta.Container, delta.During),
+			Namespace: delta.Namespace,
+			Workload:  delta.Pod + "/" + delta.Container,
+		})
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != corev1.PodPending || now.Sub(pod.CreationTimestamp.Time) < watchPendingAfter {
+			continue
+		}
+		findings = append(findings, Finding{
+			Kind:      "pendente",
+			Severity:  SeverityMedium,
+			Title:     fmt.Sprintf("Pod %s/%s pendente há %v", pod.Namespace, pod.Name, now.Sub(pod.CreationTimestamp.Time).Round(time.Second)),
+			Namespace: pod.Namespace,
+			Workload:  pod.Name,
+		})
+	}
+
+	for _, node := range nodes {
+		for _, condition := range node.Status.Conditions {
+			bad := condition.Status == corev1.ConditionTrue
+			if condition.Type == corev1.NodeReady {
+				bad = condition.Status != corev1.ConditionTrue
+			}
+			if !bad {
+				continue
+			}
+			title := fmt.Sprintf("Node %s com %s", node.Name, condition.Type)
+			if condition.Type == corev1.NodeReady {
+				title = fmt.Sprintf("Node %s não está Ready", node.Name)
+			}
+			findings = append(findings, Finding{
+				Kind:     "node-" + strings.ToLower(string(condition.Type)),
+				Severity: SeverityCritical,
+				Title:    title,
+				Workload: node.Name,
+			})
+		}
+	}
+	return findings
+}
+
+// update keeps the issues still present, remembering when each one first appeared
+func (w *Watch) update(findings []Finding, now time.Time) []*WatchIssue {
+	current := make(map[string]*WatchIssue, len(findings))
+	for _, f := range findings {
+		key := f.key()
+		issue, exists := w.issues[key]
+		if !exists {
+			issue = &WatchIssue{FirstSeen: now, New: w.refreshes > 0}
+		}
+		issue.Finding = f
+		current[key] = issue
+	}
+	w.issues = current
+
+	issues := make([]*WatchIssue, 0, len(current))
+	for _, issue := range current {
+		issues = append(issues, issue)
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Severity != issues[j].Severity {
+			return issues[i].Severity > issues[j].Severity
+		}
+		if !issues[i].FirstSeen.Equal(issues[j].FirstSeen) {
+			return issues[i].FirstSeen.After(issues[j].FirstSeen)
+		}
+		return issues[i].key() < issues[j].key()
+	})
+	return issues
+}
+
+// usagePct formats the usage as a percentage of the limit, or "-" without a limit
+func usagePct(usage, limit int64) string {
+	if limit == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", percent(usage, limit))
+}
+
+func writeTopPods(w io.Writer, title string, usage []PodUsage, less func(a, b PodUsage) bool) {
+	sort.Slice(usage, func(i, j int) bool { return less(usage[i], usage[j]) })
+	fmt.Fprintf(w, "\n%s\n", title)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NAMESPACE\tPOD\tCPU\t%% LIM\tMEMORY\t%% LIM\n")
+	for _, u := range usage[:min(len(usage), watchTopPods)] {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", u.Namespace, u.Name,
+			formatCPU(u.CPU), usagePct(u.CPU, u.LimitCPU), formatMemory(u.Memory), usagePct(u.Memory, u.LimitMemory))
+	}
+	tw.Flush()
+}
+
+// refresh reads the current state of the cluster and renders the view
+func (w *Watch) refresh(out io.Writer) error {
+	metrics := w.metrics
+	if metrics == nil {
+		metrics = &MetricsData{
+			PodMetrics:  make(map[string]*PodMetrics),
+			NodeMetrics: make(map[string]*NodeMetrics),
+			StatsWindow: w.window,
+		}
+	}
+	sample, err := sampleMetricsServer(w.metricsClient, metrics)
+	if err != nil {
+		return err
+	}
+	clear(metrics.reading)
+	// Com janela, as leituras se acumulam entre as atualizações e as tabelas e os riscos usam os picos
+	// da janela, mantidos nos buffers circulares
+	if w.window > 0 {
+		metrics.ClusterSamples = append(metrics.ClusterSamples, sample)
+		applyStatsWindow(metrics, sample.Time)
+		w.metrics = metrics
+	}
+	pods, err := listPods(w.clientset, w.workers)
+	if err != nil {
+		return err
+	}
+	nodes, err := w.clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("erro ao listar nodes: %v", err)
+	}
+	now := time.Now()
+	if w.restarts == nil {
+		w.restarts = make(RestartSnapshot)
+		for i := range pods.Items {
+			w.restarts.record(&pods.Items[i])
+		}
+	}
+	issues := w.update(w.suppressions.filter(w.watchFindings(nodes.Items, pods.Items, metrics, now)), now)
+	w.refreshes++
+
+	allocatableCPU, allocatableMemory := clusterAllocatable(nodes.Items)
+	fmt.Fprintf(out, "👀 %s - %s (a cada %v, Ctrl+C para sair)\n", w.context, now.In(w.location).Format("2006-01-02 15:04:05"), w.interval)
+	fmt.Fprintf(out, "Cluster: CPU %s de %s (%.0f%%), Memory %s de %s (%.0f%%), %d nodes, %d pods\n",
+		formatCPU(sample.CPU), formatCPU(allocatableCPU), percent(sample.CPU, allocatableCPU),
+		formatMemory(sample.Memory), formatMemory(allocatableMemory), percent(sample.Memory, allocatableMemory),
+		len(nodes.Items), len(pods.Items))
+
+	peak := ""
+	if w.window > 0 {
+		_, _, maxCPU, maxMemory := averageSamples(metrics.ClusterSamples)
+		fmt.Fprintf(out, "Pico nas últimas %v: CPU %s (%.0f%%), Memory %s (%.0f%%)\n", w.window,
+			formatCPU(maxCPU), percent(maxCPU, allocatableCPU), formatMemory(maxMemory), percent(maxMemory, allocatableMemory))
+		peak = fmt.Sprintf(" (pico nas últimas %v)", w.window)
+	}
+
+	usage := currentPodUsage(pods.Items, metrics)
+	writeTopPods(out, "Maiores consumidores de CPU"+peak+":", usage, func(a, b PodUsage) bool { return a.CPU > b.CPU })
+	writeTopPods(out, "Maiores consumidores de memória"+peak+":", usage, func(a, b PodUsage) bool { return a.Memory > b.Memory })
+
+	newIssues := 0
+	for _, issue := range issues {
+		if issue.New {
+			newIssues++
+		}
+	}
+	fmt.Fprintf(out, "\nProblemas: %d (%d surgiram durante o watch)\n", len(issues), newIssues)
+	for _, issue := range issues {
+		marker := "  "
+		if issue.New {
+			marker = "🆕"
+		}
+		fmt.Fprintf(out, "%s [%s] desde %s - %s\n", marker, issue.Severity, issue.FirstSeen.In(w.location).Format("15:04:05"), issue.Title)
+	}
+	return nil
+}
+
+// run refreshes the view every interval until interrupted. In a terminal the screen is redrawn;
+// otherwise each refresh is appended, so the output can be piped to a file
+func (w *Watch) run() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	terminal := false
+	if info, err := os.Stdout.Stat(); err == nil {
+		terminal = info.Mode()&os.ModeCharDevice != 0
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		var view strings.Builder
+		if err := w.refresh(&view); err != nil {
+			fmt.Printf("⚠️  Aviso: %v\n", err)
+		} else {
+			if terminal {
+				fmt.Print(clearScreen)
+			} else {
+				fmt.Println()
+			}
+			fmt.Print(view.String())
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("\n👋 Watch encerrado")
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Formatos prontos dos webhooks
+const (
+	webhookFormatJSON  = "json"
+	webhookFormatSlack = "slack"
+	webhookFormatTeams = "teams"
+)
+
+// Problemas listados no evento, dos mais graves para os menos graves
+const webhookMaxFindings = 10
+
+// Evento enviado quando o relatório fica pronto
+const webhookEventReportReady = "report.ready"
+
+var webhookTemplates = map[string]string{
+	webhookFormatJSON: `{{json .}}`,
+	webhookFormatSlack: `{
+  "text": {{json (printf "Relatório do cluster %s: saúde %d/100" .Cluster .HealthScore)}},
+  "blocks": [
+    {"type": "header", "text": {"type": "plain_text", "text": {{json (printf "Relatório de performance: %s" .Cluster)}}}},
+    {"type": "section", "fields": [
+      {"type": "mrkdwn", "text": {{json (printf "*Saúde:* %d/100" .HealthScore)}}},
+      {"type": "mrkdwn", "text": {{json (printf "*Riscos iminentes:* %d" .Risks)}}},
+      {"type": "mrkdwn", "text": {{json (printf "*Requests liberáveis:* %.1f cores, %.1f GiB" .WasteCPUCores .WasteMemoryGiB)}}},
+      {"type": "mrkdwn", "text": {{json (printf "*Problemas:* %d críticos, %d altos" (index .Violations "crítica") (index .Violations "alta"))}}}
+    ]}{{if .Findings}},
+    {"type": "section", "text": {"type": "mrkdwn", "text": {{json (findingLines .Findings "• ")}}}}{{end}},
+    {"type": "context", "elements": [{"type": "mrkdwn", "text": {{json (printf "Relatório: %s" .Report)}}}]}
+  ]
+}`,
+	webhookFormatTeams: `{
+  "type": "message",
+  "attachments": [{
+    "contentType": "application/vnd.microsoft.card.adaptive",
+    "content": {
+      "$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+      "type": "AdaptiveCard",
+      "version": "1.4",
+      "body": [
+        {"type": "TextBlock", "size": "Large", "weight": "Bolder", "text": {{json (printf "Relatório de performance: %s" .Cluster)}}},
+        {"type": "FactSet", "facts": [
+          {"title": "Saúde", "value": {{json (printf "%d/100" .HealthScore)}}},
+          {"title": "Riscos iminentes", "value": {{json (printf "%d" .Risks)}}},
+          {"title": "Requests liberáveis", "value": {{json (printf "%.1f cores, %.1f GiB" .WasteCPUCores .WasteMemoryGiB)}}},
+          {"title": "Problemas críticos/altos", "value": {{json (printf "%d/%d" (index .Violations "crítica") (index .Violations "alta"))}}}
+        ]}{{if .Findings}},
+        {"type": "TextBlock", "wrap": true, "text": {{json (findingLines .Findings "- ")}}}{{end}},
+        {"type": "TextBlock", "isSubtle": true, "wrap": true, "text": {{json (printf "Relatório: %s" .Report)}}}
+      ]
+    }
+  }]
+}`,
+}
+
+// WebhookConfig is a target notified when the report is ready. The payload is a Go template: one of
+// the built-in formats (json, slack, teams) or a custom template. The URL and header values may
+// reference environment variables (${SLACK_WEBHOOK_URL}) to keep secrets out of the file
+type WebhookConfig struct {
+	Name     string            `json:"name,omitempty"`
+	URL      string            `json:"url"`
+	Format   string            `json:"format,omitempty"`
+	Template string            `json:"template,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+}
+
+// WebhookEvent is the data available to the webhook templates
+type WebhookEvent struct {
+	Event          string           `json:"event"`
+	Cluster        string           `json:"cluster"`
+	GeneratedAt    time.Time        `json:"generated_at"`
+	Report         string           `json:"report"`
+	Bundle         string           `json:"bundle,omitempty"`
+	HealthScore    int              `json:"health_score"`
+	WasteCPUCores  float64          `json:"waste_cpu_cores"`
+	WasteMemoryGiB float64          `json:"waste_memory_gib"`
+	Risks          int              `json:"risks"`
+	Deployments    int              `json:"deployments"`
+	Violations     map[string]int   `json:"violations"`
+	Findings       []WebhookFinding `json:"findings,omitempty"`
+}
+
+// WebhookFinding is a finding as sent in the webhook event
+type WebhookFinding struct {
+	// Identificador e código estáveis, os mesmos do relatório JSON
+	ID        string `json:"id"`
+	Code      string `json:"code"`
+	Severity  string `json:"severity"`
+	Title     string `json:"title"`
+	Namespace string `json:"namespace,omitempty"`
+	Workload  string `json:"workload,omitempty"`
+}
+
+var webhookFuncs = template.FuncMap{
+	// json codifica qualquer valor, inclusive strings com aspas e quebras de linha
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"findingLines": func(findings []WebhookFinding, prefix string) string {
+		lines := make([]string, 0, len(findings))
+		for _, f := range findings {
+			lines = append(lines, fmt.Sprintf("%s[%s] %s", prefix, f.Severity, f.Title))
+		}
+		return strings.Join(lines, "\n")
+	},
+}
+
+// label returns the name used in messages about the target
+func (c WebhookConfig) label() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	if c.Format != "" {
+		return c.Format
+	}
+	return webhookFormatJSON
+}
+
+// parseTemplate parses the payload template of the target
+func (c WebhookConfig) parseTemplate() (*template.Template, error) {
+	text := c.Template
+	if text == "" {
+		format := c.Format
+		if format == "" {
+			format = webhookFormatJSON
+		}
+		text = webhookTemplates[format]
+	}
+	return template.New(c.label()).Funcs(webhookFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// validate checks the target and its template
+func (c WebhookConfig) validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("url é obrigatório")
+	}
+	if _, exists := webhookTemplates[c.Format]; c.Format != "" && !exists {
+		return fmt.Errorf("format deve ser json, slack ou teams")
+	}
+	if _, err := c.parseTemplate(); err != nil {
+		return fmt.Errorf("template inválido: %v", err)
+	}
+	return nil
+}
+
+// newWebhookEvent builds the report-ready event from the summary of the run
+func newWebhookEvent(summary RunSummary, findings []Finding, report, bundle string) WebhookEvent {
+	event := WebhookEvent{
+		Event:          webhookEventReportReady,
+		Cluster:        summary.Cluster,
+		GeneratedAt:    summary.Finished,
+		Report:         report,
+		Bundle:         bundle,
+		HealthScore:    summary.HealthScore,
+		WasteCPUCores:  summary.WasteCPUCores,
+		WasteMemoryGiB: summary.WasteMemoryGiB,
+		Risks:          summary.Risks,
+		Deployments:    summary.Deployments,
+		Violations:     make(map[string]int),
+	}
+	for severity := SeverityLow; severity <= SeverityCritical; severity++ {
+		event.Violations[severity.String()] = summary.Findings[severity]
+	}
+	for _, f := range findings[:min(len(findings), webhookMaxFindings)] {
+		event.Findings = append(event.Findings, WebhookFinding{
+			ID:        f.id(),
+			Code:      f.category().Code,
+			Severity:  f.Severity.String(),
+			Title:     f.Title,
+			Namespace: f.Namespace,
+			Workload:  f.Workload,
+		})
+	}
+	return event
+}
+
+// sendWebhooks renders the payload of each target and posts it; a failing target does not stop the
+// others. Returns how many targets were notified
+func sendWebhooks(targets []WebhookConfig, event WebhookEvent) (int, []error) {
+	sent := 0
+	var errs []error
+	for _, target := range targets {
+		if err := sendWebhook(target, event); err != nil {
+			errs = append(errs, fmt.Errorf("erro ao enviar webhook %s: %v", target.label(), err))
+			continue
+		}
+		sent++
+	}
+	return sent, errs
+}
+
+func sendWebhook(target WebhookConfig, event WebhookEvent) error {
+	tmpl, err := target.parseTemplate()
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, event); err != nil {
+		return fmt.Errorf("erro ao aplicar template: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, os.ExpandEnv(target.URL), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range target.Headers {
+		req.Header.Set(key, os.ExpandEnv(value))
+	}
+	resp, err := trackerHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Razão entre o pico de um dia e o dos demais dias a partir da qual o pico é considerado exclusivo do dia
+const weeklyPeakRatio = 1.5
+
+// Dias distintos de histórico necessários para separar os perfis
+const minWeeklyDays = 2
+
+var weekdayLabels = []string{"domingo", "segunda", "terça", "quarta", "quinta", "sexta", "sábado"}
+
+// UsagePeak is the highest CPU and memory observed in a set of runs
+type UsagePeak struct {
+	CPU    int64
+	Memory int64
+}
+
+func (p *UsagePeak) add(cpu, memory int64) {
+	p.CPU = max(p.CPU, cpu)
+	p.Memory = max(p.Memory, memory)
+}
+
+// WeeklyProfile separates the peaks of a deployment on weekdays and weekends across the history
+type WeeklyProfile struct {
+	Name      string
+	Namespace string
+	Days      int
+	Weekday   UsagePeak
+	Weekend   UsagePeak
+	// Pico de cada dia da semana com leituras
+	ByDay map[time.Weekday]UsagePeak
+	// Dias cujo pico supera em weeklyPeakRatio o de todos os outros dias
+	PeakDays []time.Weekday
+	// Limites recomendados com a janela atual, que cortariam o pico semanal
+	LimitCPU    int64
+	LimitMemory int64
+}
+
+func isWeekend(day time.Weekday) bool {
+	return day == time.Saturday || day == time.Sunday
+}
+
+// peak returns the highest peak among all days
+func (p *WeeklyProfile) peak() UsagePeak {
+	var peak UsagePeak
+	peak.add(p.Weekday.CPU, p.Weekday.Memory)
+	peak.add(p.Weekend.CPU, p.Weekend.Memory)
+	return peak
+}
+
+// clipped reports whether the limits recommended from the current window are below the weekly peak
+func (p *WeeklyProfile) clipped() bool {
+	peak := p.peak()
+	return peak.CPU > p.LimitCPU || peak.Memory > p.LimitMemory
+}
+
+// notable reports whether the profile is worth showing: a peak exclusive to some days, or weekday and
+// weekend peaks far apart
+func (p *WeeklyProfile) notable() bool {
+	if len(p.PeakDays) > 0 {
+		return true
+	}
+	ratio := func(a, b int64) float64 {
+		if min(a, b) == 0 {
+			return 0
+		}
+		return float64(max(a, b)) / float64(min(a, b))
+	}
+	return ratio(p.Weekday.CPU, p.Weekend.CPU) >= weeklyPeakRatio || ratio(p.Weekday.Memory, p.Weekend.Memory) >= weeklyPeakRatio
+}
+
+// buildWeeklyProfiles groups the history of each deployment, together with the current run, by day of
+// the week in the report timezone. Deployments need history on minWeeklyDays distinct days, with at
+// least one weekday and one weekend day
+func buildWeeklyProfiles(history []HistoryRecord, deploymentMetrics map[string]*DeploymentMetrics, now time.Time) []*WeeklyProfile {
+	var profiles []*WeeklyProfile
+	for key, dm := range deploymentMetrics {
+		if dm.MaxCPU == 0 && dm.MaxMemory == 0 {
+			continue
+		}
+		p := &WeeklyProfile{Name: dm.Name, Namespace: dm.Namespace, ByDay: make(map[time.Weekday]UsagePeak),
+			LimitCPU: dm.MaxCPU, LimitMemory: dm.MaxMemory}
+		days := make(map[string]bool)
+		add := func(t time.Time, cpu, memory int64) {
+			t = t.In(now.Location())
+			days[t.Format("2006-01-02")] = true
+			peak := p.ByDay[t.Weekday()]
+			peak.add(cpu, memory)
+			p.ByDay[t.Weekday()] = peak
+			if isWeekend(t.Weekday()) {
+				p.Weekend.add(cpu, memory)
+			} else {
+				p.Weekday.add(cpu, memory)
+			}
+		}
+		for _, record := range history {
+			if usage, exists := record.Deployments[key]; exists {
+				add(record.Timestamp, usage.MaxCPU, usage.MaxMemory)
+			}
+		}
+		add(now, dm.MaxCPU, dm.MaxMemory)
+
+		p.Days = len(days)
+		if p.Days < minWeeklyDays || p.Weekday == (UsagePeak{}) || p.Weekend == (UsagePeak{}) {
+			continue
+		}
+		for day, peak := range p.ByDay {
+			var others UsagePeak
+			for other, otherPeak := range p.ByDay {
+				if other != day {
+					others.add(otherPeak.CPU, otherPeak.Memory)
+				}
+			}
+			if (peak.CPU > 0 && float64(peak.CPU) >= float64(others.CPU)*weeklyPeakRatio) ||
+				(peak.Memory > 0 && float64(peak.Memory) >= float64(others.Memory)*weeklyPeakRatio) {
+				p.PeakDays = append(p.PeakDays, day)
+			}
+		}
+		sort.Slice(p.PeakDays, func(i, j int) bool { return p.PeakDays[i] < p.PeakDays[j] })
+		profiles = append(profiles, p)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].Namespace != profiles[j].Namespace {
+			return profiles[i].Namespace < profiles[j].Namespace
+		}
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
+
+// weekdayList formats the days of the week by name
+func weekdayList(days []time.Weekday) string {
+	names := make([]string, len(days))
+	for i, day := range days {
+		names[i] = weekdayLabels[day]
+	}
+	return strings.Join(names, ", ")
+}
+
+// weeklyPeakFindings points out the deployments whose limits recommended from the current window would
+// cut a peak seen only on some days of the week
+func weeklyPeakFindings(profiles []*WeeklyProfile) []Finding {
+	var findings []Finding
+	for _, p := range profiles {
+		if !p.notable() || !p.clipped() {
+			continue
+		}
+		peak := p.peak()
+		findings = append(findings, Finding{
+			Kind:      "pico-semanal",
+			Severity:  SeverityMedium,
+			Title:     fmt.Sprintf("Limites sugeridos para %s/%s cortam o pico semanal", p.Namespace, p.Name),
+			Namespace: p.Namespace,
+			Workload:  p.Name,
+			Body: fmt.Sprintf("O histórico registra picos de CPU %s e Memory %s, acima dos limites sugeridos pela janela atual (CPU %s, Memory %s).\n\nRecomendação: dimensionar os limites pelo pico semanal ou escalar o deployment apenas nos dias de pico.",
+				formatCPU(peak.CPU), formatMemory(peak.Memory), formatCPU(p.LimitCPU), formatMemory(p.LimitMemory)),
+		})
+	}
+	return findings
+}
+
+func writeWeeklyProfiles(w io.Writer, profiles []*WeeklyProfile) {
+	fmt.Fprintf(w, "\n=== Perfil Semanal de Uso ===\n")
+	fmt.Fprintf(w, "-----------------------------\n")
+
+	if len(profiles) == 0 {
+		fmt.Fprintf(w, "Histórico insuficiente para separar dias úteis e fim de semana (mínimo de %d dias, com ao menos um de cada)\n", minWeeklyDays)
+		return
+	}
+
+	shown := 0
+	for _, p := range profiles {
+		if !p.notable() {
+			continue
+		}
+		shown++
+		fmt.Fprintf(w, "\nDeployment: %s (Namespace: %s) - %d dias de histórico\n", p.Name, p.Namespace, p.Days)
+		fmt.Fprintf(w, "  Dias úteis: pico de CPU %s, Memory %s\n", formatCPU(p.Weekday.CPU), formatMemory(p.Weekday.Memory))
+		fmt.Fprintf(w, "  Fim de semana: pico de CPU %s, Memory %s\n", formatCPU(p.Weekend.CPU), formatMemory(p.Weekend.Memory))
+		if len(p.PeakDays) > 0 {
+			fmt.Fprintf(w, "  Picos apenas em: %s\n", weekdayList(p.PeakDays))
+		}
+		if p.clipped() {
+			peak := p.peak()
+			fmt.Fprintf(w, "  Problema: os limites sugeridos pela janela atual (CPU %s, Memory %s) cortariam o pico semanal\n",
+				formatCPU(p.LimitCPU), formatMemory(p.LimitMemory))
+			fmt.Fprintf(w, "  Recomendação: usar limites de pelo menos CPU %s e Memory %s, ou escalar o deployment apenas nos dias de pico\n",
+				formatCPU(peak.CPU), formatMemory(peak.Memory))
+		}
+	}
+	fmt.Fprintf(w, "\nDeployments com perfis semanais: %d (com picos concentrados em alguns dias: %d)\n", len(profiles), shown)
+}
+
+// countClippedWeeklyPeaks returns how many deployments would have their weekly peak cut by the recommended limits
+func countClippedWeeklyPeaks(profiles []*WeeklyProfile) int {
+	count := 0
+	for _, p := range profiles {
+		if p.notable() && p.clipped() {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeWindow is a recurring weekly time range, e.g. "Mon-Fri 09:00-18:00"
+type TimeWindow struct {
+	Spec  string
+	Days  [7]bool
+	Start int // minutos desde 00:00
+	End   int // minutos desde 00:00; menor que Start para faixas que cruzam a meia-noite
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	day, exists := weekdayNames[strings.ToLower(name)]
+	if !exists {
+		return 0, fmt.Errorf("dia da semana inválido: %q (use Sun, Mon, Tue, Wed, Thu, Fri ou Sat)", name)
+	}
+	return day, nil
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		if value == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, fmt.Errorf("horário inválido: %q (use HH:MM)", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseTimeWindow parses "<dias> <início>-<fim>", where dias is a range (Mon-Fri),
+// a list (Sat,Sun) or "*" for every day
+func parseTimeWindow(spec string) (TimeWindow, error) {
+	window := TimeWindow{Spec: strings.TrimSpace(spec)}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 2 {
+		return window, fmt.Errorf("janela inválida: %q (formato esperado: \"Mon-Fri 09:00-18:00\")", spec)
+	}
+
+	if fields[0] == "*" {
+		for i := range window.Days {
+			window.Days[i] = true
+		}
+	} else {
+		for _, part := range strings.Split(fields[0], ",") {
+			bounds := strings.SplitN(part, "-", 2)
+			first, err := parseWeekday(bounds[0])
+			if err != nil {
+				return window, err
+			}
+			last := first
+			if len(bounds) == 2 {
+				if last, err = parseWeekday(bounds[1]); err != nil {
+					return window, err
+				}
+			}
+			for day := first; ; day = (day + 1) % 7 {
+				window.Days[day] = true
+				if day == last {
+					break
+				}
+			}
+		}
+	}
+
+	times := strings.SplitN(fields[1], "-", 2)
+	if len(times) != 2 {
+		return window, fmt.Errorf("faixa de horário inválida: %q (use HH:MM-HH:MM)", fields[1])
+	}
+	var err error
+	if window.Start, err = parseClock(times[0]); err != nil {
+		return window, err
+	}
+	if window.End, err = parseClock(times[1]); err != nil {
+		return window, err
+	}
+	if window.Start == window.End {
+		return window, fmt.Errorf("faixa de horário vazia: %q", fields[1])
+	}
+
+	return window, nil
+}
+
+// parseTimeWindows parses a list of windows separated by ";"
+func parseTimeWindows(spec string) ([]TimeWindow, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	var windows []TimeWindow
+	for _, part := range strings.Split(spec, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		window, err := parseTimeWindow(part)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// contains reports whether t falls inside the window
+func (w TimeWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start < w.End {
+		return w.Days[t.Weekday()] && minute >= w.Start && minute < w.End
+	}
+
+	// Faixa que cruza a meia-noite: a parte após a meia-noite pertence ao dia anterior
+	if minute >= w.Start {
+		return w.Days[t.Weekday()]
+	}
+	if minute < w.End {
+		return w.Days[(t.Weekday()+6)%7]
+	}
+	return false
+}
+
+// sampleAllowed reports whether a sample taken at t should be considered, given the
+// collection windows (empty means always) and the blackout periods
+func sampleAllowed(t time.Time, windows, blackouts []TimeWindow) bool {
+	for _, b := range blackouts {
+		if b.contains(t) {
+			return false
+		}
+	}
+	if len(windows) == 0 {
+		return true
+	}
+	for _, w := range windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatTimeWindows(windows []TimeWindow) string {
+	specs := make([]string, 0, len(windows))
+	for _, w := range windows {
+		specs = append(specs, w.Spec)
+	}
+	return strings.Join(specs, "; ")
+}
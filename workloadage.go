@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// Período usado para medir a frequência de rollouts dos deployments
+const rolloutFrequencyWindow = 30 * 24 * time.Hour
+
+// WorkloadHistory is the age and the rollout history of a deployment, from its creation time and the
+// ReplicaSets it kept (limited by revisionHistoryLimit, 10 by default)
+type WorkloadHistory struct {
+	Created time.Time
+	// Revisões mantidas nos ReplicaSets
+	Revisions int
+	// Rollouts (novas revisões) nos últimos 30 dias e horário do mais recente
+	Rollouts    int
+	LastRollout time.Time
+}
+
+// rolloutsPerWeek is the rollout frequency in the last 30 days, or since the creation for younger
+// deployments
+func (h *WorkloadHistory) rolloutsPerWeek(now time.Time) float64 {
+	span := min(now.Sub(h.Created), rolloutFrequencyWindow)
+	if span <= 0 {
+		return 0
+	}
+	return float64(h.Rollouts) / (span.Hours() / (24 * 7))
+}
+
+func (h *WorkloadHistory) String() string {
+	now := time.Now()
+	s := fmt.Sprintf("criado em %s (%s), %d revisões mantidas, %d rollouts em 30 dias (%.1f por semana)",
+		h.Created.Format("2006-01-02"), formatAge(now.Sub(h.Created)), h.Revisions, h.Rollouts, h.rolloutsPerWeek(now))
+	if !h.LastRollout.IsZero() {
+		s += fmt.Sprintf(", último há %s", formatAge(now.Sub(h.LastRollout)))
+	}
+	return s
+}
+
+// assignWorkloadHistory records the age and the rollouts of each deployment. The first revision is the
+// creation of the deployment and does not count as a rollout
+func assignWorkloadHistory(deploymentMetrics map[string]*DeploymentMetrics, deployments map[string]*appsv1.Deployment, replicaSets []appsv1.ReplicaSet, now time.Time) {
+	histories := make(map[string]*WorkloadHistory)
+	for key, dm := range deploymentMetrics {
+		d, exists := deployments[key]
+		if !exists {
+			continue
+		}
+		h := &WorkloadHistory{Created: d.CreationTimestamp.Time}
+		dm.History = h
+		histories[key] = h
+	}
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		h, exists := histories[rs.Namespace+"/"+replicaSetDeployment(rs)]
+		if !exists {
+			continue
+		}
+		h.Revisions++
+		created := rs.CreationTimestamp.Time
+		if replicaSetRevision(rs) <= 1 || created.Sub(h.Created) < time.Minute {
+			continue
+		}
+		if now.Sub(created) <= rolloutFrequencyWindow {
+			h.Rollouts++
+		}
+		if created.After(h.LastRollout) {
+			h.LastRollout = created
+		}
+	}
+}
+
+// lowerConfidenceForNewWorkloads lowers one level of confidence of the recommendations of deployments
+// created after the start of the samples, whose usage may include pods of a previous deployment with the
+// same name, or younger than a day, which did not go through a full daily cycle
+func lowerConfidenceForNewWorkloads(deploymentMetrics map[string]*DeploymentMetrics, metrics *MetricsData, now time.Time) int {
+	var windowStart time.Time
+	if len(metrics.ClusterSamples) > 0 {
+		windowStart = metrics.ClusterSamples[0].Time
+	}
+	lowered := 0
+	for _, dm := range deploymentMetrics {
+		if dm.History == nil || dm.Confidence == nil {
+			continue
+		}
+		var reason string
+		switch age := now.Sub(dm.History.Created); {
+		case !windowStart.IsZero() && dm.History.Created.After(windowStart):
+			reason = fmt.Sprintf("deployment criado durante a janela das leituras (há %s)", formatAge(age))
+		case age < confidenceHighWindow:
+			reason = fmt.Sprintf("deployment criado há %s", formatAge(age))
+		default:
+			continue
+		}
+		if dm.Confidence.Level > ConfidenceLow {
+			dm.Confidence.Level--
+		}
+		dm.Confidence.Reasons = append(dm.Confidence.Reasons, reason)
+		lowered++
+	}
+	return lowered
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const unknownZone = "(sem zona)"
+
+// ZoneCapacity aggregates capacity, requests and usage of the nodes in a zone
+type ZoneCapacity struct {
+	Zone              string
+	Nodes             int
+	Pods              int
+	AllocatableCPU    int64
+	AllocatableMemory int64
+	RequestedCPU      int64
+	RequestedMemory   int64
+	UsedCPU           int64
+	UsedMemory        int64
+}
+
+// ZoneConcentratedWorkload is a multi-replica deployment whose pods all run in a single zone
+type ZoneConcentratedWorkload struct {
+	Name      string
+	Namespace string
+	Zone      string
+	Pods      int
+}
+
+// ZoneReport holds the zone-level capacity and distribution analysis
+type ZoneReport struct {
+	Zones        []ZoneCapacity
+	Concentrated []ZoneConcentratedWorkload
+}
+
+// nodeZone returns the topology zone of the node, falling back to the deprecated label
+func nodeZone(node *corev1.Node) string {
+	if zone, exists := node.Labels[corev1.LabelTopologyZone]; exists && zone != "" {
+		return zone
+	}
+	if zone, exists := node.Labels[corev1.LabelFailureDomainBetaZone]; exists && zone != "" {
+		return zone
+	}
+	return unknownZone
+}
+
+func analyzeZones(nodes []corev1.Node, pods []corev1.Pod, metrics *MetricsData, deploymentMetrics map[string]*DeploymentMetrics) *ZoneReport {
+	zones := make(map[string]*ZoneCapacity)
+	zoneOfNode := make(map[string]string)
+
+	for i := range nodes {
+		node := &nodes[i]
+		zone := nodeZone(node)
+		zoneOfNode[node.Name] = zone
+
+		zc, exists := zones[zone]
+		if !exists {
+			zc = &ZoneCapacity{Zone: zone}
+			zones[zone] = zc
+		}
+		zc.Nodes++
+		zc.AllocatableCPU += node.Status.Allocatable.Cpu().MilliValue()
+		zc.AllocatableMemory += node.Status.Allocatable.Memory().Value()
+		if nodeMetrics, exists := metrics.NodeMetrics[node.Name]; exists {
+			zc.UsedCPU += nodeMetrics.MaxCPU
+			zc.UsedMemory += nodeMetrics.MaxMemory
+		}
+	}
+
+	zoneOfPod := make(map[string]string)
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		zone, exists := zoneOfNode[pod.Spec.NodeName]
+		if !exists {
+			continue
+		}
+		zoneOfPod[pod.Namespace+"/"+pod.Name] = zone
+
+		cpu, memory := podRequests(pod)
+		zones[zone].Pods++
+		zones[zone].RequestedCPU += cpu
+		zones[zone].RequestedMemory += memory
+	}
+
+	report := &ZoneReport{}
+	for _, zc := range zones {
+		report.Zones = append(report.Zones, *zc)
+	}
+	sort.Slice(report.Zones, func(i, j int) bool {
+		return report.Zones[i].Zone < report.Zones[j].Zone
+	})
+
+	// Com uma única zona não há concentração a apontar
+	if len(zones) < 2 {
+		return report
+	}
+
+	for _, dm := range deploymentMetrics {
+		if len(dm.Pods) < 2 {
+			continue
+		}
+		podZones := make(map[string]int)
+		for _, podName := range dm.Pods {
+			if zone, exists := zoneOfPod[dm.Namespace+"/"+podName]; exists {
+				podZones[zone]++
+			}
+		}
+		if len(podZones) != 1 {
+			continue
+		}
+		for zone, count := range podZones {
+			if count < 2 {
+				continue
+			}
+			report.Concentrated = append(report.Concentrated, ZoneConcentratedWorkload{
+				Name:      dm.Name,
+				Namespace: dm.Namespace,
+				Zone:      zone,
+				Pods:      count,
+			})
+		}
+	}
+	sort.Slice(report.Concentrated, func(i, j int) bool {
+		if report.Concentrated[i].Namespace != report.Concentrated[j].Namespace {
+			return report.Concentrated[i].Namespace < report.Concentrated[j].Namespace
+		}
+		return report.Concentrated[i].Name < report.Concentrated[j].Name
+	})
+
+	return report
+}
+
+func percent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+func writeZoneReport(w io.Writer, report *ZoneReport) {
+	fmt.Fprintf(w, "\n=== Capacidade e Distribuição por Zona ===\n")
+	fmt.Fprintf(w, "------------------------------------------\n")
+
+	for _, zc := range report.Zones {
+		fmt.Fprintf(w, "\nZona: %s\n", zc.Zone)
+		fmt.Fprintf(w, "  Nodes: %d, Pods: %d\n", zc.Nodes, zc.Pods)
+		fmt.Fprintf(w, "  CPU: alocável %s, requests %s (%.1f%%), uso de pico %s (%.1f%%)\n",
+			formatCPU(zc.AllocatableCPU), formatCPU(zc.RequestedCPU), percent(zc.RequestedCPU, zc.AllocatableCPU),
+			formatCPU(zc.UsedCPU), percent(zc.UsedCPU, zc.AllocatableCPU))
+		fmt.Fprintf(w, "  Memory: alocável %s, requests %s (%.1f%%), uso de pico %s (%.1f%%)\n",
+			formatMemory(zc.AllocatableMemory), formatMemory(zc.RequestedMemory), percent(zc.RequestedMemory, zc.AllocatableMemory),
+			formatMemory(zc.UsedMemory), percent(zc.UsedMemory, zc.AllocatableMemory))
+	}
+
+	if len(report.Zones) < 2 {
+		fmt.Fprintf(w, "\nCluster com uma única zona - não há redundância entre zonas\n")
+		return
+	}
+
+	if len(report.Concentrated) == 0 {
+		fmt.Fprintf(w, "\nNenhum deployment com todas as réplicas em uma única zona\n")
+		return
+	}
+
+	fmt.Fprintf(w, "\nDeployments com todas as réplicas em uma única zona:\n")
+	for _, c := range report.Concentrated {
+		fmt.Fprintf(w, "- %s (Namespace: %s): %d pods na zona %s\n", c.Name, c.Namespace, c.Pods, c.Zone)
+	}
+	fmt.Fprintf(w, "Recomendação: Adicionar topologySpreadConstraints com topologyKey %s para tolerar a perda de uma zona\n", corev1.LabelTopologyZone)
+	fmt.Fprintf(w, "Impacto: Alto - Risco de indisponibilidade em caso de falha da zona\n")
+	fmt.Fprintf(w, "Prioridade: Alta\n")
+}